@@ -123,9 +123,9 @@ func (wb *WindowBuilder[T]) DiscardingMode() *WindowBuilder[T] {
 
 // Apply creates the windowing filter with the configured settings
 func (wb *WindowBuilder[T]) Apply() Filter[T, Stream[T]] {
-	return func(input Stream[T]) Stream[Stream[T]] {
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
 		return wb.createAdvancedWindow(input)
-	}
+	})
 }
 
 // ============================================================================
@@ -135,7 +135,7 @@ func (wb *WindowBuilder[T]) Apply() Filter[T, Stream[T]] {
 // SessionWindow creates activity-based windows that group related events
 // Windows extend as long as activity is detected within the timeout period
 func SessionWindow[T any](timeout time.Duration, activityDetector ActivityDetector[T]) Filter[T, Stream[T]] {
-	return func(input Stream[T]) Stream[Stream[T]] {
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
 		ctx, cancel := context.WithCancel(context.Background())
 		windowCh := make(chan Stream[T], 10)
 		
@@ -239,7 +239,7 @@ func SessionWindow[T any](timeout time.Duration, activityDetector ActivityDetect
 			}
 			return window, nil
 		}
-	}
+	})
 }
 
 // SessionState holds the state of a session window
@@ -1,8 +1,18 @@
 package stream
 
 import (
+	"container/list"
+	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
 )
 
 // ============================================================================
@@ -47,9 +57,182 @@ func Min[T Comparable](stream Stream[T]) (T, error) {
 	return RunAggregator(stream, MinAggregator[T, T](func(val T) T { return val }))
 }
 
-// Avg calculates average of numeric values
-func Avg[T Numeric](stream Stream[T]) (float64, error) {
-	return RunAggregator(stream, AvgAggregator[T, T](func(val T) T { return val }))
+// MinTime finds the earliest time; the zero time if the stream is empty
+func MinTime(stream Stream[time.Time]) (time.Time, error) {
+	return RunAggregator(stream, MinTimeAggregator(func(t time.Time) (time.Time, bool) { return t, true }))
+}
+
+// MaxTime finds the latest time; the zero time if the stream is empty
+func MaxTime(stream Stream[time.Time]) (time.Time, error) {
+	return RunAggregator(stream, MaxTimeAggregator(func(t time.Time) (time.Time, bool) { return t, true }))
+}
+
+// Avg calculates average of numeric values. NaN and ±Inf values propagate into the
+// result unless a NaNPolicy is given with WithNaNPolicy.
+func Avg[T Numeric](stream Stream[T], options ...NaNOption) (float64, error) {
+	guarded := guardNaN[T](newNaNConfig(options))(stream)
+	return RunAggregator(guarded, AvgAggregator[T, T](func(val T) T { return val }))
+}
+
+// ============================================================================
+// NUMERIC SAFETY - OVERFLOW AND NaN HANDLING
+// ============================================================================
+
+// ErrOverflow is returned by SumChecked when a sum does not fit its type
+var ErrOverflow = errors.New("numeric overflow")
+
+// ErrNaN is returned under NaNError when a NaN or infinite value is encountered
+var ErrNaN = errors.New("NaN or infinite value")
+
+// SumChecked sums numeric values like Sum, but fails with ErrOverflow instead of
+// wrapping when an integer sum overflows, or when a float sum of finite values
+// overflows to infinity
+func SumChecked[T Numeric](stream Stream[T]) (T, error) {
+	var sum T
+	for index := int64(0); ; index++ {
+		val, err := stream()
+		if err != nil {
+			if errors.Is(err, EOS) {
+				return sum, nil
+			}
+			return sum, err
+		}
+		next, ok := addChecked(sum, val)
+		if !ok {
+			return sum, fmt.Errorf("%w: adding %v to %v at element %d", ErrOverflow, val, sum, index)
+		}
+		sum = next
+	}
+}
+
+// addChecked adds two numbers, reporting false if the result overflowed
+func addChecked[T Numeric](a, b T) (T, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return sum, false // Integer wraparound; float addition never moves the wrong way
+	}
+	if math.IsInf(float64(sum), 0) && !math.IsInf(float64(a), 0) && !math.IsInf(float64(b), 0) {
+		return sum, false
+	}
+	return sum, true
+}
+
+// NaNPolicy defines how numeric aggregations treat NaN and ±Inf values
+type NaNPolicy int
+
+const (
+	NaNPropagate NaNPolicy = iota // Values flow into the result, as IEEE arithmetic does (the default)
+	NaNSkip                       // Values are left out, as if absent, and counted as skipped
+	NaNError                      // The first such value fails the aggregation with ErrNaN
+)
+
+// NaNOption configures how an aggregation treats NaN and ±Inf values
+type NaNOption func(*nanConfig)
+
+// nanConfig holds NaN handling configuration
+type nanConfig struct {
+	policy   NaNPolicy
+	counters []*int64 // Skipped counters
+}
+
+// WithNaNPolicy sets how NaN and ±Inf values are treated; the default is NaNPropagate
+func WithNaNPolicy(policy NaNPolicy) NaNOption {
+	return func(config *nanConfig) {
+		config.policy = policy
+	}
+}
+
+// WithSkippedCount adds the number of values skipped under NaNSkip to *counter.
+// The counter is updated atomically, so one counter may be shared by the groups of a GroupBy.
+func WithSkippedCount(counter *int64) NaNOption {
+	if counter == nil {
+		panic("skipped counter must not be nil")
+	}
+	return func(config *nanConfig) {
+		config.counters = append(config.counters, counter)
+	}
+}
+
+// newNaNConfig applies NaN options
+func newNaNConfig(options []NaNOption) nanConfig {
+	config := nanConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// skip counts a skipped value
+func (config nanConfig) skip() {
+	for _, counter := range config.counters {
+		atomic.AddInt64(counter, 1)
+	}
+}
+
+// isNaNOrInf reports whether value is a NaN or an infinity
+func isNaNOrInf(value float64) bool {
+	return math.IsNaN(value) || math.IsInf(value, 0)
+}
+
+// guardNaN applies a NaN policy to a numeric stream: under NaNSkip such values are
+// dropped, under NaNError the stream fails with ErrNaN at the first one
+func guardNaN[T Numeric](config nanConfig) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		if config.policy == NaNPropagate {
+			return input
+		}
+		var finalErr error // Sticky once a value fails under NaNError
+		return func() (T, error) {
+			var zero T
+			if finalErr != nil {
+				return zero, finalErr
+			}
+			for {
+				value, err := input()
+				if err != nil || !isNaNOrInf(float64(value)) {
+					return value, err
+				}
+				if config.policy == NaNError {
+					finalErr = fmt.Errorf("%w: %v", ErrNaN, value)
+					return zero, finalErr
+				}
+				config.skip()
+			}
+		}
+	}
+}
+
+// nanGuarded is the accumulator of an aggregator wrapped by withNaNPolicy
+type nanGuarded[A any] struct {
+	acc A
+	err error
+}
+
+// withNaNPolicy applies a NaN policy to an aggregator over Records, checking the
+// numeric value of field before each record reaches agg
+func withNaNPolicy[A, R any](agg Aggregator[Record, A, R], field string, config nanConfig) any {
+	if config.policy == NaNPropagate {
+		return agg
+	}
+	return Aggregator[Record, nanGuarded[A], R]{
+		Initial: func() nanGuarded[A] { return nanGuarded[A]{acc: agg.Initial()} },
+		Accumulate: func(state nanGuarded[A], record Record) nanGuarded[A] {
+			if value, ok := convertToFloat64(record[field]); ok && isNaNOrInf(value) {
+				if config.policy == NaNError {
+					if state.err == nil {
+						state.err = fmt.Errorf("%w in field %q: %v", ErrNaN, field, value)
+					}
+					return state
+				}
+				config.skip()
+				return state
+			}
+			state.acc = agg.Accumulate(state.acc, record)
+			return state
+		},
+		Finalize: func(state nanGuarded[A]) R { return agg.Finalize(state.acc) },
+		Check:    func(state nanGuarded[A]) error { return state.err },
+	}
 }
 
 // ============================================================================
@@ -71,6 +254,71 @@ func Collect[T any](stream Stream[T]) ([]T, error) {
 	}
 }
 
+// ErrLimitExceeded is returned by CollectWithLimit when a stream has more elements than allowed
+var ErrLimitExceeded = errors.New("stream exceeded collection limit")
+
+// CollectN gathers at most n elements. exhausted reports whether the stream ended first;
+// it is false whenever n elements were read, because finding out whether more follow
+// would consume one. The rest of the stream is left unread. A non-EOS error is returned
+// with the elements read before it, as Collect does.
+func CollectN[T any](stream Stream[T], n int) (result []T, exhausted bool, err error) {
+	if n < 0 {
+		panic("n must not be negative")
+	}
+	for len(result) < n {
+		item, err := stream()
+		if err != nil {
+			if errors.Is(err, EOS) {
+				return result, true, nil
+			}
+			return result, false, err
+		}
+		result = append(result, item)
+	}
+	return result, false, nil
+}
+
+// CollectWithLimit gathers all elements like Collect, but fails fast with ErrLimitExceeded
+// as soon as the stream produces more than max, returning the first max. Use it where a
+// stream should be finite and small, so a runaway source fails rather than exhausting memory.
+func CollectWithLimit[T any](stream Stream[T], max int) ([]T, error) {
+	if max < 0 {
+		panic("max must not be negative")
+	}
+	result, exhausted, err := CollectN(stream, max)
+	if err != nil || exhausted {
+		return result, err
+	}
+	// max elements read: the stream must end now
+	if _, err := stream(); !errors.Is(err, EOS) {
+		if err != nil {
+			return result, err
+		}
+		return result, fmt.Errorf("%w: more than %d elements", ErrLimitExceeded, max)
+	}
+	return result, nil
+}
+
+// CollectContext gathers all elements like Collect, stopping with ctx.Err() and the
+// elements read so far once ctx is done. ctx is checked before every pull, so a pull
+// that is already blocked (on an idle channel, say) is not interrupted.
+func CollectContext[T any](ctx context.Context, stream Stream[T]) ([]T, error) {
+	var result []T
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		item, err := stream()
+		if err != nil {
+			if errors.Is(err, EOS) {
+				return result, nil
+			}
+			return result, err
+		}
+		result = append(result, item)
+	}
+}
+
 // ForEach executes a function for each element
 func ForEach[T any](fn func(T)) func(Stream[T]) error {
 	return func(stream Stream[T]) error {
@@ -87,6 +335,73 @@ func ForEach[T any](fn func(T)) func(Stream[T]) error {
 	}
 }
 
+// ForEachIndexed executes a function for each element along with its zero-based
+// position, for progress reporting and the like
+func ForEachIndexed[T any](fn func(int64, T)) func(Stream[T]) error {
+	return func(stream Stream[T]) error {
+		for i := int64(0); ; i++ {
+			item, err := stream()
+			if err != nil {
+				if errors.Is(err, EOS) {
+					return nil
+				}
+				return err
+			}
+			fn(i, item)
+		}
+	}
+}
+
+// ConsumeErr executes a function for each element until it returns an error, which
+// stops consumption and is returned; the stream is not pulled again after that
+func ConsumeErr[T any](fn func(T) error) func(Stream[T]) error {
+	return func(stream Stream[T]) error {
+		for {
+			item, err := stream()
+			if err != nil {
+				if errors.Is(err, EOS) {
+					return nil
+				}
+				return err
+			}
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Drain pulls every element and discards it, returning how many there were. Use it to
+// run a pipeline for its side effects. Drain itself allocates nothing per element.
+func Drain[T any](stream Stream[T]) (int64, error) {
+	var count int64
+	for {
+		_, err := stream()
+		if err != nil {
+			if errors.Is(err, EOS) {
+				return count, nil
+			}
+			return count, err
+		}
+		count++
+	}
+}
+
+// Reduce folds all stream elements into a single accumulated value
+func Reduce[T, A any](stream Stream[T], initial A, fn func(A, T) A) (A, error) {
+	acc := initial
+	for {
+		item, err := stream()
+		if err != nil {
+			if errors.Is(err, EOS) {
+				return acc, nil
+			}
+			return acc, err
+		}
+		acc = fn(acc, item)
+	}
+}
+
 // ============================================================================
 // GENERALIZED AGGREGATION SUPPORT
 // ============================================================================
@@ -96,6 +411,120 @@ type Aggregator[T, A, R any] struct {
 	Initial    func() A           // Create initial accumulator
 	Accumulate func(A, T) A      // Process each element
 	Finalize   func(A) R         // Produce final result
+	Check      func(A) error     // Optional: report an accumulator that has failed, e.g. on NaN under NaNError
+}
+
+// finish finalizes an accumulator, failing if Check reports an error
+func (agg Aggregator[T, A, R]) finish(acc A) (R, error) {
+	if agg.Check != nil {
+		if err := agg.Check(acc); err != nil {
+			var zero R
+			return zero, err
+		}
+	}
+	return agg.Finalize(acc), nil
+}
+
+// AggregatorRunner runs an aggregator without knowing its accumulator or result
+// types, which lets Aggregates, GroupBy and GroupByStreaming dispatch type-erased
+// AggregatorSpecs. Every Aggregator implements it and is fed one element at a time.
+// An AggregatorSpec may hold any other implementation too: its elements, per group,
+// are buffered and passed to RunOn as a stream when the result is needed.
+type AggregatorRunner[T any] interface {
+	RunOn(stream Stream[T]) (any, error)
+}
+
+// incrementalRunner is an AggregatorRunner that can be fed one element at a time
+type incrementalRunner[T any] interface {
+	AggregatorRunner[T]
+	start() runningAggregate[T]
+}
+
+// startRunner opens a running aggregate for runner, buffering the elements of
+// runners that can only RunOn
+func startRunner[T any](runner AggregatorRunner[T]) runningAggregate[T] {
+	if incremental, ok := runner.(incrementalRunner[T]); ok {
+		return incremental.start()
+	}
+	return &bufferedRun[T]{runner: runner}
+}
+
+// RunOn runs the aggregator on a stream and returns the result as an any
+func (agg Aggregator[T, A, R]) RunOn(stream Stream[T]) (any, error) {
+	return AggregateWith(stream, agg)
+}
+
+// runningAggregate is a type-erased accumulator fed one element at a time
+type runningAggregate[T any] interface {
+	add(item T)
+	result() any
+	err() error
+}
+
+// aggregatorState is the runningAggregate for an Aggregator
+type aggregatorState[T, A, R any] struct {
+	agg Aggregator[T, A, R]
+	acc A
+}
+
+func (s *aggregatorState[T, A, R]) add(item T) {
+	s.acc = s.agg.Accumulate(s.acc, item)
+}
+
+func (s *aggregatorState[T, A, R]) result() any {
+	return s.agg.Finalize(s.acc)
+}
+
+func (s *aggregatorState[T, A, R]) err() error {
+	if s.agg.Check == nil {
+		return nil
+	}
+	return s.agg.Check(s.acc)
+}
+
+func (agg Aggregator[T, A, R]) start() runningAggregate[T] {
+	return &aggregatorState[T, A, R]{agg: agg, acc: agg.Initial()}
+}
+
+// bufferedRun is the runningAggregate for an AggregatorRunner that can only RunOn
+type bufferedRun[T any] struct {
+	runner  AggregatorRunner[T]
+	items   []T
+	ran     bool // Whether value and failure are for the current items
+	value   any
+	failure error
+}
+
+func (b *bufferedRun[T]) add(item T) {
+	b.items = append(b.items, item)
+	b.ran = false
+}
+
+func (b *bufferedRun[T]) run() {
+	if !b.ran {
+		b.value, b.failure = b.runner.RunOn(FromSliceAny(b.items))
+		b.ran = true
+	}
+}
+
+func (b *bufferedRun[T]) result() any {
+	b.run()
+	return b.value
+}
+
+func (b *bufferedRun[T]) err() error {
+	b.run()
+	return b.failure
+}
+
+// unsupportedAggregator reports the first spec whose Agg is not an Aggregator over T
+func unsupportedAggregator[T any](specs []AggregatorSpec[T]) error {
+	for _, spec := range specs {
+		if _, ok := spec.Agg.(AggregatorRunner[T]); !ok {
+			return fmt.Errorf("unsupported aggregator type for '%s'", spec.Name)
+		}
+	}
+	return nil
 }
 
 // AggregateWith runs a single custom aggregator on a stream
@@ -106,7 +535,7 @@ func AggregateWith[T, A, R any](stream Stream[T], agg Aggregator[T, A, R]) (R, e
 		val, err := stream()
 		if err != nil {
 			if errors.Is(err, EOS) {
-				return agg.Finalize(acc), nil
+				return agg.finish(acc)
 			}
 			var zero R
 			return zero, err
@@ -116,64 +545,63 @@ func AggregateWith[T, A, R any](stream Stream[T], agg Aggregator[T, A, R]) (R, e
 	}
 }
 
-// AggregateMultiple runs multiple aggregators in parallel using Tee
+// AggregateMultiple runs two aggregators over a stream in a single pass, feeding each
+// element to both accumulators, so the stream is read once and nothing is buffered
 func AggregateMultiple[T any, A1, A2, R1, R2 any](
 	stream Stream[T],
 	agg1 Aggregator[T, A1, R1],
 	agg2 Aggregator[T, A2, R2],
 ) (R1, R2, error) {
-	streams := Tee(stream, 2)
-	
-	result1, err1 := AggregateWith(streams[0], agg1)
-	result2, err2 := AggregateWith(streams[1], agg2)
-	
-	if err1 != nil {
-		var zero1 R1
-		var zero2 R2
-		return zero1, zero2, err1
-	}
-	if err2 != nil {
-		var zero1 R1
-		var zero2 R2
-		return zero1, zero2, err2
+	acc1, acc2 := agg1.Initial(), agg2.Initial()
+	for {
+		val, err := stream()
+		if err != nil {
+			var zero1 R1
+			var zero2 R2
+			if errors.Is(err, EOS) {
+				result1, err1 := agg1.finish(acc1)
+				result2, err2 := agg2.finish(acc2)
+				if err := errors.Join(err1, err2); err != nil {
+					return zero1, zero2, err
+				}
+				return result1, result2, nil
+			}
+			return zero1, zero2, err
+		}
+		acc1 = agg1.Accumulate(acc1, val)
+		acc2 = agg2.Accumulate(acc2, val)
 	}
-	
-	return result1, result2, nil
 }
 
-// AggregateTriple runs three aggregators in parallel
+// AggregateTriple runs three aggregators over a stream in a single pass
 func AggregateTriple[T any, A1, A2, A3, R1, R2, R3 any](
 	stream Stream[T],
 	agg1 Aggregator[T, A1, R1],
 	agg2 Aggregator[T, A2, R2],
 	agg3 Aggregator[T, A3, R3],
 ) (R1, R2, R3, error) {
-	streams := Tee(stream, 3)
-	
-	result1, err1 := AggregateWith(streams[0], agg1)
-	result2, err2 := AggregateWith(streams[1], agg2)
-	result3, err3 := AggregateWith(streams[2], agg3)
-	
-	if err1 != nil {
-		var zero1 R1
-		var zero2 R2
-		var zero3 R3
-		return zero1, zero2, zero3, err1
-	}
-	if err2 != nil {
-		var zero1 R1
-		var zero2 R2
-		var zero3 R3
-		return zero1, zero2, zero3, err2
-	}
-	if err3 != nil {
-		var zero1 R1
-		var zero2 R2
-		var zero3 R3
-		return zero1, zero2, zero3, err3
+	acc1, acc2, acc3 := agg1.Initial(), agg2.Initial(), agg3.Initial()
+	for {
+		val, err := stream()
+		if err != nil {
+			var zero1 R1
+			var zero2 R2
+			var zero3 R3
+			if errors.Is(err, EOS) {
+				result1, err1 := agg1.finish(acc1)
+				result2, err2 := agg2.finish(acc2)
+				result3, err3 := agg3.finish(acc3)
+				if err := errors.Join(err1, err2, err3); err != nil {
+					return zero1, zero2, zero3, err
+				}
+				return result1, result2, result3, nil
+			}
+			return zero1, zero2, zero3, err
+		}
+		acc1 = agg1.Accumulate(acc1, val)
+		acc2 = agg2.Accumulate(acc2, val)
+		acc3 = agg3.Accumulate(acc3, val)
 	}
-	
-	return result1, result2, result3, nil
 }
 
 // RunAggregator runs a single aggregator on a stream and returns the result
@@ -183,7 +611,7 @@ func RunAggregator[T, A, R any](stream Stream[T], agg Aggregator[T, A, R]) (R, e
 		val, err := stream()
 		if err != nil {
 			if errors.Is(err, EOS) {
-				return agg.Finalize(acc), nil
+				return agg.finish(acc)
 			}
 			var zero R
 			return zero, err
@@ -247,6 +675,41 @@ func MaxAggregator[I any, T Comparable](extract func(I) T) Aggregator[I, *T, T]
 	}
 }
 
+// MinTimeAggregator creates an aggregator that finds the earliest time, comparing
+// chronologically whatever the time zone. Inputs extract reports false for are skipped;
+// the result is the zero time if there are none.
+func MinTimeAggregator[I any](extract func(I) (time.Time, bool)) Aggregator[I, *time.Time, time.Time] {
+	return timeAggregator(extract, func(t, current time.Time) bool { return t.Before(current) })
+}
+
+// MaxTimeAggregator creates an aggregator that finds the latest time, like MinTimeAggregator
+func MaxTimeAggregator[I any](extract func(I) (time.Time, bool)) Aggregator[I, *time.Time, time.Time] {
+	return timeAggregator(extract, func(t, current time.Time) bool { return t.After(current) })
+}
+
+// timeAggregator keeps the extracted time that replaces the current one
+func timeAggregator[I any](extract func(I) (time.Time, bool), replaces func(t, current time.Time) bool) Aggregator[I, *time.Time, time.Time] {
+	return Aggregator[I, *time.Time, time.Time]{
+		Initial: func() *time.Time { return nil },
+		Accumulate: func(acc *time.Time, input I) *time.Time {
+			t, ok := extract(input)
+			if !ok {
+				return acc
+			}
+			if acc == nil || replaces(t, *acc) {
+				return &t
+			}
+			return acc
+		},
+		Finalize: func(acc *time.Time) time.Time {
+			if acc == nil {
+				return time.Time{}
+			}
+			return *acc
+		},
+	}
+}
+
 // AvgAggregator creates an average aggregator with custom value extraction
 func AvgAggregator[I any, T Numeric](extract func(I) T) Aggregator[I, [2]float64, float64] {
 	return Aggregator[I, [2]float64, float64]{
@@ -273,6 +736,65 @@ func CountAggregator[I any]() Aggregator[I, int64, int64] {
 	}
 }
 
+// PercentileAggregator creates an exact percentile aggregator (p in [0, 100]).
+// Values are buffered and interpolated linearly between the closest ranks, so
+// memory grows with the input; use StreamingPercentile for unbounded streams.
+func PercentileAggregator[I any](extract func(I) float64, p float64) Aggregator[I, []float64, float64] {
+	if p < 0 || p > 100 {
+		panic("PercentileAggregator percentile must be between 0 and 100")
+	}
+	return Aggregator[I, []float64, float64]{
+		Initial: func() []float64 { return nil },
+		Accumulate: func(acc []float64, input I) []float64 {
+			return append(acc, extract(input))
+		},
+		Finalize: func(acc []float64) float64 {
+			if len(acc) == 0 {
+				return 0
+			}
+			sort.Float64s(acc)
+			return percentileOf(acc, p)
+		},
+	}
+}
+
+// MedianAggregator creates an exact median aggregator
+func MedianAggregator[I any](extract func(I) float64) Aggregator[I, []float64, float64] {
+	return PercentileAggregator(extract, 50)
+}
+
+// StdDevAggregator creates a population standard deviation aggregator.
+// It uses Welford's method, so it runs in constant memory.
+func StdDevAggregator[I any](extract func(I) float64) Aggregator[I, [3]float64, float64] {
+	return Aggregator[I, [3]float64, float64]{
+		Initial: func() [3]float64 { return [3]float64{0, 0, 0} }, // [count, mean, sum of squared deviations]
+		Accumulate: func(acc [3]float64, input I) [3]float64 {
+			val := extract(input)
+			count := acc[0] + 1
+			delta := val - acc[1]
+			mean := acc[1] + delta/count
+			return [3]float64{count, mean, acc[2] + delta*(val-mean)}
+		},
+		Finalize: func(acc [3]float64) float64 {
+			if acc[0] == 0 {
+				return 0
+			}
+			return math.Sqrt(acc[2] / acc[0])
+		},
+	}
+}
+
+// percentileOf interpolates the p-th percentile of sorted, non-empty values
+func percentileOf(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
 // ============================================================================
 // BUILT-IN AGGREGATORS - using the generic factories
 // ============================================================================
@@ -318,63 +840,391 @@ func MaxAggregatorField[T Comparable](fieldName string) Aggregator[Record, *T, T
 	})
 }
 
-// CountAggregatorField creates an aggregator that counts records (field name is ignored but maintained for consistency)
-func CountAggregatorField(fieldName string) Aggregator[Record, int64, int64] {
-	return CountAggregator[Record]()
+// MinTimeAggregatorField creates an aggregator that finds the earliest time in a field
+// in records. Besides time.Time values it accepts RFC3339 and SQL datetime strings, as
+// read from JSON, and int64 Unix seconds; records where the field is missing or not a
+// time are skipped.
+func MinTimeAggregatorField(fieldName string) Aggregator[Record, *time.Time, time.Time] {
+	return MinTimeAggregator(func(r Record) (time.Time, bool) { return convertToTime(r[fieldName]) })
 }
 
-// ============================================================================
-// GENERALIZED AGGREGATES FUNCTION
-// ============================================================================
-
-// AggregatorSpec represents a named aggregator specification
-type AggregatorSpec[T any] struct {
-	Name string
-	Agg  interface{} // Type-erased aggregator
+// MaxTimeAggregatorField creates an aggregator that finds the latest time in a field in
+// records, like MinTimeAggregatorField
+func MaxTimeAggregatorField(fieldName string) Aggregator[Record, *time.Time, time.Time] {
+	return MaxTimeAggregator(func(r Record) (time.Time, bool) { return convertToTime(r[fieldName]) })
 }
 
-// Aggregates runs multiple named aggregators and returns results in a Record
-func Aggregates[T any](stream Stream[T], specs ...AggregatorSpec[T]) (Record, error) {
-	if len(specs) == 0 {
-		return Record{}, nil
-	}
+// PercentileAggregatorField creates an exact percentile aggregator over a numeric field in records
+func PercentileAggregatorField(fieldName string, p float64) Aggregator[Record, []float64, float64] {
+	return PercentileAggregator[Record](func(r Record) float64 {
+		return GetOr(r, fieldName, 0.0)
+	}, p)
+}
 
-	// Split stream into multiple streams using Tee
-	streams := Tee(stream, len(specs))
-	
-	// Create result record
-	result := Record{}
-	
-	// Run each aggregator on its own stream
-	for i, spec := range specs {
-		var err error
-		var value interface{}
-		
-		// Type-assert the aggregator and run it
-		switch agg := spec.Agg.(type) {
-		case Aggregator[T, T, T]:
-			value, err = AggregateWith(streams[i], agg)
-		case Aggregator[T, int64, int64]:
-			value, err = AggregateWith(streams[i], agg)
-		case Aggregator[T, *T, T]:
-			value, err = AggregateWith(streams[i], agg)
-		case Aggregator[T, [2]float64, float64]:
-			value, err = AggregateWith(streams[i], agg)
-		default:
-			// For custom aggregators, try to use reflection or provide a generic interface
-			return result, fmt.Errorf("unsupported aggregator type for '%s'", spec.Name)
+// StdDevAggregatorField creates a standard deviation aggregator over a numeric field in records
+func StdDevAggregatorField(fieldName string) Aggregator[Record, [3]float64, float64] {
+	return StdDevAggregator[Record](func(r Record) float64 {
+		return GetOr(r, fieldName, 0.0)
+	})
+}
+
+// FirstAggregatorField creates an aggregator that keeps the first value of a field in records.
+// Records where the field is missing or nil are skipped; the result is nil if no record has it.
+func FirstAggregatorField(fieldName string) Aggregator[Record, any, any] {
+	return Aggregator[Record, any, any]{
+		Initial: func() any { return nil },
+		Accumulate: func(acc any, r Record) any {
+			if acc != nil {
+				return acc
+			}
+			return r[fieldName]
+		},
+		Finalize: func(acc any) any { return acc },
+	}
+}
+
+// LastAggregatorField creates an aggregator that keeps the last value of a field in records.
+// Records where the field is missing or nil are skipped; the result is nil if no record has it.
+func LastAggregatorField(fieldName string) Aggregator[Record, any, any] {
+	return Aggregator[Record, any, any]{
+		Initial: func() any { return nil },
+		Accumulate: func(acc any, r Record) any {
+			if val := r[fieldName]; val != nil {
+				return val
+			}
+			return acc
+		},
+		Finalize: func(acc any) any { return acc },
+	}
+}
+
+// CollectAggregatorField creates an aggregator that gathers every value of a field into a stream field.
+// Records where the field is missing or nil are skipped.
+func CollectAggregatorField(fieldName string) Aggregator[Record, []any, Stream[any]] {
+	return Aggregator[Record, []any, Stream[any]]{
+		Initial: func() []any { return nil },
+		Accumulate: func(acc []any, r Record) []any {
+			if val := r[fieldName]; val != nil {
+				return append(acc, val)
+			}
+			return acc
+		},
+		Finalize: func(acc []any) Stream[any] { return FromSliceAny(acc) },
+	}
+}
+
+// GroupRecordsAggregator creates an aggregator that keeps every record it is given, in
+// order, as a Stream[Record]. The records are held by reference, not copied.
+func GroupRecordsAggregator() Aggregator[Record, []Record, Stream[Record]] {
+	return Aggregator[Record, []Record, Stream[Record]]{
+		Initial: func() []Record { return nil },
+		Accumulate: func(acc []Record, r Record) []Record {
+			return append(acc, r)
+		},
+		Finalize: func(acc []Record) Stream[Record] { return FromSlice(acc) },
+	}
+}
+
+// CountDistinctAggregatorField creates an aggregator that counts distinct values of a field in records.
+// Values are compared by their %v formatting; missing and nil values are not counted.
+func CountDistinctAggregatorField(fieldName string) Aggregator[Record, map[string]struct{}, int64] {
+	return Aggregator[Record, map[string]struct{}, int64]{
+		Initial: func() map[string]struct{} { return make(map[string]struct{}) },
+		Accumulate: func(acc map[string]struct{}, r Record) map[string]struct{} {
+			if val := r[fieldName]; val != nil {
+				acc[fmt.Sprintf("%v", val)] = struct{}{}
+			}
+			return acc
+		},
+		Finalize: func(acc map[string]struct{}) int64 { return int64(len(acc)) },
+	}
+}
+
+// ApproxCountDistinctAggregatorField creates a HyperLogLog aggregator that estimates the number of
+// distinct values of a field in records. Memory is fixed at 2^precision bytes per group and the
+// standard error is about 1.04/sqrt(2^precision); precision must be between 4 and 16.
+func ApproxCountDistinctAggregatorField(fieldName string, precision uint8) Aggregator[Record, *HyperLogLog, int64] {
+	if precision < 4 || precision > 16 {
+		panic("ApproxCountDistinct precision must be between 4 and 16")
+	}
+	return Aggregator[Record, *HyperLogLog, int64]{
+		Initial: func() *HyperLogLog { return NewHyperLogLog(precision) },
+		Accumulate: func(acc *HyperLogLog, r Record) *HyperLogLog {
+			if val := r[fieldName]; val != nil {
+				acc.Add(fmt.Sprintf("%v", val))
+			}
+			return acc
+		},
+		Finalize: func(acc *HyperLogLog) int64 { return acc.Estimate() },
+	}
+}
+
+// HyperLogLog is a fixed-size cardinality sketch, the accumulator of
+// ApproxCountDistinctAggregatorField. It is exported for custom aggregators that
+// estimate distinct values of something other than a single field.
+//
+// Example:
+//
+//	pairs := Aggregator[Record, *HyperLogLog, int64]{
+//		Initial:    func() *HyperLogLog { return NewHyperLogLog(12) },
+//		Accumulate: func(h *HyperLogLog, r Record) *HyperLogLog { h.Add(fmt.Sprint(r["from"], "->", r["to"])); return h },
+//		Finalize:   (*HyperLogLog).Estimate,
+//	}
+type HyperLogLog struct {
+	precision uint8
+	registers []uint8
+}
+
+// NewHyperLogLog creates an empty sketch of 2^precision registers; precision must be
+// between 4 and 16
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if precision < 4 || precision > 16 {
+		panic("HyperLogLog precision must be between 4 and 16")
+	}
+	return &HyperLogLog{precision: precision, registers: make([]uint8, 1<<precision)}
+}
+
+// Add counts value in the sketch
+func (h *HyperLogLog) Add(value string) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	hash := mixHash(hasher.Sum64())
+
+	index := hash >> (64 - h.precision)
+	rank := uint8(bits.LeadingZeros64(hash<<h.precision|1<<(h.precision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Estimate returns the estimated number of distinct values added
+func (h *HyperLogLog) Estimate() int64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
 		}
-		
+	}
+
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Small-range correction: linear counting
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+// mixHash spreads FNV output across all bits (splitmix64 finalizer)
+func mixHash(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// CountAggregatorField creates an aggregator that counts records (field name is ignored but maintained for consistency)
+func CountAggregatorField(fieldName string) Aggregator[Record, int64, int64] {
+	return CountAggregator[Record]()
+}
+
+// ============================================================================
+// ONE-SHOT FIELD AGGREGATES - terminal helpers over a single Record field
+// ============================================================================
+
+// The ByField helpers skip records whose field is missing, nil, or cannot be converted
+// to the requested type (so "12.5" from a CSV counts but "n/a" does not), where the
+// field aggregators above would treat it as zero.
+
+// SumByField sums a numeric field over a record stream
+func SumByField[T Numeric](stream Stream[Record], field string) (T, error) {
+	return RunAggregator(fieldConvertibleTo[T](field)(stream), SumAggregatorField[T](field))
+}
+
+// AvgByField averages a numeric field over a record stream; 0 if no record has the field
+func AvgByField(stream Stream[Record], field string) (float64, error) {
+	return RunAggregator(fieldConvertibleTo[float64](field)(stream), AvgAggregatorField[float64](field))
+}
+
+// MinByField finds the minimum of a field over a record stream; the zero value if no
+// record has the field
+func MinByField[T Comparable](stream Stream[Record], field string) (T, error) {
+	return RunAggregator(fieldConvertibleTo[T](field)(stream), MinAggregatorField[T](field))
+}
+
+// MaxByField finds the maximum of a field over a record stream; the zero value if no
+// record has the field
+func MaxByField[T Comparable](stream Stream[Record], field string) (T, error) {
+	return RunAggregator(fieldConvertibleTo[T](field)(stream), MaxAggregatorField[T](field))
+}
+
+// CountByField counts the records in which field is present and not nil, whatever its
+// type - unlike Count, which counts every record
+func CountByField(stream Stream[Record], field string) (int64, error) {
+	present := WhereSeq(func(r Record) bool { return r[field] != nil })
+	return RunAggregator(present(stream), CountAggregatorField(field))
+}
+
+// StatsByField summarizes a numeric field in a single pass, returning a Record with
+// "count", "sum", "avg", "min" and "max" over the numeric values (as float64, with count
+// an int64) and "missing", the number of records skipped because the field was missing,
+// nil or not numeric. "min" and "max" are left out when count is 0.
+func StatsByField(stream Stream[Record], field string) (Record, error) {
+	var count, missing int64
+	var sum, lo, hi float64
+	err := ForEach(func(r Record) {
+		value, ok := Get[float64](r, field)
+		if !ok {
+			missing++
+			return
+		}
+		if count == 0 || value < lo {
+			lo = value
+		}
+		if count == 0 || value > hi {
+			hi = value
+		}
+		count++
+		sum += value
+	})(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := Record{"count": count, "sum": sum, "avg": 0.0, "missing": missing}
+	if count > 0 {
+		stats["avg"] = sum / float64(count)
+		stats["min"] = lo
+		stats["max"] = hi
+	}
+	return stats, nil
+}
+
+// fieldConvertibleTo keeps the records whose field converts to T
+func fieldConvertibleTo[T any](field string) Filter[Record, Record] {
+	return WhereSeq(func(r Record) bool {
+		_, ok := Get[T](r, field)
+		return ok
+	})
+}
+
+// ============================================================================
+// GENERALIZED AGGREGATES FUNCTION
+// ============================================================================
+
+// AggregatorSpec represents a named aggregator specification
+type AggregatorSpec[T any] struct {
+	Name string
+	Agg  interface{} // An Aggregator, or any other AggregatorRunner[T]
+}
+
+// Aggregates runs multiple named aggregators and returns results in a Record.
+// The stream is read once and each element is fed to every aggregator in turn, so
+// memory stays constant however long the stream is (beyond what the aggregators
+// themselves keep, e.g. CollectField).
+func Aggregates[T any](stream Stream[T], specs ...AggregatorSpec[T]) (Record, error) {
+	if len(specs) == 0 {
+		return Record{}, nil
+	}
+	if err := unsupportedAggregator(specs); err != nil {
+		return Record{}, err
+	}
+
+	accumulators := make([]runningAggregate[T], len(specs))
+	for i, spec := range specs {
+		accumulators[i] = startRunner(spec.Agg.(AggregatorRunner[T]))
+	}
+
+	for {
+		item, err := stream()
 		if err != nil {
-			return result, err
+			if errors.Is(err, EOS) {
+				break
+			}
+			return Record{}, err
+		}
+		for _, acc := range accumulators {
+			acc.add(item)
 		}
-		
-		result[spec.Name] = value
 	}
-	
+
+	result := make(Record, len(specs))
+	for i, spec := range specs {
+		if err := accumulators[i].err(); err != nil {
+			return Record{}, fmt.Errorf("aggregator '%s': %w", spec.Name, err)
+		}
+		result[spec.Name] = accumulators[i].result()
+	}
 	return result, nil
 }
 
+// WindowedAggregate runs the aggregators over each window of a windowed stream
+// (CountWindow, TimeWindow, EventTimeTumblingWindow, ...) and emits one Record per window.
+// Besides the aggregator results each record holds "window_index" (0-based) and
+// "window_count" (elements in the window); a spec with the same name replaces them.
+// Windows are processed one at a time. Bare windows don't carry their bounds; use
+// KeyedEventTimeTumblingWindow or the event-time *WindowResults filters when you need them.
+// Example: WindowedAggregate(SumField[int64]("total", "amount"))(CountWindow[Record](100)(records))
+func WindowedAggregate[T any](specs ...AggregatorSpec[T]) Filter[Stream[T], Record] {
+	return func(input Stream[Stream[T]]) Stream[Record] {
+		index := int64(0)
+		var finalErr error // Sticky once the windows end or an aggregator fails
+		return func() (Record, error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			window, err := input()
+			if err != nil {
+				finalErr = err
+				return nil, err
+			}
+
+			count := int64(0)
+			counted := func() (T, error) {
+				item, err := window()
+				if err == nil {
+					count++
+				}
+				return item, err
+			}
+
+			aggregated, err := Aggregates(counted, specs...)
+			if err != nil {
+				finalErr = err
+				return nil, err
+			}
+			if len(specs) == 0 {
+				for err == nil {
+					_, err = counted()
+				}
+			}
+
+			result := Record{"window_index": index, "window_count": count}
+			for name, value := range aggregated {
+				result[name] = value
+			}
+			index++
+			return result, nil
+		}
+	}
+}
+
 // Helper functions to create aggregator specs
 func SumStream[T Numeric](name string) AggregatorSpec[T] {
 	return AggregatorSpec[T]{Name: name, Agg: SumAggregator[T, T](func(val T) T { return val })}
@@ -423,6 +1273,10 @@ func CustomSpec[T, A, R any](name string, agg Aggregator[T, A, R]) AggregatorSpe
 // GroupBy groups records and applies custom aggregations to each group
 func GroupBy(keyFields []string, aggregators ...AggregatorSpec[Record]) Filter[Record, Record] {
 	return func(input Stream[Record]) Stream[Record] {
+		if err := unsupportedAggregator(aggregators); err != nil {
+			return func() (Record, error) { return nil, err }
+		}
+
 		// Collect all records
 		records, err := Collect(input)
 		if err != nil {
@@ -452,34 +1306,13 @@ func GroupBy(keyFields []string, aggregators ...AggregatorSpec[Record]) Filter[R
 			}
 
 
-			// Apply custom aggregations to this group
-			if len(aggregators) > 0 {
-				groupStream := FromSlice(groupRecords)
-				
-				// Run each aggregator directly on the group
-				for _, spec := range aggregators {
-					var value interface{}
-					var err error
-					
-					// Type-assert the specific Record aggregator types
-					switch agg := spec.Agg.(type) {
-					case Aggregator[Record, int64, int64]:
-						value, err = AggregateWith(groupStream, agg)
-						groupStream = FromSlice(groupRecords) // Reset for next aggregator
-					case Aggregator[Record, [2]float64, float64]:
-						value, err = AggregateWith(groupStream, agg)
-						groupStream = FromSlice(groupRecords) // Reset for next aggregator
-					case Aggregator[Record, *int64, int64]:
-						value, err = AggregateWith(groupStream, agg)
-						groupStream = FromSlice(groupRecords) // Reset for next aggregator
-					default:
-						err = fmt.Errorf("unsupported aggregator type for '%s'", spec.Name)
-					}
-					
-					if err == nil {
-						result[spec.Name] = value
-					}
+			// Run each aggregator directly on the group
+			for _, spec := range aggregators {
+				value, err := spec.Agg.(AggregatorRunner[Record]).RunOn(FromSlice(groupRecords))
+				if err != nil {
+					return func() (Record, error) { return nil, err }
 				}
+				result[spec.Name] = value
 			}
 
 			results = append(results, result)
@@ -489,6 +1322,507 @@ func GroupBy(keyFields []string, aggregators ...AggregatorSpec[Record]) Filter[R
 	}
 }
 
+// GroupByStreaming groups records like GroupBy but folds each record into its
+// group's accumulators as it arrives instead of collecting the input first.
+// Memory is bounded by the number of distinct groups rather than the number of
+// records, which makes it suitable for inputs too large to hold in memory.
+// Output records have the same shape as GroupBy's and are emitted at EOS in
+// order of each group's first appearance.
+func GroupByStreaming(keyFields []string, aggregators ...AggregatorSpec[Record]) Filter[Record, Record] {
+	return func(input Stream[Record]) Stream[Record] {
+		var results Stream[Record]
+		var foldErr error // Sticky so later pulls repeat the failure
+
+		return func() (Record, error) {
+			if foldErr != nil {
+				return nil, foldErr
+			}
+			if results == nil {
+				if err := unsupportedAggregator(aggregators); err != nil {
+					foldErr = err
+					return nil, err
+				}
+				records, err := foldGroups(input, keyFields, aggregators)
+				if err != nil {
+					foldErr = err
+					return nil, err
+				}
+				results = FromSlice(records)
+			}
+			return results()
+		}
+	}
+}
+
+// groupState holds the key fields and running accumulators for one group
+type groupState struct {
+	keys         Record
+	accumulators []runningAggregate[Record]
+}
+
+// newGroupState starts the group of record, taking its key fields from record
+func newGroupState(record Record, keyFields []string, aggregators []AggregatorSpec[Record]) *groupState {
+	group := &groupState{keys: groupKeys(record, keyFields)}
+	for _, spec := range aggregators {
+		group.accumulators = append(group.accumulators, startRunner(spec.Agg.(AggregatorRunner[Record])))
+	}
+	return group
+}
+
+// groupKeys returns the key fields of record that are present
+func groupKeys(record Record, keyFields []string) Record {
+	keys := make(Record, len(keyFields))
+	for _, field := range keyFields {
+		if val, ok := record[field]; ok {
+			keys[field] = val
+		}
+	}
+	return keys
+}
+
+// add folds a record into the group's accumulators
+func (group *groupState) add(record Record) {
+	for _, acc := range group.accumulators {
+		acc.add(record)
+	}
+}
+
+// err returns the first failure among the group's accumulators
+func (group *groupState) err(aggregators []AggregatorSpec[Record]) error {
+	for i, acc := range group.accumulators {
+		if err := acc.err(); err != nil {
+			return fmt.Errorf("aggregator '%s': %w", aggregators[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// result returns the group's key fields and current aggregator results
+func (group *groupState) result(aggregators []AggregatorSpec[Record]) Record {
+	result := make(Record, len(group.keys)+len(aggregators))
+	for field, val := range group.keys {
+		result[field] = val
+	}
+	for i, spec := range aggregators {
+		result[spec.Name] = group.accumulators[i].result()
+	}
+	return result
+}
+
+// foldGroups consumes input, keeping one set of accumulators per group
+func foldGroups(input Stream[Record], keyFields []string, aggregators []AggregatorSpec[Record]) ([]Record, error) {
+	groups := make(map[string]*groupState)
+	var order []*groupState
+
+	for {
+		record, err := input()
+		if err != nil {
+			if err == EOS {
+				break
+			}
+			return nil, err
+		}
+
+		key := buildGroupKey(record, keyFields)
+		group, exists := groups[key]
+		if !exists {
+			group = newGroupState(record, keyFields, aggregators)
+			groups[key] = group
+			order = append(order, group)
+		}
+		group.add(record)
+	}
+
+	results := make([]Record, 0, len(order))
+	for _, group := range order {
+		if err := group.err(aggregators); err != nil {
+			return nil, err
+		}
+		results = append(results, group.result(aggregators))
+	}
+	return results, nil
+}
+
+// AggregateEvictedField is set on every record emitted by StreamingAggregateByKey:
+// true on the final result of a key whose state was evicted, false on updates
+const AggregateEvictedField = "evicted"
+
+// StreamingAggregateOption configures StreamingAggregateByKey
+type StreamingAggregateOption func(*streamingAggregateConfig)
+
+type streamingAggregateConfig struct {
+	emitEvery int           // Records per key between updates
+	maxKeys   int           // Keys kept before the least recently updated is evicted; 0 for no limit
+	keyTTL    time.Duration // Time since a key's last update before it is evicted; 0 for never
+}
+
+// WithEmitEvery emits a key's updated result after every n of its records instead of
+// after each one
+func WithEmitEvery(n int) StreamingAggregateOption {
+	if n <= 0 {
+		panic("emit every must be positive")
+	}
+	return func(c *streamingAggregateConfig) {
+		c.emitEvery = n
+	}
+}
+
+// WithMaxKeys keeps at most n keys, evicting the least recently updated
+func WithMaxKeys(n int) StreamingAggregateOption {
+	if n <= 0 {
+		panic("max keys must be positive")
+	}
+	return func(c *streamingAggregateConfig) {
+		c.maxKeys = n
+	}
+}
+
+// WithKeyTTL evicts keys that have had no record for ttl
+func WithKeyTTL(ttl time.Duration) StreamingAggregateOption {
+	if ttl <= 0 {
+		panic("key TTL must be positive")
+	}
+	return func(c *streamingAggregateConfig) {
+		c.keyTTL = ttl
+	}
+}
+
+// keyedAggregate is the state of one key of StreamingAggregateByKey
+type keyedAggregate struct {
+	key     string
+	group   *groupState
+	pending int       // Records since the last emitted result
+	updated time.Time // Arrival of the last record
+}
+
+// StreamingAggregateByKey keeps the aggregators running per key over an unbounded
+// stream. Each time a key receives a record (or every n records with WithEmitEvery)
+// its key fields and current results are emitted. WithMaxKeys and WithKeyTTL bound
+// memory by evicting keys; an evicted key emits its final result with
+// AggregateEvictedField set, and starts fresh if it reappears. TTLs are checked in
+// processing time as records arrive. At the end of the input, keys with records not
+// yet reflected in an update emit their current result.
+//
+// Example:
+//
+//	perUser := StreamingAggregateByKey([]string{"user"},
+//	    []AggregatorSpec[Record]{CountField("clicks", "url")},
+//	    WithMaxKeys(100000), WithKeyTTL(30*time.Minute))(clicks)
+func StreamingAggregateByKey(keyFields []string, aggregators []AggregatorSpec[Record], options ...StreamingAggregateOption) Filter[Record, Record] {
+	config := streamingAggregateConfig{emitEvery: 1}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return func(input Stream[Record]) Stream[Record] {
+		keys := make(map[string]*list.Element)
+		recent := list.New() // Most recently updated at the front
+		var queue []Record   // Results waiting to be emitted, in order
+
+		emit := func(entry *keyedAggregate, evicted bool) {
+			result := entry.group.result(aggregators)
+			result[AggregateEvictedField] = evicted
+			queue = append(queue, result)
+			entry.pending = 0
+		}
+		evict := func(element *list.Element) {
+			entry := recent.Remove(element).(*keyedAggregate)
+			delete(keys, entry.key)
+			emit(entry, true)
+		}
+
+		var finalErr error // Sticky once the input has ended or the aggregators are unsupported
+		if err := unsupportedAggregator(aggregators); err != nil {
+			finalErr = err
+		}
+		return func() (Record, error) {
+			for {
+				if len(queue) > 0 {
+					result := queue[0]
+					queue = queue[1:]
+					return result, nil
+				}
+				if finalErr != nil {
+					return nil, finalErr
+				}
+
+				record, err := input()
+				if err != nil {
+					finalErr = err
+					if err == EOS {
+						// Oldest first, like evictions
+						for element := recent.Back(); element != nil; element = element.Prev() {
+							if entry := element.Value.(*keyedAggregate); entry.pending > 0 {
+								emit(entry, false)
+							}
+						}
+					}
+					continue
+				}
+
+				now := time.Now()
+				if config.keyTTL > 0 {
+					for oldest := recent.Back(); oldest != nil && now.Sub(oldest.Value.(*keyedAggregate).updated) > config.keyTTL; oldest = recent.Back() {
+						evict(oldest)
+					}
+				}
+
+				key := buildGroupKey(record, keyFields)
+				element, exists := keys[key]
+				if exists {
+					recent.MoveToFront(element)
+				} else {
+					if config.maxKeys > 0 && recent.Len() >= config.maxKeys {
+						evict(recent.Back())
+					}
+					element = recent.PushFront(&keyedAggregate{key: key, group: newGroupState(record, keyFields, aggregators)})
+					keys[key] = element
+				}
+
+				entry := element.Value.(*keyedAggregate)
+				entry.group.add(record)
+				if err := entry.group.err(aggregators); err != nil {
+					finalErr = err
+					continue
+				}
+				entry.pending++
+				entry.updated = now
+				if entry.pending >= config.emitEvery {
+					emit(entry, false)
+				}
+			}
+		}
+	}
+}
+
+// ============================================================================
+// PIVOT AND UNPIVOT - RESHAPING RECORD STREAMS
+// ============================================================================
+
+// PivotOption configures Pivot
+type PivotOption func(*pivotConfig)
+
+type pivotConfig struct {
+	prefix    string
+	hasPrefix bool
+}
+
+// WithPivotColumnPrefix names Pivot's columns prefix + value instead of
+// pivotField + "_" + value; an empty prefix names them after the values alone
+func WithPivotColumnPrefix(prefix string) PivotOption {
+	return func(c *pivotConfig) {
+		c.prefix = prefix
+		c.hasPrefix = true
+	}
+}
+
+// pivotRow is one output row of Pivot: a group's key fields and its cells by pivot value
+type pivotRow struct {
+	keys  Record
+	cells map[string]*groupState
+}
+
+// Pivot reshapes long records into wide ones: it groups by keyFields and turns each
+// distinct value of pivotField into a column holding agg over the group's records with
+// that value. Records without valueField don't contribute to a cell; agg is given the
+// records themselves, so it names the field it reads (usually valueField) and its
+// Name is unused. A group with no records for a value has no such column.
+//
+// Columns are named pivotField + "_" + value (see WithPivotColumnPrefix), with
+// characters other than letters, digits and '_' replaced by '_'. Values that aren't
+// strings are formatted (times as RFC 3339) and a missing value is "null". Names
+// are assigned in sorted order of the values; a name that collides with a key field
+// or an earlier column gets a "_2", "_3", ... suffix.
+//
+// Pivot collects its whole input before emitting, so it is for finite streams only.
+// Rows are emitted in order of each group's first appearance.
+//
+// Example:
+//
+//	// (region, month, sales) → region, month_2024_01, month_2024_02, ...
+//	wide := Pivot([]string{"region"}, "month", "sales", SumField[float64]("sales", "sales"))(sales)
+func Pivot(keyFields []string, pivotField, valueField string, agg AggregatorSpec[Record], options ...PivotOption) Filter[Record, Record] {
+	config := pivotConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+	if !config.hasPrefix {
+		config.prefix = pivotField + "_"
+	}
+	aggregators := []AggregatorSpec[Record]{agg}
+
+	return func(input Stream[Record]) Stream[Record] {
+		var results Stream[Record]
+		var finalErr error // Sticky so later pulls repeat the failure
+
+		return func() (Record, error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			if results == nil {
+				if err := unsupportedAggregator(aggregators); err != nil {
+					finalErr = err
+					return nil, err
+				}
+				records, err := pivotRecords(input, keyFields, pivotField, valueField, aggregators, config.prefix)
+				if err != nil {
+					finalErr = err
+					return nil, err
+				}
+				results = FromSlice(records)
+			}
+			return results()
+		}
+	}
+}
+
+// pivotRecords consumes input, aggregating one cell per group and pivot value
+func pivotRecords(input Stream[Record], keyFields []string, pivotField, valueField string, aggregators []AggregatorSpec[Record], prefix string) ([]Record, error) {
+	rows := make(map[string]*pivotRow)
+	var order []*pivotRow
+	values := make(map[string]bool)
+
+	for {
+		record, err := input()
+		if err != nil {
+			if err == EOS {
+				break
+			}
+			return nil, err
+		}
+
+		key := buildGroupKey(record, keyFields)
+		row, exists := rows[key]
+		if !exists {
+			row = &pivotRow{keys: groupKeys(record, keyFields), cells: make(map[string]*groupState)}
+			rows[key] = row
+			order = append(order, row)
+		}
+		if _, ok := record[valueField]; !ok {
+			continue
+		}
+
+		value := pivotValue(record[pivotField])
+		cell, exists := row.cells[value]
+		if !exists {
+			cell = newGroupState(record, nil, aggregators)
+			row.cells[value] = cell
+			values[value] = true
+		}
+		cell.add(record)
+	}
+
+	columns := pivotColumns(values, keyFields, prefix)
+	results := make([]Record, 0, len(order))
+	for _, row := range order {
+		result := make(Record, len(row.keys)+len(row.cells))
+		for field, val := range row.keys {
+			result[field] = val
+		}
+		for value, cell := range row.cells {
+			if err := cell.err(aggregators); err != nil {
+				return nil, err
+			}
+			result[columns[value]] = cell.accumulators[0].result()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// pivotValue formats a pivot field value for naming its column
+func pivotValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	}
+	s, _ := convertToString(value)
+	return s
+}
+
+// pivotColumns assigns a unique column name to each pivot value, in sorted order
+func pivotColumns(values map[string]bool, keyFields []string, prefix string) map[string]string {
+	sorted := make([]string, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Strings(sorted)
+
+	taken := make(map[string]bool, len(keyFields)+len(sorted))
+	for _, field := range keyFields {
+		taken[field] = true
+	}
+	columns := make(map[string]string, len(sorted))
+	for _, value := range sorted {
+		base := strings.Map(func(r rune) rune {
+			if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return '_'
+		}, prefix+value)
+		name := base
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		taken[name] = true
+		columns[value] = name
+	}
+	return columns
+}
+
+// Unpivot reshapes wide records into long ones, the reverse of Pivot: each field of a
+// record other than idFields becomes its own record holding the id fields, varName set
+// to the field's name and valueName set to its value. Fields are emitted in sorted
+// order. Unlike Pivot it works on infinite streams.
+//
+// Example:
+//
+//	// region, month_2024_01, month_2024_02 → (region, month, sales) rows
+//	long := Unpivot([]string{"region"}, "month", "sales")(wide)
+func Unpivot(idFields []string, varName, valueName string) Filter[Record, Record] {
+	ids := make(map[string]bool, len(idFields))
+	for _, field := range idFields {
+		ids[field] = true
+	}
+
+	return func(input Stream[Record]) Stream[Record] {
+		var pending []Record // Rows of the current record not yet emitted
+		return func() (Record, error) {
+			for len(pending) == 0 {
+				record, err := input()
+				if err != nil {
+					return nil, err
+				}
+
+				fields := make([]string, 0, len(record))
+				for field := range record {
+					if !ids[field] {
+						fields = append(fields, field)
+					}
+				}
+				sort.Strings(fields)
+				for _, field := range fields {
+					row := make(Record, len(idFields)+2)
+					for _, id := range idFields {
+						if val, ok := record[id]; ok {
+							row[id] = val
+						}
+					}
+					row[varName] = field
+					row[valueName] = record[field]
+					pending = append(pending, row)
+				}
+			}
+
+			row := pending[0]
+			pending = pending[1:]
+			return row, nil
+		}
+	}
+}
+
 // buildGroupKey creates a composite key from the specified fields
 func buildGroupKey(record Record, keyFields []string) string {
 	key := ""
@@ -509,27 +1843,94 @@ func buildGroupKey(record Record, keyFields []string) string {
 // RECORD FIELD AGGREGATIONS - FOR GROUPBY
 // ============================================================================
 
-// SumField creates an aggregator that sums a numeric field in records
-func SumField[T Numeric](name, fieldName string) AggregatorSpec[Record] {
-	return AggregatorSpec[Record]{Name: name, Agg: SumAggregatorField[T](fieldName)}
+// SumField creates an aggregator that sums a numeric field in records.
+// Like the other numeric field specs below, it takes NaNOptions; by default NaN and
+// ±Inf values propagate into the result.
+func SumField[T Numeric](name, fieldName string, options ...NaNOption) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: withNaNPolicy(SumAggregatorField[T](fieldName), fieldName, newNaNConfig(options))}
 }
 
 // AvgField creates an aggregator that averages a numeric field in records
-func AvgField[T Numeric](name, fieldName string) AggregatorSpec[Record] {
-	return AggregatorSpec[Record]{Name: name, Agg: AvgAggregatorField[T](fieldName)}
+func AvgField[T Numeric](name, fieldName string, options ...NaNOption) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: withNaNPolicy(AvgAggregatorField[T](fieldName), fieldName, newNaNConfig(options))}
 }
 
 // MinField creates an aggregator that finds the minimum of a field in records
-func MinField[T Comparable](name, fieldName string) AggregatorSpec[Record] {
-	return AggregatorSpec[Record]{Name: name, Agg: MinAggregatorField[T](fieldName)}
+func MinField[T Comparable](name, fieldName string, options ...NaNOption) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: withNaNPolicy(MinAggregatorField[T](fieldName), fieldName, newNaNConfig(options))}
 }
 
 // MaxField creates an aggregator that finds the maximum of a field in records
-func MaxField[T Comparable](name, fieldName string) AggregatorSpec[Record] {
-	return AggregatorSpec[Record]{Name: name, Agg: MaxAggregatorField[T](fieldName)}
+func MaxField[T Comparable](name, fieldName string, options ...NaNOption) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: withNaNPolicy(MaxAggregatorField[T](fieldName), fieldName, newNaNConfig(options))}
 }
 
 // CountField creates an aggregator that counts records (field name is ignored but maintained for consistency)
 func CountField(name, fieldName string) AggregatorSpec[Record] {
 	return AggregatorSpec[Record]{Name: name, Agg: CountAggregatorField(fieldName)}
-}
\ No newline at end of file
+}
+
+// MinTimeField creates an aggregator that finds the earliest time in a field in records,
+// accepting time.Time values and RFC3339 strings
+func MinTimeField(name, fieldName string) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: MinTimeAggregatorField(fieldName)}
+}
+
+// MaxTimeField creates an aggregator that finds the latest time in a field in records,
+// accepting time.Time values and RFC3339 strings
+func MaxTimeField(name, fieldName string) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: MaxTimeAggregatorField(fieldName)}
+}
+
+// PercentileField creates an aggregator that finds the p-th percentile (0-100) of a numeric field in records
+func PercentileField(name, fieldName string, p float64, options ...NaNOption) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: withNaNPolicy(PercentileAggregatorField(fieldName, p), fieldName, newNaNConfig(options))}
+}
+
+// MedianField creates an aggregator that finds the median of a numeric field in records
+func MedianField(name, fieldName string, options ...NaNOption) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: withNaNPolicy(PercentileAggregatorField(fieldName, 50), fieldName, newNaNConfig(options))}
+}
+
+// StdDevField creates an aggregator that finds the population standard deviation of a numeric field in records
+func StdDevField(name, fieldName string, options ...NaNOption) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: withNaNPolicy(StdDevAggregatorField(fieldName), fieldName, newNaNConfig(options))}
+}
+
+// FirstField creates an aggregator that keeps the first non-nil value of a field in records
+func FirstField(name, fieldName string) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: FirstAggregatorField(fieldName)}
+}
+
+// LastField creates an aggregator that keeps the last non-nil value of a field in records
+func LastField(name, fieldName string) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: LastAggregatorField(fieldName)}
+}
+
+// CollectField creates an aggregator that gathers all non-nil values of a field into a Stream[any],
+// ready for CrossFlatten or serialization as a JSON array
+func CollectField(name, fieldName string) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: CollectAggregatorField(fieldName)}
+}
+
+// WithGroupRecords attaches each group's records to its result as a Stream[Record] field,
+// gathered in the same pass as the other aggregators. The field drills down with
+// CrossFlatten and serializes as a JSON array of objects; like any stream it can be read once.
+//
+// Example:
+//
+//	GroupBy([]string{"department"}, SumField[int64]("payroll", "salary"), WithGroupRecords("members"))
+func WithGroupRecords(name string) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: GroupRecordsAggregator()}
+}
+
+// CountDistinctField creates an aggregator that counts distinct non-nil values of a field in records
+func CountDistinctField(name, fieldName string) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: CountDistinctAggregatorField(fieldName)}
+}
+
+// ApproxCountDistinctField creates an aggregator that estimates distinct values of a field in records
+// using a HyperLogLog sketch of the given precision (4-16), keeping memory bounded per group
+func ApproxCountDistinctField(name, fieldName string, precision uint8) AggregatorSpec[Record] {
+	return AggregatorSpec[Record]{Name: name, Agg: ApproxCountDistinctAggregatorField(fieldName, precision)}
+}
@@ -0,0 +1,552 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// ============================================================================
+// APACHE ARROW IPC SOURCES AND SINKS - COLUMNAR INTERCHANGE
+// ============================================================================
+
+// ArrowSource configuration for reading Arrow IPC data
+type ArrowSource struct {
+	Reader io.Reader
+
+	closer *onceCloser // The file opened by NewArrowSourceFromFile
+}
+
+// NewArrowSource creates an Arrow source from a reader of IPC stream data
+// (pyarrow's ipc.new_stream) or IPC file data (ipc.new_file, Feather v2)
+func NewArrowSource(reader io.Reader) *ArrowSource {
+	return &ArrowSource{Reader: reader}
+}
+
+// NewArrowSourceFromFile creates an Arrow source from an IPC stream or file. The file
+// is closed when the source's stream ends, or by Close.
+func NewArrowSourceFromFile(filename string) (*ArrowSource, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Arrow file %s: %w", filename, err)
+	}
+
+	source := NewArrowSource(file)
+	source.closer = &onceCloser{closer: file}
+	return source, nil
+}
+
+// Close closes the file opened by NewArrowSourceFromFile, for a consumer that stops
+// before the stream ends. It does nothing for a source made from a reader.
+func (as *ArrowSource) Close() error {
+	return as.closer.Close()
+}
+
+// ToStream converts Arrow record batches to a Record stream, one record per row.
+// Integer columns become int64 (uint64 stays uint64), floats float64, timestamps and
+// dates time.Time, struct columns nested Records and list columns stream fields typed
+// by their element type (list<int64> → Stream[int64], list<struct> → Stream[Record]).
+// Null values become nil fields.
+func (as *ArrowSource) ToStream() Stream[Record] {
+	return closeOnEnd(as.readRecords(), as.closer)
+}
+
+// readRecords decodes the rows of an ArrowSource
+func (as *ArrowSource) readRecords() Stream[Record] {
+	var reader *ipc.Reader
+	var batch arrow.RecordBatch
+	var row int
+	var finalErr error // Sticky once the data can't be read
+
+	return func() (Record, error) {
+		if finalErr != nil {
+			return nil, finalErr
+		}
+
+		if reader == nil {
+			input, err := arrowStreamReader(as.Reader)
+			if err == nil {
+				reader, err = ipc.NewReader(input, ipc.WithAllocator(memory.DefaultAllocator))
+			}
+			if err != nil {
+				finalErr = fmt.Errorf("failed to read Arrow schema: %w", err)
+				return nil, finalErr
+			}
+		}
+
+		// Move to the next non-empty batch; Next releases the previous one
+		for batch == nil || row >= int(batch.NumRows()) {
+			if !reader.Next() {
+				if err := reader.Err(); err != nil {
+					finalErr = fmt.Errorf("failed to read Arrow batch: %w", err)
+				} else {
+					finalErr = EOS
+				}
+				reader.Release()
+				return nil, finalErr
+			}
+			batch = reader.RecordBatch()
+			row = 0
+		}
+
+		record := make(Record, batch.NumCols())
+		for i, field := range batch.Schema().Fields() {
+			record[field.Name] = arrowValue(batch.Column(i), row)
+		}
+		row++
+		return record, nil
+	}
+}
+
+// arrowStreamReader skips the header of the IPC file format, whose body is an IPC
+// stream followed by a footer, so both formats can be read without seeking
+func arrowStreamReader(reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(reader)
+	header, err := buffered.Peek(8)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.HasPrefix(header, []byte("ARROW1")) {
+		if _, err := buffered.Discard(8); err != nil {
+			return nil, err
+		}
+	}
+	return buffered, nil
+}
+
+// arrowValue converts one element of an Arrow array to its Record representation
+func arrowValue(column arrow.Array, i int) any {
+	if column.IsNull(i) {
+		return nil
+	}
+
+	switch a := column.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return int64(a.Value(i))
+	case *array.Int16:
+		return int64(a.Value(i))
+	case *array.Int32:
+		return int64(a.Value(i))
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return int64(a.Value(i))
+	case *array.Uint16:
+		return int64(a.Value(i))
+	case *array.Uint32:
+		return int64(a.Value(i))
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.LargeString:
+		return a.Value(i)
+	case *array.Binary:
+		return bytes.Clone(a.Value(i))
+	case *array.LargeBinary:
+		return bytes.Clone(a.Value(i))
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return a.Value(i).ToTime(unit)
+	case *array.Date32:
+		return a.Value(i).ToTime()
+	case *array.Date64:
+		return a.Value(i).ToTime()
+	case *array.Struct:
+		structType := a.DataType().(*arrow.StructType)
+		record := make(Record, a.NumField())
+		for f, field := range structType.Fields() {
+			record[field.Name] = arrowValue(a.Field(f), i)
+		}
+		return record
+	case array.ListLike:
+		start, end := a.ValueOffsets(i)
+		return arrowListStream(a.ListValues(), int(start), int(end))
+	case *array.Dictionary:
+		return arrowValue(a.Dictionary(), a.GetValueIndex(i))
+	default:
+		return a.ValueStr(i)
+	}
+}
+
+// arrowListStream converts a slice of list values to a stream typed by the element type;
+// lists with null elements become Stream[any]
+func arrowListStream(values arrow.Array, start, end int) any {
+	items := make([]any, 0, end-start)
+	hasNull := false
+	for i := start; i < end; i++ {
+		item := arrowValue(values, i)
+		hasNull = hasNull || item == nil
+		items = append(items, item)
+	}
+	if hasNull {
+		return FromSliceAny(items)
+	}
+
+	switch values.DataType().ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64, arrow.UINT8, arrow.UINT16, arrow.UINT32:
+		return FromSliceAny(typedItems[int64](items))
+	case arrow.FLOAT32, arrow.FLOAT64:
+		return FromSliceAny(typedItems[float64](items))
+	case arrow.STRING, arrow.LARGE_STRING:
+		return FromSliceAny(typedItems[string](items))
+	case arrow.BOOL:
+		return FromSliceAny(typedItems[bool](items))
+	case arrow.TIMESTAMP, arrow.DATE32, arrow.DATE64:
+		return FromSliceAny(typedItems[time.Time](items))
+	case arrow.STRUCT:
+		return FromSliceAny(typedItems[Record](items))
+	default:
+		return FromSliceAny(items)
+	}
+}
+
+// typedItems converts items known to hold T values to a []T
+func typedItems[T any](items []any) []T {
+	typed := make([]T, len(items))
+	for i, item := range items {
+		typed[i] = item.(T)
+	}
+	return typed
+}
+
+// ArrowSink writes a Record stream as an Arrow IPC stream
+type ArrowSink struct {
+	Writer    io.Writer
+	BatchSize int
+	Schema    *arrow.Schema
+}
+
+// ArrowSinkOption configures an ArrowSink
+type ArrowSinkOption func(*ArrowSink)
+
+// WithArrowBatchSize sets how many rows go into each record batch (default 1024)
+func WithArrowBatchSize(rows int) ArrowSinkOption {
+	if rows <= 0 {
+		panic("arrow batch size must be positive")
+	}
+	return func(sink *ArrowSink) {
+		sink.BatchSize = rows
+	}
+}
+
+// WithArrowSchema sets the schema to write instead of inferring it from the first record
+func WithArrowSchema(schema *arrow.Schema) ArrowSinkOption {
+	return func(sink *ArrowSink) {
+		sink.Schema = schema
+	}
+}
+
+// NewArrowSink creates an Arrow IPC stream sink to a writer
+func NewArrowSink(writer io.Writer, options ...ArrowSinkOption) *ArrowSink {
+	sink := &ArrowSink{
+		Writer:    writer,
+		BatchSize: 1024,
+	}
+	for _, option := range options {
+		option(sink)
+	}
+	return sink
+}
+
+// NewArrowSinkToFile creates an Arrow IPC stream sink to a file
+func NewArrowSinkToFile(filename string, options ...ArrowSinkOption) (*ArrowSink, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Arrow file %s: %w", filename, err)
+	}
+
+	return NewArrowSink(file, options...), nil
+}
+
+// WriteStream writes the stream in batches of BatchSize rows, each written as soon as
+// it is full. Without a schema one is inferred from the first record: int64, float64,
+// string, bool, time.Time (microsecond UTC timestamps), []byte, nested Records (structs)
+// and stream fields (lists, typed by the stream or its first element) map to the
+// matching Arrow types, with columns in field name order. Fields missing from a record
+// are written as nulls; fields not in the schema are ignored.
+func (sink *ArrowSink) WriteStream(stream Stream[Record]) error {
+	return sink.WriteStreamContext(context.Background(), stream)
+}
+
+// WriteStreamContext writes the stream like WriteStream, stopping with ctx.Err() once
+// ctx is done. ctx is checked before every record; the rows gathered so far are written
+// as a final batch and the IPC stream is closed, so the output stays readable.
+func (sink *ArrowSink) WriteStreamContext(ctx context.Context, stream Stream[Record]) error {
+	var builder *array.RecordBuilder
+	var writer *ipc.Writer
+	rows := 0
+
+	flush := func() error {
+		batch := builder.NewRecordBatch()
+		defer batch.Release()
+		if err := writer.Write(batch); err != nil {
+			return fmt.Errorf("failed to write Arrow batch: %w", err)
+		}
+		rows = 0
+		return nil
+	}
+
+	var cancelled error // Set when ctx ends the stream; the rows so far are still written
+	for {
+		if cancelled = ctx.Err(); cancelled != nil {
+			break
+		}
+		record, err := stream()
+		if err != nil {
+			if err != EOS {
+				return err
+			}
+			break
+		}
+
+		if builder == nil {
+			schema := sink.Schema
+			if schema == nil {
+				schema, record = inferArrowSchema(record)
+			}
+			builder = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+			defer builder.Release()
+			writer = ipc.NewWriter(sink.Writer, ipc.WithSchema(schema), ipc.WithAllocator(memory.DefaultAllocator))
+		}
+
+		for i, field := range builder.Schema().Fields() {
+			if err := appendArrowValue(builder.Field(i), record[field.Name]); err != nil {
+				return fmt.Errorf("field %q: %w", field.Name, err)
+			}
+		}
+		rows++
+
+		if rows == sink.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if builder == nil {
+		if sink.Schema == nil {
+			return cancelled // Nothing to infer a schema from
+		}
+		builder = array.NewRecordBuilder(memory.DefaultAllocator, sink.Schema)
+		defer builder.Release()
+		writer = ipc.NewWriter(sink.Writer, ipc.WithSchema(sink.Schema), ipc.WithAllocator(memory.DefaultAllocator))
+	}
+	if rows > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return cancelled
+}
+
+// WriteRecords writes a slice of records
+func (sink *ArrowSink) WriteRecords(records []Record) error {
+	return sink.WriteStream(FromRecordsUnsafe(records))
+}
+
+// inferArrowSchema builds a schema from the fields of a record, sorted by name. Stream
+// fields are read to find their element type, so it also returns a copy of the record
+// with those streams replaced by their items.
+func inferArrowSchema(record Record) (*arrow.Schema, Record) {
+	fields, materialized := inferArrowFields(record)
+	return arrow.NewSchema(fields, nil), materialized
+}
+
+func inferArrowFields(record Record) ([]arrow.Field, Record) {
+	names := make([]string, 0, len(record))
+	for name := range record {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	materialized := make(Record, len(record))
+	fields := make([]arrow.Field, len(names))
+	for i, name := range names {
+		dataType, value := inferArrowType(record[name])
+		fields[i] = arrow.Field{Name: name, Type: dataType, Nullable: true}
+		materialized[name] = value
+	}
+	return fields, materialized
+}
+
+// inferArrowType maps a Record value to an Arrow type; nil and unknown values become
+// strings. It returns the value with any streams replaced by their items.
+func inferArrowType(value any) (arrow.DataType, any) {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return arrow.PrimitiveTypes.Int64, value
+	case uint, uint64:
+		return arrow.PrimitiveTypes.Uint64, value
+	case float32, float64:
+		return arrow.PrimitiveTypes.Float64, value
+	case bool:
+		return arrow.FixedWidthTypes.Boolean, value
+	case time.Time:
+		return arrow.FixedWidthTypes.Timestamp_us, value
+	case []byte:
+		return arrow.BinaryTypes.Binary, value
+	case Record:
+		fields, materialized := inferArrowFields(v)
+		return arrow.StructOf(fields...), materialized
+	case []any:
+		return inferArrowListType(reflect.TypeOf(v).Elem(), v)
+	}
+	if IsStreamType(value) {
+		items, _ := collectAnyStream(value, 0)
+		return inferArrowListType(reflect.TypeOf(value).Out(0), items)
+	}
+	return arrow.BinaryTypes.String, value
+}
+
+// inferArrowListType picks a list's element type from the Go element type, or from
+// the first non-nil item when that is an interface or Record
+func inferArrowListType(elemType reflect.Type, items []any) (arrow.DataType, any) {
+	var elem arrow.DataType = arrow.BinaryTypes.String
+	if elemType.Kind() != reflect.Interface && elemType != reflect.TypeOf(Record{}) {
+		elem, _ = inferArrowType(reflect.Zero(elemType).Interface())
+	} else {
+		for _, item := range items {
+			if item != nil {
+				elem, _ = inferArrowType(item)
+				break
+			}
+		}
+	}
+
+	materialized := make([]any, len(items))
+	for i, item := range items {
+		_, materialized[i] = inferArrowType(item)
+	}
+	return arrow.ListOf(elem), materialized
+}
+
+// appendArrowValue appends a Record value to a column builder
+func appendArrowValue(builder array.Builder, value any) error {
+	if value == nil {
+		builder.AppendNull()
+		return nil
+	}
+	fail := func() error {
+		return fmt.Errorf("cannot write %T %v as %s", value, value, builder.Type())
+	}
+
+	switch b := builder.(type) {
+	case *array.Int64Builder:
+		n, ok := convertToInt64(value)
+		if !ok {
+			return fail()
+		}
+		b.Append(n)
+	case *array.Int32Builder:
+		n, ok := convertToInt64(value)
+		if !ok {
+			return fail()
+		}
+		b.Append(int32(n))
+	case *array.Uint64Builder:
+		switch v := value.(type) {
+		case uint64:
+			b.Append(v)
+		case uint:
+			b.Append(uint64(v))
+		default:
+			n, ok := convertToInt64(value)
+			if !ok || n < 0 {
+				return fail()
+			}
+			b.Append(uint64(n))
+		}
+	case *array.Float64Builder:
+		f, ok := convertToFloat64(value)
+		if !ok {
+			return fail()
+		}
+		b.Append(f)
+	case *array.Float32Builder:
+		f, ok := convertToFloat64(value)
+		if !ok {
+			return fail()
+		}
+		b.Append(float32(f))
+	case *array.StringBuilder:
+		s, ok := convertToString(value)
+		if !ok {
+			return fail()
+		}
+		b.Append(s)
+	case *array.BooleanBuilder:
+		v, ok := convertToBool(value)
+		if !ok {
+			return fail()
+		}
+		b.Append(v)
+	case *array.TimestampBuilder:
+		t, ok := convertToTime(value)
+		if !ok {
+			return fail()
+		}
+		ts, err := arrow.TimestampFromTime(t, b.Type().(*arrow.TimestampType).Unit)
+		if err != nil {
+			return err
+		}
+		b.Append(ts)
+	case *array.BinaryBuilder:
+		switch v := value.(type) {
+		case []byte:
+			b.Append(v)
+		case string:
+			b.AppendString(v)
+		default:
+			return fail()
+		}
+	case *array.StructBuilder:
+		record, ok := value.(Record)
+		if !ok {
+			return fail()
+		}
+		b.Append(true)
+		for i, field := range b.Type().(*arrow.StructType).Fields() {
+			if err := appendArrowValue(b.FieldBuilder(i), record[field.Name]); err != nil {
+				return fmt.Errorf("%s: %w", field.Name, err)
+			}
+		}
+	case *array.ListBuilder:
+		items, ok := value.([]any)
+		if !ok {
+			if !IsStreamType(value) {
+				return fail()
+			}
+			items, _ = collectAnyStream(value, 0)
+		}
+		b.Append(true)
+		for _, item := range items {
+			if err := appendArrowValue(b.ValueBuilder(), item); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported Arrow type %s", builder.Type())
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// TestArrow tests Arrow IPC sources and sinks
+func TestArrow(t *testing.T) {
+	created := time.Date(2024, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	records := []Record{
+		{"id": int64(1), "name": "Alice", "score": 9.5, "created": created, "readings": FromSlice([]int64{1, 2, 3}), "address": Record{"city": "NYC"}},
+		{"id": int64(2), "name": "Bob", "score": 7.0, "created": created.Add(time.Hour), "readings": FromSlice([]int64{}), "address": Record{"city": "LA"}},
+		{"id": int64(3), "created": created.Add(2 * time.Hour), "readings": FromSlice([]int64{4})},
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := NewArrowSink(&buffer, WithArrowBatchSize(2)).WriteRecords(records); err != nil {
+			t.Fatalf("Failed to write Arrow: %v", err)
+		}
+
+		results, err := Collect(NewArrowSource(&buffer).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read Arrow: %v", err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 records, got %d", len(results))
+		}
+
+		first := results[0]
+		if first["id"] != int64(1) || first["name"] != "Alice" || first["score"] != 9.5 {
+			t.Errorf("Expected scalar fields back, got %v", first)
+		}
+		if ts, ok := Get[time.Time](first, "created"); !ok || !ts.Equal(created) {
+			t.Errorf("Expected created %v, got %v", created, first["created"])
+		}
+		readings, ok := Get[Stream[int64]](first, "readings")
+		if !ok {
+			t.Fatalf("Expected readings as Stream[int64], got %T", first["readings"])
+		}
+		if values, _ := Collect(readings); len(values) != 3 || values[2] != 3 {
+			t.Errorf("Expected readings [1 2 3], got %v", values)
+		}
+		if address, ok := first["address"].(Record); !ok || address["city"] != "NYC" {
+			t.Errorf("Expected nested address, got %v", first["address"])
+		}
+
+		last := results[2]
+		if last["name"] != nil || last["address"] != nil {
+			t.Errorf("Expected missing fields read back as nulls, got %v", last)
+		}
+	})
+
+	t.Run("IncrementalBatches", func(t *testing.T) {
+		var buffer bytes.Buffer
+		written := 0
+		source := func() (Record, error) {
+			if written == 5 {
+				return nil, EOS
+			}
+			// Two full batches of 2 must already be written when the fifth record is pulled
+			if written == 4 && buffer.Len() == 0 {
+				t.Error("Expected complete batches to be written before the stream ends")
+			}
+			written++
+			return Record{"n": int64(written)}, nil
+		}
+		if err := NewArrowSink(&buffer, WithArrowBatchSize(2)).WriteStream(source); err != nil {
+			t.Fatalf("Failed to write Arrow: %v", err)
+		}
+
+		reader, err := ipc.NewReader(&buffer)
+		if err != nil {
+			t.Fatalf("Failed to open IPC stream: %v", err)
+		}
+		defer reader.Release()
+		var batchRows []int64
+		for reader.Next() {
+			batchRows = append(batchRows, reader.RecordBatch().NumRows())
+		}
+		if len(batchRows) != 3 || batchRows[0] != 2 || batchRows[2] != 1 {
+			t.Errorf("Expected batches of 2, 2 and 1 rows, got %v", batchRows)
+		}
+	})
+
+	t.Run("ExplicitSchema", func(t *testing.T) {
+		schema := arrow.NewSchema([]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "at", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}, Nullable: true},
+		}, nil)
+
+		var buffer bytes.Buffer
+		input := []Record{{"id": "7", "at": "2024-01-02T03:04:05Z", "ignored": true}}
+		if err := NewArrowSink(&buffer, WithArrowSchema(schema)).WriteRecords(input); err != nil {
+			t.Fatalf("Failed to write Arrow: %v", err)
+		}
+
+		results, err := Collect(NewArrowSource(&buffer).ToStream())
+		if err != nil || len(results) != 1 {
+			t.Fatalf("Expected 1 record, got %v (%v)", results, err)
+		}
+		if results[0]["id"] != int64(7) || len(results[0]) != 2 {
+			t.Errorf("Expected id 7 and only schema fields, got %v", results[0])
+		}
+		if at, _ := Get[time.Time](results[0], "at"); at.Year() != 2024 {
+			t.Errorf("Expected timestamp converted from string, got %v", results[0]["at"])
+		}
+
+		err = NewArrowSink(&bytes.Buffer{}, WithArrowSchema(schema)).WriteRecords([]Record{{"id": "x"}})
+		if err == nil || !strings.Contains(err.Error(), `"id"`) {
+			t.Errorf("Expected conversion error naming id, got %v", err)
+		}
+	})
+
+	t.Run("FileFormat", func(t *testing.T) {
+		schema := arrow.NewSchema([]arrow.Field{
+			{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+		}, nil)
+		builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+		defer builder.Release()
+		tags := builder.Field(0).(*array.ListBuilder)
+		tags.Append(true)
+		tags.ValueBuilder().(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+		batch := builder.NewRecordBatch()
+		defer batch.Release()
+
+		var buffer bytes.Buffer
+		writer, err := ipc.NewFileWriter(&buffer, ipc.WithSchema(schema))
+		if err != nil {
+			t.Fatalf("Failed to create file writer: %v", err)
+		}
+		if err := writer.Write(batch); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+		writer.Close()
+
+		results, err := Collect(NewArrowSource(&buffer).ToStream())
+		if err != nil || len(results) != 1 {
+			t.Fatalf("Expected 1 record from the IPC file format, got %v (%v)", results, err)
+		}
+		values, err := Collect(results[0]["tags"].(Stream[string]))
+		if err != nil || len(values) != 2 || values[1] != "b" {
+			t.Errorf("Expected tags [a b], got %v (%v)", values, err)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package stream
 import (
 	"math"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -211,6 +212,138 @@ func BenchmarkAutoParallel(b *testing.B) {
 	})
 }
 
+// TestHints verifies per-call Hint control over Map/Where parallelization
+func TestHints(t *testing.T) {
+	// concurrency wraps fn to record the peak number of concurrent calls
+	concurrency := func(peak *atomic.Int32) func(int64) int64 {
+		var active atomic.Int32
+		return func(x int64) int64 {
+			n := active.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			active.Add(-1)
+			return x
+		}
+	}
+	inOrder := func(t *testing.T, result []int64, n int) {
+		t.Helper()
+		if len(result) != n {
+			t.Fatalf("Expected %d results, got %d", n, len(result))
+		}
+		for i, x := range result {
+			if x != int64(i) {
+				t.Fatalf("Expected input order, got %d at %d", x, i)
+			}
+		}
+	}
+
+	t.Run("IOBoundParallelizes", func(t *testing.T) {
+		var peak atomic.Int32
+		result, err := Collect(Map(concurrency(&peak), IOBound)(Range(0, 40, 1)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		inOrder(t, result, 40)
+		if peak.Load() < 2 {
+			t.Errorf("Expected concurrent execution, peak was %d", peak.Load())
+		}
+	})
+
+	t.Run("CPUHeavyUsesEveryCPU", func(t *testing.T) {
+		var peak atomic.Int32
+		result, err := Collect(Map(concurrency(&peak), CPUHeavy)(Range(0, 40, 1)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		inOrder(t, result, 40)
+		if int(peak.Load()) > runtime.GOMAXPROCS(0) {
+			t.Errorf("Expected at most %d concurrent calls, peak was %d", runtime.GOMAXPROCS(0), peak.Load())
+		}
+	})
+
+	t.Run("CheapOverridesAdaptive", func(t *testing.T) {
+		previous := SetExecutionPolicy(Adaptive)
+		defer SetExecutionPolicy(previous)
+
+		var peak atomic.Int32
+		result, _ := Collect(Map(concurrency(&peak), CPUHeavy, Cheap)(Range(0, 10, 1)))
+		inOrder(t, result, 10)
+		if peak.Load() != 1 {
+			t.Errorf("Expected the last hint, Cheap, to keep Map sequential, peak was %d", peak.Load())
+		}
+	})
+
+	t.Run("UnhintedFollowsPolicy", func(t *testing.T) {
+		var peak atomic.Int32
+		result, _ := Collect(Map(concurrency(&peak))(Range(0, 10, 1)))
+		inOrder(t, result, 10)
+		if peak.Load() != 1 {
+			t.Errorf("Expected sequential execution without a hint, peak was %d", peak.Load())
+		}
+	})
+
+	t.Run("WhereHints", func(t *testing.T) {
+		var peak atomic.Int32
+		fn := concurrency(&peak)
+		kept, err := Collect(Where(func(x int64) bool { return fn(x)%3 == 0 }, IOBound)(Range(0, 30, 1)))
+		if err != nil || len(kept) != 10 {
+			t.Fatalf("Expected 10 results, got %d (%v)", len(kept), err)
+		}
+		for i, x := range kept {
+			if x != int64(i*3) {
+				t.Fatalf("Expected input order, got %d at %d", x, i)
+			}
+		}
+		if peak.Load() < 2 {
+			t.Errorf("Expected concurrent execution, peak was %d", peak.Load())
+		}
+	})
+}
+
+// BenchmarkHints compares hinted and unhinted Map for a cheap and a CPU-heavy function
+func BenchmarkHints(b *testing.B) {
+	data := make([]float64, 10000)
+	for i := range data {
+		data[i] = float64(i) * 0.001
+	}
+	cheap := func(x float64) float64 { return x + 1 }
+	heavy := func(x float64) float64 {
+		for i := 0; i < 200; i++ {
+			x = math.Sin(x) * math.Cos(x) * math.Sqrt(x+1)
+		}
+		return x
+	}
+
+	b.Run("CheapUnhinted", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Collect(Map(cheap)(FromSlice(data)))
+		}
+	})
+
+	b.Run("CheapAutoParallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Collect(Map(cheap, AutoParallel)(FromSlice(data)))
+		}
+	})
+
+	b.Run("HeavyUnhinted", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Collect(Map(heavy)(FromSlice(data)))
+		}
+	})
+
+	b.Run("HeavyCPUHeavy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Collect(Map(heavy, CPUHeavy)(FromSlice(data)))
+		}
+	})
+}
+
 // TestWorkerCalculation verifies optimal worker calculation
 func TestWorkerCalculation(t *testing.T) {
 	testCases := []struct {
@@ -230,4 +363,101 @@ func TestWorkerCalculation(t *testing.T) {
 			t.Errorf("For complexity %d, expected %d workers, got %d", tc.complexity, tc.expected, workers)
 		}
 	}
-}
\ No newline at end of file
+}
+// TestExecutionPolicy verifies explicit control over Map/Where parallelization
+func TestExecutionPolicy(t *testing.T) {
+	t.Run("DefaultIsSequential", func(t *testing.T) {
+		if policy := CurrentExecutionPolicy(); policy != Sequential {
+			t.Errorf("Expected Sequential by default, got %v", policy)
+		}
+	})
+
+	t.Run("MapSeqWithNonThreadSafeCounter", func(t *testing.T) {
+		counts := map[int64]int{} // Plain map: racy if fn ran concurrently
+		result, err := Collect(MapSeq(func(x int64) int64 {
+			counts[x%10]++
+			return x
+		})(Range(0, 10_000, 1)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(result) != 10_000 || counts[3] != 1_000 {
+			t.Errorf("Expected 10000 results and 1000 per bucket, got %d and %d", len(result), counts[3])
+		}
+	})
+
+	t.Run("PolicyOverride", func(t *testing.T) {
+		previous := SetExecutionPolicy(Adaptive)
+		defer SetExecutionPolicy(previous)
+		if CurrentExecutionPolicy() != Adaptive {
+			t.Fatal("Expected Adaptive after SetExecutionPolicy")
+		}
+
+		SetExecutionPolicy(Sequential)
+		calls := 0 // Not synchronized: only safe if Map stays on this goroutine
+		mapped := Map(func(x int64) int64 {
+			calls++
+			return x
+		})(Range(0, 100, 1))
+
+		if _, err := mapped(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if calls != 1 {
+			t.Errorf("Expected fn to run once per pull, got %d calls", calls)
+		}
+
+		kept, err := Collect(Where(func(x int64) bool {
+			calls++
+			return x%2 == 0
+		})(Range(0, 100, 1)))
+		if err != nil || len(kept) != 50 {
+			t.Errorf("Expected 50 even values, got %d (%v)", len(kept), err)
+		}
+	})
+
+	t.Run("MapParRunsConcurrently", func(t *testing.T) {
+		var active, peak atomic.Int32
+		result, err := Collect(MapPar(4, func(x int64) int64 {
+			n := active.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			active.Add(-1)
+			return x
+		})(Range(0, 40, 1)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if peak.Load() < 2 {
+			t.Errorf("Expected concurrent execution, peak was %d", peak.Load())
+		}
+		for i, x := range result {
+			if x != int64(i) {
+				t.Fatalf("Expected input order, got %d at %d", x, i)
+			}
+		}
+	})
+
+	t.Run("WhereParMatchesWhereSeq", func(t *testing.T) {
+		isOdd := func(x int64) bool { return x%2 == 1 }
+		sequential, _ := Collect(WhereSeq(isOdd)(Range(0, 1000, 1)))
+		parallel, err := Collect(WherePar(4, isOdd)(Range(0, 1000, 1)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(parallel) != len(sequential) {
+			t.Fatalf("Expected %d results, got %d", len(sequential), len(parallel))
+		}
+		for i := range sequential {
+			if parallel[i] != sequential[i] {
+				t.Fatalf("Expected %d at %d, got %d", sequential[i], i, parallel[i])
+			}
+		}
+	})
+}
@@ -0,0 +1,665 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// COLUMNAR BATCHES - TYPED COLUMNS FOR NUMERIC-HEAVY PIPELINES
+// ============================================================================
+
+// RecordBatch holds up to a batch size of records column by column, so numeric
+// operations can run over typed slices instead of looking fields up record by record.
+// Build batches with BatchRecords or FastTSVSource.ToBatches and turn them back into
+// records with UnbatchRecords.
+type RecordBatch struct {
+	Len     int                // Number of rows
+	Columns map[string]*Column // One column per field present in any row
+}
+
+// Column holds one field of a RecordBatch. Exactly one of Int64s, Float64s, Strings,
+// Bools and Values is set, with one element per row: the typed slice when every value
+// has that type, Float64s when int64 and float64 values are mixed, and Values for any
+// other mix. Valid[i] is false where row i had no value - the field was missing or nil -
+// and the element there is the zero value.
+type Column struct {
+	Int64s   []int64
+	Float64s []float64
+	Strings  []string
+	Bools    []bool
+	Values   []any
+	Valid    []bool
+}
+
+// Value returns row i of the column, or nil if the row has no value
+func (c *Column) Value(i int) any {
+	if !c.Valid[i] {
+		return nil
+	}
+	switch {
+	case c.Int64s != nil:
+		return c.Int64s[i]
+	case c.Float64s != nil:
+		return c.Float64s[i]
+	case c.Strings != nil:
+		return c.Strings[i]
+	case c.Bools != nil:
+		return c.Bools[i]
+	default:
+		return c.Values[i]
+	}
+}
+
+// values returns the column's populated slice
+func (c *Column) values() any {
+	switch {
+	case c.Int64s != nil:
+		return c.Int64s
+	case c.Float64s != nil:
+		return c.Float64s
+	case c.Strings != nil:
+		return c.Strings
+	case c.Bools != nil:
+		return c.Bools
+	default:
+		return c.Values
+	}
+}
+
+// Record returns row i of the batch as a Record, leaving out fields it has no value for
+func (b RecordBatch) Record(i int) Record {
+	record := AcquireRecord(len(b.Columns))
+	for field, column := range b.Columns {
+		if value := column.Value(i); value != nil {
+			record[field] = value
+		}
+	}
+	return record
+}
+
+// BatchRecords groups records into RecordBatches of size rows, the last possibly
+// shorter. Nil fields are treated as missing, so they don't come back from UnbatchRecords;
+// an int64 field in a column that also holds float64 values comes back as a float64.
+//
+// Example:
+//
+//	total, err := SumByFieldBatch[float64](BatchRecords(4096)(sales), "amount")
+func BatchRecords(size int) Filter[Record, RecordBatch] {
+	if size <= 0 {
+		panic("batch size must be positive")
+	}
+	return func(input Stream[Record]) Stream[RecordBatch] {
+		var finalErr error
+		return func() (RecordBatch, error) {
+			if finalErr != nil {
+				return RecordBatch{}, finalErr
+			}
+			builders := make(map[string]*columnBuilder)
+			rows := 0
+			for rows < size {
+				record, err := input()
+				if err != nil {
+					finalErr = err
+					break
+				}
+				for field, value := range record {
+					builder, exists := builders[field]
+					if !exists {
+						builder = newColumnBuilder(size)
+						builders[field] = builder
+					}
+					builder.pad(rows)
+					builder.add(value)
+				}
+				rows++
+			}
+			if rows == 0 {
+				return RecordBatch{}, finalErr
+			}
+			return finishBatch(builders, rows), nil
+		}
+	}
+}
+
+// UnbatchRecords turns RecordBatches back into one Record per row
+func UnbatchRecords() Filter[RecordBatch, Record] {
+	return func(input Stream[RecordBatch]) Stream[Record] {
+		var batch RecordBatch
+		row := 0
+		return func() (Record, error) {
+			for row >= batch.Len {
+				next, err := input()
+				if err != nil {
+					return nil, err
+				}
+				batch, row = next, 0
+			}
+			record := batch.Record(row)
+			row++
+			return record, nil
+		}
+	}
+}
+
+// SumByFieldBatch sums a numeric field over batches like SumByField over records,
+// skipping rows whose field is missing or doesn't convert to T
+func SumByFieldBatch[T Numeric](stream Stream[RecordBatch], field string) (T, error) {
+	var sum T
+	err := ForEach(func(batch RecordBatch) {
+		columnSum, _ := sumColumn[T](batch.Columns[field])
+		sum += columnSum
+	})(stream)
+	return sum, err
+}
+
+// AvgByFieldBatch averages a numeric field over batches like AvgByField over records;
+// 0 if no row has the field
+func AvgByFieldBatch(stream Stream[RecordBatch], field string) (float64, error) {
+	var sum float64
+	var count int64
+	err := ForEach(func(batch RecordBatch) {
+		columnSum, columnCount := sumColumn[float64](batch.Columns[field])
+		sum += columnSum
+		count += columnCount
+	})(stream)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	return sum / float64(count), nil
+}
+
+// sumColumn sums the values of c that convert to T, and counts them
+func sumColumn[T Numeric](c *Column) (sum T, count int64) {
+	if c == nil {
+		return 0, 0
+	}
+	switch {
+	case c.Float64s != nil:
+		for i, v := range c.Float64s {
+			if c.Valid[i] {
+				sum += T(v)
+				count++
+			}
+		}
+	case c.Int64s != nil:
+		for i, v := range c.Int64s {
+			if c.Valid[i] {
+				sum += T(v)
+				count++
+			}
+		}
+	default:
+		read := columnReader[T](c)
+		for i := range c.Valid {
+			if v, ok := read(i); ok {
+				sum += v
+				count++
+			}
+		}
+	}
+	return sum, count
+}
+
+// WhereBatch keeps the rows whose field converts to T and satisfies predicate, like
+// Where with Get[T] over records. Batches left empty are dropped.
+//
+// Example:
+//
+//	large := WhereBatch("amount", func(amount float64) bool { return amount > 1000 })
+func WhereBatch[T any](field string, predicate func(T) bool) Filter[RecordBatch, RecordBatch] {
+	return func(input Stream[RecordBatch]) Stream[RecordBatch] {
+		var keep []int
+		return func() (RecordBatch, error) {
+			for {
+				batch, err := input()
+				if err != nil {
+					return RecordBatch{}, err
+				}
+				read := columnReader[T](batch.Columns[field])
+				keep = keep[:0]
+				for i := 0; i < batch.Len; i++ {
+					if v, ok := read(i); ok && predicate(v) {
+						keep = append(keep, i)
+					}
+				}
+				switch len(keep) {
+				case 0:
+					continue
+				case batch.Len:
+					return batch, nil
+				}
+				return batch.take(keep), nil
+			}
+		}
+	}
+}
+
+// columnReader returns a function reading row i of c as T, converting as Get does
+func columnReader[T any](c *Column) func(i int) (T, bool) {
+	var zero T
+	if c == nil {
+		return func(int) (T, bool) { return zero, false }
+	}
+	if values, ok := c.values().([]T); ok {
+		return func(i int) (T, bool) { return values[i], c.Valid[i] }
+	}
+	switch any(zero).(type) {
+	case float64:
+		if ints := c.Int64s; ints != nil {
+			return any(func(i int) (float64, bool) { return float64(ints[i]), c.Valid[i] }).(func(int) (T, bool))
+		}
+	case int64:
+		if floats := c.Float64s; floats != nil {
+			return any(func(i int) (int64, bool) { return int64(floats[i]), c.Valid[i] }).(func(int) (T, bool))
+		}
+	}
+	return func(i int) (T, bool) {
+		value := c.Value(i)
+		if value == nil {
+			return zero, false
+		}
+		if typed, ok := value.(T); ok {
+			return typed, true
+		}
+		return convertTo[T](value)
+	}
+}
+
+// take returns a batch of the given rows
+func (b RecordBatch) take(rows []int) RecordBatch {
+	columns := make(map[string]*Column, len(b.Columns))
+	for field, column := range b.Columns {
+		columns[field] = column.take(rows)
+	}
+	return RecordBatch{Len: len(rows), Columns: columns}
+}
+
+// take returns a column of the given rows
+func (c *Column) take(rows []int) *Column {
+	result := &Column{Valid: takeRows(c.Valid, rows)}
+	switch {
+	case c.Int64s != nil:
+		result.Int64s = takeRows(c.Int64s, rows)
+	case c.Float64s != nil:
+		result.Float64s = takeRows(c.Float64s, rows)
+	case c.Strings != nil:
+		result.Strings = takeRows(c.Strings, rows)
+	case c.Bools != nil:
+		result.Bools = takeRows(c.Bools, rows)
+	default:
+		result.Values = takeRows(c.Values, rows)
+	}
+	return result
+}
+
+func takeRows[T any](values []T, rows []int) []T {
+	result := make([]T, len(rows))
+	for i, row := range rows {
+		result[i] = values[row]
+	}
+	return result
+}
+
+// ============================================================================
+// COLUMN BUILDING
+// ============================================================================
+
+// columnKind is the type of the values a columnBuilder has seen so far
+type columnKind int
+
+const (
+	kindNone columnKind = iota // Only rows without a value so far
+	kindInt64
+	kindFloat64
+	kindString
+	kindBool
+	kindMixed
+)
+
+// columnBuilder appends values to a Column, switching to a wider representation when
+// a value doesn't fit the current one
+type columnBuilder struct {
+	column   Column
+	kind     columnKind
+	capacity int
+}
+
+func newColumnBuilder(capacity int) *columnBuilder {
+	return &columnBuilder{column: Column{Valid: make([]bool, 0, capacity)}, capacity: capacity}
+}
+
+// pad adds rows without a value until the column has rows rows
+func (b *columnBuilder) pad(rows int) {
+	for len(b.column.Valid) < rows {
+		b.addNull()
+	}
+}
+
+// seek makes the next value land in row, padding the rows before it or dropping a
+// value already added for it
+func (b *columnBuilder) seek(row int) {
+	c := &b.column
+	if len(c.Valid) > row {
+		c.Valid = c.Valid[:row]
+		switch {
+		case c.Int64s != nil:
+			c.Int64s = c.Int64s[:row]
+		case c.Float64s != nil:
+			c.Float64s = c.Float64s[:row]
+		case c.Strings != nil:
+			c.Strings = c.Strings[:row]
+		case c.Bools != nil:
+			c.Bools = c.Bools[:row]
+		case c.Values != nil:
+			c.Values = c.Values[:row]
+		}
+	}
+	b.pad(row)
+}
+
+// add appends a value of any type; nil appends a row without a value
+func (b *columnBuilder) add(value any) {
+	switch v := value.(type) {
+	case nil:
+		b.addNull()
+	case int64:
+		b.addInt64(v)
+	case float64:
+		b.addFloat64(v)
+	case string:
+		b.addString(v)
+	case bool:
+		b.addBool(v)
+	default:
+		b.addMixed(value)
+	}
+}
+
+// addParsed appends a delimited field, typed as FastTSVSource types it
+func (b *columnBuilder) addParsed(value string) {
+	if value == "" {
+		b.addString("")
+		return
+	}
+	if isDecimalInteger(value) {
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			b.addInt64(v)
+			return
+		}
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		b.addFloat64(v)
+		return
+	}
+	if v, err := strconv.ParseBool(value); err == nil {
+		b.addBool(v)
+		return
+	}
+	b.addString(value)
+}
+
+// isDecimalInteger reports whether value could parse with strconv.ParseInt in base 10,
+// sparing decimals the failed attempt and the error it allocates
+func isDecimalInteger(value string) bool {
+	if value[0] == '+' || value[0] == '-' {
+		value = value[1:]
+	}
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *columnBuilder) addNull() {
+	c := &b.column
+	c.Valid = append(c.Valid, false)
+	switch b.kind {
+	case kindInt64:
+		c.Int64s = append(c.Int64s, 0)
+	case kindFloat64:
+		c.Float64s = append(c.Float64s, 0)
+	case kindString:
+		c.Strings = append(c.Strings, "")
+	case kindBool:
+		c.Bools = append(c.Bools, false)
+	case kindMixed:
+		c.Values = append(c.Values, nil)
+	}
+}
+
+func (b *columnBuilder) addInt64(v int64) {
+	switch b.start(kindInt64) {
+	case kindInt64:
+		b.column.Int64s = append(b.column.Int64s, v)
+	case kindFloat64:
+		b.column.Float64s = append(b.column.Float64s, float64(v))
+	default:
+		b.column.Values = append(b.column.Values, v)
+	}
+	b.column.Valid = append(b.column.Valid, true)
+}
+
+func (b *columnBuilder) addFloat64(v float64) {
+	if b.kind == kindInt64 {
+		// Widen the integers seen so far, so numeric columns stay typed
+		b.column.Float64s = make([]float64, len(b.column.Int64s), b.capacity)
+		for i, n := range b.column.Int64s {
+			b.column.Float64s[i] = float64(n)
+		}
+		b.column.Int64s = nil
+		b.kind = kindFloat64
+	}
+	if b.start(kindFloat64) == kindFloat64 {
+		b.column.Float64s = append(b.column.Float64s, v)
+	} else {
+		b.column.Values = append(b.column.Values, v)
+	}
+	b.column.Valid = append(b.column.Valid, true)
+}
+
+func (b *columnBuilder) addString(v string) {
+	if b.start(kindString) == kindString {
+		b.column.Strings = append(b.column.Strings, v)
+	} else {
+		b.column.Values = append(b.column.Values, v)
+	}
+	b.column.Valid = append(b.column.Valid, true)
+}
+
+func (b *columnBuilder) addBool(v bool) {
+	if b.start(kindBool) == kindBool {
+		b.column.Bools = append(b.column.Bools, v)
+	} else {
+		b.column.Values = append(b.column.Values, v)
+	}
+	b.column.Valid = append(b.column.Valid, true)
+}
+
+func (b *columnBuilder) addMixed(v any) {
+	b.toMixed()
+	b.column.Values = append(b.column.Values, v)
+	b.column.Valid = append(b.column.Valid, true)
+}
+
+// start makes sure the column can take a value of kind, returning the kind to append as:
+// kind itself, kindFloat64 for an int64 in a float column, or kindMixed
+func (b *columnBuilder) start(kind columnKind) columnKind {
+	switch b.kind {
+	case kind:
+		return kind
+	case kindNone:
+		// Rows so far had no value; give them zero values of the new kind
+		b.kind = kind
+		rows := len(b.column.Valid)
+		switch kind {
+		case kindInt64:
+			b.column.Int64s = make([]int64, rows, b.capacity)
+		case kindFloat64:
+			b.column.Float64s = make([]float64, rows, b.capacity)
+		case kindString:
+			b.column.Strings = make([]string, rows, b.capacity)
+		case kindBool:
+			b.column.Bools = make([]bool, rows, b.capacity)
+		}
+		return kind
+	case kindFloat64:
+		if kind == kindInt64 {
+			return kindFloat64
+		}
+	}
+	b.toMixed()
+	return kindMixed
+}
+
+// toMixed moves the values so far into Values
+func (b *columnBuilder) toMixed() {
+	if b.kind == kindMixed {
+		return
+	}
+	c := &b.column
+	values := make([]any, len(c.Valid), b.capacity)
+	for i := range c.Valid {
+		values[i] = c.Value(i)
+	}
+	*c = Column{Values: values, Valid: c.Valid}
+	b.kind = kindMixed
+}
+
+// finish returns the column padded to rows rows
+func (b *columnBuilder) finish(rows int) *Column {
+	b.pad(rows)
+	if b.kind == kindNone {
+		b.column.Values = make([]any, rows) // No row had a value
+	}
+	return &b.column
+}
+
+// finishBatch builds a batch from its column builders
+func finishBatch(builders map[string]*columnBuilder, rows int) RecordBatch {
+	columns := make(map[string]*Column, len(builders))
+	for field, builder := range builders {
+		columns[field] = builder.finish(rows)
+	}
+	return RecordBatch{Len: rows, Columns: columns}
+}
+
+// ============================================================================
+// BATCHED DELIMITED INPUT
+// ============================================================================
+
+// ToBatches reads the source straight into RecordBatches of size rows, typing values as
+// ToStream does but without building a Record per row. Only the named fields are kept
+// (those of WithProjection, or all, when none are given), so unused columns are never parsed.
+//
+// Example:
+//
+//	batches := NewFastTSVSourceWithSeparator(file, ",").ToBatches(8192, "amount")
+//	total, err := SumByFieldBatch[float64](batches, "amount")
+func (s *FastTSVSource) ToBatches(size int, fields ...string) Stream[RecordBatch] {
+	if size <= 0 {
+		panic("batch size must be positive")
+	}
+	scanner := bufio.NewScanner(s.Reader)
+	if len(fields) == 0 {
+		fields = s.Projection
+	}
+	var wanted map[string]bool
+	if len(fields) > 0 {
+		wanted = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			wanted[field] = true
+		}
+	}
+
+	separator := []byte(s.Separator)
+	var headers []string
+	lineNumber := 0
+	var finalErr error
+
+	return func() (RecordBatch, error) {
+		if finalErr != nil {
+			return RecordBatch{}, finalErr
+		}
+		builders := make(map[string]*columnBuilder)
+		var columns []*columnBuilder // By position in the line; nil for fields not kept
+		var parsers []func(string) any // By position; nil for values typed by addParsed
+		rows := 0
+
+		for rows < size && scanner.Scan() {
+			lineNumber++
+			line := scanner.Bytes()
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if lineNumber == 1 && s.HasHeader {
+				headers = strings.Split(string(line), s.Separator)
+				for i, header := range headers {
+					headers[i] = strings.TrimSpace(header)
+				}
+				continue
+			}
+			if len(s.Headers) > 0 {
+				headers = s.Headers
+			}
+			if len(headers) == 0 {
+				headers = make([]string, bytes.Count(line, separator)+1)
+				for i := range headers {
+					headers[i] = fmt.Sprintf("field_%d", i)
+				}
+			}
+			if columns == nil {
+				columns = make([]*columnBuilder, len(headers))
+				parsers = make([]func(string) any, len(headers))
+				for i, header := range headers {
+					if wanted != nil && !wanted[header] {
+						continue
+					}
+					if builders[header] == nil {
+						builders[header] = newColumnBuilder(size)
+					}
+					columns[i] = builders[header]
+					parsers[i] = fastColumnParser(header, s.ColumnTypes, s.RawStrings)
+				}
+			}
+
+			// Walk the fields in place rather than splitting, parsing only the kept ones
+			rest := line
+			for i := 0; i < len(columns); i++ {
+				value, remainder, more := bytes.Cut(rest, separator)
+				if columns[i] != nil {
+					columns[i].seek(rows) // A repeated header keeps its last value, as in ToStream
+					if parsers[i] != nil {
+						columns[i].add(parsers[i](string(bytes.TrimSpace(value))))
+					} else {
+						columns[i].addParsed(string(bytes.TrimSpace(value)))
+					}
+				}
+				if !more {
+					break
+				}
+				rest = remainder
+			}
+			rows++
+		}
+
+		if rows < size {
+			finalErr = EOS
+			if err := scanner.Err(); err != nil {
+				finalErr = fmt.Errorf("error reading TSV: %w", err)
+			}
+		}
+		if rows == 0 {
+			return RecordBatch{}, finalErr
+		}
+		return finishBatch(builders, rows), nil
+	}
+}
@@ -0,0 +1,185 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRecordBatch tests the columnar batch representation and the operations over it
+func TestRecordBatch(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []Record{
+		{"id": int64(1), "price": int64(10), "name": "a", "ok": true, "at": ts},
+		{"id": int64(2), "price": 12.5, "name": "b", "ok": false},
+		{"id": int64(3), "name": "c", "ok": "maybe", "tags": []string{"x"}},
+		{"id": int64(4), "price": 7.25, "name": nil, "ok": true},
+		{"id": int64(5), "price": "3.5", "name": "e"},
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		batches, err := Collect(BatchRecords(2)(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Failed to batch: %v", err)
+		}
+		if len(batches) != 3 || batches[2].Len != 1 {
+			t.Fatalf("Expected batches of 2, 2 and 1 rows, got %d batches", len(batches))
+		}
+		if batches[0].Columns["id"].Int64s == nil || batches[0].Columns["price"].Float64s == nil {
+			t.Errorf("Expected typed id and price columns, got %+v and %+v", batches[0].Columns["id"], batches[0].Columns["price"])
+		}
+		if batches[1].Columns["ok"].Values == nil {
+			t.Errorf("Expected a mixed column to hold values, got %+v", batches[1].Columns["ok"])
+		}
+
+		results, err := Collect(UnbatchRecords()(FromSliceAny(batches)))
+		if err != nil {
+			t.Fatalf("Failed to unbatch: %v", err)
+		}
+		expected := []Record{
+			{"id": int64(1), "price": 10.0, "name": "a", "ok": true, "at": ts}, // Widened alongside 12.5
+			{"id": int64(2), "price": 12.5, "name": "b", "ok": false},
+			{"id": int64(3), "name": "c", "ok": "maybe", "tags": []string{"x"}},
+			{"id": int64(4), "price": 7.25, "ok": true}, // Nil fields are dropped
+			{"id": int64(5), "price": "3.5", "name": "e"},
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
+
+	t.Run("Aggregates", func(t *testing.T) {
+		for _, size := range []int{1, 2, 100} {
+			sum, err := SumByFieldBatch[float64](BatchRecords(size)(FromRecordsUnsafe(records)), "price")
+			want, _ := SumByField[float64](FromRecordsUnsafe(records), "price")
+			if err != nil || sum != want {
+				t.Errorf("Batch size %d: expected sum %v, got %v (%v)", size, want, sum, err)
+			}
+			intSum, _ := SumByFieldBatch[int64](BatchRecords(size)(FromRecordsUnsafe(records)), "price")
+			wantInt, _ := SumByField[int64](FromRecordsUnsafe(records), "price")
+			if intSum != wantInt {
+				t.Errorf("Batch size %d: expected int64 sum %v, got %v", size, wantInt, intSum)
+			}
+			avg, _ := AvgByFieldBatch(BatchRecords(size)(FromRecordsUnsafe(records)), "price")
+			wantAvg, _ := AvgByField(FromRecordsUnsafe(records), "price")
+			if avg != wantAvg {
+				t.Errorf("Batch size %d: expected avg %v, got %v", size, wantAvg, avg)
+			}
+		}
+		if avg, err := AvgByFieldBatch(BatchRecords(2)(FromRecordsUnsafe(records)), "missing"); err != nil || avg != 0 {
+			t.Errorf("Expected 0 for a missing field, got %v (%v)", avg, err)
+		}
+	})
+
+	t.Run("WhereBatch", func(t *testing.T) {
+		cheap := func(price float64) bool { return price < 11 }
+		batched, err := Collect(UnbatchRecords()(WhereBatch("price", cheap)(BatchRecords(2)(FromRecordsUnsafe(records)))))
+		if err != nil {
+			t.Fatalf("Failed to filter: %v", err)
+		}
+		expected, _ := Collect(Where(func(r Record) bool {
+			price, ok := Get[float64](r, "price")
+			return ok && cheap(price)
+		})(FromRecordsUnsafe(records)))
+
+		var got, want []int64
+		for _, r := range batched {
+			got = append(got, GetOr(r, "id", int64(0)))
+		}
+		for _, r := range expected {
+			want = append(want, GetOr(r, "id", int64(0)))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected ids %v, got %v", want, got)
+		}
+
+		none, _ := Collect(WhereBatch("name", func(string) bool { return false })(BatchRecords(2)(FromRecordsUnsafe(records))))
+		if len(none) != 0 {
+			t.Errorf("Expected empty batches to be dropped, got %d", len(none))
+		}
+	})
+
+	t.Run("ToBatches", func(t *testing.T) {
+		data := "id,amount,note,amount\n1,5.5,x,2.5\n\n2,7.5,y\n3,n/a,z,4.5\n4,,w,6.5\n"
+		records, err := Collect(NewFastTSVSourceWithSeparator(strings.NewReader(data), ",").ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read records: %v", err)
+		}
+		batches := NewFastTSVSourceWithSeparator(strings.NewReader(data), ",").ToBatches(3)
+		fromBatches, err := Collect(UnbatchRecords()(batches))
+		if err != nil {
+			t.Fatalf("Failed to read batches: %v", err)
+		}
+		if !reflect.DeepEqual(fromBatches, records) {
+			t.Errorf("Expected the records ToStream reads, %v, got %v", records, fromBatches)
+		}
+
+		selected, err := Collect(NewFastTSVSourceWithSeparator(strings.NewReader(data), ",").ToBatches(10, "id"))
+		if err != nil || len(selected) != 1 || len(selected[0].Columns) != 1 || selected[0].Len != 4 {
+			t.Fatalf("Expected one batch of 4 rows with only id, got %+v (%v)", selected, err)
+		}
+	})
+}
+
+// floatColumnCSV returns a CSV of rows rows with an integer id, a float amount and a
+// text column, replaying one generated million-row chunk so generating it isn't timed
+func floatColumnCSV(rows int) io.Reader {
+	const chunkRows = 1_000_000
+	var chunk bytes.Buffer
+	for i := 0; i < chunkRows; i++ {
+		fmt.Fprintf(&chunk, "%d,%d.%02d,region-%d\n", i, i%1000, i%100, i%8)
+	}
+	readers := []io.Reader{strings.NewReader("id,amount,region\n")}
+	for i := 0; i < rows/chunkRows; i++ {
+		readers = append(readers, bytes.NewReader(chunk.Bytes()))
+	}
+	return io.MultiReader(readers...)
+}
+
+// BenchmarkSumFloatColumn10M compares summing one float column over 10M CSV rows as
+// records with summing it as column batches. The Memory cases sum 1M rows already read,
+// to show the cost of the representation alone.
+func BenchmarkSumFloatColumn10M(b *testing.B) {
+	const rows = 10_000_000
+
+	b.Run("CSVRecords", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			source := NewFastTSVSourceWithSeparator(floatColumnCSV(rows), ",")
+			b.StartTimer()
+			if _, err := SumByField[float64](source.ToStream(), "amount"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("CSVBatches", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			source := NewFastTSVSourceWithSeparator(floatColumnCSV(rows), ",")
+			b.StartTimer()
+			if _, err := SumByFieldBatch[float64](source.ToBatches(8192, "amount"), "amount"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	records, _ := Collect(NewFastTSVSourceWithSeparator(floatColumnCSV(rows/10), ",").ToStream())
+	batches, _ := Collect(BatchRecords(8192)(FromRecordsUnsafe(records)))
+
+	b.Run("MemoryRecords", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			SumByField[float64](FromRecordsUnsafe(records), "amount")
+		}
+	})
+
+	b.Run("MemoryBatches", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			SumByFieldBatch[float64](FromSliceAny(batches), "amount")
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// roundTripCheckpoint writes a checkpoint with a JSONSink and reads it back, as a
+// restarted process would
+func roundTripCheckpoint(t *testing.T, operators ...Checkpointable) Record {
+	t.Helper()
+	checkpoint, err := Checkpoint(operators...)
+	if err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	var buffer bytes.Buffer
+	if err := NewJSONSink(&buffer).WriteRecords([]Record{checkpoint}); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+	restored, err := Collect(NewJSONSource(&buffer).ToStream())
+	if err != nil || len(restored) != 1 {
+		t.Fatalf("Failed to read checkpoint: %v (%d records)", err, len(restored))
+	}
+	return restored[0]
+}
+
+// TestCheckpoint tests exporting and restoring operator state
+func TestCheckpoint(t *testing.T) {
+	t.Run("StreamingGroupBy", func(t *testing.T) {
+		records := make([]Record, 1000)
+		for i := range records {
+			records[i] = Record{
+				"region": fmt.Sprintf("region-%d", i%7),
+				"amount": float64(i),
+			}
+		}
+		last := func(results []Record) Record {
+			summary := results[len(results)-1]
+			delete(summary, "timestamp")
+			return summary
+		}
+
+		uninterrupted := NewStreamingGroupByOperator([]string{"region"}, 50)
+		expected, err := Collect(uninterrupted.Filter()(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Uninterrupted run failed: %v", err)
+		}
+
+		first := NewStreamingGroupByOperator([]string{"region"}, 50)
+		if _, err := Collect(first.Filter()(FromRecordsUnsafe(records[:500]))); err != nil {
+			t.Fatalf("First half failed: %v", err)
+		}
+		checkpoint := roundTripCheckpoint(t, first)
+
+		resumed := NewStreamingGroupByOperator([]string{"region"}, 50)
+		if err := Restore(checkpoint, resumed); err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		results, err := Collect(resumed.Filter()(FromRecordsUnsafe(records[500:])))
+		if err != nil {
+			t.Fatalf("Second half failed: %v", err)
+		}
+
+		if got, want := last(results), last(expected); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Expected final summary %v, got %v", want, got)
+		}
+		resumedState, _ := resumed.ExportState()
+		expectedState, _ := uninterrupted.ExportState()
+		if fmt.Sprint(resumedState) != fmt.Sprint(expectedState) {
+			t.Errorf("Expected restored statistics %v, got %v", expectedState, resumedState)
+		}
+	})
+
+	t.Run("StreamingAccumulator", func(t *testing.T) {
+		sum := NewStreamingAccumulator[int64]()
+		Collect(sum.Sum()(FromSlice([]int64{1, 2, 3})))
+		avg := NewStreamingAccumulator[float64]()
+		Collect(avg.Avg()(FromSlice([]float64{1.5, 2.5})))
+		checkpoint := roundTripCheckpoint(t, sum, avg)
+
+		restoredSum := NewStreamingAccumulator[int64]()
+		restoredAvg := NewStreamingAccumulator[float64]()
+		if err := Restore(checkpoint, restoredSum, restoredAvg); err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		sums, _ := Collect(restoredSum.Sum()(FromSlice([]int64{4})))
+		if len(sums) != 1 || sums[0] != 10 {
+			t.Errorf("Expected running sum 10, got %v", sums)
+		}
+		averages, _ := Collect(restoredAvg.Avg()(FromSlice([]float64{5})))
+		if len(averages) != 1 || averages[0] != 3 {
+			t.Errorf("Expected running average 3, got %v", averages)
+		}
+		counts, _ := Collect(restoredAvg.Count()(FromSlice([]float64{1})))
+		if len(counts) != 1 || counts[0] != 4 {
+			t.Errorf("Expected running count 4, got %v", counts)
+		}
+
+		if err := Restore(checkpoint, restoredSum); err == nil {
+			t.Error("Expected an error restoring a checkpoint into the wrong number of operators")
+		}
+	})
+
+	t.Run("WatermarkTracker", func(t *testing.T) {
+		base := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+		tracker := NewWatermarkTracker(BoundedOutOfOrdernessWatermark(time.Second))
+		tracker.UpdateWatermark(base)
+
+		restored := NewWatermarkTracker(BoundedOutOfOrdernessWatermark(time.Second))
+		if err := Restore(roundTripCheckpoint(t, tracker), restored); err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		if !restored.GetWatermark().Equal(tracker.GetWatermark()) {
+			t.Errorf("Expected watermark %v, got %v", tracker.GetWatermark(), restored.GetWatermark())
+		}
+		// An older event must not move the restored watermark back
+		if wm := restored.UpdateWatermark(base.Add(-time.Minute)); !wm.Equal(tracker.GetWatermark()) {
+			t.Errorf("Expected watermark to stay at %v, got %v", tracker.GetWatermark(), wm)
+		}
+	})
+
+	t.Run("EventTimeWindows", func(t *testing.T) {
+		base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		events := make([]Record, 40)
+		for i := range events {
+			events[i] = Record{"id": int64(i), "ts": base.Add(time.Duration(i) * time.Second)}
+		}
+		windows := func(checkpoint *WindowCheckpoint) Filter[Record, Record] {
+			return EventTimeTumblingWindowResults(10*time.Second,
+				WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+				WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(0)),
+				WithWindowCheckpoint(checkpoint))
+		}
+		describe := func(results []Record) []string {
+			var described []string
+			for _, result := range results {
+				elements, _ := Collect(GetOr(result, WindowElementsField, Stream[Record](nil)))
+				described = append(described, fmt.Sprintf("%v:%d", result[WindowStartField], len(elements)))
+			}
+			return described
+		}
+
+		expected, err := Collect(windows(nil)(FromRecordsUnsafe(events)))
+		if err != nil {
+			t.Fatalf("Uninterrupted run failed: %v", err)
+		}
+
+		// Checkpoint mid-stream, after two windows have closed, along with the
+		// position in the input, as a process that can replay its source would
+		checkpoint := NewWindowCheckpoint()
+		consumed := 0
+		source := Peek(func(Record) { consumed++ })(FromRecordsUnsafe(events))
+		stream := windows(checkpoint)(source)
+		var closed []Record
+		for len(closed) < 2 {
+			result, err := stream()
+			if err != nil {
+				t.Fatalf("First run failed: %v", err)
+			}
+			closed = append(closed, result)
+		}
+		results := describe(closed)
+		state := roundTripCheckpoint(t, checkpoint)
+
+		resumed := NewWindowCheckpoint()
+		if err := Restore(state, resumed); err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		rest, err := Collect(windows(resumed)(FromRecordsUnsafe(events[consumed:])))
+		if err != nil {
+			t.Fatalf("Resumed run failed: %v", err)
+		}
+
+		got, want := append(results, describe(rest)...), describe(expected)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Expected windows %v, got %v", want, got)
+		}
+
+		if _, err := NewWindowCheckpoint().ExportState(); err == nil {
+			t.Error("Expected an error exporting an unattached window checkpoint")
+		}
+	})
+}
@@ -1,7 +1,16 @@
 package stream
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestSum tests the Sum aggregator
@@ -291,6 +300,164 @@ func TestCollect(t *testing.T) {
 	})
 }
 
+// TestTerminalHelpers tests ForEachIndexed, ConsumeErr and Drain
+func TestTerminalHelpers(t *testing.T) {
+	t.Run("ForEachIndexed", func(t *testing.T) {
+		var seen []string
+		err := ForEachIndexed(func(i int64, s string) {
+			seen = append(seen, fmt.Sprintf("%d:%s", i, s))
+		})(FromSlice([]string{"a", "b", "c"}))
+		if err != nil || fmt.Sprintf("%q", seen) != `["0:a" "1:b" "2:c"]` {
+			t.Errorf("Expected indexed elements, got %q (%v)", seen, err)
+		}
+	})
+
+	t.Run("ConsumeErrStopsAtFailure", func(t *testing.T) {
+		failure := errors.New("bad element")
+		pulled := 0
+		source := Peek(func(int64) { pulled++ })(Range(0, 100, 1))
+
+		var consumed []int64
+		err := ConsumeErr(func(x int64) error {
+			if x == 4 {
+				return failure
+			}
+			consumed = append(consumed, x)
+			return nil
+		})(source)
+
+		if err != failure {
+			t.Errorf("Expected the element error, got %v", err)
+		}
+		if fmt.Sprint(consumed) != "[0 1 2 3]" {
+			t.Errorf("Expected 0 to 3 to be consumed, got %v", consumed)
+		}
+		if pulled != 5 {
+			t.Errorf("Expected the stream to be pulled 5 times, got %d", pulled)
+		}
+
+		if err := ConsumeErr(func(int64) error { return nil })(Range(0, 3, 1)); err != nil {
+			t.Errorf("Expected nil at EOS, got %v", err)
+		}
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		count, err := Drain(Range(0, 12345, 1))
+		if err != nil || count != 12345 {
+			t.Errorf("Expected 12345, got %d (%v)", count, err)
+		}
+
+		failure := errors.New("read failed")
+		calls := 0
+		count, err = Drain(func() (int64, error) {
+			calls++
+			if calls > 3 {
+				return 0, failure
+			}
+			return 1, nil
+		})
+		if err != failure || count != 3 {
+			t.Errorf("Expected 3 elements then the read error, got %d (%v)", count, err)
+		}
+
+		few := testing.AllocsPerRun(10, func() { Drain(Range(0, 10, 1)) })
+		many := testing.AllocsPerRun(10, func() { Drain(Range(0, 100000, 1)) })
+		if many != few {
+			t.Errorf("Expected Drain not to allocate per element, got %v allocations for 10 and %v for 100000", few, many)
+		}
+	})
+}
+
+// TestBoundedCollect tests CollectN, CollectWithLimit and CollectContext
+func TestBoundedCollect(t *testing.T) {
+	// infinite counts up forever, like a Generate source whose end condition is broken
+	infinite := func() Stream[int64] {
+		var i int64
+		return Generate(func() (int64, error) {
+			i++
+			return i, nil
+		})
+	}
+
+	t.Run("CollectN", func(t *testing.T) {
+		result, exhausted, err := CollectN(Range(0, 10, 1), 3)
+		if err != nil || exhausted || fmt.Sprint(result) != "[0 1 2]" {
+			t.Errorf("Expected [0 1 2] from a longer stream, got %v (exhausted %v, %v)", result, exhausted, err)
+		}
+
+		result, exhausted, err = CollectN(Range(0, 2, 1), 3)
+		if err != nil || !exhausted || len(result) != 2 {
+			t.Errorf("Expected 2 elements and exhaustion, got %v (exhausted %v, %v)", result, exhausted, err)
+		}
+
+		source := infinite()
+		result, exhausted, _ = CollectN(source, 5)
+		if exhausted || len(result) != 5 {
+			t.Errorf("Expected 5 elements from an infinite stream, got %v", result)
+		}
+		if next, _ := source(); next != 6 {
+			t.Errorf("Expected the stream to resume at 6, got %d", next)
+		}
+	})
+
+	t.Run("CollectWithLimit", func(t *testing.T) {
+		result, err := CollectWithLimit(Range(0, 5, 1), 5)
+		if err != nil || len(result) != 5 {
+			t.Errorf("Expected a stream of exactly the limit to succeed, got %v (%v)", result, err)
+		}
+
+		result, err = CollectWithLimit(Range(0, 6, 1), 5)
+		if !errors.Is(err, ErrLimitExceeded) || len(result) != 5 {
+			t.Errorf("Expected ErrLimitExceeded with 5 elements, got %v (%v)", result, err)
+		}
+
+		result, err = CollectWithLimit(infinite(), 1000)
+		if !errors.Is(err, ErrLimitExceeded) || len(result) != 1000 {
+			t.Errorf("Expected an infinite stream to fail fast, got %d elements (%v)", len(result), err)
+		}
+
+		failure := errors.New("read failed")
+		calls := 0
+		failing := func() (int64, error) {
+			calls++
+			if calls > 2 {
+				return 0, failure
+			}
+			return int64(calls), nil
+		}
+		if _, err := CollectWithLimit(failing, 2); err != failure {
+			t.Errorf("Expected the read error to win over the limit, got %v", err)
+		}
+	})
+
+	t.Run("CollectContext", func(t *testing.T) {
+		result, err := CollectContext(context.Background(), Range(0, 100, 1))
+		if err != nil || len(result) != 100 {
+			t.Errorf("Expected 100 elements, got %d (%v)", len(result), err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var i int64
+		source := Generate(func() (int64, error) {
+			i++
+			if i == 50 {
+				cancel()
+			}
+			return i, nil
+		})
+		result, err = CollectContext(ctx, source)
+		if !errors.Is(err, context.Canceled) || len(result) != 50 {
+			t.Errorf("Expected cancellation after 50 elements, got %d (%v)", len(result), err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := CollectContext(ctx, infinite()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the deadline to stop an infinite stream, got %v", err)
+		}
+	})
+}
+
 // TestForEach tests the ForEach function
 func TestForEach(t *testing.T) {
 	t.Run("SideEffects", func(t *testing.T) {
@@ -496,6 +663,25 @@ func TestAggregateMultiple(t *testing.T) {
 			t.Errorf("Expected count %v, got %v", expectedCount, count)
 		}
 	})
+	
+	t.Run("LargerThanTeeBuffer", func(t *testing.T) {
+		// Both aggregators must see every item of a stream too long for any fixed buffer
+		stream := Range(0, 10000, 1)
+		
+		sumAgg := SumAggregator(func(x int64) int64 { return x })
+		countAgg := CountAggregator[int64]()
+		
+		sum, count, err := AggregateMultiple(stream, sumAgg, countAgg)
+		if err != nil {
+			t.Fatalf("Failed to run multiple aggregators: %v", err)
+		}
+		if sum != 49995000 {
+			t.Errorf("Expected sum 49995000, got %v", sum)
+		}
+		if count != 10000 {
+			t.Errorf("Expected count 10000, got %v", count)
+		}
+	})
 }
 
 // TestAggregates tests the Aggregates function
@@ -534,6 +720,76 @@ func TestAggregates(t *testing.T) {
 	})
 }
 
+// TestWindowedAggregate tests running aggregators per window
+func TestWindowedAggregate(t *testing.T) {
+	t.Run("CountWindowRecords", func(t *testing.T) {
+		records := make([]Record, 1000)
+		for i := range records {
+			records[i] = NewRecord().Int("amount", int64(i+1)).Build()
+		}
+		
+		pipeline := Pipe(
+			CountWindow[Record](100),
+			WindowedAggregate(
+				SumField[int64]("total", "amount"),
+				AvgField[int64]("average", "amount"),
+			),
+		)
+		results, err := Collect(pipeline(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Failed to aggregate windows: %v", err)
+		}
+		
+		if len(results) != 10 {
+			t.Fatalf("Expected 10 window results, got %d", len(results))
+		}
+		for w, result := range results {
+			first := int64(w*100 + 1)
+			last := first + 99
+			expectedSum := (first + last) * 50
+			if GetOr(result, "total", int64(0)) != expectedSum {
+				t.Errorf("Window %d: expected total=%d, got %v", w, expectedSum, result["total"])
+			}
+			if GetOr(result, "average", 0.0) != float64(first+last)/2 {
+				t.Errorf("Window %d: expected average=%v, got %v", w, float64(first+last)/2, result["average"])
+			}
+			if result["window_count"] != int64(100) || result["window_index"] != int64(w) {
+				t.Errorf("Window %d: expected window_count=100 and window_index=%d, got %v", w, w, result)
+			}
+		}
+	})
+	
+	t.Run("GenericWindows", func(t *testing.T) {
+		windows := CountWindow[int64](4)(Range(1, 11, 1))
+		
+		results, err := Collect(WindowedAggregate(SumStream[int64]("sum"), MaxStream[int64]("max"))(windows))
+		if err != nil {
+			t.Fatalf("Failed to aggregate windows: %v", err)
+		}
+		
+		expected := []Record{
+			{"window_index": int64(0), "window_count": int64(4), "sum": int64(10), "max": int64(4)},
+			{"window_index": int64(1), "window_count": int64(4), "sum": int64(26), "max": int64(8)},
+			{"window_index": int64(2), "window_count": int64(2), "sum": int64(19), "max": int64(10)},
+		}
+		if fmt.Sprint(results) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
+	
+	t.Run("NoSpecsCountsOnly", func(t *testing.T) {
+		results, _ := Collect(WindowedAggregate[int64]()(CountWindow[int64](3)(Range(0, 7, 1))))
+		
+		counts := []any{}
+		for _, result := range results {
+			counts = append(counts, result["window_count"])
+		}
+		if fmt.Sprint(counts) != "[3 3 1]" {
+			t.Errorf("Expected window counts [3 3 1], got %v", counts)
+		}
+	})
+}
+
 // TestCountField tests the CountField function
 func TestCountField(t *testing.T) {
 	t.Run("RecordCount", func(t *testing.T) {
@@ -565,4 +821,1194 @@ func TestCountField(t *testing.T) {
 			t.Errorf("Expected custom_count=3, got %v", customCount)
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestReduce tests the Reduce terminal fold
+func TestReduce(t *testing.T) {
+	t.Run("ConcatenateMessages", func(t *testing.T) {
+		stream := FromSlice([]string{"disk full", "timeout", "refused"})
+
+		joined, err := Reduce(stream, "", func(acc string, msg string) string {
+			if acc == "" {
+				return msg
+			}
+			return acc + "; " + msg
+		})
+		if err != nil {
+			t.Fatalf("Failed to reduce stream: %v", err)
+		}
+		if joined != "disk full; timeout; refused" {
+			t.Errorf("Expected concatenated messages, got %q", joined)
+		}
+	})
+
+	t.Run("DistinctSet", func(t *testing.T) {
+		stream := FromSlice([]int64{1, 2, 1, 3, 2})
+
+		set, err := Reduce(stream, map[int64]bool{}, func(acc map[int64]bool, id int64) map[int64]bool {
+			acc[id] = true
+			return acc
+		})
+		if err != nil {
+			t.Fatalf("Failed to reduce stream: %v", err)
+		}
+		if len(set) != 3 {
+			t.Errorf("Expected 3 distinct IDs, got %d", len(set))
+		}
+	})
+
+	t.Run("PropagatesErrors", func(t *testing.T) {
+		calls := 0
+		stream := func() (int, error) {
+			calls++
+			if calls > 2 {
+				return 0, fmt.Errorf("source failed")
+			}
+			return calls, nil
+		}
+
+		_, err := Reduce(stream, 0, func(acc, v int) int { return acc + v })
+		if err == nil || !strings.Contains(err.Error(), "source failed") {
+			t.Errorf("Expected source error, got %v", err)
+		}
+	})
+}
+
+// TestGroupByStreaming tests that the incremental GroupBy matches GroupBy
+func TestGroupByStreaming(t *testing.T) {
+	records := []Record{
+		NewRecord().String("dept", "eng").Int("salary", 100).Build(),
+		NewRecord().String("dept", "ops").Int("salary", 60).Build(),
+		NewRecord().String("dept", "eng").Int("salary", 120).Build(),
+		NewRecord().String("dept", "ops").Int("salary", 80).Build(),
+		NewRecord().String("dept", "eng").Int("salary", 110).Build(),
+	}
+	specs := []AggregatorSpec[Record]{
+		CountField("count", "salary"),
+		SumField[int64]("total", "salary"),
+		AvgField[int64]("avg", "salary"),
+		MinField[int64]("min", "salary"),
+		MaxField[int64]("max", "salary"),
+	}
+
+	t.Run("MatchesGroupBy", func(t *testing.T) {
+		expected, err := Collect(GroupBy([]string{"dept"}, specs...)(FromSlice(records)))
+		if err != nil {
+			t.Fatalf("GroupBy failed: %v", err)
+		}
+		actual, err := Collect(GroupByStreaming([]string{"dept"}, specs...)(FromSlice(records)))
+		if err != nil {
+			t.Fatalf("GroupByStreaming failed: %v", err)
+		}
+
+		if len(actual) != len(expected) {
+			t.Fatalf("Expected %d groups, got %d", len(expected), len(actual))
+		}
+		byDept := make(map[string]Record)
+		for _, r := range expected {
+			byDept[GetOr(r, "dept", "")] = r
+		}
+		for _, r := range actual {
+			want := byDept[GetOr(r, "dept", "")]
+			if len(r) != len(want) {
+				t.Errorf("Expected fields %v, got %v", want, r)
+			}
+			for field, val := range want {
+				if r[field] != val {
+					t.Errorf("Group %v field %s: expected %v, got %v", r["dept"], field, val, r[field])
+				}
+			}
+		}
+	})
+
+	t.Run("FirstSeenOrder", func(t *testing.T) {
+		results, err := Collect(GroupByStreaming([]string{"dept"})(FromSlice(records)))
+		if err != nil {
+			t.Fatalf("GroupByStreaming failed: %v", err)
+		}
+		if len(results) != 2 || results[0]["dept"] != "eng" || results[1]["dept"] != "ops" {
+			t.Errorf("Expected groups eng, ops in order, got %v", results)
+		}
+	})
+
+	t.Run("PropagatesError", func(t *testing.T) {
+		failure := fmt.Errorf("read failed")
+		failing := func() (Record, error) { return nil, failure }
+
+		_, err := Collect(GroupByStreaming([]string{"dept"})(failing))
+		if err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+	})
+
+	t.Run("StickyError", func(t *testing.T) {
+		failure := fmt.Errorf("read failed")
+		calls := 0
+		flaky := func() (Record, error) {
+			calls++
+			switch calls {
+			case 1:
+				return nil, failure
+			case 2:
+				return Record{"dept": "eng"}, nil
+			}
+			return nil, EOS
+		}
+
+		grouped := GroupByStreaming([]string{"dept"})(flaky)
+		for i := 0; i < 2; i++ {
+			if _, err := grouped(); err != failure {
+				t.Errorf("Pull %d: expected source error, got %v", i+1, err)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("Expected input not to be read after the error, got %d calls", calls)
+		}
+	})
+}
+
+// BenchmarkGroupByMemory compares peak heap of GroupBy and GroupByStreaming
+// on 1M records spread over 1k groups
+func BenchmarkGroupByMemory(b *testing.B) {
+	const records = 1_000_000
+	const groups = 1_000
+
+	source := func(peak *uint64) Stream[Record] {
+		i := 0
+		var stats runtime.MemStats
+		return func() (Record, error) {
+			if i >= records {
+				return nil, EOS
+			}
+			if i%50_000 == 0 {
+				runtime.ReadMemStats(&stats)
+				if stats.HeapAlloc > *peak {
+					*peak = stats.HeapAlloc
+				}
+			}
+			r := Record{"key": int64(i % groups), "value": int64(i)}
+			i++
+			return r, nil
+		}
+	}
+
+	run := func(b *testing.B, groupBy func([]string, ...AggregatorSpec[Record]) Filter[Record, Record]) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			runtime.GC()
+			var peak uint64
+			_, _ = Collect(groupBy([]string{"key"},
+				SumField[int64]("total", "value"),
+				CountField("count", "value"),
+			)(source(&peak)))
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		}
+	}
+
+	b.Run("GroupBy", func(b *testing.B) { run(b, GroupBy) })
+	b.Run("GroupByStreaming", func(b *testing.B) { run(b, GroupByStreaming) })
+}
+
+// TestStreamingAggregateByKey tests per-key running aggregation with eviction
+func TestStreamingAggregateByKey(t *testing.T) {
+	specs := []AggregatorSpec[Record]{
+		CountField("count", "value"),
+		SumField[int64]("total", "value"),
+	}
+
+	t.Run("UpdatesPerRecord", func(t *testing.T) {
+		records := []Record{
+			{"user": "a", "value": int64(1)},
+			{"user": "b", "value": int64(10)},
+			{"user": "a", "value": int64(2)},
+		}
+		results, err := Collect(StreamingAggregateByKey([]string{"user"}, specs)(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"a:1:1", "b:1:10", "a:2:3"}
+		var got []string
+		for _, r := range results {
+			got = append(got, fmt.Sprintf("%v:%v:%v", r["user"], r["count"], r["total"]))
+			if r[AggregateEvictedField] != false {
+				t.Errorf("Expected an update, got %v", r)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Expected updates %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("LRUEviction", func(t *testing.T) {
+		// 1000 distinct users, each seen twice far apart, with room for only 10
+		var records []Record
+		for round := 0; round < 2; round++ {
+			for i := 0; i < 1000; i++ {
+				records = append(records, Record{"user": fmt.Sprintf("u%d", i), "value": int64(i)})
+			}
+		}
+		results, err := Collect(StreamingAggregateByKey([]string{"user"}, specs, WithMaxKeys(10))(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		evicted := make(map[string]int)
+		for _, r := range results {
+			user := GetOr(r, "user", "")
+			if r[AggregateEvictedField] == true {
+				evicted[user]++
+				continue
+			}
+			// Every user is evicted before it reappears, so each update starts fresh
+			if r["count"] != int64(1) {
+				t.Fatalf("Expected a reappearing user to start fresh, got %v", r)
+			}
+		}
+		// All but the last 10 users of the second round have been evicted twice
+		if len(evicted) != 1000 || evicted["u0"] != 2 || evicted["u999"] != 1 {
+			t.Errorf("Expected every user evicted with a final record, got %d users (u0: %d, u999: %d)",
+				len(evicted), evicted["u0"], evicted["u999"])
+		}
+		if len(results) != 2000+1990 {
+			t.Errorf("Expected 2000 updates and 1990 evictions, got %d records", len(results))
+		}
+	})
+
+	t.Run("LRUKeepsRecentlyUpdatedKeys", func(t *testing.T) {
+		records := []Record{
+			{"user": "a", "value": int64(1)},
+			{"user": "b", "value": int64(1)},
+			{"user": "a", "value": int64(1)}, // a is now the most recent
+			{"user": "c", "value": int64(1)}, // evicts b
+		}
+		results, _ := Collect(StreamingAggregateByKey([]string{"user"}, specs, WithMaxKeys(2))(FromRecordsUnsafe(records)))
+		var evicted []string
+		for _, r := range results {
+			if r[AggregateEvictedField] == true {
+				evicted = append(evicted, fmt.Sprintf("%v:%v", r["user"], r["count"]))
+			}
+		}
+		if fmt.Sprint(evicted) != "[b:1]" {
+			t.Errorf("Expected only b to be evicted, got %v", evicted)
+		}
+	})
+
+	t.Run("TTLAndEmitEvery", func(t *testing.T) {
+		records := []Record{
+			{"user": "a", "value": int64(1)},
+			{"user": "a", "value": int64(2)},
+			{"user": "a", "value": int64(3)},
+			{"user": "b", "value": int64(5)}, // a has been idle past its TTL
+		}
+		i := 0
+		source := func() (Record, error) {
+			if i >= len(records) {
+				return nil, EOS
+			}
+			if i == 3 {
+				time.Sleep(30 * time.Millisecond)
+			}
+			i++
+			return records[i-1], nil
+		}
+		results, err := Collect(StreamingAggregateByKey([]string{"user"}, specs,
+			WithEmitEvery(2), WithKeyTTL(20*time.Millisecond))(source))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var got []string
+		for _, r := range results {
+			got = append(got, fmt.Sprintf("%v:%v:%v", r["user"], r["total"], r[AggregateEvictedField]))
+		}
+		// a updates after 2 records, is evicted with all 3, and b's partial update flushes at EOS
+		expected := []string{"a:3:false", "a:6:true", "b:5:false"}
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+}
+
+// TestPivot tests reshaping between long and wide records
+func TestPivot(t *testing.T) {
+	sales := []Record{
+		{"region": "north", "month": "2024-01", "sales": int64(10)},
+		{"region": "north", "month": "2024-02", "sales": int64(20)},
+		{"region": "south", "month": "2024-01", "sales": int64(5)},
+		{"region": "north", "month": "2024-01", "sales": int64(1)},
+	}
+	total := SumField[int64]("total", "sales")
+
+	t.Run("Pivot", func(t *testing.T) {
+		results, err := Collect(Pivot([]string{"region"}, "month", "sales", total)(FromRecordsUnsafe(sales)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 regions, got %v", results)
+		}
+		north, south := results[0], results[1]
+		if north["region"] != "north" || north["month_2024_01"] != int64(11) || north["month_2024_02"] != int64(20) {
+			t.Errorf("Unexpected north row: %v", north)
+		}
+		if _, ok := south["month_2024_02"]; ok || south["month_2024_01"] != int64(5) || len(south) != 2 {
+			t.Errorf("Expected south to have only a January column, got %v", south)
+		}
+	})
+
+	t.Run("ColumnNames", func(t *testing.T) {
+		records := []Record{
+			{"id": "x", "kind": "a-b", "v": int64(1)},
+			{"id": "x", "kind": "a_b", "v": int64(2)},
+			{"id": "x", "kind": int64(7), "v": int64(3)},
+			{"id": "x", "v": int64(4)},              // No pivot value
+			{"id": "x", "kind": "d", "v": int64(5)}, // Collides with the key field below
+			{"id": "x", "kind": "ignored"},          // No value: no column
+		}
+		results, _ := Collect(Pivot([]string{"id", "d"}, "kind", "v", SumField[int64]("total", "v"), WithPivotColumnPrefix(""))(FromRecordsUnsafe(records)))
+		expected := Record{"id": "x", "7": int64(3), "a_b": int64(1), "a_b_2": int64(2), "d_2": int64(5), "null": int64(4)}
+		if len(results) != 1 || fmt.Sprint(results[0]) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		long := []Record{
+			{"region": "north", "month": "jan", "sales": int64(10)},
+			{"region": "north", "month": "feb", "sales": int64(20)},
+			{"region": "south", "month": "jan", "sales": int64(5)},
+		}
+		reshape := Pipe(
+			Pivot([]string{"region"}, "month", "sales", LastField("sales", "sales"), WithPivotColumnPrefix("")),
+			Unpivot([]string{"region"}, "month", "sales"),
+		)
+		results, err := Collect(reshape(FromRecordsUnsafe(long)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// Unpivot emits each row's fields in sorted order
+		expected := []Record{long[1], long[0], long[2]}
+		if fmt.Sprint(results) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
+
+	t.Run("UnpivotSkipsIDs", func(t *testing.T) {
+		wide := []Record{{"id": int64(1), "b": "y", "a": "x"}, {"id": int64(2)}}
+		results, _ := Collect(Unpivot([]string{"id"}, "field", "value")(FromRecordsUnsafe(wide)))
+		expected := []Record{
+			{"id": int64(1), "field": "a", "value": "x"},
+			{"id": int64(1), "field": "b", "value": "y"},
+		}
+		if fmt.Sprint(results) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
+}
+
+// TestGroupByAggregatorTypes tests GroupBy with aggregators beyond the int64 family
+func TestGroupByAggregatorTypes(t *testing.T) {
+	records := []Record{
+		NewRecord().String("category", "fruit").String("name", "pear").Float("price", 1.25).Build(),
+		NewRecord().String("category", "fruit").String("name", "apple").Float("price", 0.75).Build(),
+		NewRecord().String("category", "fruit").String("name", "quince").Float("price", 2.5).Build(),
+	}
+
+	groupBy := map[string]func([]string, ...AggregatorSpec[Record]) Filter[Record, Record]{
+		"GroupBy":          GroupBy,
+		"GroupByStreaming": GroupByStreaming,
+	}
+
+	for name, group := range groupBy {
+		t.Run(name+"FloatMinMax", func(t *testing.T) {
+			results, err := Collect(group([]string{"category"},
+				MinField[float64]("min_price", "price"),
+				MaxField[float64]("max_price", "price"),
+				SumField[float64]("total", "price"),
+			)(FromSlice(records)))
+			if err != nil {
+				t.Fatalf("Failed to group: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 group, got %d", len(results))
+			}
+			if results[0]["min_price"] != 0.75 || results[0]["max_price"] != 2.5 || results[0]["total"] != 4.5 {
+				t.Errorf("Unexpected float aggregates: %v", results[0])
+			}
+		})
+
+		t.Run(name+"StringMinMax", func(t *testing.T) {
+			results, err := Collect(group([]string{"category"},
+				MinField[string]("first_name", "name"),
+				MaxField[string]("last_name", "name"),
+			)(FromSlice(records)))
+			if err != nil {
+				t.Fatalf("Failed to group: %v", err)
+			}
+			if results[0]["first_name"] != "apple" || results[0]["last_name"] != "quince" {
+				t.Errorf("Unexpected string aggregates: %v", results[0])
+			}
+		})
+
+		t.Run(name+"CustomSpec", func(t *testing.T) {
+			names := Aggregator[Record, []string, string]{
+				Initial: func() []string { return nil },
+				Accumulate: func(acc []string, r Record) []string {
+					return append(acc, GetOr(r, "name", ""))
+				},
+				Finalize: func(acc []string) string { return strings.Join(acc, ",") },
+			}
+
+			results, err := Collect(group([]string{"category"}, CustomSpec("names", names))(FromSlice(records)))
+			if err != nil {
+				t.Fatalf("Failed to group: %v", err)
+			}
+			if results[0]["names"] != "pear,apple,quince" {
+				t.Errorf("Expected names in input order, got %v", results[0]["names"])
+			}
+		})
+
+		t.Run(name+"RunnerSpec", func(t *testing.T) {
+			runner := AggregatorSpec[Record]{Name: "names", Agg: namesRunner{}}
+
+			results, err := Collect(group([]string{"category"}, runner, CountField("count", "name"))(FromSlice(records)))
+			if err != nil {
+				t.Fatalf("Failed to group: %v", err)
+			}
+			if results[0]["names"] != "pear,apple,quince" || results[0]["count"] != int64(3) {
+				t.Errorf("Expected the runner given each group's records in order, got %v", results[0])
+			}
+		})
+
+		t.Run(name+"UnsupportedSpec", func(t *testing.T) {
+			bogus := AggregatorSpec[Record]{Name: "bogus", Agg: "not an aggregator"}
+
+			_, err := Collect(group([]string{"category"}, bogus)(FromSlice(records)))
+			if err == nil || !strings.Contains(err.Error(), "bogus") {
+				t.Errorf("Expected unsupported aggregator error, got %v", err)
+			}
+		})
+	}
+
+	t.Run("AggregatesCustomSpec", func(t *testing.T) {
+		longest := Aggregator[string, int, int]{
+			Initial: func() int { return 0 },
+			Accumulate: func(acc int, s string) int {
+				if len(s) > acc {
+					return len(s)
+				}
+				return acc
+			},
+			Finalize: func(acc int) int { return acc },
+		}
+
+		result, err := Aggregates(FromSlice([]string{"a", "abc", "ab"}),
+			CustomSpec("longest", longest),
+			CountStream[string]("count"),
+		)
+		if err != nil {
+			t.Fatalf("Aggregates failed: %v", err)
+		}
+		if result["longest"] != 3 || result["count"] != int64(3) {
+			t.Errorf("Unexpected aggregates: %v", result)
+		}
+	})
+}
+
+// namesRunner is an AggregatorRunner that isn't an Aggregator, joining the names it runs on
+type namesRunner struct{}
+
+func (namesRunner) RunOn(stream Stream[Record]) (any, error) {
+	names, err := Collect(Map(func(r Record) string { return GetOr(r, "name", "") })(stream))
+	return strings.Join(names, ","), err
+}
+
+// TestDistributionAggregators tests percentile, median and standard deviation
+func TestDistributionAggregators(t *testing.T) {
+	identity := func(v float64) float64 { return v }
+	oneToHundred := make([]float64, 100)
+	for i := range oneToHundred {
+		oneToHundred[i] = float64(100 - i) // Unsorted input
+	}
+
+	t.Run("Percentiles", func(t *testing.T) {
+		cases := []struct {
+			p        float64
+			expected float64
+		}{
+			{0, 1},
+			{50, 50.5},
+			{95, 95.05},
+			{99, 99.01},
+			{100, 100},
+		}
+		for _, c := range cases {
+			result, err := AggregateWith(FromSlice(oneToHundred), PercentileAggregator(identity, c.p))
+			if err != nil {
+				t.Fatalf("Percentile failed: %v", err)
+			}
+			if math.Abs(result-c.expected) > 1e-9 {
+				t.Errorf("P%v: expected %v, got %v", c.p, c.expected, result)
+			}
+		}
+	})
+
+	t.Run("Median", func(t *testing.T) {
+		result, err := AggregateWith(FromSlice([]float64{7, 1, 3}), MedianAggregator(identity))
+		if err != nil {
+			t.Fatalf("Median failed: %v", err)
+		}
+		if result != 3 {
+			t.Errorf("Expected median 3, got %v", result)
+		}
+	})
+
+	t.Run("StdDev", func(t *testing.T) {
+		result, err := AggregateWith(FromSlice(oneToHundred), StdDevAggregator(identity))
+		if err != nil {
+			t.Fatalf("StdDev failed: %v", err)
+		}
+		expected := math.Sqrt((100*100 - 1) / 12.0)
+		if math.Abs(result-expected) > 1e-9 {
+			t.Errorf("Expected stddev %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("EmptyInput", func(t *testing.T) {
+		result, err := Aggregates(FromSlice([]Record{}),
+			PercentileField("p95", "latency", 95),
+			MedianField("p50", "latency"),
+			StdDevField("stddev", "latency"),
+		)
+		if err != nil {
+			t.Fatalf("Aggregates failed: %v", err)
+		}
+		for _, name := range []string{"p95", "p50", "stddev"} {
+			if result[name] != 0.0 {
+				t.Errorf("Expected %s=0 for empty input, got %v", name, result[name])
+			}
+		}
+	})
+
+	t.Run("GroupByFields", func(t *testing.T) {
+		var records []Record
+		for i := 1; i <= 100; i++ {
+			records = append(records, NewRecord().String("endpoint", "/api").Int("latency", int64(i)).Build())
+		}
+		records = append(records, NewRecord().String("endpoint", "/health").Int("latency", 5).Build())
+
+		results, err := Collect(GroupByStreaming([]string{"endpoint"},
+			PercentileField("p99", "latency", 99),
+			MedianField("p50", "latency"),
+			StdDevField("stddev", "latency"),
+		)(FromSlice(records)))
+		if err != nil {
+			t.Fatalf("GroupBy failed: %v", err)
+		}
+
+		api, health := results[0], results[1]
+		if math.Abs(GetOr(api, "p99", 0.0)-99.01) > 1e-9 || GetOr(api, "p50", 0.0) != 50.5 {
+			t.Errorf("Unexpected /api percentiles: %v", api)
+		}
+		if health["p50"] != 5.0 || health["stddev"] != 0.0 {
+			t.Errorf("Unexpected single-record group: %v", health)
+		}
+	})
+
+	t.Run("InvalidPercentile", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic for percentile outside [0, 100]")
+			}
+		}()
+		PercentileAggregator(identity, 101)
+	})
+}
+
+// TestFirstLastCollectFields tests the non-numeric GroupBy aggregators
+func TestFirstLastCollectFields(t *testing.T) {
+	orders := []Record{
+		{"customer": "alice", "order_id": "A1", "status": "placed"},
+		{"customer": "bob", "order_id": "B1", "status": "shipped"},
+		{"customer": "alice", "order_id": "A2"}, // no status
+		{"customer": "alice", "order_id": "A3", "status": "delivered"},
+	}
+	specs := []AggregatorSpec[Record]{
+		FirstField("first_order", "order_id"),
+		LastField("last_status", "status"),
+		CollectField("orders", "order_id"),
+	}
+
+	t.Run("Groups", func(t *testing.T) {
+		results, err := Collect(GroupByStreaming([]string{"customer"}, specs...)(FromSlice(orders)))
+		if err != nil {
+			t.Fatalf("GroupBy failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 groups, got %d", len(results))
+		}
+
+		alice := results[0]
+		if alice["first_order"] != "A1" || alice["last_status"] != "delivered" {
+			t.Errorf("Unexpected alice aggregates: %v", alice)
+		}
+		ids, err := Collect(alice["orders"].(Stream[any]))
+		if err != nil || len(ids) != 3 || ids[0] != "A1" || ids[2] != "A3" {
+			t.Errorf("Expected orders [A1 A2 A3], got %v (%v)", ids, err)
+		}
+
+		// Single-record group
+		bob := results[1]
+		if bob["first_order"] != "B1" || bob["last_status"] != "shipped" {
+			t.Errorf("Unexpected bob aggregates: %v", bob)
+		}
+	})
+
+	t.Run("MissingFieldsSkipped", func(t *testing.T) {
+		records := []Record{
+			{"key": "k"},
+			{"key": "k", "value": nil},
+			{"key": "k", "value": int64(7)},
+			{"key": "k"},
+		}
+		results, err := Collect(GroupBy([]string{"key"},
+			FirstField("first", "value"),
+			LastField("last", "value"),
+			CollectField("all", "value"),
+			FirstField("never", "absent"),
+		)(FromSlice(records)))
+		if err != nil {
+			t.Fatalf("GroupBy failed: %v", err)
+		}
+
+		result := results[0]
+		if result["first"] != int64(7) || result["last"] != int64(7) {
+			t.Errorf("Expected first and last to skip missing values, got %v", result)
+		}
+		if val, exists := result["never"]; !exists || val != nil {
+			t.Errorf("Expected nil for a field no record has, got %v (present=%v)", val, exists)
+		}
+		values, _ := Collect(result["all"].(Stream[any]))
+		if len(values) != 1 {
+			t.Errorf("Expected one collected value, got %v", values)
+		}
+	})
+
+	t.Run("CollectedStreamToJSON", func(t *testing.T) {
+		grouped := GroupBy([]string{"customer"}, CollectField("orders", "order_id"))(FromSlice(orders))
+		alice := Where(func(r Record) bool { return r["customer"] == "alice" })(grouped)
+
+		var buffer bytes.Buffer
+		if err := StreamToJSON(alice, &buffer); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+
+		records, err := Collect(JSONToStream(&buffer))
+		if err != nil {
+			t.Fatalf("Failed to read JSON: %v", err)
+		}
+		if len(records) != 1 {
+			t.Fatalf("Expected 1 record, got %d", len(records))
+		}
+		ids, err := Collect(records[0]["orders"].(Stream[any]))
+		if err != nil || len(ids) != 3 || ids[1] != "A2" {
+			t.Errorf("Expected orders [A1 A2 A3] after round trip, got %v (%v)", ids, err)
+		}
+	})
+
+	t.Run("CollectedStreamCrossFlatten", func(t *testing.T) {
+		grouped := GroupByStreaming([]string{"customer"}, CollectField("order", "order_id"))(FromSlice(orders))
+		flattened, err := Collect(CrossFlatten(".", "order")(grouped))
+		if err != nil {
+			t.Fatalf("CrossFlatten failed: %v", err)
+		}
+		if len(flattened) != len(orders) {
+			t.Errorf("Expected one record per order, got %v", flattened)
+		}
+	})
+}
+
+// TestCountDistinctField tests exact and approximate distinct counting
+func TestCountDistinctField(t *testing.T) {
+	t.Run("ExactWithDuplicates", func(t *testing.T) {
+		visits := []Record{
+			{"region": "eu", "user_id": int64(1)},
+			{"region": "eu", "user_id": int64(2)},
+			{"region": "eu", "user_id": int64(1)},
+			{"region": "us", "user_id": int64(1)},
+			{"region": "us", "user_id": nil},
+			{"region": "us"},
+		}
+
+		results, err := Collect(GroupByStreaming([]string{"region"},
+			CountDistinctField("unique_users", "user_id"),
+		)(FromSlice(visits)))
+		if err != nil {
+			t.Fatalf("GroupBy failed: %v", err)
+		}
+		if results[0]["unique_users"] != int64(2) || results[1]["unique_users"] != int64(1) {
+			t.Errorf("Expected eu=2, us=1, got %v", results)
+		}
+	})
+
+	t.Run("Aggregates", func(t *testing.T) {
+		result, err := Aggregates(FromSlice([]Record{{"tag": "a"}, {"tag": "b"}, {"tag": "a"}}),
+			CountDistinctField("exact", "tag"),
+			ApproxCountDistinctField("approx", "tag", 10),
+		)
+		if err != nil {
+			t.Fatalf("Aggregates failed: %v", err)
+		}
+		if result["exact"] != int64(2) || result["approx"] != int64(2) {
+			t.Errorf("Expected 2 distinct tags, got %v", result)
+		}
+	})
+
+	t.Run("ApproxWithinFewPercent", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(42))
+		seen := make(map[int64]struct{})
+		i := 0
+		ids := func() (Record, error) {
+			if i >= 1_000_000 {
+				return nil, EOS
+			}
+			i++
+			id := rng.Int63n(5_000_000)
+			seen[id] = struct{}{}
+			return Record{"id": id}, nil
+		}
+
+		estimate, err := AggregateWith(Stream[Record](ids), ApproxCountDistinctAggregatorField("id", 14))
+		if err != nil {
+			t.Fatalf("Approximate count failed: %v", err)
+		}
+		exact := float64(len(seen))
+		if math.Abs(float64(estimate)-exact)/exact > 0.03 {
+			t.Errorf("Estimate %d is more than 3%% from exact %d", estimate, len(seen))
+		}
+	})
+
+	t.Run("InvalidPrecision", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic for precision outside [4, 16]")
+			}
+		}()
+		ApproxCountDistinctField("n", "id", 20)
+	})
+
+	t.Run("CustomSketch", func(t *testing.T) {
+		pairs := Aggregator[Record, *HyperLogLog, int64]{
+			Initial: func() *HyperLogLog { return NewHyperLogLog(10) },
+			Accumulate: func(h *HyperLogLog, r Record) *HyperLogLog {
+				h.Add(fmt.Sprint(r["from"], "->", r["to"]))
+				return h
+			},
+			Finalize: (*HyperLogLog).Estimate,
+		}
+		routes := []Record{{"from": "a", "to": "b"}, {"from": "b", "to": "a"}, {"from": "a", "to": "b"}}
+		result, err := Aggregates(FromSlice(routes), CustomSpec("routes", pairs))
+		if err != nil || result["routes"] != int64(2) {
+			t.Errorf("Expected 2 distinct routes, got %v (%v)", result, err)
+		}
+	})
+}
+
+// TestByFieldAggregates tests the one-shot field aggregates
+func TestByFieldAggregates(t *testing.T) {
+	records := func() Stream[Record] {
+		return FromSlice([]Record{
+			{"amount": 10.0},
+			{"amount": int64(30)},
+			{"other": 1.0},
+			{"amount": nil},
+			{"amount": "20"},
+			{"amount": "n/a"},
+		})
+	}
+
+	t.Run("SumAvgMinMax", func(t *testing.T) {
+		if sum, err := SumByField[float64](records(), "amount"); err != nil || sum != 60 {
+			t.Errorf("Expected sum 60, got %v (%v)", sum, err)
+		}
+		if avg, err := AvgByField(records(), "amount"); err != nil || avg != 20 {
+			t.Errorf("Expected average 20, got %v (%v)", avg, err)
+		}
+		if lo, err := MinByField[float64](records(), "amount"); err != nil || lo != 10 {
+			t.Errorf("Expected min 10, got %v (%v)", lo, err)
+		}
+		if hi, err := MaxByField[int64](records(), "amount"); err != nil || hi != 30 {
+			t.Errorf("Expected max 30, got %v (%v)", hi, err)
+		}
+	})
+
+	t.Run("CountByField", func(t *testing.T) {
+		if count, err := CountByField(records(), "amount"); err != nil || count != 4 {
+			t.Errorf("Expected 4 present values, got %v (%v)", count, err)
+		}
+	})
+
+	t.Run("StatsByField", func(t *testing.T) {
+		stats, err := StatsByField(records(), "amount")
+		if err != nil {
+			t.Fatalf("StatsByField failed: %v", err)
+		}
+		expected := Record{"count": int64(3), "sum": 60.0, "avg": 20.0, "min": 10.0, "max": 30.0, "missing": int64(3)}
+		if !stats.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, stats)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		if avg, err := AvgByField(FromSlice([]Record{{"other": 1}}), "amount"); err != nil || avg != 0 {
+			t.Errorf("Expected average 0, got %v (%v)", avg, err)
+		}
+		stats, _ := StatsByField(FromSlice([]Record{}), "amount")
+		if _, ok := stats["min"]; ok || stats["count"] != int64(0) {
+			t.Errorf("Expected no min for an empty stream, got %v", stats)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		failure := errors.New("boom")
+		source := func() (Record, error) { return nil, failure }
+		if _, err := StatsByField(source, "amount"); !errors.Is(err, failure) {
+			t.Errorf("Expected the stream error, got %v", err)
+		}
+		if _, err := SumByField[int64](source, "amount"); !errors.Is(err, failure) {
+			t.Errorf("Expected the stream error, got %v", err)
+		}
+	})
+}
+
+// TestAggregatesSinglePass tests that Aggregates reads its input once
+func TestAggregatesSinglePass(t *testing.T) {
+	const n = 100_000
+	pulls := 0
+	source := func() Stream[Record] {
+		i := int64(0)
+		return func() (Record, error) {
+			pulls++
+			if i >= n {
+				return nil, EOS
+			}
+			i++
+			return Record{"amount": i, "group": i % 10}, nil
+		}
+	}
+
+	result, err := Aggregates(source(),
+		SumField[int64]("total", "amount"),
+		CountField("count", "amount"),
+		MinField[int64]("lo", "amount"),
+		MaxField[int64]("hi", "amount"),
+		CountDistinctField("groups", "group"),
+	)
+	if err != nil {
+		t.Fatalf("Aggregates failed: %v", err)
+	}
+	expected := Record{"total": int64(n * (n + 1) / 2), "count": int64(n), "lo": int64(1), "hi": int64(n), "groups": int64(10)}
+	if !result.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+	if pulls != n+1 {
+		t.Errorf("Expected the source to be pulled %d times, got %d", n+1, pulls)
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		failure := errors.New("boom")
+		failing := func() (Record, error) { return nil, failure }
+		if _, err := Aggregates(Stream[Record](failing), SumField[int64]("total", "amount"), CountField("count", "amount")); !errors.Is(err, failure) {
+			t.Errorf("Expected the stream error, got %v", err)
+		}
+		if _, _, _, err := AggregateTriple(Stream[Record](failing), SumAggregatorField[int64]("amount"),
+			CountAggregatorField("amount"), MaxAggregatorField[int64]("amount")); !errors.Is(err, failure) {
+			t.Errorf("Expected the stream error, got %v", err)
+		}
+	})
+}
+
+// BenchmarkAggregates compares running five aggregators by reading Tee branches one
+// after another, by reading them concurrently, and in a single pass as Aggregates does
+func BenchmarkAggregates(b *testing.B) {
+	const n = 1_000_000
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = Record{"amount": int64(i), "group": int64(i % 10)}
+	}
+	specs := []AggregatorSpec[Record]{
+		SumField[int64]("total", "amount"),
+		CountField("count", "amount"),
+		MinField[int64]("lo", "amount"),
+		MaxField[int64]("hi", "amount"),
+		AvgField[int64]("avg", "amount"),
+	}
+
+	b.Run("TeeSequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			branches := Tee(FromRecordsUnsafe(records), len(specs))
+			for j, spec := range specs {
+				if _, err := spec.Agg.(AggregatorRunner[Record]).RunOn(branches[j]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("TeeConcurrent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			branches := TeeWithOptions(FromRecordsUnsafe(records), len(specs), WithTeeBuffer(1024, TeeBlock))
+			errs := make(chan error, len(specs))
+			for j, spec := range specs {
+				go func() {
+					_, err := spec.Agg.(AggregatorRunner[Record]).RunOn(branches[j])
+					errs <- err
+				}()
+			}
+			for range specs {
+				if err := <-errs; err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("SinglePass", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Aggregates(FromRecordsUnsafe(records), specs...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestNumericSafety tests overflow detection and NaN policies
+func TestNumericSafety(t *testing.T) {
+	t.Run("SumCheckedOverflow", func(t *testing.T) {
+		if _, err := SumChecked(FromSlice([]int64{math.MaxInt64 - 1, 1, 1})); !errors.Is(err, ErrOverflow) {
+			t.Errorf("Expected ErrOverflow past MaxInt64, got %v", err)
+		}
+		if _, err := SumChecked(FromSlice([]int64{math.MinInt64, -1})); !errors.Is(err, ErrOverflow) {
+			t.Errorf("Expected ErrOverflow past MinInt64, got %v", err)
+		}
+		if _, err := SumChecked(FromSlice([]uint8{200, 100})); !errors.Is(err, ErrOverflow) {
+			t.Errorf("Expected ErrOverflow past MaxUint8, got %v", err)
+		}
+		if _, err := SumChecked(FromSlice([]float64{math.MaxFloat64, math.MaxFloat64})); !errors.Is(err, ErrOverflow) {
+			t.Errorf("Expected ErrOverflow for a float sum reaching infinity, got %v", err)
+		}
+		if sum, err := SumChecked(FromSlice([]int64{math.MaxInt64, -1, 1})); err != nil || sum != math.MaxInt64 {
+			t.Errorf("Expected MaxInt64 without error, got %v (%v)", sum, err)
+		}
+		if sum, _ := Sum(FromSlice([]int64{math.MaxInt64, 1})); sum != math.MinInt64 {
+			t.Errorf("Expected Sum to keep wrapping, got %v", sum)
+		}
+	})
+
+	values := []float64{1, math.NaN(), 3, math.Inf(1)}
+
+	t.Run("Avg", func(t *testing.T) {
+		if avg, err := Avg(FromSlice(values)); err != nil || !math.IsNaN(avg) {
+			t.Errorf("Expected NaN to propagate by default, got %v (%v)", avg, err)
+		}
+		var skipped int64
+		if avg, err := Avg(FromSlice(values), WithNaNPolicy(NaNSkip), WithSkippedCount(&skipped)); err != nil || avg != 2 || skipped != 2 {
+			t.Errorf("Expected average 2 with 2 skipped, got %v with %d (%v)", avg, skipped, err)
+		}
+		if _, err := Avg(FromSlice(values), WithNaNPolicy(NaNError)); !errors.Is(err, ErrNaN) {
+			t.Errorf("Expected ErrNaN, got %v", err)
+		}
+	})
+
+	t.Run("StreamingAvg", func(t *testing.T) {
+		averages, _ := Collect(StreamingAvg[float64]()(FromSlice(values)))
+		if len(averages) != 4 || !math.IsNaN(averages[3]) {
+			t.Errorf("Expected NaN to propagate by default, got %v", averages)
+		}
+		averages, _ = Collect(StreamingAvg[float64](WithNaNPolicy(NaNSkip))(FromSlice(values)))
+		if fmt.Sprint(averages) != "[1 2]" {
+			t.Errorf("Expected [1 2], got %v", averages)
+		}
+		averages, err := Collect(StreamingAvg[float64](WithNaNPolicy(NaNError))(FromSlice(values)))
+		if !errors.Is(err, ErrNaN) || fmt.Sprint(averages) != "[1]" {
+			t.Errorf("Expected [1] then ErrNaN, got %v (%v)", averages, err)
+		}
+	})
+
+	t.Run("StreamingStats", func(t *testing.T) {
+		stats, _ := Collect(StreamingStats[float64]()(FromSlice(values)))
+		if _, ok := stats[0]["skipped"]; ok || !math.IsNaN(stats[1]["sum"].(float64)) {
+			t.Errorf("Expected NaN to propagate by default, got %v", stats)
+		}
+		stats, _ = Collect(StreamingStats[float64](WithNaNPolicy(NaNSkip))(FromSlice(values)))
+		final := stats[len(stats)-1]
+		if len(stats) != 2 || final["sum"] != 4.0 || final["skipped"] != int64(1) {
+			t.Errorf("Expected sum 4 with the NaN skipped, got %v", stats)
+		}
+		if _, err := Collect(StreamingStats[float64](WithNaNPolicy(NaNError))(FromSlice(values))); !errors.Is(err, ErrNaN) {
+			t.Errorf("Expected ErrNaN, got %v", err)
+		}
+	})
+
+	t.Run("FieldSpecs", func(t *testing.T) {
+		records := func() Stream[Record] {
+			return FromSlice([]Record{
+				{"region": "eu", "amount": 1.0},
+				{"region": "eu", "amount": math.NaN()},
+				{"region": "us", "amount": 3.0},
+			})
+		}
+
+		result, err := Aggregates(records(), SumField[float64]("total", "amount"))
+		if err != nil || !math.IsNaN(result["total"].(float64)) {
+			t.Errorf("Expected NaN to propagate by default, got %v (%v)", result, err)
+		}
+
+		var skipped int64
+		result, err = Aggregates(records(),
+			SumField[float64]("total", "amount", WithNaNPolicy(NaNSkip), WithSkippedCount(&skipped)),
+			AvgField[float64]("avg", "amount", WithNaNPolicy(NaNSkip)),
+			MaxField[float64]("max", "amount", WithNaNPolicy(NaNSkip)),
+		)
+		if err != nil || result["total"] != 4.0 || result["avg"] != 2.0 || result["max"] != 3.0 || skipped != 1 {
+			t.Errorf("Expected total 4, avg 2, max 3 with 1 skipped, got %v with %d (%v)", result, skipped, err)
+		}
+
+		_, err = Aggregates(records(), StdDevField("spread", "amount", WithNaNPolicy(NaNError)))
+		if !errors.Is(err, ErrNaN) || !strings.Contains(err.Error(), "spread") {
+			t.Errorf("Expected ErrNaN naming the aggregator, got %v", err)
+		}
+		_, err = Collect(GroupByStreaming([]string{"region"}, SumField[float64]("total", "amount", WithNaNPolicy(NaNError)))(records()))
+		if !errors.Is(err, ErrNaN) {
+			t.Errorf("Expected ErrNaN from GroupByStreaming, got %v", err)
+		}
+	})
+}
+
+// TestTimeAggregates tests earliest and latest time aggregation
+func TestTimeAggregates(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("MinMaxTime", func(t *testing.T) {
+		times := []time.Time{base, base.Add(-time.Hour), base.Add(time.Hour)}
+		if earliest, err := MinTime(FromSlice(times)); err != nil || !earliest.Equal(base.Add(-time.Hour)) {
+			t.Errorf("Expected earliest %v, got %v (%v)", base.Add(-time.Hour), earliest, err)
+		}
+		if latest, err := MaxTime(FromSlice(times)); err != nil || !latest.Equal(base.Add(time.Hour)) {
+			t.Errorf("Expected latest %v, got %v (%v)", base.Add(time.Hour), latest, err)
+		}
+		if earliest, _ := MinTime(FromSlice([]time.Time{})); !earliest.IsZero() {
+			t.Errorf("Expected the zero time for an empty stream, got %v", earliest)
+		}
+	})
+
+	t.Run("GroupByWithStringTimestamps", func(t *testing.T) {
+		tokyo := time.FixedZone("JST", 9*60*60)
+		events := []Record{
+			{"user": "alice", "ts": base},
+			{"user": "bob", "ts": base.Add(30 * time.Minute).Format(time.RFC3339)},
+			{"user": "alice", "ts": base.Add(-2 * time.Hour).Format(time.RFC3339)},
+			{"user": "alice", "ts": base.Add(time.Hour).In(tokyo)},
+			{"user": "bob", "ts": base.Add(-time.Minute)},
+			{"user": "bob", "ts": "not a time"},
+			{"user": "bob"},
+		}
+
+		results, err := Collect(GroupByStreaming([]string{"user"},
+			MinTimeField("first_seen", "ts"),
+			MaxTimeField("last_seen", "ts"),
+		)(FromSlice(events)))
+		if err != nil {
+			t.Fatalf("GroupBy failed: %v", err)
+		}
+
+		expected := map[string][2]time.Time{
+			"alice": {base.Add(-2 * time.Hour), base.Add(time.Hour)},
+			"bob":   {base.Add(-time.Minute), base.Add(30 * time.Minute)},
+		}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d groups, got %v", len(expected), results)
+		}
+		for _, result := range results {
+			want := expected[result["user"].(string)]
+			first, _ := result["first_seen"].(time.Time)
+			last, _ := result["last_seen"].(time.Time)
+			if !first.Equal(want[0]) || !last.Equal(want[1]) {
+				t.Errorf("Expected %v first seen %v and last seen %v, got %v", result["user"], want[0], want[1], result)
+			}
+		}
+	})
+}
+
+// TestWithGroupRecords tests that groups carry their records as a stream field alongside the aggregates
+func TestWithGroupRecords(t *testing.T) {
+	employees := func() Stream[Record] {
+		return FromSlice([]Record{
+			{"name": "Alice", "department": "eng", "salary": int64(100)},
+			{"name": "Bob", "department": "sales", "salary": int64(80)},
+			{"name": "Carol", "department": "eng", "salary": int64(120)},
+			{"name": "Dan", "department": "ops", "salary": int64(70)},
+			{"name": "Erin", "department": "eng", "salary": int64(90)},
+		})
+	}
+	groupings := map[string]func([]string, ...AggregatorSpec[Record]) Filter[Record, Record]{
+		"GroupBy":          GroupBy,
+		"GroupByStreaming": GroupByStreaming,
+	}
+
+	for name, groupBy := range groupings {
+		t.Run(name, func(t *testing.T) {
+			grouped, err := Collect(groupBy([]string{"department"},
+				SumField[int64]("payroll", "salary"),
+				WithGroupRecords("members"),
+			)(employees()))
+			if err != nil {
+				t.Fatalf("GroupBy failed: %v", err)
+			}
+			if len(grouped) != 3 {
+				t.Fatalf("Expected 3 groups, got %d", len(grouped))
+			}
+			for _, group := range grouped {
+				if group["department"] == "eng" && group["payroll"] != int64(310) {
+					t.Errorf("Expected eng payroll 310, got %v", group["payroll"])
+				}
+			}
+
+			flattened, err := Collect(CrossFlatten(".", "members")(FromSlice(grouped)))
+			if err != nil {
+				t.Fatalf("CrossFlatten failed: %v", err)
+			}
+			if len(flattened) != 5 {
+				t.Fatalf("Expected the 5 original records back, got %d: %v", len(flattened), flattened)
+			}
+			var eng []string
+			for _, r := range flattened {
+				member, ok := r["members"].(Record)
+				if !ok || r["department"] != member["department"] {
+					t.Errorf("Expected each member under its own group, got %v", r)
+				}
+				if r["department"] == "eng" {
+					if r["payroll"] != int64(310) {
+						t.Errorf("Expected the group's aggregates on each member, got %v", r)
+					}
+					eng = append(eng, member["name"].(string))
+				}
+			}
+			if strings.Join(eng, ",") != "Alice,Carol,Erin" {
+				t.Errorf("Expected eng members in input order, got %v", eng)
+			}
+		})
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		grouped := GroupByStreaming([]string{"department"}, CountField("n", "name"), WithGroupRecords("members"))(employees())
+		var buf bytes.Buffer
+		if err := NewJSONSink(&buf).WriteStream(Where(func(r Record) bool { return r["department"] == "sales" })(grouped)); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+		expected := `{"department":"sales","members":[{"department":"sales","name":"Bob","salary":80}],"n":1}`
+		if strings.TrimSpace(buf.String()) != expected {
+			t.Errorf("Expected %s, got %s", expected, buf.String())
+		}
+	})
+}
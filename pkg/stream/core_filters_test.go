@@ -2,7 +2,12 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -188,6 +193,125 @@ func TestLimit(t *testing.T) {
 	})
 }
 
+// TestLimitVariants tests Take, LimitCount, LimitDuration and LimitUntil
+func TestLimitVariants(t *testing.T) {
+	t.Run("TakeAndLimitCount", func(t *testing.T) {
+		taken, _ := Collect(Take[int64](2)(FromSlice([]int64{1, 2, 3})))
+		counted, _ := Collect(LimitCount[int64](2)(FromSlice([]int64{1, 2, 3})))
+		if len(taken) != 2 || len(counted) != 2 {
+			t.Errorf("Expected 2 results from each, got %v and %v", taken, counted)
+		}
+	})
+
+	t.Run("LimitDurationInfiniteSource", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		i := int64(0)
+		infinite := Generate(func() (int64, error) {
+			i++
+			time.Sleep(time.Millisecond)
+			return i, nil
+		})
+
+		start := time.Now()
+		results, err := Collect(LimitDuration[int64](50 * time.Millisecond)(infinite))
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("Failed to collect stream: %v", err)
+		}
+		if len(results) == 0 {
+			t.Error("Expected some elements before the deadline")
+		}
+		if elapsed < 50*time.Millisecond || elapsed > 500*time.Millisecond {
+			t.Errorf("Expected stream to end shortly after 50ms, took %v", elapsed)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Goroutines leaked: %d -> %d", before, after)
+		}
+	})
+
+	t.Run("LimitUntilExclusive", func(t *testing.T) {
+		results, _ := Collect(LimitUntil(func(x int64) bool { return x > 2 }, false)(FromSlice([]int64{1, 2, 3, 4})))
+		if len(results) != 2 || results[1] != 2 {
+			t.Errorf("Expected [1 2], got %v", results)
+		}
+	})
+
+	t.Run("LimitUntilInclusive", func(t *testing.T) {
+		results, _ := Collect(LimitUntil(func(x int64) bool { return x > 2 }, true)(FromSlice([]int64{1, 2, 3, 4})))
+		if len(results) != 3 || results[2] != 3 {
+			t.Errorf("Expected [1 2 3], got %v", results)
+		}
+	})
+
+	t.Run("LimitUntilNeverMatches", func(t *testing.T) {
+		results, _ := Collect(LimitUntil(func(x int64) bool { return false }, true)(FromSlice([]int64{1, 2})))
+		if len(results) != 2 {
+			t.Errorf("Expected all elements, got %v", results)
+		}
+	})
+}
+
+// TestTakeWhileSkipWhile tests the predicate-based Limit/Offset counterparts
+func TestTakeWhileSkipWhile(t *testing.T) {
+	below3 := func(x int64) bool { return x < 3 }
+
+	t.Run("TakeWhile", func(t *testing.T) {
+		results, err := Collect(TakeWhile(below3)(FromSlice([]int64{1, 2, 3, 1, 2})))
+		if err != nil {
+			t.Fatalf("Failed to collect stream: %v", err)
+		}
+		if len(results) != 2 || results[0] != 1 || results[1] != 2 {
+			t.Errorf("Expected [1 2], got %v", results)
+		}
+	})
+
+	t.Run("TakeWhileConsumesFailingElement", func(t *testing.T) {
+		input := FromSlice([]int64{1, 2, 3, 4, 5})
+		taken, _ := Collect(TakeWhile(below3)(input))
+
+		// The 3 was pulled to test the predicate, so the shared input resumes at 4
+		rest, _ := Collect(input)
+		if len(taken) != 2 || len(rest) != 2 || rest[0] != 4 {
+			t.Errorf("Expected [1 2] then [4 5], got %v then %v", taken, rest)
+		}
+	})
+
+	t.Run("SkipWhile", func(t *testing.T) {
+		results, err := Collect(SkipWhile(below3)(FromSlice([]int64{1, 2, 3, 1, 2})))
+		if err != nil {
+			t.Fatalf("Failed to collect stream: %v", err)
+		}
+		expected := []int64{3, 1, 2}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, results)
+		}
+		for i := range expected {
+			if results[i] != expected[i] {
+				t.Errorf("Expected %v at position %d, got %v", expected[i], i, results[i])
+			}
+		}
+	})
+
+	t.Run("SkipWhileAllMatch", func(t *testing.T) {
+		results, err := Collect(SkipWhile(below3)(FromSlice([]int64{1, 2})))
+		if err != nil || len(results) != 0 {
+			t.Errorf("Expected empty stream, got %v (%v)", results, err)
+		}
+	})
+
+	t.Run("PropagatesErrors", func(t *testing.T) {
+		failure := fmt.Errorf("read failed")
+		failing := func() (int64, error) { return 0, failure }
+
+		if _, err := TakeWhile(below3)(failing)(); err != failure {
+			t.Errorf("TakeWhile: expected source error, got %v", err)
+		}
+		if _, err := SkipWhile(below3)(failing)(); err != failure {
+			t.Errorf("SkipWhile: expected source error, got %v", err)
+		}
+	})
+}
+
 // TestOffset tests the Offset filter (equivalent to SQL OFFSET)
 func TestOffset(t *testing.T) {
 	t.Run("OffsetFirst2", func(t *testing.T) {
@@ -337,6 +461,82 @@ func TestPipe3(t *testing.T) {
 	})
 }
 
+// TestPipe6 tests long typed pipelines and their run-time composed equivalent
+func TestPipe6(t *testing.T) {
+	records := func() Stream[Record] {
+		return FromRecordsUnsafe([]Record{
+			{"name": "Alice", "dept": "eng", "salary": int64(120)},
+			{"name": "Bob", "dept": "sales", "salary": int64(80)},
+			{"name": "Carol", "dept": "eng", "salary": int64(100)},
+			{"name": "Dan", "dept": "ops", "salary": int64(40)},
+			{"name": "Erin", "dept": "sales", "salary": int64(90)},
+		})
+	}
+	paid := Where(func(r Record) bool { return GetOr(r, "salary", int64(0)) >= 50 })
+	bonus := AddField("bonus", func(r Record) any { return GetOr(r, "salary", int64(0)) / 10 })
+	byDept := Split([]string{"dept"})
+	sorted := SortBy("name")
+	project := Select("name", "dept", "bonus")
+
+	t.Run("Pipe6MatchesComposeAny", func(t *testing.T) {
+		typed, err := Collect(Pipe6(
+			paid,
+			bonus,
+			byDept,
+			FlatMap(func(group Stream[Record]) Stream[Record] { return group }),
+			sorted,
+			project,
+		)(records()))
+		if err != nil {
+			t.Fatalf("Pipe6 failed: %v", err)
+		}
+
+		composed, err := ComposeAny(paid, bonus, Split([]string{"dept"}), sorted, project)
+		if err != nil {
+			t.Fatalf("ComposeAny failed: %v", err)
+		}
+		dynamic, err := Collect(composed(records()))
+		if err != nil {
+			t.Fatalf("Composed pipeline failed: %v", err)
+		}
+
+		if len(typed) != 4 || len(dynamic) != len(typed) {
+			t.Fatalf("Expected 4 results from both, got %v and %v", typed, dynamic)
+		}
+		for i := range typed {
+			if !typed[i].Equal(dynamic[i]) {
+				t.Errorf("Result %d: Pipe6 gave %v, ComposeAny gave %v", i, typed[i], dynamic[i])
+			}
+		}
+	})
+
+	t.Run("Pipe8", func(t *testing.T) {
+		inc := Map(func(x int64) int64 { return x + 1 })
+		results, _ := Collect(Pipe8(inc, inc, inc, inc, inc, inc, inc,
+			Map(func(x int64) string { return fmt.Sprint(x) }))(FromSlice([]int64{0, 10})))
+		if fmt.Sprintf("%q", results) != `["7" "17"]` {
+			t.Errorf("Expected [\"7\" \"17\"], got %q", results)
+		}
+	})
+
+	t.Run("ComposeAnyRejectsMistypedStage", func(t *testing.T) {
+		_, err := ComposeAny(paid, Map(func(x int64) int64 { return x }), project)
+		if err == nil {
+			t.Fatal("Expected an error for a Filter[int64, int64] stage")
+		}
+		if !strings.Contains(err.Error(), "stage 2") || !strings.Contains(err.Error(), "int64") {
+			t.Errorf("Expected the error to name stage 2 and its type, got %v", err)
+		}
+
+		if _, err := ComposeAny(paid, nil); err == nil {
+			t.Error("Expected an error for a nil stage")
+		}
+		if _, err := ComposeAny(paid, Filter[Record, Record](nil)); err == nil {
+			t.Error("Expected an error for a nil typed stage")
+		}
+	})
+}
+
 // TestChain tests the Chain function
 func TestChain(t *testing.T) {
 	t.Run("MultipleFilters", func(t *testing.T) {
@@ -436,6 +636,74 @@ func TestSelect(t *testing.T) {
 	})
 }
 
+// TestSelectPattern tests SelectPrefix, SelectPattern, DropPrefix and DropPattern
+func TestSelectPattern(t *testing.T) {
+	customers := []Record{{"id": int64(1), "cust": Record{"name": "Alice", "tier": "gold"}}}
+	orders := []Record{{"id": int64(1), "order": Record{"id": "o-9", "total": 12.5, "note": "gift"}}}
+	joined := func() Stream[Record] {
+		return DotFlatten(".")(InnerJoin(FromRecordsUnsafe(orders), "id", "id")(FromRecordsUnsafe(customers)))
+	}
+	
+	t.Run("PatternPlusNamedField", func(t *testing.T) {
+		results, err := Collect(SelectPattern("cust.*", "order.id")(joined()))
+		if err != nil {
+			t.Fatalf("Failed to collect records: %v", err)
+		}
+		
+		expected := Record{"cust.name": "Alice", "cust.tier": "gold", "order.id": "o-9"}
+		if len(results) != 1 || !reflect.DeepEqual(results[0], expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
+	
+	t.Run("WildcardPositions", func(t *testing.T) {
+		results, _ := Collect(SelectPattern("*.id", "order.*t*")(joined()))
+		
+		// The join prefixes the shared key as left.id/right.id, which *.id also matches
+		expected := Record{"left.id": int64(1), "right.id": int64(1), "order.id": "o-9", "order.total": 12.5, "order.note": "gift"}
+		if !reflect.DeepEqual(results[0], expected) {
+			t.Errorf("Expected %v, got %v", expected, results[0])
+		}
+	})
+	
+	t.Run("Prefix", func(t *testing.T) {
+		results, _ := Collect(SelectPrefix("cust.")(joined()))
+		if !reflect.DeepEqual(results[0], Record{"cust.name": "Alice", "cust.tier": "gold"}) {
+			t.Errorf("Expected only cust.* fields, got %v", results[0])
+		}
+		
+		results, _ = Collect(DropPrefix("cust.", "order.")(joined()))
+		for field := range results[0] {
+			if strings.HasPrefix(field, "cust.") || strings.HasPrefix(field, "order.") {
+				t.Errorf("Expected %s to be dropped", field)
+			}
+		}
+	})
+	
+	t.Run("DropPattern", func(t *testing.T) {
+		results, _ := Collect(DropPattern("*.note", "cust.*")(joined()))
+		
+		for _, field := range []string{"order.note", "cust.name", "cust.tier"} {
+			if _, exists := results[0][field]; exists {
+				t.Errorf("Expected %s to be dropped", field)
+			}
+		}
+		if results[0]["order.total"] != 12.5 {
+			t.Errorf("Expected order.total to be kept, got %v", results[0])
+		}
+	})
+	
+	t.Run("NoMatchSelectsNothing", func(t *testing.T) {
+		results, err := Collect(SelectPattern("missing.*", "nope")(joined()))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(results) != 1 || len(results[0]) != 0 {
+			t.Errorf("Expected one empty record, got %v", results)
+		}
+	})
+}
+
 // TestUpdate tests the Update filter
 func TestUpdate(t *testing.T) {
 	t.Run("AddField", func(t *testing.T) {
@@ -500,6 +768,131 @@ func TestUpdate(t *testing.T) {
 			}
 		}
 	})
+	
+	t.Run("InPlaceMutationIsShared", func(t *testing.T) {
+		records := []Record{NewRecord().String("name", "alice").Build()}
+		
+		// Update hands fn the input record itself, so in-place edits leak back to the source
+		_, err := Collect(Update(func(r Record) Record {
+			r["name"] = "changed"
+			return r
+		})(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Failed to collect updated records: %v", err)
+		}
+		if records[0]["name"] != "changed" {
+			t.Errorf("Expected in-place mutation to reach the input record, got %v", records[0]["name"])
+		}
+	})
+}
+
+// TestRenameFields tests the RenameFields filter
+func TestRenameFields(t *testing.T) {
+	t.Run("RenameAndKeepOthers", func(t *testing.T) {
+		input := NewRecord().String("fname", "Alice").Int("age", 30).Build()
+		
+		results, err := Collect(RenameFields(map[string]string{"fname": "name"})(FromRecordsUnsafe([]Record{input})))
+		if err != nil {
+			t.Fatalf("Failed to collect renamed records: %v", err)
+		}
+		
+		expected := Record{"name": "Alice", "age": int64(30)}
+		if !reflect.DeepEqual(results[0], expected) {
+			t.Errorf("Expected %v, got %v", expected, results[0])
+		}
+		if _, exists := input["name"]; exists || input["fname"] != "Alice" {
+			t.Errorf("Input record should not be modified, got %v", input)
+		}
+	})
+	
+	t.Run("OverwriteAndSwap", func(t *testing.T) {
+		input := Record{"a": 1, "b": 2, "c": 3}
+		
+		results, _ := Collect(RenameFields(map[string]string{"a": "b", "b": "a", "c": "a"})(FromRecordsUnsafe([]Record{input})))
+		
+		// a→b and b→a swap; c→a is applied after b→a in sorted order and wins
+		expected := Record{"a": 3, "b": 1}
+		if !reflect.DeepEqual(results[0], expected) {
+			t.Errorf("Expected %v, got %v", expected, results[0])
+		}
+	})
+	
+	t.Run("MissingSourceField", func(t *testing.T) {
+		input := Record{"name": "Alice"}
+		
+		results, _ := Collect(RenameFields(map[string]string{"email": "name"})(FromRecordsUnsafe([]Record{input})))
+		
+		if !reflect.DeepEqual(results[0], input) {
+			t.Errorf("Expected missing source to leave record unchanged, got %v", results[0])
+		}
+	})
+}
+
+// TestDropFields tests the DropFields filter
+func TestDropFields(t *testing.T) {
+	input := Record{"name": "Alice", "ssn": "123", "age": 30}
+	
+	results, err := Collect(DropFields("ssn", "missing")(FromRecordsUnsafe([]Record{input})))
+	if err != nil {
+		t.Fatalf("Failed to collect records: %v", err)
+	}
+	
+	expected := Record{"name": "Alice", "age": 30}
+	if !reflect.DeepEqual(results[0], expected) {
+		t.Errorf("Expected %v, got %v", expected, results[0])
+	}
+	if _, exists := input["ssn"]; !exists {
+		t.Errorf("Input record should not be modified")
+	}
+}
+
+// TestAddField tests the AddField filter
+func TestAddField(t *testing.T) {
+	t.Run("ComputedField", func(t *testing.T) {
+		input := NewRecord().Float("price", 2.5).Int("qty", 4).Build()
+		
+		results, err := Collect(AddField("total", func(r Record) any {
+			return GetOr(r, "price", 0.0) * float64(GetOr(r, "qty", int64(0)))
+		})(FromRecordsUnsafe([]Record{input})))
+		if err != nil {
+			t.Fatalf("Failed to collect records: %v", err)
+		}
+		
+		if results[0]["total"] != 10.0 {
+			t.Errorf("Expected total=10, got %v", results[0]["total"])
+		}
+		if _, exists := input["total"]; exists {
+			t.Errorf("Input record should not be modified")
+		}
+	})
+	
+	t.Run("ChainedInPipe3", func(t *testing.T) {
+		records := []Record{
+			{"item": "apple", "price": 1.5, "qty": int64(2), "internal": true},
+			{"item": "pear", "price": 2.0, "qty": int64(3), "internal": false},
+		}
+		
+		pipeline := Pipe3(
+			AddField("total", func(r Record) any {
+				return GetOr(r, "price", 0.0) * float64(GetOr(r, "qty", int64(0)))
+			}),
+			RenameFields(map[string]string{"item": "name", "total": "price"}),
+			DropFields("internal", "qty"),
+		)
+		
+		results, err := Collect(pipeline(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Failed to collect records: %v", err)
+		}
+		
+		expected := []Record{
+			{"name": "apple", "price": 3.0},
+			{"name": "pear", "price": 6.0},
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
 }
 
 // TestExtractField tests the ExtractField function
@@ -555,6 +948,81 @@ func TestExtractField(t *testing.T) {
 	})
 }
 
+// TestExtractFieldStrict tests strict field extraction and the field access helpers behind it
+func TestExtractFieldStrict(t *testing.T) {
+	records := []Record{
+		{"id": int64(1), "amount": 2.5},
+		{"id": int64(2), "amount": "3"},
+		{"id": int64(3), "amount": "n/a"},
+		{"id": int64(4)},
+	}
+
+	t.Run("Unconvertible", func(t *testing.T) {
+		results, err := Collect(ExtractFieldStrict[float64]("amount")(FromRecordsUnsafe(records)))
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Missing || fieldErr.Value != "n/a" || fieldErr.Type != "float64" {
+			t.Fatalf("Expected a *FieldError for the value n/a, got %v", err)
+		}
+		if want := `record 2: field "amount": cannot convert string n/a to float64`; err.Error() != want {
+			t.Errorf("Expected %q, got %q", want, err.Error())
+		}
+		if len(results) != 2 || results[0] != 2.5 || results[1] != 3.0 {
+			t.Errorf("Expected 2.5 and 3 before the error, got %v", results)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		stream := ExtractFieldStrict[float64]("amout")(FromRecordsUnsafe(records))
+		_, err := stream()
+		if want := `record 0: field "amout" not found (record has amount, id)`; err == nil || err.Error() != want {
+			t.Errorf("Expected %q, got %v", want, err)
+		}
+		if _, again := stream(); again != err {
+			t.Errorf("Expected the error to be sticky, got %v", again)
+		}
+	})
+
+	t.Run("LenientUnchanged", func(t *testing.T) {
+		results, err := Collect(ExtractField[float64]("amount")(FromRecordsUnsafe(records)))
+		if err != nil || !reflect.DeepEqual(results, []float64{2.5, 3, 0, 0}) {
+			t.Errorf("Expected zero values for bad and missing fields, got %v (%v)", results, err)
+		}
+	})
+
+	t.Run("MustGet", func(t *testing.T) {
+		if MustGet[int64](records[0], "id") != 1 {
+			t.Error("Expected MustGet to return the value")
+		}
+		defer func() {
+			err, ok := recover().(*FieldError)
+			if !ok || !err.Missing || err.Field != "amout" {
+				t.Errorf("Expected a panic with a *FieldError, got %v", err)
+			}
+		}()
+		MustGet[float64](records[0], "amout")
+	})
+
+	t.Run("LintFields", func(t *testing.T) {
+		var warnings []string
+		warn := func(field string, seen []string) {
+			warnings = append(warnings, fmt.Sprintf("%s %v", field, seen))
+		}
+		results, err := Collect(LintFields([]string{"id", "amout", "note"}, 2, warn)(FromRecordsUnsafe(records)))
+		if err != nil || len(results) != len(records) {
+			t.Fatalf("Expected records passed through, got %d (%v)", len(results), err)
+		}
+		if want := []string{"amout [amount id]", "note [amount id]"}; !reflect.DeepEqual(warnings, want) {
+			t.Errorf("Expected warnings %v, got %v", want, warnings)
+		}
+
+		warnings = nil
+		Collect(LintFields([]string{"id", "missing"}, 100, warn)(FromRecordsUnsafe(records[:1])))
+		if len(warnings) != 1 {
+			t.Errorf("Expected a warning at the end of a stream shorter than the sample, got %v", warnings)
+		}
+	})
+}
+
 // TestWithContext tests the WithContext function
 func TestWithContext(t *testing.T) {
 	t.Run("ContextCancellation", func(t *testing.T) {
@@ -576,6 +1044,286 @@ func TestWithContext(t *testing.T) {
 	})
 }
 
+// boobyTrapped yields records then EOS, and panics if it is pulled again after EOS
+func boobyTrapped(records []Record) Stream[Record] {
+	i := 0
+	return func() (Record, error) {
+		if i > len(records) {
+			panic("pulled after EOS")
+		}
+		i++
+		if i > len(records) {
+			return nil, EOS
+		}
+		return records[i-1], nil
+	}
+}
+
+// drainThenRepull reads stream to EOS, then checks that further pulls keep returning EOS
+func drainThenRepull[U any](t *testing.T, stream Stream[U]) {
+	t.Helper()
+	if _, err := Collect(stream); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := stream(); err != EOS {
+			t.Fatalf("Expected EOS on extra pull %d, got %v", i+1, err)
+		}
+	}
+}
+
+// TestFuse tests the post-EOS contract of Fuse and the operators that apply it
+func TestFuse(t *testing.T) {
+	t.Run("LatchesFirstError", func(t *testing.T) {
+		failure := errors.New("boom")
+		calls := 0
+		stream := Fuse(func() (int64, error) {
+			calls++
+			if calls == 1 {
+				return 1, nil
+			}
+			if calls == 2 {
+				return 0, failure
+			}
+			return 0, EOS // A source that would change its mind if pulled again
+		})
+		if item, err := stream(); item != 1 || err != nil {
+			t.Fatalf("Expected 1, got %v (%v)", item, err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := stream(); err != failure {
+				t.Errorf("Expected the first error every time, got %v", err)
+			}
+		}
+		if calls != 2 {
+			t.Errorf("Expected the source to be called twice, got %d", calls)
+		}
+
+		drainThenRepull(t, Fuse(boobyTrapped([]Record{{"id": int64(1)}})))
+	})
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := func() []Record {
+		out := make([]Record, 6)
+		for i := range out {
+			out[i] = Record{
+				"id":   int64(i % 3),
+				"ts":   base.Add(time.Duration(i) * time.Second),
+				"tags": FromSlice([]string{"a", "b"}),
+			}
+		}
+		return out
+	}
+	right := func() Stream[Record] {
+		return FromRecordsUnsafe([]Record{{"id": int64(0), "name": "zero"}, {"id": int64(1), "name": "one"}})
+	}
+	eventTime := func() EventTimeWindowOption {
+		return WithTimestampExtractor(NewRecordTimestampExtractor("ts"))
+	}
+
+	operators := map[string]func(t *testing.T, input Stream[Record]){
+		"FlatMap": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, FlatMap(func(r Record) Stream[Record] {
+				return FromRecordsUnsafe([]Record{r, r})
+			})(input))
+		},
+		"CrossFlatten": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, CrossFlatten(".", "tags")(input))
+		},
+		"InnerJoin": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, InnerJoin(right(), "id", "id")(input))
+		},
+		"FullJoin": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, FullJoin(right(), "id", "id")(input))
+		},
+		"CrossJoin": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, CrossJoin(right())(input))
+		},
+		"CountWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, CountWindow[Record](4)(input))
+		},
+		"SlidingCountWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, SlidingCountWindow[Record](3, 2)(input))
+		},
+		"Chunk": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, Chunk[Record](4)(input))
+		},
+		"ChunkBy": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, ChunkBy(func(r Record) any { return r["id"] })(input))
+		},
+		"TimeWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, TimeWindow[Record](time.Hour)(input))
+		},
+		"SlidingTimeWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, SlidingTimeWindow[Record](time.Hour, 30*time.Minute)(input))
+		},
+		"SessionGapWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, SessionGapWindow[Record](time.Hour)(input))
+		},
+		"TriggeredWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, TriggeredWindow[Record](NewCountTrigger[Record](4))(input))
+		},
+		"EventTimeTumblingWindowResults": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, EventTimeTumblingWindowResults(2*time.Second, eventTime())(input))
+		},
+		"EventTimeSessionWindowResults": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, EventTimeSessionWindowResults(time.Second, eventTime())(input))
+		},
+		"KeyedEventTimeTumblingWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, KeyedEventTimeTumblingWindow([]string{"id"}, 2*time.Second, eventTime())(input))
+		},
+		"SessionWindow": func(t *testing.T, input Stream[Record]) {
+			drainThenRepull(t, SessionWindow(time.Hour, func(Record) bool { return true })(input))
+		},
+	}
+	for name, run := range operators {
+		t.Run(name, func(t *testing.T) {
+			run(t, boobyTrapped(records()))
+		})
+	}
+}
+
+// errorWithin pulls stream in a goroutine, reporting the error if the pull returns within d
+func errorWithin[T any](stream Stream[T], d time.Duration) (error, bool) {
+	done := make(chan error, 1)
+	go func() {
+		_, err := stream()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err, true
+	case <-time.After(d):
+		return nil, false
+	}
+}
+
+// TestContextCancellation tests that cancellable sources and operators give up while blocked
+func TestContextCancellation(t *testing.T) {
+	t.Run("FromChannelContext", func(t *testing.T) {
+		never := make(chan int64) // Nothing is ever sent
+
+		// WithContext can't interrupt a pull already blocked on the channel
+		ctx, cancel := context.WithCancel(context.Background())
+		blocked := WithContext(ctx, FromChannel(never))
+		done := make(chan struct{})
+		go func() {
+			blocked()
+			close(done)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		select {
+		case <-done:
+			t.Fatal("Expected WithContext(FromChannel) to stay blocked after cancel")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		ctx, cancel = context.WithCancel(context.Background())
+		stream := FromChannelContext(ctx, never)
+		time.AfterFunc(20*time.Millisecond, cancel)
+		start := time.Now()
+		if err, returned := errorWithin(stream, time.Second); !returned || err != context.Canceled {
+			t.Fatalf("Expected context.Canceled promptly, got %v (returned %v)", err, returned)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("Expected a prompt return, took %v", elapsed)
+		}
+		if _, err := stream(); err != context.Canceled {
+			t.Errorf("Expected later pulls to return context.Canceled, got %v", err)
+		}
+
+		// Items and the end of the channel come through while ctx is live
+		ch := make(chan Record, 2)
+		ch <- Record{"id": int64(1)}
+		close(ch)
+		results, err := Collect(FromChannelAnyContext(context.Background(), ch))
+		if err != nil || len(results) != 1 {
+			t.Errorf("Expected 1 record then EOS, got %v (%v)", results, err)
+		}
+
+		close(never) // Release the goroutine blocked in WithContext
+		<-done
+	})
+
+	t.Run("GenerateContext", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		stream := GenerateContext(ctx, func(ctx context.Context) (int64, error) {
+			calls++
+			if calls <= 3 {
+				return int64(calls), nil
+			}
+			<-ctx.Done() // A blocking receive that honours ctx
+			return 0, errors.New("receive aborted")
+		})
+		time.AfterFunc(20*time.Millisecond, cancel)
+		results, err := Collect(stream)
+		if err != context.Canceled || len(results) != 3 {
+			t.Errorf("Expected 3 items then context.Canceled, got %v (%v)", results, err)
+		}
+		if _, err := stream(); err != context.Canceled || calls != 4 {
+			t.Errorf("Expected context.Canceled without calling the generator, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("ParallelContext", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+		defer close(release)
+		slow := ParallelContext(ctx, 2, func(x int64) int64 {
+			<-release // Workers never finish until the test ends
+			return x
+		})(FromSlice([]int64{1, 2, 3}))
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if err, returned := errorWithin(slow, time.Second); !returned || err != context.Canceled {
+			t.Fatalf("Expected context.Canceled promptly, got %v (returned %v)", err, returned)
+		}
+		if _, err := slow(); err != context.Canceled {
+			t.Errorf("Expected later pulls to return context.Canceled, got %v", err)
+		}
+
+		results, err := Collect(ParallelContext(context.Background(), 2, func(x int64) int64 { return x * 2 })(FromSlice([]int64{1, 2, 3})))
+		if err != nil || len(results) != 3 {
+			t.Errorf("Expected 3 results, got %v (%v)", results, err)
+		}
+	})
+
+	t.Run("TeeContext", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		branches := TeeWithOptions(Range(0, 100, 1), 2, WithTeeBuffer(1, TeeBlock), WithTeeContext(ctx))
+		if _, err := branches[0](); err != nil {
+			t.Fatalf("Failed to read the first item: %v", err)
+		}
+		// Branch 1 never reads, so branch 0 waits for it under TeeBlock
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if err, returned := errorWithin(branches[0], time.Second); !returned || err != context.Canceled {
+			t.Fatalf("Expected context.Canceled promptly, got %v (returned %v)", err, returned)
+		}
+		if _, err := branches[1](); err != context.Canceled {
+			t.Errorf("Expected the other branch to be stopped too, got %v", err)
+		}
+	})
+
+	t.Run("SplitContext", func(t *testing.T) {
+		records := []Record{{"k": "a"}, {"k": "a"}, {"k": "b"}, {"k": "b"}}
+		ctx, cancel := context.WithCancel(context.Background())
+		groups := SplitWithOptions([]string{"k"}, WithSplitBuffer(1, SplitBlock), WithSplitContext(ctx))(FromRecordsUnsafe(records))
+		first, err := groups()
+		if err != nil {
+			t.Fatalf("Failed to read the first group: %v", err)
+		}
+		// Group "a" holds a record nobody reads, so the next group waits for it
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if err, returned := errorWithin(groups, time.Second); !returned || err != context.Canceled {
+			t.Fatalf("Expected context.Canceled promptly, got %v (returned %v)", err, returned)
+		}
+		if _, err := first(); err != context.Canceled {
+			t.Errorf("Expected substreams to be stopped too, got %v", err)
+		}
+	})
+}
+
 // TestTee tests the Tee function
 func TestTee(t *testing.T) {
 	t.Run("TeeInto2", func(t *testing.T) {
@@ -615,30 +1363,187 @@ func TestTee(t *testing.T) {
 			}
 		}
 	})
-}
-
-// TestFlatMap tests the FlatMap function
-func TestFlatMap(t *testing.T) {
-	t.Run("IntToRange", func(t *testing.T) {
-		input := FromSlice([]int64{2, 3})
-		
-		// Each int becomes a range from 1 to that number
-		flattened := FlatMap(func(x int64) Stream[int64] {
-			return Range(1, x+1, 1)
-		})(input)
-		
-		results, err := Collect(flattened)
-		if err != nil {
-			t.Fatalf("Failed to collect flattened stream: %v", err)
+	
+	t.Run("SourceErrorReachesAllBranches", func(t *testing.T) {
+		boom := errors.New("boom")
+		calls := 0
+		input := func() (int64, error) {
+			calls++
+			if calls > 3 {
+				return 0, boom
+			}
+			return int64(calls), nil
 		}
 		
-		expected := []int64{1, 2, 1, 2, 3} // Range(1,3) + Range(1,4)
-		if len(results) != len(expected) {
-			t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+		streams := Tee[int64](input, 2)
+		for i, branch := range streams {
+			results, err := Collect(branch)
+			if err != boom || len(results) != 3 {
+				t.Errorf("Branch %d: expected 3 items then boom, got %v, %v", i, results, err)
+			}
 		}
-		
-		for i, result := range results {
-			if result != expected[i] {
+	})
+	
+	t.Run("ConcurrentBranches", func(t *testing.T) {
+		streams := Tee(Range(0, 5000, 1), 3)
+		
+		var wg sync.WaitGroup
+		counts := make([]int, len(streams))
+		for i, branch := range streams {
+			wg.Add(1)
+			go func(i int, branch Stream[int64]) {
+				defer wg.Done()
+				results, _ := Collect(branch)
+				counts[i] = len(results)
+			}(i, branch)
+		}
+		wg.Wait()
+		
+		for i, count := range counts {
+			if count != 5000 {
+				t.Errorf("Branch %d: expected 5000 items, got %d", i, count)
+			}
+		}
+	})
+	
+	t.Run("BlockBackpressure", func(t *testing.T) {
+		streams := TeeWithOptions(Range(0, 1000, 1), 2, WithTeeBuffer(4, TeeBlock))
+		
+		// The fast branch may run at most 4 items ahead of the slow one
+		done := make(chan int)
+		go func() {
+			results, _ := Collect(streams[0])
+			done <- len(results)
+		}()
+		var slow []int64
+		for {
+			item, err := streams[1]()
+			if err != nil {
+				break
+			}
+			slow = append(slow, item)
+		}
+		
+		if fast := <-done; fast != 1000 || len(slow) != 1000 {
+			t.Errorf("Expected both branches to see 1000 items, got %d and %d", fast, len(slow))
+		}
+	})
+	
+	t.Run("FailLaggingSurfacesError", func(t *testing.T) {
+		streams := TeeWithOptions(Range(0, 100, 1), 2, WithTeeBuffer(10, TeeFailLagging))
+		
+		fast, err := Collect(streams[0])
+		if err != nil || len(fast) != 100 {
+			t.Fatalf("Expected fast branch to see 100 items, got %d (%v)", len(fast), err)
+		}
+		if _, err := streams[1](); err != ErrTeeOverflow {
+			t.Errorf("Expected ErrTeeOverflow from lagging branch, got %v", err)
+		}
+	})
+}
+
+// TestMaterialize tests Materialize and Replayable
+func TestMaterialize(t *testing.T) {
+	t.Run("SequentialConsumers", func(t *testing.T) {
+		users := []Record{
+			NewRecord().Int("id", 1).String("name", "Alice").Build(),
+			NewRecord().Int("id", 2).String("name", "Bob").Build(),
+			NewRecord().Int("id", 3).String("name", "Carol").Build(),
+		}
+		
+		cached, err := Materialize(FromRecordsUnsafe(users))
+		if err != nil {
+			t.Fatalf("Failed to materialize: %v", err)
+		}
+		
+		for i := 0; i < 3; i++ {
+			results, err := Collect(cached.Stream())
+			if err != nil {
+				t.Fatalf("Consumer %d: failed to collect: %v", i, err)
+			}
+			if !reflect.DeepEqual(results, users) {
+				t.Errorf("Consumer %d: expected %v, got %v", i, users, results)
+			}
+		}
+	})
+	
+	t.Run("Lazy", func(t *testing.T) {
+		pulls := 0
+		source := Peek(func(int64) { pulls++ })(Range(0, 5, 1))
+		
+		cached, _ := Materialize(source, WithLazyMaterialize())
+		if pulls != 0 {
+			t.Fatalf("Expected no pulls before first read, got %d", pulls)
+		}
+		
+		first := cached.Stream()
+		first()
+		first()
+		if pulls != 2 {
+			t.Errorf("Expected 2 pulls after reading 2 items, got %d", pulls)
+		}
+		
+		all, _ := Collect(cached.Stream())
+		rest, _ := Collect(first)
+		if len(all) != 5 || len(rest) != 3 || pulls != 5 {
+			t.Errorf("Expected 5 and 3 items with 5 pulls, got %d, %d, %d", len(all), len(rest), pulls)
+		}
+	})
+	
+	t.Run("MaxItems", func(t *testing.T) {
+		if _, err := Materialize(Range(0, 10, 1), WithMaxItems(5)); err != ErrMaterializeLimit {
+			t.Errorf("Expected ErrMaterializeLimit, got %v", err)
+		}
+		if _, err := Materialize(Range(0, 5, 1), WithMaxItems(5)); err != nil {
+			t.Errorf("Expected exactly 5 items to fit, got %v", err)
+		}
+		
+		cached, _ := Materialize(Range(0, 10, 1), WithMaxItems(5), WithLazyMaterialize())
+		results, err := Collect(cached.Stream())
+		if err != ErrMaterializeLimit || len(results) != 5 {
+			t.Errorf("Expected 5 items then ErrMaterializeLimit, got %d items and %v", len(results), err)
+		}
+	})
+	
+	t.Run("SourceError", func(t *testing.T) {
+		boom := errors.New("boom")
+		failing := func() (int64, error) { return 0, boom }
+		
+		if _, err := Materialize[int64](failing); err != boom {
+			t.Errorf("Expected boom, got %v", err)
+		}
+		
+		cached, _ := Materialize[int64](failing, WithLazyMaterialize())
+		for i := 0; i < 2; i++ {
+			if _, err := cached.Stream()(); err != boom {
+				t.Errorf("Read %d: expected boom replayed, got %v", i, err)
+			}
+		}
+	})
+}
+
+// TestFlatMap tests the FlatMap function
+func TestFlatMap(t *testing.T) {
+	t.Run("IntToRange", func(t *testing.T) {
+		input := FromSlice([]int64{2, 3})
+		
+		// Each int becomes a range from 1 to that number
+		flattened := FlatMap(func(x int64) Stream[int64] {
+			return Range(1, x+1, 1)
+		})(input)
+		
+		results, err := Collect(flattened)
+		if err != nil {
+			t.Fatalf("Failed to collect flattened stream: %v", err)
+		}
+		
+		expected := []int64{1, 2, 1, 2, 3} // Range(1,3) + Range(1,4)
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+		}
+		
+		for i, result := range results {
+			if result != expected[i] {
 				t.Errorf("Expected %v at position %d, got %v", expected[i], i, result)
 			}
 		}
@@ -672,4 +1577,699 @@ func TestFlatMap(t *testing.T) {
 			}
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestDistinct tests the Distinct family of deduplication filters
+func TestDistinct(t *testing.T) {
+	t.Run("Distinct", func(t *testing.T) {
+		stream := FromSlice([]int64{3, 1, 3, 2, 1, 4})
+
+		results, err := Collect(Distinct[int64]()(stream))
+		if err != nil {
+			t.Fatalf("Failed to collect stream: %v", err)
+		}
+
+		expected := []int64{3, 1, 2, 4}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, results)
+		}
+		for i, result := range results {
+			if result != expected[i] {
+				t.Errorf("Expected %v at position %d, got %v", expected[i], i, result)
+			}
+		}
+	})
+
+	t.Run("DistinctByFirstWins", func(t *testing.T) {
+		records := []Record{
+			NewRecord().String("id", "a").Int("version", 1).Build(),
+			NewRecord().String("id", "b").Int("version", 1).Build(),
+			NewRecord().String("id", "a").Int("version", 2).Build(),
+			NewRecord().String("id", "b").Int("version", 2).Build(),
+			NewRecord().String("id", "c").Int("version", 1).Build(),
+		}
+
+		deduped := DistinctBy(func(r Record) string { return GetOr(r, "id", "") })(FromSlice(records))
+		results, err := Collect(deduped)
+		if err != nil {
+			t.Fatalf("Failed to collect stream: %v", err)
+		}
+
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 results, got %d", len(results))
+		}
+		for _, result := range results {
+			if GetOr(result, "version", int64(0)) != 1 {
+				t.Errorf("Expected first occurrence (version 1) to win, got %v", result)
+			}
+		}
+	})
+
+	t.Run("DistinctRecent", func(t *testing.T) {
+		stream := FromSlice([]string{"a", "b", "a", "c", "d", "a", "d"})
+
+		// Only the last 2 emitted keys are remembered
+		recent := DistinctRecent(func(s string) string { return s }, 2)(stream)
+		results, err := Collect(recent)
+		if err != nil {
+			t.Fatalf("Failed to collect stream: %v", err)
+		}
+
+		// "a" is suppressed while remembered, then emitted again once forgotten
+		expected := []string{"a", "b", "c", "d", "a"}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, results)
+		}
+		for i, result := range results {
+			if result != expected[i] {
+				t.Errorf("Expected %v at position %d, got %v", expected[i], i, result)
+			}
+		}
+	})
+}
+
+// TestConcat tests sequential stream concatenation
+func TestConcat(t *testing.T) {
+	t.Run("InOrder", func(t *testing.T) {
+		results, err := Collect(Concat(
+			FromSlice([]int64{1, 2}),
+			FromSlice([]int64{}),
+			FromSlice([]int64{3, 4, 5}),
+		))
+		if err != nil {
+			t.Fatalf("Concat failed: %v", err)
+		}
+
+		expected := []int64{1, 2, 3, 4, 5}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+		}
+		for i := range expected {
+			if results[i] != expected[i] {
+				t.Errorf("Expected %v at position %d, got %v", expected[i], i, results[i])
+			}
+		}
+	})
+
+	t.Run("NoStreams", func(t *testing.T) {
+		_, err := Concat[int64]()()
+		if err != EOS {
+			t.Errorf("Expected EOS, got %v", err)
+		}
+	})
+
+	t.Run("PropagatesError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		failing := func() (int64, error) { return 0, failure }
+
+		_, err := Collect(Concat(FromSlice([]int64{1}), failing, FromSlice([]int64{2})))
+		if err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+	})
+}
+
+// TestMerge tests concurrent stream merging
+func TestMerge(t *testing.T) {
+	t.Run("AllElements", func(t *testing.T) {
+		results, err := Collect(Merge(
+			FromSlice([]int64{1, 2, 3}),
+			FromSlice([]int64{4, 5}),
+			FromSlice([]int64{6}),
+		))
+		if err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+
+		total := int64(0)
+		for _, v := range results {
+			total += v
+		}
+		if len(results) != 6 || total != 21 {
+			t.Errorf("Expected 6 elements summing to 21, got %v", results)
+		}
+	})
+
+	t.Run("NoStreams", func(t *testing.T) {
+		_, err := Merge[int64]()()
+		if err != EOS {
+			t.Errorf("Expected EOS, got %v", err)
+		}
+	})
+
+	t.Run("PropagatesError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		failing := func() (int64, error) { return 0, failure }
+
+		_, err := Collect(Merge(FromSlice([]int64{1, 2, 3}), failing))
+		if err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+	})
+
+	t.Run("ContextCancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		infinite := Generate(func() (int64, error) { return 1, nil })
+		merged := MergeContext(ctx, infinite)
+
+		if _, err := merged(); err != nil {
+			t.Fatalf("Expected element before cancel, got %v", err)
+		}
+		cancel()
+
+		if _, err := merged(); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+// TestRoundRobin tests deterministic one-at-a-time interleaving
+func TestRoundRobin(t *testing.T) {
+	t.Run("Fairness", func(t *testing.T) {
+		results, err := Collect(RoundRobin(
+			FromSlice([]int64{1, 2, 3, 4}),
+			FromSlice([]int64{10}),
+			FromSlice([]int64{}),
+			FromSlice([]int64{20, 21, 22}),
+		))
+		if err != nil {
+			t.Fatalf("RoundRobin failed: %v", err)
+		}
+		expected := []int64{1, 10, 20, 2, 21, 3, 22, 4}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	})
+
+	t.Run("Lazy", func(t *testing.T) {
+		pulls := []int{0, 0}
+		counting := func(i int) Stream[int64] {
+			return func() (int64, error) {
+				pulls[i]++
+				return int64(i), nil
+			}
+		}
+		if _, err := Collect(Take[int64](5)(RoundRobin(counting(0), counting(1)))); err != nil {
+			t.Fatalf("RoundRobin failed: %v", err)
+		}
+		if pulls[0] != 3 || pulls[1] != 2 {
+			t.Errorf("Expected 3 and 2 pulls, got %v", pulls)
+		}
+	})
+
+	t.Run("ZeroAndOneInput", func(t *testing.T) {
+		if _, err := RoundRobin[int64]()(); err != EOS {
+			t.Errorf("Expected EOS, got %v", err)
+		}
+		results, err := Collect(RoundRobin(FromSlice([]int64{1, 2})))
+		if err != nil || !reflect.DeepEqual(results, []int64{1, 2}) {
+			t.Errorf("Expected [1 2], got %v (%v)", results, err)
+		}
+	})
+
+	t.Run("PropagatesError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		failing := func() (int64, error) { return 0, failure }
+
+		merged := RoundRobin(FromSlice([]int64{1, 2}), failing)
+		if item, err := merged(); err != nil || item != 1 {
+			t.Fatalf("Expected 1 first, got %v (%v)", item, err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := merged(); err != failure {
+				t.Errorf("Expected the source error on every pull, got %v", err)
+			}
+		}
+	})
+}
+
+// TestMergeByKey tests merging sorted streams into one sorted stream
+func TestMergeByKey(t *testing.T) {
+	byTime := func(a, b Record) bool { return a["ts"].(time.Time).Before(b["ts"].(time.Time)) }
+	day := func(name string, hours ...int) Stream[Record] {
+		records := make([]Record, len(hours))
+		for i, hour := range hours {
+			records[i] = Record{"ts": time.Date(2024, 3, 1, hour, 0, 0, 0, time.UTC), "src": name}
+		}
+		return FromSlice(records)
+	}
+
+	t.Run("Chronological", func(t *testing.T) {
+		results, err := Collect(MergeByKey(byTime, day("a", 1, 5, 9), day("b", 2, 3, 10, 11), day("c", 0, 5)))
+		if err != nil {
+			t.Fatalf("MergeByKey failed: %v", err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, fmt.Sprintf("%s%d", r["src"], r["ts"].(time.Time).Hour()))
+		}
+		// Equal times keep the order of their input streams
+		expected := "c0 a1 b2 b3 a5 c5 a9 b10 b11"
+		if strings.Join(got, " ") != expected {
+			t.Errorf("Expected %s, got %s", expected, strings.Join(got, " "))
+		}
+	})
+
+	t.Run("ZeroAndOneInput", func(t *testing.T) {
+		if _, err := MergeByKey(byTime)(); err != EOS {
+			t.Errorf("Expected EOS, got %v", err)
+		}
+		results, err := Collect(MergeByKey(byTime, day("a", 1, 2)))
+		if err != nil || len(results) != 2 {
+			t.Errorf("Expected 2 records, got %v (%v)", results, err)
+		}
+	})
+
+	t.Run("Lazy", func(t *testing.T) {
+		pulled := 0
+		endless := func() (Record, error) {
+			pulled++
+			return Record{"ts": time.Date(2024, 3, 1, 0, 0, pulled, 0, time.UTC)}, nil
+		}
+		if _, err := Collect(Take[Record](3)(MergeByKey(byTime, endless, day("a", 1)))); err != nil {
+			t.Fatalf("MergeByKey failed: %v", err)
+		}
+		if pulled > 4 {
+			t.Errorf("Expected one record held per input, pulled %d", pulled)
+		}
+	})
+
+	t.Run("PropagatesError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		calls := 0
+		failing := func() (Record, error) {
+			if calls++; calls == 1 {
+				return Record{"ts": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}, nil
+			}
+			return nil, failure
+		}
+		merged := MergeByKey(byTime, day("a", 1), failing)
+		for i := 0; i < 2; i++ {
+			if _, err := merged(); err != failure {
+				t.Errorf("Expected the source error on every pull, got %v", err)
+			}
+		}
+	})
+
+	t.Run("NilLessPanics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic for nil less")
+			}
+		}()
+		MergeByKey(nil)
+	})
+}
+
+// TestBuffer tests the prefetching Buffer filter
+func TestBuffer(t *testing.T) {
+	t.Run("AllElementsInOrder", func(t *testing.T) {
+		results, err := Collect(Buffer[int64](3)(FromSlice([]int64{1, 2, 3, 4, 5, 6, 7})))
+		if err != nil {
+			t.Fatalf("Buffer failed: %v", err)
+		}
+		if fmt.Sprint(results) != "[1 2 3 4 5 6 7]" {
+			t.Errorf("Expected [1 2 3 4 5 6 7], got %v", results)
+		}
+	})
+
+	t.Run("PullsAhead", func(t *testing.T) {
+		var mu sync.Mutex
+		pulls := 0
+		source := Generate(func() (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			pulls++
+			return pulls, nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		BufferContext[int](ctx, 4)(source)
+		time.Sleep(50 * time.Millisecond)
+
+		// Four buffered plus one waiting for space, with nothing consumed yet
+		mu.Lock()
+		defer mu.Unlock()
+		if pulls != 5 {
+			t.Errorf("Expected 5 pulls ahead of the consumer, got %d", pulls)
+		}
+	})
+
+	t.Run("ErrorAfterBufferedItems", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		calls := int64(0)
+		source := func() (int64, error) {
+			calls++
+			if calls > 3 {
+				return 0, failure
+			}
+			return calls, nil
+		}
+
+		buffered := Buffer[int64](10)(source)
+		results, err := Collect(buffered)
+		if err != failure || len(results) != 3 {
+			t.Fatalf("Expected 3 items then the source error, got %v (%v)", results, err)
+		}
+		if _, err := buffered(); err != failure {
+			t.Errorf("Expected sticky source error, got %v", err)
+		}
+	})
+
+	t.Run("ContextCancelStopsProducer", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		buffered := BufferContext[int](ctx, 2)(Generate(func() (int, error) { return 1, nil }))
+
+		if _, err := buffered(); err != nil {
+			t.Fatalf("Expected an element, got %v", err)
+		}
+		cancel()
+		if _, err := buffered(); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Potential goroutine leak in Buffer: %d -> %d", before, after)
+		}
+	})
+}
+
+// BenchmarkBuffer compares a 10ms-latency source feeding a 10ms-per-item Map
+// with and without Buffer between them
+func BenchmarkBuffer(b *testing.B) {
+	const items = 20
+	source := func() Stream[int] {
+		count := 0
+		return func() (int, error) {
+			if count >= items {
+				return 0, EOS
+			}
+			time.Sleep(10 * time.Millisecond)
+			count++
+			return count, nil
+		}
+	}
+	work := MapSeq(func(x int) int {
+		time.Sleep(10 * time.Millisecond)
+		return x
+	})
+
+	b.Run("Unbuffered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Collect(work(source()))
+		}
+	})
+
+	b.Run("Buffered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Collect(work(Buffer[int](8)(source())))
+		}
+	})
+}
+
+// TestSampling tests the sampling and shuffling filters
+func TestSampling(t *testing.T) {
+	t.Run("SampleEvery", func(t *testing.T) {
+		results, err := Collect(SampleEvery[int64](3)(Range(0, 10, 1)))
+		if err != nil {
+			t.Fatalf("SampleEvery failed: %v", err)
+		}
+		if fmt.Sprint(results) != "[0 3 6 9]" {
+			t.Errorf("Expected [0 3 6 9], got %v", results)
+		}
+	})
+
+	t.Run("SampleFractionWithinBounds", func(t *testing.T) {
+		const n = 10_000
+		sampled, err := Collect(SampleFraction[int64](0.1, 42)(Range(0, n, 1)))
+		if err != nil {
+			t.Fatalf("SampleFraction failed: %v", err)
+		}
+
+		// Binomial(10000, 0.1) has a standard deviation of 30; allow 5 of them
+		if len(sampled) < 850 || len(sampled) > 1150 {
+			t.Errorf("Expected about 1000 samples, got %d", len(sampled))
+		}
+
+		again, _ := Collect(SampleFraction[int64](0.1, 42)(Range(0, n, 1)))
+		if fmt.Sprint(again) != fmt.Sprint(sampled) {
+			t.Error("Expected the same seed to keep the same elements")
+		}
+	})
+
+	t.Run("ReservoirSampleUniform", func(t *testing.T) {
+		const runs = 10_000
+		counts := make([]int, 10)
+		for seed := int64(0); seed < runs; seed++ {
+			sample, err := ReservoirSample[int64](3, seed)(Range(0, 10, 1))
+			if err != nil || len(sample) != 3 {
+				t.Fatalf("Expected 3 samples, got %v (%v)", sample, err)
+			}
+			for _, v := range sample {
+				counts[v]++
+			}
+		}
+
+		// Each element is chosen with probability 3/10
+		for v, count := range counts {
+			if count < 2700 || count > 3300 {
+				t.Errorf("Element %d chosen %d times, expected about 3000", v, count)
+			}
+		}
+	})
+
+	t.Run("ReservoirSampleShortStream", func(t *testing.T) {
+		sample, err := ReservoirSample[int64](5, 1)(FromSlice([]int64{1, 2}))
+		if err != nil || len(sample) != 2 {
+			t.Errorf("Expected the whole short stream, got %v (%v)", sample, err)
+		}
+	})
+
+	t.Run("Shuffle", func(t *testing.T) {
+		shuffled, err := Collect(Shuffle[int64](7)(Range(0, 20, 1)))
+		if err != nil {
+			t.Fatalf("Shuffle failed: %v", err)
+		}
+
+		seen := make(map[int64]bool)
+		for _, v := range shuffled {
+			seen[v] = true
+		}
+		if len(shuffled) != 20 || len(seen) != 20 {
+			t.Errorf("Expected a permutation of 0..19, got %v", shuffled)
+		}
+
+		again, _ := Collect(Shuffle[int64](7)(Range(0, 20, 1)))
+		if fmt.Sprint(again) != fmt.Sprint(shuffled) {
+			t.Error("Expected the same seed to give the same order")
+		}
+		sorted, _ := Collect(Range(0, 20, 1))
+		if fmt.Sprint(shuffled) == fmt.Sprint(sorted) {
+			t.Error("Expected the order to change")
+		}
+	})
+}
+
+// TestPeek tests the pass-through Peek filter
+func TestPeek(t *testing.T) {
+	t.Run("CountBetweenWhereAndSelect", func(t *testing.T) {
+		users := []Record{
+			{"name": "Alice", "age": int64(30), "city": "NYC"},
+			{"name": "Bob", "age": int64(17), "city": "LA"},
+			{"name": "Carol", "age": int64(45), "city": "SF"},
+		}
+
+		passed := 0
+		pipeline := Pipe3(
+			Where(func(r Record) bool { return GetOr(r, "age", int64(0)) >= 18 }),
+			Peek(func(Record) { passed++ }),
+			Select("name"),
+		)
+
+		results, err := Collect(pipeline(FromSlice(users)))
+		if err != nil {
+			t.Fatalf("Failed to collect stream: %v", err)
+		}
+		if passed != 2 || len(results) != 2 {
+			t.Errorf("Expected 2 records past Where, counted %d and got %d", passed, len(results))
+		}
+		if _, exists := results[0]["age"]; exists {
+			t.Errorf("Expected Select to run after Peek, got %v", results[0])
+		}
+	})
+
+	t.Run("ErrorsPassThrough", func(t *testing.T) {
+		failure := fmt.Errorf("read failed")
+		called := false
+		_, err := Peek(func(int64) { called = true })(func() (int64, error) { return 0, failure })()
+		if err != failure || called {
+			t.Errorf("Expected error without callback, got %v (called=%v)", err, called)
+		}
+	})
+}
+
+// TestLog tests the Log debugging filter
+func TestLog(t *testing.T) {
+	t.Run("FormatsElements", func(t *testing.T) {
+		var out strings.Builder
+		records := []Record{{"b": 2, "a": "x"}, {"a": "y"}}
+
+		results, err := Collect(Log[Record]("users", &out)(FromSlice(records)))
+		if err != nil || len(results) != 2 {
+			t.Fatalf("Expected records to pass through, got %v (%v)", results, err)
+		}
+
+		expected := "users[0] a=x b=2\nusers[1] a=y\n"
+		if out.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("NonRecordValues", func(t *testing.T) {
+		var out strings.Builder
+		_, _ = Collect(Log[int64]("n", &out)(FromSlice([]int64{7})))
+		if out.String() != "n[0] 7\n" {
+			t.Errorf("Expected %q, got %q", "n[0] 7\n", out.String())
+		}
+	})
+
+	t.Run("IndexPerStream", func(t *testing.T) {
+		var out strings.Builder
+		logger := Log[int64]("n", &out)
+		_, _ = Collect(logger(FromSlice([]int64{1, 2})))
+		_, _ = Collect(logger(FromSlice([]int64{3})))
+		if expected := "n[0] 1\nn[1] 2\nn[0] 3\n"; out.String() != expected {
+			t.Errorf("Expected each stream to count from 0, %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("ConcurrentUse", func(t *testing.T) {
+		var out strings.Builder
+		logger := Log[int64]("c", &out)
+
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = Collect(logger(Range(0, 100, 1)))
+			}()
+		}
+		wg.Wait()
+
+		if lines := strings.Count(out.String(), "\n"); lines != 400 {
+			t.Errorf("Expected 400 log lines, got %d", lines)
+		}
+		if last := strings.Count(out.String(), "c[99] "); last != 4 {
+			t.Errorf("Expected every stream to reach index 99, got %d", last)
+		}
+	})
+}
+
+// flakySource yields 1..n but fails every failEvery-th call without advancing
+func flakySource(n int64, failEvery int) Stream[int64] {
+	calls := 0
+	next := int64(1)
+	return Generate(func() (int64, error) {
+		calls++
+		if calls%failEvery == 0 {
+			return 0, fmt.Errorf("transient failure on call %d", calls)
+		}
+		if next > n {
+			return 0, EOS
+		}
+		next++
+		return next - 1, nil
+	})
+}
+
+// TestRetry tests retrying transient source errors
+func TestRetry(t *testing.T) {
+	t.Run("RecoversTransientFailures", func(t *testing.T) {
+		results, err := Collect(Retry[int64](2, time.Millisecond)(flakySource(10, 3)))
+		if err != nil {
+			t.Fatalf("Expected retries to recover, got %v", err)
+		}
+		if len(results) != 10 || results[9] != 10 {
+			t.Errorf("Expected 1..10, got %v", results)
+		}
+	})
+
+	t.Run("GivesUpAfterAttempts", func(t *testing.T) {
+		calls := 0
+		alwaysFails := func() (int64, error) {
+			calls++
+			return 0, fmt.Errorf("down")
+		}
+
+		_, err := Retry[int64](3, 0)(alwaysFails)()
+		if err == nil || calls != 4 {
+			t.Errorf("Expected error after 1 pull + 3 retries, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("EOSNotRetried", func(t *testing.T) {
+		calls := 0
+		empty := func() (int64, error) {
+			calls++
+			return 0, EOS
+		}
+
+		_, err := Retry[int64](3, time.Second)(empty)()
+		if err != EOS || calls != 1 {
+			t.Errorf("Expected immediate EOS, got %v after %d calls", err, calls)
+		}
+	})
+}
+
+// TestOnErrorResume tests falling back to another stream on error
+func TestOnErrorResume(t *testing.T) {
+	t.Run("SwitchesToFallback", func(t *testing.T) {
+		results, err := Collect(OnErrorResume(FromSlice([]int64{100, 200}))(flakySource(10, 4)))
+		if err != nil {
+			t.Fatalf("Expected fallback to complete, got %v", err)
+		}
+		expected := []int64{1, 2, 3, 100, 200}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, results)
+		}
+		for i := range expected {
+			if results[i] != expected[i] {
+				t.Errorf("Expected %v at position %d, got %v", expected[i], i, results[i])
+			}
+		}
+	})
+
+	t.Run("NoErrorNoFallback", func(t *testing.T) {
+		results, _ := Collect(OnErrorResume(FromSlice([]int64{100}))(FromSlice([]int64{1, 2})))
+		if len(results) != 2 {
+			t.Errorf("Expected source only, got %v", results)
+		}
+	})
+}
+
+// TestSkipErrors tests dropping erroring pulls
+func TestSkipErrors(t *testing.T) {
+	t.Run("Unlimited", func(t *testing.T) {
+		results, err := Collect(Pipe(SkipErrors[int64](-1), Limit[int64](20))(flakySource(10, 2)))
+		if err != nil {
+			t.Fatalf("Expected errors to be skipped, got %v", err)
+		}
+		if len(results) != 10 {
+			t.Errorf("Expected 1..10, got %v", results)
+		}
+	})
+
+	t.Run("MaxSkipped", func(t *testing.T) {
+		results, err := Collect(SkipErrors[int64](2)(flakySource(10, 3)))
+		if err == nil {
+			t.Fatal("Expected the third error to be returned")
+		}
+		if len(results) != 6 {
+			t.Errorf("Expected 6 elements before giving up, got %v", results)
+		}
+	})
+}
@@ -1,7 +1,12 @@
 package stream
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -95,6 +100,22 @@ func (wt *WatermarkTracker) UpdateWatermark(eventTime time.Time) time.Time {
 	return wt.currentWatermark
 }
 
+// AdvanceIdle moves the watermark forward while the source is idle: to the maximum event
+// time seen, or to the generator's watermark for now if that is later
+func (wt *WatermarkTracker) AdvanceIdle(now time.Time) time.Time {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	target := wt.generator(now)
+	if wt.initialized && wt.maxEventTime.After(target) {
+		target = wt.maxEventTime
+	}
+	if target.After(wt.currentWatermark) {
+		wt.currentWatermark = target
+	}
+	return wt.currentWatermark
+}
+
 // GetWatermark returns the current watermark
 func (wt *WatermarkTracker) GetWatermark() time.Time {
 	wt.mu.RLock()
@@ -102,6 +123,38 @@ func (wt *WatermarkTracker) GetWatermark() time.Time {
 	return wt.currentWatermark
 }
 
+// ExportState returns the current watermark and the maximum event time seen
+func (wt *WatermarkTracker) ExportState() (Record, error) {
+	wt.mu.RLock()
+	defer wt.mu.RUnlock()
+	return Record{
+		"watermark":      checkpointTime(wt.currentWatermark),
+		"max_event_time": checkpointTime(wt.maxEventTime),
+		"initialized":    wt.initialized,
+	}, nil
+}
+
+// RestoreState replaces the watermark and maximum event time with an exported state
+func (wt *WatermarkTracker) RestoreState(state Record) error {
+	watermark, err := parseCheckpointTime(state, "watermark")
+	if err != nil {
+		return err
+	}
+	maxEventTime, err := parseCheckpointTime(state, "max_event_time")
+	if err != nil {
+		return err
+	}
+	initialized, ok := state["initialized"].(bool)
+	if !ok {
+		return fmt.Errorf("checkpoint field %q: expected a bool, got %v", "initialized", state["initialized"])
+	}
+
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	wt.currentWatermark, wt.maxEventTime, wt.initialized = watermark, maxEventTime, initialized
+	return nil
+}
+
 // ============================================================================
 // EVENT-TIME WINDOW CONFIGURATION
 // ============================================================================
@@ -121,6 +174,13 @@ type EventTimeWindowConfig struct {
 	WatermarkGenerator WatermarkGenerator
 	LateDataPolicy     LateDataPolicy
 	AllowedLateness    time.Duration
+	SessionKey         func(Record) string      // Session windows only; nil means one global session
+	LateDataSink       func(Record)             // Receives late records under SideOutputLate
+	IdleTimeout        time.Duration            // Advance the watermark after this long without input; 0 disables
+	IdleContext        context.Context          // Stops the idle-timeout reader goroutine; nil means never
+	Aggregators        []AggregatorSpec[Record] // Keyed windows only; results replace the elements field
+	Checkpoint         *WindowCheckpoint        // Exposes the open windows for checkpointing; nil disables
+	DuplicateSink      func(Record)             // DeduplicateByKey only; receives suppressed duplicates
 }
 
 // EventTimeWindowOption configures event-time windows
@@ -157,6 +217,73 @@ func WithAllowedLateness(lateness time.Duration) EventTimeWindowOption {
 	}
 }
 
+// WithLateDataSink sends late records to sink under the SideOutputLate policy.
+// A record is late when every window it belongs to has already fired.
+func WithLateDataSink(sink func(Record)) EventTimeWindowOption {
+	return func(config *EventTimeWindowConfig) {
+		config.LateDataSink = sink
+	}
+}
+
+// WithIdleTimeout advances the watermark when no record has arrived for timeout, so open
+// windows fire while the source is quiet instead of waiting for the next record. The
+// watermark moves to the maximum event time seen, or to the generator's watermark for the
+// current wall-clock time if that is later. The input is then read by a goroutine that stops
+// at the end of the input; if the consumer may stop early, use WithIdleTimeoutContext.
+func WithIdleTimeout(timeout time.Duration) EventTimeWindowOption {
+	return WithIdleTimeoutContext(context.Background(), timeout)
+}
+
+// WithIdleTimeoutContext is WithIdleTimeout with an external context. Cancelling ctx stops
+// the reader goroutine and the windowed stream returns ctx.Err() from then on.
+func WithIdleTimeoutContext(ctx context.Context, timeout time.Duration) EventTimeWindowOption {
+	if timeout <= 0 {
+		panic("idle timeout must be positive")
+	}
+	return func(config *EventTimeWindowConfig) {
+		config.IdleTimeout = timeout
+		config.IdleContext = ctx
+	}
+}
+
+// WithWindowAggregators makes KeyedEventTimeTumblingWindow emit the aggregator results
+// for each (key, window) instead of its elements
+func WithWindowAggregators(specs ...AggregatorSpec[Record]) EventTimeWindowOption {
+	return func(config *EventTimeWindowConfig) {
+		config.Aggregators = specs
+	}
+}
+
+// WithWindowCheckpoint connects the window filter to checkpoint, through which its open
+// windows and watermark can be exported and restored (see WindowCheckpoint)
+func WithWindowCheckpoint(checkpoint *WindowCheckpoint) EventTimeWindowOption {
+	return func(config *EventTimeWindowConfig) {
+		config.Checkpoint = checkpoint
+	}
+}
+
+// WithDuplicateSink sends the records DeduplicateByKey suppresses to sink, e.g. to count them
+func WithDuplicateSink(sink func(Record)) EventTimeWindowOption {
+	return func(config *EventTimeWindowConfig) {
+		config.DuplicateSink = sink
+	}
+}
+
+// WithSessionKey tracks EventTimeSessionWindow sessions independently per key
+func WithSessionKey(keyFn func(Record) string) EventTimeWindowOption {
+	return func(config *EventTimeWindowConfig) {
+		config.SessionKey = keyFn
+	}
+}
+
+// WithSessionKeyFields tracks EventTimeSessionWindow sessions per combination of field values.
+// The fields stay on the emitted records, so consumers can read a session's key from them.
+func WithSessionKeyFields(fields ...string) EventTimeWindowOption {
+	return WithSessionKey(func(record Record) string {
+		return buildGroupKey(record, fields)
+	})
+}
+
 // ============================================================================
 // EVENT-TIME WINDOW STATE
 // ============================================================================
@@ -174,6 +301,7 @@ type EventTimeWindowState struct {
 	windowStart  time.Time
 	windowEnd    time.Time
 	fired        bool
+	pane         int // Index of the latest firing; re-firings under UpdateWindow increment it
 	latePolicy   LateDataPolicy
 	lateElements []TimestampedRecord // Elements that arrived after firing
 }
@@ -220,20 +348,30 @@ func (ws *EventTimeWindowState) AddElement(element Record, eventTime time.Time)
 	return true
 }
 
-// ShouldFire determines if the window should fire based on watermark
+// ShouldFire determines if the window should fire based on watermark.
+// Under UpdateWindow a fired window fires again once late elements have arrived.
 func (ws *EventTimeWindowState) ShouldFire(watermark time.Time) bool {
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
-	return !ws.fired && !watermark.Before(ws.windowEnd)
+	if ws.fired {
+		return ws.latePolicy == UpdateWindow && len(ws.lateElements) > 0
+	}
+	return !watermark.Before(ws.windowEnd)
 }
 
-// Fire triggers the window to emit results
+// Fire triggers the window to emit results. Re-firing under UpdateWindow merges
+// the late elements and returns the full updated contents.
 func (ws *EventTimeWindowState) Fire() []Record {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
 	if ws.fired {
-		return nil // Already fired
+		if ws.latePolicy != UpdateWindow || len(ws.lateElements) == 0 {
+			return nil // Already fired
+		}
+		ws.elements = append(ws.elements, ws.lateElements...)
+		ws.lateElements = ws.lateElements[:0]
+		ws.pane++
 	}
 
 	ws.fired = true
@@ -268,127 +406,22 @@ func EventTimeTumblingWindow(
 	windowSize time.Duration,
 	options ...EventTimeWindowOption,
 ) func(Stream[Record]) Stream[Stream[Record]] {
-
-	// Apply default configuration
-	config := &EventTimeWindowConfig{
-		LateDataPolicy:     DropLateData,
-		WatermarkGenerator: BoundedOutOfOrdernessWatermark(30 * time.Second), // Default 30s lateness
-	}
-
-	for _, option := range options {
-		option(config)
-	}
-
-	if config.TimestampExtractor == nil {
-		panic("EventTimeTumblingWindow requires a timestamp extractor")
-	}
+	config := newEventTimeWindowConfig("EventTimeTumblingWindow", options)
+	panes := eventTimeWindowPanes(windowSize, windowSize, nil, config)
 
 	return func(input Stream[Record]) Stream[Stream[Record]] {
-		watermarkTracker := NewWatermarkTracker(config.WatermarkGenerator)
-		windowsMap := make(map[time.Time]*EventTimeWindowState)
-		var mu sync.RWMutex
-
-		return func() (Stream[Record], error) {
-			for {
-				// Get next element from input stream
-				element, err := input()
-				if err == EOS {
-					// Handle end of stream - fire all remaining windows
-					mu.Lock()
-
-					// Find any windows that should fire
-					currentWatermark := watermarkTracker.GetWatermark()
-					var readyWindows []*EventTimeWindowState
-
-					for _, window := range windowsMap {
-						if window.ShouldFire(currentWatermark) || err == EOS {
-							readyWindows = append(readyWindows, window)
-						}
-					}
-
-					if len(readyWindows) > 0 {
-						// Fire the earliest window
-						sort.Slice(readyWindows, func(i, j int) bool {
-							return readyWindows[i].windowStart.Before(readyWindows[j].windowStart)
-						})
-
-						window := readyWindows[0]
-						result := window.Fire()
-
-						// Remove fired window from map
-						delete(windowsMap, window.windowStart)
-						mu.Unlock()
-
-						if len(result) > 0 {
-							return FromSlice(result), nil
-						}
-						// Continue to check for more windows
-						continue
-					}
-
-					mu.Unlock()
-					return nil, EOS
-				}
-
-				if err != nil {
-					return nil, err
-				}
-
-				// Extract event time and standardize it
-				eventTime := config.TimestampExtractor(element)
-				eventTime = StandardizeTime(eventTime)
-
-				// Update watermark
-				watermark := watermarkTracker.UpdateWatermark(eventTime)
-
-				mu.Lock()
-
-				// Determine which window this element belongs to
-				windowStart := eventTime.Truncate(windowSize)
-
-				// Get or create window
-				window, exists := windowsMap[windowStart]
-				if !exists {
-					windowEnd := windowStart.Add(windowSize)
-					window = NewEventTimeWindowState(windowStart, windowEnd, config.LateDataPolicy)
-					windowsMap[windowStart] = window
-				}
-
-				// Add element to window
-				window.AddElement(element, eventTime)
-
-				// Check if any windows should fire
-				var readyWindows []*EventTimeWindowState
-				for _, w := range windowsMap {
-					if w.ShouldFire(watermark) {
-						readyWindows = append(readyWindows, w)
-					}
-				}
-
-				if len(readyWindows) > 0 {
-					// Fire the earliest ready window
-					sort.Slice(readyWindows, func(i, j int) bool {
-						return readyWindows[i].windowStart.Before(readyWindows[j].windowStart)
-					})
-
-					windowToFire := readyWindows[0]
-					result := windowToFire.Fire()
+		return paneStreams(panes(input))
+	}
+}
 
-					// Remove fired window from map
-					delete(windowsMap, windowToFire.windowStart)
-					mu.Unlock()
+// EventTimeTumblingWindowResults is EventTimeTumblingWindow emitting one Record per window
+// that carries the window bounds and late-data counts alongside its elements (see WindowStartField)
+func EventTimeTumblingWindowResults(windowSize time.Duration, options ...EventTimeWindowOption) Filter[Record, Record] {
+	config := newEventTimeWindowConfig("EventTimeTumblingWindowResults", options)
+	panes := eventTimeWindowPanes(windowSize, windowSize, nil, config)
 
-					if len(result) > 0 {
-						return FromSlice(result), nil
-					}
-					// Continue processing if window was empty
-					continue
-				}
-
-				mu.Unlock()
-				// Continue processing more elements
-			}
-		}
+	return func(input Stream[Record]) Stream[Record] {
+		return paneRecords(panes(input))
 	}
 }
 
@@ -402,146 +435,76 @@ func EventTimeSlidingWindow(
 	slideInterval time.Duration,
 	options ...EventTimeWindowOption,
 ) func(Stream[Record]) Stream[Stream[Record]] {
+	config := newEventTimeWindowConfig("EventTimeSlidingWindow", options)
+	panes := eventTimeWindowPanes(windowSize, slideInterval, nil, config)
 
-	// Apply default configuration
-	config := &EventTimeWindowConfig{
-		LateDataPolicy:     DropLateData,
-		WatermarkGenerator: BoundedOutOfOrdernessWatermark(30 * time.Second), // Default 30s lateness
+	return func(input Stream[Record]) Stream[Stream[Record]] {
+		return paneStreams(panes(input))
 	}
+}
 
-	for _, option := range options {
-		option(config)
-	}
+// EventTimeSlidingWindowResults is EventTimeSlidingWindow emitting one Record per window
+// that carries the window bounds and late-data counts alongside its elements (see WindowStartField)
+func EventTimeSlidingWindowResults(windowSize, slideInterval time.Duration, options ...EventTimeWindowOption) Filter[Record, Record] {
+	config := newEventTimeWindowConfig("EventTimeSlidingWindowResults", options)
+	panes := eventTimeWindowPanes(windowSize, slideInterval, nil, config)
 
-	if config.TimestampExtractor == nil {
-		panic("EventTimeSlidingWindow requires a timestamp extractor")
+	return func(input Stream[Record]) Stream[Record] {
+		return paneRecords(panes(input))
 	}
+}
 
-	return func(input Stream[Record]) Stream[Stream[Record]] {
-		watermarkTracker := NewWatermarkTracker(config.WatermarkGenerator)
-		windowsMap := make(map[time.Time]*EventTimeWindowState)
-		var mu sync.RWMutex
-
-		return func() (Stream[Record], error) {
-			for {
-				// Get next element from input stream
-				element, err := input()
-				if err == EOS {
-					// Handle end of stream - fire all remaining windows
-					mu.Lock()
-
-					// Find any windows that should fire
-					currentWatermark := watermarkTracker.GetWatermark()
-					var readyWindows []*EventTimeWindowState
-
-					for _, window := range windowsMap {
-						if window.ShouldFire(currentWatermark) || err == EOS {
-							readyWindows = append(readyWindows, window)
-						}
-					}
-
-					if len(readyWindows) > 0 {
-						// Fire the earliest window
-						sort.Slice(readyWindows, func(i, j int) bool {
-							return readyWindows[i].windowStart.Before(readyWindows[j].windowStart)
-						})
-
-						window := readyWindows[0]
-						result := window.Fire()
-
-						// Remove fired window from map
-						delete(windowsMap, window.windowStart)
-						mu.Unlock()
-
-						if len(result) > 0 {
-							return FromSlice(result), nil
-						}
-						// Continue to check for more windows
-						continue
-					}
-
-					mu.Unlock()
-					return nil, EOS
-				}
-
-				if err != nil {
-					return nil, err
-				}
-
-				// Extract event time and standardize it
-				eventTime := config.TimestampExtractor(element)
-				eventTime = StandardizeTime(eventTime)
-
-				// Update watermark
-				watermark := watermarkTracker.UpdateWatermark(eventTime)
-
-				mu.Lock()
-
-				// For sliding windows, create multiple windows for each slide
-				// Calculate which windows this element should belong to
-				windowStart := eventTime.Truncate(slideInterval)
-				for {
-					// Check if this element falls within a window starting at windowStart
-					windowEnd := windowStart.Add(windowSize)
-					if eventTime.Before(windowStart) || !eventTime.Before(windowEnd) {
-						// Element doesn't belong to this window, try previous window
-						windowStart = windowStart.Add(-slideInterval)
-						if windowStart.Add(windowSize).Before(eventTime) {
-							// Element is too far ahead, stop looking backwards
-							break
-						}
-						continue
-					}
-
-					// Element belongs to this window - get or create it
-					window, exists := windowsMap[windowStart]
-					if !exists {
-						window = NewEventTimeWindowState(windowStart, windowEnd, config.LateDataPolicy)
-						windowsMap[windowStart] = window
-					}
+// ============================================================================
+// KEYED EVENT-TIME TUMBLING WINDOW
+// ============================================================================
 
-					// Add element to window
-					window.AddElement(element, eventTime)
+// KeyedEventTimeTumblingWindow keeps separate tumbling event-time windows for each combination
+// of keyFields values and emits one Record per (key, window) once the watermark passes the
+// window's end. Each record holds the key fields, the window metadata (see WindowStartField)
+// and the window's elements, or the results of the aggregators set with WithWindowAggregators.
+// Only open (key, window) pairs are kept in memory.
+// Example: KeyedEventTimeTumblingWindow([]string{"symbol"}, time.Minute, WithTimestampExtractor(...),
+// WithWindowAggregators(FirstField("open", "price"), MaxField[float64]("high", "price")))
+func KeyedEventTimeTumblingWindow(keyFields []string, windowSize time.Duration, options ...EventTimeWindowOption) Filter[Record, Record] {
+	config := newEventTimeWindowConfig("KeyedEventTimeTumblingWindow", options)
+	panes := eventTimeWindowPanes(windowSize, windowSize, func(record Record) string {
+		return buildGroupKey(record, keyFields)
+	}, config)
+
+	return func(input Stream[Record]) Stream[Record] {
+		if err := unsupportedAggregator(config.Aggregators); err != nil {
+			return func() (Record, error) { return nil, err }
+		}
+		windows := panes(input)
 
-					// Move to next potential window
-					windowStart = windowStart.Add(-slideInterval)
-					if windowStart.Add(windowSize).Before(eventTime) {
-						// Element is too far ahead, stop looking backwards
-						break
-					}
-				}
+		var finalErr error // Sticky once an aggregator fails
+		return func() (Record, error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			pane, err := windows()
+			if err != nil {
+				return nil, err
+			}
 
-				// Check if any windows should fire
-				var readyWindows []*EventTimeWindowState
-				for _, w := range windowsMap {
-					if w.ShouldFire(watermark) {
-						readyWindows = append(readyWindows, w)
-					}
+			result := pane.record()
+			for _, field := range keyFields {
+				if val, exists := pane.records[0][field]; exists {
+					result[field] = val
 				}
-
-				if len(readyWindows) > 0 {
-					// Fire the earliest ready window
-					sort.Slice(readyWindows, func(i, j int) bool {
-						return readyWindows[i].windowStart.Before(readyWindows[j].windowStart)
-					})
-
-					windowToFire := readyWindows[0]
-					result := windowToFire.Fire()
-
-					// Remove fired window from map
-					delete(windowsMap, windowToFire.windowStart)
-					mu.Unlock()
-
-					if len(result) > 0 {
-						return FromSlice(result), nil
+			}
+			if len(config.Aggregators) > 0 {
+				delete(result, WindowElementsField)
+				for _, spec := range config.Aggregators {
+					value, err := spec.Agg.(AggregatorRunner[Record]).RunOn(FromSlice(pane.records))
+					if err != nil {
+						finalErr = err
+						return nil, err
 					}
-					// Continue processing if window was empty
-					continue
+					result[spec.Name] = value
 				}
-
-				mu.Unlock()
-				// Continue processing more elements
 			}
+			return result, nil
 		}
 	}
 }
@@ -558,6 +521,7 @@ type EventTimeSessionState struct {
 	sessionEnd   time.Time
 	lastActivity time.Time
 	fired        bool
+	pane         int // Index of the latest firing; re-firings under UpdateWindow increment it
 	latePolicy   LateDataPolicy
 	lateElements []TimestampedRecord
 }
@@ -615,7 +579,10 @@ func (ss *EventTimeSessionState) ShouldFire(watermark time.Time, sessionTimeout
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
-	if ss.fired || len(ss.elements) == 0 {
+	if ss.fired {
+		return ss.latePolicy == UpdateWindow && len(ss.lateElements) > 0
+	}
+	if len(ss.elements) == 0 {
 		return false
 	}
 
@@ -624,13 +591,27 @@ func (ss *EventTimeSessionState) ShouldFire(watermark time.Time, sessionTimeout
 	return !watermark.Before(sessionEndTime)
 }
 
-// Fire triggers the session to emit results
+// Fire triggers the session to emit results. Re-firing under UpdateWindow merges
+// the late elements, widening the session bounds, and returns the full updated contents.
 func (ss *EventTimeSessionState) Fire() []Record {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
 	if ss.fired {
-		return nil // Already fired
+		if ss.latePolicy != UpdateWindow || len(ss.lateElements) == 0 {
+			return nil // Already fired
+		}
+		for _, late := range ss.lateElements {
+			if late.Timestamp.Before(ss.sessionStart) {
+				ss.sessionStart = late.Timestamp
+			}
+			if late.Timestamp.After(ss.sessionEnd) {
+				ss.sessionEnd = late.Timestamp
+			}
+		}
+		ss.elements = append(ss.elements, ss.lateElements...)
+		ss.lateElements = ss.lateElements[:0]
+		ss.pane++
 	}
 
 	ss.fired = true
@@ -661,8 +642,87 @@ func EventTimeSessionWindow(
 	sessionTimeout time.Duration,
 	options ...EventTimeWindowOption,
 ) func(Stream[Record]) Stream[Stream[Record]] {
+	config := newEventTimeWindowConfig("EventTimeSessionWindow", options)
+	panes := eventTimeSessionPanes(sessionTimeout, config)
+
+	return func(input Stream[Record]) Stream[Stream[Record]] {
+		return paneStreams(panes(input))
+	}
+}
+
+// EventTimeSessionWindowResults is EventTimeSessionWindow emitting one Record per session
+// that carries the session bounds and late-data counts alongside its elements (see WindowStartField).
+// A session spans from its first event to its last event plus the session timeout.
+func EventTimeSessionWindowResults(sessionTimeout time.Duration, options ...EventTimeWindowOption) Filter[Record, Record] {
+	config := newEventTimeWindowConfig("EventTimeSessionWindowResults", options)
+	panes := eventTimeSessionPanes(sessionTimeout, config)
+
+	return func(input Stream[Record]) Stream[Record] {
+		return paneRecords(panes(input))
+	}
+}
+
+// ============================================================================
+// EVENT-TIME WINDOW RESULTS
+// ============================================================================
+
+// Field names of the Records emitted by the event-time *WindowResults filters.
+// The elements field holds a Stream[Record], so CrossFlatten(".", WindowElementsField)
+// turns a result back into one record per element that keeps the window metadata.
+const (
+	WindowStartField       = "window_start"       // time.Time, inclusive
+	WindowEndField         = "window_end"         // time.Time, exclusive
+	WindowElementCount     = "element_count"      // int64
+	WindowLateDroppedCount = "late_dropped_count" // int64, late records left out since the previous result
+	WindowElementsField    = "elements"           // Stream[Record] sorted by event time
+	WindowPaneField        = "pane"               // int64, 0 for the first firing; updates under UpdateWindow count up
+)
+
+// eventTimePane is one emission of an event-time window
+type eventTimePane struct {
+	start       time.Time
+	end         time.Time
+	records     []Record
+	lateDropped int64 // Late records left out since the previous pane
+	pane        int64 // 0 for the first firing of a window, then 1, 2, ... for updates
+}
 
-	// Apply default configuration
+// paneStreams emits the elements of each pane as a bare stream
+func paneStreams(panes Stream[eventTimePane]) Stream[Stream[Record]] {
+	return func() (Stream[Record], error) {
+		pane, err := panes()
+		if err != nil {
+			return nil, err
+		}
+		return FromSlice(pane.records), nil
+	}
+}
+
+// paneRecords emits each pane as a Record with its window metadata
+func paneRecords(panes Stream[eventTimePane]) Stream[Record] {
+	return func() (Record, error) {
+		pane, err := panes()
+		if err != nil {
+			return nil, err
+		}
+		return pane.record(), nil
+	}
+}
+
+// record converts the pane to a Record with its window metadata
+func (pane eventTimePane) record() Record {
+	return Record{
+		WindowStartField:       pane.start,
+		WindowEndField:         pane.end,
+		WindowElementCount:     int64(len(pane.records)),
+		WindowLateDroppedCount: pane.lateDropped,
+		WindowElementsField:    FromSlice(pane.records),
+		WindowPaneField:        pane.pane,
+	}
+}
+
+// newEventTimeWindowConfig applies options over the default event-time window configuration
+func newEventTimeWindowConfig(name string, options []EventTimeWindowOption) *EventTimeWindowConfig {
 	config := &EventTimeWindowConfig{
 		LateDataPolicy:     DropLateData,
 		WatermarkGenerator: BoundedOutOfOrdernessWatermark(30 * time.Second), // Default 30s lateness
@@ -673,76 +733,339 @@ func EventTimeSessionWindow(
 	}
 
 	if config.TimestampExtractor == nil {
-		panic("EventTimeSessionWindow requires a timestamp extractor")
+		panic(name + " requires a timestamp extractor")
 	}
+	return config
+}
 
-	return func(input Stream[Record]) Stream[Stream[Record]] {
+// lateRecord handles a record whose window has already fired and returns 1 for the dropped count
+func (config *EventTimeWindowConfig) lateRecord(record Record) int64 {
+	if config.LateDataPolicy == SideOutputLate && config.LateDataSink != nil {
+		config.LateDataSink(record)
+	}
+	return 1
+}
+
+// errSourceIdle is returned by idleInput when no record has arrived for the idle timeout
+var errSourceIdle = errors.New("event-time source idle")
+
+// idleInput reads input through a goroutine and returns errSourceIdle whenever no record
+// arrives within config.IdleTimeout. Without an idle timeout input is returned unchanged.
+func (config *EventTimeWindowConfig) idleInput(input Stream[Record]) Stream[Record] {
+	if config.IdleTimeout <= 0 {
+		return input
+	}
+	parent := config.IdleContext
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	ch := make(chan windowItem[Record])
+	started := false
+
+	var finalErr error // Sticky once the input has ended
+	return func() (Record, error) {
+		if finalErr != nil {
+			return nil, finalErr
+		}
+		if !started {
+			started = true
+			go windowReader(ctx, input, ch)
+		}
+
+		timer := time.NewTimer(config.IdleTimeout)
+		defer timer.Stop()
+
+		select {
+		case next := <-ch:
+			if next.err != nil {
+				cancel()
+				finalErr = next.err
+			}
+			return next.item, next.err
+		case <-timer.C:
+			return nil, errSourceIdle
+		case <-ctx.Done():
+			finalErr = ctx.Err()
+			return nil, finalErr
+		}
+	}
+}
+
+// eventTimeWindowPanes assigns each record to every window of windowSize starting on a
+// multiple of slide that contains it, and emits windows in start order once the watermark
+// passes their end. Records whose windows have all fired are late. Under UpdateWindow fired
+// windows are kept until the watermark passes their end plus the allowed lateness, and late
+// records re-fire them with the next pane index.
+// A non-nil keyFn keeps separate windows per key.
+func eventTimeWindowPanes(windowSize, slide time.Duration, keyFn func(Record) string, config *EventTimeWindowConfig) Filter[Record, eventTimePane] {
+	// windowKey identifies one window of one key
+	type windowKey struct {
+		key   string
+		start time.Time
+	}
+
+	var lateness time.Duration
+	if config.LateDataPolicy == UpdateWindow {
+		lateness = config.AllowedLateness
+	}
+
+	return fused(func(input Stream[Record]) Stream[eventTimePane] {
+		input = config.idleInput(input)
 		watermarkTracker := NewWatermarkTracker(config.WatermarkGenerator)
-		sessionsMap := make(map[string]*EventTimeSessionState) // Using string key for session ID
-		var mu sync.RWMutex
+		windowsMap := make(map[windowKey]*EventTimeWindowState)
+		var lateDropped int64
+		inputDone := false
+
+		checkpoint := config.Checkpoint
+		checkpoint.attach(func() windowCheckpointState {
+			watermark, _ := watermarkTracker.ExportState()
+			state := windowCheckpointState{watermark: watermark, lateDropped: lateDropped}
+			for id, window := range windowsMap {
+				window.mu.RLock()
+				state.windows = append(state.windows, checkpointedWindow{
+					key:          id.key,
+					start:        window.windowStart,
+					end:          window.windowEnd,
+					fired:        window.fired,
+					pane:         window.pane,
+					elements:     slices.Clone(window.elements),
+					lateElements: slices.Clone(window.lateElements),
+				})
+				window.mu.RUnlock()
+			}
+			return state
+		}, func(state windowCheckpointState) {
+			watermarkTracker.RestoreState(state.watermark)
+			lateDropped = state.lateDropped
+			windowsMap = make(map[windowKey]*EventTimeWindowState, len(state.windows))
+			for _, restored := range state.windows {
+				window := NewEventTimeWindowState(restored.start, restored.end, config.LateDataPolicy)
+				window.fired, window.pane = restored.fired, restored.pane
+				window.elements = append(window.elements, restored.elements...)
+				window.lateElements = append(window.lateElements, restored.lateElements...)
+				windowsMap[windowKey{key: restored.key, start: restored.start}] = window
+			}
+		})
 
-		return func() (Stream[Record], error) {
+		return func() (eventTimePane, error) {
+			checkpoint.lock()
+			defer checkpoint.unlock()
 			for {
-				// Get next element from input stream
-				element, err := input()
+				// Fire the earliest ready window (by start, then key); at end of stream every unfired
+				// window is ready. Fired windows are released once no more late updates can arrive.
+				watermark := watermarkTracker.GetWatermark()
+				var readyKey windowKey
+				var ready *EventTimeWindowState
+				for id, window := range windowsMap {
+					switch {
+					case window.ShouldFire(watermark) || (inputDone && !window.HasFired()):
+						if ready == nil || id.start.Before(readyKey.start) || (id.start.Equal(readyKey.start) && id.key < readyKey.key) {
+							readyKey, ready = id, window
+						}
+					case window.HasFired() && (inputDone || !watermark.Before(window.windowEnd.Add(lateness))):
+						delete(windowsMap, id)
+					}
+				}
+				if ready != nil {
+					records := ready.Fire()
+					if config.LateDataPolicy != UpdateWindow {
+						delete(windowsMap, readyKey)
+					}
+					pane := eventTimePane{start: ready.windowStart, end: ready.windowEnd, records: records, lateDropped: lateDropped, pane: int64(ready.pane)}
+					lateDropped = 0
+					return pane, nil
+				}
+				if inputDone {
+					return eventTimePane{}, EOS
+				}
+
+				element, err := checkpoint.pull(input)
+				if err == errSourceIdle {
+					watermarkTracker.AdvanceIdle(StandardizeTime(time.Now()))
+					continue
+				}
 				if err == EOS {
-					// Handle end of stream - fire all remaining sessions
-					mu.Lock()
+					inputDone = true
+					continue
+				}
+				if err != nil {
+					return eventTimePane{}, err
+				}
+
+				// Extract event time and standardize it
+				eventTime := StandardizeTime(config.TimestampExtractor(element))
 
-					// Find any sessions that should fire
-					currentWatermark := watermarkTracker.GetWatermark()
-					var readySessions []*EventTimeSessionState
+				key := ""
+				if keyFn != nil {
+					key = keyFn(element)
+				}
 
-					for _, session := range sessionsMap {
-						if session.ShouldFire(currentWatermark, sessionTimeout) || err == EOS {
-							readySessions = append(readySessions, session)
+				// Every window that ended at or before the watermark has fired by now;
+				// windows still in the map accept the record, as late data if they have fired
+				assigned := false
+				for windowStart := eventTime.Truncate(slide); windowStart.Add(windowSize).After(eventTime); windowStart = windowStart.Add(-slide) {
+					windowEnd := windowStart.Add(windowSize)
+					id := windowKey{key: key, start: windowStart}
+					window, exists := windowsMap[id]
+					if !exists {
+						if !watermark.Before(windowEnd.Add(lateness)) {
+							continue
 						}
+						window = NewEventTimeWindowState(windowStart, windowEnd, config.LateDataPolicy)
+						windowsMap[id] = window
 					}
+					window.AddElement(element, eventTime)
+					assigned = true
+				}
+				if !assigned {
+					lateDropped += config.lateRecord(element)
+				}
 
-					if len(readySessions) > 0 {
-						// Fire the earliest session (by last activity)
-						sort.Slice(readySessions, func(i, j int) bool {
-							return readySessions[i].lastActivity.Before(readySessions[j].lastActivity)
-						})
-
-						session := readySessions[0]
-						result := session.Fire()
-
-						// Remove fired session from map
-						for id, s := range sessionsMap {
-							if s == session {
-								delete(sessionsMap, id)
-								break
-							}
-						}
-						mu.Unlock()
+				watermarkTracker.UpdateWatermark(eventTime)
+			}
+		}
+	})
+}
+
+// eventTimeSessionPanes groups records into per-key sessions that close after sessionTimeout
+// without activity, and emits each session once the watermark passes its last activity plus
+// the timeout. Records whose timeout has already passed the watermark are late. Under
+// UpdateWindow fired sessions are kept until the watermark passes their end plus the timeout
+// and allowed lateness, and late records re-fire the session they fall within.
+func eventTimeSessionPanes(sessionTimeout time.Duration, config *EventTimeWindowConfig) Filter[Record, eventTimePane] {
+	var lateness time.Duration
+	if config.LateDataPolicy == UpdateWindow {
+		lateness = config.AllowedLateness
+	}
+
+	return fused(func(input Stream[Record]) Stream[eventTimePane] {
+		input = config.idleInput(input)
+		watermarkTracker := NewWatermarkTracker(config.WatermarkGenerator)
+		sessionsMap := make(map[string]*EventTimeSessionState) // Using string key for session ID
+		closedSessions := 0                                    // Timed-out sessions still waiting for the watermark
+		var lateDropped int64
+		inputDone := false
+
+		checkpoint := config.Checkpoint
+		checkpoint.attach(func() windowCheckpointState {
+			watermark, _ := watermarkTracker.ExportState()
+			state := windowCheckpointState{watermark: watermark, lateDropped: lateDropped, closedSessions: closedSessions}
+			for id, session := range sessionsMap {
+				session.mu.RLock()
+				state.windows = append(state.windows, checkpointedWindow{
+					key:          id,
+					start:        session.sessionStart,
+					end:          session.sessionEnd,
+					lastActivity: session.lastActivity,
+					fired:        session.fired,
+					pane:         session.pane,
+					elements:     slices.Clone(session.elements),
+					lateElements: slices.Clone(session.lateElements),
+				})
+				session.mu.RUnlock()
+			}
+			return state
+		}, func(state windowCheckpointState) {
+			watermarkTracker.RestoreState(state.watermark)
+			lateDropped, closedSessions = state.lateDropped, state.closedSessions
+			sessionsMap = make(map[string]*EventTimeSessionState, len(state.windows))
+			for _, restored := range state.windows {
+				session := NewEventTimeSessionState(config.LateDataPolicy)
+				session.sessionStart, session.sessionEnd, session.lastActivity = restored.start, restored.end, restored.lastActivity
+				session.fired, session.pane = restored.fired, restored.pane
+				session.elements = append(session.elements, restored.elements...)
+				session.lateElements = append(session.lateElements, restored.lateElements...)
+				sessionsMap[restored.key] = session
+			}
+		})
 
-						if len(result) > 0 {
-							return FromSlice(result), nil
+		return func() (eventTimePane, error) {
+			checkpoint.lock()
+			defer checkpoint.unlock()
+			for {
+				// Fire the earliest ready session (by last activity); at end of stream every unfired
+				// session is ready. Fired sessions are released once no more late updates can arrive.
+				watermark := watermarkTracker.GetWatermark()
+				readyID := ""
+				var ready *EventTimeSessionState
+				for id, session := range sessionsMap {
+					switch {
+					case session.ShouldFire(watermark, sessionTimeout) || (inputDone && !session.HasFired()):
+						if ready == nil || session.lastActivity.Before(ready.lastActivity) {
+							readyID, ready = id, session
 						}
-						// Continue to check for more sessions
-						continue
+					case session.HasFired() && (inputDone || !watermark.Before(session.sessionEnd.Add(sessionTimeout+lateness))):
+						delete(sessionsMap, id)
 					}
-
-					mu.Unlock()
-					return nil, EOS
+				}
+				if ready != nil {
+					records := ready.Fire()
+					if config.LateDataPolicy != UpdateWindow {
+						delete(sessionsMap, readyID)
+					} else if !strings.Contains(readyID, "\x00") {
+						// Keep the fired session for late updates, freeing its key for a new session
+						delete(sessionsMap, readyID)
+						closedSessions++
+						sessionsMap[fmt.Sprintf("%s\x00%d", readyID, closedSessions)] = ready
+					}
+					pane := eventTimePane{start: ready.sessionStart, end: ready.sessionEnd.Add(sessionTimeout), records: records, lateDropped: lateDropped, pane: int64(ready.pane)}
+					lateDropped = 0
+					return pane, nil
+				}
+				if inputDone {
+					return eventTimePane{}, EOS
 				}
 
+				element, err := checkpoint.pull(input)
+				if err == errSourceIdle {
+					watermarkTracker.AdvanceIdle(StandardizeTime(time.Now()))
+					continue
+				}
+				if err == EOS {
+					inputDone = true
+					continue
+				}
 				if err != nil {
-					return nil, err
+					return eventTimePane{}, err
 				}
 
 				// Extract event time and standardize it
-				eventTime := config.TimestampExtractor(element)
-				eventTime = StandardizeTime(eventTime)
+				eventTime := StandardizeTime(config.TimestampExtractor(element))
 
-				// Update watermark
-				watermark := watermarkTracker.UpdateWatermark(eventTime)
+				// Sessions are tracked per key; without a key function every element shares one session
+				sessionID := "global"
+				if config.SessionKey != nil {
+					sessionID = config.SessionKey(element)
+				}
 
-				mu.Lock()
+				// Every session that timed out at or before the watermark has fired by now
+				if !watermark.Before(eventTime.Add(sessionTimeout)) {
+					if !watermark.Before(eventTime.Add(sessionTimeout + lateness)) {
+						lateDropped += config.lateRecord(element)
+						continue
+					}
 
-				// Determine session ID (for now, use a global session - could be extended to per-user sessions)
-				sessionID := "global" // In practice, this could be extracted from the record
+					// Within the allowed lateness: update the fired session the record falls in,
+					// or give it a session of its own
+					var target *EventTimeSessionState
+					for id, session := range sessionsMap {
+						if strings.HasPrefix(id, sessionID+"\x00") && session.HasFired() &&
+							!eventTime.Before(session.sessionStart.Add(-sessionTimeout)) && !eventTime.After(session.sessionEnd.Add(sessionTimeout)) {
+							target = session
+							break
+						}
+					}
+					if target == nil {
+						target = NewEventTimeSessionState(config.LateDataPolicy)
+						closedSessions++
+						sessionsMap[fmt.Sprintf("%s\x00%d", sessionID, closedSessions)] = target
+					}
+					target.AddElement(element, eventTime)
+					continue
+				}
+				watermarkTracker.UpdateWatermark(eventTime)
 
 				// Get or create session
 				session, exists := sessionsMap[sessionID]
@@ -751,66 +1074,333 @@ func EventTimeSessionWindow(
 					sessionsMap[sessionID] = session
 				}
 
-				// Check if this element extends the session or if the session has timed out
+				// An element beyond the timeout closes the current session; park it under a unique ID
+				// until the watermark fires it and start a new session for this key
 				if len(session.elements) > 0 && eventTime.Sub(session.lastActivity) > sessionTimeout {
-					// Session has timed out - fire current session and start new one
-					if session.ShouldFire(watermark, sessionTimeout) {
-						result := session.Fire()
-						delete(sessionsMap, sessionID)
-
-						// Create new session for this element
-						newSession := NewEventTimeSessionState(config.LateDataPolicy)
-						newSession.AddElement(element, eventTime)
-						sessionsMap[sessionID] = newSession
-
-						mu.Unlock()
-
-						if len(result) > 0 {
-							return FromSlice(result), nil
-						}
-						continue
-					}
+					closedSessions++
+					sessionsMap[fmt.Sprintf("%s\x00%d", sessionID, closedSessions)] = session
+					session = NewEventTimeSessionState(config.LateDataPolicy)
+					sessionsMap[sessionID] = session
 				}
 
 				// Add element to session
 				session.AddElement(element, eventTime)
+			}
+		}
+	})
+}
 
-				// Check if any sessions should fire
-				var readySessions []*EventTimeSessionState
-				for _, s := range sessionsMap {
-					if s.ShouldFire(watermark, sessionTimeout) {
-						readySessions = append(readySessions, s)
+// ============================================================================
+// DEDUPLICATION WITHIN A TIME HORIZON
+// ============================================================================
+
+// DeduplicateByKey drops records whose key was kept within window of them, e.g. to
+// suppress redelivered messages. Unlike Distinct its memory is bounded: keys expire
+// once they can no longer match, so only the traffic within the window is held.
+//
+// With WithTimestampExtractor records are compared by event time and keys expire as
+// the watermark advances (WithWatermarkGenerator or WithAllowedLateness; 30 seconds of
+// lateness by default). A record behind the watermark can no longer be checked, so it
+// follows the late-data policy: dropped by default, sent to WithLateDataSink under
+// SideOutputLate, or passed through under UpdateWindow. Without an extractor records
+// are compared by processing time.
+//
+// Duplicates don't extend the horizon; WithDuplicateSink receives each one.
+func DeduplicateByKey(keyFn func(Record) string, window time.Duration, options ...EventTimeWindowOption) Filter[Record, Record] {
+	if window <= 0 {
+		panic("DeduplicateByKey window must be positive")
+	}
+	config := &EventTimeWindowConfig{
+		LateDataPolicy:     DropLateData,
+		WatermarkGenerator: BoundedOutOfOrdernessWatermark(30 * time.Second), // Default 30s lateness
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	return func(input Stream[Record]) Stream[Record] {
+		type keptRecord struct {
+			key       string
+			timestamp time.Time
+		}
+
+		var tracker *WatermarkTracker
+		if config.TimestampExtractor != nil {
+			tracker = NewWatermarkTracker(config.WatermarkGenerator)
+		}
+		seen := make(map[string][]time.Time) // Times of the kept records of each key
+		var kept []keptRecord                // In arrival order, for expiry
+
+		// expire forgets kept records no later record can fall within window of
+		expire := func(watermark time.Time) {
+			horizon := watermark.Add(-window)
+			for len(kept) > 0 && !kept[0].timestamp.After(horizon) {
+				oldest := kept[0]
+				kept = kept[1:]
+				times := seen[oldest.key]
+				for i, t := range times {
+					if t.Equal(oldest.timestamp) {
+						times = append(times[:i], times[i+1:]...)
+						break
 					}
 				}
+				if len(times) == 0 {
+					delete(seen, oldest.key)
+				} else {
+					seen[oldest.key] = times
+				}
+			}
+			if len(kept) == 0 {
+				kept = nil // Release the backing array
+			}
+		}
 
-				if len(readySessions) > 0 {
-					// Fire the earliest ready session
-					sort.Slice(readySessions, func(i, j int) bool {
-						return readySessions[i].lastActivity.Before(readySessions[j].lastActivity)
-					})
-
-					sessionToFire := readySessions[0]
-					result := sessionToFire.Fire()
+		return func() (Record, error) {
+			for {
+				record, err := input()
+				if err != nil {
+					return nil, err
+				}
 
-					// Remove fired session from map
-					for id, s := range sessionsMap {
-						if s == sessionToFire {
-							delete(sessionsMap, id)
-							break
+				timestamp, watermark := time.Now(), time.Time{}
+				if tracker != nil {
+					timestamp = config.TimestampExtractor(record)
+					watermark = tracker.UpdateWatermark(timestamp)
+					if timestamp.Before(watermark) {
+						if config.LateDataPolicy == UpdateWindow {
+							return record, nil
 						}
+						config.lateRecord(record)
+						continue
 					}
-					mu.Unlock()
+				} else {
+					watermark = timestamp
+				}
+				expire(watermark)
 
-					if len(result) > 0 {
-						return FromSlice(result), nil
+				key := keyFn(record)
+				duplicate := false
+				for _, t := range seen[key] {
+					if d := timestamp.Sub(t); d < window && d > -window {
+						duplicate = true
+						break
+					}
+				}
+				if duplicate {
+					if config.DuplicateSink != nil {
+						config.DuplicateSink(record)
 					}
-					// Continue processing if session was empty
 					continue
 				}
 
-				mu.Unlock()
-				// Continue processing more elements
+				seen[key] = append(seen[key], timestamp)
+				kept = append(kept, keptRecord{key: key, timestamp: timestamp})
+				return record, nil
 			}
 		}
 	}
 }
+
+// ============================================================================
+// EVENT-TIME CHECKPOINTS
+// ============================================================================
+
+// WindowCheckpoint exports and restores the open windows, watermark and late-record
+// count of an event-time window filter created with WithWindowCheckpoint. It implements
+// Checkpointable. State restored before the filter is applied to its input is loaded
+// when it is applied; a checkpoint follows the input the filter was applied to last.
+// Window elements are exported as they are, so fields that don't survive the chosen
+// serialization (e.g. time.Time through JSON) come back converted.
+type WindowCheckpoint struct {
+	mu      sync.Mutex
+	export  func() windowCheckpointState
+	apply   func(windowCheckpointState)
+	pending *windowCheckpointState
+}
+
+// NewWindowCheckpoint creates a checkpoint to pass to WithWindowCheckpoint
+func NewWindowCheckpoint() *WindowCheckpoint {
+	return &WindowCheckpoint{}
+}
+
+// windowCheckpointState is the exportable state of a window filter
+type windowCheckpointState struct {
+	watermark      Record // WatermarkTracker state
+	lateDropped    int64
+	closedSessions int
+	windows        []checkpointedWindow
+}
+
+// checkpointedWindow is one open window or session; lastActivity is for sessions only
+type checkpointedWindow struct {
+	key                      string
+	start, end, lastActivity time.Time
+	fired                    bool
+	pane                     int
+	elements, lateElements   []TimestampedRecord
+}
+
+// ExportState returns the filter's open windows, watermark and late-record count
+func (cp *WindowCheckpoint) ExportState() (Record, error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	var state windowCheckpointState
+	switch {
+	case cp.export != nil:
+		state = cp.export()
+	case cp.pending != nil:
+		state = *cp.pending
+	default:
+		return nil, errors.New("window checkpoint is not connected to a window filter")
+	}
+
+	windows := make([]Record, len(state.windows))
+	for i, window := range state.windows {
+		windows[i] = Record{
+			"key":           window.key,
+			"start":         checkpointTime(window.start),
+			"end":           checkpointTime(window.end),
+			"last_activity": checkpointTime(window.lastActivity),
+			"fired":         window.fired,
+			"pane":          int64(window.pane),
+			"elements":      exportTimestampedRecords(window.elements),
+			"late_elements": exportTimestampedRecords(window.lateElements),
+		}
+	}
+	return Record{
+		"watermark":       state.watermark,
+		"late_dropped":    state.lateDropped,
+		"closed_sessions": int64(state.closedSessions),
+		"windows":         FromSliceAny(windows),
+	}, nil
+}
+
+// RestoreState replaces the filter's windows, watermark and late-record count with an
+// exported state, or keeps it until the filter is applied to its input
+func (cp *WindowCheckpoint) RestoreState(record Record) error {
+	state := windowCheckpointState{}
+	var err error
+
+	if state.watermark, err = checkpointRecord(record, "watermark"); err != nil {
+		return err
+	}
+	if err := NewWatermarkTracker(nil).RestoreState(state.watermark); err != nil {
+		return err
+	}
+	if state.lateDropped, err = checkpointInt(record, "late_dropped"); err != nil {
+		return err
+	}
+	closed, err := checkpointInt(record, "closed_sessions")
+	if err != nil {
+		return err
+	}
+	state.closedSessions = int(closed)
+
+	windows, err := checkpointRecords(record, "windows")
+	if err != nil {
+		return err
+	}
+	for _, window := range windows {
+		restored := checkpointedWindow{}
+		key, ok := window["key"].(string)
+		if !ok {
+			return fmt.Errorf("checkpoint field %q: expected a string, got %v", "key", window["key"])
+		}
+		restored.key = key
+		if restored.start, err = parseCheckpointTime(window, "start"); err != nil {
+			return err
+		}
+		if restored.end, err = parseCheckpointTime(window, "end"); err != nil {
+			return err
+		}
+		if restored.lastActivity, err = parseCheckpointTime(window, "last_activity"); err != nil {
+			return err
+		}
+		if restored.fired, ok = window["fired"].(bool); !ok {
+			return fmt.Errorf("checkpoint field %q: expected a bool, got %v", "fired", window["fired"])
+		}
+		pane, err := checkpointInt(window, "pane")
+		if err != nil {
+			return err
+		}
+		restored.pane = int(pane)
+		if restored.elements, err = restoreTimestampedRecords(window, "elements"); err != nil {
+			return err
+		}
+		if restored.lateElements, err = restoreTimestampedRecords(window, "late_elements"); err != nil {
+			return err
+		}
+		state.windows = append(state.windows, restored)
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.apply != nil {
+		cp.apply(state)
+	} else {
+		cp.pending = &state
+	}
+	return nil
+}
+
+// attach connects a window filter's state, loading any state restored beforehand
+func (cp *WindowCheckpoint) attach(export func() windowCheckpointState, apply func(windowCheckpointState)) {
+	if cp == nil {
+		return
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.export, cp.apply = export, apply
+	if cp.pending != nil {
+		apply(*cp.pending)
+		cp.pending = nil
+	}
+}
+
+// lock guards a window filter's state while it processes; a nil checkpoint is a no-op
+func (cp *WindowCheckpoint) lock() {
+	if cp != nil {
+		cp.mu.Lock()
+	}
+}
+
+func (cp *WindowCheckpoint) unlock() {
+	if cp != nil {
+		cp.mu.Unlock()
+	}
+}
+
+// pull reads the next record with the state unlocked, so exports don't wait on a slow source
+func (cp *WindowCheckpoint) pull(input Stream[Record]) (Record, error) {
+	cp.unlock()
+	defer cp.lock()
+	return input()
+}
+
+// exportTimestampedRecords stores elements as a stream field of {record, timestamp} Records
+func exportTimestampedRecords(elements []TimestampedRecord) Stream[Record] {
+	records := make([]Record, len(elements))
+	for i, element := range elements {
+		records[i] = Record{"record": element.Record, "timestamp": checkpointTime(element.Timestamp)}
+	}
+	return FromSliceAny(records)
+}
+
+// restoreTimestampedRecords reads elements stored by exportTimestampedRecords
+func restoreTimestampedRecords(state Record, field string) ([]TimestampedRecord, error) {
+	records, err := checkpointRecords(state, field)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]TimestampedRecord, len(records))
+	for i, record := range records {
+		if elements[i].Record, err = checkpointRecord(record, "record"); err != nil {
+			return nil, err
+		}
+		if elements[i].Timestamp, err = parseCheckpointTime(record, "timestamp"); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
@@ -0,0 +1,569 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEventTimeSessionWindow tests per-key event-time session windows
+func TestEventTimeSessionWindow(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(user string, offset time.Duration, action string) Record {
+		return Record{"user_id": user, "ts": base.Add(offset), "action": action}
+	}
+	// Interleaved users with overlapping timestamps, then a later second session for alice
+	events := []Record{
+		event("alice", 0, "login"),
+		event("bob", 1*time.Second, "login"),
+		event("alice", 2*time.Second, "view"),
+		event("bob", 3*time.Second, "view"),
+		event("alice", 4*time.Second, "logout"),
+		event("alice", 60*time.Second, "login"),
+	}
+	sessions := func(options ...EventTimeWindowOption) []string {
+		options = append([]EventTimeWindowOption{
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(0)),
+		}, options...)
+		windows, err := Collect(EventTimeSessionWindow(10*time.Second, options...)(FromRecordsUnsafe(events)))
+		if err != nil {
+			t.Fatalf("Failed to collect sessions: %v", err)
+		}
+
+		var result []string
+		for _, window := range windows {
+			records, _ := Collect(window)
+			var members []string
+			for _, r := range records {
+				members = append(members, fmt.Sprintf("%s:%s", r["user_id"], r["action"]))
+			}
+			result = append(result, strings.Join(members, " "))
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	t.Run("PerKeySessions", func(t *testing.T) {
+		got := sessions(WithSessionKeyFields("user_id"))
+
+		expected := []string{
+			"alice:login",
+			"alice:login alice:view alice:logout",
+			"bob:login bob:view",
+		}
+		if strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected sessions %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("CustomKeyFunction", func(t *testing.T) {
+		got := sessions(WithSessionKey(func(r Record) string {
+			return strings.ToUpper(GetOr(r, "user_id", ""))
+		}))
+
+		if len(got) != 3 {
+			t.Errorf("Expected 3 sessions, got %v", got)
+		}
+	})
+
+	t.Run("GlobalSessionWithoutKey", func(t *testing.T) {
+		got := sessions()
+
+		expected := []string{
+			"alice:login",
+			"alice:login bob:login alice:view bob:view alice:logout",
+		}
+		if strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected sessions %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("TimedOutSessionWaitsForWatermark", func(t *testing.T) {
+		// With a lagging watermark alice's first session can't fire when her next event arrives,
+		// but it must still be closed rather than extended
+		got := sessions(WithSessionKeyFields("user_id"), WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(time.Hour)))
+
+		expected := []string{
+			"alice:login",
+			"alice:login alice:view alice:logout",
+			"bob:login bob:view",
+		}
+		if strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected sessions %v, got %v", expected, got)
+		}
+	})
+}
+
+// TestEventTimeWindowResults tests window metadata and late-data side output
+func TestEventTimeWindowResults(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(id string, offset time.Duration) Record {
+		return Record{"id": id, "ts": base.Add(offset)}
+	}
+	// "late" arrives after the watermark has passed the end of its window
+	events := []Record{
+		event("a", 0),
+		event("b", 3*time.Second),
+		event("c", 12*time.Second),
+		event("late", 5*time.Second),
+		event("d", 25*time.Second),
+	}
+	options := func(extra ...EventTimeWindowOption) []EventTimeWindowOption {
+		return append([]EventTimeWindowOption{
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(0)),
+		}, extra...)
+	}
+	ids := func(result Record) string {
+		elements, _ := Collect(GetOr(result, WindowElementsField, Stream[Record](nil)))
+		var members []string
+		for _, r := range elements {
+			members = append(members, GetOr(r, "id", ""))
+		}
+		return strings.Join(members, " ")
+	}
+
+	t.Run("TumblingBoundaries", func(t *testing.T) {
+		results, err := Collect(EventTimeTumblingWindowResults(10*time.Second, options()...)(FromRecordsUnsafe(events)))
+		if err != nil {
+			t.Fatalf("Failed to collect results: %v", err)
+		}
+
+		expected := []struct {
+			start, end time.Duration
+			count      int64
+			late       int64
+			ids        string
+		}{
+			{0, 10 * time.Second, 2, 0, "a b"},
+			{10 * time.Second, 20 * time.Second, 1, 1, "c"},
+			{20 * time.Second, 30 * time.Second, 1, 0, "d"},
+		}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d windows, got %v", len(expected), results)
+		}
+		for i, want := range expected {
+			got := results[i]
+			if got[WindowStartField] != base.Add(want.start) || got[WindowEndField] != base.Add(want.end) {
+				t.Errorf("Window %d: expected [%v, %v), got [%v, %v)", i, base.Add(want.start), base.Add(want.end), got[WindowStartField], got[WindowEndField])
+			}
+			if got[WindowElementCount] != want.count || got[WindowLateDroppedCount] != want.late {
+				t.Errorf("Window %d: expected %d elements and %d late, got %v and %v", i, want.count, want.late, got[WindowElementCount], got[WindowLateDroppedCount])
+			}
+			if members := ids(got); members != want.ids {
+				t.Errorf("Window %d: expected elements %q, got %q", i, want.ids, members)
+			}
+		}
+	})
+
+	t.Run("SideOutputLate", func(t *testing.T) {
+		var late []string
+		sink := WithLateDataSink(func(r Record) {
+			late = append(late, GetOr(r, "id", ""))
+		})
+
+		windows, err := Collect(EventTimeTumblingWindow(10*time.Second, options(WithLateDataPolicy(SideOutputLate), sink)...)(FromRecordsUnsafe(events)))
+		if err != nil {
+			t.Fatalf("Failed to collect windows: %v", err)
+		}
+		if len(windows) != 3 {
+			t.Errorf("Expected 3 windows, got %d", len(windows))
+		}
+		if len(late) != 1 || late[0] != "late" {
+			t.Errorf("Expected the late record in the side output, got %v", late)
+		}
+
+		late = nil
+		Collect(EventTimeTumblingWindow(10*time.Second, options(sink)...)(FromRecordsUnsafe(events)))
+		if len(late) != 0 {
+			t.Errorf("Expected no side output under DropLateData, got %v", late)
+		}
+	})
+
+	t.Run("SlidingBoundaries", func(t *testing.T) {
+		results, _ := Collect(EventTimeSlidingWindowResults(10*time.Second, 5*time.Second, options()...)(FromRecordsUnsafe(events[:3])))
+
+		var got []string
+		for _, r := range results {
+			start := r[WindowStartField].(time.Time).Sub(base)
+			end := r[WindowEndField].(time.Time).Sub(base)
+			got = append(got, fmt.Sprintf("[%v,%v):%s", start, end, ids(r)))
+		}
+		expected := "[-5s,5s):a b|[0s,10s):a b|[5s,15s):c|[10s,20s):c"
+		if strings.Join(got, "|") != expected {
+			t.Errorf("Expected %s, got %s", expected, strings.Join(got, "|"))
+		}
+	})
+
+	t.Run("SessionBoundaries", func(t *testing.T) {
+		results, _ := Collect(EventTimeSessionWindowResults(5*time.Second, options()...)(FromRecordsUnsafe(events)))
+
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 sessions, got %v", results)
+		}
+		first := results[0]
+		if first[WindowStartField] != base || first[WindowEndField] != base.Add(8*time.Second) {
+			t.Errorf("Expected first session [0s, 8s), got [%v, %v)", first[WindowStartField], first[WindowEndField])
+		}
+		if results[1][WindowLateDroppedCount] != int64(1) {
+			t.Errorf("Expected the late record counted on the second session, got %v", results[1])
+		}
+	})
+
+	t.Run("CrossFlattenElements", func(t *testing.T) {
+		flattened, _ := Collect(DotFlatten(".")(CrossFlatten(".", WindowElementsField)(
+			EventTimeTumblingWindowResults(10*time.Second, options()...)(FromRecordsUnsafe(events)))))
+
+		if len(flattened) != 4 {
+			t.Fatalf("Expected one record per element, got %v", flattened)
+		}
+		if flattened[1]["elements.id"] != "b" || flattened[1][WindowStartField] != base {
+			t.Errorf("Expected element b with its window start, got %v", flattened[1])
+		}
+	})
+}
+
+// TestEventTimeUpdateWindow tests re-firing windows with late data under UpdateWindow
+func TestEventTimeUpdateWindow(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(id string, offset time.Duration) Record {
+		return Record{"id": id, "ts": base.Add(offset)}
+	}
+	options := []EventTimeWindowOption{
+		WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+		WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(0)),
+		WithLateDataPolicy(UpdateWindow),
+		WithAllowedLateness(10 * time.Second),
+	}
+	describe := func(results []Record) string {
+		var panes []string
+		for _, r := range results {
+			elements, _ := Collect(r[WindowElementsField].(Stream[Record]))
+			var ids []string
+			for _, e := range elements {
+				ids = append(ids, GetOr(e, "id", ""))
+			}
+			panes = append(panes, fmt.Sprintf("%v#%d:%s(late %d)",
+				r[WindowStartField].(time.Time).Sub(base), r[WindowPaneField], strings.Join(ids, " "), r[WindowLateDroppedCount]))
+		}
+		return strings.Join(panes, "|")
+	}
+
+	t.Run("TumblingRefire", func(t *testing.T) {
+		events := []Record{
+			event("a", 0),
+			event("b", 3*time.Second),
+			event("c", 12*time.Second),   // Fires [0s, 10s)
+			event("late", 5*time.Second), // After firing but within the allowed lateness
+			event("d", 25*time.Second),   // Watermark passes 10s + 10s lateness, releasing [0s, 10s)
+			event("too-late", 6*time.Second),
+		}
+
+		results, err := Collect(EventTimeTumblingWindowResults(10*time.Second, options...)(FromRecordsUnsafe(events)))
+		if err != nil {
+			t.Fatalf("Failed to collect results: %v", err)
+		}
+
+		expected := "0s#0:a b(late 0)|0s#1:a b late(late 0)|10s#0:c(late 0)|20s#0:d(late 1)"
+		if got := describe(results); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	})
+
+	t.Run("SessionRefire", func(t *testing.T) {
+		events := []Record{
+			event("a", 0),
+			event("b", 3*time.Second),
+			event("c", 12*time.Second),
+			event("late", 5*time.Second),
+		}
+
+		results, _ := Collect(EventTimeSessionWindowResults(5*time.Second, options...)(FromRecordsUnsafe(events)))
+
+		expected := "0s#0:a b(late 0)|0s#1:a b late(late 0)|12s#0:c(late 0)"
+		if got := describe(results); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+		if results[1][WindowEndField] != base.Add(10*time.Second) {
+			t.Errorf("Expected the update to extend the session end to 10s, got %v", results[1][WindowEndField])
+		}
+	})
+
+	t.Run("BareWindowsEmitUpdates", func(t *testing.T) {
+		events := []Record{event("a", 0), event("c", 12*time.Second), event("late", 5*time.Second)}
+
+		windows, _ := Collect(EventTimeTumblingWindow(10*time.Second, options...)(FromRecordsUnsafe(events)))
+
+		var sizes []int
+		for _, window := range windows {
+			records, _ := Collect(window)
+			sizes = append(sizes, len(records))
+		}
+		if fmt.Sprint(sizes) != "[1 2 1]" {
+			t.Errorf("Expected window sizes [1 2 1], got %v", sizes)
+		}
+	})
+}
+
+// TestEventTimeIdleTimeout tests firing windows while the source is quiet
+func TestEventTimeIdleTimeout(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	burst := []Record{
+		{"id": "a", "ts": base},
+		{"id": "b", "ts": base.Add(12 * time.Second)},
+	}
+
+	t.Run("BurstThenSilence", func(t *testing.T) {
+		// A live source that sends a two-window burst and then goes quiet until released
+		release := make(chan struct{})
+		defer close(release)
+		sent := 0
+		source := func() (Record, error) {
+			if sent < len(burst) {
+				sent++
+				return burst[sent-1], nil
+			}
+			<-release
+			return nil, EOS
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		windows := EventTimeTumblingWindowResults(10*time.Second,
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(time.Minute)),
+			WithIdleTimeoutContext(ctx, 50*time.Millisecond),
+		)(source)
+
+		start := time.Now()
+		for i, expected := range []time.Time{base, base.Add(10 * time.Second)} {
+			result, err := windows()
+			if err != nil {
+				t.Fatalf("Window %d: unexpected error %v", i, err)
+			}
+			if result[WindowStartField] != expected {
+				t.Errorf("Window %d: expected start %v, got %v", i, expected, result[WindowStartField])
+			}
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected both windows within the idle timeout, took %v", elapsed)
+		}
+
+		cancel()
+		if _, err := windows(); err != context.Canceled {
+			t.Errorf("Expected context.Canceled after cancel, got %v", err)
+		}
+	})
+
+	t.Run("FinalFlushInStartOrder", func(t *testing.T) {
+		events := []Record{
+			{"id": "c", "ts": base.Add(25 * time.Second)},
+			{"id": "a", "ts": base.Add(5 * time.Second)},
+			{"id": "b", "ts": base.Add(15 * time.Second)},
+		}
+
+		results, err := Collect(EventTimeTumblingWindowResults(10*time.Second,
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(time.Hour)),
+			WithIdleTimeout(time.Minute),
+		)(FromRecordsUnsafe(events)))
+		if err != nil {
+			t.Fatalf("Failed to collect results: %v", err)
+		}
+
+		var starts []time.Duration
+		for _, r := range results {
+			starts = append(starts, r[WindowStartField].(time.Time).Sub(base))
+		}
+		if fmt.Sprint(starts) != "[0s 10s 20s]" {
+			t.Errorf("Expected windows flushed in start order, got %v", starts)
+		}
+	})
+}
+
+// TestKeyedEventTimeTumblingWindow tests per-key event-time windows
+func TestKeyedEventTimeTumblingWindow(t *testing.T) {
+	base := time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)
+	trade := func(symbol string, offset time.Duration, price float64, volume int64) Record {
+		return Record{"symbol": symbol, "ts": base.Add(offset), "price": price, "volume": volume}
+	}
+	// Interleaved symbols with out-of-order trades inside the watermark's lateness
+	trades := []Record{
+		trade("AAPL", 10*time.Second, 150.0, 100),
+		trade("MSFT", 5*time.Second, 300.0, 50),
+		trade("AAPL", 40*time.Second, 152.0, 200),
+		trade("AAPL", 2*time.Second, 149.0, 10), // Out of order: earliest AAPL trade of the minute
+		trade("MSFT", 50*time.Second, 298.0, 70),
+		trade("AAPL", 70*time.Second, 155.0, 300),
+		trade("MSFT", 30*time.Second, 305.0, 20), // Out of order
+		trade("MSFT", 80*time.Second, 301.0, 40),
+	}
+	options := []EventTimeWindowOption{
+		WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+		WithWatermarkGenerator(BoundedOutOfOrdernessWatermark(time.Minute)),
+	}
+
+	t.Run("OHLCBars", func(t *testing.T) {
+		bars, err := Collect(KeyedEventTimeTumblingWindow([]string{"symbol"}, time.Minute, append(options,
+			WithWindowAggregators(
+				FirstField("open", "price"),
+				MaxField[float64]("high", "price"),
+				MinField[float64]("low", "price"),
+				LastField("close", "price"),
+				SumField[int64]("volume", "volume"),
+			))...)(FromRecordsUnsafe(trades)))
+		if err != nil {
+			t.Fatalf("Failed to collect bars: %v", err)
+		}
+
+		var got []string
+		for _, bar := range bars {
+			got = append(got, fmt.Sprintf("%s@%v %v/%v/%v/%v vol=%v",
+				bar["symbol"], bar[WindowStartField].(time.Time).Sub(base), bar["open"], bar["high"], bar["low"], bar["close"], bar["volume"]))
+			if _, exists := bar[WindowElementsField]; exists {
+				t.Errorf("Expected aggregates instead of elements, got %v", bar)
+			}
+		}
+		expected := []string{
+			"AAPL@0s 149/152/149/152 vol=310",
+			"MSFT@0s 300/305/298/298 vol=140",
+			"AAPL@1m0s 155/155/155/155 vol=300",
+			"MSFT@1m0s 301/301/301/301 vol=40",
+		}
+		if strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("NestedElements", func(t *testing.T) {
+		windows, _ := Collect(KeyedEventTimeTumblingWindow([]string{"symbol"}, time.Minute, options...)(FromRecordsUnsafe(trades[:4])))
+
+		if len(windows) != 2 {
+			t.Fatalf("Expected 2 (key, window) results, got %v", windows)
+		}
+		aapl := windows[0]
+		elements, _ := Collect(aapl[WindowElementsField].(Stream[Record]))
+		if aapl["symbol"] != "AAPL" || aapl[WindowEndField] != base.Add(time.Minute) || len(elements) != 3 || elements[0]["price"] != 149.0 {
+			t.Errorf("Expected AAPL window [0s, 1m) with 3 time-ordered trades, got %v with %v", aapl, elements)
+		}
+	})
+}
+
+// TestDeduplicateByKey tests suppressing repeated keys within a time horizon
+func TestDeduplicateByKey(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	message := func(id string, offset time.Duration) Record {
+		return Record{"id": id, "ts": base.Add(offset)}
+	}
+	byID := func(r Record) string { return GetOr(r, "id", "") }
+	kept := func(results []Record) string {
+		var keys []string
+		for _, r := range results {
+			keys = append(keys, fmt.Sprintf("%s@%v", r["id"], r["ts"].(time.Time).Sub(base)))
+		}
+		return strings.Join(keys, " ")
+	}
+
+	t.Run("EventTimeInsideAndOutsideWindow", func(t *testing.T) {
+		messages := []Record{
+			message("a", 0),
+			message("b", time.Second),
+			message("a", 5*time.Second),  // Redelivered within 10s: dropped
+			message("a", 12*time.Second), // Outside the window of the first: kept
+			message("b", 30*time.Second), // Kept
+			message("a", 21*time.Second), // Out of order, within the lateness and 10s of a@12s: dropped
+		}
+		duplicates := 0
+		results, err := Collect(DeduplicateByKey(byID, 10*time.Second,
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithAllowedLateness(15*time.Second),
+			WithDuplicateSink(func(Record) { duplicates++ }),
+		)(FromRecordsUnsafe(messages)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := kept(results); got != "a@0s b@1s a@12s b@30s" {
+			t.Errorf("Unexpected records kept: %s", got)
+		}
+		if duplicates != 2 {
+			t.Errorf("Expected 2 duplicates suppressed, got %d", duplicates)
+		}
+	})
+
+	t.Run("OutOfOrderDuplicateOfLaterRecord", func(t *testing.T) {
+		// The earlier-stamped copy arrives second but is still within 10s of the first
+		messages := []Record{message("a", 8*time.Second), message("a", 0)}
+		results, _ := Collect(DeduplicateByKey(byID, 10*time.Second,
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithAllowedLateness(20*time.Second),
+		)(FromRecordsUnsafe(messages)))
+		if got := kept(results); got != "a@8s" {
+			t.Errorf("Unexpected records kept: %s", got)
+		}
+	})
+
+	t.Run("LateRecords", func(t *testing.T) {
+		messages := []Record{
+			message("a", 0),
+			message("b", 60*time.Second),
+			message("a", 5*time.Second), // Behind the watermark (b@60s - 15s)
+		}
+		var late []Record
+		results, _ := Collect(DeduplicateByKey(byID, 10*time.Second,
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithAllowedLateness(15*time.Second),
+			WithLateDataPolicy(SideOutputLate),
+			WithLateDataSink(func(r Record) { late = append(late, r) }),
+		)(FromRecordsUnsafe(messages)))
+		if got := kept(results); got != "a@0s b@1m0s" {
+			t.Errorf("Unexpected records kept: %s", got)
+		}
+		if got := kept(late); got != "a@5s" {
+			t.Errorf("Expected the late record in the side output, got %s", got)
+		}
+	})
+
+	t.Run("RepeatsAfterExpiry", func(t *testing.T) {
+		// 1000 unique keys, then the same keys 1000s later once they have expired
+		var records []Record
+		for round := 0; round < 2; round++ {
+			for i := 0; i < 1000; i++ {
+				records = append(records, message(fmt.Sprintf("m%d", i), time.Duration(round*1000+i)*time.Second))
+			}
+		}
+		results, err := Collect(DeduplicateByKey(byID, 10*time.Second,
+			WithTimestampExtractor(NewRecordTimestampExtractor("ts")),
+			WithAllowedLateness(5*time.Second),
+		)(FromRecordsUnsafe(records)))
+		if err != nil || len(results) != 2000 {
+			t.Errorf("Expected all 2000 records kept, got %d (%v)", len(results), err)
+		}
+	})
+
+	t.Run("ProcessingTime", func(t *testing.T) {
+		ids := []string{"a", "a", "b", "a"}
+		i := 0
+		source := func() (Record, error) {
+			if i >= len(ids) {
+				return nil, EOS
+			}
+			if i == 3 {
+				time.Sleep(30 * time.Millisecond)
+			}
+			i++
+			return Record{"id": ids[i-1]}, nil
+		}
+		results, _ := Collect(DeduplicateByKey(byID, 20*time.Millisecond)(source))
+		var got []string
+		for _, r := range results {
+			got = append(got, GetOr(r, "id", ""))
+		}
+		if strings.Join(got, " ") != "a b a" {
+			t.Errorf("Expected a b a, got %v", got)
+		}
+	})
+}
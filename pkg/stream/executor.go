@@ -3,6 +3,8 @@ package stream
 import (
 	"context"
 	"reflect"
+	"runtime"
+	"sync/atomic"
 )
 
 // ============================================================================
@@ -137,6 +139,81 @@ func getExecutor(op Operation, ctx ExecutionContext) Executor {
 	return globalExecutorManager.SelectBest(op, ctx)
 }
 
+// ============================================================================
+// EXECUTION POLICY - WHEN MAP AND WHERE MAY PARALLELIZE
+// ============================================================================
+
+// ExecutionPolicy controls whether Map and Where may run their function on
+// several goroutines. It does not affect MapSeq/WhereSeq or MapPar/WherePar,
+// which always run the way their names say.
+type ExecutionPolicy int32
+
+const (
+	// Sequential runs Map and Where on the consumer's goroutine (the default),
+	// so closures over non-thread-safe state are safe
+	Sequential ExecutionPolicy = iota
+	// Adaptive lets Map and Where parallelize based on estimated function
+	// complexity; functions must then be safe for concurrent use. Prefer
+	// hinting individual calls, such as Map(fn, CPUHeavy).
+	Adaptive
+)
+
+var executionPolicy atomic.Int32
+
+// SetExecutionPolicy sets the package-wide policy for Map and Where and
+// returns the previous one. Filters read it when they are applied to a stream.
+func SetExecutionPolicy(policy ExecutionPolicy) ExecutionPolicy {
+	return ExecutionPolicy(executionPolicy.Swap(int32(policy)))
+}
+
+// CurrentExecutionPolicy returns the package-wide policy for Map and Where
+func CurrentExecutionPolicy() ExecutionPolicy {
+	return ExecutionPolicy(executionPolicy.Load())
+}
+
+// Hint tells Map and Where what their function costs, so they run it the right way
+// without guessing. An explicit hint takes precedence over the execution policy, and
+// any hint but Cheap asserts that the function is safe for concurrent use.
+type Hint int
+
+const (
+	// Cheap runs the function on the consumer's goroutine, even under Adaptive
+	Cheap Hint = iota
+	// CPUHeavy runs the function on one worker per available CPU
+	CPUHeavy
+	// IOBound runs the function on several workers per CPU, for functions that
+	// mostly wait on the network or disk
+	IOBound
+	// AutoParallel estimates the function's complexity and parallelizes on that
+	// basis, as the Adaptive policy does, whatever the policy is
+	AutoParallel
+)
+
+// estimateWorkers is returned by hintWorkers when Map or Where should estimate
+const estimateWorkers = -1
+
+// hintWorkers resolves the hints given to Map or Where: 0 means run sequentially,
+// estimateWorkers means estimate from the function, anything else is a worker count.
+// Without a hint the execution policy decides; with several, the last one wins.
+func hintWorkers(hints []Hint) int {
+	if len(hints) == 0 {
+		if CurrentExecutionPolicy() == Sequential {
+			return 0
+		}
+		return estimateWorkers
+	}
+	switch hints[len(hints)-1] {
+	case CPUHeavy:
+		return runtime.GOMAXPROCS(0)
+	case IOBound:
+		return 4 * runtime.GOMAXPROCS(0)
+	case AutoParallel:
+		return estimateWorkers
+	default:
+		return 0
+	}
+}
+
 // ============================================================================
 // OPERATION BUILDERS - HELP CREATE OPERATION METADATA
 // ============================================================================
@@ -0,0 +1,297 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ============================================================================
+// FAST CSV SOURCES - QUOTE-AWARE SPLITTING WITHOUT encoding/csv
+// ============================================================================
+
+// FastCSVSource reads comma-separated data like FastTSVSource, but honours quoted
+// fields: a quoted field may hold separators, line breaks and quotes doubled as "".
+// CRLF line endings are accepted. Values are typed as FastTSVSource types them - int64,
+// float64, bool or string, with no time probing - unless ColumnTypes or RawStrings say
+// otherwise. Each value is typed on its own, not by column as CSVSource does by default.
+type FastCSVSource struct {
+	Reader      io.Reader
+	HasHeader   bool
+	Separator   string // Any string, typically ","
+	Headers     []string
+	ColumnTypes map[string]FieldKind // Columns parsed as one kind instead of inferred
+	RawStrings  bool                 // Leave columns not in ColumnTypes as strings
+	Projection  []string             // When set, the only columns read into records
+}
+
+// NewFastCSVSource creates a fast CSV source with a header row
+func NewFastCSVSource(reader io.Reader) *FastCSVSource {
+	return &FastCSVSource{
+		Reader:    reader,
+		HasHeader: true,
+		Separator: ",",
+	}
+}
+
+// NewFastCSVSourceWithSeparator creates a fast quote-aware source with a custom separator
+func NewFastCSVSourceWithSeparator(reader io.Reader, separator string) *FastCSVSource {
+	return &FastCSVSource{
+		Reader:    reader,
+		HasHeader: true,
+		Separator: separator,
+	}
+}
+
+// WithHeaders sets custom headers for the fast CSV source
+func (s *FastCSVSource) WithHeaders(headers []string) *FastCSVSource {
+	s.Headers = headers
+	s.HasHeader = false // Custom headers means no header row in data
+	return s
+}
+
+// WithoutHeader indicates the data has no header row
+func (s *FastCSVSource) WithoutHeader() *FastCSVSource {
+	s.HasHeader = false
+	return s
+}
+
+// WithColumnTypes parses the named columns as the given kinds rather than inferring
+// each value's type. A value that doesn't parse as its kind is kept as a string.
+func (s *FastCSVSource) WithColumnTypes(types map[string]FieldKind) *FastCSVSource {
+	s.ColumnTypes = types
+	return s
+}
+
+// WithRawStrings keeps every value not covered by WithColumnTypes as a string
+func (s *FastCSVSource) WithRawStrings() *FastCSVSource {
+	s.RawStrings = true
+	return s
+}
+
+// WithProjection reads only the named columns into records. Other fields are still
+// scanned for quotes, but are neither copied, typed nor stored. With no fields, every
+// column is read.
+func (s *FastCSVSource) WithProjection(fields ...string) *FastCSVSource {
+	s.Projection = fields
+	return s
+}
+
+// SetProjection is WithProjection for ProjectableSource
+func (s *FastCSVSource) SetProjection(fields ...string) { s.WithProjection(fields...) }
+
+// ToStream converts the fast CSV source to a Record stream. An unterminated quoted
+// field ends the stream with an error.
+func (s *FastCSVSource) ToStream() Stream[Record] {
+	reader := &csvFieldReader{scanner: bufio.NewScanner(s.Reader), separator: []byte(s.Separator)}
+
+	var headers []string
+	var parsers []func(string) any
+	headerRead := !s.HasHeader
+	projected := projectionSet(s.Projection)
+
+	return func() (Record, error) {
+		for {
+			fields, err := reader.next()
+			if err != nil {
+				return nil, err
+			}
+			if fields == nil {
+				continue // Blank line
+			}
+
+			if !headerRead {
+				headers = append([]string(nil), fields...)
+				headerRead = true
+				continue
+			}
+
+			if len(s.Headers) > 0 {
+				headers = s.Headers
+			}
+			if len(headers) == 0 {
+				headers = make([]string, len(fields))
+				for i := range headers {
+					headers[i] = fmt.Sprintf("field_%d", i)
+				}
+			}
+			if len(parsers) != len(headers) {
+				parsers = fastColumnParsers(headers, s.ColumnTypes, s.RawStrings)
+			}
+			if projected != nil && reader.wanted == nil {
+				reader.wanted = wantedColumns(headers, projected)
+			}
+
+			record := make(Record, len(headers))
+			for i, field := range fields {
+				if i < len(headers) && (reader.wanted == nil || reader.wanted[i]) {
+					record[headers[i]] = parsers[i](field)
+				}
+			}
+			return record, nil
+		}
+	}
+}
+
+// csvFieldReader splits scanned lines into quote-aware fields, reading on past line
+// ends that fall inside quotes
+type csvFieldReader struct {
+	scanner   *bufio.Scanner
+	separator []byte
+	line      int
+	field     []byte   // Reused buffer for the field being unquoted
+	fields    []string // Reused result slice
+	wanted    []bool   // When set, fields at other positions are returned empty
+}
+
+// keep returns the text of the next field, or "" if it isn't wanted
+func (r *csvFieldReader) keep(field []byte) string {
+	if i := len(r.fields); r.wanted != nil && (i >= len(r.wanted) || !r.wanted[i]) {
+		return ""
+	}
+	return string(field)
+}
+
+// next returns the fields of the next line, nil for a blank line, or EOS at the end
+// of the input. Unquoted fields are trimmed of surrounding space; quoted ones are kept
+// as written.
+func (r *csvFieldReader) next() ([]string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading CSV: %w", err)
+		}
+		return nil, EOS
+	}
+	r.line++
+	line := r.scanner.Bytes() // ScanLines already dropped any \r before the \n
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, nil
+	}
+
+	r.fields = r.fields[:0]
+	for {
+		// Skip space before an opening quote, unless the space is the separator
+		start := len(line) - len(bytes.TrimLeftFunc(line, func(c rune) bool {
+			return (c == ' ' || c == '\t') && !bytes.ContainsRune(r.separator, c)
+		}))
+
+		if start < len(line) && line[start] == '"' {
+			var err error
+			if line, err = r.quoted(line[start+1:]); err != nil {
+				return nil, err
+			}
+			// Anything between the closing quote and the separator is kept too
+			before, after, more := bytes.Cut(line, r.separator)
+			r.field = append(r.field, bytes.TrimSpace(before)...)
+			r.fields = append(r.fields, r.keep(r.field))
+			if !more {
+				return r.fields, nil
+			}
+			line = after
+			continue
+		}
+
+		before, after, more := bytes.Cut(line, r.separator)
+		r.fields = append(r.fields, r.keep(bytes.TrimSpace(before)))
+		if !more {
+			return r.fields, nil
+		}
+		line = after
+	}
+}
+
+// quoted unquotes a field whose opening quote has been consumed into r.field, scanning
+// further lines if the field spans them, and returns what follows the closing quote
+func (r *csvFieldReader) quoted(line []byte) ([]byte, error) {
+	startLine := r.line
+	r.field = r.field[:0]
+	for {
+		i := bytes.IndexByte(line, '"')
+		if i < 0 {
+			r.field = append(r.field, line...)
+			r.field = append(r.field, '\n')
+			if !r.scanner.Scan() {
+				if err := r.scanner.Err(); err != nil {
+					return nil, fmt.Errorf("error reading CSV: %w", err)
+				}
+				return nil, fmt.Errorf("error reading CSV: unterminated quoted field starting on line %d", startLine)
+			}
+			r.line++
+			line = r.scanner.Bytes()
+			continue
+		}
+		r.field = append(r.field, line[:i]...)
+		line = line[i+1:]
+		if len(line) > 0 && line[0] == '"' {
+			r.field = append(r.field, '"') // A doubled quote stands for one
+			line = line[1:]
+			continue
+		}
+		return line, nil
+	}
+}
+
+// ============================================================================
+// COLUMN TYPING FOR THE FAST SOURCES
+// ============================================================================
+
+// fastColumnParsers returns the function typing each column of a fast source: the kind
+// columnTypes gives it, the string itself if raw is set, and parseSimpleValue otherwise
+func fastColumnParsers(headers []string, columnTypes map[string]FieldKind, raw bool) []func(string) any {
+	parsers := make([]func(string) any, len(headers))
+	for i, header := range headers {
+		parsers[i] = fastColumnParser(header, columnTypes, raw)
+		if parsers[i] == nil {
+			parsers[i] = parseSimpleValue
+		}
+	}
+	return parsers
+}
+
+// fastColumnParser returns the function typing one column, or nil if its values are
+// inferred with parseSimpleValue
+func fastColumnParser(header string, columnTypes map[string]FieldKind, raw bool) func(string) any {
+	if kind, ok := columnTypes[header]; ok {
+		return func(value string) any { return parseKindValue(value, kind) }
+	}
+	if raw {
+		return func(value string) any { return value }
+	}
+	return nil
+}
+
+// parseKindValue parses value as kind, keeping the string if it doesn't parse. Empty
+// values stay empty strings, as parseSimpleValue leaves them.
+func parseKindValue(value string, kind FieldKind) any {
+	if value == "" {
+		return ""
+	}
+	switch kind {
+	case IntKind:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case FloatKind:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case BoolKind:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	case TimeKind:
+		for _, format := range csvTimeFormats {
+			if v, err := time.Parse(format, value); err == nil {
+				return v
+			}
+		}
+	case StringKind:
+		return value
+	default:
+		return parseSimpleValue(value)
+	}
+	return value
+}
@@ -0,0 +1,205 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFastCSVSource tests the quote-aware fast CSV source
+func TestFastCSVSource(t *testing.T) {
+	t.Run("Quotes", func(t *testing.T) {
+		data := "name,quote,age\n" +
+			"\"Smith, Alice\",\"She said \"\"hi\"\"\",30\n" +
+			"  \"  padded  \"  ,\"\",25\n" +
+			"Bob,\"two\nlines\",40\n"
+		records, err := Collect(NewFastCSVSource(strings.NewReader(data)).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		expected := []Record{
+			{"name": "Smith, Alice", "quote": `She said "hi"`, "age": int64(30)},
+			{"name": "  padded  ", "quote": "", "age": int64(25)},
+			{"name": "Bob", "quote": "two\nlines", "age": int64(40)},
+		}
+		if !reflect.DeepEqual(records, expected) {
+			t.Errorf("Expected %v, got %v", expected, records)
+		}
+	})
+
+	t.Run("CRLF", func(t *testing.T) {
+		data := "id,note\r\n1,\"a,b\"\r\n\r\n2,\"c\r\nd\"\r\n3,plain\r\n"
+		records, err := Collect(NewFastCSVSource(strings.NewReader(data)).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		expected := []Record{
+			{"id": int64(1), "note": "a,b"},
+			{"id": int64(2), "note": "c\nd"}, // As encoding/csv reads it
+			{"id": int64(3), "note": "plain"},
+		}
+		if !reflect.DeepEqual(records, expected) {
+			t.Errorf("Expected %v, got %v", expected, records)
+		}
+	})
+
+	t.Run("MatchesCSVSource", func(t *testing.T) {
+		data := "id,name,score,active\n1,\"Doe, Jane\",9.5,true\n2,\"x \"\"y\"\"\",-3,false\n3,,7,\n"
+		fast, err := Collect(NewFastCSVSource(strings.NewReader(data)).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		standard, err := Collect(NewCSVSource(strings.NewReader(data)).WithTypeInference(InferenceAggressive).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read with CSVSource: %v", err)
+		}
+		if !reflect.DeepEqual(fast, standard) {
+			t.Errorf("Expected the records CSVSource reads, %v, got %v", standard, fast)
+		}
+	})
+
+	t.Run("SeparatorAndHeaders", func(t *testing.T) {
+		data := "a;\"b;c\";3\n"
+		records, err := Collect(NewFastCSVSourceWithSeparator(strings.NewReader(data), ";").WithHeaders([]string{"x", "y", "z"}).ToStream())
+		if err != nil || len(records) != 1 || records[0]["y"] != "b;c" || records[0]["z"] != int64(3) {
+			t.Errorf("Expected one record split on ;, got %v (%v)", records, err)
+		}
+		records, err = Collect(NewFastCSVSource(strings.NewReader("1,\"2\"\n")).WithoutHeader().ToStream())
+		if err != nil || len(records) != 1 || records[0]["field_0"] != int64(1) || records[0]["field_1"] != int64(2) {
+			t.Errorf("Expected default field names, got %v (%v)", records, err)
+		}
+		records, _ = Collect(NewFastCSVSourceWithSeparator(strings.NewReader("x\ty\n\"a\"\t\t1\n"), "\t").ToStream())
+		if len(records) != 1 || records[0]["x"] != "a" || records[0]["y"] != "" {
+			t.Errorf("Expected an empty TSV field to stay a field, got %v", records)
+		}
+	})
+
+	t.Run("UnterminatedQuote", func(t *testing.T) {
+		stream := NewFastCSVSource(strings.NewReader("id,note\n1,ok\n2,\"never closed\n3,x\n")).ToStream()
+		if _, err := stream(); err != nil {
+			t.Fatalf("Expected the first record, got %v", err)
+		}
+		if _, err := stream(); err == nil || err == EOS || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("Expected an unterminated quote error on line 3, got %v", err)
+		}
+	})
+}
+
+// TestFastSourceColumnTypes tests WithColumnTypes and WithRawStrings on both fast sources
+func TestFastSourceColumnTypes(t *testing.T) {
+	data := "id,code,at,flag,amount\n1,007,2024-01-02,true,3.5\n2,abc,not a time,yes,4\n"
+	ts := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	types := map[string]FieldKind{"code": StringKind, "at": TimeKind, "amount": FloatKind}
+
+	type streamer interface{ ToStream() Stream[Record] }
+	sources := map[string]func() streamer{
+		"CSV": func() streamer {
+			return NewFastCSVSource(strings.NewReader(data)).WithColumnTypes(types)
+		},
+		"TSV": func() streamer {
+			return NewFastTSVSourceWithSeparator(strings.NewReader(data), ",").WithColumnTypes(types)
+		},
+		"CSVRaw": func() streamer {
+			return NewFastCSVSource(strings.NewReader(data)).WithColumnTypes(types).WithRawStrings()
+		},
+		"TSVRaw": func() streamer {
+			return NewFastTSVSourceWithSeparator(strings.NewReader(data), ",").WithColumnTypes(types).WithRawStrings()
+		},
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			records, err := Collect(source().ToStream())
+			if err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			expected := []Record{
+				{"id": int64(1), "code": "007", "at": ts, "flag": true, "amount": 3.5},
+				{"id": int64(2), "code": "abc", "at": "not a time", "flag": "yes", "amount": 4.0},
+			}
+			if strings.HasSuffix(name, "Raw") {
+				expected[0]["id"], expected[0]["flag"] = "1", "true"
+				expected[1]["id"] = "2"
+			}
+			if !reflect.DeepEqual(records, expected) {
+				t.Errorf("Expected %v, got %v", expected, records)
+			}
+		})
+	}
+
+	t.Run("DefaultTyping", func(t *testing.T) {
+		// Each value is typed on its own, with strconv's bool words only
+		data := "country,account,flag\nNO,00123,F\nSE,00456,yes\n"
+		for name, source := range map[string]streamer{
+			"CSV": NewFastCSVSource(strings.NewReader(data)),
+			"TSV": NewFastTSVSourceWithSeparator(strings.NewReader(data), ","),
+		} {
+			records, err := Collect(source.ToStream())
+			if err != nil {
+				t.Fatalf("%s: failed to read: %v", name, err)
+			}
+			expected := []Record{
+				{"country": "NO", "account": int64(123), "flag": false},
+				{"country": "SE", "account": int64(456), "flag": "yes"},
+			}
+			if !reflect.DeepEqual(records, expected) {
+				t.Errorf("%s: expected %v, got %v", name, expected, records)
+			}
+		}
+	})
+
+	t.Run("ToBatches", func(t *testing.T) {
+		source := NewFastTSVSourceWithSeparator(strings.NewReader(data), ",").WithColumnTypes(types).WithRawStrings()
+		batches, err := Collect(source.ToBatches(10, "id", "amount"))
+		if err != nil || len(batches) != 1 {
+			t.Fatalf("Expected one batch, got %d (%v)", len(batches), err)
+		}
+		if batches[0].Columns["id"].Strings == nil || batches[0].Columns["amount"].Float64s == nil {
+			t.Errorf("Expected a string id column and a float amount column, got %+v and %+v", batches[0].Columns["id"], batches[0].Columns["amount"])
+		}
+	})
+}
+
+// mixedCSV returns a CSV of rows rows with numeric, quoted text and date columns
+func mixedCSV(rows int) []byte {
+	var data bytes.Buffer
+	data.WriteString("id,name,amount,region,created\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&data, "%d,\"Customer %d, Ltd\",%d.%02d,region-%d,2024-%02d-%02d\n", i, i%5000, i%1000, i%100, i%8, i%12+1, i%28+1)
+	}
+	return data.Bytes()
+}
+
+// BenchmarkFastCSVSource compares reading a 1M-row CSV with CSVSource and FastCSVSource,
+// inferring types and with column types given up front
+func BenchmarkFastCSVSource(b *testing.B) {
+	data := mixedCSV(1_000_000)
+	types := map[string]FieldKind{"id": IntKind, "amount": FloatKind, "created": TimeKind}
+
+	b.Run("CSVSource", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := ForEach(func(Record) {})(NewCSVSource(bytes.NewReader(data)).ToStream()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("FastCSVSource", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := ForEach(func(Record) {})(NewFastCSVSource(bytes.NewReader(data)).ToStream()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("FastCSVSourceTyped", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			source := NewFastCSVSource(bytes.NewReader(data)).WithColumnTypes(types).WithRawStrings()
+			if err := ForEach(func(Record) {})(source.ToStream()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,7 +2,14 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,9 +26,23 @@ type Filter[T, U any] func(Stream[T]) Stream[U]
 // FUNCTIONAL OPERATIONS - TYPE SAFE AND COMPOSABLE
 // ============================================================================
 
-// Map transforms each element in a stream with automatic parallelization for large datasets
-func Map[T, U any](fn func(T) U) Filter[T, U] {
+// Map transforms each element in a stream. By default fn runs on the consumer's
+// goroutine; a Hint such as CPUHeavy or IOBound runs it on several goroutines with
+// ParallelOrdered, so output order always matches input order. Without a hint the
+// execution policy decides: under SetExecutionPolicy(Adaptive) Map estimates fn's
+// complexity, as it does for the AutoParallel hint.
+// Example: Map(resizeImage, CPUHeavy)
+func Map[T, U any](fn func(T) U, hints ...Hint) Filter[T, U] {
 	return func(input Stream[T]) Stream[U] {
+		switch workers := hintWorkers(hints); workers {
+		case 0:
+			return MapSeq(fn)(input)
+		case estimateWorkers:
+			// Estimated below
+		default:
+			return ParallelOrdered(workers, fn)(input)
+		}
+
 		// Try to estimate dataset size by sampling
 		complexity := estimateFunctionComplexity(fn)
 		
@@ -36,11 +57,30 @@ func Map[T, U any](fn func(T) U) Filter[T, U] {
 	}
 }
 
+// MapSeq transforms each element on the consumer's goroutine, never in parallel
+func MapSeq[T, U any](fn func(T) U) Filter[T, U] {
+	return func(input Stream[T]) Stream[U] {
+		return func() (U, error) {
+			item, err := input()
+			if err != nil {
+				var zero U
+				return zero, err
+			}
+			return fn(item), nil
+		}
+	}
+}
+
+// MapPar always transforms elements on workers goroutines, keeping input order
+func MapPar[T, U any](workers int, fn func(T) U) Filter[T, U] {
+	return ParallelOrdered(workers, fn)
+}
+
 // autoParallelMap automatically uses parallel processing for complex operations
 func autoParallelMap[T, U any](fn func(T) U, input Stream[T], complexity int) Stream[U] {
 	// Use parallel processing with worker count based on complexity and available CPUs
 	workers := calculateOptimalWorkers(complexity)
-	return Parallel(workers, fn)(input)
+	return ParallelOrdered(workers, fn)(input)
 }
 
 // adaptiveMap decides between sequential and parallel based on data characteristics
@@ -49,18 +89,11 @@ func adaptiveMap[T, U any](fn func(T) U, input Stream[T], complexity int) Stream
 	// In future, this could sample the stream to estimate size
 	if complexity >= 3 {
 		workers := calculateOptimalWorkers(complexity)
-		return Parallel(workers, fn)(input)
+		return ParallelOrdered(workers, fn)(input)
 	}
 	
 	// Sequential implementation for simple operations
-	return func() (U, error) {
-		item, err := input()
-		if err != nil {
-			var zero U
-			return zero, err
-		}
-		return fn(item), nil
-	}
+	return MapSeq(fn)(input)
 }
 
 // calculateOptimalWorkers determines optimal worker count based on operation characteristics
@@ -85,9 +118,20 @@ func calculateOptimalWorkers(complexity int) int {
 	return baseWorkers
 }
 
-// Where keeps only elements matching a predicate with automatic parallelization
-func Where[T any](predicate func(T) bool) Filter[T, T] {
+// Where keeps only elements matching a predicate. Like Map, it runs predicate on the
+// consumer's goroutine unless given a Hint (or the Adaptive policy) that parallelizes it,
+// and parallel execution preserves input order.
+func Where[T any](predicate func(T) bool, hints ...Hint) Filter[T, T] {
 	return func(input Stream[T]) Stream[T] {
+		switch workers := hintWorkers(hints); workers {
+		case 0:
+			return WhereSeq(predicate)(input)
+		case estimateWorkers:
+			// Estimated below
+		default:
+			return parallelWhere(workers, predicate, input)
+		}
+
 		complexity := estimateFunctionComplexity(predicate)
 		
 		// Auto-parallelize complex predicates
@@ -95,7 +139,13 @@ func Where[T any](predicate func(T) bool) Filter[T, T] {
 			return autoParallelFilter(predicate, input, complexity)
 		}
 		
-		// Sequential implementation for simple predicates
+		return WhereSeq(predicate)(input)
+	}
+}
+
+// WhereSeq keeps matching elements, evaluating predicate on the consumer's goroutine
+func WhereSeq[T any](predicate func(T) bool) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
 		return func() (T, error) {
 			for {
 				item, err := input()
@@ -111,8 +161,20 @@ func Where[T any](predicate func(T) bool) Filter[T, T] {
 	}
 }
 
+// WherePar always evaluates predicate on workers goroutines, keeping input order
+func WherePar[T any](workers int, predicate func(T) bool) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		return parallelWhere(workers, predicate, input)
+	}
+}
+
 // autoParallelFilter implements parallel filtering for complex predicates
 func autoParallelFilter[T any](predicate func(T) bool, input Stream[T], complexity int) Stream[T] {
+	return parallelWhere(calculateOptimalWorkers(complexity), predicate, input)
+}
+
+// parallelWhere evaluates predicate with ParallelOrdered, then drops the misses
+func parallelWhere[T any](workers int, predicate func(T) bool, input Stream[T]) Stream[T] {
 	// Create a filter function that returns the item or nil
 	filterFn := func(item T) *T {
 		if predicate(item) {
@@ -121,10 +183,8 @@ func autoParallelFilter[T any](predicate func(T) bool, input Stream[T], complexi
 		return nil
 	}
 	
-	workers := calculateOptimalWorkers(complexity)
-	
 	// Use parallel processing, then filter out nils
-	parallelStream := Parallel(workers, filterFn)(input)
+	parallelStream := ParallelOrdered(workers, filterFn)(input)
 	
 	return func() (T, error) {
 		for {
@@ -156,6 +216,91 @@ func Limit[T any](n int) Filter[T, T] {
 	}
 }
 
+// Take is an alias for Limit, for readers coming from functional stream libraries
+func Take[T any](n int) Filter[T, T] {
+	return Limit[T](n)
+}
+
+// LimitCount caps the stream at n elements; it is Limit under the name that
+// pairs with LimitDuration and LimitUntil
+func LimitCount[T any](n int) Filter[T, T] {
+	return Limit[T](n)
+}
+
+// LimitDuration ends the stream once d has elapsed since the first element was
+// requested, which bounds infinite sources such as Generate by wall-clock time.
+// The deadline is checked between elements, so an upstream call that blocks
+// (e.g. FromChannel waiting on a quiet channel) is not interrupted.
+func LimitDuration[T any](d time.Duration) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		var deadline time.Time
+		return func() (T, error) {
+			if deadline.IsZero() {
+				deadline = time.Now().Add(d)
+			}
+			if !time.Now().Before(deadline) {
+				var zero T
+				return zero, EOS
+			}
+			return input()
+		}
+	}
+}
+
+// LimitUntil ends the stream when pred first returns true. With inclusive set,
+// the matching element is emitted before EOS; otherwise it is dropped.
+func LimitUntil[T any](pred func(T) bool, inclusive bool) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		done := false
+		return func() (T, error) {
+			var zero T
+			if done {
+				return zero, EOS
+			}
+			item, err := input()
+			if err != nil {
+				return zero, err
+			}
+			if pred(item) {
+				done = true
+				if !inclusive {
+					return zero, EOS
+				}
+			}
+			return item, nil
+		}
+	}
+}
+
+// TakeWhile passes elements through while pred holds and ends at the first
+// element that fails it, which is not emitted. Pulling that element consumes
+// it from the input, so it is lost if the same input stream is read again
+// afterwards - Tee the input first if the remainder is needed.
+func TakeWhile[T any](pred func(T) bool) Filter[T, T] {
+	return LimitUntil(func(item T) bool { return !pred(item) }, false)
+}
+
+// SkipWhile drops the leading run of elements matching pred, then passes the
+// first non-matching element and everything after it through unchanged.
+func SkipWhile[T any](pred func(T) bool) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		skipping := true
+		return func() (T, error) {
+			for skipping {
+				item, err := input()
+				if err != nil {
+					return item, err
+				}
+				if !pred(item) {
+					skipping = false
+					return item, nil
+				}
+			}
+			return input()
+		}
+	}
+}
+
 // Offset skips first N elements (equivalent to SQL OFFSET)
 func Offset[T any](n int) Filter[T, T] {
 	return func(input Stream[T]) Stream[T] {
@@ -173,238 +318,1390 @@ func Offset[T any](n int) Filter[T, T] {
 	}
 }
 
+// Distinct passes through only the first occurrence of each value.
+// The seen-set grows with the number of distinct values, so memory is
+// unbounded on infinite streams with high cardinality - use DistinctRecent there.
+func Distinct[T comparable]() Filter[T, T] {
+	return DistinctBy(func(item T) T { return item })
+}
+
+// DistinctBy passes through only the first element for each key returned by keyFn.
+// Works with Records and other non-comparable types, e.g.
+//   DistinctBy(func(r Record) string { return GetOr(r, "id", "") })
+// Memory grows with the number of distinct keys seen.
+func DistinctBy[T any, K comparable](keyFn func(T) K) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		seen := make(map[K]struct{})
+		return func() (T, error) {
+			for {
+				item, err := input()
+				if err != nil {
+					var zero T
+					return zero, err
+				}
+				key := keyFn(item)
+				if _, exists := seen[key]; exists {
+					continue
+				}
+				seen[key] = struct{}{}
+				return item, nil
+			}
+		}
+	}
+}
+
+// DistinctRecent suppresses elements whose key matches one of the last n emitted keys.
+// Memory is bounded by n, making it suitable for infinite streams; a key that
+// reappears after being forgotten is emitted again.
+func DistinctRecent[T any, K comparable](keyFn func(T) K, n int) Filter[T, T] {
+	if n <= 0 {
+		panic("DistinctRecent size must be positive")
+	}
+
+	return func(input Stream[T]) Stream[T] {
+		seen := make(map[K]struct{}, n)
+		recent := make([]K, 0, n) // Ring buffer of emitted keys
+		next := 0
+		return func() (T, error) {
+			for {
+				item, err := input()
+				if err != nil {
+					var zero T
+					return zero, err
+				}
+				key := keyFn(item)
+				if _, exists := seen[key]; exists {
+					continue
+				}
+
+				// Remember this key, forgetting the oldest once full
+				if len(recent) < n {
+					recent = append(recent, key)
+				} else {
+					delete(seen, recent[next])
+					recent[next] = key
+					next = (next + 1) % n
+				}
+				seen[key] = struct{}{}
+				return item, nil
+			}
+		}
+	}
+}
+
 // ============================================================================
 // STREAM COMPOSITION - BEAUTIFUL CHAINING
 // ============================================================================
 
 // Pipe composes two filters
 func Pipe[T, U, V any](f1 Filter[T, U], f2 Filter[U, V]) Filter[T, V] {
-	return func(input Stream[T]) Stream[V] {
+	return describeComposition(func(input Stream[T]) Stream[V] {
 		return f2(f1(input))
-	}
+	}, "Pipe", planOf(f1), planOf(f2))
 }
 
 // Pipe3 composes three filters
 func Pipe3[T, U, V, W any](f1 Filter[T, U], f2 Filter[U, V], f3 Filter[V, W]) Filter[T, W] {
-	return func(input Stream[T]) Stream[W] {
+	return describeComposition(func(input Stream[T]) Stream[W] {
 		return f3(f2(f1(input)))
-	}
+	}, "Pipe3", planOf(f1), planOf(f2), planOf(f3))
+}
+
+// Pipe4 composes four filters
+func Pipe4[T, U, V, W, X any](f1 Filter[T, U], f2 Filter[U, V], f3 Filter[V, W], f4 Filter[W, X]) Filter[T, X] {
+	return describeComposition(func(input Stream[T]) Stream[X] {
+		return f4(f3(f2(f1(input))))
+	}, "Pipe4", planOf(f1), planOf(f2), planOf(f3), planOf(f4))
+}
+
+// Pipe5 composes five filters
+func Pipe5[T, U, V, W, X, Y any](f1 Filter[T, U], f2 Filter[U, V], f3 Filter[V, W], f4 Filter[W, X], f5 Filter[X, Y]) Filter[T, Y] {
+	return describeComposition(func(input Stream[T]) Stream[Y] {
+		return f5(f4(f3(f2(f1(input)))))
+	}, "Pipe5", planOf(f1), planOf(f2), planOf(f3), planOf(f4), planOf(f5))
+}
+
+// Pipe6 composes six filters
+func Pipe6[T, U, V, W, X, Y, Z any](f1 Filter[T, U], f2 Filter[U, V], f3 Filter[V, W], f4 Filter[W, X], f5 Filter[X, Y], f6 Filter[Y, Z]) Filter[T, Z] {
+	return describeComposition(func(input Stream[T]) Stream[Z] {
+		return f6(f5(f4(f3(f2(f1(input))))))
+	}, "Pipe6", planOf(f1), planOf(f2), planOf(f3), planOf(f4), planOf(f5), planOf(f6))
+}
+
+// Pipe7 composes seven filters
+func Pipe7[T, U, V, W, X, Y, Z, A any](f1 Filter[T, U], f2 Filter[U, V], f3 Filter[V, W], f4 Filter[W, X], f5 Filter[X, Y], f6 Filter[Y, Z], f7 Filter[Z, A]) Filter[T, A] {
+	return describeComposition(func(input Stream[T]) Stream[A] {
+		return f7(f6(f5(f4(f3(f2(f1(input)))))))
+	}, "Pipe7", planOf(f1), planOf(f2), planOf(f3), planOf(f4), planOf(f5), planOf(f6), planOf(f7))
+}
+
+// Pipe8 composes eight filters
+func Pipe8[T, U, V, W, X, Y, Z, A, B any](f1 Filter[T, U], f2 Filter[U, V], f3 Filter[V, W], f4 Filter[W, X], f5 Filter[X, Y], f6 Filter[Y, Z], f7 Filter[Z, A], f8 Filter[A, B]) Filter[T, B] {
+	return describeComposition(func(input Stream[T]) Stream[B] {
+		return f8(f7(f6(f5(f4(f3(f2(f1(input))))))))
+	}, "Pipe8", planOf(f1), planOf(f2), planOf(f3), planOf(f4), planOf(f5), planOf(f6), planOf(f7), planOf(f8))
 }
 
 // Chain applies multiple filters of the same type
 func Chain[T any](filters ...Filter[T, T]) Filter[T, T] {
-	return func(input Stream[T]) Stream[T] {
+	children := make([]*planNode, len(filters))
+	for i, filter := range filters {
+		children[i] = planOf(filter)
+	}
+	return describeComposition(func(input Stream[T]) Stream[T] {
 		result := input
 		for _, filter := range filters {
 			result = filter(result)
 		}
 		return result
+	}, "Chain", children...)
+}
+
+// ComposeAny chains any number of Record filters whose types are only known at run time,
+// such as stages chosen from configuration. Each stage must be a Filter[Record, Record],
+// or a Filter[Record, Stream[Record]] (Split, for example), whose substreams are then
+// read one after another as a single stream. Any other stage is reported by its position
+// and type.
+func ComposeAny(filters ...any) (Filter[Record, Record], error) {
+	stages := make([]Filter[Record, Record], len(filters))
+	for i, filter := range filters {
+		switch f := filter.(type) {
+		case Filter[Record, Record]:
+			stages[i] = f
+		case func(Stream[Record]) Stream[Record]:
+			stages[i] = f
+		case Filter[Record, Stream[Record]]:
+			stages[i] = flattenStage(f)
+		case func(Stream[Record]) Stream[Stream[Record]]:
+			stages[i] = flattenStage(f)
+		default:
+			return nil, fmt.Errorf("ComposeAny stage %d: %T is not a Filter[Record, Record] or Filter[Record, Stream[Record]]", i+1, filter)
+		}
+		if stages[i] == nil {
+			return nil, fmt.Errorf("ComposeAny stage %d: nil filter", i+1)
+		}
+	}
+	return Chain(stages...), nil
+}
+
+// flattenStage adapts a filter producing substreams to one producing their records,
+// keeping its description for Explain
+func flattenStage(f Filter[Record, Stream[Record]]) Filter[Record, Record] {
+	if f == nil {
+		return nil
 	}
+	flatten := FlatMap(func(substream Stream[Record]) Stream[Record] { return substream })
+	return describeComposition(Pipe(f, flatten), "flatten", planOf(f))
 }
 
 // ============================================================================
 // RECORD-SPECIFIC OPERATIONS - SQL-LIKE POWER
 // ============================================================================
 
-// Select extracts specific fields from records
+// Select extracts specific fields from records. An ordered record's fields keep
+// their order (see SetFieldOrder).
 func Select(fields ...string) Filter[Record, Record] {
-	return Map(func(r Record) Record {
-		result := make(Record)
+	return projectingSelect(Map(func(r Record) Record {
+		result := AcquireRecord(len(fields))
 		for _, field := range fields {
 			if val, exists := r[field]; exists {
 				result[field] = val
 			}
 		}
+		inheritFieldOrder(result, r)
 		return result
-	})
+	}), fields)
 }
 
-// Update modifies records
-func Update(fn func(Record) Record) Filter[Record, Record] {
+// SelectPrefix keeps the fields whose names start with any of the prefixes.
+// Example: SelectPrefix("cust.") after a join WithPrefixes("cust.", "order.")
+func SelectPrefix(prefixes ...string) Filter[Record, Record] {
+	return selectMatching(func(field string) bool {
+		return hasAnyPrefix(field, prefixes)
+	}, true)
+}
+
+// DropPrefix removes the fields whose names start with any of the prefixes
+func DropPrefix(prefixes ...string) Filter[Record, Record] {
+	return selectMatching(func(field string) bool {
+		return hasAnyPrefix(field, prefixes)
+	}, false)
+}
+
+// SelectPattern keeps the fields whose names match any of the glob patterns.
+// '*' matches any run of characters (including dots); every other character is literal,
+// so a pattern without '*' names one field: SelectPattern("cust.*", "order.id").
+// Patterns that match nothing simply select nothing.
+func SelectPattern(globs ...string) Filter[Record, Record] {
+	return selectMatching(func(field string) bool {
+		return matchesAnyGlob(field, globs)
+	}, true)
+}
+
+// DropPattern removes the fields whose names match any of the glob patterns (see SelectPattern)
+func DropPattern(globs ...string) Filter[Record, Record] {
+	return selectMatching(func(field string) bool {
+		return matchesAnyGlob(field, globs)
+	}, false)
+}
+
+// selectMatching copies the fields for which match(field) == keep
+func selectMatching(match func(string) bool, keep bool) Filter[Record, Record] {
 	return Map(func(r Record) Record {
-		return fn(r)
+		result := make(Record)
+		for k, v := range r {
+			if match(k) == keep {
+				result[k] = v
+			}
+		}
+		inheritFieldOrder(result, r)
+		return result
 	})
 }
 
-// ExtractField gets a typed field from records
-func ExtractField[T any](field string) Filter[Record, T] {
-	return Map(func(r Record) T {
-		val, _ := Get[T](r, field)
-		return val
-	})
+func hasAnyPrefix(field string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
-// ============================================================================
-// CONCURRENT PROCESSING
-// ============================================================================
+func matchesAnyGlob(field string, globs []string) bool {
+	for _, glob := range globs {
+		if matchGlob(glob, field) {
+			return true
+		}
+	}
+	return false
+}
 
-// Parallel processes elements concurrently using simple goroutines
-func Parallel[T, U any](workers int, fn func(T) U) Filter[T, U] {
-	return func(input Stream[T]) Stream[U] {
-		inputCh := make(chan T, workers)
-		outputCh := make(chan U, workers)
-		workerDone := make(chan struct{}, workers)
+// matchGlob reports whether name matches pattern, where '*' matches any run of characters
+func matchGlob(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
 
-		// Start workers
-		for i := 0; i < workers; i++ {
-			go func() {
-				for item := range inputCh {
-					result := fn(item)
-					outputCh <- result
-				}
-				workerDone <- struct{}{}
-			}()
+	// The first part anchors the start, the last part anchors the end
+	first, last := parts[0], parts[len(parts)-1]
+	if len(name) < len(first)+len(last) || !strings.HasPrefix(name, first) || !strings.HasSuffix(name, last) {
+		return false
+	}
+
+	// Middle parts must appear in order between the anchors
+	rest := name[len(first) : len(name)-len(last)]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
 		}
+		rest = rest[i+len(part):]
+	}
+	return true
+}
 
-		// Feed input and manage cleanup
-		go func() {
-			defer close(inputCh)
-			for {
-				item, err := input()
-				if err != nil {
-					break // Input stream ended
+// Update modifies records.
+// fn receives the input record itself, not a copy: mutating it in place changes a map that
+// other holders (Tee branches, collected slices) share. Return a new Record (SetField,
+// Record.Set) or use RenameFields/DropFields/AddField, which always copy.
+// A new record fn builds from an ordered record takes on its field order unless fn
+// gave it one (see SetFieldOrder).
+func Update(fn func(Record) Record) Filter[Record, Record] {
+	return Map(func(r Record) Record {
+		result := fn(r)
+		if !sameRecord(result, r) && fieldOrder(result) == nil {
+			inheritFieldOrder(result, r)
+		}
+		return result
+	})
+}
+
+// RenameFields renames fields using an old → new name mapping, keeping all other fields.
+// A renamed field overwrites any existing field with the target name; missing source fields
+// are ignored. Renames read from the input record, so swaps like {"a": "b", "b": "a"} work.
+// Output records are copies - the input is never modified.
+func RenameFields(renames map[string]string) Filter[Record, Record] {
+	// Apply in sorted order so several sources mapping to one target resolve deterministically
+	sources := make([]string, 0, len(renames))
+	for from := range renames {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+
+	return Map(func(r Record) Record {
+		result := make(Record, len(r))
+		for k, v := range r {
+			if _, renamed := renames[k]; !renamed {
+				result[k] = v
+			}
+		}
+		for _, from := range sources {
+			if val, exists := r[from]; exists {
+				result[renames[from]] = val
+			}
+		}
+		if order := fieldOrder(r); order != nil {
+			// A renamed field keeps its place under its new name
+			renamedOrder := orderedKeys(r, order)
+			for i, field := range renamedOrder {
+				if to, renamed := renames[field]; renamed {
+					renamedOrder[i] = to
 				}
-				inputCh <- item
 			}
-		}()
+			SetFieldOrder(result, renamedOrder...)
+		}
+		return result
+	})
+}
 
-		// Cleanup coordinator
-		go func() {
-			// Wait for all workers to signal completion
-			for i := 0; i < workers; i++ {
-				<-workerDone
-			}
-			close(outputCh)
-		}()
+// DropFields removes the named fields from records, keeping everything else.
+// Output records are copies - the input is never modified.
+func DropFields(fields ...string) Filter[Record, Record] {
+	drop := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		drop[field] = true
+	}
 
-		// Return simple stream
-		return func() (U, error) {
-			item, ok := <-outputCh
-			if !ok {
-				var zero U
-				return zero, EOS
+	return Map(func(r Record) Record {
+		result := make(Record, len(r))
+		for k, v := range r {
+			if !drop[k] {
+				result[k] = v
 			}
-			return item, nil
 		}
-	}
+		inheritFieldOrder(result, r)
+		return result
+	})
 }
 
-// ============================================================================
-// CONTEXT SUPPORT
-// ============================================================================
+// AddField adds a computed field, overwriting any existing field with the same name.
+// Example: AddField("total", func(r Record) any { return GetOr(r, "price", 0.0) * GetOr(r, "qty", 0.0) })
+// Output records are copies - the input is never modified.
+func AddField(name string, fn func(Record) any) Filter[Record, Record] {
+	return Map(func(r Record) Record {
+		return r.Set(name, fn(r))
+	})
+}
 
-// WithContext adds context support to a stream
-func WithContext[T any](ctx context.Context, stream Stream[T]) Stream[T] {
-	return func() (T, error) {
-		select {
-		case <-ctx.Done():
-			var zero T
-			return zero, ctx.Err()
-		default:
-			return stream()
+// Peek calls fn for every element as it flows through, without modifying it.
+// Errors and EOS pass through untouched and nothing is buffered. When placed
+// inside a Parallel or auto-parallel Map section fn may be called concurrently,
+// so it must be safe for concurrent use.
+func Peek[T any](fn func(T)) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		return func() (T, error) {
+			item, err := input()
+			if err == nil {
+				fn(item)
+			}
+			return item, err
 		}
 	}
 }
 
-// ============================================================================
-// STREAM UTILITIES
-// ============================================================================
+// Log writes each element to w as "prefix[index] value", where Records are
+// shown as sorted key=value pairs and other values via %v. Each stream the filter
+// is applied to counts from 0. Writes to w are serialized, so Log is safe to use
+// where elements arrive concurrently, or on several streams at once.
+func Log[T any](prefix string, w io.Writer) Filter[T, T] {
+	var mu sync.Mutex // Guards w, which every stream shares
+	return func(input Stream[T]) Stream[T] {
+		index := 0
+		return Peek(func(item T) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(w, "%s[%d] %s\n", prefix, index, formatLogValue(item))
+			index++
+		})(input)
+	}
+}
 
-// Tee splits a stream into multiple identical streams for parallel consumption.
-// Works with both finite and infinite streams using a broadcasting dispatcher with proper cleanup.
+// formatLogValue renders Records with sorted keys so log output is stable
+func formatLogValue(item any) string {
+	record, ok := item.(Record)
+	if !ok {
+		return fmt.Sprintf("%v", item)
+	}
+
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", key, record[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// ExtractField gets a typed field from records. A record missing the field, or whose
+// value doesn't convert to T, yields the zero value; see ExtractFieldStrict.
+func ExtractField[T any](field string) Filter[Record, T] {
+	return Map(func(r Record) T {
+		val, _ := Get[T](r, field)
+		return val
+	})
+}
+
+// ExtractFieldStrict gets a typed field from records like ExtractField, but ends the
+// stream with an error naming the record's index, from 0, and wrapping a *FieldError
+// when a record is missing the field or its value doesn't convert to T
+//
+// Example:
+//
+//	amounts := ExtractFieldStrict[float64]("amout")(orders)
+//	_, err := amounts() // record 0: field "amout" not found (record has amount, id)
+func ExtractFieldStrict[T any](field string) Filter[Record, T] {
+	return func(input Stream[Record]) Stream[T] {
+		var index int64
+		var finalErr error
+		return func() (T, error) {
+			var zero T
+			if finalErr != nil {
+				return zero, finalErr
+			}
+			r, err := input()
+			if err != nil {
+				finalErr = err
+				return zero, err
+			}
+			val, err := GetErr[T](r, field)
+			if err != nil {
+				finalErr = fmt.Errorf("record %d: %w", index, err)
+				return zero, finalErr
+			}
+			index++
+			return val, nil
+		}
+	}
+}
+
+// LintFields passes records through unchanged while checking the first sample of them
+// for the given fields. Once sample records have gone by, or the stream ends sooner, it
+// calls warn for each field none of them had, with the fields that were seen, sorted - a
+// development aid for catching misspelled field names before they become zero values.
+//
+// Example:
+//
+//	checked := LintFields([]string{"amount", "user_id"}, 100, func(field string, seen []string) {
+//	    log.Printf("field %q never present; records have %v", field, seen)
+//	})(orders)
+func LintFields(fields []string, sample int, warn func(field string, seen []string)) Filter[Record, Record] {
+	return func(input Stream[Record]) Stream[Record] {
+		seen := make(map[string]bool)
+		count := 0
+		done := false
+
+		report := func() {
+			done = true
+			var missing []string
+			for _, field := range fields {
+				if !seen[field] {
+					missing = append(missing, field)
+				}
+			}
+			if len(missing) == 0 {
+				return
+			}
+			names := make([]string, 0, len(seen))
+			for name := range seen {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, field := range missing {
+				warn(field, names)
+			}
+		}
+
+		return func() (Record, error) {
+			r, err := input()
+			if done {
+				return r, err
+			}
+			if err != nil {
+				if err == EOS {
+					report()
+				}
+				return r, err
+			}
+			for name := range r {
+				seen[name] = true
+			}
+			if count++; count >= sample {
+				report()
+			}
+			return r, nil
+		}
+	}
+}
+
+// ============================================================================
+// CONCURRENT PROCESSING
+// ============================================================================
+
+// Parallel processes elements concurrently using simple goroutines.
+// Results arrive in completion order; use ParallelOrdered to keep input order.
+// A panic in fn ends the stream with an error instead of hanging it.
+func Parallel[T, U any](workers int, fn func(T) U) Filter[T, U] {
+	return ParallelWithError(workers, func(item T) (U, error) {
+		return fn(item), nil
+	})
+}
+
+// ParallelWithError is Parallel for functions that can fail. The first error
+// from fn, a panic in fn, or a non-EOS input error ends the stream: no more
+// input is read, in-flight items are drained and discarded, and the error is
+// returned on this and every later pull.
+func ParallelWithError[T, U any](workers int, fn func(T) (U, error)) Filter[T, U] {
+	return ParallelWithErrorContext(context.Background(), workers, fn)
+}
+
+// ParallelContext is Parallel stopped by ctx; see ParallelWithErrorContext
+func ParallelContext[T, U any](ctx context.Context, workers int, fn func(T) U) Filter[T, U] {
+	return ParallelWithErrorContext(ctx, workers, func(item T) (U, error) {
+		return fn(item), nil
+	})
+}
+
+// ParallelWithErrorContext is ParallelWithError stopped by ctx: a pull waiting for a
+// result returns ctx.Err() once ctx is done, as does every later pull, and the workers
+// exit without finishing their items. A worker still inside fn, or an input pull still
+// blocked, returns first; pass ctx to fn to cut those short too.
+func ParallelWithErrorContext[T, U any](ctx context.Context, workers int, fn func(T) (U, error)) Filter[T, U] {
+	if workers <= 0 {
+		panic("workers must be positive")
+	}
+
+	type result struct {
+		value U
+		err   error
+	}
+
+	return func(input Stream[T]) Stream[U] {
+		inputCh := make(chan T, workers)
+		outputCh := make(chan result, workers)
+		stop := make(chan struct{}) // Closed by the consumer after an error
+		var inputErr error
+		var wg sync.WaitGroup
+
+		// Start workers
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for item := range inputCh {
+					value, err := callRecovered(fn, item)
+					select {
+					case outputCh <- result{value: value, err: err}:
+					case <-stop:
+						return
+					}
+				}
+			}()
+		}
+
+		// Feed input and manage cleanup
+		go func() {
+			defer close(inputCh)
+			for {
+				item, err := input()
+				if err != nil {
+					if err != EOS {
+						inputErr = err
+					}
+					return
+				}
+				select {
+				case inputCh <- item:
+				case <-stop:
+					return
+				}
+			}
+		}()
+
+		// Close output once every worker has finished
+		go func() {
+			wg.Wait()
+			close(outputCh)
+		}()
+
+		var finalErr error
+		return func() (U, error) {
+			var zero U
+			if finalErr != nil {
+				return zero, finalErr
+			}
+			if err := ctx.Err(); err != nil {
+				finalErr = err
+				close(stop) // Workers and the feeder exit instead of sending
+				return zero, finalErr
+			}
+			var r result
+			var ok bool
+			select {
+			case r, ok = <-outputCh:
+			case <-ctx.Done():
+				finalErr = ctx.Err()
+				close(stop) // Workers and the feeder exit instead of sending
+				return zero, finalErr
+			}
+			if !ok {
+				finalErr = EOS
+				if inputErr != nil {
+					finalErr = inputErr
+				}
+				return zero, finalErr
+			}
+			if r.err != nil {
+				finalErr = r.err
+				close(stop)
+				for range outputCh {
+					// Drain in-flight items so the workers exit
+				}
+				return zero, finalErr
+			}
+			return r.value, nil
+		}
+	}
+}
+
+// callRecovered calls fn, turning a panic into an error that names the item
+func callRecovered[T, U any](fn func(T) (U, error), item T) (value U, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parallel worker panicked on item %v: %v", item, r)
+		}
+	}()
+	return fn(item)
+}
+
+// orderedWindowPerWorker bounds how many items ParallelOrdered keeps in flight
+// per worker, which caps the reorder buffer when one item is slow
+const orderedWindowPerWorker = 4
+
+// ParallelOrdered processes elements concurrently like Parallel but emits
+// results in input order. Items are tagged with a sequence number and results
+// that finish early wait in a reorder buffer; at most workers*4 items are in
+// flight, so a slow item stalls the input rather than growing the buffer.
+// A non-EOS input error or a panic in fn is returned after the results
+// before it, and the remaining in-flight items are drained.
+func ParallelOrdered[T, U any](workers int, fn func(T) U) Filter[T, U] {
+	if workers <= 0 {
+		panic("workers must be positive")
+	}
+
+	type job struct {
+		seq  int
+		item T
+	}
+	type result struct {
+		seq   int
+		value U
+		err   error
+	}
+
+	call := func(item T) (U, error) { return fn(item), nil }
+
+	return func(input Stream[T]) Stream[U] {
+		window := workers * orderedWindowPerWorker
+		slots := make(chan struct{}, window) // One token per item in flight
+		inputCh := make(chan job, workers)
+		outputCh := make(chan result, window)
+		stop := make(chan struct{}) // Closed by the consumer after an error
+		var inputErr error
+		var wg sync.WaitGroup
+
+		// Start workers
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range inputCh {
+					value, err := callRecovered(call, j.item)
+					outputCh <- result{seq: j.seq, value: value, err: err}
+				}
+			}()
+		}
+
+		// Feed input, waiting for a free slot before each item
+		go func() {
+			defer close(inputCh)
+			for seq := 0; ; seq++ {
+				select {
+				case slots <- struct{}{}:
+				case <-stop:
+					return
+				}
+				item, err := input()
+				if err != nil {
+					if err != EOS {
+						inputErr = err
+					}
+					return
+				}
+				inputCh <- job{seq: seq, item: item}
+			}
+		}()
+
+		// Close output once every worker has finished; wg.Wait also orders
+		// the feeder's write of inputErr before the consumer reads it
+		go func() {
+			wg.Wait()
+			close(outputCh)
+		}()
+
+		pending := make(map[int]result, window)
+		next := 0
+		var finalErr error
+
+		return func() (U, error) {
+			var zero U
+			if finalErr != nil {
+				return zero, finalErr
+			}
+			for {
+				if r, ok := pending[next]; ok {
+					delete(pending, next)
+					if r.err != nil {
+						finalErr = r.err
+						close(stop)
+						for range outputCh {
+							// Drain in-flight items so the workers exit
+						}
+						return zero, finalErr
+					}
+					next++
+					<-slots
+					return r.value, nil
+				}
+				r, ok := <-outputCh
+				if !ok {
+					finalErr = EOS
+					if inputErr != nil {
+						finalErr = inputErr
+					}
+					return zero, finalErr
+				}
+				pending[r.seq] = r
+			}
+		}
+	}
+}
+
+// ============================================================================
+// CONTEXT SUPPORT
+// ============================================================================
+
+// WithContext stops a stream once ctx is done: ctx is checked before every pull, and
+// from then on pulls return ctx.Err(). A pull that is already blocked inside stream -
+// on a channel nobody sends on, say - is not interrupted and returns only when stream
+// does. For sources that must give up while blocked use FromChannelContext or
+// GenerateContext; MergeContext and BufferContext do the same for their goroutines.
+func WithContext[T any](ctx context.Context, stream Stream[T]) Stream[T] {
+	return func() (T, error) {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		default:
+			return stream()
+		}
+	}
+}
+
+// ============================================================================
+// ERROR RECOVERY
+// ============================================================================
+
+// Retry re-pulls the input when it returns a non-EOS error, up to attempts
+// times per element, sleeping backoff before the first retry and doubling the
+// wait each time after. The retry budget resets after every successful pull.
+// EOS is never retried; once attempts are exhausted the last error is returned.
+func Retry[T any](attempts int, backoff time.Duration) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		return func() (T, error) {
+			wait := backoff
+			for retry := 0; ; retry++ {
+				item, err := input()
+				if err == nil || err == EOS || retry >= attempts {
+					return item, err
+				}
+				time.Sleep(wait)
+				wait *= 2
+			}
+		}
+	}
+}
+
+// OnErrorResume switches to fallback for the rest of the stream when the
+// input returns a non-EOS error. The failed element is not retried.
+func OnErrorResume[T any](fallback Stream[T]) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		resumed := false
+		return func() (T, error) {
+			if resumed {
+				return fallback()
+			}
+			item, err := input()
+			if err != nil && err != EOS {
+				resumed = true
+				return fallback()
+			}
+			return item, err
+		}
+	}
+}
+
+// SkipErrors drops pulls that return a non-EOS error, such as malformed lines
+// from JSONSource, and carries on with the next element. After maxSkipped
+// errors have been dropped the next one is returned; a negative maxSkipped
+// skips without limit.
+func SkipErrors[T any](maxSkipped int) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		skipped := 0
+		return func() (T, error) {
+			for {
+				item, err := input()
+				if err == nil || err == EOS {
+					return item, err
+				}
+				if maxSkipped >= 0 && skipped >= maxSkipped {
+					return item, err
+				}
+				skipped++
+			}
+		}
+	}
+}
+
+// ============================================================================
+// STREAM UTILITIES
+// ============================================================================
+
+// Fuse latches the first error stream returns, EOS or any other: every later call
+// returns it again without calling stream. It is the Stream contract made explicit, for
+// sources whose behaviour after the end is unknown, such as a Generate function that
+// would run user code again. Don't fuse a stream whose errors are meant to be skipped,
+// such as one read through SkipErrors.
+//
+// Example:
+//
+//	safe := Fuse(Generate(readNext)) // readNext is never called after it returns EOS
+func Fuse[T any](stream Stream[T]) Stream[T] {
+	var finalErr error // Sticky once stream has returned an error
+	return func() (T, error) {
+		if finalErr != nil {
+			var zero T
+			return zero, finalErr
+		}
+		item, err := stream()
+		if err != nil {
+			finalErr = err
+		}
+		return item, err
+	}
+}
+
+// fused applies Fuse on both sides of a filter, so the filter never pulls its input
+// after the input has ended and its output keeps returning the error it ended with
+func fused[T, U any](filter Filter[T, U]) Filter[T, U] {
+	return func(input Stream[T]) Stream[U] {
+		return Fuse(filter(Fuse(input)))
+	}
+}
+
+// Tee splits a stream into n identical streams. Items a branch hasn't read yet are
+// buffered for it, so branches may be consumed in any order or speed - including one
+// after another - without losing data; the cost is memory for the slowest branch's backlog.
+// Source errors (and EOS) reach every branch after its buffered items.
+// Use TeeWithOptions to bound the buffer.
 func Tee[T any](stream Stream[T], n int) []Stream[T] {
+	return TeeWithOptions(stream, n)
+}
+
+// ErrTeeOverflow is returned by a Tee branch dropped under TeeFailLagging
+var ErrTeeOverflow = errors.New("tee branch fell too far behind and was dropped")
+
+// TeeOverflowPolicy defines what Tee does when a branch's buffer is full
+type TeeOverflowPolicy int
+
+const (
+	TeeBlock       TeeOverflowPolicy = iota // Readers ahead wait for lagging branches (branches must be read concurrently)
+	TeeFailLagging                          // A lagging branch is dropped and returns ErrTeeOverflow
+)
+
+// TeeOption configures TeeWithOptions behavior
+type TeeOption func(*teeConfig)
+
+// teeConfig holds Tee configuration
+type teeConfig struct {
+	ctx        context.Context
+	bufferSize int // 0 means unbounded
+	overflow   TeeOverflowPolicy
+}
+
+// WithTeeBuffer bounds how many unread items each branch may buffer and sets what
+// happens when a branch falls further behind. The default is an unbounded buffer.
+func WithTeeBuffer(size int, policy TeeOverflowPolicy) TeeOption {
+	if size <= 0 {
+		panic("buffer size must be positive")
+	}
+	return func(config *teeConfig) {
+		config.bufferSize = size
+		config.overflow = policy
+	}
+}
+
+// WithTeeContext stops every branch once ctx is done: a branch waiting for a lagging
+// branch under TeeBlock, or for another branch's pull, returns ctx.Err() at once, as
+// does every later pull. A pull already reading the source returns first.
+func WithTeeContext(ctx context.Context) TeeOption {
+	return func(config *teeConfig) {
+		config.ctx = ctx
+	}
+}
+
+// TeeWithOptions is Tee configured with options.
+// Branches are pulled on demand without background goroutines, so abandoned branches leak
+// nothing but their buffered items. Branches are safe to read from different goroutines.
+func TeeWithOptions[T any](stream Stream[T], n int, options ...TeeOption) []Stream[T] {
 	if n <= 0 {
 		return nil
 	}
-	
-	ctx, cancel := context.WithCancel(context.Background())
-	channels := make([]chan T, n)
-	abandoned := make([]bool, n) // Track abandoned streams
-	var mu sync.RWMutex
-	
+	config := &teeConfig{ctx: context.Background()}
+	for _, option := range options {
+		option(config)
+	}
+
+	state := &teeState[T]{
+		source: stream,
+		config: config,
+		queues: make([][]T, n),
+		errs:   make([]error, n),
+	}
+	state.cond = sync.NewCond(&state.mu)
+	context.AfterFunc(config.ctx, func() {
+		state.mu.Lock()
+		state.cond.Broadcast() // Wake waiting branches to see ctx is done
+		state.mu.Unlock()
+	})
+
+	streams := make([]Stream[T], n)
 	for i := 0; i < n; i++ {
-		channels[i] = make(chan T, 100)
+		branch := i
+		streams[i] = func() (T, error) {
+			return state.next(branch)
+		}
+	}
+	return streams
+}
+
+// teeState is shared by the branches of one Tee
+type teeState[T any] struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	source  Stream[T]
+	config  *teeConfig
+	queues  [][]T   // Items each branch has not read yet
+	errs    []error // Per-branch failure (ErrTeeOverflow)
+	pulling bool    // A branch is reading the source outside the lock
+	srcErr  error   // Source error or EOS, seen by each branch once its queue is empty
+}
+
+// next returns branch i's next item, reading the source when the branch is caught up
+func (t *teeState[T]) next(i int) (T, error) {
+	var zero T
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for {
+		if err := t.config.ctx.Err(); err != nil {
+			return zero, err
+		}
+		if t.errs[i] != nil {
+			return zero, t.errs[i]
+		}
+		if queue := t.queues[i]; len(queue) > 0 {
+			item := queue[0]
+			queue[0] = zero
+			t.queues[i] = queue[1:]
+			t.cond.Broadcast() // Room may have opened for a blocked reader
+			return item, nil
+		}
+		if t.srcErr != nil {
+			return zero, t.srcErr
+		}
+		if t.pulling || t.mustWait(i) {
+			t.cond.Wait()
+			continue
+		}
+
+		// Read the source without holding the lock so other branches can drain their queues
+		t.pulling = true
+		t.mu.Unlock()
+		item, err := t.source()
+		t.mu.Lock()
+		t.pulling = false
+		t.cond.Broadcast()
+
+		if err != nil {
+			t.srcErr = err
+			continue
+		}
+		for j := range t.queues {
+			if j == i || t.errs[j] != nil {
+				continue
+			}
+			if t.config.bufferSize > 0 && len(t.queues[j]) >= t.config.bufferSize {
+				// Only reachable under TeeFailLagging; TeeBlock waits in mustWait instead
+				t.errs[j] = ErrTeeOverflow
+				t.queues[j] = nil
+				continue
+			}
+			t.queues[j] = append(t.queues[j], item)
+		}
+		return item, nil
+	}
+}
+
+// mustWait reports whether branch i has to wait for lagging branches before reading ahead
+func (t *teeState[T]) mustWait(i int) bool {
+	if t.config.bufferSize == 0 || t.config.overflow != TeeBlock {
+		return false
+	}
+	for j, queue := range t.queues {
+		if j != i && t.errs[j] == nil && len(queue) >= t.config.bufferSize {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrMaterializeLimit is returned when a materialized stream exceeds WithMaxItems
+var ErrMaterializeLimit = errors.New("materialized stream exceeds item limit")
+
+// MaterializeOption configures Materialize behavior
+type MaterializeOption func(*materializeConfig)
+
+// materializeConfig holds Materialize configuration
+type materializeConfig struct {
+	maxItems int // 0 means unlimited
+	lazy     bool
+}
+
+// WithMaxItems caps how many items Materialize will cache; caching more fails with ErrMaterializeLimit
+func WithMaxItems(n int) MaterializeOption {
+	if n <= 0 {
+		panic("max items must be positive")
+	}
+	return func(config *materializeConfig) {
+		config.maxItems = n
+	}
+}
+
+// WithLazyMaterialize defers reading the source until a replayed stream is pulled;
+// items are then cached as far as the furthest reader has got
+func WithLazyMaterialize() MaterializeOption {
+	return func(config *materializeConfig) {
+		config.lazy = true
+	}
+}
+
+// Replayable caches a stream's items so they can be read any number of times
+type Replayable[T any] struct {
+	mu     sync.Mutex
+	source Stream[T]
+	config *materializeConfig
+	items  []T
+	err    error // Source error or EOS once the source has ended
+}
+
+// Materialize drains stream into memory and returns a Replayable handing out fresh,
+// independent streams over the cached items. Any source error (or ErrMaterializeLimit)
+// is returned here, or with WithLazyMaterialize replayed by every stream that reaches it.
+// Example: users, _ := Materialize(FromRecordsUnsafe(rows)); InnerJoin(users.Stream(), "id", "user_id")
+func Materialize[T any](stream Stream[T], options ...MaterializeOption) (*Replayable[T], error) {
+	config := &materializeConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	r := &Replayable[T]{source: stream, config: config}
+	if config.lazy {
+		return r, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.err == nil {
+		r.fill()
+	}
+	if r.err != EOS {
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// Stream returns a new stream over the cached items, starting from the first one.
+// Streams are independent and safe to read from different goroutines.
+func (r *Replayable[T]) Stream() Stream[T] {
+	position := 0
+	return func() (T, error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for position >= len(r.items) {
+			if r.err != nil {
+				var zero T
+				return zero, r.err
+			}
+			r.fill()
+		}
+		item := r.items[position]
+		position++
+		return item, nil
+	}
+}
+
+// fill reads one more item from the source into the cache; r.mu must be held
+func (r *Replayable[T]) fill() {
+	item, err := r.source()
+	if err != nil {
+		r.err = err
+		r.source = nil
+		return
+	}
+	if r.config.maxItems > 0 && len(r.items) >= r.config.maxItems {
+		r.err = ErrMaterializeLimit
+		r.source = nil
+		return
+	}
+	r.items = append(r.items, item)
+}
+
+// Concat drains each stream in turn, producing their elements as one stream.
+// A non-EOS error from any input is returned immediately.
+func Concat[T any](streams ...Stream[T]) Stream[T] {
+	current := 0
+	return func() (T, error) {
+		for current < len(streams) {
+			item, err := streams[current]()
+			if err == nil {
+				return item, nil
+			}
+			if err != EOS {
+				var zero T
+				return zero, err
+			}
+			current++
+		}
+		var zero T
+		return zero, EOS
+	}
+}
+
+// RoundRobin interleaves streams deterministically, taking one element from each
+// input in turn and skipping inputs once they end, so every partition is consumed
+// at the same pace. It pulls lazily on the caller's goroutine. The first non-EOS
+// error from any input ends the stream and is returned from then on.
+// Example: RoundRobin(FromSlice([]int64{1, 2, 3}), FromSlice([]int64{10})) → 1, 10, 2, 3
+func RoundRobin[T any](streams ...Stream[T]) Stream[T] {
+	active := append([]Stream[T](nil), streams...)
+	next := 0
+	var finalErr error // Sticky once every input ends or one fails
+	return func() (T, error) {
+		var zero T
+		for finalErr == nil {
+			if len(active) == 0 {
+				finalErr = EOS
+				break
+			}
+			if next >= len(active) {
+				next = 0
+			}
+			item, err := active[next]()
+			if err == nil {
+				next++
+				return item, nil
+			}
+			if err != EOS {
+				finalErr = err
+				break
+			}
+			// Drop the ended input; the one after it moves into its turn
+			active = append(active[:next], active[next+1:]...)
+		}
+		return zero, finalErr
+	}
+}
+
+// MergeByKey merges streams that are each sorted by less into one sorted stream,
+// such as per-day files combined chronologically. It holds one record per input and
+// pulls lazily; records that compare equal keep the order of their input streams.
+// Inputs that aren't sorted are merged without error, but the output isn't sorted.
+// The first non-EOS error from any input ends the stream and is returned from then on.
+// Example: MergeByKey(func(a, b Record) bool { return a["ts"].(int64) < b["ts"].(int64) }, monday, tuesday)
+func MergeByKey(less func(a, b Record) bool, streams ...Stream[Record]) Stream[Record] {
+	if less == nil {
+		panic("MergeByKey requires a less function")
+	}
+	return mergeSorted(streams, func(a, b Record) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	})
+}
+
+// Merge interleaves several streams concurrently, so a slow source doesn't hold up
+// fast ones. Output order is not guaranteed. Each pull reads every input at most one
+// element ahead, each in its own goroutine, and a goroutine exits as soon as its read
+// returns; a consumer that stops early leaves nothing running once those reads end.
+// The first non-EOS error from any input is returned and stops the merge.
+func Merge[T any](streams ...Stream[T]) Stream[T] {
+	return MergeContext(context.Background(), streams...)
+}
+
+// MergeContext is Merge with an external context. Once ctx is cancelled the merged
+// stream returns ctx.Err() from then on, without waiting for reads in flight, which
+// makes it the way to stop waiting on inputs that block.
+func MergeContext[T any](ctx context.Context, streams ...Stream[T]) Stream[T] {
+	type mergeItem struct {
+		from int
+		item T
+		err  error
 	}
-	
-	// Start broadcaster goroutine with cancellation
-	go func() {
-		defer func() {
-			for _, ch := range channels {
-				close(ch)
+
+	// At most one read per input is in flight, so sends never block
+	results := make(chan mergeItem, len(streams))
+	reading := make([]bool, len(streams))
+	ended := make([]bool, len(streams))
+	remaining := len(streams)
+
+	var finalErr error // Sticky once the merge has ended
+	return func() (T, error) {
+		var zero T
+		if finalErr != nil {
+			return zero, finalErr
+		}
+		if err := ctx.Err(); err != nil {
+			finalErr = err
+			return zero, err
+		}
+
+		// Start a read on every live input that isn't already being read
+		for i, input := range streams {
+			if !ended[i] && !reading[i] {
+				reading[i] = true
+				go func(from int, input Stream[T]) {
+					item, err := input()
+					results <- mergeItem{from: from, item: item, err: err}
+				}(i, input)
 			}
-		}()
-		
-		for {
+		}
+
+		for remaining > 0 {
 			select {
 			case <-ctx.Done():
-				return
-			default:
-				item, err := stream()
-				if err != nil {
-					return // Source stream ended
+				finalErr = ctx.Err()
+				return zero, finalErr
+			case next := <-results:
+				reading[next.from] = false
+				if next.err == EOS {
+					ended[next.from] = true
+					remaining--
+					continue
 				}
-				
-				// Broadcast to non-abandoned channels only
-				mu.RLock()
-				activeCount := 0
-				for i, ch := range channels {
-					if abandoned[i] {
-						continue
-					}
-					activeCount++
-					
-					select {
-					case ch <- item:
-						// Successfully sent
-					case <-ctx.Done():
-						mu.RUnlock()
-						return
-					default:
-						// Channel full - consumer too slow, mark as abandoned to prevent leak
-						mu.RUnlock()
-						mu.Lock()
-						abandoned[i] = true
-						close(ch) // Close abandoned channel
-						mu.Unlock()
-						mu.RLock()
-					}
+				if next.err != nil {
+					finalErr = next.err
+					return zero, next.err
 				}
-				mu.RUnlock()
-				
-				// If all streams abandoned, terminate
-				if activeCount == 0 {
-					cancel()
+				return next.item, nil
+			}
+		}
+		finalErr = EOS
+		return zero, EOS
+	}
+}
+
+// Buffer decouples a slow producer from a slow consumer: a goroutine eagerly
+// pulls up to size elements ahead into a bounded channel, so production and
+// consumption overlap. No elements are dropped, and the input's EOS or error is
+// returned once the buffered elements before it have been read.
+// The producer stops at the end of the input; if the consumer may stop early,
+// use BufferContext and cancel its context to release it.
+func Buffer[T any](size int) Filter[T, T] {
+	return BufferContext[T](context.Background(), size)
+}
+
+// BufferContext is Buffer with an external context. Cancelling ctx stops the
+// producer goroutine and the buffered stream returns ctx.Err() from then on.
+func BufferContext[T any](ctx context.Context, size int) Filter[T, T] {
+	if size <= 0 {
+		panic("buffer size must be positive")
+	}
+
+	return func(input Stream[T]) Stream[T] {
+		type buffered struct {
+			item T
+			err  error
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		ch := make(chan buffered, size)
+
+		// Pull ahead until the input ends or the consumer goes away
+		go func() {
+			for {
+				item, err := input()
+				select {
+				case ch <- buffered{item: item, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
 					return
 				}
 			}
-		}
-	}()
-	
-	// Create output streams with cleanup tracking
-	streams := make([]Stream[T], n)
-	for i := 0; i < n; i++ {
-		ch := channels[i]
-		streams[i] = func() (T, error) {
+		}()
+
+		var finalErr error // Sticky once the input has ended
+		return func() (T, error) {
+			var zero T
+			if finalErr != nil {
+				return zero, finalErr
+			}
+			if err := ctx.Err(); err != nil {
+				finalErr = err
+				return zero, err
+			}
+
 			select {
-			case <-ctx.Done():
-				var zero T
-				return zero, ctx.Err()
-			case item, ok := <-ch:
-				if !ok {
-					var zero T
-					return zero, EOS
+			case next := <-ch:
+				if next.err != nil {
+					cancel()
+					finalErr = next.err
+					return zero, next.err
 				}
-				return item, nil
+				return next.item, nil
+			case <-ctx.Done():
+				finalErr = ctx.Err()
+				return zero, finalErr
 			}
 		}
 	}
-	
-	return streams
 }
 
 // FlatMap transforms elements and flattens the resulting streams
 func FlatMap[T, U any](fn func(T) Stream[U]) Filter[T, U] {
-	return func(input Stream[T]) Stream[U] {
+	return fused(func(input Stream[T]) Stream[U] {
 		var currentStream Stream[U]
 		
 		return func() (U, error) {
@@ -434,7 +1731,7 @@ func FlatMap[T, U any](fn func(T) Stream[U]) Filter[T, U] {
 				currentStream = fn(inputItem)
 			}
 		}
-	}
+	})
 }
 
 // DotFlatten flattens nested records using dot product flattening (single output per input).
@@ -446,97 +1743,168 @@ func FlatMap[T, U any](fn func(T) Stream[U]) Filter[T, U] {
 // Example with different lengths: {"short": Stream["a", "b"], "long": Stream[1, 2, 3, 4]} →
 //   [{"short": "a", "long": 1}, {"short": "b", "long": 2}] (elements 3, 4 discarded)
 func DotFlatten(separator string, fields ...string) Filter[Record, Record] {
+	return DotFlattenWith(separator, WithFlattenFields(fields...))
+}
+
+// FlattenOption configures DotFlattenWith behavior
+type FlattenOption func(*flattenConfig)
+
+// flattenConfig holds DotFlatten configuration
+type flattenConfig struct {
+	fields   []string
+	maps     bool
+	structs  bool
+	maxDepth int // 0 means unlimited
+}
+
+// WithFlattenFields restricts flattening to the given top-level fields
+func WithFlattenFields(fields ...string) FlattenOption {
+	return func(config *flattenConfig) {
+		config.fields = fields
+	}
+}
+
+// WithFlattenMaps also descends into map[string]any values, using map keys as path segments
+func WithFlattenMaps() FlattenOption {
+	return func(config *flattenConfig) {
+		config.maps = true
+	}
+}
+
+// WithFlattenStructs also descends into the exported fields of struct values (or pointers to structs).
+// Unexported fields are skipped; structs without exported fields (such as time.Time) are kept as-is.
+func WithFlattenStructs() FlattenOption {
+	return func(config *flattenConfig) {
+		config.structs = true
+	}
+}
+
+// WithMaxDepth limits how many levels of nesting are flattened.
+// Values nested deeper than the limit are kept un-flattened, which also guards against cyclic maps.
+func WithMaxDepth(n int) FlattenOption {
+	if n <= 0 {
+		panic("max depth must be positive")
+	}
+	return func(config *flattenConfig) {
+		config.maxDepth = n
+	}
+}
+
+// DotFlattenWith is DotFlatten configured with options.
+// Example: DotFlattenWith(".", WithFlattenMaps(), WithMaxDepth(3))
+func DotFlattenWith(separator string, options ...FlattenOption) Filter[Record, Record] {
 	if separator == "" {
 		separator = "."
 	}
+	config := &flattenConfig{}
+	for _, option := range options {
+		option(config)
+	}
 
 	return func(input Stream[Record]) Stream[Record] {
-		var expandedRecords []Record
-		var currentIndex int
+		var expanded Stream[Record] // The current record's expansion, built as it is pulled
 
 		return func() (Record, error) {
-			// If we have expanded records to return, return them first
-			if currentIndex < len(expandedRecords) {
-				record := expandedRecords[currentIndex]
-				currentIndex++
-				return record, nil
-			}
-
-			// Get next input record
-			record, err := input()
-			if err != nil {
-				return nil, err
-			}
+			for {
+				if expanded != nil {
+					if record, err := expanded(); err == nil {
+						return record, nil
+					}
+					expanded = nil
+				}
 
-			// Expand the record (handling both nested records and streams)
-			expandedRecords = dotFlattenRecordWithStreams(record, "", separator, fields...)
-			currentIndex = 0
+				// Get next input record
+				record, err := input()
+				if err != nil {
+					return nil, err
+				}
 
-			// Return first expanded record
-			if len(expandedRecords) > 0 {
-				currentIndex = 1
-				return expandedRecords[0], nil
+				// Expand the record (handling both nested records and streams)
+				flattened, expansion := dotFlattenRecordWithStreams(record, "", separator, config)
+				if expansion == nil {
+					return flattened, nil
+				}
+				expanded = expansion
 			}
-
-			// Fallback (shouldn't happen)
-			return record, nil
 		}
 	}
 }
 
-// dotFlattenRecord recursively flattens a record using dot notation
-// If fields are specified, only flattens those top-level fields
-func dotFlattenRecord(record Record, prefix, separator string, fields ...string) Record {
-	result := make(Record)
-	
-	// Create a set of fields to flatten for quick lookup
-	fieldsToFlatten := make(map[string]bool)
-	if len(fields) > 0 {
-		for _, field := range fields {
-			fieldsToFlatten[field] = true
+// nestedFields returns the fields of a value DotFlatten should descend into.
+// Records always qualify; map[string]any and structs only when enabled in config.
+func nestedFields(value any, config *flattenConfig) (Record, bool) {
+	switch v := value.(type) {
+	case Record:
+		return v, true
+	case map[string]any:
+		if config.maps {
+			return Record(v), true
 		}
+		return nil, false
 	}
-	
+	if !config.structs || value == nil {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	fields := make(Record)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		if field := rt.Field(i); field.IsExported() {
+			fields[field.Name] = rv.Field(i).Interface()
+		}
+	}
+	if len(fields) == 0 {
+		return nil, false
+	}
+	return fields, true
+}
+
+// dotFlattenInto adds the fields of record to result, flattened using dot notation, so
+// nested levels don't each build a record of their own. depth is the nesting level of
+// record; nesting beyond config.maxDepth is kept as-is.
+func dotFlattenInto(result, record Record, prefix, separator string, config *flattenConfig, depth int) {
 	for key, value := range record {
 		newKey := key
 		if prefix != "" {
 			newKey = prefix + separator + key
 		}
-		
-		// Check if this field should be flattened (only applies to top-level fields)
-		shouldFlatten := len(fields) == 0 || prefix != "" || fieldsToFlatten[key]
-		
-		// If the value is a nested record, flatten it recursively
-		if nestedRecord, ok := value.(Record); ok && shouldFlatten {
-			flattened := dotFlattenRecord(nestedRecord, newKey, separator)
-			for flatKey, flatValue := range flattened {
-				result[flatKey] = flatValue
-			}
+
+		// If the value is nested and within the depth limit, flatten it recursively
+		if nested, ok := nestedFields(value, config); ok && (config.maxDepth == 0 || depth < config.maxDepth) {
+			dotFlattenInto(result, nested, newKey, separator, config, depth+1)
 		} else {
-			// For non-record values (including streams), or fields not to be flattened, keep as-is
+			// For non-nested values (including streams), keep as-is
 			result[newKey] = value
 		}
 	}
-	
-	return result
 }
 
-// dotFlattenRecordWithStreams flattens a record using dot product expansion for streams
-// Returns multiple records when streams are present (dot product expansion)
-// Uses minimum length when streams have different lengths, discarding excess elements
-func dotFlattenRecordWithStreams(record Record, prefix, separator string, fields ...string) []Record {
+// dotFlattenRecordWithStreams flattens a record using dot product expansion for streams.
+// Without stream fields it returns the flattened record and a nil stream; otherwise the
+// stream yields the expansion one record per pull, from the stream values of this record
+// alone. Uses minimum length when streams have different lengths, discarding excess elements
+func dotFlattenRecordWithStreams(record Record, prefix, separator string, config *flattenConfig) (Record, Stream[Record]) {
 	// Create a set of fields to flatten for quick lookup
 	fieldsToFlatten := make(map[string]bool)
-	if len(fields) > 0 {
-		for _, field := range fields {
-			fieldsToFlatten[field] = true
-		}
+	for _, field := range config.fields {
+		fieldsToFlatten[field] = true
 	}
 
 	// Collect all stream fields that should be expanded
 	var streamFields []string
 	var streamValues [][]interface{}
-	var nonStreamRecord Record = make(Record)
+	nonStreamRecord := AcquireRecord(len(record))
 
 	for key, value := range record {
 		newKey := key
@@ -545,37 +1913,32 @@ func dotFlattenRecordWithStreams(record Record, prefix, separator string, fields
 		}
 
 		// Check if this field should be flattened (only applies to top-level fields)
-		shouldFlatten := len(fields) == 0 || prefix != "" || fieldsToFlatten[key]
+		shouldFlatten := len(config.fields) == 0 || prefix != "" || fieldsToFlatten[key]
 
-		// If the value is a nested record, flatten it recursively
-		if nestedRecord, ok := value.(Record); ok && shouldFlatten {
-			flattened := dotFlattenRecord(nestedRecord, newKey, separator)
-			for flatKey, flatValue := range flattened {
-				nonStreamRecord[flatKey] = flatValue
-			}
-		} else if stream, ok := value.(Stream[interface{}]); ok && shouldFlatten {
-			// This is a stream field - collect its values for dot product expansion
-			var values []interface{}
-			for {
-				if val, err := stream(); err == nil {
-					values = append(values, val)
-				} else {
-					break
-				}
-			}
+		// Fields not to be flattened are kept as-is, leaving any stream unread
+		if !shouldFlatten {
+			nonStreamRecord[newKey] = value
+			continue
+		}
+
+		// If the value is nested, flatten it recursively
+		if nested, ok := nestedFields(value, config); ok {
+			dotFlattenInto(nonStreamRecord, nested, newKey, separator, config, 1)
+		} else if values, ok := streamFieldValues(value); ok {
+			// This is a stream field (typed stream or slice) - collect its values for dot product expansion
 			if len(values) > 0 {
 				streamFields = append(streamFields, newKey)
 				streamValues = append(streamValues, values)
 			}
 		} else {
-			// For non-record, non-stream values, or fields not to be flattened, keep as-is
+			// For non-record, non-stream values, keep as-is
 			nonStreamRecord[newKey] = value
 		}
 	}
 
 	// If no stream fields, return single record
 	if len(streamFields) == 0 {
-		return []Record{nonStreamRecord}
+		return nonStreamRecord, nil
 	}
 
 	// Determine the length for dot product (use minimum length of all streams)
@@ -587,9 +1950,12 @@ func dotFlattenRecordWithStreams(record Record, prefix, separator string, fields
 	}
 
 	// Create dot product expansion - pair corresponding elements from each stream
-	var results []Record
-	for i := 0; i < minLen; i++ {
-		result := make(Record)
+	i := 0
+	return nil, func() (Record, error) {
+		if i == minLen {
+			return nil, EOS
+		}
+		result := make(Record, len(nonStreamRecord)+len(streamFields))
 
 		// Copy non-stream fields
 		for key, value := range nonStreamRecord {
@@ -601,10 +1967,9 @@ func dotFlattenRecordWithStreams(record Record, prefix, separator string, fields
 			result[fieldName] = streamValues[j][i]
 		}
 
-		results = append(results, result)
+		i++
+		return result, nil
 	}
-
-	return results
 }
 
 // CrossFlatten expands stream fields using cross product (cartesian product) expansion.
@@ -615,8 +1980,8 @@ func CrossFlatten(separator string, fields ...string) Filter[Record, Record] {
 		separator = "."
 	}
 	
-	return func(input Stream[Record]) Stream[Record] {
-		var expandedStream Stream[Record]
+	return fused(func(input Stream[Record]) Stream[Record] {
+		var expandedStream Stream[Record] // The current record's product, built as it is pulled
 		
 		return func() (Record, error) {
 			for {
@@ -636,21 +2001,101 @@ func CrossFlatten(separator string, fields ...string) Filter[Record, Record] {
 					return nil, err
 				}
 				
-				// Use field-specific flatten algorithm 
-				flattened := crossFlattenRecord(record, separator, fields...)
-				if len(flattened) > 1 {
-					// Multiple records produced, create stream from them
-					expandedStream = FromSlice(flattened)
-				} else if len(flattened) == 1 {
-					// Single record produced, return it directly
-					return flattened[0], nil
-				} else {
-					// No records produced (shouldn't happen), return original
+				// Records without stream fields to expand pass through unchanged
+				expandedStream = crossFlattenRecord(record, fields...)
+				if expandedStream == nil {
 					return record, nil
 				}
 			}
 		}
+	})
+}
+
+// NormalizeListFields converts list fields to the canonical Stream[any] form, which
+// CollectField builds and JSONSource reads arrays into. Typed streams and slices (other
+// than []byte) are collected into a Stream[any]; fields already in that form are left
+// unread. Only the given fields are converted, or every field if none are given.
+// Records with nothing to convert pass through as they are; the others are copied.
+func NormalizeListFields(fields ...string) Filter[Record, Record] {
+	return Map(func(r Record) Record {
+		var result Record
+		for _, field := range fields {
+			result = normalizeListField(r, result, field)
+		}
+		if len(fields) == 0 {
+			for field := range r {
+				result = normalizeListField(r, result, field)
+			}
+		}
+		if result == nil {
+			return r
+		}
+		return result
+	})
+}
+
+// normalizeListField converts r's field to a Stream[any] in result if it is a list in
+// another form, copying r into result first if result is still nil
+func normalizeListField(r, result Record, field string) Record {
+	value := r[field]
+	if _, canonical := value.(Stream[any]); canonical {
+		return result
+	}
+	values, ok := streamFieldValues(value)
+	if !ok {
+		return result
+	}
+	if result == nil {
+		result = AcquireRecord(len(r))
+		for k, v := range r {
+			result[k] = v
+		}
+		inheritFieldOrder(result, r)
+	}
+	result[field] = FromSliceAny(values)
+	return result
+}
+
+// streamFieldValues collects the elements of a stream-shaped field value: any
+// Stream[T] (or func() (T, error)) or a []T slice. Strings, []byte and Records
+// are not stream-shaped. The boolean reports whether value was stream-shaped.
+func streamFieldValues(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case nil, string, []byte, Record:
+		return nil, false
+	case Stream[any]:
+		var values []any
+		for {
+			item, err := v()
+			if err != nil {
+				return values, true
+			}
+			values = append(values, item)
+		}
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		return values, true
+	case reflect.Func:
+		if !isStreamFunc(rv.Type()) || rv.IsNil() {
+			return nil, false
+		}
+		var values []any
+		for {
+			results := rv.Call(nil)
+			if !results[1].IsNil() {
+				return values, true
+			}
+			values = append(values, results[0].Interface())
+		}
 	}
+	return nil, false
 }
 
 // cross performs cartesian product of record slices
@@ -684,36 +2129,32 @@ func flattenRecord(r Record, sep string) []Record {
 	var fs []string
 	
 	for f := range r {
-		if s, ok := r[f].(Stream[interface{}]); ok {
+		if values, ok := streamFieldValues(r[f]); ok {
 			var rs []Record
-			for {
-				if record, err := s(); err == nil {
-					if f != "" {
-						er := make(Record)
-						if recordMap, ok := record.(Record); ok {
-							// Stream contains records - add field prefix
-							for fi := range recordMap {
-								ef := []string{f}
-								if fi != "" {
-									ef = append(ef, fi)
-								}
-								er[strings.Join(ef, sep)] = recordMap[fi]
+			for _, record := range values {
+				if f != "" {
+					er := make(Record)
+					if recordMap, ok := record.(Record); ok {
+						// Stream contains records - add field prefix
+						for fi := range recordMap {
+							ef := []string{f}
+							if fi != "" {
+								ef = append(ef, fi)
 							}
-						} else {
-							// Stream contains scalar values - use field name directly
-							er[f] = record
+							er[strings.Join(ef, sep)] = recordMap[fi]
 						}
-						rs = append(rs, flattenRecord(er, sep)...)
 					} else {
-						if recordMap, ok := record.(Record); ok {
-							rs = append(rs, flattenRecord(recordMap, sep)...)
-						} else {
-							// Scalar in unnamed field
-							rs = append(rs, Record{"": record})
-						}
+						// Stream contains scalar values - use field name directly
+						er[f] = record
 					}
+					rs = append(rs, flattenRecord(er, sep)...)
 				} else {
-					break
+					if recordMap, ok := record.(Record); ok {
+						rs = append(rs, flattenRecord(recordMap, sep)...)
+					} else {
+						// Scalar in unnamed field
+						rs = append(rs, Record{"": record})
+					}
 				}
 			}
 			columns = append(columns, rs)
@@ -736,69 +2177,232 @@ func flattenRecord(r Record, sep string) []Record {
 			cr[f] = r[f]
 		}
 	}
-	
-	return crs
+	
+	return crs
+}
+
+// crossFlattenRecord expands specified stream fields using cartesian product, or all
+// stream fields if none are specified. The product is built one record per pull, from
+// the stream values of r alone, with the first expanded field varying fastest. It
+// returns nil if r has no stream fields to expand. Empty stream fields are dropped.
+func crossFlattenRecord(r Record, fields ...string) Stream[Record] {
+	// Create a set of fields to expand for quick lookup
+	fieldsToExpand := make(map[string]bool)
+	for _, field := range fields {
+		fieldsToExpand[field] = true
+	}
+	
+	var streamFields []string
+	var columns [][]any
+	var nonStreamFields []string
+	for _, f := range r.Keys() {
+		// Check if this field should be expanded; other streams are kept unread
+		if len(fields) > 0 && !fieldsToExpand[f] {
+			nonStreamFields = append(nonStreamFields, f)
+			continue
+		}
+		
+		if values, ok := streamFieldValues(r[f]); ok {
+			if len(values) > 0 {
+				streamFields = append(streamFields, f)
+				columns = append(columns, values)
+			}
+		} else {
+			// Non-stream field
+			nonStreamFields = append(nonStreamFields, f)
+		}
+	}
+	
+	// If no stream fields to expand, there is nothing to build
+	if len(columns) == 0 {
+		return nil
+	}
+	
+	positions := make([]int, len(columns)) // The element of each column in the next record
+	done := false
+	return func() (Record, error) {
+		if done {
+			return nil, EOS
+		}
+		result := make(Record, len(nonStreamFields)+len(columns))
+		for _, f := range nonStreamFields {
+			result[f] = r[f]
+		}
+		for i, f := range streamFields {
+			result[f] = columns[i][positions[i]]
+		}
+		
+		// Advance like an odometer; done once every column wraps around
+		done = true
+		for i := range positions {
+			if positions[i]++; positions[i] < len(columns[i]) {
+				done = false
+				break
+			}
+			positions[i] = 0
+		}
+		return result, nil
+	}
+}
+
+// UnflattenOption configures Unflatten behavior
+type UnflattenOption func(*unflattenConfig)
+
+// unflattenConfig holds Unflatten configuration
+type unflattenConfig struct {
+	conflictKey   string
+	conflictError bool
+	arrays        bool
+}
+
+// WithConflictKey sets the key a scalar is stored under when nested fields share its name.
+// Default is "_value", so {"a": 1, "a.b": 2} becomes {"a": {"_value": 1, "b": 2}}.
+func WithConflictKey(key string) UnflattenOption {
+	return func(config *unflattenConfig) {
+		config.conflictKey = key
+	}
+}
+
+// WithConflictError makes Unflatten fail when a scalar and nested fields share a name
+func WithConflictError() UnflattenOption {
+	return func(config *unflattenConfig) {
+		config.conflictError = true
+	}
+}
+
+// WithArrayDetection turns nested records whose keys are exactly 0..n-1 into Stream[any] fields.
+// Example: {"tags.0": "a", "tags.1": "b"} → {"tags": Stream["a", "b"]}
+func WithArrayDetection() UnflattenOption {
+	return func(config *unflattenConfig) {
+		config.arrays = true
+	}
+}
+
+// Unflatten rebuilds nested records from separator-joined field names - the inverse of DotFlatten.
+// Example: {"id": 1, "user.name": "Alice", "user.city": "NYC"} → {"id": 1, "user": {"name": "Alice", "city": "NYC"}}
+// When a scalar and nested fields share a name, nesting wins and the scalar moves under the conflict key.
+func Unflatten(separator string, options ...UnflattenOption) Filter[Record, Record] {
+	if separator == "" {
+		separator = "."
+	}
+	config := &unflattenConfig{conflictKey: "_value"}
+	for _, option := range options {
+		option(config)
+	}
+
+	return func(input Stream[Record]) Stream[Record] {
+		var finalErr error // Sticky once a conflict is reported
+		return func() (Record, error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			record, err := input()
+			if err != nil {
+				return nil, err
+			}
+			result, err := unflattenRecord(record, separator, config)
+			if err != nil {
+				finalErr = err
+				return nil, err
+			}
+			return result, nil
+		}
+	}
 }
 
-// crossFlattenRecord expands specified stream fields using cartesian product
-// If no fields specified, expands all stream fields
-func crossFlattenRecord(r Record, sep string, fields ...string) []Record {
-	var columns [][]Record
-	var nonStreamFields []string
-	
-	// Create a set of fields to expand for quick lookup
-	fieldsToExpand := make(map[string]bool)
-	if len(fields) > 0 {
-		for _, field := range fields {
-			fieldsToExpand[field] = true
-		}
+// unflattenNode is a record under construction; only nodes are merged, never input values
+type unflattenNode struct {
+	fields map[string]any // Leaf values or *unflattenNode
+}
+
+// unflattenRecord nests the fields of record by splitting their names on separator
+func unflattenRecord(record Record, separator string, config *unflattenConfig) (Record, error) {
+	// Sorted keys make conflict handling independent of map iteration order
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
 	}
-	
-	for f := range r {
-		if s, ok := r[f].(Stream[interface{}]); ok {
-			// Check if this field should be expanded
-			shouldExpand := len(fields) == 0 || fieldsToExpand[f]
-			
-			if shouldExpand {
-				var rs []Record
-				for {
-					if record, err := s(); err == nil {
-						// Create a record with this stream value
-						newRecord := Record{f: record}
-						rs = append(rs, newRecord)
-					} else {
-						break
-					}
+	sort.Strings(keys)
+
+	root := &unflattenNode{fields: make(map[string]any)}
+	for _, key := range keys {
+		segments := strings.Split(key, separator)
+		node := root
+		for i, segment := range segments[:len(segments)-1] {
+			value, exists := node.fields[segment]
+			if !exists {
+				value = &unflattenNode{fields: make(map[string]any)}
+				node.fields[segment] = value
+			} else if _, ok := value.(*unflattenNode); !ok {
+				// A scalar already holds this name; nesting wins unless configured to fail
+				if config.conflictError {
+					return nil, fmt.Errorf("field %q conflicts with nested fields", strings.Join(segments[:i+1], separator))
 				}
-				if len(rs) > 0 {
-					columns = append(columns, rs)
-				}
-			} else {
-				// Keep stream field as-is (don't expand)
-				nonStreamFields = append(nonStreamFields, f)
+				value = &unflattenNode{fields: map[string]any{config.conflictKey: value}}
+				node.fields[segment] = value
 			}
+			node = value.(*unflattenNode)
+		}
+
+		leaf := segments[len(segments)-1]
+		if child, ok := node.fields[leaf].(*unflattenNode); ok {
+			if config.conflictError {
+				return nil, fmt.Errorf("field %q conflicts with nested fields", key)
+			}
+			child.fields[config.conflictKey] = record[key]
+			continue
+		}
+		node.fields[leaf] = record[key]
+	}
+
+	return root.record(config), nil
+}
+
+// record converts the node tree to a Record
+func (n *unflattenNode) record(config *unflattenConfig) Record {
+	result := make(Record, len(n.fields))
+	for key, value := range n.fields {
+		if child, ok := value.(*unflattenNode); ok {
+			result[key] = child.value(config)
 		} else {
-			// Non-stream field
-			nonStreamFields = append(nonStreamFields, f)
+			result[key] = value
 		}
 	}
-	
-	// If no stream fields to expand, return original record
-	if len(columns) == 0 {
-		return []Record{r}
+	return result
+}
+
+// value converts a nested node to a Record, or to a Stream[any] for array-like nodes when enabled
+func (n *unflattenNode) value(config *unflattenConfig) any {
+	if config.arrays {
+		if items, ok := n.asArray(config); ok {
+			return FromSliceAny(items)
+		}
 	}
-	
-	// Create cartesian product of expanded fields
-	crs := cross(columns)
-	
-	// Add non-stream fields to each result record
-	for _, cr := range crs {
-		for _, f := range nonStreamFields {
-			cr[f] = r[f]
+	return n.record(config)
+}
+
+// asArray returns the node's values in index order if its keys are exactly 0..n-1
+func (n *unflattenNode) asArray(config *unflattenConfig) ([]any, bool) {
+	if len(n.fields) == 0 {
+		return nil, false
+	}
+	for key := range n.fields {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(n.fields) || strconv.Itoa(index) != key {
+			return nil, false
 		}
 	}
-	
-	return crs
+
+	items := make([]any, len(n.fields))
+	for key, value := range n.fields {
+		index, _ := strconv.Atoi(key)
+		if child, ok := value.(*unflattenNode); ok {
+			items[index] = child.value(config)
+		} else {
+			items[index] = value
+		}
+	}
+	return items, true
 }
 
 // JoinOption configures join behavior
@@ -808,6 +2412,7 @@ type JoinOption func(*joinConfig)
 type joinConfig struct {
 	leftPrefix  string
 	rightPrefix string
+	keyEncoder  func(any) string
 }
 
 // WithPrefixes sets custom prefixes for field name conflicts
@@ -819,46 +2424,110 @@ func WithPrefixes(leftPrefix, rightPrefix string) JoinOption {
 	}
 }
 
+// WithKeyEncoder replaces the canonical join key encoding. Key values that encode to the
+// same string match; records whose key field is missing or nil still never match.
+func WithKeyEncoder(encoder func(any) string) JoinOption {
+	return func(config *joinConfig) {
+		config.keyEncoder = encoder
+	}
+}
+
 // InnerJoin performs an inner join between left stream and right stream.
 // Only records with matching keys in both streams are returned.
 // WARNING: Right stream is collected into memory - must be finite and reasonably sized.
 func InnerJoin(rightStream Stream[Record], leftKey, rightKey string, options ...JoinOption) Filter[Record, Record] {
-	return createJoin(rightStream, leftKey, rightKey, innerJoinType, options...)
+	return createJoin(rightStream, leftKey, rightKey, JoinInner, options...)
 }
 
 // LeftJoin performs a left join between left stream and right stream.
 // All records from left stream are returned, with matching right records when available.
 // WARNING: Right stream is collected into memory - must be finite and reasonably sized.
 func LeftJoin(rightStream Stream[Record], leftKey, rightKey string, options ...JoinOption) Filter[Record, Record] {
-	return createJoin(rightStream, leftKey, rightKey, leftJoinType, options...)
+	return createJoin(rightStream, leftKey, rightKey, JoinLeft, options...)
 }
 
 // RightJoin performs a right join between left stream and right stream.
 // All records from right stream are returned, with matching left records when available.
+// Right records no left record matched follow the joined rows, in right stream order.
 // WARNING: Right stream is collected into memory - must be finite and reasonably sized.
 func RightJoin(rightStream Stream[Record], leftKey, rightKey string, options ...JoinOption) Filter[Record, Record] {
-	return createJoin(rightStream, leftKey, rightKey, rightJoinType, options...)
+	return createJoin(rightStream, leftKey, rightKey, JoinRight, options...)
 }
 
 // FullJoin performs a full outer join between left stream and right stream.
-// All records from both streams are returned, with matching when available.
+// All records from both streams are returned, with matching when available. As in a SQL
+// FULL OUTER JOIN, a right record is unmatched only if no left record matched it; those
+// follow the left stream's rows, in right stream order.
 // WARNING: Right stream is collected into memory - must be finite and reasonably sized.
 func FullJoin(rightStream Stream[Record], leftKey, rightKey string, options ...JoinOption) Filter[Record, Record] {
-	return createJoin(rightStream, leftKey, rightKey, fullJoinType, options...)
+	return createJoin(rightStream, leftKey, rightKey, JoinFull, options...)
+}
+
+// SemiJoin returns left records that have at least one matching right record.
+// Each left record is emitted unchanged exactly once, no matter how many right records match.
+// WARNING: Right stream is collected into memory - must be finite and reasonably sized.
+func SemiJoin(rightStream Stream[Record], leftKey, rightKey string, options ...JoinOption) Filter[Record, Record] {
+	return createJoin(rightStream, leftKey, rightKey, JoinSemi, options...)
 }
 
-type joinType int
+// AntiJoin returns left records that have no matching right record.
+// Left records whose key field is missing or nil never match and are always emitted.
+// WARNING: Right stream is collected into memory - must be finite and reasonably sized.
+func AntiJoin(rightStream Stream[Record], leftKey, rightKey string, options ...JoinOption) Filter[Record, Record] {
+	return createJoin(rightStream, leftKey, rightKey, JoinAnti, options...)
+}
+
+// CrossJoin pairs every left record with every right record, merging each pair with the
+// same prefix handling as the keyed joins. Output is produced lazily, one pair at a time.
+// WARNING: Right stream is collected into memory - must be finite and reasonably sized.
+func CrossJoin(rightStream Stream[Record], options ...JoinOption) Filter[Record, Record] {
+	return createConditionJoin(rightStream, nil, options...)
+}
+
+// ConditionJoin pairs left and right records for which predicate returns true, for joins
+// that are not on equal keys, such as an event time falling between a window's start and end.
+// Every right record is tested against every left record.
+// WARNING: Right stream is collected into memory - must be finite and reasonably sized.
+func ConditionJoin(rightStream Stream[Record], predicate func(left, right Record) bool, options ...JoinOption) Filter[Record, Record] {
+	if predicate == nil {
+		panic("ConditionJoin predicate must not be nil")
+	}
+	return createConditionJoin(rightStream, predicate, options...)
+}
+
+// JoinKind selects which records a join emits
+type JoinKind int
 
 const (
-	innerJoinType joinType = iota
-	leftJoinType
-	rightJoinType
-	fullJoinType
+	JoinInner JoinKind = iota // Matched pairs only
+	JoinLeft                  // Matched pairs, plus unmatched left records
+	JoinRight                 // Matched pairs, plus unmatched right records
+	JoinFull                  // Matched pairs, plus unmatched records from both sides
+	JoinSemi                  // Left records with at least one match, unchanged
+	JoinAnti                  // Left records with no match, unchanged
 )
 
-// createJoin implements the hash join algorithm for all join types
-func createJoin(rightStream Stream[Record], leftKey, rightKey string, jType joinType, options ...JoinOption) Filter[Record, Record] {
-	// Apply configuration options
+// createJoin implements the hash join algorithm for all join types.
+// Keys are compared by their canonical encoding (see canonicalJoinKey); a record whose
+// key field is missing or nil matches nothing, like NULL in SQL.
+func createJoin(rightStream Stream[Record], leftKey, rightKey string, kind JoinKind, options ...JoinOption) Filter[Record, Record] {
+	config := newJoinConfig(options)
+
+	// The right stream is single-use, so the hash table is built once and
+	// shared by every application of the returned filter
+	var table JoinTable
+	var buildOnce sync.Once
+	return fused(func(leftStream Stream[Record]) Stream[Record] {
+		buildOnce.Do(func() {
+			// A right stream error ends the table early, as EOS would
+			table, _ = buildJoinTable(rightStream, rightKey, config.keyEncoder)
+		})
+		return table.probe(leftStream, leftKey, kind, config)
+	})
+}
+
+// newJoinConfig applies join options over the default prefixes
+func newJoinConfig(options []JoinOption) *joinConfig {
 	config := &joinConfig{
 		leftPrefix:  "left.",
 		rightPrefix: "right.",
@@ -866,260 +2535,708 @@ func createJoin(rightStream Stream[Record], leftKey, rightKey string, jType join
 	for _, option := range options {
 		option(config)
 	}
+	return config
+}
+
+// JoinTable is the hash table side of a join, built once from a finite stream and then
+// shared: it is never modified after it is built, so one table can serve any number of
+// pipelines, including concurrent ones. The table holds its records by reference, so they
+// must not be modified while the table is in use.
+type JoinTable struct {
+	records    []Record         // Every record, in the order it was read
+	rows       map[string][]int // Positions in records of the records with each key
+	keyEncoder func(any) string
+}
+
+// BuildJoinTable reads stream to the end and indexes its records by the rightKey field.
+// Only WithKeyEncoder applies here; the table keeps the encoding for every join it serves.
+// WARNING: The stream is collected into memory - must be finite and reasonably sized.
+func BuildJoinTable(stream Stream[Record], rightKey string, options ...JoinOption) (JoinTable, error) {
+	return buildJoinTable(stream, rightKey, newJoinConfig(options).keyEncoder)
+}
+
+// buildJoinTable indexes stream, returning the records read so far on error
+func buildJoinTable(stream Stream[Record], rightKey string, encoder func(any) string) (JoinTable, error) {
+	table := JoinTable{
+		rows:       make(map[string][]int),
+		keyEncoder: encoder,
+	}
+	for {
+		record, err := stream()
+		if err == EOS {
+			return table, nil
+		}
+		if err != nil {
+			return table, err
+		}
+		table.add(record, rightKey)
+	}
+}
+
+// add appends record to the table, indexing it under its key unless it has none
+func (table *JoinTable) add(record Record, keyField string) {
+	if key, ok := joinKey(record, keyField, table.keyEncoder); ok {
+		table.rows[key] = append(table.rows[key], len(table.records))
+	}
+	table.records = append(table.records, record)
+}
+
+// Len returns the number of records in the table
+func (table JoinTable) Len() int {
+	return len(table.records)
+}
+
+// Join joins a left stream against the table on the leftKey field. The returned filter
+// can be applied any number of times without rebuilding the table. Options set the field
+// prefixes; the key encoding was fixed by BuildJoinTable.
+func (table JoinTable) Join(leftKey string, kind JoinKind, options ...JoinOption) Filter[Record, Record] {
+	config := newJoinConfig(options)
+	if config.keyEncoder != nil {
+		panic("WithKeyEncoder must be passed to BuildJoinTable, not JoinTable.Join")
+	}
+	config.keyEncoder = table.keyEncoder
+	return func(leftStream Stream[Record]) Stream[Record] {
+		return table.probe(leftStream, leftKey, kind, config)
+	}
+}
+
+// LookupJoin joins a left stream against an in-memory lookup table keyed by join key.
+// Left keys are encoded as for any join - canonically, or with WithKeyEncoder - and then
+// looked up in table, so int64(7) and 7.0 both find the entry "7". The table is indexed
+// once, when LookupJoin is called, and the filter can be reused across pipelines.
+// Under JoinRight and JoinFull unmatched entries are emitted in key order.
+func LookupJoin(table map[string]Record, leftKey string, kind JoinKind, options ...JoinOption) Filter[Record, Record] {
+	config := newJoinConfig(options)
+	joinTable := JoinTable{
+		records:    make([]Record, 0, len(table)),
+		rows:       make(map[string][]int, len(table)),
+		keyEncoder: config.keyEncoder,
+	}
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		joinTable.rows[key] = []int{len(joinTable.records)}
+		joinTable.records = append(joinTable.records, table[key])
+	}
+	return fused(func(leftStream Stream[Record]) Stream[Record] {
+		return joinTable.probe(leftStream, leftKey, kind, config)
+	})
+}
+
+// probe streams left records through the table. Everything it tracks is local to the
+// returned stream, so the table itself is only ever read. Right and full joins flag each
+// right record as it is matched, then emit the unflagged ones, keyless records included,
+// in the order the table read them.
+func (table JoinTable) probe(leftStream Stream[Record], leftKey string, kind JoinKind, config *joinConfig) Stream[Record] {
+	var rightMatched []bool // Per right record, for right/full joins
+	if kind == JoinRight || kind == JoinFull {
+		rightMatched = make([]bool, len(table.records))
+	}
+
+	var pendingResults []Record
+	pendingIndex := 0
+	leftFinished := false
+
+	return func() (Record, error) {
+		// Return pending results first
+		if pendingIndex < len(pendingResults) {
+			result := pendingResults[pendingIndex]
+			pendingIndex++
+			return result, nil
+		}
+
+		// Reset pending results, reusing the buffer across left records
+		pendingResults = pendingResults[:0]
+		pendingIndex = 0
+
+		// Advance the left stream until a left record produces results or it ends
+		for !leftFinished {
+			leftRecord, err := leftStream()
+			if err != nil {
+				leftFinished = true
+				// Handle right/full join unmatched right records
+				if rightMatched != nil {
+					for i, rightRecord := range table.records {
+						if !rightMatched[i] {
+							merged := mergeRecords(nil, rightRecord, config.leftPrefix, config.rightPrefix)
+							pendingResults = append(pendingResults, merged)
+						}
+					}
+					if len(pendingResults) > 0 {
+						result := pendingResults[0]
+						pendingIndex = 1
+						return result, nil
+					}
+				}
+				return nil, EOS
+			}
+
+			// Get the join key value from left record
+			leftKeyValue, hasKey := joinKey(leftRecord, leftKey, config.keyEncoder)
+
+			// Look up matching right records; a left record without a key matches nothing
+			if matches, exists := table.rows[leftKeyValue]; exists && hasKey {
+				for _, i := range matches {
+					if rightMatched != nil {
+						rightMatched[i] = true
+					}
+				}
+
+				switch kind {
+				case JoinSemi:
+					// Semi join: emit the left record once, no right fields
+					pendingResults = append(pendingResults, leftRecord)
+				case JoinAnti:
+					// Anti join: matched left records are dropped
+				default:
+					// Create joined records for each match
+					for _, i := range matches {
+						merged := mergeRecords(leftRecord, table.records[i], config.leftPrefix, config.rightPrefix)
+						pendingResults = append(pendingResults, merged)
+					}
+				}
+			} else {
+				// No match found
+				switch kind {
+				case JoinLeft, JoinFull:
+					// Left/Full join: include left record with nil right
+					merged := mergeRecords(leftRecord, nil, config.leftPrefix, config.rightPrefix)
+					pendingResults = append(pendingResults, merged)
+				case JoinAnti:
+					// Anti join: unmatched left records pass through unchanged
+					pendingResults = append(pendingResults, leftRecord)
+				}
+				// Inner/Right/Semi join: skip this left record
+			}
+
+			// Return first result if any
+			if len(pendingResults) > 0 {
+				result := pendingResults[0]
+				pendingIndex = 1
+				return result, nil
+			}
+
+			// No results, loop round to the next left record
+		}
+
+		return nil, EOS
+	}
+}
+
+// createConditionJoin implements the nested loop join behind CrossJoin and ConditionJoin;
+// a nil predicate matches every pair
+func createConditionJoin(rightStream Stream[Record], predicate func(left, right Record) bool, options ...JoinOption) Filter[Record, Record] {
+	config := newJoinConfig(options)
+
+	// As with createJoin, the right stream is collected once and shared by every
+	// application of the returned filter
+	var rightRecords []Record
+	var buildOnce sync.Once
+	buildRightRecords := func() {
+		for {
+			rightRecord, err := rightStream()
+			if err != nil {
+				break // End of right stream
+			}
+			rightRecords = append(rightRecords, rightRecord)
+		}
+	}
+
+	return fused(func(leftStream Stream[Record]) Stream[Record] {
+		buildOnce.Do(buildRightRecords)
+
+		var leftRecord Record
+		rightIndex := len(rightRecords) // Forces a left read on the first call
+		var finalErr error              // Sticky once the left stream has ended
+
+		return func() (Record, error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			for {
+				// Walk the right records for the current left record
+				for rightIndex < len(rightRecords) {
+					rightRecord := rightRecords[rightIndex]
+					rightIndex++
+					if predicate == nil || predicate(leftRecord, rightRecord) {
+						return mergeRecords(leftRecord, rightRecord, config.leftPrefix, config.rightPrefix), nil
+					}
+				}
+
+				if len(rightRecords) == 0 {
+					// Nothing can ever match, so skip reading the left stream
+					finalErr = EOS
+					return nil, finalErr
+				}
+
+				next, err := leftStream()
+				if err != nil {
+					finalErr = err
+					return nil, err
+				}
+				leftRecord = next
+				rightIndex = 0
+			}
+		}
+	})
+}
+
+// joinKey extracts the encoded join key from a record, using encoder if it is set;
+// ok is false when the key field is missing or nil
+func joinKey(record Record, keyField string, encoder func(any) string) (key string, ok bool) {
+	value, exists := record[keyField]
+	if !exists || value == nil {
+		return "", false
+	}
+	if encoder != nil {
+		return encoder(value), true
+	}
+	return canonicalJoinKey(value), true
+}
+
+// canonicalJoinKey encodes a join key value so that equal values encode equally across
+// types: integral floats match integers of any width (1.0 joins 1), times compare by
+// instant regardless of location or monotonic clock reading, and strings are kept as-is,
+// so "1" from a text source still joins the number 1
+func canonicalJoinKey(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		// The NUL prefix keeps times apart from numbers and ordinary strings
+		return "\x00time:" + strconv.FormatInt(v.UnixNano(), 10)
+	case float64:
+		return canonicalFloatKey(v)
+	case float32:
+		return canonicalFloatKey(float64(v))
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	}
+	if n, ok := convertToInt64(value); ok {
+		return strconv.FormatInt(n, 10)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// canonicalFloatKey encodes integral floats as integers and the rest in their shortest form
+func canonicalFloatKey(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// mergeRecords combines left and right records, handling field name conflicts
+func mergeRecords(leftRecord, rightRecord Record, leftPrefix, rightPrefix string) Record {
+	result := AcquireRecord(len(leftRecord) + len(rightRecord))
+	for key, value := range leftRecord {
+		result[key] = value
+	}
+
+	// Without both prefixes, conflicting right values win
+	policy := OverwriteOnConflict
+	if leftPrefix != "" && rightPrefix != "" {
+		policy = PrefixOnConflict(leftPrefix, rightPrefix)
+	}
+	MergeInto(result, rightRecord, policy)
+	inheritFieldOrder(result, leftRecord, rightRecord)
+	return result
+}
+
+// Split splits a stream of records into substreams based on key fields.
+// Each substream contains all records that share the same key values, and new substreams
+// are emitted as new keys appear, so it works with both finite and infinite streams.
+// Records a substream hasn't read yet are buffered for it, so substreams may be read in any
+// order - including one after another - without losing data; the cost is memory for unread
+// groups. Use SplitWithOptions to bound the buffers.
+func Split(keyFields []string) Filter[Record, Stream[Record]] {
+	return SplitWithOptions(keyFields)
+}
+
+// ErrSplitOverflow is returned by a Split substream dropped under SplitFailGroup
+var ErrSplitOverflow = errors.New("split group fell too far behind and was dropped")
+
+// ErrSplitTimeout is returned when a Split reader waits longer than WithSplitTimeout for a full group
+var ErrSplitTimeout = errors.New("split reader timed out waiting for a full group to drain")
+
+// SplitOverflowPolicy defines what Split does when a group's buffer is full
+type SplitOverflowPolicy int
+
+const (
+	SplitBlock     SplitOverflowPolicy = iota // Readers wait for the full group to drain (substreams must be read concurrently)
+	SplitFailGroup                            // The full group is dropped and its substream returns ErrSplitOverflow
+	SplitDrop                                 // Records for the full group are dropped; see WithSplitDropHandler
+)
+
+// SplitOption configures SplitWithOptions behavior
+type SplitOption func(*splitConfig)
+
+// splitConfig holds Split configuration
+type splitConfig struct {
+	ctx        context.Context
+	bufferSize int // 0 means unbounded
+	overflow   SplitOverflowPolicy
+	timeout    time.Duration // 0 means SplitBlock waits forever
+	onDrop     func(Record)
+}
+
+// WithSplitBuffer bounds how many unread records each group may buffer and sets what
+// happens when a group falls further behind. The default is an unbounded buffer.
+func WithSplitBuffer(size int, policy SplitOverflowPolicy) SplitOption {
+	if size <= 0 {
+		panic("buffer size must be positive")
+	}
+	return func(config *splitConfig) {
+		config.bufferSize = size
+		config.overflow = policy
+	}
+}
+
+// WithSplitTimeout fails a reader with ErrSplitTimeout once it has waited longer than
+// timeout for a full group to drain under SplitBlock, instead of waiting forever on a
+// substream nobody reads
+func WithSplitTimeout(timeout time.Duration) SplitOption {
+	if timeout <= 0 {
+		panic("split timeout must be positive")
+	}
+	return func(config *splitConfig) {
+		config.timeout = timeout
+	}
+}
+
+// WithSplitDropHandler calls fn for every record dropped under SplitDrop or SplitFailGroup
+func WithSplitDropHandler(fn func(Record)) SplitOption {
+	return func(config *splitConfig) {
+		config.onDrop = fn
+	}
+}
+
+// WithSplitContext stops Split once ctx is done: a substream or the stream of
+// substreams waiting for a full group to drain, or for another reader's pull, returns
+// ctx.Err() at once, as does every later pull. A pull already reading the source
+// returns first.
+func WithSplitContext(ctx context.Context) SplitOption {
+	return func(config *splitConfig) {
+		config.ctx = ctx
+	}
+}
+
+// SplitWithOptions is Split configured with options.
+// The input is read on demand by whichever stream is pulled, without background goroutines,
+// so abandoned substreams leak nothing but their buffered records. The substreams and the
+// stream of substreams are safe to read from different goroutines.
+func SplitWithOptions(keyFields []string, options ...SplitOption) Filter[Record, Stream[Record]] {
+	config := &splitConfig{ctx: context.Background()}
+	for _, option := range options {
+		option(config)
+	}
+
+	return func(input Stream[Record]) Stream[Stream[Record]] {
+		state := &splitState{
+			source:    input,
+			keyFields: keyFields,
+			config:    config,
+			groups:    make(map[string]*splitGroup),
+		}
+		state.cond = sync.NewCond(&state.mu)
+		context.AfterFunc(config.ctx, func() {
+			state.mu.Lock()
+			state.cond.Broadcast() // Wake waiting readers to see ctx is done
+			state.mu.Unlock()
+		})
+		return state.nextGroup
+	}
+}
+
+// splitState is shared by the substreams of one Split
+type splitState struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	source    Stream[Record]
+	keyFields []string
+	config    *splitConfig
+	groups    map[string]*splitGroup
+	pending   []*splitGroup // New groups the stream of substreams hasn't returned yet
+	outerErr  error         // Failure of the stream of substreams (ErrSplitTimeout)
+	pulling   bool          // A reader is reading the source outside the lock
+	srcErr    error         // Source error or EOS, seen by each reader once its queue is empty
+}
+
+// splitGroup holds the unread records of one key
+type splitGroup struct {
+	queue []Record
+	err   error // ErrSplitOverflow or ErrSplitTimeout
+}
+
+// nextGroup returns the substream for the next new key, reading the source until one appears
+func (s *splitState) nextGroup() (Stream[Record], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deadline time.Time
+	for {
+		if err := s.config.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if s.outerErr != nil {
+			return nil, s.outerErr
+		}
+		if len(s.pending) > 0 {
+			group := s.pending[0]
+			s.pending[0] = nil
+			s.pending = s.pending[1:]
+			return func() (Record, error) {
+				return s.next(group)
+			}, nil
+		}
+		if s.srcErr != nil {
+			return nil, s.srcErr
+		}
+		if err := s.read(nil, &deadline); err != nil {
+			s.outerErr = err
+		}
+	}
+}
+
+// next returns group's next record, reading the source when the group is caught up
+func (s *splitState) next(group *splitGroup) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deadline time.Time
+	for {
+		if err := s.config.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if group.err != nil {
+			return nil, group.err
+		}
+		if len(group.queue) > 0 {
+			record := group.queue[0]
+			group.queue[0] = nil
+			group.queue = group.queue[1:]
+			s.cond.Broadcast() // Room may have opened for a blocked reader
+			return record, nil
+		}
+		if s.srcErr != nil {
+			return nil, s.srcErr
+		}
+		if err := s.read(group, &deadline); err != nil {
+			group.err = err
+		}
+	}
+}
+
+// read makes progress for reader (nil for the stream of substreams): it waits while another
+// reader is pulling or a full group must drain first, otherwise it reads one record from the
+// source into its group. The caller holds the lock and re-checks its state afterwards.
+func (s *splitState) read(reader *splitGroup, deadline *time.Time) error {
+	if s.pulling {
+		s.cond.Wait()
+		return nil
+	}
+	if s.mustWait(reader) {
+		return s.waitForRoom(deadline)
+	}
+
+	// Read the source without holding the lock so other substreams can drain their queues
+	s.pulling = true
+	s.mu.Unlock()
+	record, err := s.source()
+	s.mu.Lock()
+	s.pulling = false
+	s.cond.Broadcast()
+
+	if err != nil {
+		s.srcErr = err
+		return nil
+	}
 
-	return func(leftStream Stream[Record]) Stream[Record] {
-		// Build hash table from right stream (WARNING: collects entire right stream into memory)
-		rightMap := make(map[string][]Record)
-		rightKeysUsed := make(map[string]bool) // Track which right keys were matched (for full join)
-		
-		// Collect right stream into hash map
-		for {
-			rightRecord, err := rightStream()
-			if err != nil {
-				break // End of right stream
-			}
-			
-			// Get the join key value from right record
-			rightKeyValue := getJoinKeyValue(rightRecord, rightKey)
-			if rightKeyValue != "" {
-				rightMap[rightKeyValue] = append(rightMap[rightKeyValue], rightRecord)
+	key := buildGroupKey(record, s.keyFields)
+	group, exists := s.groups[key]
+	if !exists {
+		group = &splitGroup{}
+		s.groups[key] = group
+		s.pending = append(s.pending, group)
+	}
+	if group.err != nil {
+		s.drop(record)
+		return nil
+	}
+	if s.config.bufferSize > 0 && len(group.queue) >= s.config.bufferSize {
+		// Only reachable under SplitFailGroup and SplitDrop; SplitBlock waits in mustWait instead
+		if s.config.overflow == SplitFailGroup {
+			group.err = ErrSplitOverflow
+			for _, queued := range group.queue {
+				s.drop(queued)
 			}
+			group.queue = nil
 		}
+		s.drop(record)
+		return nil
+	}
+	group.queue = append(group.queue, record)
+	return nil
+}
 
-		// For right and full joins, we need to track unmatched right records
-		var unmatchedRightRecords []Record
-		if jType == rightJoinType || jType == fullJoinType {
-			// Prepare list of all right records for later processing
-			for key, records := range rightMap {
-				for _, record := range records {
-					unmatchedRightRecords = append(unmatchedRightRecords, record)
-				}
-				rightKeysUsed[key] = false
-			}
+// mustWait reports whether reader has to wait for full groups to drain before reading ahead
+func (s *splitState) mustWait(reader *splitGroup) bool {
+	if s.config.bufferSize == 0 || s.config.overflow != SplitBlock {
+		return false
+	}
+	for _, group := range s.groups {
+		if group != reader && group.err == nil && len(group.queue) >= s.config.bufferSize {
+			return true
 		}
+	}
+	return false
+}
 
-		var pendingResults []Record
-		pendingIndex := 0
-		leftFinished := false
+// waitForRoom waits for a group to drain, failing with ErrSplitTimeout once the reader
+// has waited longer than the configured timeout
+func (s *splitState) waitForRoom(deadline *time.Time) error {
+	if s.config.timeout == 0 {
+		s.cond.Wait()
+		return nil
+	}
 
-		return func() (Record, error) {
-			// Return pending results first
-			if pendingIndex < len(pendingResults) {
-				result := pendingResults[pendingIndex]
-				pendingIndex++
-				return result, nil
-			}
+	now := time.Now()
+	if deadline.IsZero() {
+		*deadline = now.Add(s.config.timeout)
+	}
+	if !now.Before(*deadline) {
+		return ErrSplitTimeout
+	}
+	wake := time.AfterFunc(deadline.Sub(now), func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	s.cond.Wait()
+	wake.Stop()
+	return nil
+}
 
-			// Reset pending results
-			pendingResults = nil
-			pendingIndex = 0
+// drop reports a record that no substream will see
+func (s *splitState) drop(record Record) {
+	if s.config.onDrop != nil {
+		s.config.onDrop(record)
+	}
+}
 
-			// Process left stream
-			if !leftFinished {
-				leftRecord, err := leftStream()
-				if err != nil {
-					leftFinished = true
-					// Handle right/full join unmatched right records
-					if jType == rightJoinType || jType == fullJoinType {
-						for key, used := range rightKeysUsed {
-							if !used {
-								for _, rightRecord := range rightMap[key] {
-									merged := mergeRecords(nil, rightRecord, config.leftPrefix, config.rightPrefix)
-									pendingResults = append(pendingResults, merged)
-								}
-							}
-						}
-						if len(pendingResults) > 0 {
-							result := pendingResults[0]
-							pendingIndex = 1
-							return result, nil
-						}
-					}
-					return nil, EOS
-				}
+// ============================================================================
+// SAMPLING - CHEAP SUBSETS OF LARGE STREAMS
+// ============================================================================
 
-				// Get the join key value from left record
-				leftKeyValue := getJoinKeyValue(leftRecord, leftKey)
-				
-				// Look up matching right records
-				if matchingRightRecords, exists := rightMap[leftKeyValue]; exists && leftKeyValue != "" {
-					// Mark this right key as used
-					rightKeysUsed[leftKeyValue] = true
-					
-					// Create joined records for each match
-					for _, rightRecord := range matchingRightRecords {
-						merged := mergeRecords(leftRecord, rightRecord, config.leftPrefix, config.rightPrefix)
-						pendingResults = append(pendingResults, merged)
-					}
-				} else {
-					// No match found
-					if jType == leftJoinType || jType == fullJoinType {
-						// Left/Full join: include left record with nil right
-						merged := mergeRecords(leftRecord, nil, config.leftPrefix, config.rightPrefix)
-						pendingResults = append(pendingResults, merged)
-					}
-					// Inner/Right join: skip this left record
-				}
+// SampleEvery keeps the first element and every nth one after it
+func SampleEvery[T any](n int) Filter[T, T] {
+	if n <= 0 {
+		panic("sample interval must be positive")
+	}
 
-				// Return first result if any
-				if len(pendingResults) > 0 {
-					result := pendingResults[0]
-					pendingIndex = 1
-					return result, nil
+	return func(input Stream[T]) Stream[T] {
+		index := 0
+		return func() (T, error) {
+			for {
+				item, err := input()
+				if err != nil {
+					return item, err
+				}
+				keep := index%n == 0
+				index++
+				if keep {
+					return item, nil
 				}
-
-				// No results, continue to next left record
-				return createJoin(rightStream, leftKey, rightKey, jType, options...)(leftStream)()
 			}
-
-			return nil, EOS
 		}
 	}
 }
 
-// getJoinKeyValue extracts the join key value from a record
-func getJoinKeyValue(record Record, keyField string) string {
-	if value, exists := record[keyField]; exists {
-		return fmt.Sprintf("%v", value)
+// SampleFraction keeps each element independently with probability p.
+// The same seed always keeps the same elements of the same input.
+func SampleFraction[T any](p float64, seed int64) Filter[T, T] {
+	if p < 0 || p > 1 {
+		panic("sample fraction must be between 0 and 1")
 	}
-	return ""
-}
 
-// mergeRecords combines left and right records, handling field name conflicts
-func mergeRecords(leftRecord, rightRecord Record, leftPrefix, rightPrefix string) Record {
-	result := make(Record)
-
-	// Add left record fields
-	if leftRecord != nil {
-		for key, value := range leftRecord {
-			result[key] = value
+	return func(input Stream[T]) Stream[T] {
+		rng := rand.New(rand.NewSource(seed))
+		return func() (T, error) {
+			for {
+				item, err := input()
+				if err != nil {
+					return item, err
+				}
+				if rng.Float64() < p {
+					return item, nil
+				}
+			}
 		}
 	}
+}
+
+// ReservoirSample consumes a stream of unknown length in one pass and returns
+// k elements chosen uniformly at random (Algorithm R), in no particular order.
+// Memory is bounded by k; shorter streams are returned whole.
+func ReservoirSample[T any](k int, seed int64) func(Stream[T]) ([]T, error) {
+	if k <= 0 {
+		panic("sample size must be positive")
+	}
 
-	// Add right record fields, handling conflicts
-	if rightRecord != nil {
-		for key, value := range rightRecord {
-			if _, exists := result[key]; exists && leftPrefix != "" && rightPrefix != "" {
-				// Conflict: add prefixed versions
-				if leftRecord != nil {
-					result[leftPrefix+key] = result[key]
-					delete(result, key)
+	return func(stream Stream[T]) ([]T, error) {
+		rng := rand.New(rand.NewSource(seed))
+		reservoir := make([]T, 0, k)
+		seen := 0
+		for {
+			item, err := stream()
+			if err != nil {
+				if err == EOS {
+					return reservoir, nil
 				}
-				result[rightPrefix+key] = value
-			} else {
-				// No conflict or no prefixes
-				result[key] = value
+				return reservoir, err
+			}
+			seen++
+			if len(reservoir) < k {
+				reservoir = append(reservoir, item)
+			} else if j := rng.Intn(seen); j < k {
+				reservoir[j] = item
 			}
 		}
 	}
-
-	return result
 }
 
-// Split splits a stream of records into substreams based on key fields.
-// Each substream contains all records that share the same key values.
-// Works with both finite and infinite streams using a central dispatcher.
-func Split(keyFields []string) Filter[Record, Stream[Record]] {
-	return func(input Stream[Record]) Stream[Stream[Record]] {
-		ctx, cancel := context.WithCancel(context.Background())
-		newSubstreams := make(chan Stream[Record], 10)
-		groupChannels := make(map[string]chan Record)
-		abandonedGroups := make(map[string]bool)
-		var mu sync.RWMutex
-		
-		// Start dispatcher goroutine with cancellation
-		go func() {
-			defer func() {
-				for _, ch := range groupChannels {
-					close(ch)
-				}
-				close(newSubstreams)
-			}()
-			
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					record, err := input()
-					if err != nil {
-						return // Input stream ended
-					}
-					
-					key := buildGroupKey(record, keyFields)
-					
-					// Check if group was abandoned
-					mu.RLock()
-					isAbandoned := abandonedGroups[key]
-					mu.RUnlock()
-					
-					if isAbandoned {
-						continue // Skip abandoned groups
-					}
-					
-					// Create new substream if needed
-					if _, exists := groupChannels[key]; !exists {
-						groupChan := make(chan Record, 100)
-						groupChannels[key] = groupChan
-						
-						// Create substream with cancellation
-						substream := func() (Record, error) {
-							select {
-							case <-ctx.Done():
-								return nil, ctx.Err()
-							case record, ok := <-groupChan:
-								if !ok {
-									return nil, EOS
-								}
-								return record, nil
-							}
-						}
-						
-						// Emit new substream 
-						select {
-						case newSubstreams <- substream:
-						case <-ctx.Done():
-							return
-						default:
-							// Consumer too slow, abandon this group
-							mu.Lock()
-							abandonedGroups[key] = true
-							close(groupChan)
-							delete(groupChannels, key)
-							mu.Unlock()
-							continue
-						}
-					}
-					
-					// Send record to group channel with non-blocking fallback
-					select {
-					case groupChannels[key] <- record:
-					case <-ctx.Done():
-						return
-					default:
-						// Group consumer too slow - abandon it
-						mu.Lock()
-						abandonedGroups[key] = true
-						close(groupChannels[key])
-						delete(groupChannels, key)
-						mu.Unlock()
-					}
-				}
+// Shuffle collects the input on first pull and emits it in a random order
+// (Fisher-Yates). The same seed always produces the same order; intended for
+// finite streams such as test fixtures.
+func Shuffle[T any](seed int64) Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		var shuffled Stream[T]
+		var collectErr error
+
+		return func() (T, error) {
+			if collectErr != nil {
+				var zero T
+				return zero, collectErr
 			}
-		}()
-		
-		// Return stream with cancellation support
-		return func() (Stream[Record], error) {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case substream, ok := <-newSubstreams:
-				if !ok {
-					cancel() // Cleanup when done
-					return nil, EOS
+			if shuffled == nil {
+				items, err := Collect(input)
+				if err != nil {
+					collectErr = err
+					var zero T
+					return zero, err
 				}
-				return substream, nil
+				rng := rand.New(rand.NewSource(seed))
+				rng.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+				shuffled = FromSliceAny(items)
 			}
+			return shuffled()
 		}
 	}
 }
@@ -1131,25 +3248,32 @@ func Split(keyFields []string) Filter[Record, Stream[Record]] {
 // CountWindow groups elements into batches of N elements.
 // Each batch is emitted as a finite stream, enabling aggregations on infinite streams.
 // Perfect for processing infinite streams in manageable chunks.
+// Once the input ends, the partial batch is emitted and the input is not pulled again.
 func CountWindow[T any](windowSize int) Filter[T, Stream[T]] {
 	if windowSize <= 0 {
 		panic("CountWindow size must be positive")
 	}
 	
-	return func(input Stream[T]) Stream[Stream[T]] {
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
+		var finalErr error // Sticky once the input has ended
 		return func() (Stream[T], error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+
 			// Collect windowSize elements into a batch
 			batch := make([]T, 0, windowSize)
 			
 			for len(batch) < windowSize {
 				item, err := input()
 				if err != nil {
+					finalErr = err
 					// If we hit EOS or error before filling window
 					if len(batch) == 0 {
 						// No elements collected, propagate error
 						return nil, err
 					}
-					// Partial batch - emit what we have
+					// Partial batch - emit what we have; the error follows on the next pull
 					break
 				}
 				batch = append(batch, item)
@@ -1158,73 +3282,356 @@ func CountWindow[T any](windowSize int) Filter[T, Stream[T]] {
 			// Convert batch to stream
 			return FromSliceAny(batch), nil
 		}
+	})
+}
+
+// Chunk groups elements into slices of size; the final chunk may be shorter.
+// Unlike CountWindow it emits plain slices, ready for bulk-insert APIs or
+// ToChannelBatched. Each chunk is a fresh slice the consumer may keep. An input
+// error ends the current chunk early and is returned on the next pull.
+func Chunk[T any](size int) Filter[T, []T] {
+	if size <= 0 {
+		panic("Chunk size must be positive")
+	}
+
+	return fused(func(input Stream[T]) Stream[[]T] {
+		var pendingErr error
+		return func() ([]T, error) {
+			if pendingErr != nil {
+				return nil, pendingErr
+			}
+
+			chunk := make([]T, 0, size)
+			for len(chunk) < size {
+				item, err := input()
+				if err != nil {
+					pendingErr = err
+					if len(chunk) == 0 {
+						return nil, err
+					}
+					return chunk, nil
+				}
+				chunk = append(chunk, item)
+			}
+			return chunk, nil
+		}
+	})
+}
+
+// ChunkBy groups consecutive elements with the same key into one slice,
+// starting a new chunk whenever the key changes - the natural companion to
+// sorted input. Keys that reappear later start a new chunk.
+func ChunkBy[T any, K comparable](keyFn func(T) K) Filter[T, []T] {
+	return fused(func(input Stream[T]) Stream[[]T] {
+		var pendingErr error
+		var next T // First element of the following chunk, read ahead
+		hasNext := false
+		capHint := 0
+
+		return func() ([]T, error) {
+			if !hasNext {
+				if pendingErr != nil {
+					return nil, pendingErr
+				}
+				item, err := input()
+				if err != nil {
+					pendingErr = err
+					return nil, err
+				}
+				next, hasNext = item, true
+			}
+
+			chunk := make([]T, 0, capHint) // Sized like the previous chunk
+			chunk = append(chunk, next)
+			key := keyFn(next)
+			hasNext = false
+			for {
+				item, err := input()
+				if err != nil {
+					pendingErr = err
+					break
+				}
+				if keyFn(item) != key {
+					next, hasNext = item, true
+					break
+				}
+				chunk = append(chunk, item)
+			}
+			capHint = len(chunk)
+			return chunk, nil
+		}
+	})
+}
+
+// Flatten emits the elements of each slice in turn, the inverse of Chunk
+func Flatten[T any]() Filter[[]T, T] {
+	return func(input Stream[[]T]) Stream[T] {
+		var current []T
+		index := 0
+		return func() (T, error) {
+			for index >= len(current) {
+				chunk, err := input()
+				if err != nil {
+					var zero T
+					return zero, err
+				}
+				current, index = chunk, 0
+			}
+			item := current[index]
+			index++
+			return item, nil
+		}
 	}
 }
 
 // TimeWindow groups elements into time-based windows.
-// Collects elements for the specified duration, then emits as a finite stream.
+// Each window collects the elements that arrive within duration of it being requested and
+// is emitted as a finite stream; windows with no elements are skipped. A single reader
+// goroutine pulls the input, so an element still being read when a window closes goes into
+// the next window. The reader stops at the end of the input; if the consumer may stop
+// early, use TimeWindowContext and cancel its context to release it.
 func TimeWindow[T any](duration time.Duration) Filter[T, Stream[T]] {
+	return TimeWindowContext[T](context.Background(), duration)
+}
+
+// TimeWindowContext is TimeWindow with an external context. Cancelling ctx stops the
+// reader goroutine and the windowed stream returns ctx.Err() from then on.
+func TimeWindowContext[T any](ctx context.Context, duration time.Duration) Filter[T, Stream[T]] {
 	if duration <= 0 {
 		panic("TimeWindow duration must be positive")
 	}
-	
-	return func(input Stream[T]) Stream[Stream[T]] {
+
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
+		ctx, cancel := context.WithCancel(ctx)
+		ch := make(chan windowItem[T])
+		started := false
+
+		var finalErr error // Sticky once the input has ended
 		return func() (Stream[T], error) {
-			batch := make([]T, 0)
-			deadline := time.Now().Add(duration)
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			if !started {
+				started = true
+				go windowReader(ctx, input, ch)
+			}
+
+			var batch []T
 			timer := time.NewTimer(duration)
 			defer timer.Stop()
-			
+
+			for {
+				select {
+				case next := <-ch:
+					if next.err != nil {
+						cancel()
+						finalErr = next.err
+						if len(batch) == 0 {
+							return nil, finalErr
+						}
+						// Emit the partial window; the error follows on the next pull
+						return FromSliceAny(batch), nil
+					}
+					batch = append(batch, next.item)
+				case <-timer.C:
+					if len(batch) > 0 {
+						return FromSliceAny(batch), nil
+					}
+					// Nothing arrived - skip the empty window
+					timer.Reset(duration)
+				case <-ctx.Done():
+					finalErr = ctx.Err()
+					return nil, finalErr
+				}
+			}
+		}
+	})
+}
+
+// windowItem is an element, or the input's terminal error, passed from a window's reader goroutine
+type windowItem[T any] struct {
+	item T
+	err  error
+}
+
+// windowReader pulls input into ch until the input ends or ctx is done
+func windowReader[T any](ctx context.Context, input Stream[T], ch chan<- windowItem[T]) {
+	for {
+		item, err := input()
+		select {
+		case ch <- windowItem[T]{item: item, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// SlidingTimeWindow creates overlapping processing-time windows of length size, one every
+// slide, timed from the first pull. Each window holds the elements that arrived during its
+// span; windows with no elements are skipped. At the end of the input the elements the next
+// window would have covered are emitted as a final partial window.
+// If the consumer may stop early, use SlidingTimeWindowContext and cancel its context.
+func SlidingTimeWindow[T any](size, slide time.Duration) Filter[T, Stream[T]] {
+	return SlidingTimeWindowContext[T](context.Background(), size, slide)
+}
+
+// SlidingTimeWindowContext is SlidingTimeWindow with an external context. Cancelling ctx
+// stops the reader goroutine and the windowed stream returns ctx.Err() from then on.
+func SlidingTimeWindowContext[T any](ctx context.Context, size, slide time.Duration) Filter[T, Stream[T]] {
+	if size <= 0 || slide <= 0 {
+		panic("SlidingTimeWindow size and slide must be positive")
+	}
+	if slide > size {
+		panic("SlidingTimeWindow slide cannot be larger than window size")
+	}
+
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
+		type stamped struct {
+			at   time.Time
+			item T
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		ch := make(chan windowItem[T])
+		var buffer []stamped   // Elements that a current or later window still covers
+		var windowEnd time.Time // End of the next window to emit
+
+		var pendingErr error // Input ended; the remaining buffer is emitted first
+		var finalErr error   // Sticky once everything has been emitted
+		return func() (Stream[T], error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			if windowEnd.IsZero() {
+				windowEnd = time.Now().Add(size)
+				go windowReader(ctx, input, ch)
+			}
+			if pendingErr != nil {
+				finalErr = pendingErr
+				return nil, finalErr
+			}
+
+			timer := time.NewTimer(time.Until(windowEnd))
+			defer timer.Stop()
+
+			for {
+				select {
+				case next := <-ch:
+					if next.err != nil {
+						cancel()
+						pendingErr = next.err
+						if len(buffer) == 0 {
+							finalErr = pendingErr
+							return nil, finalErr
+						}
+						window := make([]T, len(buffer))
+						for i, s := range buffer {
+							window[i] = s.item
+						}
+						buffer = nil
+						return FromSliceAny(window), nil
+					}
+					buffer = append(buffer, stamped{at: time.Now(), item: next.item})
+				case <-timer.C:
+					start := windowEnd.Add(-size)
+					var window []T
+					for _, s := range buffer {
+						if !s.at.Before(start) && s.at.Before(windowEnd) {
+							window = append(window, s.item)
+						}
+					}
+
+					// Drop elements no later window covers
+					windowEnd = windowEnd.Add(slide)
+					cut := windowEnd.Add(-size)
+					keep := 0
+					for keep < len(buffer) && buffer[keep].at.Before(cut) {
+						keep++
+					}
+					buffer = buffer[keep:]
+
+					if len(window) > 0 {
+						return FromSliceAny(window), nil
+					}
+					timer.Reset(time.Until(windowEnd))
+				case <-ctx.Done():
+					finalErr = ctx.Err()
+					return nil, finalErr
+				}
+			}
+		}
+	})
+}
+
+// SessionGapWindow groups bursts of elements: a window starts with the first element after
+// the previous one closed and closes once no element has arrived for gap. The final partial
+// window is emitted at the end of the input. Unlike SessionWindow it needs no activity
+// detector, which suits tail -f style sources built with Generate.
+// If the consumer may stop early, use SessionGapWindowContext and cancel its context.
+func SessionGapWindow[T any](gap time.Duration) Filter[T, Stream[T]] {
+	return SessionGapWindowContext[T](context.Background(), gap)
+}
+
+// SessionGapWindowContext is SessionGapWindow with an external context. Cancelling ctx
+// stops the reader goroutine and the windowed stream returns ctx.Err() from then on.
+func SessionGapWindowContext[T any](ctx context.Context, gap time.Duration) Filter[T, Stream[T]] {
+	if gap <= 0 {
+		panic("SessionGapWindow gap must be positive")
+	}
+
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
+		ctx, cancel := context.WithCancel(ctx)
+		ch := make(chan windowItem[T])
+		started := false
+
+		var finalErr error // Sticky once the input has ended
+		return func() (Stream[T], error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			if !started {
+				started = true
+				go windowReader(ctx, input, ch)
+			}
+
+			// The gap timer only runs once the session has its first element
+			var batch []T
+			timer := time.NewTimer(gap)
+			timer.Stop()
+			defer timer.Stop()
+			var timeout <-chan time.Time
+
 			for {
-				// Try to get next item with deadline-based timeout
-				done := make(chan bool, 1)
-				var item T
-				var err error
-				
-				go func() {
-					item, err = input()
-					done <- true
-				}()
-				
 				select {
-				case <-done:
-					if err != nil {
-						// Stream ended or error
+				case next := <-ch:
+					if next.err != nil {
+						cancel()
+						finalErr = next.err
 						if len(batch) == 0 {
-							return nil, err
+							return nil, finalErr
 						}
-						// Return partial batch
 						return FromSliceAny(batch), nil
 					}
-					batch = append(batch, item)
-					
-					// Check if deadline passed after processing item
-					if time.Now().After(deadline) {
-						break
-					}
-					
-				case <-timer.C:
-					// Window expired - return what we have
-					break
-				}
-			}
-			
-			if len(batch) == 0 {
-				// No items collected in time window, try once more
-				item, err := input()
-				if err != nil {
-					return nil, err
+					batch = append(batch, next.item)
+					timer.Reset(gap)
+					timeout = timer.C
+				case <-timeout:
+					return FromSliceAny(batch), nil
+				case <-ctx.Done():
+					finalErr = ctx.Err()
+					return nil, finalErr
 				}
-				batch = append(batch, item)
 			}
-			
-			return FromSliceAny(batch), nil
 		}
-	}
+	})
 }
 
 // SlidingCountWindow creates overlapping windows of size windowSize with step stepSize.
 // Each window slides by stepSize elements, creating overlapping batches.
+// Once the input ends it is not pulled again.
 func SlidingCountWindow[T any](windowSize, stepSize int) Filter[T, Stream[T]] {
 	if windowSize <= 0 || stepSize <= 0 {
 		panic("SlidingCountWindow size and step must be positive")
@@ -1233,28 +3640,26 @@ func SlidingCountWindow[T any](windowSize, stepSize int) Filter[T, Stream[T]] {
 		panic("SlidingCountWindow step cannot be larger than window size")
 	}
 	
-	return func(input Stream[T]) Stream[Stream[T]] {
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
 		buffer := make([]T, 0, windowSize)
 		
+		var finalErr error // Sticky once the input has ended
 		return func() (Stream[T], error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+
 			// Fill initial buffer if needed
 			for len(buffer) < windowSize {
 				item, err := input()
 				if err != nil {
-					// Stream ended - only return window if we have full size
-					if len(buffer) < windowSize {
-						return nil, err
-					}
-					break
+					// Stream ended - the partial buffer never makes a full window
+					finalErr = err
+					return nil, err
 				}
 				buffer = append(buffer, item)
 			}
 			
-			// Only create window if we have full size
-			if len(buffer) < windowSize {
-				return nil, EOS
-			}
-			
 			// Create current window
 			window := make([]T, len(buffer))
 			copy(window, buffer)
@@ -1271,7 +3676,7 @@ func SlidingCountWindow[T any](windowSize, stepSize int) Filter[T, Stream[T]] {
 			
 			return FromSliceAny(window), nil
 		}
-	}
+	})
 }
 
 // ============================================================================
@@ -1280,18 +3685,28 @@ func SlidingCountWindow[T any](windowSize, stepSize int) Filter[T, Stream[T]] {
 
 // StreamingSum emits running sum continuously as each element arrives.
 // Perfect for real-time dashboards and monitoring.
+// Use StreamingAccumulator to checkpoint the running sum.
 func StreamingSum[T Numeric]() Filter[T, T] {
 	return func(input Stream[T]) Stream[T] {
-		var runningSum T
-		
-		return func() (T, error) {
+		return NewStreamingAccumulator[T]().Sum()(input)
+	}
+}
+
+// StreamingReduce emits the running accumulator as each element arrives.
+// It is the user-defined counterpart of StreamingSum: the final accumulator is
+// returned alongside EOS or any upstream error.
+func StreamingReduce[T, A any](initial A, fn func(A, T) A) Filter[T, A] {
+	return func(input Stream[T]) Stream[A] {
+		acc := initial
+
+		return func() (A, error) {
 			value, err := input()
 			if err != nil {
-				return runningSum, err
+				return acc, err
 			}
-			
-			runningSum += value
-			return runningSum, nil
+
+			acc = fn(acc, value)
+			return acc, nil
 		}
 	}
 }
@@ -1314,27 +3729,133 @@ func StreamingCount[T any]() Filter[T, int64] {
 }
 
 // StreamingAvg emits running average as each element arrives.
-func StreamingAvg[T Numeric]() Filter[T, float64] {
+// NaN and ±Inf values propagate unless a NaNPolicy is given with WithNaNPolicy.
+// Use StreamingAccumulator to checkpoint the running average.
+func StreamingAvg[T Numeric](options ...NaNOption) Filter[T, float64] {
+	config := newNaNConfig(options)
+	return func(input Stream[T]) Stream[float64] {
+		return NewStreamingAccumulator[T]().Avg()(guardNaN[T](config)(input))
+	}
+}
+
+// StreamingAccumulator holds the running sum and count behind StreamingSum,
+// StreamingCount and StreamingAvg outside of their filters, so they can be
+// checkpointed (see Checkpointable) and restored after a restart. Each of its
+// filters updates both, and every stream they are applied to adds to the same totals.
+type StreamingAccumulator[T Numeric] struct {
+	mu    sync.Mutex
+	sum   T
+	count int64
+}
+
+// NewStreamingAccumulator creates an empty running accumulator
+func NewStreamingAccumulator[T Numeric]() *StreamingAccumulator[T] {
+	return &StreamingAccumulator[T]{}
+}
+
+// add accumulates a value and returns the new totals
+func (acc *StreamingAccumulator[T]) add(value T) (T, int64) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	acc.sum += value
+	acc.count++
+	return acc.sum, acc.count
+}
+
+// totals returns the current sum and count
+func (acc *StreamingAccumulator[T]) totals() (T, int64) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	return acc.sum, acc.count
+}
+
+// Sum emits the running sum, like StreamingSum
+func (acc *StreamingAccumulator[T]) Sum() Filter[T, T] {
+	return func(input Stream[T]) Stream[T] {
+		return func() (T, error) {
+			value, err := input()
+			if err != nil {
+				sum, _ := acc.totals()
+				return sum, err
+			}
+			sum, _ := acc.add(value)
+			return sum, nil
+		}
+	}
+}
+
+// Count emits the running count, like StreamingCount
+func (acc *StreamingAccumulator[T]) Count() Filter[T, int64] {
+	return func(input Stream[T]) Stream[int64] {
+		return func() (int64, error) {
+			value, err := input()
+			if err != nil {
+				_, count := acc.totals()
+				return count, err
+			}
+			_, count := acc.add(value)
+			return count, nil
+		}
+	}
+}
+
+// Avg emits the running average, like StreamingAvg
+func (acc *StreamingAccumulator[T]) Avg() Filter[T, float64] {
 	return func(input Stream[T]) Stream[float64] {
-		var sum T
-		var count int64 = 0
-		
 		return func() (float64, error) {
 			value, err := input()
 			if err != nil {
+				sum, count := acc.totals()
 				if count == 0 {
 					return 0, err
 				}
 				return float64(sum) / float64(count), err
 			}
-			
-			sum += value
-			count++
+			sum, count := acc.add(value)
 			return float64(sum) / float64(count), nil
 		}
 	}
 }
 
+// ExportState returns the running sum and count
+func (acc *StreamingAccumulator[T]) ExportState() (Record, error) {
+	sum, count := acc.totals()
+	return Record{"sum": sum, "count": count}, nil
+}
+
+// RestoreState replaces the running sum and count with an exported state
+func (acc *StreamingAccumulator[T]) RestoreState(state Record) error {
+	count, err := checkpointInt(state, "count")
+	if err != nil {
+		return err
+	}
+
+	var sum T
+	switch v := state["sum"].(type) {
+	case T:
+		sum = v
+	default:
+		if kind := reflect.ValueOf(sum).Kind(); kind == reflect.Float32 || kind == reflect.Float64 {
+			f, ok := convertToFloat64(v)
+			if !ok {
+				return fmt.Errorf("checkpoint field %q: expected a number, got %v", "sum", v)
+			}
+			sum = T(f)
+		} else {
+			n, err := checkpointInt(state, "sum")
+			if err != nil {
+				return err
+			}
+			sum = T(n)
+		}
+	}
+
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	acc.sum, acc.count = sum, count
+	return nil
+}
+
 // StreamingMax emits running maximum as each element arrives.
 func StreamingMax[T Comparable]() Filter[T, T] {
 	return func(input Stream[T]) Stream[T] {
@@ -1380,9 +3901,28 @@ func StreamingMin[T Comparable]() Filter[T, T] {
 }
 
 // StreamingStats emits comprehensive running statistics for each element.
-// Returns Record with count, sum, avg, min, max.
-func StreamingStats[T Numeric]() Filter[T, Record] {
+// Returns Record with count, sum, avg, min, max. NaN and ±Inf values propagate
+// unless a NaNPolicy is given with WithNaNPolicy; under NaNSkip the Record also
+// holds "skipped", the number of values left out so far.
+func StreamingStats[T Numeric](options ...NaNOption) Filter[T, Record] {
 	return func(input Stream[T]) Stream[Record] {
+		var skipped int64
+		config := newNaNConfig(options)
+		config.counters = append(config.counters, &skipped)
+		input = guardNaN[T](config)(input)
+		stats := func(count int64, sum, lo, hi T) Record {
+			builder := NewRecord().
+				Int("count", count).
+				Set("sum", sum).
+				Set("avg", float64(sum)/float64(count)).
+				Set("min", lo).
+				Set("max", hi)
+			if config.policy == NaNSkip {
+				builder.Int("skipped", skipped)
+			}
+			return builder.Build()
+		}
+
 		var sum T
 		var count int64 = 0
 		var currentMin, currentMax T
@@ -1395,13 +3935,7 @@ func StreamingStats[T Numeric]() Filter[T, Record] {
 					return nil, err
 				}
 				// Return final stats
-				return NewRecord().
-					Int("count", count).
-					Set("sum", sum).
-					Set("avg", float64(sum)/float64(count)).
-					Set("min", currentMin).
-					Set("max", currentMax).
-					Build(), err
+				return stats(count, sum, currentMin, currentMax), err
 			}
 			
 			// Update statistics
@@ -1422,15 +3956,127 @@ func StreamingStats[T Numeric]() Filter[T, Record] {
 			}
 			
 			// Return current stats
-			return NewRecord().
-				Int("count", count).
-				Set("sum", sum).
-				Set("avg", float64(sum)/float64(count)).
-				Set("min", currentMin).
-				Set("max", currentMax).
-				Build(), nil
+			return stats(count, sum, currentMin, currentMax), nil
+		}
+	}
+}
+
+// StreamingPercentile emits a running estimate of the p-th percentile (0-100)
+// as each element arrives. It uses the P-squared algorithm, which tracks five
+// markers instead of buffering values, so memory stays constant on infinite
+// streams. Estimates are exact until five elements have been seen.
+func StreamingPercentile[T Numeric](p float64) Filter[T, float64] {
+	if p < 0 || p > 100 {
+		panic("StreamingPercentile percentile must be between 0 and 100")
+	}
+	return func(input Stream[T]) Stream[float64] {
+		estimator := newP2Estimator(p / 100)
+
+		return func() (float64, error) {
+			value, err := input()
+			if err != nil {
+				return estimator.estimate(), err
+			}
+
+			estimator.add(float64(value))
+			return estimator.estimate(), nil
+		}
+	}
+}
+
+// p2Estimator implements the P-squared quantile estimator (Jain & Chlamtac, 1985)
+type p2Estimator struct {
+	quantile  float64
+	initial   []float64  // first five observations
+	heights   [5]float64 // marker heights
+	positions [5]float64 // actual marker positions
+	desired   [5]float64 // desired marker positions
+	increment [5]float64 // desired position increments per observation
+}
+
+func newP2Estimator(quantile float64) *p2Estimator {
+	return &p2Estimator{
+		quantile:  quantile,
+		increment: [5]float64{0, quantile / 2, quantile, (1 + quantile) / 2, 1},
+	}
+}
+
+func (e *p2Estimator) add(x float64) {
+	if len(e.initial) < 5 {
+		e.initial = append(e.initial, x)
+		if len(e.initial) == 5 {
+			sorted := append([]float64(nil), e.initial...)
+			sort.Float64s(sorted)
+			copy(e.heights[:], sorted)
+			q := e.quantile
+			e.positions = [5]float64{1, 2, 3, 4, 5}
+			e.desired = [5]float64{1, 1 + 2*q, 1 + 4*q, 3 + 2*q, 5}
+		}
+		return
+	}
+
+	// Find the cell containing x, extending the extremes if needed
+	var k int
+	switch {
+	case x < e.heights[0]:
+		e.heights[0] = x
+		k = 0
+	case x >= e.heights[4]:
+		e.heights[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3; k++ {
+			if x < e.heights[k+1] {
+				break
+			}
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		e.positions[i]++
+	}
+	for i := range e.desired {
+		e.desired[i] += e.increment[i]
+	}
+
+	// Adjust the middle markers towards their desired positions
+	for i := 1; i < 4; i++ {
+		d := e.desired[i] - e.positions[i]
+		if (d >= 1 && e.positions[i+1]-e.positions[i] > 1) || (d <= -1 && e.positions[i-1]-e.positions[i] < -1) {
+			step := math.Copysign(1, d)
+			height := e.parabolic(i, step)
+			if e.heights[i-1] < height && height < e.heights[i+1] {
+				e.heights[i] = height
+			} else {
+				e.heights[i] = e.linear(i, step)
+			}
+			e.positions[i] += step
+		}
+	}
+}
+
+func (e *p2Estimator) parabolic(i int, step float64) float64 {
+	n, q := e.positions, e.heights
+	return q[i] + step/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+step)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-step)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+func (e *p2Estimator) linear(i int, step float64) float64 {
+	j := i + int(step)
+	return e.heights[i] + step*(e.heights[j]-e.heights[i])/(e.positions[j]-e.positions[i])
+}
+
+func (e *p2Estimator) estimate() float64 {
+	if len(e.initial) < 5 {
+		if len(e.initial) == 0 {
+			return 0
 		}
+		sorted := append([]float64(nil), e.initial...)
+		sort.Float64s(sorted)
+		return percentileOf(sorted, e.quantile*100)
 	}
+	return e.heights[2]
 }
 
 // ============================================================================
@@ -1443,6 +4089,30 @@ type Trigger[T any] interface {
 	ResetState() any
 }
 
+// FireMode chooses the window of the element that fires a trigger
+type FireMode int
+
+const (
+	FireIncluding FireMode = iota // The element is the last of the window that fires
+	FireExcluding                 // The element is held back to start the next window
+)
+
+// BoundaryTrigger is a Trigger that chooses the FireMode of the element that fires
+// it; other triggers fire including the element
+type BoundaryTrigger[T any] interface {
+	Trigger[T]
+	// FireMode is called after ShouldFire returns true, with the same state
+	FireMode(state any) FireMode
+}
+
+// triggerFireMode returns the FireMode of a trigger that has just fired
+func triggerFireMode[T any](trigger Trigger[T], state any) FireMode {
+	if boundary, ok := trigger.(BoundaryTrigger[T]); ok {
+		return boundary.FireMode(state)
+	}
+	return FireIncluding
+}
+
 // CountTrigger fires every N elements
 type CountTrigger[T any] struct {
 	N     int
@@ -1453,79 +4123,401 @@ func NewCountTrigger[T any](n int) *CountTrigger[T] {
 	return &CountTrigger[T]{N: n, count: 0}
 }
 
-func (ct *CountTrigger[T]) ShouldFire(element T, state any) bool {
-	ct.count++
-	return ct.count >= ct.N
+func (ct *CountTrigger[T]) ShouldFire(element T, state any) bool {
+	ct.count++
+	return ct.count >= ct.N
+}
+
+func (ct *CountTrigger[T]) ResetState() any {
+	ct.count = 0
+	return nil
+}
+
+// ValueChangeTrigger fires when a specific field value changes. By default the
+// changed element starts the next window (FireExcluding), so each window holds one
+// value; set Mode to FireIncluding to end the window with it instead.
+type ValueChangeTrigger[T any] struct {
+	ExtractFunc func(T) any
+	Mode        FireMode
+	lastValue   any
+	initialized bool
+}
+
+func NewValueChangeTrigger[T any](extractFunc func(T) any) *ValueChangeTrigger[T] {
+	return &ValueChangeTrigger[T]{
+		ExtractFunc: extractFunc,
+		Mode:        FireExcluding,
+		initialized: false,
+	}
+}
+
+func (vct *ValueChangeTrigger[T]) ShouldFire(element T, state any) bool {
+	currentValue := vct.ExtractFunc(element)
+	
+	if !vct.initialized {
+		vct.lastValue = currentValue
+		vct.initialized = true
+		// Fire on first element (no previous value to compare), unless it would
+		// only close an empty window
+		return vct.Mode == FireIncluding
+	}
+	
+	if currentValue != vct.lastValue {
+		vct.lastValue = currentValue
+		return true
+	}
+	
+	return false
+}
+
+func (vct *ValueChangeTrigger[T]) ResetState() any {
+	// Keep the last value for next comparison
+	return nil
+}
+
+func (vct *ValueChangeTrigger[T]) FireMode(state any) FireMode {
+	return vct.Mode
+}
+
+// TimedTrigger is a Trigger that can also fire on the clock, without a new element.
+// TriggeredWindow waits for the deadline alongside the next element.
+type TimedTrigger[T any] interface {
+	Trigger[T]
+	// Deadline returns when the window opened with state should fire, or the zero
+	// time when only an element can fire it
+	Deadline(state any) time.Time
+}
+
+// TimeTrigger fires once Duration has passed since the window opened
+type TimeTrigger[T any] struct {
+	Duration time.Duration
+}
+
+func NewTimeTrigger[T any](d time.Duration) *TimeTrigger[T] {
+	if d <= 0 {
+		panic("TimeTrigger duration must be positive")
+	}
+	return &TimeTrigger[T]{Duration: d}
+}
+
+func (tt *TimeTrigger[T]) ShouldFire(element T, state any) bool {
+	return !time.Now().Before(tt.Deadline(state))
+}
+
+// ResetState opens a window, returning its opening time
+func (tt *TimeTrigger[T]) ResetState() any {
+	return time.Now()
+}
+
+func (tt *TimeTrigger[T]) Deadline(state any) time.Time {
+	opened, _ := state.(time.Time)
+	return opened.Add(tt.Duration)
+}
+
+// PredicateTrigger fires on an element matching Predicate, e.g. an end-of-batch marker
+type PredicateTrigger[T any] struct {
+	Predicate func(T) bool
+}
+
+func NewPredicateTrigger[T any](predicate func(T) bool) *PredicateTrigger[T] {
+	return &PredicateTrigger[T]{Predicate: predicate}
+}
+
+func (pt *PredicateTrigger[T]) ShouldFire(element T, state any) bool {
+	return pt.Predicate(element)
+}
+
+func (pt *PredicateTrigger[T]) ResetState() any {
+	return nil
+}
+
+// AnyTrigger fires as soon as any of its triggers fires, e.g. every 1000 elements
+// or every 5 seconds, whichever comes first. Every trigger sees every element. The
+// element is held back for the next window if any trigger it fired excludes it.
+type AnyTrigger[T any] struct {
+	Triggers []Trigger[T]
+}
+
+// compositeTriggerState holds the states of a combinator's triggers, which of them
+// have fired and the FireMode of the last element
+type compositeTriggerState struct {
+	states []any
+	fired  []bool
+	mode   FireMode
+}
+
+// newCompositeTriggerState resets triggers, collecting their states
+func newCompositeTriggerState[T any](triggers []Trigger[T]) *compositeTriggerState {
+	composite := &compositeTriggerState{states: make([]any, len(triggers)), fired: make([]bool, len(triggers))}
+	for i, trigger := range triggers {
+		composite.states[i] = trigger.ResetState()
+	}
+	return composite
+}
+
+func NewAnyTrigger[T any](triggers ...Trigger[T]) *AnyTrigger[T] {
+	if len(triggers) == 0 {
+		panic("AnyTrigger needs at least one trigger")
+	}
+	return &AnyTrigger[T]{Triggers: triggers}
+}
+
+func (at *AnyTrigger[T]) ShouldFire(element T, state any) bool {
+	composite, ok := state.(*compositeTriggerState)
+	if !ok {
+		composite = newCompositeTriggerState(at.Triggers)
+	}
+	fire := false
+	composite.mode = FireIncluding
+	for i, trigger := range at.Triggers {
+		if trigger.ShouldFire(element, composite.states[i]) {
+			fire = true
+			if triggerFireMode(trigger, composite.states[i]) == FireExcluding {
+				composite.mode = FireExcluding
+			}
+		}
+	}
+	return fire
+}
+
+// ResetState resets every trigger, returning their states
+func (at *AnyTrigger[T]) ResetState() any {
+	return newCompositeTriggerState(at.Triggers)
+}
+
+func (at *AnyTrigger[T]) FireMode(state any) FireMode {
+	if composite, ok := state.(*compositeTriggerState); ok {
+		return composite.mode
+	}
+	return FireIncluding
+}
+
+// Deadline is the earliest deadline of the timed triggers
+func (at *AnyTrigger[T]) Deadline(state any) time.Time {
+	composite, ok := state.(*compositeTriggerState)
+	if !ok {
+		return time.Time{}
+	}
+	var earliest time.Time
+	for i, trigger := range at.Triggers {
+		if timed, ok := trigger.(TimedTrigger[T]); ok {
+			deadline := timed.Deadline(composite.states[i])
+			if !deadline.IsZero() && (earliest.IsZero() || deadline.Before(earliest)) {
+				earliest = deadline
+			}
+		}
+	}
+	return earliest
+}
+
+// AllTrigger fires once each of its triggers has fired since the window opened. The
+// element is held back for the next window if any trigger it fired excludes it.
+type AllTrigger[T any] struct {
+	Triggers []Trigger[T]
+}
+
+func NewAllTrigger[T any](triggers ...Trigger[T]) *AllTrigger[T] {
+	if len(triggers) == 0 {
+		panic("AllTrigger needs at least one trigger")
+	}
+	return &AllTrigger[T]{Triggers: triggers}
+}
+
+func (at *AllTrigger[T]) ShouldFire(element T, state any) bool {
+	all, ok := state.(*compositeTriggerState)
+	if !ok {
+		all = newCompositeTriggerState(at.Triggers)
+	}
+	fire := true
+	all.mode = FireIncluding
+	for i, trigger := range at.Triggers {
+		if !all.fired[i] && trigger.ShouldFire(element, all.states[i]) {
+			all.fired[i] = true
+			if triggerFireMode(trigger, all.states[i]) == FireExcluding {
+				all.mode = FireExcluding
+			}
+		}
+		fire = fire && all.fired[i]
+	}
+	return fire
 }
 
-func (ct *CountTrigger[T]) ResetState() any {
-	ct.count = 0
-	return nil
+// ResetState resets every trigger, returning their states
+func (at *AllTrigger[T]) ResetState() any {
+	return newCompositeTriggerState(at.Triggers)
 }
 
-// ValueChangeTrigger fires when a specific field value changes
-type ValueChangeTrigger[T any] struct {
-	ExtractFunc func(T) any
-	lastValue   any
-	initialized bool
+func (at *AllTrigger[T]) FireMode(state any) FireMode {
+	if all, ok := state.(*compositeTriggerState); ok {
+		return all.mode
+	}
+	return FireIncluding
 }
 
-func NewValueChangeTrigger[T any](extractFunc func(T) any) *ValueChangeTrigger[T] {
-	return &ValueChangeTrigger[T]{
-		ExtractFunc: extractFunc,
-		initialized: false,
+// Deadline is when the last timed trigger that hasn't fired will fire, once every
+// untimed trigger has fired; until then only an element can fire the window
+func (at *AllTrigger[T]) Deadline(state any) time.Time {
+	all, ok := state.(*compositeTriggerState)
+	if !ok {
+		return time.Time{}
+	}
+	var latest time.Time
+	for i, trigger := range at.Triggers {
+		if all.fired[i] {
+			continue
+		}
+		timed, ok := trigger.(TimedTrigger[T])
+		if !ok {
+			return time.Time{}
+		}
+		deadline := timed.Deadline(all.states[i])
+		if deadline.IsZero() {
+			return time.Time{}
+		}
+		if deadline.After(latest) {
+			latest = deadline
+		}
 	}
+	return latest
 }
 
-func (vct *ValueChangeTrigger[T]) ShouldFire(element T, state any) bool {
-	currentValue := vct.ExtractFunc(element)
-	
-	if !vct.initialized {
-		vct.lastValue = currentValue
-		vct.initialized = true
-		return true // Fire on first element (no previous value to compare)
-	}
-	
-	if currentValue != vct.lastValue {
-		vct.lastValue = currentValue
-		return true
+// TriggeredWindowOption configures TriggeredWindow
+type TriggeredWindowOption func(*triggeredWindowConfig)
+
+type triggeredWindowConfig struct {
+	emitEmpty bool
+}
+
+// WithEmptyWindows emits an empty window when a timed trigger fires before any element
+// has arrived; by default such windows are skipped
+func WithEmptyWindows() TriggeredWindowOption {
+	return func(c *triggeredWindowConfig) {
+		c.emitEmpty = true
 	}
-	
-	return false
 }
 
-func (vct *ValueChangeTrigger[T]) ResetState() any {
-	// Keep the last value for next comparison
-	return nil
+// TriggeredWindow creates windows based on trigger conditions.
+// The element that fires the trigger ends its window, unless the trigger is a
+// BoundaryTrigger that excludes it; it then starts the next window.
+// Once the input ends, the partial batch is emitted and the input is not pulled again.
+// A TimedTrigger can fire with no new element: its input is then read on a background
+// goroutine, which stops at the end of the input. If the consumer may stop early, use
+// TriggeredWindowContext and cancel its context to release it.
+func TriggeredWindow[T any](trigger Trigger[T], options ...TriggeredWindowOption) Filter[T, Stream[T]] {
+	return TriggeredWindowContext(context.Background(), trigger, options...)
 }
 
-// TriggeredWindow creates windows based on trigger conditions
-func TriggeredWindow[T any](trigger Trigger[T]) Filter[T, Stream[T]] {
-	return func(input Stream[T]) Stream[Stream[T]] {
+// TriggeredWindowContext is TriggeredWindow with an external context. Cancelling ctx
+// stops the reader goroutine of a timed trigger and the windowed stream returns
+// ctx.Err() from then on.
+func TriggeredWindowContext[T any](ctx context.Context, trigger Trigger[T], options ...TriggeredWindowOption) Filter[T, Stream[T]] {
+	config := triggeredWindowConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+	timed, _ := trigger.(TimedTrigger[T])
+
+	return fused(func(input Stream[T]) Stream[Stream[T]] {
+		ctx, cancel := context.WithCancel(ctx)
+		var ch chan windowItem[T] // Set once the reader goroutine has started
+		var held T                // Element that fired excluding, the first of the next window
+		hasHeld := false
+
+		var finalErr error // Sticky once the input has ended
 		return func() (Stream[T], error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+
 			batch := make([]T, 0)
 			triggerState := trigger.ResetState()
+			timer := time.NewTimer(0)
+			timer.Stop()
+			defer timer.Stop()
+
+			// add puts an element in the window and reports whether the window fires
+			add := func(item T) bool {
+				if !trigger.ShouldFire(item, triggerState) {
+					batch = append(batch, item)
+					return false
+				}
+				if triggerFireMode(trigger, triggerState) == FireExcluding {
+					if len(batch) == 0 {
+						// Nothing to close - the element just starts this window
+						batch = append(batch, item)
+						return false
+					}
+					held, hasHeld = item, true
+					return true
+				}
+				batch = append(batch, item)
+				return true
+			}
+
+			if hasHeld {
+				hasHeld = false
+				if add(held) {
+					return FromSliceAny(batch), nil
+				}
+			}
 			
 			for {
-				item, err := input()
+				var deadline time.Time
+				if timed != nil {
+					deadline = timed.Deadline(triggerState)
+				}
+
+				var item T
+				var err error
+				if deadline.IsZero() && ch == nil {
+					// Only an element can fire the window, so read it directly
+					item, err = input()
+				} else {
+					if ch == nil {
+						ch = make(chan windowItem[T])
+						go windowReader(ctx, input, ch)
+					}
+					var timeout <-chan time.Time
+					if !deadline.IsZero() {
+						timer.Reset(time.Until(deadline))
+						timeout = timer.C
+					}
+
+					select {
+					case next := <-ch:
+						item, err = next.item, next.err
+						timer.Stop()
+					case <-timeout:
+						if len(batch) > 0 || config.emitEmpty {
+							return FromSliceAny(batch), nil
+						}
+						// Nothing arrived - skip the empty window and open the next
+						triggerState = trigger.ResetState()
+						continue
+					case <-ctx.Done():
+						finalErr = ctx.Err()
+						return nil, finalErr
+					}
+				}
+
 				if err != nil {
+					cancel()
+					finalErr = err
 					// Stream ended
 					if len(batch) == 0 {
 						return nil, err
 					}
-					// Return partial batch
+					// Return partial batch; the error follows on the next pull
 					return FromSliceAny(batch), nil
 				}
 				
-				batch = append(batch, item)
-				
-				if trigger.ShouldFire(item, triggerState) {
+				if add(item) {
 					// Fire trigger - emit current batch
 					return FromSliceAny(batch), nil
 				}
 			}
 		}
-	}
+	})
 }
 
 // ============================================================================
@@ -1535,42 +4527,161 @@ func TriggeredWindow[T any](trigger Trigger[T]) Filter[T, Stream[T]] {
 // StreamingGroupBy maintains running group statistics and emits updates.
 // Unlike regular GroupBy, this works with infinite streams by emitting
 // updated group totals as new records arrive.
+// Use NewStreamingGroupByOperator to checkpoint the running statistics.
 func StreamingGroupBy(keyFields []string, updateInterval int) Filter[Record, Record] {
 	return func(input Stream[Record]) Stream[Record] {
-		groupStats := make(map[string]*groupAccumulator)
-		processedCount := 0
-		
+		return NewStreamingGroupByOperator(keyFields, updateInterval).Filter()(input)
+	}
+}
+
+// StreamingGroupByOperator is StreamingGroupBy with running statistics that outlive
+// its filter, so they can be checkpointed (see Checkpointable) and restored after a
+// restart. Every stream the filter is applied to adds to the same statistics.
+type StreamingGroupByOperator struct {
+	mu             sync.Mutex
+	keyFields      []string
+	updateInterval int
+	groupStats     map[string]*groupAccumulator
+	processedCount int
+}
+
+// NewStreamingGroupByOperator creates a StreamingGroupBy with exportable state
+func NewStreamingGroupByOperator(keyFields []string, updateInterval int) *StreamingGroupByOperator {
+	return &StreamingGroupByOperator{
+		keyFields:      keyFields,
+		updateInterval: updateInterval,
+		groupStats:     make(map[string]*groupAccumulator),
+	}
+}
+
+// Filter returns the StreamingGroupBy filter that updates this operator's statistics
+func (op *StreamingGroupByOperator) Filter() Filter[Record, Record] {
+	return func(input Stream[Record]) Stream[Record] {
 		return func() (Record, error) {
 			// Process updateInterval records before emitting
-			for i := 0; i < updateInterval; i++ {
+			for i := 0; i < op.updateInterval; i++ {
 				record, err := input()
 				if err != nil {
+					op.mu.Lock()
+					defer op.mu.Unlock()
 					// Stream ended or error
-					if len(groupStats) == 0 {
+					if len(op.groupStats) == 0 {
 						return nil, err
 					}
 					// Emit final group summary
-					return emitGroupSummary(groupStats, processedCount), err
-				}
-				
-				key := buildGroupKey(record, keyFields)
-				
-				// Update or create group stats
-				if stats, exists := groupStats[key]; exists {
-					stats.update(record)
-				} else {
-					stats := newGroupAccumulator(record, keyFields)
-					stats.update(record)
-					groupStats[key] = stats
+					return emitGroupSummary(op.groupStats, op.processedCount), err
 				}
-				
-				processedCount++
+				op.add(record)
 			}
-			
+
 			// Emit current group summary
-			return emitGroupSummary(groupStats, processedCount), nil
+			op.mu.Lock()
+			defer op.mu.Unlock()
+			return emitGroupSummary(op.groupStats, op.processedCount), nil
+		}
+	}
+}
+
+// add updates the statistics of the record's group
+func (op *StreamingGroupByOperator) add(record Record) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	key := buildGroupKey(record, op.keyFields)
+
+	// Update or create group stats
+	if stats, exists := op.groupStats[key]; exists {
+		stats.update(record)
+	} else {
+		stats := newGroupAccumulator(record, op.keyFields)
+		stats.update(record)
+		op.groupStats[key] = stats
+	}
+
+	op.processedCount++
+}
+
+// ExportState returns the count of processed records and each group's key values,
+// count and numeric field sums
+func (op *StreamingGroupByOperator) ExportState() (Record, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	groups := make(Record, len(op.groupStats))
+	for key, stats := range op.groupStats {
+		keyValues := make(Record, len(stats.keyValues))
+		for field, value := range stats.keyValues {
+			keyValues[field] = value
+		}
+		sums := make(Record, len(stats.numericSums))
+		for field, sum := range stats.numericSums {
+			sums[field] = sum
+		}
+		groups[key] = Record{
+			"keys":  keyValues,
+			"count": stats.count,
+			"sums":  sums,
+		}
+	}
+	return Record{
+		"processed": int64(op.processedCount),
+		"groups":    groups,
+	}, nil
+}
+
+// RestoreState replaces the operator's statistics with an exported state
+func (op *StreamingGroupByOperator) RestoreState(state Record) error {
+	processed, err := checkpointInt(state, "processed")
+	if err != nil {
+		return err
+	}
+	groups, err := checkpointRecord(state, "groups")
+	if err != nil {
+		return err
+	}
+
+	groupStats := make(map[string]*groupAccumulator, len(groups))
+	for key := range groups {
+		group, err := checkpointRecord(groups, key)
+		if err != nil {
+			return err
+		}
+		count, err := checkpointInt(group, "count")
+		if err != nil {
+			return err
+		}
+		keyValues, err := checkpointRecord(group, "keys")
+		if err != nil {
+			return err
+		}
+		sums, err := checkpointRecord(group, "sums")
+		if err != nil {
+			return err
+		}
+
+		stats := &groupAccumulator{
+			keyValues:   make(map[string]any, len(keyValues)),
+			count:       count,
+			numericSums: make(map[string]float64, len(sums)),
+		}
+		for field, value := range keyValues {
+			stats.keyValues[field] = value
+		}
+		for field, value := range sums {
+			sum, ok := convertToFloat64(value)
+			if !ok {
+				return fmt.Errorf("checkpoint group %q: sum of %q is not a number: %v", key, field, value)
+			}
+			stats.numericSums[field] = sum
 		}
+		groupStats[key] = stats
 	}
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.groupStats = groupStats
+	op.processedCount = int(processed)
+	return nil
 }
 
 // groupAccumulator maintains running statistics for a group
@@ -1619,7 +4730,8 @@ func emitGroupSummary(groupStats map[string]*groupAccumulator, totalProcessed in
 	var largestGroup *groupAccumulator
 	var largestKey string
 	for key, stats := range groupStats {
-		if largestGroup == nil || stats.count > largestGroup.count {
+		// Ties go to the smallest key so the summary doesn't depend on map order
+		if largestGroup == nil || stats.count > largestGroup.count || (stats.count == largestGroup.count && key < largestKey) {
 			largestGroup = stats
 			largestKey = key
 		}
@@ -1639,35 +4751,45 @@ func emitGroupSummary(groupStats map[string]*groupAccumulator, totalProcessed in
 // SORTING OPERATIONS
 // ============================================================================
 
-// Sort sorts elements using a custom comparison function
+// Sort sorts elements using a custom comparison function.
+// The sort is stable: elements that compare equal keep their input order.
+// Input is collected lazily when the first element is requested, and an input
+// error during collection is returned through the stream.
 // For finite streams only - infinite streams require windowing
 func Sort[T any](cmp func(a, b T) int) Filter[T, T] {
 	return func(input Stream[T]) Stream[T] {
-		// Collect all elements for sorting
 		var elements []T
+		var collectErr error
+		collected := false
+		index := 0
 
-		// Read entire stream
-		for {
-			item, err := input()
-			if err != nil {
-				if err == EOS {
-					break
+		return func() (T, error) {
+			if !collected {
+				collected = true
+
+				// Read entire stream
+				for {
+					item, err := input()
+					if err != nil {
+						if err != EOS {
+							collectErr = err
+						}
+						break
+					}
+					elements = append(elements, item)
 				}
-				// Return error stream
-				return func() (T, error) {
-					var zero T
-					return zero, err
+
+				// Sort the collected elements
+				if collectErr == nil {
+					sortSlice(elements, cmp)
 				}
 			}
-			elements = append(elements, item)
-		}
 
-		// Sort the collected elements
-		sortSlice(elements, cmp)
+			if collectErr != nil {
+				var zero T
+				return zero, collectErr
+			}
 
-		// Return stream of sorted elements
-		index := 0
-		return func() (T, error) {
 			if index >= len(elements) {
 				var zero T
 				return zero, EOS
@@ -1679,6 +4801,19 @@ func Sort[T any](cmp func(a, b T) int) Filter[T, T] {
 	}
 }
 
+// SortLess sorts elements using a less function, keeping equal elements in input order.
+// For finite streams only - infinite streams require windowing
+func SortLess[T any](less func(a, b T) bool) Filter[T, T] {
+	return Sort(func(a, b T) int {
+		if less(a, b) {
+			return -1
+		} else if less(b, a) {
+			return 1
+		}
+		return 0
+	})
+}
+
 // SortAsc sorts elements in ascending order using Comparable constraint
 func SortAsc[T Comparable]() Filter[T, T] {
 	return Sort(func(a, b T) int {
@@ -1703,58 +4838,84 @@ func SortDesc[T Comparable]() Filter[T, T] {
 	})
 }
 
-// SortBy sorts Records by specified fields in ascending order
-func SortBy(fields ...string) Filter[Record, Record] {
-	return Sort(func(a, b Record) int {
-		for _, field := range fields {
-			aVal, aExists := a[field]
-			bVal, bExists := b[field]
+// SortKey describes one Record field to sort on and its direction
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// Asc creates an ascending SortKey for a field
+func Asc(field string) SortKey {
+	return SortKey{Field: field}
+}
+
+// Desc creates a descending SortKey for a field
+func Desc(field string) SortKey {
+	return SortKey{Field: field, Descending: true}
+}
+
+// SortByKeys sorts Records by the given keys, each with its own direction.
+// Values are compared across types: integers and floats compare numerically, times
+// chronologically (against an RFC3339 string too), and values of unrelated types are
+// ordered by kind (nil, bool, number, string, time).
+// Records missing a field sort before records that have it (after, for descending keys).
+// Example: SortByKeys(Asc("department"), Desc("salary"))
+func SortByKeys(keys ...SortKey) Filter[Record, Record] {
+	return Sort(compareByKeys(keys))
+}
 
-			// Handle missing fields
-			if !aExists && !bExists {
+// compareByKeys compares Records by keys in order, for SortByKeys and ExternalSortByKeys
+func compareByKeys(keys []SortKey) func(a, b Record) int {
+	return func(a, b Record) int {
+		for _, key := range keys {
+			result := compareRecordField(a, b, key.Field)
+			if result == 0 {
 				continue
 			}
-			if !aExists {
-				return -1
-			}
-			if !bExists {
-				return 1
-			}
-
-			// Compare values if they're comparable
-			if result := compareValues(aVal, bVal); result != 0 {
-				return result
+			if key.Descending {
+				return -result
 			}
+			return result
 		}
 		return 0
-	})
+	}
+}
+
+// SortBy sorts Records by specified fields in ascending order
+func SortBy(fields ...string) Filter[Record, Record] {
+	keys := make([]SortKey, len(fields))
+	for i, field := range fields {
+		keys[i] = Asc(field)
+	}
+	return SortByKeys(keys...)
 }
 
 // SortByDesc sorts Records by specified fields in descending order
 func SortByDesc(fields ...string) Filter[Record, Record] {
-	return Sort(func(a, b Record) int {
-		for _, field := range fields {
-			aVal, aExists := a[field]
-			bVal, bExists := b[field]
+	keys := make([]SortKey, len(fields))
+	for i, field := range fields {
+		keys[i] = Desc(field)
+	}
+	return SortByKeys(keys...)
+}
 
-			// Handle missing fields
-			if !aExists && !bExists {
-				continue
-			}
-			if !aExists {
-				return 1
-			}
-			if !bExists {
-				return -1
-			}
+// compareRecordField compares one field of two records, treating missing fields as smallest
+func compareRecordField(a, b Record, field string) int {
+	aVal, aExists := a[field]
+	bVal, bExists := b[field]
 
-			// Compare values if they're comparable (reverse order)
-			if result := compareValues(aVal, bVal); result != 0 {
-				return -result
-			}
-		}
+	// Handle missing fields
+	if !aExists && !bExists {
 		return 0
-	})
+	}
+	if !aExists {
+		return -1
+	}
+	if !bExists {
+		return 1
+	}
+
+	return compareValues(aVal, bVal)
 }
 
 // ============================================================================
@@ -1835,48 +4996,43 @@ func BottomK[T any](k int, cmp func(a, b T) int) Filter[T, T] {
 // HELPER FUNCTIONS
 // ============================================================================
 
-// sortSlice sorts a slice using the comparison function
+// sortSlice sorts a slice using the comparison function, preserving the order of equal elements
 func sortSlice[T any](elements []T, cmp func(a, b T) int) {
-	// Simple bubble sort for now - could optimize with quicksort/mergesort
-	n := len(elements)
-	for i := 0; i < n; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if cmp(elements[j], elements[j+1]) > 0 {
-				elements[j], elements[j+1] = elements[j+1], elements[j]
-			}
-		}
-	}
+	sort.SliceStable(elements, func(i, j int) bool {
+		return cmp(elements[i], elements[j]) < 0
+	})
 }
 
-// compareValues compares two Value interfaces
+// compareValues compares two Value interfaces.
+// Integers and floats compare numerically regardless of their concrete type;
+// values of unrelated types are ordered by kind so sorting stays deterministic.
 func compareValues(a, b any) int {
-	switch aVal := a.(type) {
-	case int64:
-		if bVal, ok := b.(int64); ok {
-			if aVal < bVal {
-				return -1
-			} else if aVal > bVal {
-				return 1
-			}
-			return 0
+	if aInt, ok := integerValue(a); ok {
+		if bInt, ok := integerValue(b); ok {
+			return compareOrdered(aInt, bInt)
 		}
-	case float64:
-		if bVal, ok := b.(float64); ok {
-			if aVal < bVal {
-				return -1
-			} else if aVal > bVal {
-				return 1
-			}
-			return 0
+	}
+	if aNum, ok := numericValue(a); ok {
+		if bNum, ok := numericValue(b); ok {
+			return compareOrdered(aNum, bNum)
+		}
+	}
+
+	if aTime, ok := a.(time.Time); ok {
+		if bTime, ok := timeValue(b); ok {
+			return aTime.Compare(bTime)
+		}
+	}
+	if bTime, ok := b.(time.Time); ok {
+		if aTime, ok := timeValue(a); ok {
+			return aTime.Compare(bTime)
 		}
+	}
+
+	switch aVal := a.(type) {
 	case string:
 		if bVal, ok := b.(string); ok {
-			if aVal < bVal {
-				return -1
-			} else if aVal > bVal {
-				return 1
-			}
-			return 0
+			return compareOrdered(aVal, bVal)
 		}
 	case bool:
 		if bVal, ok := b.(bool); ok {
@@ -1888,7 +5044,71 @@ func compareValues(a, b any) int {
 			return 0
 		}
 	}
-	return 0 // Unknown types considered equal
+
+	// Mismatched or unknown types - order by kind
+	return compareOrdered(valueKindRank(a), valueKindRank(b))
+}
+
+// timeValue extracts a time.Time, or parses an RFC3339 string as one
+func timeValue(v any) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, val)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// compareOrdered compares two ordered values
+func compareOrdered[T Comparable](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// integerValue extracts signed and unsigned integers that fit in an int64
+func integerValue(v any) (int64, bool) {
+	switch val := v.(type) {
+	case int, int8, int16, int32, int64:
+		return convertToInt64(val)
+	case uint, uint8, uint16, uint32, uint64:
+		if u := reflect.ValueOf(val).Uint(); u <= math.MaxInt64 {
+			return int64(u), true
+		}
+	}
+	return 0, false
+}
+
+// numericValue extracts any integer or float as a float64
+func numericValue(v any) (float64, bool) {
+	switch val := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return convertToFloat64(val)
+	}
+	return 0, false
+}
+
+// valueKindRank orders values of different kinds: nil, bool, number, string, time, other
+func valueKindRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return 2
+	case string:
+		return 3
+	case time.Time:
+		return 4
+	default:
+		return 5
+	}
 }
 
 // minHeap implements a min-heap for TopK functionality
@@ -1,7 +1,12 @@
 package stream
 
 import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestDotFlatten tests the DotFlatten function
@@ -215,6 +220,75 @@ func TestDotFlatten(t *testing.T) {
 			t.Errorf("Expected age=30, got %v", result["age"])
 		}
 	})
+
+	t.Run("MapsAndStructs", func(t *testing.T) {
+		type point struct {
+			X, Y  int
+			label string
+		}
+		record := Record{
+			"id": 1,
+			"meta": map[string]any{
+				"source": map[string]any{
+					"host": map[string]any{"name": "db1", "port": 5432},
+				},
+			},
+			"pos": point{X: 3, Y: 4, label: "hidden"},
+		}
+
+		results, err := Collect(DotFlattenWith(".", WithFlattenMaps(), WithFlattenStructs())(FromRecordsUnsafe([]Record{record})))
+		if err != nil {
+			t.Fatalf("Failed to collect flattened results: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+
+		result := results[0]
+		if result["meta.source.host.name"] != "db1" || result["meta.source.host.port"] != 5432 {
+			t.Errorf("Expected nested map fields flattened, got %v", result)
+		}
+		if result["pos.X"] != 3 || result["pos.Y"] != 4 {
+			t.Errorf("Expected exported struct fields flattened, got %v", result)
+		}
+		if _, exists := result["pos.label"]; exists {
+			t.Errorf("Unexported struct field should be skipped")
+		}
+		if len(result) != 5 {
+			t.Errorf("Expected 5 fields, got %d: %v", len(result), result)
+		}
+	})
+
+	t.Run("MapsOpaqueByDefault", func(t *testing.T) {
+		record := Record{"meta": map[string]any{"a": 1}}
+
+		results, _ := Collect(DotFlatten(".")(FromRecordsUnsafe([]Record{record})))
+		if _, ok := results[0]["meta"].(map[string]any); !ok {
+			t.Errorf("Expected map to be kept without WithFlattenMaps, got %v", results[0])
+		}
+	})
+
+	t.Run("MaxDepth", func(t *testing.T) {
+		cyclic := map[string]any{"name": "loop"}
+		cyclic["self"] = cyclic
+		record := Record{"a": Record{"b": Record{"c": 1}}, "cyclic": cyclic}
+
+		results, err := Collect(DotFlattenWith(".", WithFlattenMaps(), WithMaxDepth(2))(FromRecordsUnsafe([]Record{record})))
+		if err != nil {
+			t.Fatalf("Failed to collect flattened results: %v", err)
+		}
+
+		result := results[0]
+		if result["a.b.c"] != 1 {
+			t.Errorf("Expected a.b.c=1 within depth limit, got %v", result)
+		}
+		if result["cyclic.self.name"] != "loop" {
+			t.Errorf("Expected cyclic.self.name=loop, got %v", result)
+		}
+		if _, ok := result["cyclic.self.self"].(map[string]any); !ok {
+			t.Errorf("Expected value beyond depth limit kept un-flattened, got %T", result["cyclic.self.self"])
+		}
+	})
 }
 
 // TestCrossFlatten tests the CrossFlatten function
@@ -385,6 +459,363 @@ func TestCrossFlatten(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("TypedStreamsAndSlices", func(t *testing.T) {
+		record := NewRecord().
+			Int("id", 7).
+			String("label", "x").
+			Set("tags", FromSlice([]string{"a", "b"})).
+			Set("owners", FromSliceAny([]Record{{"name": "Alice"}})).
+			Set("counts", FromSlice([]int64{10, 20})).
+			Set("scores", []float64{1.5, 2.5}).
+			Build()
+
+		results, err := Collect(CrossFlatten(".")(FromRecordsUnsafe([]Record{record})))
+		if err != nil {
+			t.Fatalf("Failed to collect flattened results: %v", err)
+		}
+
+		// 2 tags x 1 owner x 2 counts x 2 scores
+		if len(results) != 8 {
+			t.Fatalf("Expected 8 results, got %d", len(results))
+		}
+		seen := make(map[string]bool)
+		for i, result := range results {
+			if result["id"] != int64(7) || result["label"] != "x" {
+				t.Errorf("Result %d: Expected non-stream fields preserved, got %v", i, result)
+			}
+			if _, ok := result["tags"].(string); !ok {
+				t.Errorf("Result %d: Expected tags expanded to a string, got %T", i, result["tags"])
+			}
+			if owner, ok := result["owners"].(Record); !ok || owner["name"] != "Alice" {
+				t.Errorf("Result %d: Expected owners expanded to a Record, got %v", i, result["owners"])
+			}
+			if _, ok := result["counts"].(int64); !ok {
+				t.Errorf("Result %d: Expected counts expanded to an int64, got %T", i, result["counts"])
+			}
+			if _, ok := result["scores"].(float64); !ok {
+				t.Errorf("Result %d: Expected scores expanded to a float64, got %T", i, result["scores"])
+			}
+			seen[fmt.Sprint(result["tags"], result["counts"], result["scores"])] = true
+		}
+		if len(seen) != 8 {
+			t.Errorf("Expected 8 distinct combinations, got %d", len(seen))
+		}
+	})
+
+	t.Run("UnselectedTypedStreamUnread", func(t *testing.T) {
+		pulls := 0
+		lazy := Stream[int64](func() (int64, error) {
+			pulls++
+			return 0, EOS
+		})
+		record := Record{"tags": FromSlice([]string{"a", "b"}), "lazy": lazy}
+
+		results, err := Collect(CrossFlatten(".", "tags")(FromRecordsUnsafe([]Record{record})))
+		if err != nil || len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d (%v)", len(results), err)
+		}
+		if pulls != 0 {
+			t.Errorf("Expected the unselected stream not to be read, got %d pulls", pulls)
+		}
+	})
+}
+
+// TestStreamFieldElementTypes tests that stream fields expand and serialize the same way whatever their element type
+func TestStreamFieldElementTypes(t *testing.T) {
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	times := func() Stream[time.Time] { return FromSliceAny([]time.Time{t0, t0.Add(time.Hour)}) }
+	people := func() Stream[Record] { return FromSlice([]Record{{"name": "Ann"}, {"name": "Bo"}}) }
+
+	t.Run("CrossFlatten", func(t *testing.T) {
+		results, err := Collect(CrossFlatten(".")(Once(Record{"id": int64(1), "at": times(), "who": people()})))
+		if err != nil {
+			t.Fatalf("CrossFlatten failed: %v", err)
+		}
+		if len(results) != 4 {
+			t.Fatalf("Expected 4 records, got %d: %v", len(results), results)
+		}
+		for _, r := range results {
+			if _, ok := r["at"].(time.Time); !ok {
+				t.Errorf("Expected a time.Time element, got %T", r["at"])
+			}
+			if _, ok := r["who"].(Record); !ok {
+				t.Errorf("Expected a Record element, got %T", r["who"])
+			}
+		}
+	})
+
+	t.Run("DotFlatten", func(t *testing.T) {
+		results, err := Collect(DotFlatten(".")(Once(Record{"id": int64(1), "at": times(), "who": people()})))
+		if err != nil {
+			t.Fatalf("DotFlatten failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 records, got %d: %v", len(results), results)
+		}
+		if results[1]["at"] != t0.Add(time.Hour) || !reflect.DeepEqual(results[1]["who"], Record{"name": "Bo"}) {
+			t.Errorf("Expected the second elements paired, got %v", results[1])
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewJSONSink(&buf).WriteStream(Once(Record{"at": times(), "who": people()})); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+		expected := `{"at":["2024-01-02T03:04:05Z","2024-01-02T04:04:05Z"],"who":[{"name":"Ann"},{"name":"Bo"}]}`
+		if strings.TrimSpace(buf.String()) != expected {
+			t.Errorf("Expected %s, got %s", expected, buf.String())
+		}
+	})
+}
+
+// TestFlattenLaziness tests that the flatten filters pull one input record at a time and build each expansion as it is read
+func TestFlattenLaziness(t *testing.T) {
+	// Each record carries two 3-element stream fields; pulled counts the records read
+	source := func(pulled *int) Stream[Record] {
+		records := Map(func(i int64) Record {
+			return Record{
+				"id":     i,
+				"tags":   FromSliceAny([]any{"a", "b", "c"}),
+				"scores": FromSlice([]int64{i, i + 1, i + 2}),
+			}
+		})(Range(0, 1000000, 1))
+		return func() (Record, error) {
+			*pulled++
+			return records()
+		}
+	}
+
+	t.Run("CrossFlatten", func(t *testing.T) {
+		pulled := 0
+		results, err := Collect(Take[Record](10)(CrossFlatten(".")(source(&pulled))))
+		if err != nil {
+			t.Fatalf("CrossFlatten failed: %v", err)
+		}
+		if len(results) != 10 {
+			t.Fatalf("Expected 10 records, got %d", len(results))
+		}
+		if pulled > 3 {
+			t.Errorf("Expected at most 3 source records pulled for 10 outputs of 9 per record, got %d", pulled)
+		}
+		// The product of the first record comes first, then the second's
+		if results[8]["id"] != int64(0) || results[9]["id"] != int64(1) {
+			t.Errorf("Expected 9 records per input, got ids %v and %v", results[8]["id"], results[9]["id"])
+		}
+	})
+
+	t.Run("DotFlatten", func(t *testing.T) {
+		pulled := 0
+		results, err := Collect(Take[Record](10)(DotFlatten(".")(source(&pulled))))
+		if err != nil {
+			t.Fatalf("DotFlatten failed: %v", err)
+		}
+		if len(results) != 10 {
+			t.Fatalf("Expected 10 records, got %d", len(results))
+		}
+		if pulled > 5 {
+			t.Errorf("Expected at most 5 source records pulled for 10 outputs of 3 per record, got %d", pulled)
+		}
+		if results[4]["tags"] != "b" || results[4]["scores"] != int64(2) {
+			t.Errorf("Expected the second pair of the second record, got %v", results[4])
+		}
+	})
+
+	t.Run("LargeProduct", func(t *testing.T) {
+		// A million-record product; taking a few must not build the rest
+		big := make([]int64, 1000)
+		record := Record{"x": FromSlice(big), "y": FromSlice(big)}
+		allocs := testing.AllocsPerRun(1, func() {
+			record["x"], record["y"] = FromSlice(big), FromSlice(big)
+			if _, err := Collect(Take[Record](3)(CrossFlatten(".")(Once(record)))); err != nil {
+				t.Fatalf("CrossFlatten failed: %v", err)
+			}
+		})
+		if allocs > 50000 {
+			t.Errorf("Expected allocations for the stream values only, got %.0f", allocs)
+		}
+	})
+
+	t.Run("CrossOrder", func(t *testing.T) {
+		results, err := Collect(CrossFlatten(".")(Once(Record{
+			"a": FromSliceAny([]any{1, 2}),
+			"b": FromSliceAny([]any{"x", "y", "z"}),
+		})))
+		if err != nil {
+			t.Fatalf("CrossFlatten failed: %v", err)
+		}
+		var pairs []string
+		for _, r := range results {
+			pairs = append(pairs, fmt.Sprintf("%v%v", r["a"], r["b"]))
+		}
+		if strings.Join(pairs, " ") != "1x 2x 1y 2y 1z 2z" {
+			t.Errorf("Expected the first field to vary fastest, got %v", pairs)
+		}
+	})
+}
+
+// TestListFieldForms tests that a list field behaves the same whether it is a Stream[any], a typed stream or a slice
+func TestListFieldForms(t *testing.T) {
+	forms := []struct {
+		name string
+		list func() any
+	}{
+		{"StreamAny", func() any { return FromSliceAny([]any{int64(1), int64(2), int64(3)}) }},
+		{"TypedStream", func() any { return FromSlice([]int64{1, 2, 3}) }},
+		{"Slice", func() any { return []int64{1, 2, 3} }},
+	}
+	record := func(list any) Record { return Record{"id": "a", "n": list} }
+
+	for _, form := range forms {
+		t.Run(form.name, func(t *testing.T) {
+			values, ok := AsList(record(form.list()), "n")
+			if !ok || !reflect.DeepEqual(values, []any{int64(1), int64(2), int64(3)}) {
+				t.Errorf("AsList: expected [1 2 3], got %v (%v)", values, ok)
+			}
+
+			flattened, err := Collect(CrossFlatten(".")(Once(record(form.list()))))
+			if err != nil {
+				t.Fatalf("CrossFlatten failed: %v", err)
+			}
+			var ns []any
+			for _, r := range flattened {
+				ns = append(ns, r["n"])
+			}
+			if !reflect.DeepEqual(ns, []any{int64(1), int64(2), int64(3)}) {
+				t.Errorf("CrossFlatten: expected [1 2 3], got %v", ns)
+			}
+
+			var buf bytes.Buffer
+			if err := NewJSONSink(&buf).WriteStream(Once(record(form.list()))); err != nil {
+				t.Fatalf("Failed to write JSON: %v", err)
+			}
+			if got := strings.TrimSpace(buf.String()); got != `{"id":"a","n":[1,2,3]}` {
+				t.Errorf("JSONSink: expected a JSON array, got %s", got)
+			}
+
+			normalized, err := Collect(NormalizeListFields()(Once(record(form.list()))))
+			if err != nil {
+				t.Fatalf("NormalizeListFields failed: %v", err)
+			}
+			list, ok := normalized[0]["n"].(Stream[any])
+			if !ok {
+				t.Fatalf("Expected a Stream[any], got %T", normalized[0]["n"])
+			}
+			if items, _ := Collect(list); !reflect.DeepEqual(items, []any{int64(1), int64(2), int64(3)}) {
+				t.Errorf("NormalizeListFields: expected [1 2 3], got %v", items)
+			}
+		})
+	}
+
+	t.Run("NotLists", func(t *testing.T) {
+		r := Record{"s": "abc", "b": []byte("abc"), "r": Record{"x": 1}}
+		for _, field := range []string{"s", "b", "r", "missing"} {
+			if values, ok := AsList(r, field); ok {
+				t.Errorf("Expected %s not to be a list, got %v", field, values)
+			}
+		}
+		normalized, err := Collect(NormalizeListFields()(Once(r)))
+		if err != nil || !sameRecord(normalized[0], r) {
+			t.Errorf("Expected a record without lists to pass through unchanged, got %v (%v)", normalized, err)
+		}
+	})
+
+	t.Run("SelectedFields", func(t *testing.T) {
+		r := Record{"a": []string{"x"}, "b": []string{"y"}}
+		normalized, err := Collect(NormalizeListFields("a")(Once(r)))
+		if err != nil {
+			t.Fatalf("NormalizeListFields failed: %v", err)
+		}
+		if _, ok := normalized[0]["a"].(Stream[any]); !ok {
+			t.Errorf("Expected a to be converted, got %T", normalized[0]["a"])
+		}
+		if _, ok := normalized[0]["b"].([]string); !ok {
+			t.Errorf("Expected b to be left alone, got %T", normalized[0]["b"])
+		}
+		if _, ok := r["a"].([]string); !ok {
+			t.Errorf("Expected the input record unchanged, got %T", r["a"])
+		}
+	})
+}
+
+// TestUnflatten tests the Unflatten function
+func TestUnflatten(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		original := Record{
+			"id": 1,
+			"customer": Record{
+				"name": "Alice",
+				"address": Record{"city": "NYC", "zip": "10001"},
+			},
+		}
+
+		results, err := Collect(Unflatten(".")(DotFlatten(".")(FromRecordsUnsafe([]Record{original}))))
+		if err != nil {
+			t.Fatalf("Failed to collect unflattened results: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		if !reflect.DeepEqual(results[0], original) {
+			t.Errorf("Expected %v, got %v", original, results[0])
+		}
+	})
+
+	t.Run("ConflictKey", func(t *testing.T) {
+		record := Record{"a": 1, "a.b": 2}
+
+		results, _ := Collect(Unflatten(".")(FromRecordsUnsafe([]Record{record})))
+		expected := Record{"a": Record{"_value": 1, "b": 2}}
+		if !reflect.DeepEqual(results[0], expected) {
+			t.Errorf("Expected %v, got %v", expected, results[0])
+		}
+
+		results, _ = Collect(Unflatten("_", WithConflictKey("self"))(FromRecordsUnsafe([]Record{{"a_b_c": 3, "a_b": 2}})))
+		expected = Record{"a": Record{"b": Record{"self": 2, "c": 3}}}
+		if !reflect.DeepEqual(results[0], expected) {
+			t.Errorf("Expected %v, got %v", expected, results[0])
+		}
+	})
+
+	t.Run("ConflictError", func(t *testing.T) {
+		stream := Unflatten(".", WithConflictError())(FromRecordsUnsafe([]Record{{"a": 1, "a.b": 2}, {"c": 3}}))
+
+		if _, err := stream(); err == nil {
+			t.Fatal("Expected conflict error")
+		}
+		if _, err := stream(); err == nil || err == EOS {
+			t.Errorf("Expected error to be sticky, got %v", err)
+		}
+	})
+
+	t.Run("ArrayDetection", func(t *testing.T) {
+		record := Record{"tags.0": "a", "tags.1": "b", "gaps.0": "x", "gaps.2": "y", "pts.0.x": 1, "pts.1.x": 2}
+
+		results, err := Collect(Unflatten(".", WithArrayDetection())(FromRecordsUnsafe([]Record{record})))
+		if err != nil {
+			t.Fatalf("Failed to collect unflattened results: %v", err)
+		}
+		result := results[0]
+
+		tags, ok := result["tags"].(Stream[any])
+		if !ok {
+			t.Fatalf("Expected tags to become a Stream[any], got %T", result["tags"])
+		}
+		if values, _ := Collect(tags); !reflect.DeepEqual(values, []any{"a", "b"}) {
+			t.Errorf("Expected [a b], got %v", values)
+		}
+		if _, ok := result["gaps"].(Record); !ok {
+			t.Errorf("Expected non-contiguous indices to stay a Record, got %T", result["gaps"])
+		}
+		pts, ok := result["pts"].(Stream[any])
+		if !ok {
+			t.Fatalf("Expected pts to become a Stream[any], got %T", result["pts"])
+		}
+		if values, _ := Collect(pts); !reflect.DeepEqual(values, []any{Record{"x": 1}, Record{"x": 2}}) {
+			t.Errorf("Expected [{x:1} {x:2}], got %v", values)
+		}
+	})
 }
 
 // TestFlattenIntegration tests both flatten functions working together
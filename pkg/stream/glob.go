@@ -0,0 +1,384 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ============================================================================
+// GLOB SOURCES - MANY FILES AS ONE RECORD STREAM
+// ============================================================================
+
+// SourceFileField is added by NewGlobSource to every record: the path of the file it came from
+const SourceFileField = "_source_file"
+
+// FileParser turns the contents of one file into records, for NewGlobSource
+type FileParser func(reader io.Reader) Stream[Record]
+
+// CSVFile parses a file as CSV with a header row
+func CSVFile(reader io.Reader) Stream[Record] {
+	return NewCSVSource(reader).ToStream()
+}
+
+// TSVFile parses a file as TSV with a header row
+func TSVFile(reader io.Reader) Stream[Record] {
+	return NewTSVSource(reader).ToStream()
+}
+
+// JSONLinesFile parses a file as one JSON object per line
+func JSONLinesFile(reader io.Reader) Stream[Record] {
+	return NewJSONSource(reader).ToStream()
+}
+
+// GlobOption configures NewGlobSource
+type GlobOption func(*globConfig)
+
+// globConfig holds NewGlobSource configuration
+type globConfig struct {
+	ctx          context.Context
+	recursive    bool
+	parallel     int
+	errorHandler func(path string, err error) error
+}
+
+// WithRecursive matches the pattern's file name part in every directory below the
+// pattern's directory, instead of in that directory alone
+func WithRecursive() GlobOption {
+	return func(config *globConfig) {
+		config.recursive = true
+	}
+}
+
+// WithParallelFiles reads up to n files at once, each in its own goroutine. Records of
+// different files are interleaved in arrival order; each file's own order is kept.
+func WithParallelFiles(n int) GlobOption {
+	if n <= 0 {
+		panic("parallel files must be positive")
+	}
+	return func(config *globConfig) {
+		config.parallel = n
+	}
+}
+
+// WithGlobContext stops the source when ctx is cancelled: open files are closed, any
+// reading goroutines exit, and pulls return ctx.Err(). Use it when the consumer may
+// stop before the last file is exhausted.
+func WithGlobContext(ctx context.Context) GlobOption {
+	return func(config *globConfig) {
+		config.ctx = ctx
+	}
+}
+
+// WithFileErrorHandler decides what happens when a file can't be opened or its
+// parser returns an error: returning nil skips the rest of that file, returning an
+// error ends the stream with it. err already names the file. When no handler is set
+// the first such error ends the stream. Under WithParallelFiles the handler is
+// called from one goroutine at a time.
+func WithFileErrorHandler(handler func(path string, err error) error) GlobOption {
+	return func(config *globConfig) {
+		config.errorHandler = handler
+	}
+}
+
+// SkipFileErrors skips files that can't be opened or parsed, from the first bad row
+// on, instead of ending the stream
+func SkipFileErrors() GlobOption {
+	return WithFileErrorHandler(func(string, error) error { return nil })
+}
+
+// NewGlobSource concatenates the files matching pattern (see filepath.Match) into one
+// record stream, parsing each with parse and adding SourceFileField to every record.
+// Files are listed on the first pull and read one at a time in sorted path order; each
+// is opened only when reached and closed as soon as it is exhausted.
+//
+// Example:
+//
+//	orders := NewGlobSource("exports/orders-*.csv", CSVFile, SkipFileErrors())
+func NewGlobSource(pattern string, parse FileParser, options ...GlobOption) Stream[Record] {
+	config := globConfig{ctx: context.Background()}
+	for _, option := range options {
+		option(&config)
+	}
+
+	g := &globSource{pattern: pattern, parse: parse, config: config}
+	var records Stream[Record]
+	var finalErr error // Sticky once the files are exhausted or one fails
+	return func() (Record, error) {
+		if finalErr != nil {
+			return nil, finalErr
+		}
+		if err := config.ctx.Err(); err != nil {
+			g.closeAll()
+			finalErr = err
+			return nil, err
+		}
+		if records == nil {
+			paths, err := g.paths()
+			if err != nil {
+				finalErr = err
+				return nil, err
+			}
+			context.AfterFunc(config.ctx, g.closeAll)
+			if config.parallel > 1 {
+				records = g.readParallel(paths)
+			} else {
+				records = g.readSequential(paths)
+			}
+		}
+
+		record, err := records()
+		if err != nil {
+			g.closeAll()
+			finalErr = err
+			return nil, err
+		}
+		return record, nil
+	}
+}
+
+// globSource is the state of one NewGlobSource
+type globSource struct {
+	pattern string
+	parse   FileParser
+	config  globConfig
+
+	mu     sync.Mutex
+	open   map[*os.File]bool // Files being read, closed by closeAll
+	closed bool              // closeAll has run; files opened after it are closed at once
+}
+
+// paths lists the matching files in sorted order
+func (g *globSource) paths() ([]string, error) {
+	if !g.config.recursive {
+		matches, err := filepath.Glob(g.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("glob %q: %w", g.pattern, err)
+		}
+		var files []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	root, name := filepath.Split(g.pattern)
+	if root == "" {
+		root = "."
+	}
+	if _, err := filepath.Match(name, ""); err != nil {
+		return nil, fmt.Errorf("glob %q: %w", g.pattern, err)
+	}
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if matched, _ := filepath.Match(name, entry.Name()); matched && !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("glob %q: %w", g.pattern, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// openFile opens a file and tracks it so closeAll can release it
+func (g *globSource) openFile(path string) (*os.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		file.Close()
+		if err := g.config.ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", path, os.ErrClosed) // The stream has already ended
+	}
+	if g.open == nil {
+		g.open = make(map[*os.File]bool)
+	}
+	g.open[file] = true
+	return file, nil
+}
+
+// closeFile closes a file opened by openFile
+func (g *globSource) closeFile(file *os.File) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.open[file] {
+		delete(g.open, file)
+		file.Close()
+	}
+}
+
+// closeAll closes every open file and stops further files from being opened
+func (g *globSource) closeAll() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	for file := range g.open {
+		file.Close()
+	}
+	g.open = nil
+}
+
+// fileError passes a file's error to the handler, returning the error to end the stream with
+func (g *globSource) fileError(path string, err error) error {
+	if ctxErr := g.config.ctx.Err(); ctxErr != nil {
+		return ctxErr // Reads fail once closeAll has closed the files
+	}
+	if g.config.errorHandler == nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.config.errorHandler(path, err)
+}
+
+// readFile streams the records of one file to emit, closing it when done; it
+// returns the error that should end the stream, if any
+func (g *globSource) readFile(path string, emit func(Record) bool) error {
+	file, err := g.openFile(path)
+	if err != nil {
+		return g.fileError(path, err)
+	}
+	defer g.closeFile(file)
+
+	records := g.parse(file)
+	for {
+		record, err := records()
+		if err == EOS {
+			return nil
+		}
+		if err != nil {
+			return g.fileError(path, fmt.Errorf("%s: %w", path, err))
+		}
+		record[SourceFileField] = path
+		if !emit(record) {
+			return nil
+		}
+	}
+}
+
+// readSequential reads the files one after another on the consumer's goroutine
+func (g *globSource) readSequential(paths []string) Stream[Record] {
+	index := 0
+	var file *os.File
+	var records Stream[Record]
+	var path string
+
+	return func() (Record, error) {
+		for {
+			if records == nil {
+				if index >= len(paths) {
+					return nil, EOS
+				}
+				path = paths[index]
+				index++
+				opened, err := g.openFile(path)
+				if err != nil {
+					if err := g.fileError(path, err); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				file, records = opened, g.parse(opened)
+			}
+
+			record, err := records()
+			if err == nil {
+				record[SourceFileField] = path
+				return record, nil
+			}
+			g.closeFile(file)
+			file, records = nil, nil
+			if err != EOS {
+				if err := g.fileError(path, fmt.Errorf("%s: %w", path, err)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+}
+
+// readParallel reads up to config.parallel files at once, merging their records
+func (g *globSource) readParallel(paths []string) Stream[Record] {
+	ctx, cancel := context.WithCancel(g.config.ctx)
+	type pulled struct {
+		record Record
+		err    error
+	}
+	items := make(chan pulled)
+	queue := make(chan string, len(paths))
+	for _, path := range paths {
+		queue <- path
+	}
+	close(queue)
+
+	var workers sync.WaitGroup
+	for i := 0; i < min(g.config.parallel, len(paths)); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for path := range queue {
+				err := g.readFile(path, func(record Record) bool {
+					select {
+					case items <- pulled{record: record}:
+						return true
+					case <-ctx.Done():
+						return false
+					}
+				})
+				if err != nil {
+					select {
+					case items <- pulled{err: err}:
+					case <-ctx.Done():
+					}
+					return
+				}
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(items)
+	}()
+
+	return func() (Record, error) {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				cancel()
+				if err := g.config.ctx.Err(); err != nil {
+					return nil, err
+				}
+				return nil, EOS
+			}
+			if item.err != nil {
+				cancel() // Stop the other files
+			}
+			return item.record, item.err
+		case <-ctx.Done():
+			cancel()
+			return nil, g.config.ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeGlobFiles writes files into a temp dir and returns the dir
+func writeGlobFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	return dir
+}
+
+// describeGlobRecords lists records as file:id, in stream order
+func describeGlobRecords(records []Record) []string {
+	described := make([]string, len(records))
+	for i, record := range records {
+		described[i] = fmt.Sprintf("%s:%v", filepath.Base(GetOr(record, SourceFileField, "")), record["id"])
+	}
+	return described
+}
+
+// TestGlobSource tests reading many files as one stream
+func TestGlobSource(t *testing.T) {
+	parts := map[string]string{
+		"part-1.csv": "id,amount\n1,10\n2,20\n",
+		"part-2.csv": "",
+		"part-3.csv": "id,amount\n5,50\n6,60,extra\n7,70\n",
+		"part-0.csv": "id,amount\n0,0\n",
+		"notes.txt":  "not a part\n",
+	}
+
+	t.Run("SortedWithSourceField", func(t *testing.T) {
+		dir := writeGlobFiles(t, parts)
+		records, err := Collect(NewGlobSource(filepath.Join(dir, "part-*.csv"), CSVFile, SkipFileErrors()))
+		if err != nil {
+			t.Fatalf("Glob source failed: %v", err)
+		}
+		got := describeGlobRecords(records)
+		expected := []string{"part-0.csv:0", "part-1.csv:1", "part-1.csv:2", "part-3.csv:5"}
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", expected) {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	})
+
+	t.Run("ErrorNamesFile", func(t *testing.T) {
+		dir := writeGlobFiles(t, parts)
+		records, err := Collect(NewGlobSource(filepath.Join(dir, "part-*.csv"), CSVFile))
+		if err == nil || !strings.Contains(err.Error(), "part-3.csv") {
+			t.Errorf("Expected an error naming part-3.csv, got %v", err)
+		}
+		if len(records) != 4 {
+			t.Errorf("Expected the 4 records before the bad row, got %v", records)
+		}
+	})
+
+	t.Run("FileErrorHandler", func(t *testing.T) {
+		dir := writeGlobFiles(t, parts)
+		var failed []string
+		_, err := Collect(NewGlobSource(filepath.Join(dir, "part-*.csv"), CSVFile,
+			WithFileErrorHandler(func(path string, err error) error {
+				failed = append(failed, filepath.Base(path))
+				return nil
+			})))
+		if err != nil || fmt.Sprint(failed) != "[part-3.csv]" {
+			t.Errorf("Expected the handler to see part-3.csv, got %v (%v)", failed, err)
+		}
+	})
+
+	t.Run("Recursive", func(t *testing.T) {
+		dir := writeGlobFiles(t, map[string]string{
+			"a.jsonl":          `{"id": 1}` + "\n",
+			"2024/01/b.jsonl":  `{"id": 2}` + "\n",
+			"2024/02/c.jsonl":  `{"id": 3}` + "\n",
+			"2024/02/skip.csv": "id\n4\n",
+		})
+		records, err := Collect(NewGlobSource(filepath.Join(dir, "*.jsonl"), JSONLinesFile, WithRecursive()))
+		if err != nil {
+			t.Fatalf("Glob source failed: %v", err)
+		}
+		if got := describeGlobRecords(records); fmt.Sprint(got) != "[b.jsonl:2 c.jsonl:3 a.jsonl:1]" {
+			t.Errorf("Expected every .jsonl file in path order, got %v", got)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		files := make(map[string]string)
+		var expected []string
+		for i := 0; i < 8; i++ {
+			var content strings.Builder
+			content.WriteString("id\n")
+			for j := 0; j < 100; j++ {
+				id := i*100 + j
+				fmt.Fprintf(&content, "%d\n", id)
+				expected = append(expected, fmt.Sprintf("part-%d.tsv:%d", i, id))
+			}
+			files[fmt.Sprintf("part-%d.tsv", i)] = content.String()
+		}
+		dir := writeGlobFiles(t, files)
+
+		records, err := Collect(NewGlobSource(filepath.Join(dir, "*.tsv"), TSVFile, WithParallelFiles(3)))
+		if err != nil {
+			t.Fatalf("Parallel glob source failed: %v", err)
+		}
+		got := describeGlobRecords(records)
+		sort.Strings(got)
+		sort.Strings(expected)
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Expected all %d records, got %d", len(expected), len(got))
+		}
+	})
+
+	t.Run("StopEarlyWithContext", func(t *testing.T) {
+		dir := writeGlobFiles(t, map[string]string{
+			"a.csv": "id\n1\n2\n3\n",
+			"b.csv": "id\n4\n5\n6\n",
+			"c.csv": "id\n7\n8\n9\n",
+		})
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		records := NewGlobSource(filepath.Join(dir, "*.csv"), CSVFile, WithParallelFiles(2), WithGlobContext(ctx))
+		if _, err := records(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cancel()
+		if _, err := records(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Expected reading goroutines to exit, %d -> %d", before, after)
+		}
+	})
+
+	t.Run("NoMatches", func(t *testing.T) {
+		records, err := Collect(NewGlobSource(filepath.Join(t.TempDir(), "*.csv"), CSVFile))
+		if err != nil || len(records) != 0 {
+			t.Errorf("Expected an empty stream, got %v (%v)", records, err)
+		}
+		if _, err := NewGlobSource("[", CSVFile)(); err == nil {
+			t.Error("Expected an error for a bad pattern")
+		}
+	})
+}
@@ -98,6 +98,29 @@ func TestNoGoroutineLeaks(t *testing.T) {
 			t.Errorf("Potential goroutine leak in Split: %d -> %d", before, after)
 		}
 	})
+
+	// Test Merge function
+	t.Run("Merge", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+
+		// Merge two infinite streams and read a single element
+		ones := Generate(func() (int, error) { return 1, nil })
+		twos := Generate(func() (int, error) { return 2, nil })
+		merged := Merge(ones, twos)
+
+		_, err := merged()
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		// Abandoning the merged stream should release the feeders
+		time.Sleep(100 * time.Millisecond)
+
+		after := runtime.NumGoroutine()
+		if after > before+1 {
+			t.Errorf("Potential goroutine leak in Merge: %d -> %d", before, after)
+		}
+	})
 }
 
 // TestChannelOperationsSafety tests that channel operations don't block indefinitely
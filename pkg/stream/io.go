@@ -2,19 +2,32 @@ package stream
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 	
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
@@ -24,12 +37,41 @@ import (
 
 // CSVSource configuration for reading CSV data
 type CSVSource struct {
-	Reader    io.Reader
-	HasHeader bool
-	Separator rune
-	Headers   []string
+	Reader        io.Reader
+	HasHeader     bool
+	Separator     rune
+	Headers       []string
+	TypeInference TypeInference        // How field text becomes typed values
+	ColumnTypes   map[string]FieldKind // Columns parsed as one kind, whatever TypeInference says
+	Projection    []string             // When set, the only columns read into records
+
+	closer *onceCloser // The file opened by NewCSVSourceFromFile / NewTSVSourceFromFile
 }
 
+// TypeInference controls how CSVSource types the text of its fields
+type TypeInference int
+
+const (
+	// InferenceConservative types a column only when its sampled values agree: plain
+	// decimal numbers without leading zeros become int64 or float64, and true/false
+	// become bool. Anything else - "00123", "1e5", "NaN", "NO", dates - stays a string.
+	// The sample is the rows already buffered when the first row is read, so a source
+	// on a pipe or socket emits its first record once that row arrives.
+	InferenceConservative TypeInference = iota
+	// InferenceAggressive types each value on its own, as any bool word, number or time
+	// it parses as (see parseCSVValue)
+	InferenceAggressive
+	// InferenceOff keeps every value as the string read, untrimmed
+	InferenceOff
+)
+
+// csvInferenceSampleRows is the most rows InferenceConservative reads ahead to type columns
+const csvInferenceSampleRows = 1000
+
+// csvReadBufferSize is the buffer CSVSource reads through; InferenceConservative samples
+// only the rows it already holds, never waiting on the reader for more
+const csvReadBufferSize = 64 * 1024
+
 // NewCSVSource creates a CSV source from a reader
 func NewCSVSource(reader io.Reader) *CSVSource {
 	return &CSVSource{
@@ -39,14 +81,24 @@ func NewCSVSource(reader io.Reader) *CSVSource {
 	}
 }
 
-// NewCSVSourceFromFile creates a CSV source from a file
+// NewCSVSourceFromFile creates a CSV source from a file. The file is closed when the
+// source's stream ends, or by Close.
 func NewCSVSourceFromFile(filename string) (*CSVSource, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open CSV file %s: %w", filename, err)
 	}
 	
-	return NewCSVSource(file), nil
+	source := NewCSVSource(file)
+	source.closer = &onceCloser{closer: file}
+	return source, nil
+}
+
+// Close closes the file opened by NewCSVSourceFromFile or NewTSVSourceFromFile, for a
+// consumer that stops before the stream ends. It does nothing for a source made from a
+// reader, and closing twice is harmless.
+func (cs *CSVSource) Close() error {
+	return cs.closer.Close()
 }
 
 // WithHeaders sets custom headers for the CSV
@@ -62,71 +114,177 @@ func (cs *CSVSource) WithoutHeaders() *CSVSource {
 	return cs
 }
 
+// WithTypeInference sets how values are typed; InferenceConservative by default
+func (cs *CSVSource) WithTypeInference(inference TypeInference) *CSVSource {
+	cs.TypeInference = inference
+	return cs
+}
+
+// WithColumnType parses one column as kind whatever the type inference, keeping a value
+// that doesn't parse as a string. Use StringKind to keep a column's text as it is.
+//
+// Example:
+//
+//	source := NewCSVSource(file).WithColumnType("zip", StringKind).WithColumnType("signed_up", TimeKind)
+func (cs *CSVSource) WithColumnType(column string, kind FieldKind) *CSVSource {
+	if cs.ColumnTypes == nil {
+		cs.ColumnTypes = make(map[string]FieldKind)
+	}
+	cs.ColumnTypes[column] = kind
+	return cs
+}
+
+// WithProjection reads only the named columns into records; the others are neither
+// typed nor stored, so they are absent from the records rather than empty. Columns
+// the input doesn't have are absent too. With no fields, every column is read.
+//
+// Example:
+//
+//	source := NewCSVSource(file).WithProjection("id", "amount")
+func (cs *CSVSource) WithProjection(fields ...string) *CSVSource {
+	cs.Projection = fields
+	return cs
+}
+
 // ToStream converts CSV data to a Record stream
 func (cs *CSVSource) ToStream() Stream[Record] {
-	reader := csv.NewReader(cs.Reader)
+	return closeOnEnd(FromResults[Record]()(cs.ToResults()), cs.closer)
+}
+
+// ToResults converts CSV data to a Result stream. A malformed row, such as one with the
+// wrong number of fields, becomes a failed Result holding the row's text instead of ending
+// the stream. Index counts data rows from 0.
+func (cs *CSVSource) ToResults() Stream[Result[Record]] {
+	return closeOnEnd(cs.readResults(), cs.closer)
+}
+
+// readResults parses the rows of a CSVSource
+func (cs *CSVSource) readResults() Stream[Result[Record]] {
+	buffered := bufio.NewReaderSize(cs.Reader, csvReadBufferSize) // csv.Reader reads through it as is
+	reader := csv.NewReader(buffered)
 	reader.Comma = cs.Separator
-	
+
 	var headers []string
 	var headerRead bool = false
-	
-	return func() (Record, error) {
+	var index int64
+	var parsers []func(string) any // By column position, built as columns are first seen
+	projected := projectionSet(cs.Projection)
+	var wanted []bool // By header position, under a projection
+
+	// InferenceConservative reads buffered rows ahead to type columns from a sample
+	sampled := cs.TypeInference != InferenceConservative
+	var pending []csvRow
+	var sample [][]string
+	var pendingErr error // What reading returned after the pending rows
+
+	readRow := func() ([]string, error) {
+		if len(pending) > 0 {
+			row := pending[0]
+			pending = pending[1:]
+			return row.fields, row.err
+		}
+		if pendingErr != nil {
+			return nil, pendingErr
+		}
+		return reader.Read()
+	}
+
+	parser := func(i int, column string) func(string) any {
+		for len(parsers) <= i {
+			parsers = append(parsers, nil)
+		}
+		if parsers[i] == nil {
+			parsers[i] = cs.columnParser(column, sampleColumn(sample, i))
+		}
+		return parsers[i]
+	}
+
+	return func() (Result[Record], error) {
 		// Read headers on first call if needed
 		if !headerRead {
 			if cs.HasHeader {
 				headerRow, err := reader.Read()
+				if err == io.EOF {
+					return Result[Record]{}, EOS // Empty input: no header, no rows
+				}
 				if err != nil {
-					return nil, err
+					return Result[Record]{}, err
 				}
 				headers = headerRow
 			} else if len(cs.Headers) > 0 {
 				headers = cs.Headers
-			} else {
-				// Generate default headers
-				firstRow, err := reader.Read()
-				if err != nil {
-					return nil, err
-				}
-				
-				headers = make([]string, len(firstRow))
-				for i := range headers {
-					headers[i] = fmt.Sprintf("col%d", i)
+			}
+			headerRead = true
+		}
+
+		if !sampled {
+			sampled = true
+			// The first row is needed anyway; later ones only if already buffered
+			for len(pending) < csvInferenceSampleRows && (len(pending) == 0 || buffered.Buffered() > 0) {
+				row, err := reader.Read()
+				var parseErr *csv.ParseError
+				if err != nil && !errors.As(err, &parseErr) {
+					pendingErr = err
+					break
 				}
-				
-				// Process first row as data
-				record := make(Record)
-				for i, value := range firstRow {
-					if i < len(headers) {
-						record[headers[i]] = parseCSVValue(value)
-					}
+				pending = append(pending, csvRow{fields: row, err: err})
+				if err == nil {
+					sample = append(sample, row)
 				}
-				headerRead = true
-				return record, nil
 			}
-			headerRead = true
 		}
-		
+
 		// Read data row
-		row, err := reader.Read()
+		row, err := readRow()
+		if err == io.EOF {
+			return Result[Record]{}, EOS
+		}
+		result := Result[Record]{Index: index}
+		index++
 		if err != nil {
-			if err == io.EOF {
-				return nil, EOS
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return Result[Record]{}, err // I/O failure - the rest of the input is unreadable
 			}
-			return nil, err
+			result.Err = err
+			result.Raw = strings.Join(row, string(cs.Separator))
+			return result, nil
 		}
-		
+
+		if headers == nil {
+			// Generate default headers from the first row
+			headers = make([]string, len(row))
+			for i := range headers {
+				headers[i] = fmt.Sprintf("col%d", i)
+			}
+		}
+		if projected != nil && wanted == nil {
+			wanted = wantedColumns(headers, projected)
+		}
+
 		// Convert to Record
-		record := make(Record)
+		record := make(Record, len(row))
+		if projected != nil {
+			record = make(Record, len(projected))
+		}
 		for i, value := range row {
+			var column string
 			if i < len(headers) {
-				record[headers[i]] = parseCSVValue(value)
+				if wanted != nil && !wanted[i] {
+					continue
+				}
+				column = headers[i]
 			} else {
 				// Handle extra columns
-				record[fmt.Sprintf("extra_col%d", i)] = parseCSVValue(value)
+				column = fmt.Sprintf("extra_col%d", i)
+				if projected != nil && !projected[column] {
+					continue
+				}
 			}
+			record[column] = parser(i, column)(value)
 		}
-		
-		return record, nil
+		result.Value = record
+		return result, nil
 	}
 }
 
@@ -135,12 +293,19 @@ func (cs *CSVSource) ToStream() Stream[Record] {
 // ============================================================================
 
 // FastTSVSource configuration for reading delimited data with simple string splitting
-// This is much faster than CSV parsing but doesn't support escaping or quoted fields
+// This is much faster than CSV parsing but doesn't support escaping or quoted fields.
+// Unlike CSVSource, which by default types a column only when a sample of it agrees,
+// the fast sources type each value on its own as it is read (see parseSimpleValue):
+// "00123" becomes 123 and "F" false, though bool words such as "NO" stay strings. Use
+// ColumnTypes or RawStrings where that matters.
 type FastTSVSource struct {
-	Reader    io.Reader
-	HasHeader bool
-	Separator string  // Can be any string, typically "\t" for TSV
-	Headers   []string
+	Reader      io.Reader
+	HasHeader   bool
+	Separator   string  // Can be any string, typically "\t" for TSV
+	Headers     []string
+	ColumnTypes map[string]FieldKind // Columns parsed as one kind instead of inferred
+	RawStrings  bool                 // Leave columns not in ColumnTypes as strings
+	Projection  []string             // When set, the only columns read into records
 }
 
 // NewFastTSVSource creates a fast TSV source using simple string splitting
@@ -174,12 +339,37 @@ func (s *FastTSVSource) WithoutHeader() *FastTSVSource {
 	return s
 }
 
+// WithColumnTypes parses the named columns as the given kinds rather than inferring
+// each value's type. A value that doesn't parse as its kind is kept as a string.
+func (s *FastTSVSource) WithColumnTypes(types map[string]FieldKind) *FastTSVSource {
+	s.ColumnTypes = types
+	return s
+}
+
+// WithRawStrings keeps every value not covered by WithColumnTypes as a string
+func (s *FastTSVSource) WithRawStrings() *FastTSVSource {
+	s.RawStrings = true
+	return s
+}
+
+// WithProjection reads only the named columns into records. Lines are split no further
+// than the last named column, and the other columns are neither typed nor stored.
+// With no fields, every column is read.
+func (s *FastTSVSource) WithProjection(fields ...string) *FastTSVSource {
+	s.Projection = fields
+	return s
+}
+
 // ToStream converts the fast TSV source to a Record stream
 func (s *FastTSVSource) ToStream() Stream[Record] {
 	scanner := bufio.NewScanner(s.Reader)
 	
 	var headers []string
+	var parsers []func(string) any
 	lineNumber := 0
+	projected := projectionSet(s.Projection)
+	var wanted []bool // By header position, under a projection
+	split := -1       // How many pieces to split lines into; -1 splits them all
 	
 	return func() (Record, error) {
 		for scanner.Scan() {
@@ -191,7 +381,7 @@ func (s *FastTSVSource) ToStream() Stream[Record] {
 				continue
 			}
 			
-			fields := strings.Split(line, s.Separator)
+			fields := strings.SplitN(line, s.Separator, split)
 			
 			// Handle header row
 			if lineNumber == 1 && s.HasHeader {
@@ -214,14 +404,33 @@ func (s *FastTSVSource) ToStream() Stream[Record] {
 					headers[i] = fmt.Sprintf("field_%d", i)
 				}
 			}
+			if len(parsers) != len(headers) {
+				parsers = fastColumnParsers(headers, s.ColumnTypes, s.RawStrings)
+			}
+			if projected != nil && wanted == nil {
+				wanted = wantedColumns(headers, projected)
+				// Later lines stop splitting after the last wanted column
+				for i := len(wanted) - 1; i >= 0; i-- {
+					if wanted[i] {
+						split = i + 2
+						break
+					}
+				}
+				if split == -1 {
+					split = 1
+				}
+			}
 			
 			// Create record
 			record := make(Record)
 			for i, field := range fields {
+				if wanted != nil && (i >= len(wanted) || !wanted[i]) {
+					continue
+				}
 				if i < len(headers) {
 					// Simple trim and type conversion
 					value := strings.TrimSpace(field)
-					record[headers[i]] = parseSimpleValue(value)
+					record[headers[i]] = parsers[i](value)
 				}
 			}
 			
@@ -275,17 +484,214 @@ func NewTSVSource(reader io.Reader) *CSVSource {
 	}
 }
 
-// NewTSVSourceFromFile creates a TSV source from a file
+// NewTSVSourceFromFile creates a TSV source from a file. The file is closed when the
+// source's stream ends, or by Close.
 func NewTSVSourceFromFile(filename string) (*CSVSource, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open TSV file %s: %w", filename, err)
 	}
 	
-	return NewTSVSource(file), nil
+	source := NewTSVSource(file)
+	source.closer = &onceCloser{closer: file}
+	return source, nil
+}
+
+// ============================================================================
+// PROJECTION PUSHDOWN - READING ONLY THE FIELDS A PIPELINE USES
+// ============================================================================
+
+// ProjectableSource is a record source that can read only some of its fields, such
+// as CSVSource (CSV and TSV), FastCSVSource, FastTSVSource and JSONSource. Other
+// sources implement it to take part in ProjectInto: SetProjection limits the fields
+// later ToStream calls read, and a nil or empty list means every field.
+type ProjectableSource interface {
+	ToStream() Stream[Record]
+	SetProjection(fields ...string)
+}
+
+// SetProjection is WithProjection for ProjectableSource
+func (cs *CSVSource) SetProjection(fields ...string) { cs.WithProjection(fields...) }
+
+// SetProjection is WithProjection for ProjectableSource
+func (s *FastTSVSource) SetProjection(fields ...string) { s.WithProjection(fields...) }
+
+// SetProjection is WithProjection for ProjectableSource
+func (js *JSONSource) SetProjection(fields ...string) { js.WithProjection(fields...) }
+
+// selectProjections maps the closures of Select filters to the fields they keep, so
+// ProjectInto can recognize a Select; entries are keyed weakly, as plans are
+var selectProjections sync.Map // weak.Pointer[byte] → []string
+
+// projectingSelect records that f keeps only fields
+func projectingSelect(f Filter[Record, Record], fields []string) Filter[Record, Record] {
+	rememberClosure(&selectProjections, filterClosure(f), fields)
+	return f
+}
+
+// ProjectInto reads source through filter. When filter is a Select, the source is
+// first given its fields as a projection, so the columns the Select drops are never
+// parsed; the output is the same either way. Any other filter is applied as it is.
+// The projection is set on source itself with SetProjection, replacing any it had,
+// and stays for later reads of the same source.
+//
+// Example:
+//
+//	rows := ProjectInto(NewCSVSource(file), Select("id", "amount")) // other columns skipped
+func ProjectInto(source ProjectableSource, filter Filter[Record, Record]) Stream[Record] {
+	if fields, ok := recallClosure(&selectProjections, filterClosure(filter)); ok {
+		source.SetProjection(fields.([]string)...)
+	}
+	return filter(source.ToStream())
+}
+
+// projectionSet returns the projected fields as a set, or nil without a projection
+func projectionSet(fields []string) map[string]bool {
+	if fields == nil {
+		return nil
+	}
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		set[field] = true
+	}
+	return set
+}
+
+// wantedColumns marks the header positions a projection keeps
+func wantedColumns(headers []string, projected map[string]bool) []bool {
+	wanted := make([]bool, len(headers))
+	for i, header := range headers {
+		wanted[i] = projected[header]
+	}
+	return wanted
+}
+
+// csvRow is a row read ahead by CSVSource, with the parse error it came with
+type csvRow struct {
+	fields []string
+	err    error
+}
+
+// sampleColumn returns the values at position i of the sampled rows
+func sampleColumn(sample [][]string, i int) []string {
+	var values []string
+	for _, row := range sample {
+		if i < len(row) {
+			values = append(values, row[i])
+		}
+	}
+	return values
+}
+
+// columnParser returns the function typing one column's values: its ColumnTypes kind
+// if it has one, otherwise as the TypeInference says, judging sample if conservative
+func (cs *CSVSource) columnParser(column string, sample []string) func(string) any {
+	if kind, ok := cs.ColumnTypes[column]; ok {
+		return func(value string) any { return parseKindValue(strings.TrimSpace(value), kind) }
+	}
+	switch cs.TypeInference {
+	case InferenceAggressive:
+		return parseCSVValue
+	case InferenceOff:
+		return func(value string) any { return value }
+	}
+	return conservativeParser(sample)
+}
+
+// conservativeParser returns the InferenceConservative parser for a column with the
+// sampled values: int64 if every non-empty one is a plain integer, float64 if every one is
+// a plain number, bool if every one is true or false, and the trimmed string otherwise.
+// Later values that don't fit the column's type stay strings.
+func conservativeParser(sample []string) func(string) any {
+	integers, numbers, bools, seen := true, true, true, false
+	for _, value := range sample {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		seen = true
+		plain, integer := isPlainNumber(value)
+		integers = integers && integer
+		numbers = numbers && plain
+		bools = bools && (strings.EqualFold(value, "true") || strings.EqualFold(value, "false"))
+	}
+
+	switch {
+	case !seen:
+		return func(value string) any { return strings.TrimSpace(value) }
+	case integers:
+		return func(value string) any {
+			value = strings.TrimSpace(value)
+			if _, integer := isPlainNumber(value); integer {
+				if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+					return v
+				}
+			}
+			return value
+		}
+	case numbers:
+		return func(value string) any {
+			value = strings.TrimSpace(value)
+			if plain, _ := isPlainNumber(value); plain {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					return v
+				}
+			}
+			return value
+		}
+	case bools:
+		return func(value string) any {
+			value = strings.TrimSpace(value)
+			if v, err := strconv.ParseBool(value); err == nil && len(value) > 1 {
+				return v // true or false, not 1, 0, t or f
+			}
+			return value
+		}
+	default:
+		return func(value string) any { return strings.TrimSpace(value) }
+	}
+}
+
+// isPlainNumber reports whether value is a decimal number with an optional sign and
+// fraction and no leading zeros, such as -12 or 0.5 but not 007, 1e5, .5 or NaN, and
+// whether it is an integer
+func isPlainNumber(value string) (plain, integer bool) {
+	if value != "" && (value[0] == '-' || value[0] == '+') {
+		value = value[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if whole == "" || (len(whole) > 1 && whole[0] == '0') || !allDigits(whole) {
+		return false, false
+	}
+	if hasFraction {
+		return fraction != "" && allDigits(fraction), false
+	}
+	return true, true
 }
 
-// parseCSVValue attempts to parse CSV string values into appropriate types
+// allDigits reports whether s holds only ASCII digits
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// csvTimeFormats are the layouts parseCSVValue tries for time values
+var csvTimeFormats = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// parseCSVValue attempts to parse CSV string values into appropriate types. This is
+// InferenceAggressive: bool words such as "no", numbers with leading zeros or exponents
+// and anything that parses as a time are all converted.
 func parseCSVValue(value string) any {
 	value = strings.TrimSpace(value)
 	
@@ -314,16 +720,7 @@ func parseCSVValue(value string) any {
 	}
 	
 	// Time values (common formats)
-	timeFormats := []string{
-		time.RFC3339,
-		time.RFC3339Nano,
-		"2006-01-02 15:04:05",
-		"2006-01-02T15:04:05",
-		"2006-01-02",
-		"15:04:05",
-	}
-	
-	for _, format := range timeFormats {
+	for _, format := range csvTimeFormats {
 		if timeValue, err := time.Parse(format, value); err == nil {
 			return timeValue
 		}
@@ -343,6 +740,10 @@ type CSVSink struct {
 	Separator rune
 	Headers   []string
 	headerWritten bool
+
+	schema      csvSchemaPolicy
+	columns     []string
+	headerOrder []string // Columns to put first, set by WithHeaderOrder
 }
 
 // NewCSVSink creates a CSV sink to a writer
@@ -370,15 +771,62 @@ func (sink *CSVSink) WithHeaders(headers []string) *CSVSink {
 	return sink
 }
 
+// WithHeaderOrder puts the columns in order first, in that order, and then the first
+// record's other fields as the columns would be without it. Listed columns the first
+// record lacks are kept. Unlike WithHeaders, which fixes the exact column set, the
+// first record's fields are never dropped; later records' extra fields still are.
+func (sink *CSVSink) WithHeaderOrder(order []string) *CSVSink {
+	sink.headerOrder = order
+	return sink
+}
+
+// WithStrictSchema makes WriteStream fail when a record has a field that is not
+// a column or lacks one of the columns, instead of dropping or blanking it
+func (sink *CSVSink) WithStrictSchema() *CSVSink {
+	sink.schema.strict = true
+	return sink
+}
+
+// WithDroppedFieldHandler calls handler for each field dropped because it is not a column
+func (sink *CSVSink) WithDroppedFieldHandler(handler func(field string, record Record)) *CSVSink {
+	sink.schema.onDropped = handler
+	return sink
+}
+
+// WithMissingFieldHandler calls handler for each column a record has no value for
+func (sink *CSVSink) WithMissingFieldHandler(handler func(field string, record Record)) *CSVSink {
+	sink.schema.onMissing = handler
+	return sink
+}
+
+// DroppedFields returns how many record fields have been dropped because they were not columns
+func (sink *CSVSink) DroppedFields() int {
+	return sink.schema.dropped
+}
+
+// MissingFields returns how many columns have been written empty because a record lacked them
+func (sink *CSVSink) MissingFields() int {
+	return sink.schema.missing
+}
+
 // WriteStream writes a Record stream to CSV format
 func (sink *CSVSink) WriteStream(stream Stream[Record]) error {
+	return sink.WriteStreamContext(context.Background(), stream)
+}
+
+// WriteStreamContext writes the stream like WriteStream, stopping with ctx.Err() once
+// ctx is done. ctx is checked before every record; the rows written so far are flushed.
+func (sink *CSVSink) WriteStreamContext(ctx context.Context, stream Stream[Record]) error {
 	writer := csv.NewWriter(sink.Writer)
 	writer.Comma = sink.Separator
 	defer writer.Flush()
 	
-	var headers []string
+	headers := sink.columns
 	
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		record, err := stream()
 		if err != nil {
 			if err == EOS {
@@ -392,17 +840,19 @@ func (sink *CSVSink) WriteStream(stream Stream[Record]) error {
 			if len(sink.Headers) > 0 {
 				headers = sink.Headers
 			} else {
-				// Extract headers from first record
-				headers = make([]string, 0, len(record))
-				for key := range record {
-					headers = append(headers, key)
-				}
+				headers = orderedCSVHeaders(sink.headerOrder, csvHeadersFor(record))
 			}
 			
 			if err := writer.Write(headers); err != nil {
 				return fmt.Errorf("failed to write CSV headers: %w", err)
 			}
 			sink.headerWritten = true
+			sink.columns = headers
+		}
+		
+		// Check for fields that have no column and columns with no field
+		if err := sink.schema.check(record, headers); err != nil {
+			return err
 		}
 		
 		// Write record data
@@ -447,6 +897,91 @@ func NewTSVSinkToFile(filename string) (*CSVSink, error) {
 	return NewTSVSink(file), nil
 }
 
+// csvHeadersFor derives CSV headers from a record's fields: its Keys, so sorted and
+// deterministic, or in field order for an ordered record
+func csvHeadersFor(record Record) []string {
+	return record.Keys()
+}
+
+// orderedCSVHeaders returns the columns in order followed by the fields not among them
+func orderedCSVHeaders(order, fields []string) []string {
+	if len(order) == 0 {
+		return fields
+	}
+	headers := append([]string(nil), order...)
+	listed := make(map[string]bool, len(order))
+	for _, column := range order {
+		listed[column] = true
+	}
+	for _, field := range fields {
+		if !listed[field] {
+			headers = append(headers, field)
+		}
+	}
+	return headers
+}
+
+// csvSchemaPolicy checks records against the CSV columns, counting and
+// reporting fields that are dropped or missing, or rejecting them when strict
+type csvSchemaPolicy struct {
+	strict    bool
+	onDropped func(field string, record Record)
+	onMissing func(field string, record Record)
+	dropped   int
+	missing   int
+}
+
+// check applies the policy to one record before it is written
+func (p *csvSchemaPolicy) check(record Record, headers []string) error {
+	var extra []string
+	for key := range record {
+		if !containsString(headers, key) {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+
+	var absent []string
+	for _, header := range headers {
+		if _, exists := record[header]; !exists {
+			absent = append(absent, header)
+		}
+	}
+
+	if p.strict {
+		if len(extra) > 0 {
+			return fmt.Errorf("record field %q is not in CSV header %v", extra[0], headers)
+		}
+		if len(absent) > 0 {
+			return fmt.Errorf("record is missing CSV column %q", absent[0])
+		}
+	}
+
+	p.dropped += len(extra)
+	if p.onDropped != nil {
+		for _, field := range extra {
+			p.onDropped(field, record)
+		}
+	}
+	p.missing += len(absent)
+	if p.onMissing != nil {
+		for _, field := range absent {
+			p.onMissing(field, record)
+		}
+	}
+	return nil
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 // formatCSVValue converts any value to a string for CSV output
 func formatCSVValue(value any) string {
 	switch v := value.(type) {
@@ -481,6 +1016,8 @@ type StreamingCSVWriter struct {
 	writer   *csv.Writer
 	headers  []string
 	headerWritten bool
+	schema        csvSchemaPolicy
+	sync          periodicSync
 }
 
 // NewStreamingCSVWriter creates a streaming CSV writer
@@ -490,19 +1027,66 @@ func NewStreamingCSVWriter(w io.Writer, headers []string) *StreamingCSVWriter {
 		writer:  writer,
 		headers: headers,
 		headerWritten: false,
+		sync:          periodicSync{target: w},
 	}
 }
 
-// WriteRecord writes a single record to the CSV stream
+// WithFsyncEvery syncs the underlying file to disk after every n records, and on Close,
+// so at most n records are lost if the machine goes down. It only applies when the
+// writer has a Sync method, as *os.File does.
+func (scw *StreamingCSVWriter) WithFsyncEvery(n int) *StreamingCSVWriter {
+	scw.sync.setEvery(n)
+	return scw
+}
+
+// WithStrictSchema makes WriteRecord fail when a record has a field that is not
+// a column or lacks one of the columns, instead of dropping or blanking it
+func (scw *StreamingCSVWriter) WithStrictSchema() *StreamingCSVWriter {
+	scw.schema.strict = true
+	return scw
+}
+
+// WithDroppedFieldHandler calls handler for each field dropped because it is not a column
+func (scw *StreamingCSVWriter) WithDroppedFieldHandler(handler func(field string, record Record)) *StreamingCSVWriter {
+	scw.schema.onDropped = handler
+	return scw
+}
+
+// WithMissingFieldHandler calls handler for each column a record has no value for
+func (scw *StreamingCSVWriter) WithMissingFieldHandler(handler func(field string, record Record)) *StreamingCSVWriter {
+	scw.schema.onMissing = handler
+	return scw
+}
+
+// DroppedFields returns how many record fields have been dropped because they were not columns
+func (scw *StreamingCSVWriter) DroppedFields() int {
+	return scw.schema.dropped
+}
+
+// MissingFields returns how many columns have been written empty because a record lacked them
+func (scw *StreamingCSVWriter) MissingFields() int {
+	return scw.schema.missing
+}
+
+// WriteRecord writes a single record to the CSV stream.
+// Without explicit headers, the first record's fields are used in sorted order, or in
+// field order if it is an ordered record (see SetFieldOrder).
 func (scw *StreamingCSVWriter) WriteRecord(record Record) error {
 	// Write headers on first record
 	if !scw.headerWritten {
+		if len(scw.headers) == 0 {
+			scw.headers = csvHeadersFor(record)
+		}
 		if err := scw.writer.Write(scw.headers); err != nil {
 			return fmt.Errorf("failed to write CSV headers: %w", err)
 		}
 		scw.headerWritten = true
 	}
 	
+	if err := scw.schema.check(record, scw.headers); err != nil {
+		return err
+	}
+	
 	// Write record data
 	row := make([]string, len(scw.headers))
 	for i, header := range scw.headers {
@@ -517,13 +1101,66 @@ func (scw *StreamingCSVWriter) WriteRecord(record Record) error {
 	
 	// Flush immediately for streaming
 	scw.writer.Flush()
-	return scw.writer.Error()
+	if err := scw.writer.Error(); err != nil {
+		return err
+	}
+	return scw.sync.written()
 }
 
-// Close flushes and closes the writer
+// Close flushes the writer, and syncs it under WithFsyncEvery. It doesn't close the
+// underlying io.Writer.
 func (scw *StreamingCSVWriter) Close() error {
 	scw.writer.Flush()
-	return scw.writer.Error()
+	if err := scw.writer.Error(); err != nil {
+		return err
+	}
+	return scw.sync.flush()
+}
+
+// periodicSync syncs a writer to disk every so many records, for WithFsyncEvery
+type periodicSync struct {
+	target  io.Writer
+	every   int // 0 means never
+	pending int // Records written since the last sync
+}
+
+// setEvery sets how many records to write between syncs
+func (ps *periodicSync) setEvery(n int) {
+	if n <= 0 {
+		panic("fsync interval must be positive")
+	}
+	ps.every = n
+}
+
+// written counts a record, syncing once every records are pending
+func (ps *periodicSync) written() error {
+	if ps.every == 0 {
+		return nil
+	}
+	ps.pending++
+	if ps.pending < ps.every {
+		return nil
+	}
+	return ps.flush()
+}
+
+// flush syncs any pending records
+func (ps *periodicSync) flush() error {
+	if ps.every == 0 || ps.pending == 0 {
+		return nil
+	}
+	ps.pending = 0
+	return syncWriter(ps.target)
+}
+
+// syncWriter syncs w to disk if it has a Sync method
+func syncWriter(w io.Writer) error {
+	if syncer, ok := w.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			return fmt.Errorf("failed to sync: %w", err)
+		}
+	}
+	return nil
 }
 
 // ============================================================================
@@ -534,6 +1171,21 @@ func (scw *StreamingCSVWriter) Close() error {
 type JSONSource struct {
 	Reader io.Reader
 	Format JSONFormat
+
+	// LineErrorHandler decides what happens to a JSON Lines line that fails to
+	// parse: returning nil skips the line, returning an error ends the stream
+	// with it. When nil, the first bad line ends the stream.
+	LineErrorHandler func(lineNumber int, line string, err error) error
+
+	// ArrayPath is the dot-separated path, such as "data.items", to the array of
+	// records inside a top-level JSON object, for JSONArray documents that wrap it
+	ArrayPath string
+
+	// Projection, when set, names the only top-level fields read into records
+	Projection []string
+
+	skippedLines int
+	closer       *onceCloser // The file opened by NewJSONSourceFromFile
 }
 
 // JSONFormat specifies how JSON data is structured
@@ -558,68 +1210,297 @@ func (js *JSONSource) WithFormat(format JSONFormat) *JSONSource {
 	return js
 }
 
-// ToStream converts JSON data to a Record stream
-func (js *JSONSource) ToStream() Stream[Record] {
-	switch js.Format {
-	case JSONArray:
-		return js.arrayToStream()
-	default: // JSONLines
-		return js.linesToStream()
-	}
+// WithArrayPath reads the records from the array at a dot-separated path inside a
+// top-level object, as many APIs wrap their results, and sets the format to JSONArray
+//
+// Example:
+//
+//	// {"meta": {...}, "data": {"items": [{...}, {...}]}}
+//	items := NewJSONSource(response.Body).WithArrayPath("data.items").ToStream()
+func (js *JSONSource) WithArrayPath(path string) *JSONSource {
+	js.ArrayPath = path
+	js.Format = JSONArray
+	return js
 }
 
-// linesToStream handles JSON Lines format (one JSON object per line)
-func (js *JSONSource) linesToStream() Stream[Record] {
-	scanner := bufio.NewScanner(js.Reader)
-	
-	return func() (Record, error) {
-		if !scanner.Scan() {
-			if err := scanner.Err(); err != nil {
-				return nil, err
+// WithLineErrorHandler sets the handler for JSON Lines lines that fail to parse
+func (js *JSONSource) WithLineErrorHandler(handler func(lineNumber int, line string, err error) error) *JSONSource {
+	js.LineErrorHandler = handler
+	return js
+}
+
+// SkipInvalidLines skips JSON Lines lines that fail to parse instead of ending the stream
+func (js *JSONSource) SkipInvalidLines() *JSONSource {
+	return js.WithLineErrorHandler(func(int, string, error) error { return nil })
+}
+
+// WithProjection reads only the named top-level fields into records. The other fields
+// are checked to be valid JSON but not decoded into values, and are absent from the
+// records. With no fields, every field is read.
+func (js *JSONSource) WithProjection(fields ...string) *JSONSource {
+	js.Projection = fields
+	return js
+}
+
+// decodeRecord decodes one JSON object with decode into a Record, keeping only the
+// projected fields when there is a projection
+func (js *JSONSource) decodeRecord(decode func(any) error) (Record, error) {
+	if js.Projection == nil {
+		var jsonObj map[string]any
+		if err := decode(&jsonObj); err != nil {
+			return nil, err
+		}
+		return convertJSONToRecord(jsonObj), nil
+	}
+
+	var rawObj map[string]json.RawMessage
+	if err := decode(&rawObj); err != nil {
+		return nil, err
+	}
+	record := make(Record, len(js.Projection))
+	for _, field := range js.Projection {
+		raw, ok := rawObj[field]
+		if !ok {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		record[field] = convertJSONValue(value)
+	}
+	return record, nil
+}
+
+// SkippedLines returns how many invalid lines the line error handler has skipped so far
+func (js *JSONSource) SkippedLines() int {
+	return js.skippedLines
+}
+
+// ToStream converts JSON data to a Record stream
+func (js *JSONSource) ToStream() Stream[Record] {
+	switch js.Format {
+	case JSONArray:
+		return closeOnEnd(js.arrayToStream(), js.closer)
+	default: // JSONLines
+		return closeOnEnd(js.linesToStream(), js.closer)
+	}
+}
+
+// ToResults converts JSON data to a Result stream. A JSON Lines line that fails to parse
+// becomes a failed Result holding the line, bypassing LineErrorHandler; Index counts
+// non-empty lines from 0. A JSONArray document that fails to parse still ends the stream.
+func (js *JSONSource) ToResults() Stream[Result[Record]] {
+	return closeOnEnd(js.readResults(), js.closer)
+}
+
+// readResults parses the records of a JSONSource into Results
+func (js *JSONSource) readResults() Stream[Result[Record]] {
+	if js.Format == JSONArray {
+		records := js.arrayToStream()
+		var index int64
+		return func() (Result[Record], error) {
+			record, err := records()
+			if err != nil {
+				return Result[Record]{}, err
 			}
-			return nil, EOS
+			index++
+			return Result[Record]{Value: record, Index: index - 1}, nil
 		}
-		
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-		if line == "" {
-			// Skip empty lines, try next
-			return js.linesToStream()()
+	}
+
+	scanner := bufio.NewScanner(js.Reader)
+	lineNumber := 0
+	var index int64
+
+	return func() (Result[Record], error) {
+		for scanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue // Skip empty lines
+			}
+
+			result := Result[Record]{Index: index}
+			index++
+			record, err := js.decodeRecord(func(v any) error { return json.Unmarshal([]byte(line), v) })
+			if err != nil {
+				result.Err = fmt.Errorf("failed to parse JSON line %d: %w", lineNumber, err)
+				result.Raw = line
+				return result, nil
+			}
+			result.Value = record
+			return result, nil
 		}
-		
-		var jsonObj map[string]any
-		if err := json.Unmarshal([]byte(line), &jsonObj); err != nil {
-			return nil, fmt.Errorf("failed to parse JSON line: %w", err)
+
+		if err := scanner.Err(); err != nil {
+			return Result[Record]{}, err
+		}
+		return Result[Record]{}, EOS
+	}
+}
+
+// linesToStream handles JSON Lines format (one JSON object per line)
+func (js *JSONSource) linesToStream() Stream[Record] {
+	scanner := bufio.NewScanner(js.Reader)
+	lineNumber := 0
+	
+	return func() (Record, error) {
+		for scanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue // Skip empty lines
+			}
+			
+			record, err := js.decodeRecord(func(v any) error { return json.Unmarshal([]byte(line), v) })
+			if err != nil {
+				err = fmt.Errorf("failed to parse JSON line %d: %w", lineNumber, err)
+				if js.LineErrorHandler == nil {
+					return nil, err
+				}
+				if handlerErr := js.LineErrorHandler(lineNumber, line, err); handlerErr != nil {
+					return nil, handlerErr
+				}
+				js.skippedLines++
+				continue
+			}
+			
+			return record, nil
 		}
 		
-		return convertJSONToRecord(jsonObj), nil
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, EOS
 	}
 }
 
-// arrayToStream handles JSON Array format (single array of objects)
+// arrayToStream handles JSON Array format, decoding one element at a time so only
+// the current record is held in memory
 func (js *JSONSource) arrayToStream() Stream[Record] {
-	// Read entire input
-	data, err := io.ReadAll(js.Reader)
-	if err != nil {
-		return func() (Record, error) {
-			return nil, fmt.Errorf("failed to read JSON array: %w", err)
+	decoder := json.NewDecoder(js.Reader)
+	started := false
+	index := 0
+	var finalErr error
+
+	return func() (Record, error) {
+		if finalErr != nil {
+			return nil, finalErr
+		}
+		if !started {
+			started = true
+			if finalErr = findJSONArray(decoder, js.ArrayPath); finalErr != nil {
+				return nil, finalErr
+			}
+		}
+
+		if !decoder.More() {
+			if _, err := decoder.Token(); err != nil { // The closing ']'
+				finalErr = fmt.Errorf("failed to parse JSON array: %w", err)
+				return nil, finalErr
+			}
+			finalErr = EOS
+			if js.ArrayPath == "" {
+				// Nothing may follow a top-level array; the rest of a wrapping object isn't read
+				if token, err := decoder.Token(); err != io.EOF {
+					finalErr = fmt.Errorf("failed to parse JSON array: unexpected %v after the closing ]", describeJSONToken(token, err))
+				}
+			}
+			return nil, finalErr
 		}
+
+		record, err := js.decodeRecord(decoder.Decode)
+		if err != nil {
+			finalErr = fmt.Errorf("failed to parse JSON array element %d: %w", index, err)
+			return nil, finalErr
+		}
+		index++
+		return record, nil
 	}
-	
-	var jsonArray []map[string]any
-	if err := json.Unmarshal(data, &jsonArray); err != nil {
-		return func() (Record, error) {
-			return nil, fmt.Errorf("failed to parse JSON array: %w", err)
+}
+
+// findJSONArray reads decoder up to and including the '[' opening the array at path, a
+// dot-separated path of object keys, or at the top level if path is empty
+func findJSONArray(decoder *json.Decoder, path string) error {
+	var keys []string
+	if path != "" {
+		keys = strings.Split(path, ".")
+	}
+	for depth, key := range keys {
+		if err := expectJSONDelim(decoder, '{', strings.Join(keys[:depth], ".")); err != nil {
+			return err
+		}
+		for {
+			if !decoder.More() {
+				return fmt.Errorf("failed to parse JSON array: path %q not found", path)
+			}
+			token, err := decoder.Token()
+			if err != nil {
+				return fmt.Errorf("failed to parse JSON array: %w", err)
+			}
+			if token == key {
+				break
+			}
+			if err := skipJSONValue(decoder); err != nil {
+				return fmt.Errorf("failed to parse JSON array: %w", err)
+			}
 		}
 	}
-	
-	// Convert to Record slice and use FromSlice
-	records := make([]Record, len(jsonArray))
-	for i, obj := range jsonArray {
-		records[i] = convertJSONToRecord(obj)
+	return expectJSONDelim(decoder, '[', path)
+}
+
+// expectJSONDelim reads the next token, failing unless it is delim. at names where
+// the token is, for the error.
+func expectJSONDelim(decoder *json.Decoder, delim json.Delim, at string) error {
+	token, err := decoder.Token()
+	if token == delim {
+		return nil
+	}
+	kind := "an array"
+	if delim == '{' {
+		kind = "an object"
+	}
+	if at == "" {
+		at = "the top level"
+	}
+	return fmt.Errorf("failed to parse JSON array: expected %s at %s, got %v", kind, at, describeJSONToken(token, err))
+}
+
+// skipJSONValue reads past the next value without decoding it
+func skipJSONValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
 	}
-	
-	return FromSlice(records)
+}
+
+// describeJSONToken describes a token read, or the error reading it, for error messages
+func describeJSONToken(token json.Token, err error) string {
+	switch {
+	case err == io.EOF:
+		return "end of input"
+	case err != nil:
+		return err.Error()
+	case token == nil:
+		return "null"
+	}
+	if s, ok := token.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(token)
 }
 
 // convertJSONToRecord converts a JSON object to a Record, preserving structure
@@ -663,8 +1544,24 @@ type JSONSink struct {
 	Writer io.Writer
 	Format JSONFormat
 	Pretty bool
+
+	MaxStreamElements  int               // Stream fields are cut off after this many elements; 0 means no limit
+	NestedKeySeparator string            // When set, keys are re-nested on this separator (see Unflatten)
+	StreamPolicy       StreamFieldPolicy // What to do with stream-valued fields
+	SortedKeys         bool              // Write every object's keys sorted, ignoring record field order
+	FieldOrder         []string          // Fields written first in each record, in this order
+	FsyncEvery         int               // Sync Writer to disk after this many JSON Lines records; 0 means never
 }
 
+// StreamFieldPolicy defines how JSONSink writes stream-valued fields
+type StreamFieldPolicy int
+
+const (
+	StreamFieldCollect StreamFieldPolicy = iota // Collect the stream into a JSON array
+	StreamFieldSkip                             // Leave the field out
+	StreamFieldError                            // Fail the write
+)
+
 // NewJSONSink creates a JSON sink to a writer (defaults to JSON Lines)
 func NewJSONSink(writer io.Writer) *JSONSink {
 	return &JSONSink{
@@ -686,24 +1583,92 @@ func (sink *JSONSink) WithPrettyPrint() *JSONSink {
 	return sink
 }
 
+// WithMaxStreamElements stops collecting a stream field after n elements, so an infinite
+// stream field can't hang the sink. A record with a cut-off field gets "_truncated": true.
+func (sink *JSONSink) WithMaxStreamElements(n int) *JSONSink {
+	if n <= 0 {
+		panic("max stream elements must be positive")
+	}
+	sink.MaxStreamElements = n
+	return sink
+}
+
+// WithNestedKeys writes flattened keys such as "user.name" as nested objects,
+// splitting them on separator like Unflatten
+func (sink *JSONSink) WithNestedKeys(separator string) *JSONSink {
+	if separator == "" {
+		separator = "."
+	}
+	sink.NestedKeySeparator = separator
+	return sink
+}
+
+// WithStreamPolicy sets how stream-valued fields are written
+func (sink *JSONSink) WithStreamPolicy(policy StreamFieldPolicy) *JSONSink {
+	sink.StreamPolicy = policy
+	return sink
+}
+
+// WithFsyncEvery syncs Writer to disk after every n records under JSONLines, and once
+// the stream is written, so at most n records are lost if the machine goes down. Under
+// JSONArray the array is synced once written. It only applies when Writer has a Sync
+// method, as *os.File does.
+func (sink *JSONSink) WithFsyncEvery(n int) *JSONSink {
+	if n <= 0 {
+		panic("fsync interval must be positive")
+	}
+	sink.FsyncEvery = n
+	return sink
+}
+
+// WithSortedKeys writes the keys of every object, nested ones included, in sorted
+// order, so the output doesn't depend on how the records were built. Without it,
+// ordered records are written in their field order (see SetFieldOrder).
+func (sink *JSONSink) WithSortedKeys() *JSONSink {
+	sink.SortedKeys = true
+	return sink
+}
+
+// WithFieldOrder writes the given fields first in each record, in that order; the
+// rest follow in the record's own order, or sorted. Nested objects are unaffected.
+//
+// Example:
+//
+//	NewJSONSink(os.Stdout).WithFieldOrder([]string{"id", "name"}) // {"id":1,"name":"a","age":3,...}
+func (sink *JSONSink) WithFieldOrder(fields []string) *JSONSink {
+	sink.FieldOrder = append([]string(nil), fields...)
+	return sink
+}
+
 // WriteStream writes a Record stream to JSON format
 func (sink *JSONSink) WriteStream(stream Stream[Record]) error {
+	return sink.WriteStreamContext(context.Background(), stream)
+}
+
+// WriteStreamContext writes the stream like WriteStream, stopping with ctx.Err() once
+// ctx is done. ctx is checked before every record. JSON Lines already written stay
+// written; under JSONArray the records gathered so far are written as a complete array.
+func (sink *JSONSink) WriteStreamContext(ctx context.Context, stream Stream[Record]) error {
 	switch sink.Format {
 	case JSONArray:
-		return sink.writeAsArray(stream)
+		return sink.writeAsArray(ctx, stream)
 	default: // JSONLines
-		return sink.writeAsLines(stream)
+		return sink.writeAsLines(ctx, stream)
 	}
 }
 
 // writeAsLines writes each record as a separate JSON line
-func (sink *JSONSink) writeAsLines(stream Stream[Record]) error {
+func (sink *JSONSink) writeAsLines(ctx context.Context, stream Stream[Record]) error {
 	encoder := json.NewEncoder(sink.Writer)
-	if !sink.Pretty {
-		encoder.SetIndent("", "")
+	if sink.Pretty {
+		encoder.SetIndent("", "  ")
 	}
+	sync := periodicSync{target: sink.Writer, every: sink.FsyncEvery}
 	
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		record, err := stream()
 		if err != nil {
 			if err == EOS {
@@ -712,21 +1677,31 @@ func (sink *JSONSink) writeAsLines(stream Stream[Record]) error {
 			return err
 		}
 		
-		jsonObj := convertRecordToJSON(record)
+		jsonObj, err := sink.recordToJSON(record)
+		if err != nil {
+			return err
+		}
 		if err := encoder.Encode(jsonObj); err != nil {
 			return fmt.Errorf("failed to write JSON line: %w", err)
 		}
+		if err := sync.written(); err != nil {
+			return err
+		}
 	}
 	
-	return nil
+	return sync.flush()
 }
 
 // writeAsArray writes all records as a single JSON array
-func (sink *JSONSink) writeAsArray(stream Stream[Record]) error {
+func (sink *JSONSink) writeAsArray(ctx context.Context, stream Stream[Record]) error {
 	// Collect all records first
-	var jsonArray []map[string]any
+	var jsonArray []any
+	var cancelled error // Set when ctx ends the stream; the records so far are still written
 	
 	for {
+		if cancelled = ctx.Err(); cancelled != nil {
+			break
+		}
 		record, err := stream()
 		if err != nil {
 			if err == EOS {
@@ -735,7 +1710,10 @@ func (sink *JSONSink) writeAsArray(stream Stream[Record]) error {
 			return err
 		}
 		
-		jsonObj := convertRecordToJSON(record)
+		jsonObj, err := sink.recordToJSON(record)
+		if err != nil {
+			return err
+		}
 		jsonArray = append(jsonArray, jsonObj)
 	}
 	
@@ -753,141 +1731,753 @@ func (sink *JSONSink) writeAsArray(stream Stream[Record]) error {
 		return fmt.Errorf("failed to marshal JSON array: %w", err)
 	}
 	
-	_, err = sink.Writer.Write(data)
-	if err != nil {
-		return fmt.Errorf("failed to write JSON array: %w", err)
+	_, err = sink.Writer.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write JSON array: %w", err)
+	}
+	if sink.FsyncEvery > 0 {
+		if err := syncWriter(sink.Writer); err != nil {
+			return err
+		}
+	}
+	
+	return cancelled
+}
+
+// WriteRecords writes a slice of Records to JSON format
+func (sink *JSONSink) WriteRecords(records []Record) error {
+	return sink.WriteStream(FromSlice(records))
+}
+
+// recordToJSON converts a Record to a JSON-serializable object: a map, whose keys
+// encoding/json sorts, or an orderedJSONObject for an ordered record or FieldOrder
+func (sink *JSONSink) recordToJSON(record Record) (any, error) {
+	if sink.NestedKeySeparator != "" {
+		nested, err := unflattenRecord(record, sink.NestedKeySeparator, &unflattenConfig{conflictKey: "_value"})
+		if err != nil {
+			return nil, err
+		}
+		record = nested
+	}
+	return sink.objectToJSON(record, "")
+}
+
+// objectToJSON converts the fields of a (possibly nested) Record; path names it in errors
+func (sink *JSONSink) objectToJSON(record Record, path string) (any, error) {
+	jsonObj := make(map[string]any)
+	for key, value := range record {
+		if IsStreamType(value) {
+			switch sink.StreamPolicy {
+			case StreamFieldSkip:
+				continue
+			case StreamFieldError:
+				return nil, fmt.Errorf("field %q is a stream", path+key)
+			}
+			items, truncated := collectAnyStream(value, sink.MaxStreamElements)
+			if truncated {
+				jsonObj["_truncated"] = true
+			}
+			jsonArray := make([]any, len(items))
+			for i, item := range items {
+				converted, err := sink.valueToJSON(item, fmt.Sprintf("%s%s[%d]", path, key, i))
+				if err != nil {
+					return nil, err
+				}
+				jsonArray[i] = converted
+			}
+			jsonObj[key] = jsonArray
+			continue
+		}
+
+		converted, err := sink.valueToJSON(value, path+key)
+		if err != nil {
+			return nil, err
+		}
+		jsonObj[key] = converted
+	}
+	order := fieldOrder(record)
+	if sink.SortedKeys {
+		order = nil
+	}
+	if path == "" && len(sink.FieldOrder) > 0 {
+		order = append(append([]string(nil), sink.FieldOrder...), order...)
+	}
+	if order != nil {
+		return orderedJSONObject{keys: orderedKeys(jsonObj, order), values: jsonObj}, nil
+	}
+	return jsonObj, nil
+}
+
+// orderedJSONObject marshals as a JSON object with its keys in the given order
+type orderedJSONObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// MarshalJSON writes the object's fields in key order
+func (o orderedJSONObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// valueToJSON converts Record field values to JSON-serializable types
+func (sink *JSONSink) valueToJSON(value any, path string) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool, int64, uint64, float64, string:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint:
+		return uint64(v), nil
+	case uint8:
+		return uint64(v), nil
+	case uint16:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case float32:
+		return float64(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case Record:
+		// Nested Record
+		return sink.objectToJSON(v, path+".")
+	default:
+		if IsStreamType(value) {
+			// A stream inside a stream field; the policy was applied to the outer field
+			items, _ := collectAnyStream(value, sink.MaxStreamElements)
+			jsonArray := make([]any, len(items))
+			for i, item := range items {
+				converted, err := sink.valueToJSON(item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+				jsonArray[i] = converted
+			}
+			return jsonArray, nil
+		}
+		if items, ok := streamFieldValues(value); ok {
+			// Slices and arrays are lists like stream fields
+			jsonArray := make([]any, len(items))
+			for i, item := range items {
+				converted, err := sink.valueToJSON(item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+				jsonArray[i] = converted
+			}
+			return jsonArray, nil
+		}
+		// Fallback to string representation
+		return fmt.Sprintf("%v", value), nil
+	}
+}
+
+// IsStreamType checks if a value is a Stream[T] type, or any function of the same
+// shape, func() (T, error), whatever its element type T
+func IsStreamType(value any) bool {
+	return isStreamFunc(reflect.TypeOf(value))
+}
+
+// errorType is the reflect.Type of the error interface
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// isStreamFunc reports whether t has the shape of a Stream: func() (T, error). Every
+// stream field check goes through it, so streams of any element type are treated alike.
+func isStreamFunc(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Func && t.NumIn() == 0 && t.NumOut() == 2 && t.Out(1) == errorType
+}
+
+// collectAnyStream attempts to collect items from any stream type using reflection.
+// A positive limit stops after that many items; truncated reports whether items remained.
+func collectAnyStream(value any, limit int) (collected []any, truncated bool) {
+	// Use reflection to call the stream function repeatedly
+	if !IsStreamType(value) {
+		return nil, false
+	}
+	streamFunc := reflect.ValueOf(value)
+	if streamFunc.IsNil() {
+		return nil, false
+	}
+	
+	for {
+		// Call the stream function
+		results := streamFunc.Call(nil)
+		if len(results) != 2 {
+			break
+		}
+		
+		// Check for error
+		errorVal := results[1]
+		if !errorVal.IsNil() {
+			// Check if it's EOS error
+			if err, ok := errorVal.Interface().(error); ok && err == EOS {
+				break
+			}
+			// Other errors, stop collection
+			break
+		}
+		
+		if limit > 0 && len(collected) == limit {
+			return collected, true
+		}
+		
+		// Add the value to collection
+		collected = append(collected, results[0].Interface())
+	}
+	
+	return collected, false
+}
+
+// File-based JSON functions
+
+// NewJSONSourceFromFile creates a JSON source from a file. The file is closed when the
+// source's stream ends, or by Close.
+func NewJSONSourceFromFile(filename string) (*JSONSource, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JSON file %s: %w", filename, err)
+	}
+	
+	source := NewJSONSource(file)
+	source.closer = &onceCloser{closer: file}
+	return source, nil
+}
+
+// Close closes the file opened by NewJSONSourceFromFile, for a consumer that stops
+// before the stream ends. It does nothing for a source made from a reader.
+func (js *JSONSource) Close() error {
+	return js.closer.Close()
+}
+
+func NewJSONSinkToFile(filename string) (*JSONSink, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JSON file %s: %w", filename, err)
+	}
+	
+	return NewJSONSink(file), nil
+}
+
+// ============================================================================
+// TABLE OUTPUT - TERMINAL PRESENTATION
+// ============================================================================
+
+// TableOption configures WriteTable
+type TableOption func(*tableConfig)
+
+type tableConfig struct {
+	columns  []string
+	maxWidth int
+	maxRows  int
+}
+
+// WithTableColumns sets the columns to show and their order; by default every field
+// of the shown rows appears, sorted by name
+func WithTableColumns(columns ...string) TableOption {
+	return func(config *tableConfig) {
+		config.columns = columns
+	}
+}
+
+// WithTableMaxWidth cuts cells longer than width characters, ending them with "..."
+func WithTableMaxWidth(width int) TableOption {
+	if width < 4 {
+		panic("table max width must be at least 4")
+	}
+	return func(config *tableConfig) {
+		config.maxWidth = width
+	}
+}
+
+// WithTableMaxRows shows at most n rows followed by a "... N more rows" footer.
+// The rest of the stream is still read to count it.
+func WithTableMaxRows(n int) TableOption {
+	if n <= 0 {
+		panic("table max rows must be positive")
+	}
+	return func(config *tableConfig) {
+		config.maxRows = n
+	}
+}
+
+// WriteTable renders a Record stream as an aligned text table for terminals.
+// Nested Records are summarized as "{N fields}" and stream fields as "[stream]"
+// rather than being read.
+func WriteTable(stream Stream[Record], w io.Writer, options ...TableOption) error {
+	config := &tableConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	var rows []Record
+	more := 0
+	for {
+		record, err := stream()
+		if err != nil {
+			if err == EOS {
+				break
+			}
+			return err
+		}
+		if config.maxRows > 0 && len(rows) >= config.maxRows {
+			more++
+			continue
+		}
+		rows = append(rows, record)
+	}
+
+	// Without WithColumns, columns are sorted - or, once a row is ordered, in the order
+	// the rows' fields are first seen
+	columns := config.columns
+	if columns == nil {
+		seen := make(map[string]bool)
+		ordered := false
+		for _, row := range rows {
+			ordered = ordered || fieldOrder(row) != nil
+			for _, field := range row.Keys() {
+				if !seen[field] {
+					seen[field] = true
+					columns = append(columns, field)
+				}
+			}
+		}
+		if !ordered {
+			sort.Strings(columns)
+		}
+	}
+
+	// Format every cell first so column widths are known
+	cells := make([][]string, len(rows)+1)
+	cells[0] = make([]string, len(columns))
+	for i, column := range columns {
+		cells[0][i] = truncateCell(column, config.maxWidth)
+	}
+	for r, row := range rows {
+		cells[r+1] = make([]string, len(columns))
+		for i, column := range columns {
+			cells[r+1][i] = truncateCell(formatTableValue(row[column]), config.maxWidth)
+		}
+	}
+
+	widths := make([]int, len(columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	var out strings.Builder
+	writeLine := func(line []string) {
+		var b strings.Builder
+		for i, cell := range line {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+		}
+		out.WriteString(strings.TrimRight(b.String(), " "))
+		out.WriteString("\n")
+	}
+
+	writeLine(cells[0])
+	separator := make([]string, len(columns))
+	for i, width := range widths {
+		separator[i] = strings.Repeat("-", width)
+	}
+	writeLine(separator)
+	for _, line := range cells[1:] {
+		writeLine(line)
+	}
+	if more > 0 {
+		fmt.Fprintf(&out, "... %d more rows\n", more)
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// formatTableValue formats one cell, summarizing nested Records and streams
+func formatTableValue(value any) string {
+	switch v := value.(type) {
+	case Record:
+		if len(v) == 1 {
+			return "{1 field}"
+		}
+		return fmt.Sprintf("{%d fields}", len(v))
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if IsStreamType(value) {
+		return "[stream]"
+	}
+	return formatCSVValue(value)
+}
+
+// truncateCell cuts a cell to width characters; a width of 0 means no limit
+func truncateCell(cell string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(cell) <= width {
+		return cell
+	}
+	runes := []rune(cell)
+	return string(runes[:width-3]) + "..."
+}
+
+// Head collects the first n elements of a stream, for a quick look at its data
+func Head[T any](stream Stream[T], n int) ([]T, error) {
+	return Collect(Limit[T](n)(stream))
+}
+
+// TopN returns the n elements that sort first under less, in that order, reading
+// the stream in one pass and holding only n elements at a time
+func TopN[T any](stream Stream[T], n int, less func(a, b T) bool) ([]T, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	// The heap keeps the largest elements under cmp, which are the smallest under less
+	heap := newMinHeap(n, func(a, b T) int {
+		if less(a, b) {
+			return 1
+		}
+		if less(b, a) {
+			return -1
+		}
+		return 0
+	})
+	for {
+		item, err := stream()
+		if err != nil {
+			if err == EOS {
+				break
+			}
+			return nil, err
+		}
+		heap.add(item)
+	}
+	return heap.sortedResults(), nil
+}
+
+// ============================================================================
+// COMMAND SOURCES - PROCESS OUTPUT AS RECORDS
+// ============================================================================
+
+// CommandSource runs an external command and parses its standard output into
+// Records. The command starts on the first pull and runs once, so use a single
+// As* stream per source. A non-zero exit status is returned as a stream error
+// after stdout is exhausted, with the command's stderr in the message.
+// Cancel the context or call Close to stop the command early.
+type CommandSource struct {
+	Name string
+	Args []string
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	cmd     *exec.Cmd
+	stdout  io.Reader
+	stderr  bytes.Buffer
+	started bool
+	waited  bool
+	err     error
+}
+
+// NewCommandSource creates a source for the output of name run with args
+func NewCommandSource(ctx context.Context, name string, args ...string) *CommandSource {
+	ctx, cancel := context.WithCancel(ctx)
+	return &CommandSource{
+		Name:   name,
+		Args:   args,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Stdout starts the command if needed and returns its standard output
+func (cs *CommandSource) Stdout() (io.Reader, error) {
+	if cs.started {
+		return cs.stdout, cs.err
+	}
+	cs.started = true
+	
+	cs.cmd = exec.CommandContext(cs.ctx, cs.Name, cs.Args...)
+	cs.cmd.Stderr = &cs.stderr
+	stdout, err := cs.cmd.StdoutPipe()
+	if err != nil {
+		cs.cancel()
+		cs.err = fmt.Errorf("failed to open stdout of %s: %w", cs.Name, err)
+		return nil, cs.err
+	}
+	if err := cs.cmd.Start(); err != nil {
+		cs.cancel()
+		cs.err = fmt.Errorf("failed to start %s: %w", cs.Name, err)
+		return nil, cs.err
+	}
+	
+	cs.stdout = stdout
+	return cs.stdout, nil
+}
+
+// AsLines produces one Record per output line with fields "line" and "line_number"
+func (cs *CommandSource) AsLines() Stream[Record] {
+	return cs.parse(func(reader io.Reader) Stream[Record] {
+		scanner := bufio.NewScanner(reader)
+		lineNumber := int64(0)
+		return func() (Record, error) {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, err
+				}
+				return nil, EOS
+			}
+			lineNumber++
+			return Record{"line": scanner.Text(), "line_number": lineNumber}, nil
+		}
+	})
+}
+
+// AsCSV parses the output as CSV with a header row
+func (cs *CommandSource) AsCSV() Stream[Record] {
+	return cs.parse(func(reader io.Reader) Stream[Record] {
+		return NewCSVSource(reader).ToStream()
+	})
+}
+
+// AsTSV parses the output as TSV with a header row
+func (cs *CommandSource) AsTSV() Stream[Record] {
+	return cs.parse(func(reader io.Reader) Stream[Record] {
+		return NewTSVSource(reader).ToStream()
+	})
+}
+
+// AsJSONLines parses the output as one JSON object per line
+func (cs *CommandSource) AsJSONLines() Stream[Record] {
+	return cs.parse(func(reader io.Reader) Stream[Record] {
+		return NewJSONSource(reader).ToStream()
+	})
+}
+
+// Close stops the command if it is still running and waits for it to exit
+func (cs *CommandSource) Close() error {
+	cs.cancel()
+	if cs.started && cs.cmd.Process != nil && !cs.waited {
+		cs.waited = true
+		cs.cmd.Wait() // The command was killed, so its exit status is not an error here
+	}
+	return nil
+}
+
+// parse starts the command on first pull and wraps the parsed output so the
+// exit status is checked once the parser reaches the end
+func (cs *CommandSource) parse(parser func(io.Reader) Stream[Record]) Stream[Record] {
+	var records Stream[Record]
+	var final error
+	
+	return func() (Record, error) {
+		if final != nil {
+			return nil, final
+		}
+		if records == nil {
+			stdout, err := cs.Stdout()
+			if err != nil {
+				final = err
+				return nil, err
+			}
+			records = parser(stdout)
+		}
+		
+		record, err := records()
+		if err == nil {
+			return record, nil
+		}
+		final = cs.finish(err)
+		return nil, final
+	}
+}
+
+// finish waits for the command after its output ends, turning a failed exit
+// into an error; parse errors stop the command and are returned as they are.
+// Once Close has reaped the command the stream simply ends.
+func (cs *CommandSource) finish(streamErr error) error {
+	if cs.waited {
+		return EOS
+	}
+	if streamErr != EOS {
+		cs.Close()
+		return streamErr
+	}
+	
+	cs.waited = true
+	waitErr := cs.cmd.Wait()
+	defer cs.cancel()
+	if err := cs.ctx.Err(); err != nil && waitErr != nil {
+		return err
+	}
+	if waitErr != nil {
+		if stderr := strings.TrimSpace(cs.stderr.String()); stderr != "" {
+			return fmt.Errorf("command %s failed: %w: %s", cs.Name, waitErr, stderr)
+		}
+		return fmt.Errorf("command %s failed: %w", cs.Name, waitErr)
 	}
-	
-	return nil
+	return EOS
 }
 
-// WriteRecords writes a slice of Records to JSON format
-func (sink *JSONSink) WriteRecords(records []Record) error {
-	return sink.WriteStream(FromSlice(records))
+// ============================================================================
+// HTTP SOURCES - REST AND NDJSON APIS
+// ============================================================================
+
+// HTTPSource fetches JSON records over HTTP, optionally following pagination.
+// Each page is requested lazily once the previous one has been consumed.
+// Responses with status >= 400 end the stream with an error that includes the
+// start of the response body. Call Close when abandoning a stream mid-page.
+type HTTPSource struct {
+	Client   *http.Client
+	Request  *http.Request
+	NextPage func(resp *http.Response, lastRecord Record) (*http.Request, bool)
+	ctx      context.Context
+	current  *http.Response
 }
 
-// convertRecordToJSON converts a Record to a JSON-serializable map
-func convertRecordToJSON(record Record) map[string]any {
-	jsonObj := make(map[string]any)
-	for key, value := range record {
-		jsonObj[key] = convertRecordValueToJSON(value)
-	}
-	return jsonObj
+// NewHTTPSource creates a source for a single request (a nil client uses http.DefaultClient)
+func NewHTTPSource(client *http.Client, req *http.Request) *HTTPSource {
+	return NewPaginatedHTTPSource(client, req, nil)
 }
 
-// convertRecordValueToJSON converts Record field values to JSON-serializable types
-func convertRecordValueToJSON(value any) any {
-	switch v := value.(type) {
-	case nil:
-		return nil
-	case bool, int64, float64, string:
-		return v
-	case int:
-		return int64(v)
-	case float32:
-		return float64(v)
-	case time.Time:
-		return v.Format(time.RFC3339)
-	case Record:
-		// Nested Record
-		return convertRecordToJSON(v)
-	case Stream[any]:
-		// Convert stream to array for JSON serialization
-		items, _ := Collect(v)
-		jsonArray := make([]any, len(items))
-		for i, item := range items {
-			jsonArray[i] = convertRecordValueToJSON(item)
-		}
-		return jsonArray
-	default:
-		// Handle all other Stream types by attempting to collect them
-		if IsStreamType(value) {
-			// Try to collect the stream and convert to JSON array
-			if collected := collectAnyStream(value); collected != nil {
-				jsonArray := make([]any, len(collected))
-				for i, item := range collected {
-					jsonArray[i] = convertRecordValueToJSON(item)
-				}
-				return jsonArray
-			}
-			// If collection fails, return placeholder
-			return fmt.Sprintf("<Stream[%T]>", value)
-		}
-		// Fallback to string representation
-		return fmt.Sprintf("%v", value)
+// NewPaginatedHTTPSource creates a source that calls nextPage after each page
+// is exhausted, with the page's response and its last record (nil for an empty
+// page), to get the request for the next page; returning false ends the stream
+func NewPaginatedHTTPSource(client *http.Client, firstReq *http.Request, nextPage func(resp *http.Response, lastRecord Record) (*http.Request, bool)) *HTTPSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPSource{
+		Client:   client,
+		Request:  firstReq,
+		NextPage: nextPage,
 	}
 }
 
-// IsStreamType checks if a value is a Stream[T] type
-func IsStreamType(value any) bool {
-	typeStr := fmt.Sprintf("%T", value)
-	// Stream[T] types appear as "stream.Stream[TYPE]" or as function types "func() (TYPE, error)"
-	return strings.Contains(typeStr, "stream.Stream[") || strings.Contains(typeStr, "func() (") && strings.Contains(typeStr, ", error)")
+// WithContext applies ctx to every page request, so cancelling it aborts the stream
+func (hs *HTTPSource) WithContext(ctx context.Context) *HTTPSource {
+	hs.ctx = ctx
+	return hs
 }
 
-// collectAnyStream attempts to collect items from any stream type using reflection
-func collectAnyStream(value any) []any {
-	// Use reflection to call the stream function repeatedly
-	streamFunc := reflect.ValueOf(value)
-	if streamFunc.Kind() != reflect.Func {
-		return nil
-	}
-	
-	// Check if it matches Stream[T] signature: func() (T, error)
-	streamType := streamFunc.Type()
-	if streamType.NumIn() != 0 || streamType.NumOut() != 2 {
-		return nil
-	}
-	
-	// Second return must be error
-	errorType := reflect.TypeOf((*error)(nil)).Elem()
-	if !streamType.Out(1).Implements(errorType) {
+// Close releases the response of the page currently being read, for consumers
+// that stop before the stream ends
+func (hs *HTTPSource) Close() error {
+	if hs.current == nil {
 		return nil
 	}
+	err := hs.current.Body.Close()
+	hs.current = nil
+	return err
+}
+
+// AsJSONLines decodes each page as one JSON object per line
+func (hs *HTTPSource) AsJSONLines() Stream[Record] {
+	return hs.pages(func(body io.Reader) Stream[Record] {
+		return NewJSONSource(body).ToStream()
+	})
+}
+
+// AsJSONArray decodes each page as a JSON array of objects
+func (hs *HTTPSource) AsJSONArray() Stream[Record] {
+	return hs.pages(func(body io.Reader) Stream[Record] {
+		return NewJSONSource(body).WithFormat(JSONArray).ToStream()
+	})
+}
+
+// pages walks the pages, decoding each response body with decode
+func (hs *HTTPSource) pages(decode func(io.Reader) Stream[Record]) Stream[Record] {
+	next := hs.Request
+	var page Stream[Record]
+	var lastRecord Record
+	var final error
 	
-	var collected []any
-	for {
-		// Call the stream function
-		results := streamFunc.Call(nil)
-		if len(results) != 2 {
-			break
-		}
-		
-		// Check for error
-		errorVal := results[1]
-		if !errorVal.IsNil() {
-			// Check if it's EOS error
-			if err, ok := errorVal.Interface().(error); ok && err == EOS {
+	return func() (Record, error) {
+		for final == nil {
+			if page == nil {
+				if next == nil {
+					final = EOS
+					break
+				}
+				resp, err := hs.fetch(next)
+				if err != nil {
+					final = err
+					break
+				}
+				hs.current = resp
+				page = decode(resp.Body)
+				lastRecord = nil
+			}
+			
+			record, err := page()
+			if err == nil {
+				lastRecord = record
+				return record, nil
+			}
+			resp := hs.current
+			hs.Close()
+			if err != EOS {
+				final = err
 				break
 			}
-			// Other errors, stop collection
-			break
+			
+			// Page exhausted - ask for the next one
+			page = nil
+			next = nil
+			if hs.NextPage != nil {
+				if req, ok := hs.NextPage(resp, lastRecord); ok {
+					next = req
+				}
+			}
 		}
-		
-		// Add the value to collection
-		collected = append(collected, results[0].Interface())
+		return nil, final
 	}
-	
-	return collected
 }
 
-// File-based JSON functions
-func NewJSONSourceFromFile(filename string) (*JSONSource, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open JSON file %s: %w", filename, err)
+// fetch performs one page request, turning error statuses into errors
+func (hs *HTTPSource) fetch(req *http.Request) (*http.Response, error) {
+	if hs.ctx != nil {
+		req = req.WithContext(hs.ctx)
 	}
-	
-	return NewJSONSource(file), nil
-}
-
-func NewJSONSinkToFile(filename string) (*JSONSink, error) {
-	file, err := os.Create(filename)
+	resp, err := hs.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create JSON file %s: %w", filename, err)
+		return nil, fmt.Errorf("HTTP request to %s failed: %w", req.URL, err)
 	}
-	
-	return NewJSONSink(file), nil
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("HTTP %d from %s: %s", resp.StatusCode, req.URL, strings.TrimSpace(string(snippet)))
+	}
+	return resp, nil
 }
 
 // ============================================================================
@@ -899,8 +2489,21 @@ type ProtobufSource struct {
 	Reader      io.Reader
 	MessageDesc protoreflect.MessageDescriptor
 	Format      ProtobufFormat
+
+	// MaxMessageSize is the largest length-delimited message read, in bytes; a longer
+	// length prefix, usually a sign of corrupt data, fails the stream. 0 means 4 MiB.
+	MaxMessageSize int
+
+	closer *onceCloser // The file opened by NewProtobufSourceFromFile
 }
 
+// defaultMaxProtobufMessageSize is the MaxMessageSize used when none is set
+const defaultMaxProtobufMessageSize = 4 << 20
+
+// ErrProtobufMessageTooLarge is wrapped by ProtobufSource errors for a length prefix
+// over MaxMessageSize
+var ErrProtobufMessageTooLarge = errors.New("protobuf message larger than the maximum size")
+
 // ProtobufFormat specifies how protobuf data is structured
 type ProtobufFormat int
 
@@ -924,40 +2527,79 @@ func (ps *ProtobufSource) WithFormat(format ProtobufFormat) *ProtobufSource {
 	return ps
 }
 
+// WithMaxMessageSize sets the largest length-delimited message read, in bytes. A length
+// prefix over it fails the stream with ErrProtobufMessageTooLarge instead of allocating
+// whatever a corrupt prefix claims. The default is 4 MiB.
+func (ps *ProtobufSource) WithMaxMessageSize(n int) *ProtobufSource {
+	if n <= 0 {
+		panic("max message size must be positive")
+	}
+	ps.MaxMessageSize = n
+	return ps
+}
+
 // ToStream converts protobuf data to a Record stream
 func (ps *ProtobufSource) ToStream() Stream[Record] {
 	switch ps.Format {
 	case ProtobufJSON:
-		return ps.jsonToStream()
+		return closeOnEnd(ps.jsonToStream(), ps.closer)
 	default: // ProtobufDelimited
-		return ps.delimitedToStream()
+		return closeOnEnd(ps.delimitedToStream(), ps.closer)
 	}
 }
 
-// delimitedToStream handles length-delimited protobuf messages
+// delimitedToStream handles length-delimited protobuf messages. The reader is buffered,
+// as length prefixes are read a byte at a time, and errors are sticky.
 func (ps *ProtobufSource) delimitedToStream() Stream[Record] {
+	reader := bufio.NewReader(ps.Reader)
+	maxSize := ps.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxProtobufMessageSize
+	}
+	var msgData []byte
+	var finalErr error
+	index := 0
+
 	return func() (Record, error) {
-		// Read length prefix (varint)
-		length, err := readVarint(ps.Reader)
+		if finalErr != nil {
+			return nil, finalErr
+		}
+		fail := func(err error) (Record, error) {
+			finalErr = err
+			return nil, err
+		}
+
+		// Read length prefix (varint); io.EOF means the input ended between messages
+		length, err := binary.ReadUvarint(reader)
 		if err != nil {
 			if err == io.EOF {
-				return nil, EOS
+				return fail(EOS)
 			}
-			return nil, fmt.Errorf("failed to read message length: %w", err)
+			return fail(fmt.Errorf("failed to read length of message %d: %w", index, err))
+		}
+		if length > uint64(maxSize) {
+			return fail(fmt.Errorf("message %d: %w: length prefix says %d bytes, limit is %d", index, ErrProtobufMessageTooLarge, length, maxSize))
 		}
 		
-		// Read message data
-		msgData := make([]byte, length)
-		if _, err := io.ReadFull(ps.Reader, msgData); err != nil {
-			return nil, fmt.Errorf("failed to read message data: %w", err)
+		// Read message data, reusing the buffer; Unmarshal copies what it keeps
+		if uint64(cap(msgData)) < length {
+			msgData = make([]byte, length)
+		}
+		msgData = msgData[:length]
+		if _, err := io.ReadFull(reader, msgData); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return fail(fmt.Errorf("failed to read message %d: %w", index, err))
 		}
 		
 		// Parse protobuf message
 		msg := dynamicpb.NewMessage(ps.MessageDesc)
 		if err := proto.Unmarshal(msgData, msg); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal protobuf message: %w", err)
+			return fail(fmt.Errorf("failed to unmarshal protobuf message %d: %w", index, err))
 		}
 		
+		index++
 		return convertProtobufToRecord(msg), nil
 	}
 }
@@ -1053,10 +2695,18 @@ func convertProtobufScalarValue(fd protoreflect.FieldDescriptor, v protoreflect.
 	case protoreflect.BytesKind:
 		return string(v.Bytes())
 	case protoreflect.MessageKind:
+		// Well-known types such as Timestamp have a natural Record form
+		if value, ok := wellKnownToRecordValue(v.Message()); ok {
+			return value
+		}
 		// Nested message
 		return convertProtobufToRecord(v.Message().Interface())
 	case protoreflect.EnumKind:
-		return string(fd.Enum().Values().ByNumber(v.Enum()).Name())
+		// Enums are read as their name, or their number when the enum doesn't declare it
+		if enumValue := fd.Enum().Values().ByNumber(v.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return int64(v.Enum())
 	default:
 		return fmt.Sprintf("%v", v.Interface())
 	}
@@ -1067,6 +2717,38 @@ type ProtobufSink struct {
 	Writer      io.Writer
 	MessageDesc protoreflect.MessageDescriptor
 	Format      ProtobufFormat
+
+	unknownPolicy UnknownFieldPolicy
+	unknownFields int
+}
+
+// ErrUnknownProtobufField is wrapped by ProtobufSink errors for a record field its
+// message has no field for, under FailOnUnknownFields
+var ErrUnknownProtobufField = errors.New("field not in protobuf message")
+
+// UnknownFieldPolicy decides what ProtobufSink does with record fields its message has
+// no field for. Build one with IgnoreUnknownFields, WarnUnknownFields or FailOnUnknownFields.
+type UnknownFieldPolicy struct {
+	fail bool
+	warn func(field string, record Record)
+}
+
+// IgnoreUnknownFields leaves unknown fields out of the message, the default
+func IgnoreUnknownFields() UnknownFieldPolicy {
+	return UnknownFieldPolicy{}
+}
+
+// WarnUnknownFields leaves unknown fields out of the message and calls handler for each,
+// with the field's dotted path - "address.zip" for a field of a nested record - and the
+// top-level record it came from
+func WarnUnknownFields(handler func(field string, record Record)) UnknownFieldPolicy {
+	return UnknownFieldPolicy{warn: handler}
+}
+
+// FailOnUnknownFields makes the write fail on the first unknown field, with an error
+// wrapping ErrUnknownProtobufField, so a misspelled field name can't silently vanish
+func FailOnUnknownFields() UnknownFieldPolicy {
+	return UnknownFieldPolicy{fail: true}
 }
 
 // NewProtobufSink creates a protobuf sink to a writer
@@ -1084,19 +2766,66 @@ func (sink *ProtobufSink) WithFormat(format ProtobufFormat) *ProtobufSink {
 	return sink
 }
 
+// WithUnknownFieldPolicy sets what happens to record fields the message has no field for,
+// in nested records too. By default they are left out silently.
+//
+// Example:
+//
+//	sink := NewProtobufSink(w, personDesc).WithUnknownFieldPolicy(FailOnUnknownFields())
+//	err := sink.WriteStream(records)
+//	// failed to convert record 3 to protobuf: field "user_Id": field not in protobuf message streamv2.User
+func (sink *ProtobufSink) WithUnknownFieldPolicy(policy UnknownFieldPolicy) *ProtobufSink {
+	sink.unknownPolicy = policy
+	return sink
+}
+
+// UnknownFields returns how many record fields have been left out because the message
+// has no field for them
+func (sink *ProtobufSink) UnknownFields() int {
+	return sink.unknownFields
+}
+
+// convert builds the message for a record, applying the unknown field policy
+func (sink *ProtobufSink) convert(record Record) (*dynamicpb.Message, error) {
+	onUnknown := func(field string, msgDesc protoreflect.MessageDescriptor) error {
+		if sink.unknownPolicy.fail {
+			return fmt.Errorf("field %q: %w %s", field, ErrUnknownProtobufField, msgDesc.FullName())
+		}
+		sink.unknownFields++
+		if sink.unknownPolicy.warn != nil {
+			sink.unknownPolicy.warn(field, record)
+		}
+		return nil
+	}
+	msg := dynamicpb.NewMessage(sink.MessageDesc)
+	if err := convertRecordToProtobuf(record, msg, "", onUnknown); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
 // WriteStream writes a Record stream to protobuf format
 func (sink *ProtobufSink) WriteStream(stream Stream[Record]) error {
+	return sink.WriteStreamContext(context.Background(), stream)
+}
+
+// WriteStreamContext writes the stream like WriteStream, stopping with ctx.Err() once
+// ctx is done. ctx is checked before every record, so only whole messages are written.
+func (sink *ProtobufSink) WriteStreamContext(ctx context.Context, stream Stream[Record]) error {
 	switch sink.Format {
 	case ProtobufJSON:
-		return sink.writeAsJSON(stream)
+		return sink.writeAsJSON(ctx, stream)
 	default: // ProtobufDelimited
-		return sink.writeAsDelimited(stream)
+		return sink.writeAsDelimited(ctx, stream)
 	}
 }
 
 // writeAsDelimited writes length-delimited protobuf messages
-func (sink *ProtobufSink) writeAsDelimited(stream Stream[Record]) error {
-	for {
+func (sink *ProtobufSink) writeAsDelimited(ctx context.Context, stream Stream[Record]) error {
+	for index := 0; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		record, err := stream()
 		if err != nil {
 			if err == EOS {
@@ -1106,9 +2835,9 @@ func (sink *ProtobufSink) writeAsDelimited(stream Stream[Record]) error {
 		}
 		
 		// Convert Record to protobuf message
-		msg := dynamicpb.NewMessage(sink.MessageDesc)
-		if err := convertRecordToProtobuf(record, msg); err != nil {
-			return fmt.Errorf("failed to convert record to protobuf: %w", err)
+		msg, err := sink.convert(record)
+		if err != nil {
+			return fmt.Errorf("failed to convert record %d to protobuf: %w", index, err)
 		}
 		
 		// Marshal message
@@ -1131,8 +2860,11 @@ func (sink *ProtobufSink) writeAsDelimited(stream Stream[Record]) error {
 }
 
 // writeAsJSON writes protobuf messages as JSON lines
-func (sink *ProtobufSink) writeAsJSON(stream Stream[Record]) error {
-	for {
+func (sink *ProtobufSink) writeAsJSON(ctx context.Context, stream Stream[Record]) error {
+	for index := 0; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		record, err := stream()
 		if err != nil {
 			if err == EOS {
@@ -1142,9 +2874,9 @@ func (sink *ProtobufSink) writeAsJSON(stream Stream[Record]) error {
 		}
 		
 		// Convert Record to protobuf message
-		msg := dynamicpb.NewMessage(sink.MessageDesc)
-		if err := convertRecordToProtobuf(record, msg); err != nil {
-			return fmt.Errorf("failed to convert record to protobuf: %w", err)
+		msg, err := sink.convert(record)
+		if err != nil {
+			return fmt.Errorf("failed to convert record %d to protobuf: %w", index, err)
 		}
 		
 		// Marshal as JSON
@@ -1170,21 +2902,32 @@ func (sink *ProtobufSink) WriteRecords(records []Record) error {
 	return sink.WriteStream(FromSlice(records))
 }
 
-// convertRecordToProtobuf converts a Record to a protobuf message
-func convertRecordToProtobuf(record Record, msg *dynamicpb.Message) error {
+// unknownFieldFunc is called for a record field, by dotted path, that the message
+// described by msgDesc has no field for; returning an error fails the conversion
+type unknownFieldFunc func(field string, msgDesc protoreflect.MessageDescriptor) error
+
+// convertRecordToProtobuf converts a Record to a protobuf message. path prefixes the
+// field names of a nested record in errors and in calls to onUnknown.
+func convertRecordToProtobuf(record Record, msg *dynamicpb.Message, path string, onUnknown unknownFieldFunc) error {
 	msgReflect := msg.ProtoReflect()
 	msgDesc := msgReflect.Descriptor()
 	
-	for fieldName, value := range record {
+	// Keys are sorted, so the same field is reported first on every run
+	for _, fieldName := range record.Keys() {
 		// Find field descriptor by name
 		fd := msgDesc.Fields().ByName(protoreflect.Name(fieldName))
 		if fd == nil {
-			// Skip unknown fields
+			if err := onUnknown(path+fieldName, msgDesc); err != nil {
+				return err
+			}
 			continue
 		}
 		
-		protobufValue, err := convertRecordValueToProtobuf(msgReflect, fd, value)
+		protobufValue, err := convertRecordValueToProtobuf(msgReflect, fd, record[fieldName], path+fieldName+".", onUnknown)
 		if err != nil {
+			if errors.Is(err, ErrUnknownProtobufField) {
+				return err
+			}
 			return fmt.Errorf("failed to convert field '%s': %w", fieldName, err)
 		}
 		
@@ -1195,29 +2938,20 @@ func convertRecordToProtobuf(record Record, msg *dynamicpb.Message) error {
 }
 
 // convertRecordValueToProtobuf converts Record field values to protobuf values
-func convertRecordValueToProtobuf(msgReflect protoreflect.Message, fd protoreflect.FieldDescriptor, value any) (protoreflect.Value, error) {
+func convertRecordValueToProtobuf(msgReflect protoreflect.Message, fd protoreflect.FieldDescriptor, value any, path string, onUnknown unknownFieldFunc) (protoreflect.Value, error) {
 	if fd.IsList() {
 		// Handle repeated fields
 		list := msgReflect.Mutable(fd).List()
-		
-		// Handle Stream[T] values
-		if streamValue, ok := value.(Stream[any]); ok {
-			items, _ := Collect(streamValue)
-			for _, item := range items {
-				itemValue, err := convertRecordScalarToProtobuf(fd, item)
-				if err != nil {
-					return protoreflect.Value{}, err
-				}
-				list.Append(itemValue)
-			}
-		} else if slice, ok := value.([]any); ok {
-			for _, item := range slice {
-				itemValue, err := convertRecordScalarToProtobuf(fd, item)
-				if err != nil {
-					return protoreflect.Value{}, err
-				}
-				list.Append(itemValue)
+		items, err := repeatedItems(value)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("%w for repeated protobuf %s", err, fd.Kind())
+		}
+		for _, item := range items {
+			itemValue, err := convertRecordScalarToProtobuf(fd, item, path, onUnknown)
+			if err != nil {
+				return protoreflect.Value{}, err
 			}
+			list.Append(itemValue)
 		}
 		
 		return protoreflect.ValueOfList(list), nil
@@ -1230,7 +2964,7 @@ func convertRecordValueToProtobuf(msgReflect protoreflect.Message, fd protorefle
 		if recordValue, ok := value.(Record); ok {
 			for k, v := range recordValue {
 				key := protoreflect.ValueOfString(k).MapKey()
-				val, err := convertRecordScalarToProtobuf(fd.MapValue(), v)
+				val, err := convertRecordScalarToProtobuf(fd.MapValue(), v, path+k+".", onUnknown)
 				if err != nil {
 					return protoreflect.Value{}, err
 				}
@@ -1241,11 +2975,33 @@ func convertRecordValueToProtobuf(msgReflect protoreflect.Message, fd protorefle
 		return protoreflect.ValueOfMap(mapVal), nil
 	}
 	
-	return convertRecordScalarToProtobuf(fd, value)
+	return convertRecordScalarToProtobuf(fd, value, path, onUnknown)
+}
+
+// repeatedItems returns the elements of a value written to a repeated field: a stream
+// of any element type, collected as the JSON sink collects stream fields, or a slice or
+// array of any element type. nil has no elements.
+func repeatedItems(value any) ([]any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if IsStreamType(value) {
+		items, _ := collectAnyStream(value, 0)
+		return items, nil
+	}
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot convert %T", value)
+	}
+	items := make([]any, list.Len())
+	for i := range items {
+		items[i] = list.Index(i).Interface()
+	}
+	return items, nil
 }
 
 // convertRecordScalarToProtobuf converts scalar Record values to protobuf values
-func convertRecordScalarToProtobuf(fd protoreflect.FieldDescriptor, value any) (protoreflect.Value, error) {
+func convertRecordScalarToProtobuf(fd protoreflect.FieldDescriptor, value any, path string, onUnknown unknownFieldFunc) (protoreflect.Value, error) {
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
 		if b, ok := value.(bool); ok {
@@ -1283,11 +3039,16 @@ func convertRecordScalarToProtobuf(fd protoreflect.FieldDescriptor, value any) (
 		if s, ok := convertToString(value); ok {
 			return protoreflect.ValueOfBytes([]byte(s)), nil
 		}
+	case protoreflect.EnumKind:
+		return protobufEnumValue(fd.Enum(), value)
 	case protoreflect.MessageKind:
+		if message, ok, err := recordValueToWellKnown(fd.Message(), value); ok {
+			return message, err
+		}
 		if record, ok := value.(Record); ok {
 			// Create nested message
 			nestedMsg := dynamicpb.NewMessage(fd.Message())
-			if err := convertRecordToProtobuf(record, nestedMsg); err != nil {
+			if err := convertRecordToProtobuf(record, nestedMsg, path, onUnknown); err != nil {
 				return protoreflect.Value{}, err
 			}
 			return protoreflect.ValueOfMessage(nestedMsg.ProtoReflect()), nil
@@ -1297,30 +3058,7 @@ func convertRecordScalarToProtobuf(fd protoreflect.FieldDescriptor, value any) (
 	return protoreflect.Value{}, fmt.Errorf("cannot convert %T to protobuf %s", value, fd.Kind())
 }
 
-// Utility functions for varint encoding/decoding
-func readVarint(r io.Reader) (uint64, error) {
-	var result uint64
-	var shift uint
-	
-	for {
-		var b [1]byte
-		if _, err := r.Read(b[:]); err != nil {
-			return 0, err
-		}
-		
-		result |= uint64(b[0]&0x7F) << shift
-		if b[0]&0x80 == 0 {
-			break
-		}
-		shift += 7
-		if shift >= 64 {
-			return 0, fmt.Errorf("varint too long")
-		}
-	}
-	
-	return result, nil
-}
-
+// writeVarint writes a length prefix
 func writeVarint(w io.Writer, value uint64) error {
 	for value >= 0x80 {
 		if _, err := w.Write([]byte{byte(value) | 0x80}); err != nil {
@@ -1333,13 +3071,24 @@ func writeVarint(w io.Writer, value uint64) error {
 }
 
 // File-based protobuf functions
+
+// NewProtobufSourceFromFile creates a protobuf source from a file. The file is closed
+// when the source's stream ends, or by Close.
 func NewProtobufSourceFromFile(filename string, messageDesc protoreflect.MessageDescriptor) (*ProtobufSource, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open protobuf file %s: %w", filename, err)
 	}
 	
-	return NewProtobufSource(file, messageDesc), nil
+	source := NewProtobufSource(file, messageDesc)
+	source.closer = &onceCloser{closer: file}
+	return source, nil
+}
+
+// Close closes the file opened by NewProtobufSourceFromFile, for a consumer that stops
+// before the stream ends. It does nothing for a source made from a reader.
+func (ps *ProtobufSource) Close() error {
+	return ps.closer.Close()
 }
 
 func NewProtobufSinkToFile(filename string, messageDesc protoreflect.MessageDescriptor) (*ProtobufSink, error) {
@@ -1351,6 +3100,122 @@ func NewProtobufSinkToFile(filename string, messageDesc protoreflect.MessageDesc
 	return NewProtobufSink(file, messageDesc), nil
 }
 
+// NewProtobufSourceFromFiles opens a delimited protobuf data file whose messages are
+// described by messageName in a descriptor set file (see LoadMessageDescriptorFromDescriptorSet)
+func NewProtobufSourceFromFiles(dataFile, descriptorSetFile, messageName string) (*ProtobufSource, error) {
+	messageDesc, err := LoadMessageDescriptorFromDescriptorSet(descriptorSetFile, messageName)
+	if err != nil {
+		return nil, err
+	}
+	return NewProtobufSourceFromFile(dataFile, messageDesc)
+}
+
+// LoadMessageDescriptorFromDescriptorSet reads a FileDescriptorSet, as written by
+// `protoc --include_imports --descriptor_set_out=FILE`, and returns the descriptor of
+// the message with the given full name (e.g. "mypackage.Person"). Imports missing from
+// the set are resolved against the descriptors compiled into the binary.
+func LoadMessageDescriptorFromDescriptorSet(path string, fullName string) (protoreflect.MessageDescriptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set %s: %w", path, err)
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set %s: %w", path, err)
+	}
+	files, err := descriptorSetFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", path, err)
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(fullName))
+	if err != nil {
+		return nil, fmt.Errorf("message %s not found in %s: %w", fullName, path, err)
+	}
+	messageDesc, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s in %s is not a message", fullName, path)
+	}
+	return messageDesc, nil
+}
+
+// LoadMessageDescriptorFromProto compiles a .proto file with protoc, which must be on
+// the PATH, and returns the descriptor of the message with the given full name.
+// Imports are looked up relative to the .proto file's directory.
+func LoadMessageDescriptorFromProto(path string, fullName string) (protoreflect.MessageDescriptor, error) {
+	protoc, err := exec.LookPath("protoc")
+	if err != nil {
+		return nil, fmt.Errorf("protoc is required to load %s: %w", path, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "streamv2-proto")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	setFile := filepath.Join(tmpDir, "descriptor_set.pb")
+	cmd := exec.Command(protoc,
+		"--include_imports",
+		"--descriptor_set_out="+setFile,
+		"--proto_path="+filepath.Dir(path),
+		filepath.Base(path))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("protoc failed for %s: %w: %s", path, err, strings.TrimSpace(string(output)))
+	}
+
+	return LoadMessageDescriptorFromDescriptorSet(setFile, fullName)
+}
+
+// descriptorSetFiles builds a file registry from a descriptor set, adding files once
+// their imports are available so the set's order doesn't matter
+func descriptorSetFiles(set *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	files := new(protoregistry.Files)
+	resolver := descriptorResolver{files}
+	pending := set.GetFile()
+	for len(pending) > 0 {
+		var waiting []*descriptorpb.FileDescriptorProto
+		var lastErr error
+		for _, fileProto := range pending {
+			file, err := protodesc.NewFile(fileProto, resolver)
+			if err != nil {
+				waiting = append(waiting, fileProto)
+				lastErr = err
+				continue
+			}
+			if err := files.RegisterFile(file); err != nil {
+				return nil, err
+			}
+		}
+		if len(waiting) == len(pending) {
+			return nil, lastErr
+		}
+		pending = waiting
+	}
+	return files, nil
+}
+
+// descriptorResolver finds files in a descriptor set first, then among the
+// descriptors compiled into the binary (e.g. well-known types)
+type descriptorResolver struct {
+	files *protoregistry.Files
+}
+
+func (r descriptorResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r descriptorResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if desc, err := r.files.FindDescriptorByName(name); err == nil {
+		return desc, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
 // ============================================================================
 // CONVENIENCE FUNCTIONS
 // ============================================================================
@@ -1392,12 +3257,24 @@ func StreamToCSV(stream Stream[Record], writer io.Writer) error {
 	return sink.WriteStream(stream)
 }
 
+// StreamToCSVContext writes a Record stream to a CSV writer, stopping with ctx.Err()
+// once ctx is done; the rows written so far are flushed
+func StreamToCSVContext(ctx context.Context, stream Stream[Record], writer io.Writer) error {
+	return NewCSVSink(writer).WriteStreamContext(ctx, stream)
+}
+
 // StreamToTSV writes a Record stream to a TSV writer
 func StreamToTSV(stream Stream[Record], writer io.Writer) error {
 	sink := NewTSVSink(writer)
 	return sink.WriteStream(stream)
 }
 
+// StreamToTSVContext writes a Record stream to a TSV writer, stopping with ctx.Err()
+// once ctx is done; the rows written so far are flushed
+func StreamToTSVContext(ctx context.Context, stream Stream[Record], writer io.Writer) error {
+	return NewTSVSink(writer).WriteStreamContext(ctx, stream)
+}
+
 // JSONToStream reads JSON from a reader and returns a Record stream (defaults to JSON Lines)
 func JSONToStream(reader io.Reader) Stream[Record] {
 	source := NewJSONSource(reader)
@@ -1410,74 +3287,67 @@ func StreamToJSON(stream Stream[Record], writer io.Writer) error {
 	return sink.WriteStream(stream)
 }
 
-// File-based convenience functions for backward compatibility
+// StreamToJSONContext writes a Record stream as JSON Lines, stopping with ctx.Err()
+// once ctx is done
+func StreamToJSONContext(ctx context.Context, stream Stream[Record], writer io.Writer) error {
+	return NewJSONSink(writer).WriteStreamContext(ctx, stream)
+}
+
+// File-based convenience functions for backward compatibility. Each closes its file
+// when the stream ends; use OpenCSVFile and friends to close it earlier. The StreamTo*File
+// writers create the file up front and write as records arrive; pass WithAtomicWrite so
+// a stream that fails part way leaves no partial file behind.
 func CSVToStreamFromFile(filename string) (Stream[Record], error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open CSV file %s: %w", filename, err)
-	}
-	return CSVToStream(file), nil
+	stream, _, err := openFileStream(filename, "CSV", CSVToStream)
+	return stream, err
 }
 
 func TSVToStreamFromFile(filename string) (Stream[Record], error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open TSV file %s: %w", filename, err)
-	}
-	return TSVToStream(file), nil
+	stream, _, err := openFileStream(filename, "TSV", TSVToStream)
+	return stream, err
 }
 
 // FastTSVToStreamFromFile reads TSV file using fast string splitting
 func FastTSVToStreamFromFile(filename string) (Stream[Record], error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open TSV file %s: %w", filename, err)
-	}
-	return FastTSVToStream(file), nil
+	stream, _, err := openFileStream(filename, "TSV", FastTSVToStream)
+	return stream, err
 }
 
 // FastDelimitedToStreamFromFile reads delimited file with custom separator
 func FastDelimitedToStreamFromFile(filename string, separator string) (Stream[Record], error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
-	}
-	return FastTSVToStreamWithSeparator(file, separator), nil
+	stream, _, err := openFileStream(filename, "delimited", func(reader io.Reader) Stream[Record] {
+		return FastTSVToStreamWithSeparator(reader, separator)
+	})
+	return stream, err
 }
 
-func StreamToCSVFile(stream Stream[Record], filename string) error {
-	file, err := os.Create(filename)
+func StreamToCSVFile(stream Stream[Record], filename string, options ...FileWriteOption) error {
+	file, err := createOutputFile(filename, "CSV", options)
 	if err != nil {
-		return fmt.Errorf("failed to create CSV file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
-	return StreamToCSV(stream, file)
+	return file.finish(StreamToCSV(stream, file))
 }
 
-func StreamToTSVFile(stream Stream[Record], filename string) error {
-	file, err := os.Create(filename)
+func StreamToTSVFile(stream Stream[Record], filename string, options ...FileWriteOption) error {
+	file, err := createOutputFile(filename, "TSV", options)
 	if err != nil {
-		return fmt.Errorf("failed to create TSV file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
-	return StreamToTSV(stream, file)
+	return file.finish(StreamToTSV(stream, file))
 }
 
 func JSONToStreamFromFile(filename string) (Stream[Record], error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open JSON file %s: %w", filename, err)
-	}
-	return JSONToStream(file), nil
+	stream, _, err := openFileStream(filename, "JSON", JSONToStream)
+	return stream, err
 }
 
-func StreamToJSONFile(stream Stream[Record], filename string) error {
-	file, err := os.Create(filename)
+func StreamToJSONFile(stream Stream[Record], filename string, options ...FileWriteOption) error {
+	file, err := createOutputFile(filename, "JSON", options)
 	if err != nil {
-		return fmt.Errorf("failed to create JSON file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
-	return StreamToJSON(stream, file)
+	return file.finish(StreamToJSON(stream, file))
 }
 
 // ProtobufToStream reads protobuf from a reader and returns a Record stream  
@@ -1493,11 +3363,10 @@ func StreamToProtobuf(stream Stream[Record], writer io.Writer, messageDesc proto
 }
 
 func ProtobufToStreamFromFile(filename string, messageDesc protoreflect.MessageDescriptor) (Stream[Record], error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open protobuf file %s: %w", filename, err)
-	}
-	return ProtobufToStream(file, messageDesc), nil
+	stream, _, err := openFileStream(filename, "protobuf", func(reader io.Reader) Stream[Record] {
+		return ProtobufToStream(reader, messageDesc)
+	})
+	return stream, err
 }
 
 func StreamToProtobufFile(stream Stream[Record], filename string, messageDesc protoreflect.MessageDescriptor) error {
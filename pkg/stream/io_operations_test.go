@@ -2,9 +2,22 @@ package stream
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -401,6 +414,140 @@ func TestStreamToTSV(t *testing.T) {
 	})
 }
 
+// TestCSVSinkHeaderPolicies tests header ordering and schema strictness
+func TestCSVSinkHeaderPolicies(t *testing.T) {
+	records := func() []Record {
+		return []Record{
+			{"name": "Alice", "city": "NYC", "age": int64(30)},
+			{"name": "Bob", "age": int64(25)},                            // missing city
+			{"name": "Carol", "city": "SF", "age": int64(41), "x": true}, // extra field
+		}
+	}
+
+	t.Run("SortedByDefault", func(t *testing.T) {
+		var buffer bytes.Buffer
+		sink := NewCSVSink(&buffer)
+		if err := sink.WriteRecords(records()); err != nil {
+			t.Fatalf("Failed to write CSV: %v", err)
+		}
+
+		expected := "age,city,name\n30,NYC,Alice\n25,,Bob\n41,SF,Carol\n"
+		if buffer.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buffer.String())
+		}
+		if sink.DroppedFields() != 1 {
+			t.Errorf("Expected 1 dropped field, got %d", sink.DroppedFields())
+		}
+		if sink.MissingFields() != 1 {
+			t.Errorf("Expected 1 missing field, got %d", sink.MissingFields())
+		}
+	})
+
+	t.Run("HeaderOrder", func(t *testing.T) {
+		var buffer bytes.Buffer
+		var dropped, missing []string
+		sink := NewCSVSink(&buffer).
+			WithHeaderOrder([]string{"name", "x", "city"}).
+			WithDroppedFieldHandler(func(field string, record Record) {
+				dropped = append(dropped, fmt.Sprintf("%v.%s", record["name"], field))
+			}).
+			WithMissingFieldHandler(func(field string, record Record) {
+				missing = append(missing, fmt.Sprintf("%v.%s", record["name"], field))
+			})
+		if err := sink.WriteRecords(records()); err != nil {
+			t.Fatalf("Failed to write CSV: %v", err)
+		}
+
+		// The listed columns come first, x kept although the first record lacks it,
+		// then the first record's other fields
+		expected := "name,x,city,age\nAlice,,NYC,30\nBob,,,25\nCarol,true,SF,41\n"
+		if buffer.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buffer.String())
+		}
+		if len(dropped) != 0 {
+			t.Errorf("Expected no dropped fields, got %v", dropped)
+		}
+		if strings.Join(missing, " ") != "Alice.x Bob.x Bob.city" {
+			t.Errorf("Expected missing [Alice.x Bob.x Bob.city], got %v", missing)
+		}
+	})
+
+	t.Run("StrictSchemaExtraField", func(t *testing.T) {
+		var buffer bytes.Buffer
+		complete := []Record{
+			{"name": "Alice", "city": "NYC"},
+			{"name": "Carol", "city": "SF", "x": true},
+		}
+		err := NewCSVSink(&buffer).WithStrictSchema().WriteRecords(complete)
+		if err == nil || !strings.Contains(err.Error(), `"x"`) {
+			t.Fatalf("Expected error naming field x, got %v", err)
+		}
+
+		// Rows before the offending record are still written
+		expected := "city,name\nNYC,Alice\n"
+		if buffer.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buffer.String())
+		}
+	})
+
+	t.Run("StrictSchemaMissingField", func(t *testing.T) {
+		var buffer bytes.Buffer
+		err := NewCSVSink(&buffer).WithStrictSchema().WriteRecords(records())
+		if err == nil || !strings.Contains(err.Error(), `missing CSV column "city"`) {
+			t.Fatalf("Expected error naming missing column city, got %v", err)
+		}
+
+		expected := "age,city,name\n30,NYC,Alice\n"
+		if buffer.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buffer.String())
+		}
+	})
+
+	t.Run("StreamingWriterStrict", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer := NewStreamingCSVWriter(&buffer, nil).WithStrictSchema()
+
+		var err error
+		for _, record := range records() {
+			if err = writer.WriteRecord(record); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Fatal("Expected strict schema error")
+		}
+
+		expected := "age,city,name\n30,NYC,Alice\n"
+		if buffer.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buffer.String())
+		}
+	})
+
+	t.Run("StreamingWriterCountsDropped", func(t *testing.T) {
+		var buffer bytes.Buffer
+		var dropped []string
+		writer := NewStreamingCSVWriter(&buffer, []string{"name"}).
+			WithDroppedFieldHandler(func(field string, record Record) {
+				dropped = append(dropped, field)
+			})
+		for _, record := range records() {
+			if err := writer.WriteRecord(record); err != nil {
+				t.Fatalf("Failed to write record: %v", err)
+			}
+		}
+
+		if buffer.String() != "name\nAlice\nBob\nCarol\n" {
+			t.Errorf("Unexpected output %q", buffer.String())
+		}
+		if writer.DroppedFields() != 6 || len(dropped) != 6 {
+			t.Errorf("Expected 6 dropped fields, got %d (handler saw %d)", writer.DroppedFields(), len(dropped))
+		}
+		if writer.MissingFields() != 0 {
+			t.Errorf("Expected no missing fields, got %d", writer.MissingFields())
+		}
+	})
+}
+
 // TestNewStreamingCSVWriter tests streaming CSV writer
 func TestNewStreamingCSVWriter(t *testing.T) {
 	t.Run("StreamingWrite", func(t *testing.T) {
@@ -514,6 +661,186 @@ func TestJSONToStream(t *testing.T) {
 	})
 }
 
+// TestJSONSourceInvalidLines tests the JSON Lines error policy
+func TestJSONSourceInvalidLines(t *testing.T) {
+	mixed := `{"id": 1}
+
+{"id": 2, "truncated
+not json at all
+   
+{"id": 3}
+`
+
+	t.Run("FailFastByDefault", func(t *testing.T) {
+		records, err := Collect(NewJSONSource(strings.NewReader(mixed)).ToStream())
+		if err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("Expected parse error on line 3, got %v", err)
+		}
+		if len(records) != 1 {
+			t.Errorf("Expected 1 record before the error, got %d", len(records))
+		}
+	})
+
+	t.Run("SkipInvalidLines", func(t *testing.T) {
+		source := NewJSONSource(strings.NewReader(mixed)).SkipInvalidLines()
+		records, err := Collect(source.ToStream())
+		if err != nil {
+			t.Fatalf("Expected invalid lines to be skipped, got %v", err)
+		}
+		if len(records) != 2 || records[0]["id"] != int64(1) || records[1]["id"] != int64(3) {
+			t.Errorf("Expected ids 1 and 3, got %v", records)
+		}
+		if source.SkippedLines() != 2 {
+			t.Errorf("Expected 2 skipped lines, got %d", source.SkippedLines())
+		}
+	})
+
+	t.Run("HandlerRoutesBadLines", func(t *testing.T) {
+		var badLines []int
+		source := NewJSONSource(strings.NewReader(mixed)).WithLineErrorHandler(
+			func(lineNumber int, line string, err error) error {
+				badLines = append(badLines, lineNumber)
+				return nil
+			})
+
+		records, err := Collect(source.ToStream())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(records) != 2 || len(badLines) != 2 || badLines[0] != 3 || badLines[1] != 4 {
+			t.Errorf("Expected bad lines [3 4], got %v (records %v)", badLines, records)
+		}
+	})
+
+	t.Run("HandlerCanAbort", func(t *testing.T) {
+		abort := fmt.Errorf("too many bad lines")
+		source := NewJSONSource(strings.NewReader(mixed)).WithLineErrorHandler(
+			func(int, string, error) error { return abort })
+
+		if _, err := Collect(source.ToStream()); err != abort {
+			t.Errorf("Expected handler error, got %v", err)
+		}
+	})
+
+	t.Run("ManyBlankLines", func(t *testing.T) {
+		input := strings.Repeat("\n", 100000) + `{"id": 1}` + "\n"
+		records, err := Collect(NewJSONSource(strings.NewReader(input)).ToStream())
+		if err != nil || len(records) != 1 {
+			t.Errorf("Expected 1 record after blank lines, got %v (%v)", records, err)
+		}
+	})
+}
+
+// generatedJSONReader produces a JSON array of n objects as it is read, counting the
+// bytes handed out, so a test can tell how much of the input a source has consumed
+type generatedJSONReader struct {
+	n, next int
+	pending []byte
+	read    int
+}
+
+func (r *generatedJSONReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		switch {
+		case r.next > r.n:
+			return 0, io.EOF
+		case r.next == r.n:
+			r.pending = []byte("]")
+		case r.next == 0:
+			r.pending = fmt.Appendf(nil, `[{"id": 0, "name": "item-0", "tags": ["a", "b"]}`)
+		default:
+			r.pending = fmt.Appendf(nil, `, {"id": %d, "name": "item-%d", "tags": ["a", "b"]}`, r.next, r.next)
+		}
+		r.next++
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.read += n
+	return n, nil
+}
+
+// TestJSONArrayStreaming tests that JSONArray documents are decoded an element at a time
+func TestJSONArrayStreaming(t *testing.T) {
+	t.Run("LargeArray", func(t *testing.T) {
+		const n = 100000
+		reader := &generatedJSONReader{n: n}
+		stream := NewJSONSource(reader).WithFormat(JSONArray).ToStream()
+
+		first, err := stream()
+		if err != nil || first["id"] != int64(0) {
+			t.Fatalf("Expected the first record, got %v (%v)", first, err)
+		}
+		if reader.read > 64*1024 {
+			t.Errorf("Expected the first record after reading a little of the input, read %d bytes", reader.read)
+		}
+
+		count := 1
+		var previous int
+		for {
+			record, err := stream()
+			if err == EOS {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed after %d records: %v", count, err)
+			}
+			if record["id"] != int64(count) {
+				t.Fatalf("Expected id %d, got %v", count, record["id"])
+			}
+			count++
+			if count%10000 == 0 {
+				// The input is consumed as records are, never far ahead of them
+				if reader.read-previous > 2*1024*1024 {
+					t.Fatalf("Expected reading to keep pace with records, read %d bytes by record %d", reader.read, count)
+				}
+				previous = reader.read
+			}
+		}
+		if count != n {
+			t.Errorf("Expected %d records, got %d", n, count)
+		}
+	})
+
+	t.Run("ArrayPath", func(t *testing.T) {
+		data := `{"meta": {"next": null, "skip": [1, {"items": []}]}, "count": 2,
+			"data": {"total": 2, "items": [{"id": 1, "tags": ["x"]}, {"id": 2, "nested": {"ok": true}}]},
+			"trailer": "not read"}`
+		records, err := Collect(NewJSONSource(strings.NewReader(data)).WithArrayPath("data.items").ToStream())
+		if err != nil || len(records) != 2 {
+			t.Fatalf("Expected 2 records, got %v (%v)", records, err)
+		}
+		if records[0]["id"] != int64(1) || records[1]["nested"].(Record)["ok"] != true {
+			t.Errorf("Expected the items under data.items, got %v", records)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name, data, path, message string
+		}{
+			{"NotAnArray", `{"id": 1}`, "", "expected an array at the top level"},
+			{"TrailingGarbage", `[{"id": 1}] {"id": 2}`, "", "after the closing ]"},
+			{"NotAnObject", `[{"id": 1}, 2]`, "", "element 1"},
+			{"Truncated", `[{"id": 1}, {"id": 2`, "", "element 1"},
+			{"MissingPath", `{"data": {"rows": []}}`, "data.items", `path "data.items" not found`},
+			{"PathNotArray", `{"data": {"items": null}}`, "data.items", "expected an array at data.items"},
+			{"PathThroughArray", `{"data": [1]}`, "data.items", "expected an object at data"},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				source := NewJSONSource(strings.NewReader(test.data)).WithFormat(JSONArray)
+				if test.path != "" {
+					source.WithArrayPath(test.path)
+				}
+				records, err := Collect(source.ToStream())
+				if err == nil || !strings.Contains(err.Error(), test.message) {
+					t.Errorf("Expected an error mentioning %q, got %v (after %v)", test.message, err, records)
+				}
+			})
+		}
+	})
+}
+
 // TestNewJSONSink tests JSON sink creation
 func TestNewJSONSink(t *testing.T) {
 	t.Run("BasicJSONSink", func(t *testing.T) {
@@ -556,6 +883,73 @@ func TestJSONSinkWithPrettyPrint(t *testing.T) {
 			t.Error("Expected Pretty to be true")
 		}
 	})
+
+	t.Run("JSONLinesIndented", func(t *testing.T) {
+		var buffer bytes.Buffer
+		records := []Record{{"id": int64(1), "tags": FromSlice([]string{"a"})}, {"id": int64(2)}}
+		if err := NewJSONSink(&buffer).WithPrettyPrint().WriteStream(FromRecordsUnsafe(records)); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+		expected := "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}\n{\n  \"id\": 2\n}\n"
+		if buffer.String() != expected {
+			t.Errorf("Expected indented records, got %q", buffer.String())
+		}
+	})
+}
+
+// TestJSONSinkKeyOrder tests the exact output of compact, pretty, sorted and field-ordered JSON
+func TestJSONSinkKeyOrder(t *testing.T) {
+	record := func() Record {
+		address := NewRecord().Ordered().String("zip", "10001").String("city", "NYC").Build()
+		return NewRecord().Ordered().
+			String("name", "Alice").
+			Int("id", 7).
+			Record("address", address).
+			Stream("orders", FromSlice([]Record{{"total": 9.5, "sku": "x"}})).
+			Build()
+	}
+	tests := []struct {
+		name     string
+		sink     func(*bytes.Buffer) *JSONSink
+		expected string
+	}{
+		{"Compact", func(b *bytes.Buffer) *JSONSink { return NewJSONSink(b) },
+			`{"name":"Alice","id":7,"address":{"zip":"10001","city":"NYC"},"orders":[{"sku":"x","total":9.5}]}` + "\n"},
+		{"Sorted", func(b *bytes.Buffer) *JSONSink { return NewJSONSink(b).WithSortedKeys() },
+			`{"address":{"city":"NYC","zip":"10001"},"id":7,"name":"Alice","orders":[{"sku":"x","total":9.5}]}` + "\n"},
+		{"FieldOrder", func(b *bytes.Buffer) *JSONSink { return NewJSONSink(b).WithFieldOrder([]string{"id", "missing", "orders"}) },
+			`{"id":7,"orders":[{"sku":"x","total":9.5}],"name":"Alice","address":{"zip":"10001","city":"NYC"}}` + "\n"},
+		{"FieldOrderSorted", func(b *bytes.Buffer) *JSONSink {
+			return NewJSONSink(b).WithFieldOrder([]string{"id"}).WithSortedKeys()
+		}, `{"id":7,"address":{"city":"NYC","zip":"10001"},"name":"Alice","orders":[{"sku":"x","total":9.5}]}` + "\n"},
+		{"Pretty", func(b *bytes.Buffer) *JSONSink { return NewJSONSink(b).WithSortedKeys().WithPrettyPrint() },
+			"{\n  \"address\": {\n    \"city\": \"NYC\",\n    \"zip\": \"10001\"\n  },\n  \"id\": 7,\n  \"name\": \"Alice\",\n" +
+				"  \"orders\": [\n    {\n      \"sku\": \"x\",\n      \"total\": 9.5\n    }\n  ]\n}\n"},
+		{"Array", func(b *bytes.Buffer) *JSONSink { return NewJSONSink(b).WithFormat(JSONArray).WithFieldOrder([]string{"id"}) },
+			`[{"id":7,"name":"Alice","address":{"zip":"10001","city":"NYC"},"orders":[{"sku":"x","total":9.5}]}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := tt.sink(&buffer).WriteStream(FromRecordsUnsafe([]Record{record()})); err != nil {
+				t.Fatalf("Failed to write JSON: %v", err)
+			}
+			if buffer.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buffer.String())
+			}
+		})
+	}
+
+	t.Run("PlainRecord", func(t *testing.T) {
+		var buffer bytes.Buffer
+		plain := Record{"b": int64(2), "a": int64(1), "c": int64(3)}
+		if err := NewJSONSink(&buffer).WithFieldOrder([]string{"c"}).WriteStream(FromRecordsUnsafe([]Record{plain})); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+		if output := buffer.String(); output != `{"c":3,"a":1,"b":2}`+"\n" {
+			t.Errorf("Expected c first and the rest sorted, got %q", output)
+		}
+	})
 }
 
 // TestStreamToJSON tests stream to JSON conversion
@@ -624,119 +1018,898 @@ func TestStreamToJSON(t *testing.T) {
 	})
 }
 
-// TestIsStreamType tests stream type detection
-func TestIsStreamType(t *testing.T) {
-	t.Run("ValidStreamType", func(t *testing.T) {
-		stream := FromSlice([]int64{1, 2, 3})
-		
-		if !IsStreamType(stream) {
-			t.Error("Expected IsStreamType to return true for stream")
+// TestJSONSinkOptions tests nested keys, stream field policies and numeric output
+func TestJSONSinkOptions(t *testing.T) {
+	t.Run("NumericTypes", func(t *testing.T) {
+		var buffer bytes.Buffer
+		record := Record{"small": int32(-7), "big": uint64(18446744073709551615), "tiny": uint8(3), "ratio": float32(0.5)}
+		if err := NewJSONSink(&buffer).WriteStream(FromRecordsUnsafe([]Record{record})); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+
+		output := strings.TrimSpace(buffer.String())
+		if output != `{"big":18446744073709551615,"ratio":0.5,"small":-7,"tiny":3}` {
+			t.Errorf("Expected plain JSON numbers, got %s", output)
 		}
 	})
-	
-	t.Run("InvalidStreamType", func(t *testing.T) {
-		notAStream := "not a stream"
-		
-		if IsStreamType(notAStream) {
-			t.Error("Expected IsStreamType to return false for non-stream")
+
+	t.Run("NestedKeys", func(t *testing.T) {
+		var buffer bytes.Buffer
+		record := Record{"user.name": "Alice", "user.address.city": "NYC", "id": int64(1)}
+		if err := NewJSONSink(&buffer).WithNestedKeys(".").WriteStream(FromRecordsUnsafe([]Record{record})); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+
+		output := strings.TrimSpace(buffer.String())
+		if output != `{"id":1,"user":{"address":{"city":"NYC"},"name":"Alice"}}` {
+			t.Errorf("Expected nested objects, got %s", output)
 		}
 	})
-}
 
-// TestParseCSVValue tests CSV value parsing
-func TestParseCSVValue(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected any
-	}{
-		{"", ""},
-		{"123", int64(123)},
-		{"123.45", 123.45},
-		{"true", true},
-		{"false", false},
-		{"yes", true},
-		{"no", false},
-		{"hello", "hello"},
-		{"  spaced  ", "spaced"},
-	}
-	
-	for _, test := range tests {
-		t.Run(test.input, func(t *testing.T) {
-			result := parseCSVValue(test.input)
-			if result != test.expected {
-				t.Errorf("parseCSVValue(%q) = %v (%T), expected %v (%T)", 
-					test.input, result, result, test.expected, test.expected)
-			}
+	t.Run("MaxStreamElements", func(t *testing.T) {
+		var buffer bytes.Buffer
+		counter := int64(0)
+		infinite := Stream[int64](func() (int64, error) {
+			counter++
+			return counter, nil
 		})
-	}
+		record := Record{"id": int64(1), "readings": infinite}
+		if err := NewJSONSink(&buffer).WithMaxStreamElements(3).WriteStream(FromRecordsUnsafe([]Record{record})); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+
+		output := strings.TrimSpace(buffer.String())
+		if output != `{"_truncated":true,"id":1,"readings":[1,2,3]}` {
+			t.Errorf("Expected 3 readings and a truncation marker, got %s", output)
+		}
+
+		buffer.Reset()
+		record = Record{"tags": FromSliceAny([]any{"a", "b"})}
+		if err := NewJSONSink(&buffer).WithMaxStreamElements(2).WriteStream(FromRecordsUnsafe([]Record{record})); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+		if output := strings.TrimSpace(buffer.String()); output != `{"tags":["a","b"]}` {
+			t.Errorf("Expected no truncation marker at exactly the limit, got %s", output)
+		}
+	})
+
+	t.Run("StreamPolicy", func(t *testing.T) {
+		record := Record{"id": int64(1), "tags": FromSliceAny([]any{"a"})}
+
+		var buffer bytes.Buffer
+		if err := NewJSONSink(&buffer).WithStreamPolicy(StreamFieldSkip).WriteStream(FromRecordsUnsafe([]Record{record})); err != nil {
+			t.Fatalf("Failed to write JSON: %v", err)
+		}
+		if output := strings.TrimSpace(buffer.String()); output != `{"id":1}` {
+			t.Errorf("Expected the stream field skipped, got %s", output)
+		}
+
+		nested := Record{"order": Record{"items": FromSliceAny([]any{1})}}
+		err := NewJSONSink(&bytes.Buffer{}).WithStreamPolicy(StreamFieldError).WriteStream(FromRecordsUnsafe([]Record{nested}))
+		if err == nil || !strings.Contains(err.Error(), `"order.items"`) {
+			t.Errorf("Expected an error naming order.items, got %v", err)
+		}
+	})
 }
 
-// TestFormatCSVValue tests CSV value formatting
-func TestFormatCSVValue(t *testing.T) {
-	tests := []struct {
-		input    any
-		expected string
-	}{
-		{"hello", "hello"},
-		{int64(123), "123"},
-		{123.45, "123.45"},
-		{true, "true"},
-		{false, "false"},
-		{nil, ""},
-		{time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), "2023-01-01T12:00:00Z"},
+// TestWriteStreamContext tests cancelling sinks part way through a large stream
+func TestWriteStreamContext(t *testing.T) {
+	const total, cancelAt = 100000, 1000
+
+	// cancelling makes a 100k-record stream that cancels its context after cancelAt records
+	cancelling := func() (context.Context, Stream[Record]) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		i := int64(0)
+		return ctx, func() (Record, error) {
+			if i == total {
+				return nil, EOS
+			}
+			i++
+			if i == cancelAt {
+				cancel()
+			}
+			return Record{"id": i}, nil
+		}
 	}
-	
-	for _, test := range tests {
-		t.Run("", func(t *testing.T) {
-			result := formatCSVValue(test.input)
-			if result != test.expected {
-				t.Errorf("formatCSVValue(%v) = %q, expected %q", test.input, result, test.expected)
+	// checkPrefix asserts that records are ids 1..n for some n no more than cancelAt
+	checkPrefix := func(t *testing.T, records []Record) {
+		t.Helper()
+		if len(records) == 0 || len(records) > cancelAt {
+			t.Fatalf("Expected a prefix of at most %d records, got %d", cancelAt, len(records))
+		}
+		for i, record := range records {
+			if id, _ := convertToInt64(record["id"]); id != int64(i+1) {
+				t.Fatalf("Expected record %d to have id %d, got %v", i, i+1, record["id"])
 			}
-		})
+		}
 	}
-}
 
-// TestFileOperations tests file-based operations (using temp files)
-func TestFileOperations(t *testing.T) {
-	t.Run("CSVFileOperations", func(t *testing.T) {
-		// Create temporary file
-		tmpfile, err := os.CreateTemp("", "test*.csv")
-		if err != nil {
-			t.Fatalf("Failed to create temp file: %v", err)
+	t.Run("CSV", func(t *testing.T) {
+		var buffer bytes.Buffer
+		ctx, records := cancelling()
+		start := time.Now()
+		if err := StreamToCSVContext(ctx, records, &buffer); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
 		}
-		defer os.Remove(tmpfile.Name())
-		defer tmpfile.Close()
-		
-		// Write test data
-		csvData := "name,age\nAlice,30\nBob,25"
-		if _, err := tmpfile.WriteString(csvData); err != nil {
-			t.Fatalf("Failed to write test data: %v", err)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected a prompt return, took %v", elapsed)
 		}
-		tmpfile.Close()
-		
-		// Test reading from file
-		stream, err := CSVToStreamFromFile(tmpfile.Name())
+		written, err := Collect(CSVToStream(&buffer))
 		if err != nil {
-			t.Fatalf("Failed to create stream from CSV file: %v", err)
+			t.Fatalf("Failed to read the partial CSV: %v", err)
 		}
-		
-		records, err := Collect(stream)
+		checkPrefix(t, written)
+	})
+
+	t.Run("JSONLines", func(t *testing.T) {
+		var buffer bytes.Buffer
+		ctx, records := cancelling()
+		if err := StreamToJSONContext(ctx, records, &buffer); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		written, err := Collect(JSONToStream(&buffer))
 		if err != nil {
-			t.Fatalf("Failed to collect records from file stream: %v", err)
+			t.Fatalf("Failed to read the partial JSON: %v", err)
 		}
-		
-		if len(records) != 2 {
-			t.Fatalf("Expected 2 records from file, got %d", len(records))
+		checkPrefix(t, written)
+	})
+
+	t.Run("JSONArray", func(t *testing.T) {
+		var buffer bytes.Buffer
+		ctx, records := cancelling()
+		if err := NewJSONSink(&buffer).WithFormat(JSONArray).WriteStreamContext(ctx, records); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
 		}
-		
-		if records[0]["name"] != "Alice" {
-			t.Errorf("Expected name 'Alice' from file, got %v", records[0]["name"])
+		written, err := Collect(NewJSONSource(&buffer).WithFormat(JSONArray).ToStream())
+		if err != nil {
+			t.Fatalf("Expected a complete array of the records so far: %v", err)
 		}
+		checkPrefix(t, written)
 	})
-	
-	t.Run("JSONFileOperations", func(t *testing.T) {
-		// Create temporary file
-		tmpfile, err := os.CreateTemp("", "test*.json")
+
+	t.Run("Arrow", func(t *testing.T) {
+		var buffer bytes.Buffer
+		ctx, records := cancelling()
+		if err := NewArrowSink(&buffer, WithArrowBatchSize(300)).WriteStreamContext(ctx, records); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		written, err := Collect(NewArrowSource(&buffer).ToStream())
+		if err != nil {
+			t.Fatalf("Expected a readable Arrow stream: %v", err)
+		}
+		checkPrefix(t, written)
+		if len(written) < cancelAt {
+			t.Errorf("Expected the partial batch to be flushed, got %d records", len(written))
+		}
+	})
+
+	t.Run("AlreadyCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var buffer bytes.Buffer
+		if err := NewCSVSink(&buffer).WriteStreamContext(ctx, FromRecordsUnsafe([]Record{{"id": int64(1)}})); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if buffer.Len() != 0 {
+			t.Errorf("Expected nothing written, got %q", buffer.String())
+		}
+	})
+}
+
+// TestProtobufDescriptorLoading tests loading message descriptors from descriptor sets
+func TestProtobufDescriptorLoading(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		personDesc, err := LoadMessageDescriptorFromDescriptorSet("testdata/person.pb", "streamv2.test.Person")
+		if err != nil {
+			t.Fatalf("Failed to load descriptor: %v", err)
+		}
+
+		dataFile := filepath.Join(t.TempDir(), "people.bin")
+		file, err := os.Create(dataFile)
+		if err != nil {
+			t.Fatalf("Failed to create data file: %v", err)
+		}
+		records := []Record{
+			{"name": "Alice", "age": int64(30), "tags": FromSliceAny([]any{"admin", "ops"}), "address": Record{"city": "NYC"}},
+			{"name": "Bob", "age": int64(25)},
+		}
+		if err := NewProtobufSink(file, personDesc).WriteStream(FromRecordsUnsafe(records)); err != nil {
+			t.Fatalf("Failed to write protobuf: %v", err)
+		}
+		file.Close()
+
+		source, err := NewProtobufSourceFromFiles(dataFile, "testdata/person.pb", "streamv2.test.Person")
+		if err != nil {
+			t.Fatalf("Failed to open protobuf source: %v", err)
+		}
+		results, err := Collect(source.ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read protobuf: %v", err)
+		}
+
+		if len(results) != 2 || results[0]["name"] != "Alice" || results[0]["age"] != int64(30) || results[1]["name"] != "Bob" {
+			t.Fatalf("Expected Alice and Bob back, got %v", results)
+		}
+		if address, ok := results[0]["address"].(Record); !ok || address["city"] != "NYC" {
+			t.Errorf("Expected nested address, got %v", results[0]["address"])
+		}
+		tags, ok := results[0]["tags"].(Stream[any])
+		if !ok {
+			t.Fatalf("Expected tags stream, got %T", results[0]["tags"])
+		}
+		if values, _ := Collect(tags); len(values) != 2 || values[1] != "ops" {
+			t.Errorf("Expected tags [admin ops], got %v", values)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := LoadMessageDescriptorFromDescriptorSet("testdata/person.pb", "streamv2.test.Missing"); err == nil || !strings.Contains(err.Error(), "streamv2.test.Missing") {
+			t.Errorf("Expected unknown message error, got %v", err)
+		}
+		if _, err := LoadMessageDescriptorFromDescriptorSet("testdata/person.pb", "streamv2.test.Person.name"); err == nil || !strings.Contains(err.Error(), "not a message") {
+			t.Errorf("Expected not-a-message error, got %v", err)
+		}
+		if _, err := LoadMessageDescriptorFromDescriptorSet("testdata/person.proto", "streamv2.test.Person"); err == nil {
+			t.Error("Expected parse error for a non-descriptor-set file")
+		}
+	})
+
+	t.Run("FromProto", func(t *testing.T) {
+		if _, err := exec.LookPath("protoc"); err != nil {
+			t.Skip("protoc not installed")
+		}
+		desc, err := LoadMessageDescriptorFromProto("testdata/person.proto", "streamv2.test.Person")
+		if err != nil {
+			t.Fatalf("Failed to compile proto: %v", err)
+		}
+		if desc.Fields().ByName("address") == nil {
+			t.Errorf("Expected address field in %v", desc.FullName())
+		}
+	})
+}
+
+// TestProtobufUnknownFields tests the unknown field policies and typed repeated values of ProtobufSink
+func TestProtobufUnknownFields(t *testing.T) {
+	personDesc, err := LoadMessageDescriptorFromDescriptorSet("testdata/person.pb", "streamv2.test.Person")
+	if err != nil {
+		t.Fatalf("Failed to load descriptor: %v", err)
+	}
+	records := func() Stream[Record] {
+		return FromRecordsUnsafe([]Record{
+			{"name": "Alice", "age": int64(30)},
+			{"name": "Bob", "agee": int64(25), "address": Record{"city": "NYC", "zip": "10001"}},
+		})
+	}
+	readBack := func(t *testing.T, data *bytes.Buffer) []Record {
+		t.Helper()
+		results, err := Collect(NewProtobufSource(data, personDesc).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read protobuf: %v", err)
+		}
+		return results
+	}
+
+	t.Run("Ignore", func(t *testing.T) {
+		var buffer bytes.Buffer
+		sink := NewProtobufSink(&buffer, personDesc)
+		if err := sink.WriteStream(records()); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if sink.UnknownFields() != 2 {
+			t.Errorf("Expected 2 unknown fields counted, got %d", sink.UnknownFields())
+		}
+		if results := readBack(t, &buffer); len(results) != 2 || results[1]["name"] != "Bob" {
+			t.Errorf("Expected both records written, got %v", results)
+		}
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		var buffer bytes.Buffer
+		var warned []string
+		sink := NewProtobufSink(&buffer, personDesc).WithUnknownFieldPolicy(WarnUnknownFields(func(field string, record Record) {
+			warned = append(warned, fmt.Sprintf("%s@%v", field, record["name"]))
+		}))
+		if err := sink.WriteStream(records()); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if expected := []string{"address.zip@Bob", "agee@Bob"}; !reflect.DeepEqual(warned, expected) {
+			t.Errorf("Expected warnings %v, got %v", expected, warned)
+		}
+		if results := readBack(t, &buffer); len(results) != 2 {
+			t.Errorf("Expected both records written, got %v", results)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		for _, format := range []ProtobufFormat{ProtobufDelimited, ProtobufJSON} {
+			var buffer bytes.Buffer
+			err := NewProtobufSink(&buffer, personDesc).WithFormat(format).WithUnknownFieldPolicy(FailOnUnknownFields()).WriteStream(records())
+			if !errors.Is(err, ErrUnknownProtobufField) {
+				t.Fatalf("Expected ErrUnknownProtobufField, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "record 1") || !strings.Contains(err.Error(), `"address.zip"`) {
+				t.Errorf("Expected the record index and field path, got %v", err)
+			}
+		}
+	})
+
+	t.Run("ConversionErrorIndex", func(t *testing.T) {
+		var buffer bytes.Buffer
+		bad := []Record{{"name": "Alice"}, {"name": "Bob"}, {"name": "Carol", "age": "old"}}
+		err := NewProtobufSink(&buffer, personDesc).WriteStream(FromRecordsUnsafe(bad))
+		if err == nil || !strings.Contains(err.Error(), "record 2") || !strings.Contains(err.Error(), "'age'") {
+			t.Errorf("Expected an error naming record 2 and field age, got %v", err)
+		}
+	})
+
+	t.Run("TypedRepeated", func(t *testing.T) {
+		var buffer bytes.Buffer
+		typed := []Record{
+			{"name": "Alice", "tags": []string{"admin", "ops"}},
+			{"name": "Bob", "tags": FromSlice([]string{"dev"})},
+			{"name": "Carol", "tags": [2]string{"a", "b"}},
+		}
+		if err := NewProtobufSink(&buffer, personDesc).WriteStream(FromRecordsUnsafe(typed)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		results := readBack(t, &buffer)
+		expected := [][]any{{"admin", "ops"}, {"dev"}, {"a", "b"}}
+		for i, result := range results {
+			tags, ok := result["tags"].(Stream[any])
+			if !ok {
+				t.Fatalf("Expected a tags stream, got %T", result["tags"])
+			}
+			if values, _ := Collect(tags); !reflect.DeepEqual(values, expected[i]) {
+				t.Errorf("Expected tags %v, got %v", expected[i], values)
+			}
+		}
+
+		err := NewProtobufSink(&buffer, personDesc).WriteStream(FromRecordsUnsafe([]Record{{"tags": int64(1)}}))
+		if err == nil || !strings.Contains(err.Error(), "repeated") {
+			t.Errorf("Expected a repeated field conversion error, got %v", err)
+		}
+	})
+}
+
+// readCounter counts the Read calls made on a reader
+type readCounter struct {
+	io.Reader
+	reads int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(p)
+}
+
+// TestProtobufDelimitedReading tests buffered reading and the length prefix checks of ProtobufSource
+func TestProtobufDelimitedReading(t *testing.T) {
+	personDesc, err := LoadMessageDescriptorFromDescriptorSet("testdata/person.pb", "streamv2.test.Person")
+	if err != nil {
+		t.Fatalf("Failed to load descriptor: %v", err)
+	}
+	var valid bytes.Buffer
+	people := Map(func(i int64) Record { return Record{"name": "p" + strconv.FormatInt(i, 10), "age": i} })(Range(0, 1000, 1))
+	if err := NewProtobufSink(&valid, personDesc).WriteStream(people); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	t.Run("Buffered", func(t *testing.T) {
+		reader := &readCounter{Reader: bytes.NewReader(valid.Bytes())}
+		count, err := Count(NewProtobufSource(reader, personDesc).ToStream())
+		if err != nil || count != 1000 {
+			t.Fatalf("Expected 1000 records, got %d (%v)", count, err)
+		}
+		if reader.reads > 10 {
+			t.Errorf("Expected a few buffered reads for %d bytes, got %d", valid.Len(), reader.reads)
+		}
+	})
+
+	first := valid.Bytes()[:1+int(valid.Bytes()[0])] // One whole message, prefix included
+	tests := []struct {
+		name   string
+		data   []byte
+		source func(*ProtobufSource) *ProtobufSource
+		is     error
+		text   string
+	}{
+		{"HugePrefix", binary.AppendUvarint(nil, 1<<40), nil, ErrProtobufMessageTooLarge, "message 0"},
+		{"OverLimit", valid.Bytes(), func(ps *ProtobufSource) *ProtobufSource { return ps.WithMaxMessageSize(4) }, ErrProtobufMessageTooLarge, "limit is 4"},
+		{"PartialPrefix", append(append([]byte(nil), first...), 0x80), nil, io.ErrUnexpectedEOF, "length of message 1"},
+		{"TruncatedMessage", append(binary.AppendUvarint(nil, 10), 1, 2, 3), nil, io.ErrUnexpectedEOF, "message 0"},
+		{"MissingMessage", binary.AppendUvarint(nil, 10), nil, io.ErrUnexpectedEOF, "message 0"},
+		{"Overflow", bytes.Repeat([]byte{0xFF}, 11), nil, nil, "overflows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := NewProtobufSource(bytes.NewReader(tt.data), personDesc)
+			if tt.source != nil {
+				source = tt.source(source)
+			}
+			stream := source.ToStream()
+			var err error
+			for err == nil {
+				_, err = stream()
+			}
+			if err == EOS || (tt.is != nil && !errors.Is(err, tt.is)) || !strings.Contains(err.Error(), tt.text) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.text, err)
+			}
+			if _, again := stream(); again != err {
+				t.Errorf("Expected the error to be sticky, got %v", again)
+			}
+		})
+	}
+}
+
+// collectStreamFields replaces the stream fields of a record read back from a source,
+// and of its nested records, with the slices they hold
+func collectStreamFields(value any) any {
+	switch v := value.(type) {
+	case Record:
+		collected := make(Record, len(v))
+		for key, field := range v {
+			collected[key] = collectStreamFields(field)
+		}
+		return collected
+	case Stream[any]:
+		items, _ := Collect(v)
+		for i, item := range items {
+			items[i] = collectStreamFields(item)
+		}
+		return items
+	}
+	return value
+}
+
+// TestProtobufWellKnownTypes tests that enums and well-known types round-trip through ProtobufSink and ProtobufSource
+func TestProtobufWellKnownTypes(t *testing.T) {
+	eventDesc, err := LoadMessageDescriptorFromDescriptorSet("testdata/events.pb", "streamv2.test.Event")
+	if err != nil {
+		t.Fatalf("Failed to load descriptor: %v", err)
+	}
+	at := time.Date(2024, 3, 4, 5, 6, 7, 890000000, time.UTC)
+	records := func() []Record {
+		return []Record{
+			{
+				"id":      "e1",
+				"level":   "WARN",
+				"at":      at,
+				"took":    1500 * time.Millisecond,
+				"retries": int64(0),
+				"note":    "retried",
+				"acked":   true,
+				"score":   0.25,
+				"attributes": Record{
+					"region": "eu",
+					"count":  int64(2),
+					"ok":     true,
+					"tags":   FromSliceAny([]any{"a", 1.5}),
+					"inner":  Record{"missing": nil},
+				},
+				"history": []string{"INFO", "ERROR"},
+				"seen":    []time.Time{at, at.Add(time.Hour)},
+			},
+			{"id": "e2", "level": int64(3), "history": FromSlice([]int64{1, 7}), "at": "2024-01-02T03:04:05Z", "took": "1m30s"},
+		}
+	}
+	expected := []any{
+		Record{
+			"id":      "e1",
+			"level":   "WARN",
+			"at":      at,
+			"took":    1500 * time.Millisecond,
+			"retries": int64(0),
+			"note":    "retried",
+			"acked":   true,
+			"score":   0.25,
+			"attributes": Record{
+				"region": "eu",
+				"count":  2.0,
+				"ok":     true,
+				"tags":   []any{"a", 1.5},
+				"inner":  Record{"missing": nil},
+			},
+			"history": []any{"INFO", "ERROR"},
+			"seen":    []any{at, at.Add(time.Hour)},
+		},
+		Record{
+			"id":      "e2",
+			"level":   "ERROR",
+			"history": []any{"INFO", int64(7)},
+			"at":      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			"took":    90 * time.Second,
+		},
+	}
+
+	for name, format := range map[string]ProtobufFormat{"Delimited": ProtobufDelimited, "JSON": ProtobufJSON} {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := NewProtobufSink(&buffer, eventDesc).WithFormat(format).WriteStream(FromRecordsUnsafe(records())); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			results, err := Collect(NewProtobufSource(&buffer, eventDesc).WithFormat(format).ToStream())
+			if err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			if len(results) != len(expected) {
+				t.Fatalf("Expected %d records, got %d", len(expected), len(results))
+			}
+			for i, result := range results {
+				if got := collectStreamFields(result); !reflect.DeepEqual(got, expected[i]) {
+					t.Errorf("Record %d: expected %v, got %v", i, expected[i], got)
+				}
+			}
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			record   Record
+			contains string
+		}{
+			{Record{"level": "VERBOSE"}, `"VERBOSE" is not a value of enum streamv2.test.Level`},
+			{Record{"at": "yesterday"}, "google.protobuf.Timestamp"},
+			{Record{"took": 1.5}, "google.protobuf.Duration"},
+			{Record{"retries": "many"}, "'retries'"},
+			{Record{"attributes": "flat"}, "google.protobuf.Struct"},
+		}
+		for _, tt := range tests {
+			var buffer bytes.Buffer
+			err := NewProtobufSink(&buffer, eventDesc).WriteStream(FromRecordsUnsafe([]Record{tt.record}))
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected an error containing %q for %v, got %v", tt.contains, tt.record, err)
+			}
+		}
+	})
+}
+
+// TestWriteTable tests aligned table rendering
+func TestWriteTable(t *testing.T) {
+	records := []Record{
+		{"name": "Alice", "age": int64(30), "address": Record{"city": "NYC", "zip": "10001"}, "tags": FromSlice([]string{"a"})},
+		{"name": "Bob", "age": int64(5), "score": 9.5},
+		{"name": "Christopher Robin", "age": int64(8)},
+	}
+
+	t.Run("DefaultColumns", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := WriteTable(FromRecordsUnsafe(records), &buffer); err != nil {
+			t.Fatalf("Failed to write table: %v", err)
+		}
+
+		expected := "" +
+			"address     age  name               score  tags\n" +
+			"----------  ---  -----------------  -----  --------\n" +
+			"{2 fields}  30   Alice                     [stream]\n" +
+			"            5    Bob                9.5\n" +
+			"            8    Christopher Robin\n"
+		if buffer.String() != expected {
+			t.Errorf("Unexpected table:\n%s\nexpected:\n%s", buffer.String(), expected)
+		}
+	})
+
+	t.Run("ColumnsWidthAndRows", func(t *testing.T) {
+		var buffer bytes.Buffer
+		err := WriteTable(FromRecordsUnsafe(records), &buffer,
+			WithTableColumns("name", "age"),
+			WithTableMaxWidth(8),
+			WithTableMaxRows(1))
+		if err != nil {
+			t.Fatalf("Failed to write table: %v", err)
+		}
+
+		expected := "" +
+			"name   age\n" +
+			"-----  ---\n" +
+			"Alice  30\n" +
+			"... 2 more rows\n"
+		if buffer.String() != expected {
+			t.Errorf("Unexpected table:\n%s\nexpected:\n%s", buffer.String(), expected)
+		}
+
+		buffer.Reset()
+		WriteTable(FromRecordsUnsafe(records[2:]), &buffer, WithTableColumns("name"), WithTableMaxWidth(8))
+		if !strings.Contains(buffer.String(), "Chris...\n") {
+			t.Errorf("Expected long cell cut to 8 characters, got:\n%s", buffer.String())
+		}
+	})
+}
+
+// TestTopN tests Head and bounded TopN selection
+func TestTopN(t *testing.T) {
+	values := make([]int64, 1000)
+	for i := range values {
+		values[i] = int64((i * 7919) % 1000)
+	}
+
+	t.Run("MatchesFullSort", func(t *testing.T) {
+		less := func(a, b int64) bool { return a > b }
+		top, err := TopN(FromSlice(values), 10, less)
+		if err != nil {
+			t.Fatalf("TopN failed: %v", err)
+		}
+
+		sorted := append([]int64(nil), values...)
+		sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		if fmt.Sprint(top) != fmt.Sprint(sorted[:10]) {
+			t.Errorf("Expected %v, got %v", sorted[:10], top)
+		}
+	})
+
+	t.Run("Records", func(t *testing.T) {
+		records := []Record{{"n": int64(3)}, {"n": int64(1)}, {"n": int64(2)}}
+		top, _ := TopN(FromRecordsUnsafe(records), 5, func(a, b Record) bool {
+			return GetOr(a, "n", int64(0)) < GetOr(b, "n", int64(0))
+		})
+		if len(top) != 3 || top[0]["n"] != int64(1) || top[2]["n"] != int64(3) {
+			t.Errorf("Expected all 3 records ascending, got %v", top)
+		}
+	})
+
+	t.Run("Head", func(t *testing.T) {
+		head, err := Head(FromSlice(values), 3)
+		if err != nil || fmt.Sprint(head) != fmt.Sprint(values[:3]) {
+			t.Errorf("Expected %v, got %v (%v)", values[:3], head, err)
+		}
+	})
+}
+
+// TestIsStreamType tests stream type detection
+func TestIsStreamType(t *testing.T) {
+	t.Run("ValidStreamType", func(t *testing.T) {
+		stream := FromSlice([]int64{1, 2, 3})
+		
+		if !IsStreamType(stream) {
+			t.Error("Expected IsStreamType to return true for stream")
+		}
+	})
+	
+	t.Run("InvalidStreamType", func(t *testing.T) {
+		notAStream := "not a stream"
+		
+		if IsStreamType(notAStream) {
+			t.Error("Expected IsStreamType to return false for non-stream")
+		}
+	})
+
+	t.Run("AnyElementType", func(t *testing.T) {
+		streams := []any{
+			FromSliceAny([]time.Time{time.Unix(0, 0)}),
+			FromSlice([]Record{{"a": 1}}),
+			FromSliceAny([]Stream[int64]{FromSlice([]int64{1})}),
+			func() (struct{ X int }, error) { return struct{ X int }{}, EOS },
+		}
+		for _, s := range streams {
+			if !IsStreamType(s) {
+				t.Errorf("Expected %T to be a stream", s)
+			}
+		}
+	})
+
+	t.Run("SimilarShapes", func(t *testing.T) {
+		notStreams := []any{
+			nil,
+			[]func() (int, error){},
+			func(int) func() (int, error) { return nil },
+			func() (int, bool) { return 0, false },
+			func() error { return nil },
+			"stream.Stream[int64]",
+		}
+		for _, s := range notStreams {
+			if IsStreamType(s) {
+				t.Errorf("Expected %T not to be a stream", s)
+			}
+		}
+	})
+}
+
+// TestParseCSVValue tests CSV value parsing
+func TestParseCSVValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"", ""},
+		{"123", int64(123)},
+		{"123.45", 123.45},
+		{"true", true},
+		{"false", false},
+		{"yes", true},
+		{"no", false},
+		{"hello", "hello"},
+		{"  spaced  ", "spaced"},
+	}
+	
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result := parseCSVValue(test.input)
+			if result != test.expected {
+				t.Errorf("parseCSVValue(%q) = %v (%T), expected %v (%T)", 
+					test.input, result, result, test.expected, test.expected)
+			}
+		})
+	}
+}
+
+// TestCSVTypeInference tests CSVSource's type inference modes and column type overrides
+func TestCSVTypeInference(t *testing.T) {
+	data := "country,account,score,flag,joined\n" +
+		"NO,00123,42,true,2024-01-02\n" +
+		"SE,00456,N/A,false,2024-02-03\n" +
+		"DK,00789,17,TRUE,2024-03-04\n"
+	joined := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		inference TypeInference
+		expected  Record // The first row
+		score     any    // The second row's score
+	}{
+		{"Conservative", InferenceConservative,
+			Record{"country": "NO", "account": "00123", "score": "42", "flag": true, "joined": "2024-01-02"}, "N/A"},
+		{"Aggressive", InferenceAggressive,
+			Record{"country": false, "account": int64(123), "score": int64(42), "flag": true, "joined": joined}, "N/A"},
+		{"Off", InferenceOff,
+			Record{"country": "NO", "account": "00123", "score": "42", "flag": "true", "joined": "2024-01-02"}, "N/A"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records, err := Collect(NewCSVSource(strings.NewReader(data)).WithTypeInference(test.inference).ToStream())
+			if err != nil || len(records) != 3 {
+				t.Fatalf("Expected 3 records, got %d (%v)", len(records), err)
+			}
+			for field, want := range test.expected {
+				if got := records[0][field]; got != want {
+					t.Errorf("Field %s: expected %v (%T), got %v (%T)", field, want, want, got, got)
+				}
+			}
+			if records[1]["score"] != test.score {
+				t.Errorf("Expected score %v, got %v", test.score, records[1]["score"])
+			}
+		})
+	}
+
+	t.Run("NumericColumns", func(t *testing.T) {
+		data := "id,price,code,big\n1,2.5,0,1e5\n-2,3,10,2\n3,,7,NaN\n"
+		records, err := Collect(NewCSVSource(strings.NewReader(data)).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		first := records[0]
+		if first["id"] != int64(1) || first["price"] != 2.5 || first["code"] != int64(0) || first["big"] != "1e5" {
+			t.Errorf("Expected int id, float price, int code and string big, got %v", first)
+		}
+		if records[1]["price"] != 3.0 || records[2]["price"] != "" {
+			t.Errorf("Expected a float column with an empty value, got %v and %v", records[1]["price"], records[2]["price"])
+		}
+	})
+
+	t.Run("ColumnTypes", func(t *testing.T) {
+		source := NewCSVSource(strings.NewReader(data)).
+			WithTypeInference(InferenceAggressive).
+			WithColumnType("country", StringKind).
+			WithColumnType("account", StringKind).
+			WithColumnType("score", IntKind)
+		records, err := Collect(source.ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		if records[0]["country"] != "NO" || records[0]["account"] != "00123" || records[0]["score"] != int64(42) {
+			t.Errorf("Expected the overridden columns typed as given, got %v", records[0])
+		}
+		if records[1]["score"] != "N/A" || records[0]["joined"] != joined {
+			t.Errorf("Expected unparseable values kept and other columns inferred, got %v", records[1])
+		}
+	})
+
+	t.Run("StreamingInput", func(t *testing.T) {
+		reader, writer := io.Pipe()
+		defer writer.Close()
+		go fmt.Fprint(writer, "id,name\n1,Alice\n")
+
+		// The writer stays open, so a source waiting for a full sample would block
+		records := NewCSVSource(reader).ToStream()
+		first := make(chan Record, 1)
+		go func() {
+			record, _ := records()
+			first <- record
+		}()
+		select {
+		case record := <-first:
+			if record["id"] != int64(1) || record["name"] != "Alice" {
+				t.Errorf("Expected the first row typed, got %v", record)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the first record without waiting for more rows")
+		}
+	})
+
+	t.Run("BeyondSample", func(t *testing.T) {
+		var data strings.Builder
+		data.WriteString("id\n")
+		for i := 1; i <= csvInferenceSampleRows; i++ {
+			fmt.Fprintf(&data, "%d\n", i)
+		}
+		data.WriteString("007\nlate\n")
+		records, err := Collect(NewCSVSource(strings.NewReader(data.String())).ToStream())
+		if err != nil || len(records) != csvInferenceSampleRows+2 {
+			t.Fatalf("Expected %d records, got %d (%v)", csvInferenceSampleRows+2, len(records), err)
+		}
+		n := len(records)
+		if records[0]["id"] != int64(1) || records[n-2]["id"] != "007" || records[n-1]["id"] != "late" {
+			t.Errorf("Expected ints, then values past the sample that don't fit kept as strings, got %v, %v, %v",
+				records[0]["id"], records[n-2]["id"], records[n-1]["id"])
+		}
+	})
+}
+
+// TestFormatCSVValue tests CSV value formatting
+func TestFormatCSVValue(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{"hello", "hello"},
+		{int64(123), "123"},
+		{123.45, "123.45"},
+		{true, "true"},
+		{false, "false"},
+		{nil, ""},
+		{time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), "2023-01-01T12:00:00Z"},
+	}
+	
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			result := formatCSVValue(test.input)
+			if result != test.expected {
+				t.Errorf("formatCSVValue(%v) = %q, expected %q", test.input, result, test.expected)
+			}
+		})
+	}
+}
+
+// TestFileOperations tests file-based operations (using temp files)
+func TestFileOperations(t *testing.T) {
+	t.Run("CSVFileOperations", func(t *testing.T) {
+		// Create temporary file
+		tmpfile, err := os.CreateTemp("", "test*.csv")
+		if err != nil {
+			t.Fatalf("Failed to create temp file: %v", err)
+		}
+		defer os.Remove(tmpfile.Name())
+		defer tmpfile.Close()
+		
+		// Write test data
+		csvData := "name,age\nAlice,30\nBob,25"
+		if _, err := tmpfile.WriteString(csvData); err != nil {
+			t.Fatalf("Failed to write test data: %v", err)
+		}
+		tmpfile.Close()
+		
+		// Test reading from file
+		stream, err := CSVToStreamFromFile(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("Failed to create stream from CSV file: %v", err)
+		}
+		
+		records, err := Collect(stream)
+		if err != nil {
+			t.Fatalf("Failed to collect records from file stream: %v", err)
+		}
+		
+		if len(records) != 2 {
+			t.Fatalf("Expected 2 records from file, got %d", len(records))
+		}
+		
+		if records[0]["name"] != "Alice" {
+			t.Errorf("Expected name 'Alice' from file, got %v", records[0]["name"])
+		}
+	})
+	
+	t.Run("JSONFileOperations", func(t *testing.T) {
+		// Create temporary file
+		tmpfile, err := os.CreateTemp("", "test*.json")
 		if err != nil {
 			t.Fatalf("Failed to create temp file: %v", err)
 		}
@@ -770,4 +1943,464 @@ func TestFileOperations(t *testing.T) {
 			t.Errorf("Expected name 'Alice' from file, got %v", records[0]["name"])
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestCommandSource tests parsing external command output
+func TestCommandSource(t *testing.T) {
+	t.Run("Lines", func(t *testing.T) {
+		records, err := Collect(NewCommandSource(context.Background(), "/bin/echo", "hello world").AsLines())
+		if err != nil {
+			t.Fatalf("Failed to read command output: %v", err)
+		}
+		if len(records) != 1 || records[0]["line"] != "hello world" || records[0]["line_number"] != int64(1) {
+			t.Errorf("Unexpected records: %v", records)
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		source := NewCommandSource(context.Background(), "printf", `name,age\nAlice,30\nBob,25\n`)
+		records, err := Collect(source.AsCSV())
+		if err != nil {
+			t.Fatalf("Failed to read command output: %v", err)
+		}
+		if len(records) != 2 || records[1]["name"] != "Bob" || records[1]["age"] != int64(25) {
+			t.Errorf("Unexpected records: %v", records)
+		}
+	})
+
+	t.Run("JSONLines", func(t *testing.T) {
+		source := NewCommandSource(context.Background(), "/bin/echo", `{"pod": "web-1", "ready": true}`)
+		records, err := Collect(source.AsJSONLines())
+		if err != nil {
+			t.Fatalf("Failed to read command output: %v", err)
+		}
+		if len(records) != 1 || records[0]["pod"] != "web-1" || records[0]["ready"] != true {
+			t.Errorf("Unexpected records: %v", records)
+		}
+	})
+
+	t.Run("NonZeroExit", func(t *testing.T) {
+		source := NewCommandSource(context.Background(), "sh", "-c", "echo partial; echo boom >&2; exit 3")
+		records, err := Collect(source.AsLines())
+		if len(records) != 1 {
+			t.Errorf("Expected output before the failure, got %v", records)
+		}
+		if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("Expected exit status and stderr in error, got %v", err)
+		}
+	})
+
+	t.Run("MissingCommand", func(t *testing.T) {
+		_, err := Collect(NewCommandSource(context.Background(), "definitely-not-a-command").AsLines())
+		if err == nil {
+			t.Error("Expected start error")
+		}
+	})
+
+	t.Run("EarlyTermination", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		source := NewCommandSource(ctx, "yes")
+		lines, err := Collect(Limit[Record](3)(source.AsLines()))
+		if err != nil || len(lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %v (%v)", lines, err)
+		}
+
+		// yes never exits on its own, so Close must kill and reap it
+		done := make(chan struct{})
+		go func() {
+			source.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close did not stop the command")
+		}
+		if source.cmd.ProcessState == nil {
+			t.Error("Expected the process to be reaped")
+		}
+	})
+
+	t.Run("PullAfterClose", func(t *testing.T) {
+		source := NewCommandSource(context.Background(), "yes")
+		lines := source.AsLines()
+		if _, err := lines(); err != nil {
+			t.Fatalf("Expected a line, got %v", err)
+		}
+		source.Close()
+
+		// Buffered output may drain, then the closed stream just ends
+		var err error
+		for err == nil {
+			_, err = lines()
+		}
+		if err != EOS {
+			t.Errorf("Expected EOS after Close, got %v", err)
+		}
+	})
+
+	t.Run("ContextCancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		lines := NewCommandSource(ctx, "yes").AsLines()
+		if _, err := lines(); err != nil {
+			t.Fatalf("Expected a line, got %v", err)
+		}
+		cancel()
+
+		// Buffered output drains, then the stream ends with the context error
+		var err error
+		for err == nil {
+			_, err = lines()
+		}
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+// TestHTTPSource tests HTTP sources against a local server
+func TestHTTPSource(t *testing.T) {
+	var pageRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events":
+			fmt.Fprintln(w, `{"id": 1, "user": {"name": "alice"}}`)
+			fmt.Fprintln(w, `{"id": 2, "tags": ["a", "b"]}`)
+		case "/pages":
+			atomic.AddInt32(&pageRequests, 1)
+			page := r.URL.Query().Get("page")
+			switch page {
+			case "", "1":
+				fmt.Fprint(w, `[{"id": 1}, {"id": 2}]`)
+			case "2":
+				fmt.Fprint(w, `[{"id": 3}]`)
+			default:
+				fmt.Fprint(w, `[]`)
+			}
+		case "/flaky":
+			if r.URL.Query().Get("page") == "2" {
+				http.Error(w, "database exploded", http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprint(w, `[{"id": 1}]`)
+		}
+	}))
+	defer server.Close()
+
+	// nextPage follows ?page=N until a page comes back empty
+	nextPage := func(resp *http.Response, last Record) (*http.Request, bool) {
+		if last == nil {
+			return nil, false
+		}
+		page, _ := strconv.Atoi(resp.Request.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		url := *resp.Request.URL
+		url.RawQuery = fmt.Sprintf("page=%d", page+1)
+		req, _ := http.NewRequest(http.MethodGet, url.String(), nil)
+		return req, true
+	}
+
+	t.Run("JSONLines", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+		records, err := Collect(NewHTTPSource(nil, req).AsJSONLines())
+		if err != nil {
+			t.Fatalf("Failed to read HTTP source: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("Expected 2 records, got %d", len(records))
+		}
+		if user, ok := records[0]["user"].(Record); !ok || user["name"] != "alice" {
+			t.Errorf("Expected nested Record, got %v", records[0]["user"])
+		}
+		if tags, ok := records[1]["tags"].(Stream[any]); !ok {
+			t.Errorf("Expected tags as stream, got %T", records[1]["tags"])
+		} else if values, _ := Collect(tags); len(values) != 2 {
+			t.Errorf("Expected 2 tags, got %v", values)
+		}
+	})
+
+	t.Run("MultiPage", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/pages", nil)
+		records, err := Collect(NewPaginatedHTTPSource(server.Client(), req, nextPage).AsJSONArray())
+		if err != nil {
+			t.Fatalf("Failed to read pages: %v", err)
+		}
+		if len(records) != 3 || records[2]["id"] != int64(3) {
+			t.Errorf("Expected ids 1..3 across pages, got %v", records)
+		}
+	})
+
+	t.Run("EmptyPageEnds", func(t *testing.T) {
+		atomic.StoreInt32(&pageRequests, 0)
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/pages", nil)
+		records, err := Collect(NewPaginatedHTTPSource(nil, req, nextPage).AsJSONArray())
+		if err != nil || len(records) != 3 {
+			t.Fatalf("Expected 3 records, got %v (%v)", records, err)
+		}
+
+		// Pages 1 and 2 have records, page 3 is empty and must stop pagination
+		if n := atomic.LoadInt32(&pageRequests); n != 3 {
+			t.Errorf("Expected 3 page requests, got %d", n)
+		}
+	})
+
+	t.Run("CloseMidPage", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+		source := NewHTTPSource(nil, req)
+		if _, err := source.AsJSONLines()(); err != nil {
+			t.Fatalf("Expected a record, got %v", err)
+		}
+		if source.current == nil {
+			t.Fatal("Expected an open response mid-page")
+		}
+		if err := source.Close(); err != nil || source.current != nil {
+			t.Errorf("Expected Close to release the response, got %v", err)
+		}
+	})
+
+	t.Run("MidStreamServerError", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/flaky", nil)
+		records, err := Collect(NewPaginatedHTTPSource(nil, req, nextPage).AsJSONArray())
+		if len(records) != 1 {
+			t.Errorf("Expected the first page before the error, got %v", records)
+		}
+		if err == nil || !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "database exploded") {
+			t.Errorf("Expected 500 error with body snippet, got %v", err)
+		}
+	})
+
+	t.Run("ContextCancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+		_, err := Collect(NewHTTPSource(nil, req).WithContext(ctx).AsJSONLines())
+		if err == nil || !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+// BenchmarkProtobufSource reads a file of 1M small length-delimited messages
+func BenchmarkProtobufSource(b *testing.B) {
+	personDesc, err := LoadMessageDescriptorFromDescriptorSet("testdata/person.pb", "streamv2.test.Person")
+	if err != nil {
+		b.Fatalf("Failed to load descriptor: %v", err)
+	}
+	path := filepath.Join(b.TempDir(), "people.bin")
+	people := Map(func(i int64) Record {
+		return Record{"name": "person-" + strconv.FormatInt(i, 10), "age": i % 90}
+	})(Range(0, 1_000_000, 1))
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := NewProtobufSink(file, personDesc).WriteStream(people); err != nil {
+		b.Fatal(err)
+	}
+	file.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		count, err := Count(NewProtobufSource(file, personDesc).ToStream())
+		file.Close()
+		if err != nil || count != 1_000_000 {
+			b.Fatalf("Expected 1M records, got %d (%v)", count, err)
+		}
+	}
+}
+
+// TestSourceProjection tests that projected sources read only the named fields, with the values an unprojected read gives
+func TestSourceProjection(t *testing.T) {
+	csvData := "id,name,amount,note,active\n1,Alice,12.5,\"a, b\",true\n2,Bob,7,x,false\n"
+	tsvData := strings.ReplaceAll(strings.ReplaceAll(csvData, ",", "\t"), "\"a\t b\"", "a b")
+	jsonLines := `{"id":1,"name":"Alice","amount":12.5,"note":"a, b","active":true,"tags":["x"]}` + "\n" +
+		`{"id":2,"name":"Bob","amount":7,"note":"x","active":false,"extra":{"deep":[1,2]}}` + "\n"
+	jsonArray := "[" + strings.ReplaceAll(strings.TrimSpace(jsonLines), "\n", ",") + "]"
+
+	sources := []struct {
+		name    string
+		full    func() ProjectableSource
+		project func(fields ...string) ProjectableSource
+	}{
+		{"CSV", func() ProjectableSource { return NewCSVSource(strings.NewReader(csvData)) },
+			func(f ...string) ProjectableSource { return NewCSVSource(strings.NewReader(csvData)).WithProjection(f...) }},
+		{"TSV", func() ProjectableSource { return NewTSVSource(strings.NewReader(tsvData)) },
+			func(f ...string) ProjectableSource { return NewTSVSource(strings.NewReader(tsvData)).WithProjection(f...) }},
+		{"FastCSV", func() ProjectableSource { return NewFastCSVSource(strings.NewReader(csvData)) },
+			func(f ...string) ProjectableSource { return NewFastCSVSource(strings.NewReader(csvData)).WithProjection(f...) }},
+		{"FastTSV", func() ProjectableSource { return NewFastTSVSource(strings.NewReader(tsvData)) },
+			func(f ...string) ProjectableSource { return NewFastTSVSource(strings.NewReader(tsvData)).WithProjection(f...) }},
+		{"JSONLines", func() ProjectableSource { return NewJSONSource(strings.NewReader(jsonLines)) },
+			func(f ...string) ProjectableSource { return NewJSONSource(strings.NewReader(jsonLines)).WithProjection(f...) }},
+		{"JSONArray", func() ProjectableSource { return NewJSONSource(strings.NewReader(jsonArray)).WithFormat(JSONArray) },
+			func(f ...string) ProjectableSource {
+				return NewJSONSource(strings.NewReader(jsonArray)).WithFormat(JSONArray).WithProjection(f...)
+			}},
+	}
+
+	for _, source := range sources {
+		t.Run(source.name, func(t *testing.T) {
+			full, err := Collect(source.full().ToStream())
+			if err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			projected, err := Collect(source.project("amount", "id", "missing").ToStream())
+			if err != nil {
+				t.Fatalf("Failed to read projected: %v", err)
+			}
+			if len(projected) != len(full) {
+				t.Fatalf("Expected %d records, got %d", len(full), len(projected))
+			}
+			for i, r := range projected {
+				// Unprojected and missing columns are absent, not empty
+				if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"amount", "id"}) {
+					t.Errorf("Record %d: expected only amount and id, got %v", i, r)
+				}
+				if r["id"] != full[i]["id"] || r["amount"] != full[i]["amount"] {
+					t.Errorf("Record %d: expected the unprojected values %v, got %v", i, full[i], r)
+				}
+			}
+
+			all, err := Collect(source.project().ToStream())
+			if err != nil || len(all) != 2 || len(all[0]) != len(full[0]) {
+				t.Errorf("Expected every field without projected fields, got %v (%v)", all, err)
+			}
+		})
+	}
+
+	t.Run("FastTSVBatches", func(t *testing.T) {
+		batches, err := Collect(NewFastTSVSource(strings.NewReader(tsvData)).WithProjection("name").ToBatches(10))
+		if err != nil || len(batches) != 1 {
+			t.Fatalf("Expected one batch, got %v (%v)", batches, err)
+		}
+		if _, ok := batches[0].Columns["name"]; !ok || len(batches[0].Columns) != 1 {
+			t.Errorf("Expected only the projected column, got %v", batches[0].Columns)
+		}
+	})
+
+	t.Run("CSVTypeInference", func(t *testing.T) {
+		// Columns are typed from the same sample whether or not others are projected away
+		data := "zip,n\n01234,1\n98765,2\n"
+		records, err := Collect(NewCSVSource(strings.NewReader(data)).WithProjection("zip").ToStream())
+		if err != nil || records[0]["zip"] != "01234" {
+			t.Errorf("Expected zip kept as a string, got %v (%v)", records, err)
+		}
+	})
+
+	t.Run("ProjectInto", func(t *testing.T) {
+		source := NewCSVSource(strings.NewReader(csvData))
+		records, err := Collect(ProjectInto(source, Select("name", "id")))
+		if err != nil {
+			t.Fatalf("ProjectInto failed: %v", err)
+		}
+		if !reflect.DeepEqual(source.Projection, []string{"name", "id"}) {
+			t.Errorf("Expected the Select pushed into the source, got %v", source.Projection)
+		}
+		if len(records) != 2 || records[1]["name"] != "Bob" || len(records[1]) != 2 {
+			t.Errorf("Unexpected records %v", records)
+		}
+
+		// Other filters apply without a projection
+		other := NewJSONSource(strings.NewReader(jsonLines))
+		records, err = Collect(ProjectInto(other, DropFields("note")))
+		if err != nil {
+			t.Fatalf("ProjectInto failed: %v", err)
+		}
+		if other.Projection != nil || len(records[0]) != 5 {
+			t.Errorf("Expected no projection and every field but note, got %v and %v", other.Projection, records[0])
+		}
+	})
+
+	t.Run("CustomSource", func(t *testing.T) {
+		source := &projectedRows{rows: []Record{{"id": int64(1), "name": "Alice", "note": "x"}}}
+		records, err := Collect(ProjectInto(source, Select("id")))
+		if err != nil || len(records) != 1 {
+			t.Fatalf("Expected one record, got %v (%v)", records, err)
+		}
+		if !reflect.DeepEqual(source.fields, []string{"id"}) || !reflect.DeepEqual(records[0], Record{"id": int64(1)}) {
+			t.Errorf("Expected the Select pushed into the custom source, got %v and %v", source.fields, records[0])
+		}
+	})
+}
+
+// projectedRows is a ProjectableSource outside the package's own sources
+type projectedRows struct {
+	rows   []Record
+	fields []string
+}
+
+func (p *projectedRows) SetProjection(fields ...string) { p.fields = fields }
+
+func (p *projectedRows) ToStream() Stream[Record] {
+	return Map(func(r Record) Record {
+		if len(p.fields) == 0 {
+			return r
+		}
+		projected := make(Record, len(p.fields))
+		for _, field := range p.fields {
+			if value, ok := r[field]; ok {
+				projected[field] = value
+			}
+		}
+		return projected
+	})(FromSlice(p.rows))
+}
+
+// wideCSV builds a CSV of rows rows and cols numeric columns named c0, c1, ...
+func wideCSV(rows, cols int) []byte {
+	var data bytes.Buffer
+	for c := 0; c < cols; c++ {
+		if c > 0 {
+			data.WriteByte(',')
+		}
+		fmt.Fprintf(&data, "c%d", c)
+	}
+	data.WriteByte('\n')
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if c > 0 {
+				data.WriteByte(',')
+			}
+			fmt.Fprintf(&data, "%d.%d", r, c)
+		}
+		data.WriteByte('\n')
+	}
+	return data.Bytes()
+}
+
+// BenchmarkSourceProjection compares reading 3 of 200 columns with and without a projection
+func BenchmarkSourceProjection(b *testing.B) {
+	data := wideCSV(10_000, 200)
+	sources := []struct {
+		name   string
+		source func() ProjectableSource
+	}{
+		{"CSVSource", func() ProjectableSource { return NewCSVSource(bytes.NewReader(data)) }},
+		{"FastCSVSource", func() ProjectableSource { return NewFastCSVSource(bytes.NewReader(data)) }},
+	}
+	for _, s := range sources {
+		b.Run(s.name+"/Select", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := ForEach(func(Record) {})(Select("c1", "c100", "c199")(s.source().ToStream())); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(s.name+"/ProjectInto", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := ForEach(func(Record) {})(ProjectInto(s.source(), Select("c1", "c100", "c199"))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
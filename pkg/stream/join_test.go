@@ -1,8 +1,13 @@
 package stream
 
 import (
+	"errors"
 	"fmt"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestInnerJoin tests inner join functionality
@@ -300,6 +305,107 @@ func TestFullJoin(t *testing.T) {
 	})
 }
 
+// TestJoinSQLSemantics tests every keyed join against the rows SQL returns for the same
+// tables, where missing and nil keys are NULL and duplicate keys appear on both sides
+func TestJoinSQLSemantics(t *testing.T) {
+	// Rows are written "l/r", with NULL for the side a row doesn't have
+	row := func(r Record) string {
+		l, ok := r["l"]
+		if !ok {
+			l = "NULL"
+		}
+		rv, ok := r["r"]
+		if !ok {
+			rv = "NULL"
+		}
+		return fmt.Sprintf("%v/%v", l, rv)
+	}
+
+	tests := []struct {
+		name  string
+		left  []Record
+		right []Record
+		inner []string
+		lft   []string
+		rght  []string
+		full  []string
+	}{
+		{
+			name:  "DuplicatesBothSides",
+			left:  []Record{{"lk": 1, "l": "a1"}, {"lk": 1, "l": "a2"}, {"lk": 2, "l": "b"}},
+			right: []Record{{"rk": 1, "r": "x1"}, {"rk": 3, "r": "z"}, {"rk": 1, "r": "x2"}},
+			inner: []string{"a1/x1", "a1/x2", "a2/x1", "a2/x2"},
+			lft:   []string{"a1/x1", "a1/x2", "a2/x1", "a2/x2", "b/NULL"},
+			rght:  []string{"a1/x1", "a1/x2", "a2/x1", "a2/x2", "NULL/z"},
+			full:  []string{"a1/x1", "a1/x2", "a2/x1", "a2/x2", "b/NULL", "NULL/z"},
+		},
+		{
+			name:  "NullKeys",
+			left:  []Record{{"lk": nil, "l": "n"}, {"l": "m"}, {"lk": 1, "l": "a"}},
+			right: []Record{{"r": "m"}, {"rk": 1, "r": "x"}, {"rk": nil, "r": "n"}},
+			inner: []string{"a/x"},
+			lft:   []string{"n/NULL", "m/NULL", "a/x"},
+			rght:  []string{"a/x", "NULL/m", "NULL/n"},
+			full:  []string{"n/NULL", "m/NULL", "a/x", "NULL/m", "NULL/n"},
+		},
+		{
+			name:  "EmptyStringIsAValue",
+			left:  []Record{{"lk": "", "l": "e"}, {"lk": nil, "l": "n"}},
+			right: []Record{{"rk": nil, "r": "n"}, {"rk": "", "r": "e1"}, {"rk": "", "r": "e2"}},
+			inner: []string{"e/e1", "e/e2"},
+			lft:   []string{"e/e1", "e/e2", "n/NULL"},
+			rght:  []string{"e/e1", "e/e2", "NULL/n"},
+			full:  []string{"e/e1", "e/e2", "n/NULL", "NULL/n"},
+		},
+		{
+			name:  "UnmatchedRightInInputOrder",
+			left:  []Record{{"lk": 5, "l": "a"}},
+			right: []Record{{"rk": 9, "r": "p"}, {"rk": 5, "r": "q"}, {"rk": 7, "r": "s"}, {"rk": 9, "r": "t"}, {"rk": 8, "r": "u"}},
+			inner: []string{"a/q"},
+			lft:   []string{"a/q"},
+			rght:  []string{"a/q", "NULL/p", "NULL/s", "NULL/t", "NULL/u"},
+			full:  []string{"a/q", "NULL/p", "NULL/s", "NULL/t", "NULL/u"},
+		},
+		{
+			name:  "EmptyLeft",
+			right: []Record{{"rk": 1, "r": "x"}, {"r": "m"}},
+			rght:  []string{"NULL/x", "NULL/m"},
+			full:  []string{"NULL/x", "NULL/m"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joins := []struct {
+				kind     string
+				join     func(Stream[Record], string, string, ...JoinOption) Filter[Record, Record]
+				expected []string
+			}{
+				{"Inner", InnerJoin, tt.inner},
+				{"Left", LeftJoin, tt.lft},
+				{"Right", RightJoin, tt.rght},
+				{"Full", FullJoin, tt.full},
+			}
+			for _, j := range joins {
+				// Every run gives the same rows in the same order
+				for run := 0; run < 5; run++ {
+					results, err := Collect(j.join(FromRecordsUnsafe(tt.right), "lk", "rk")(FromRecordsUnsafe(tt.left)))
+					if err != nil {
+						t.Fatalf("%s: failed to collect join results: %v", j.kind, err)
+					}
+					rows := make([]string, len(results))
+					for i, r := range results {
+						rows[i] = row(r)
+					}
+					if strings.Join(rows, " ") != strings.Join(j.expected, " ") {
+						t.Fatalf("%s: expected %v, got %v", j.kind, j.expected, rows)
+					}
+				}
+			}
+		})
+	}
+}
+
 // TestJoinPerformance tests join with larger datasets
 func TestJoinPerformance(t *testing.T) {
 	t.Run("LargeDataset", func(t *testing.T) {
@@ -336,4 +442,606 @@ func TestJoinPerformance(t *testing.T) {
 			t.Fatalf("Expected 500 results, got %d", len(results))
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestSemiJoin tests semi join functionality
+func TestSemiJoin(t *testing.T) {
+	t.Run("DuplicateRightKeys", func(t *testing.T) {
+		users := []Record{
+			NewRecord().Int("id", 1).String("name", "Alice").Build(),
+			NewRecord().Int("id", 3).String("name", "Charlie").Build(),
+			NewRecord().Int("id", 2).String("name", "Bob").Build(),
+		}
+		orders := []Record{
+			NewRecord().Int("userId", 1).Float("amount", 10.0).Build(),
+			NewRecord().Int("userId", 1).Float("amount", 20.0).Build(),
+			NewRecord().Int("userId", 3).Float("amount", 30.0).Build(),
+		}
+
+		joined := SemiJoin(FromRecordsUnsafe(orders), "id", "userId")(FromRecordsUnsafe(users))
+		results, err := Collect(joined)
+		if err != nil {
+			t.Fatalf("Failed to collect join results: %v", err)
+		}
+
+		// Alice once despite two orders, Charlie once
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(results))
+		}
+		if GetOr(results[0], "name", "") != "Alice" || GetOr(results[1], "name", "") != "Charlie" {
+			t.Errorf("Expected Alice and Charlie, got %v", results)
+		}
+
+		// Left records are emitted unchanged - no right fields merged in
+		for _, result := range results {
+			if len(result) != 2 {
+				t.Errorf("Expected only left fields, got %v", result)
+			}
+			if result.Has("amount") {
+				t.Errorf("Semi join should not merge right fields, got %v", result)
+			}
+		}
+	})
+
+	t.Run("MissingKeyField", func(t *testing.T) {
+		left := []Record{
+			NewRecord().Int("id", 1).String("name", "Alice").Build(),
+			NewRecord().String("name", "NoKey").Build(),
+		}
+		right := []Record{
+			NewRecord().Int("userId", 1).Build(),
+			NewRecord().String("other", "no key").Build(),
+		}
+
+		joined := SemiJoin(FromRecordsUnsafe(right), "id", "userId")(FromRecordsUnsafe(left))
+		results, err := Collect(joined)
+		if err != nil {
+			t.Fatalf("Failed to collect join results: %v", err)
+		}
+
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		if GetOr(results[0], "name", "") != "Alice" {
+			t.Errorf("Expected Alice, got %v", results[0])
+		}
+	})
+
+	// Unmatched left records before a match used to send the join back into itself
+	t.Run("UnmatchedBeforeMatch", func(t *testing.T) {
+		tests := []struct {
+			name string
+			left []Record
+		}{
+			{"UnmatchedKey", []Record{
+				NewRecord().Int("id", 1).String("name", "Alice").Build(),
+				NewRecord().Int("id", 2).String("name", "Bob").Build(),
+				NewRecord().Int("id", 3).String("name", "Charlie").Build(),
+			}},
+			{"MissingKey", []Record{
+				NewRecord().String("name", "NoKey").Build(),
+				NewRecord().Int("id", 1).String("name", "Alice").Build(),
+				NewRecord().Int("id", 3).String("name", "Charlie").Build(),
+			}},
+		}
+		right := []Record{
+			NewRecord().Int("userId", 1).Build(),
+			NewRecord().Int("userId", 3).Build(),
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				joined := SemiJoin(FromRecordsUnsafe(right), "id", "userId")(FromRecordsUnsafe(tt.left))
+				results, err := Collect(joined)
+				if err != nil {
+					t.Fatalf("Failed to collect join results: %v", err)
+				}
+				if len(results) != 2 || GetOr(results[0], "name", "") != "Alice" || GetOr(results[1], "name", "") != "Charlie" {
+					t.Errorf("Expected Alice and Charlie, got %v", results)
+				}
+			})
+		}
+	})
+}
+
+// TestAntiJoin tests anti join functionality
+func TestAntiJoin(t *testing.T) {
+	t.Run("DuplicateRightKeys", func(t *testing.T) {
+		users := []Record{
+			NewRecord().Int("id", 1).String("name", "Alice").Build(),
+			NewRecord().Int("id", 2).String("name", "Bob").Build(),
+			NewRecord().Int("id", 3).String("name", "Charlie").Build(),
+		}
+		orders := []Record{
+			NewRecord().Int("userId", 1).Float("amount", 10.0).Build(),
+			NewRecord().Int("userId", 1).Float("amount", 20.0).Build(),
+			NewRecord().Int("userId", 3).Float("amount", 30.0).Build(),
+		}
+
+		joined := AntiJoin(FromRecordsUnsafe(orders), "id", "userId")(FromRecordsUnsafe(users))
+		results, err := Collect(joined)
+		if err != nil {
+			t.Fatalf("Failed to collect join results: %v", err)
+		}
+
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		if GetOr(results[0], "name", "") != "Bob" {
+			t.Errorf("Expected Bob, got %v", results[0])
+		}
+		if len(results[0]) != 2 {
+			t.Errorf("Expected left record unchanged, got %v", results[0])
+		}
+	})
+
+	t.Run("MissingAndEmptyKeys", func(t *testing.T) {
+		left := []Record{
+			NewRecord().Int("id", 1).String("name", "Alice").Build(),
+			NewRecord().String("name", "NoKey").Build(),
+			NewRecord().String("id", "").String("name", "EmptyKey").Build(),
+		}
+		right := []Record{
+			NewRecord().Int("userId", 1).Build(),
+			NewRecord().String("userId", "").Build(),
+		}
+
+		joined := AntiJoin(FromRecordsUnsafe(right), "id", "userId")(FromRecordsUnsafe(left))
+		results, err := Collect(joined)
+		if err != nil {
+			t.Fatalf("Failed to collect join results: %v", err)
+		}
+
+		// A missing key never matches, but an empty string is a key like any other
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		if GetOr(results[0], "name", "") != "NoKey" {
+			t.Errorf("Expected NoKey, got %v", results)
+		}
+	})
+}
+
+// TestJoinKeyEncoding tests that join keys compare by value rather than by their %v text
+func TestJoinKeyEncoding(t *testing.T) {
+	joins := map[string]func(Stream[Record], string, string, ...JoinOption) Filter[Record, Record]{
+		"InnerJoin": InnerJoin,
+		"LeftJoin":  LeftJoin,
+		"RightJoin": RightJoin,
+		"FullJoin":  FullJoin,
+	}
+	// matched counts the results carrying fields from both sides
+	matched := func(results []Record) int {
+		n := 0
+		for _, result := range results {
+			if result.Has("name") && result.Has("department") {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("IntFloatEquality", func(t *testing.T) {
+		for name, join := range joins {
+			left := []Record{
+				{"id": int64(1), "name": "Alice"},
+				{"id": 2.0, "name": "Bob"},
+				{"id": 3.5, "name": "Carol"},
+			}
+			right := []Record{
+				{"userId": 1.0, "department": "Engineering"},
+				{"userId": int32(2), "department": "Sales"},
+				{"userId": "3.5", "department": "Support"},
+				{"userId": 3.05, "department": "Nobody"},
+			}
+			results, err := Collect(join(FromRecordsUnsafe(right), "id", "userId")(FromRecordsUnsafe(left)))
+			if err != nil {
+				t.Fatalf("%s: failed to collect join results: %v", name, err)
+			}
+			if n := matched(results); n != 3 {
+				t.Errorf("%s: expected 3 matches, got %d: %v", name, n, results)
+			}
+		}
+	})
+
+	t.Run("TimeKeys", func(t *testing.T) {
+		instant := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		now := time.Now()
+		for name, join := range joins {
+			left := []Record{
+				{"at": instant, "name": "Alice"},
+				{"at": now, "name": "Bob"},
+			}
+			right := []Record{
+				// Same instants, different location and no monotonic reading
+				{"at": instant.In(time.FixedZone("EST", -5*3600)), "department": "Engineering"},
+				{"at": now.Round(0), "department": "Sales"},
+			}
+			results, err := Collect(join(FromRecordsUnsafe(right), "at", "at")(FromRecordsUnsafe(left)))
+			if err != nil {
+				t.Fatalf("%s: failed to collect join results: %v", name, err)
+			}
+			if n := matched(results); n != 2 {
+				t.Errorf("%s: expected 2 matches, got %d: %v", name, n, results)
+			}
+		}
+	})
+
+	t.Run("MissingVersusEmpty", func(t *testing.T) {
+		expected := map[string]int{"InnerJoin": 1, "LeftJoin": 3, "RightJoin": 3, "FullJoin": 5}
+		for name, join := range joins {
+			left := []Record{
+				{"id": "", "name": "Empty"},
+				{"name": "Missing"},
+				{"id": nil, "name": "Nil"},
+			}
+			right := []Record{
+				{"userId": "", "department": "Blank"},
+				{"department": "Missing"},
+				{"userId": nil, "department": "Nil"},
+			}
+			results, err := Collect(join(FromRecordsUnsafe(right), "id", "userId")(FromRecordsUnsafe(left)))
+			if err != nil {
+				t.Fatalf("%s: failed to collect join results: %v", name, err)
+			}
+			// Only the empty strings match; missing and nil keys are kept apart
+			if n := matched(results); n != 1 {
+				t.Errorf("%s: expected 1 match, got %d: %v", name, n, results)
+			}
+			if len(results) != expected[name] {
+				t.Errorf("%s: expected %d results, got %d: %v", name, expected[name], len(results), results)
+			}
+		}
+	})
+
+	t.Run("WithKeyEncoder", func(t *testing.T) {
+		left := []Record{{"email": "Alice@Example.com", "name": "Alice"}}
+		right := []Record{{"email": "alice@example.com", "department": "Engineering"}}
+		lower := WithKeyEncoder(func(value any) string {
+			return strings.ToLower(fmt.Sprint(value))
+		})
+
+		results, _ := Collect(InnerJoin(FromRecordsUnsafe(right), "email", "email", lower)(FromRecordsUnsafe(left)))
+		if matched(results) != 1 {
+			t.Errorf("Expected the custom encoder to match case-insensitively, got %v", results)
+		}
+		results, _ = Collect(AntiJoin(FromRecordsUnsafe(right), "email", "email", lower)(FromRecordsUnsafe(left)))
+		if len(results) != 0 {
+			t.Errorf("Expected the custom encoder to apply to AntiJoin, got %v", results)
+		}
+	})
+}
+
+// TestJoinTable tests reusable join tables and lookup joins
+func TestJoinTable(t *testing.T) {
+	departments := func() Stream[Record] {
+		return FromRecordsUnsafe([]Record{
+			NewRecord().Int("userId", 1).String("department", "Engineering").Build(),
+			NewRecord().Int("userId", 2).String("department", "Sales").Build(),
+			NewRecord().String("department", "Unassigned").Build(),
+		})
+	}
+	users := func(n int) Stream[Record] {
+		records := make([]Record, n)
+		for i := range records {
+			records[i] = Record{"id": int64(i%3 + 1), "seq": int64(i)}
+		}
+		return FromRecordsUnsafe(records)
+	}
+
+	t.Run("ConcurrentPipelines", func(t *testing.T) {
+		table, err := BuildJoinTable(departments(), "userId")
+		if err != nil {
+			t.Fatalf("Failed to build table: %v", err)
+		}
+		if table.Len() != 3 {
+			t.Errorf("Expected 3 records in the table, got %d", table.Len())
+		}
+
+		join := table.Join("id", JoinInner)
+		var wg sync.WaitGroup
+		counts := make([]int, 8)
+		for p := range counts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Half the pipelines share a filter, half make their own
+				filter := join
+				if p%2 == 1 {
+					filter = table.Join("id", JoinLeft)
+				}
+				results, err := Collect(filter(users(3000)))
+				if err != nil {
+					t.Errorf("Pipeline %d failed: %v", p, err)
+				}
+				counts[p] = len(results)
+			}()
+		}
+		wg.Wait()
+
+		for p, count := range counts {
+			expected := 2000
+			if p%2 == 1 {
+				expected = 3000
+			}
+			if count != expected {
+				t.Errorf("Pipeline %d: expected %d results, got %d", p, expected, count)
+			}
+		}
+	})
+
+	t.Run("RightJoinPerPipeline", func(t *testing.T) {
+		table, _ := BuildJoinTable(departments(), "userId")
+		join := table.Join("id", JoinRight)
+		for run := 0; run < 2; run++ {
+			// Unmatched tracking starts afresh for every pipeline
+			results, _ := Collect(join(FromRecordsUnsafe([]Record{{"id": int64(1)}})))
+			if len(results) != 3 {
+				t.Errorf("Run %d: expected 3 results, got %v", run, results)
+			}
+		}
+	})
+
+	t.Run("BuildError", func(t *testing.T) {
+		failure := errors.New("read failed")
+		calls := 0
+		source := func() (Record, error) {
+			calls++
+			if calls > 1 {
+				return nil, failure
+			}
+			return Record{"userId": int64(1)}, nil
+		}
+		if _, err := BuildJoinTable(source, "userId"); err != failure {
+			t.Errorf("Expected the read error, got %v", err)
+		}
+	})
+
+	t.Run("KeyEncoderFixedAtBuild", func(t *testing.T) {
+		encoder := WithKeyEncoder(func(v any) string { return fmt.Sprint(v) })
+		table, _ := BuildJoinTable(departments(), "userId", encoder)
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic for WithKeyEncoder passed to Join")
+			}
+		}()
+		table.Join("id", JoinInner, encoder)
+	})
+
+	t.Run("LookupJoin", func(t *testing.T) {
+		lookup := map[string]Record{
+			"1": {"department": "Engineering"},
+			"2": {"department": "Sales"},
+		}
+		left := []Record{{"id": int64(1)}, {"id": 2.0}, {"id": int64(3)}}
+
+		join := LookupJoin(lookup, "id", JoinLeft)
+		for run := 0; run < 2; run++ {
+			results, err := Collect(join(FromRecordsUnsafe(left)))
+			if err != nil {
+				t.Fatalf("Run %d: failed to collect join results: %v", run, err)
+			}
+			var departments []string
+			for _, result := range results {
+				departments = append(departments, GetOr(result, "department", "-"))
+			}
+			if expected := []string{"Engineering", "Sales", "-"}; fmt.Sprintf("%q", departments) != fmt.Sprintf("%q", expected) {
+				t.Errorf("Run %d: expected %q, got %q", run, expected, departments)
+			}
+		}
+
+		anti, _ := Collect(LookupJoin(lookup, "id", JoinAnti)(FromRecordsUnsafe(left)))
+		if len(anti) != 1 || GetOr(anti[0], "id", int64(0)) != 3 {
+			t.Errorf("Expected only id 3 to be unmatched, got %v", anti)
+		}
+	})
+}
+
+// BenchmarkJoinTable compares a join that builds its hash table for every pipeline
+// with one shared JoinTable
+func BenchmarkJoinTable(b *testing.B) {
+	right := make([]Record, 10000)
+	for i := range right {
+		right[i] = Record{"userId": int64(i), "department": fmt.Sprintf("dept-%d", i%50)}
+	}
+	left := make([]Record, 100)
+	for i := range left {
+		left[i] = Record{"id": int64(i * 7)}
+	}
+
+	b.Run("RebuildPerPipeline", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Collect(InnerJoin(FromRecordsUnsafe(right), "id", "userId")(FromRecordsUnsafe(left)))
+		}
+	})
+
+	b.Run("SharedTable", func(b *testing.B) {
+		table, _ := BuildJoinTable(FromRecordsUnsafe(right), "userId")
+		join := table.Join("id", JoinInner)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			Collect(join(FromRecordsUnsafe(left)))
+		}
+	})
+}
+
+// TestCrossJoin tests cartesian product joins
+func TestCrossJoin(t *testing.T) {
+	t.Run("EveryPair", func(t *testing.T) {
+		sales := []Record{
+			NewRecord().String("region", "north").Float("revenue", 100).Build(),
+			NewRecord().String("region", "south").Float("revenue", 200).Build(),
+		}
+		scenarios := []Record{
+			NewRecord().String("scenario", "low").Float("growth", 0.9).Build(),
+			NewRecord().String("scenario", "base").Float("growth", 1.0).Build(),
+			NewRecord().String("scenario", "high").Float("growth", 1.2).Build(),
+		}
+
+		results, err := Collect(CrossJoin(FromRecordsUnsafe(scenarios))(FromRecordsUnsafe(sales)))
+		if err != nil {
+			t.Fatalf("Failed to collect join results: %v", err)
+		}
+
+		var pairs []string
+		for _, result := range results {
+			pairs = append(pairs, GetOr(result, "region", "")+"/"+GetOr(result, "scenario", ""))
+		}
+		expected := []string{"north/low", "north/base", "north/high", "south/low", "south/base", "south/high"}
+		if fmt.Sprintf("%q", pairs) != fmt.Sprintf("%q", expected) {
+			t.Errorf("Expected pairs %q, got %q", expected, pairs)
+		}
+	})
+
+	t.Run("EmptyRightStream", func(t *testing.T) {
+		left := []Record{NewRecord().Int("id", 1).Build()}
+		results, err := Collect(CrossJoin(FromRecordsUnsafe(nil))(FromRecordsUnsafe(left)))
+		if err != nil {
+			t.Fatalf("Failed to collect join results: %v", err)
+		}
+		if len(results) != 0 {
+			t.Errorf("Expected no results, got %v", results)
+		}
+	})
+
+	t.Run("PrefixConflicts", func(t *testing.T) {
+		left := []Record{NewRecord().String("name", "Alice").Int("id", 1).Build()}
+		right := []Record{NewRecord().String("name", "Admin").Build()}
+
+		results, _ := Collect(CrossJoin(FromRecordsUnsafe(right))(FromRecordsUnsafe(left)))
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		if GetOr(results[0], "left.name", "") != "Alice" || GetOr(results[0], "right.name", "") != "Admin" || results[0].Has("name") {
+			t.Errorf("Expected default prefixes on the conflicting field, got %v", results[0])
+		}
+
+		results, _ = Collect(CrossJoin(FromRecordsUnsafe(right), WithPrefixes("user.", "role."))(FromRecordsUnsafe(left)))
+		if GetOr(results[0], "user.name", "") != "Alice" || GetOr(results[0], "role.name", "") != "Admin" || GetOr(results[0], "id", int64(0)) != 1 {
+			t.Errorf("Expected custom prefixes on the conflicting field only, got %v", results[0])
+		}
+	})
+
+	t.Run("Lazy", func(t *testing.T) {
+		right := []Record{NewRecord().Int("n", 1).Build(), NewRecord().Int("n", 2).Build()}
+
+		// An unbounded left stream still yields pairs as they are pulled
+		var i int64
+		left := GenerateAny(func() (Record, error) {
+			i++
+			return Record{"id": i}, nil
+		})
+		results, err := Collect(Limit[Record](3)(CrossJoin(FromRecordsUnsafe(right))(left)))
+		if err != nil || len(results) != 3 {
+			t.Fatalf("Expected 3 results, got %d (%v)", len(results), err)
+		}
+		if i != 2 {
+			t.Errorf("Expected 2 left records to be read, got %d", i)
+		}
+	})
+}
+
+// TestConditionJoin tests joins on arbitrary predicates
+func TestConditionJoin(t *testing.T) {
+	t.Run("TimeRange", func(t *testing.T) {
+		base := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		shifts := []Record{
+			NewRecord().String("shift", "morning").Time("start", base).Time("end", base.Add(4*time.Hour)).Build(),
+			NewRecord().String("shift", "afternoon").Time("start", base.Add(4*time.Hour)).Time("end", base.Add(8*time.Hour)).Build(),
+		}
+		events := []Record{
+			NewRecord().String("event", "login").Time("ts", base.Add(time.Hour)).Build(),
+			NewRecord().String("event", "deploy").Time("ts", base.Add(5*time.Hour)).Build(),
+			NewRecord().String("event", "page").Time("ts", base.Add(10*time.Hour)).Build(),
+		}
+
+		during := func(event, shift Record) bool {
+			ts := GetOr(event, "ts", time.Time{})
+			return !ts.Before(GetOr(shift, "start", time.Time{})) && ts.Before(GetOr(shift, "end", time.Time{}))
+		}
+		results, err := Collect(ConditionJoin(FromRecordsUnsafe(shifts), during)(FromRecordsUnsafe(events)))
+		if err != nil {
+			t.Fatalf("Failed to collect join results: %v", err)
+		}
+
+		var pairs []string
+		for _, result := range results {
+			pairs = append(pairs, GetOr(result, "event", "")+"/"+GetOr(result, "shift", ""))
+		}
+		expected := []string{"login/morning", "deploy/afternoon"}
+		if fmt.Sprintf("%q", pairs) != fmt.Sprintf("%q", expected) {
+			t.Errorf("Expected pairs %q, got %q", expected, pairs)
+		}
+	})
+
+	t.Run("NilPredicatePanics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic for a nil predicate")
+			}
+		}()
+		ConditionJoin(FromRecordsUnsafe(nil), nil)
+	})
+}
+
+// TestJoinLongUnmatchedRun guards against the join re-entering itself for every
+// left record that produces no output
+func TestJoinLongUnmatchedRun(t *testing.T) {
+	t.Run("InnerJoinMillionUnmatched", func(t *testing.T) {
+		const leftCount = 1000000
+		right := []Record{
+			NewRecord().Int("userId", -1).String("department", "Nobody").Build(),
+		}
+
+		// Generate left records lazily so the input itself stays out of memory
+		var i int64
+		left := GenerateAny(func() (Record, error) {
+			if i >= leftCount {
+				return nil, EOS
+			}
+			i++
+			return Record{"id": i}, nil
+		})
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		count, err := Count(InnerJoin(FromRecordsUnsafe(right), "id", "userId")(left))
+		if err != nil {
+			t.Fatalf("Failed to run join: %v", err)
+		}
+		if count != 0 {
+			t.Errorf("Expected 0 results, got %d", count)
+		}
+		if i != leftCount {
+			t.Errorf("Expected all %d left records to be consumed, got %d", leftCount, i)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		if after.HeapAlloc > before.HeapAlloc+16*1024*1024 {
+			t.Errorf("Join retained too much memory: %d -> %d bytes", before.HeapAlloc, after.HeapAlloc)
+		}
+	})
+
+	t.Run("FilterReusedAcrossStreams", func(t *testing.T) {
+		right := []Record{
+			NewRecord().Int("userId", 2).String("department", "Sales").Build(),
+		}
+		join := InnerJoin(FromRecordsUnsafe(right), "id", "userId")
+
+		for run := 0; run < 2; run++ {
+			left := FromRecordsUnsafe([]Record{
+				NewRecord().Int("id", 1).Build(),
+				NewRecord().Int("id", 2).Build(),
+			})
+			results, err := Collect(join(left))
+			if err != nil {
+				t.Fatalf("Run %d: failed to collect join results: %v", run, err)
+			}
+			if len(results) != 1 || GetOr(results[0], "department", "") != "Sales" {
+				t.Errorf("Run %d: expected one Sales match, got %v", run, results)
+			}
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// METRICS - COUNTS, THROUGHPUT AND STAGE LATENCY
+// ============================================================================
+
+// MetricsCollector receives the measurements made by Instrument
+type MetricsCollector interface {
+	IncCount(name string)
+	ObserveLatency(name string, d time.Duration)
+}
+
+// Metric name suffixes used by Instrument, appended to the stage name
+const (
+	MetricErrors     = ".errors"     // Count of non-EOS errors returned by upstream
+	MetricUpstream   = ".upstream"   // Latency: time spent waiting for upstream to produce each element
+	MetricDownstream = ".downstream" // Latency: time downstream spent before pulling the next element
+)
+
+// Instrument passes elements through unchanged while reporting them to collector.
+// Each element counts once under name. Because streams are pulled, every pull is
+// timed twice: MetricUpstream is how long the input took to produce the element (all
+// stages before this one), and MetricDownstream is how long the consumer took before
+// pulling again (all stages after this one). Upstream errors count under MetricErrors.
+func Instrument[T any](name string, collector MetricsCollector) Filter[T, T] {
+	upstreamName := name + MetricUpstream
+	downstreamName := name + MetricDownstream
+	errorsName := name + MetricErrors
+
+	return func(input Stream[T]) Stream[T] {
+		var returned time.Time
+		return func() (T, error) {
+			start := time.Now()
+			if !returned.IsZero() {
+				collector.ObserveLatency(downstreamName, start.Sub(returned))
+			}
+
+			item, err := input()
+			returned = time.Now()
+			if err != nil {
+				if err != EOS {
+					collector.IncCount(errorsName)
+				}
+				return item, err
+			}
+
+			collector.ObserveLatency(upstreamName, returned.Sub(start))
+			collector.IncCount(name)
+			return item, nil
+		}
+	}
+}
+
+// MetricsFuncs adapts plain functions to MetricsCollector, so a metrics library can
+// be wired in without this package importing it. Nil functions are skipped.
+//
+//	collector := stream.MetricsFuncs{
+//	    Count:   func(name string) { recordsTotal.WithLabelValues(name).Inc() },
+//	    Latency: func(name string, d time.Duration) { latency.WithLabelValues(name).Observe(d.Seconds()) },
+//	}
+type MetricsFuncs struct {
+	Count   func(name string)
+	Latency func(name string, d time.Duration)
+}
+
+// IncCount calls Count
+func (m MetricsFuncs) IncCount(name string) {
+	if m.Count != nil {
+		m.Count(name)
+	}
+}
+
+// ObserveLatency calls Latency
+func (m MetricsFuncs) ObserveLatency(name string, d time.Duration) {
+	if m.Latency != nil {
+		m.Latency(name, d)
+	}
+}
+
+// InMemoryMetrics is a MetricsCollector that keeps counts and latency summaries in
+// memory. It is safe for concurrent use.
+type InMemoryMetrics struct {
+	mu        sync.Mutex
+	started   time.Time
+	counts    map[string]int64
+	latencies map[string]*latencySummary
+}
+
+type latencySummary struct {
+	count         int64
+	total, lo, hi time.Duration
+}
+
+// NewInMemoryMetrics creates an empty in-memory collector; throughput is measured
+// from its creation
+func NewInMemoryMetrics() *InMemoryMetrics {
+	return &InMemoryMetrics{
+		started:   time.Now(),
+		counts:    make(map[string]int64),
+		latencies: make(map[string]*latencySummary),
+	}
+}
+
+// IncCount adds one to the named count
+func (m *InMemoryMetrics) IncCount(name string) {
+	m.mu.Lock()
+	m.counts[name]++
+	m.mu.Unlock()
+}
+
+// ObserveLatency adds a duration to the named latency summary
+func (m *InMemoryMetrics) ObserveLatency(name string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summary, exists := m.latencies[name]
+	if !exists {
+		summary = &latencySummary{lo: d, hi: d}
+		m.latencies[name] = summary
+	}
+	summary.count++
+	summary.total += d
+	summary.lo = min(summary.lo, d)
+	summary.hi = max(summary.hi, d)
+}
+
+// Snapshot returns the current metrics as a Record:
+//
+//	elapsed_seconds: float64 since the collector was created
+//	counts:          Record of name → int64
+//	throughput:      Record of name → float64 per second
+//	latency:         Record of name → Record{count, total_ms, mean_ms, min_ms, max_ms}
+func (m *InMemoryMetrics) Snapshot() Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elapsed := time.Since(m.started).Seconds()
+	counts := make(Record, len(m.counts))
+	throughput := make(Record, len(m.counts))
+	for name, count := range m.counts {
+		counts[name] = count
+		throughput[name] = float64(count) / elapsed
+	}
+
+	latency := make(Record, len(m.latencies))
+	for name, summary := range m.latencies {
+		latency[name] = Record{
+			"count":    summary.count,
+			"total_ms": durationMillis(summary.total),
+			"mean_ms":  durationMillis(summary.total) / float64(summary.count),
+			"min_ms":   durationMillis(summary.lo),
+			"max_ms":   durationMillis(summary.hi),
+		}
+	}
+
+	return Record{
+		"elapsed_seconds": elapsed,
+		"counts":          counts,
+		"throughput":      throughput,
+		"latency":         latency,
+	}
+}
+
+// durationMillis converts a duration to fractional milliseconds
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestInstrument tests stage metrics collection
+func TestInstrument(t *testing.T) {
+	t.Run("ThreeStages", func(t *testing.T) {
+		values := make([]int64, 1000)
+		for i := range values {
+			values[i] = int64(i)
+		}
+
+		metrics := NewInMemoryMetrics()
+		pipeline := Pipe5(
+			Instrument[int64]("source", metrics),
+			Where(func(x int64) bool { return x%2 == 0 }),
+			Instrument[int64]("evens", metrics),
+			Where(func(x int64) bool { return x%10 == 0 }),
+			Instrument[int64]("tens", metrics),
+		)
+		results, err := Collect(pipeline(FromSlice(values)))
+		if err != nil || len(results) != 100 {
+			t.Fatalf("Expected 100 results, got %d (%v)", len(results), err)
+		}
+
+		snapshot := metrics.Snapshot()
+		counts := snapshot["counts"].(Record)
+		for name, expected := range map[string]int64{"source": 1000, "evens": 500, "tens": 100} {
+			if counts[name] != expected {
+				t.Errorf("Expected %s count %d, got %v", name, expected, counts[name])
+			}
+		}
+
+		latency := snapshot["latency"].(Record)
+		upstream, ok := latency["tens"+MetricUpstream].(Record)
+		if !ok || upstream["count"] != int64(100) {
+			t.Fatalf("Expected 100 upstream observations for tens, got %v", latency["tens"+MetricUpstream])
+		}
+		for _, field := range []string{"total_ms", "mean_ms", "min_ms", "max_ms"} {
+			if v, ok := upstream[field].(float64); !ok || v < 0 {
+				t.Errorf("Expected non-negative %s, got %v", field, upstream[field])
+			}
+		}
+		if downstream := latency["source"+MetricDownstream].(Record); downstream["count"] != int64(1000) {
+			t.Errorf("Expected a downstream observation after each of 1000 elements, got %v", downstream["count"])
+		}
+		if throughput := snapshot["throughput"].(Record); throughput["source"].(float64) <= 0 {
+			t.Errorf("Expected positive throughput, got %v", throughput["source"])
+		}
+
+		// Snapshots are plain Records, so they can go straight to a JSONSink
+		var buffer bytes.Buffer
+		if err := NewJSONSink(&buffer).WriteRecords([]Record{snapshot}); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil || decoded["counts"] == nil {
+			t.Errorf("Expected a JSON snapshot, got %s (%v)", buffer.String(), err)
+		}
+	})
+
+	t.Run("ErrorsAndFuncs", func(t *testing.T) {
+		counts := make(map[string]int)
+		var observed time.Duration
+		collector := MetricsFuncs{
+			Count:   func(name string) { counts[name]++ },
+			Latency: func(name string, d time.Duration) { observed += d },
+		}
+
+		failure := errors.New("boom")
+		calls := 0
+		source := func() (int64, error) {
+			calls++
+			if calls == 2 {
+				return 0, failure
+			}
+			if calls > 3 {
+				return 0, EOS
+			}
+			return int64(calls), nil
+		}
+
+		stream := Instrument[int64]("stage", collector)(source)
+		for {
+			if _, err := stream(); err == EOS {
+				break
+			}
+		}
+		if counts["stage"] != 2 || counts["stage"+MetricErrors] != 1 {
+			t.Errorf("Expected 2 elements and 1 error, got %v", counts)
+		}
+
+		// A collector with no functions is a no-op
+		Instrument[int64]("stage", MetricsFuncs{})(FromSlice([]int64{1}))()
+	})
+}
@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+// ============================================================================
+// PIPELINE PLANS - OPT-IN DESCRIPTIONS OF COMPOSED FILTERS
+// ============================================================================
+
+// planNode describes a named filter, or a composition (Pipe, Chain) of filters
+type planNode struct {
+	name     string
+	children []*planNode
+}
+
+// plans maps filter closures to their descriptions. Filters stay plain functions, so
+// a closure's address is its identity. Entries are keyed weakly, like the field order
+// of ordered records, so a closure allocated where a freed one was never finds the
+// freed one's entry, and removing that entry never touches the new closure's.
+var plans sync.Map // weak.Pointer[byte] → *planNode
+
+// filterClosure returns the address of a filter's closure
+func filterClosure[T, U any](f Filter[T, U]) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&f))
+}
+
+// rememberClosure stores value for a closure in registry until the closure is freed
+func rememberClosure(registry *sync.Map, closure unsafe.Pointer, value any) {
+	ptr := (*byte)(closure)
+	key := weak.Make(ptr)
+	registry.Store(key, value)
+	runtime.AddCleanup(ptr, func(key weak.Pointer[byte]) {
+		registry.Delete(key)
+	}, key)
+}
+
+// recallClosure returns the value stored for a closure in registry
+func recallClosure(registry *sync.Map, closure unsafe.Pointer) (any, bool) {
+	return registry.Load(weak.Make((*byte)(closure)))
+}
+
+// planOf returns the description of a filter, or nil when it has none
+func planOf[T, U any](f Filter[T, U]) *planNode {
+	if f == nil {
+		return nil
+	}
+	if node, ok := recallClosure(&plans, filterClosure(f)); ok {
+		return node.(*planNode)
+	}
+	return nil
+}
+
+// describe records the description of a filter closure created by this package
+func describe[T, U any](f Filter[T, U], node *planNode) Filter[T, U] {
+	rememberClosure(&plans, filterClosure(f), node)
+	return f
+}
+
+// describeComposition records a composition of filters, but only when one of them is
+// described, so pipelines that never use Named pay nothing for it
+func describeComposition[T, U any](f Filter[T, U], name string, children ...*planNode) Filter[T, U] {
+	described := false
+	for i, child := range children {
+		if child != nil {
+			described = true
+		} else {
+			children[i] = &planNode{name: "(unnamed)"}
+		}
+	}
+	if !described {
+		return f
+	}
+	return describe(f, &planNode{name: name, children: children})
+}
+
+// Named gives a filter a name for Explain and ExplainDOT. The returned filter behaves
+// exactly like f. Compositions of named filters built with Pipe through Pipe8
+// and Chain are described too, so naming the stages of a pipeline is enough to explain it.
+func Named[T, U any](name string, f Filter[T, U]) Filter[T, U] {
+	named := func(input Stream[T]) Stream[U] {
+		return f(input)
+	}
+
+	// Naming a described composition labels it rather than hiding its stages
+	node := &planNode{name: name}
+	if inner := planOf(f); inner != nil {
+		node.children = []*planNode{inner}
+	}
+	return describe(Filter[T, U](named), node)
+}
+
+// Explain describes a pipeline built from named filters as an indented tree, one
+// stage per line. Filters that were never named appear as "(unnamed)".
+func Explain[T, U any](f Filter[T, U]) string {
+	node := planOf(f)
+	if node == nil {
+		return "(unnamed)\n"
+	}
+
+	var b strings.Builder
+	var write func(node *planNode, depth int)
+	write = func(node *planNode, depth int) {
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString(node.name)
+		b.WriteString("\n")
+		for _, child := range node.children {
+			write(child, depth+1)
+		}
+	}
+	write(node, 0)
+	return b.String()
+}
+
+// ExplainDOT describes a pipeline built from named filters as a graphviz digraph.
+// Stages are connected in data flow order and compositions are drawn as clusters.
+func ExplainDOT[T, U any](f Filter[T, U]) string {
+	node := planOf(f)
+	if node == nil {
+		node = &planNode{name: "(unnamed)"}
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph pipeline {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	nodes, clusters := 0, 0
+	// write renders a node and returns the ids of its first and last stages
+	var write func(node *planNode, indent string) (string, string)
+	write = func(node *planNode, indent string) (string, string) {
+		if len(node.children) == 0 {
+			id := fmt.Sprintf("n%d", nodes)
+			nodes++
+			fmt.Fprintf(&b, "%s%s [label=%q];\n", indent, id, node.name)
+			return id, id
+		}
+
+		fmt.Fprintf(&b, "%ssubgraph cluster_%d {\n", indent, clusters)
+		clusters++
+		fmt.Fprintf(&b, "%s  label=%q;\n", indent, node.name)
+		var first, last string
+		for i, child := range node.children {
+			childFirst, childLast := write(child, indent+"  ")
+			if i == 0 {
+				first = childFirst
+			} else {
+				fmt.Fprintf(&b, "%s  %s -> %s;\n", indent, last, childFirst)
+			}
+			last = childLast
+		}
+		fmt.Fprintf(&b, "%s}\n", indent)
+		return first, last
+	}
+	write(node, "  ")
+
+	b.WriteString("}\n")
+	return b.String()
+}
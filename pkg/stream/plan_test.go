@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestExplain tests pipeline plans built from named filters
+func TestExplain(t *testing.T) {
+	records := []Record{
+		{"name": "Alice", "active": true, "price": 10.0, "qty": int64(2)},
+		{"name": "Bob", "active": false, "price": 5.0, "qty": int64(1)},
+		{"name": "Carol", "active": true, "price": 2.5, "qty": int64(4)},
+	}
+	active := Where(func(r Record) bool { return GetOr(r, "active", false) })
+	total := Map(func(r Record) Record {
+		return r.Set("total", GetOr(r, "price", 0.0)*float64(GetOr(r, "qty", int64(0))))
+	})
+	project := Select("name", "total")
+
+	pipeline := Pipe3(
+		Named("active customers", active),
+		Named("compute total", total),
+		Named("select name,total", project),
+	)
+
+	t.Run("TextPlan", func(t *testing.T) {
+		expected := "Pipe3\n" +
+			"  active customers\n" +
+			"  compute total\n" +
+			"  select name,total\n"
+		if plan := Explain(pipeline); plan != expected {
+			t.Errorf("Unexpected plan:\n%s\nexpected:\n%s", plan, expected)
+		}
+
+		nested := Named("report", Pipe(pipeline, Limit[Record](1)))
+		expected = "report\n" +
+			"  Pipe\n" +
+			"    Pipe3\n" +
+			"      active customers\n" +
+			"      compute total\n" +
+			"      select name,total\n" +
+			"    (unnamed)\n"
+		if plan := Explain(nested); plan != expected {
+			t.Errorf("Unexpected nested plan:\n%s\nexpected:\n%s", plan, expected)
+		}
+
+		if plan := Explain(Pipe(active, project)); plan != "(unnamed)\n" {
+			t.Errorf("Expected unnamed pipelines to stay undescribed, got %q", plan)
+		}
+	})
+
+	t.Run("DOTPlan", func(t *testing.T) {
+		expected := "digraph pipeline {\n" +
+			"  rankdir=LR;\n" +
+			"  node [shape=box];\n" +
+			"  subgraph cluster_0 {\n" +
+			"    label=\"Pipe3\";\n" +
+			"    n0 [label=\"active customers\"];\n" +
+			"    n1 [label=\"compute total\"];\n" +
+			"    n0 -> n1;\n" +
+			"    n2 [label=\"select name,total\"];\n" +
+			"    n1 -> n2;\n" +
+			"  }\n" +
+			"}\n"
+		if dot := ExplainDOT(pipeline); dot != expected {
+			t.Errorf("Unexpected DOT:\n%s\nexpected:\n%s", dot, expected)
+		}
+	})
+
+	t.Run("BehaviorUnchanged", func(t *testing.T) {
+		named, err := Collect(pipeline(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Named pipeline failed: %v", err)
+		}
+		plain, _ := Collect(Pipe3(active, total, project)(FromRecordsUnsafe(records)))
+		if fmt.Sprint(named) != fmt.Sprint(plain) || len(named) != 2 || named[1]["total"] != 10.0 {
+			t.Errorf("Expected the same results as the unnamed pipeline, got %v and %v", named, plain)
+		}
+	})
+
+	t.Run("RegistryCleanup", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			Named(fmt.Sprintf("stage %d", i), active)
+		}
+		count := func() int {
+			n := 0
+			plans.Range(func(_, _ any) bool { n++; return true })
+			return n
+		}
+		before := count()
+		for i := 0; i < 10 && count() >= before; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+		if after := count(); after >= before {
+			t.Errorf("Expected unreachable named filters to leave the registry, still %d entries", after)
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ============================================================================
+// PROTOBUF ENUMS AND WELL-KNOWN TYPES
+// ============================================================================
+
+// Well-known message types with a natural Record form. Messages are matched by full
+// name, so descriptors loaded from a descriptor set work as well as compiled-in ones.
+const (
+	protoTimestamp = "google.protobuf.Timestamp" // time.Time
+	protoDuration  = "google.protobuf.Duration"  // time.Duration
+	protoStruct    = "google.protobuf.Struct"    // Record
+	protoValue     = "google.protobuf.Value"     // nil, float64, string, bool, Record or Stream[any]
+	protoListValue = "google.protobuf.ListValue" // Stream[any]
+)
+
+// isProtoWrapper reports whether name is one of the google.protobuf wrapper messages,
+// such as Int64Value, which hold a single field named value
+func isProtoWrapper(name protoreflect.FullName) bool {
+	switch name {
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
+
+// wellKnownToRecordValue converts a well-known message to its Record form, reporting
+// false for any other message
+func wellKnownToRecordValue(msg protoreflect.Message) (any, bool) {
+	name := msg.Descriptor().FullName()
+	fields := msg.Descriptor().Fields()
+	switch {
+	case name == protoTimestamp:
+		seconds := msg.Get(fields.ByName("seconds")).Int()
+		nanos := msg.Get(fields.ByName("nanos")).Int()
+		return time.Unix(seconds, nanos).UTC(), true
+	case name == protoDuration:
+		seconds := msg.Get(fields.ByName("seconds")).Int()
+		nanos := msg.Get(fields.ByName("nanos")).Int()
+		return time.Duration(seconds)*time.Second + time.Duration(nanos), true
+	case isProtoWrapper(name):
+		fd := fields.ByName("value")
+		return convertProtobufScalarValue(fd, msg.Get(fd)), true
+	case name == protoStruct:
+		record := make(Record)
+		msg.Get(fields.ByName("fields")).Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			record[key.String()], _ = wellKnownToRecordValue(value.Message())
+			return true
+		})
+		return record, true
+	case name == protoListValue:
+		list := msg.Get(fields.ByName("values")).List()
+		items := make([]any, list.Len())
+		for i := range items {
+			items[i], _ = wellKnownToRecordValue(list.Get(i).Message())
+		}
+		return FromSliceAny(items), true
+	case name == protoValue:
+		kind := msg.WhichOneof(msg.Descriptor().Oneofs().ByName("kind"))
+		if kind == nil {
+			return nil, true
+		}
+		switch kind.Name() {
+		case "number_value":
+			return msg.Get(kind).Float(), true
+		case "string_value":
+			return msg.Get(kind).String(), true
+		case "bool_value":
+			return msg.Get(kind).Bool(), true
+		case "struct_value", "list_value":
+			return wellKnownToRecordValue(msg.Get(kind).Message())
+		}
+		return nil, true // null_value
+	}
+	return nil, false
+}
+
+// recordValueToWellKnown builds a well-known message of type md from a Record value,
+// reporting false if md isn't a well-known type with a Record form
+func recordValueToWellKnown(md protoreflect.MessageDescriptor, value any) (protoreflect.Value, bool, error) {
+	name := md.FullName()
+	if name != protoTimestamp && name != protoDuration && name != protoStruct &&
+		name != protoValue && name != protoListValue && !isProtoWrapper(name) {
+		return protoreflect.Value{}, false, nil
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	switch {
+	case name == protoTimestamp:
+		t, ok := convertToTime(value)
+		if !ok {
+			return protoreflect.Value{}, true, fmt.Errorf("cannot convert %T to protobuf %s", value, name)
+		}
+		msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+		msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+	case name == protoDuration:
+		d, ok := convertToDuration(value)
+		if !ok {
+			return protoreflect.Value{}, true, fmt.Errorf("cannot convert %T to protobuf %s", value, name)
+		}
+		msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(int64(d/time.Second)))
+		msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(d%time.Second)))
+	case isProtoWrapper(name):
+		fd := fields.ByName("value")
+		inner, err := convertRecordScalarToProtobuf(fd, value, "", ignoreUnknownField)
+		if err != nil {
+			return protoreflect.Value{}, true, err
+		}
+		msg.Set(fd, inner)
+	case name == protoStruct:
+		record, ok := value.(Record)
+		if !ok {
+			return protoreflect.Value{}, true, fmt.Errorf("cannot convert %T to protobuf %s", value, name)
+		}
+		fd := fields.ByName("fields")
+		entries := msg.Mutable(fd).Map()
+		for _, key := range record.Keys() {
+			entry, _, err := recordValueToWellKnown(fd.MapValue().Message(), record[key])
+			if err != nil {
+				return protoreflect.Value{}, true, fmt.Errorf("field '%s': %w", key, err)
+			}
+			entries.Set(protoreflect.ValueOfString(key).MapKey(), entry)
+		}
+	case name == protoListValue:
+		items, err := repeatedItems(value)
+		if err != nil {
+			return protoreflect.Value{}, true, fmt.Errorf("%w to protobuf %s", err, name)
+		}
+		fd := fields.ByName("values")
+		list := msg.Mutable(fd).List()
+		for _, item := range items {
+			element, _, err := recordValueToWellKnown(fd.Message(), item)
+			if err != nil {
+				return protoreflect.Value{}, true, err
+			}
+			list.Append(element)
+		}
+	case name == protoValue:
+		if err := setProtoValueKind(msg, value); err != nil {
+			return protoreflect.Value{}, true, err
+		}
+	}
+	return protoreflect.ValueOfMessage(msg), true, nil
+}
+
+// setProtoValueKind sets the kind oneof of a google.protobuf.Value message from a
+// Record value: nil, a number, a string, a bool, a time (as an RFC 3339 string),
+// a Record, or a stream or slice
+func setProtoValueKind(msg *dynamicpb.Message, value any) error {
+	fields := msg.Descriptor().Fields()
+	switch v := value.(type) {
+	case nil:
+		msg.Set(fields.ByName("null_value"), protoreflect.ValueOfEnum(0))
+	case string:
+		msg.Set(fields.ByName("string_value"), protoreflect.ValueOfString(v))
+	case bool:
+		msg.Set(fields.ByName("bool_value"), protoreflect.ValueOfBool(v))
+	case time.Time:
+		msg.Set(fields.ByName("string_value"), protoreflect.ValueOfString(v.Format(time.RFC3339Nano)))
+	case Record:
+		fd := fields.ByName("struct_value")
+		nested, _, err := recordValueToWellKnown(fd.Message(), v)
+		if err != nil {
+			return err
+		}
+		msg.Set(fd, nested)
+	default:
+		if isNumericKind(reflect.ValueOf(value).Kind()) {
+			number, _ := convertTo[float64](value)
+			msg.Set(fields.ByName("number_value"), protoreflect.ValueOfFloat64(number))
+			return nil
+		}
+		fd := fields.ByName("list_value")
+		list, _, err := recordValueToWellKnown(fd.Message(), value)
+		if err != nil {
+			return fmt.Errorf("cannot convert %T to protobuf %s", value, protoValue)
+		}
+		msg.Set(fd, list)
+	}
+	return nil
+}
+
+// convertToDuration converts a Record value to a time.Duration: a time.Duration, an
+// integer count of nanoseconds, or a string time.ParseDuration accepts such as "1m30s"
+func convertToDuration(value any) (time.Duration, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, true
+	case string:
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		return d, err == nil
+	case float32, float64:
+		return 0, false
+	}
+	if n, ok := convertToInt64(value); ok {
+		return time.Duration(n), true
+	}
+	return 0, false
+}
+
+// protobufEnumValue converts an enum's name, or its number, to the enum's value.
+// Numbers the enum doesn't declare are kept, as proto3 enums allow.
+func protobufEnumValue(enum protoreflect.EnumDescriptor, value any) (protoreflect.Value, error) {
+	if name, ok := value.(string); ok {
+		if enumValue := enum.Values().ByName(protoreflect.Name(strings.TrimSpace(name))); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
+		}
+		if _, numeric := convertToInt64(name); !numeric {
+			return protoreflect.Value{}, fmt.Errorf("%q is not a value of enum %s", name, enum.FullName())
+		}
+	}
+	if number, ok := convertToInt64(value); ok && number >= math.MinInt32 && number <= math.MaxInt32 {
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(number)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("cannot convert %T %v to enum %s", value, value, enum.FullName())
+}
+
+// ignoreUnknownField is the unknownFieldFunc for values with no record fields of their own
+func ignoreUnknownField(string, protoreflect.MessageDescriptor) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"weak"
+)
+
+// ============================================================================
+// ORDERED RECORDS - FIELD ORDER KEPT BESIDE THE MAP
+// ============================================================================
+
+// recordOrders holds the field order of ordered records, keyed weakly by the record's
+// map so an entry never keeps its record alive and goes once the record is collected
+var recordOrders sync.Map // weak.Pointer[byte] -> []string
+
+// orderedRecords counts the entries in recordOrders, so pipelines with no ordered
+// records skip looking theirs up
+var orderedRecords atomic.Int64
+
+// SetFieldOrder makes r an ordered record whose fields come in the given order from
+// Keys and the sinks, and returns r. Fields r gains later, or that fields leaves out,
+// follow in sorted order; listed fields r doesn't have are skipped. Select, Update,
+// RenameFields, DropFields, Record.Set and joins carry the order to the records they
+// build, appending fields they add. NewRecord().Ordered() builds ordered records too.
+//
+// Example:
+//
+//	row := SetFieldOrder(Record{"name": "Alice", "age": int64(30), "id": int64(7)}, "id", "name", "age")
+//	StreamToCSV(Once(row), os.Stdout) // id,name,age
+func SetFieldOrder(r Record, fields ...string) Record {
+	ptr := recordPointer(r)
+	if ptr == nil {
+		return r
+	}
+	key := weak.Make(ptr)
+	if _, loaded := recordOrders.Swap(key, append([]string(nil), fields...)); !loaded {
+		orderedRecords.Add(1)
+		runtime.AddCleanup(ptr, forgetFieldOrder, key)
+	}
+	return r
+}
+
+// fieldOrder returns the order r was given with SetFieldOrder, or nil if it has none
+func fieldOrder(r Record) []string {
+	if orderedRecords.Load() == 0 {
+		return nil
+	}
+	ptr := recordPointer(r)
+	if ptr == nil {
+		return nil
+	}
+	if fields, ok := recordOrders.Load(weak.Make(ptr)); ok {
+		return fields.([]string)
+	}
+	return nil
+}
+
+// clearFieldOrder drops r's order, for a record about to be reused
+func clearFieldOrder(r Record) {
+	if orderedRecords.Load() == 0 {
+		return
+	}
+	if ptr := recordPointer(r); ptr != nil {
+		forgetFieldOrder(weak.Make(ptr))
+	}
+}
+
+// forgetFieldOrder removes an entry from recordOrders, once
+func forgetFieldOrder(key weak.Pointer[byte]) {
+	if _, loaded := recordOrders.LoadAndDelete(key); loaded {
+		orderedRecords.Add(-1)
+	}
+}
+
+// recordPointer returns the address identifying r's map, nil for a nil record
+func recordPointer(r Record) *byte {
+	return (*byte)(reflect.ValueOf(r).UnsafePointer())
+}
+
+// orderedKeys returns r's fields in its order, then any fields the order leaves out
+// in sorted order
+func orderedKeys(r Record, order []string) []string {
+	keys := make([]string, 0, len(r))
+	listed := make(map[string]bool, len(order))
+	for _, field := range order {
+		if _, exists := r[field]; exists && !listed[field] {
+			listed[field] = true
+			keys = append(keys, field)
+		}
+	}
+	rest := len(keys)
+	for field := range r {
+		if !listed[field] {
+			keys = append(keys, field)
+		}
+	}
+	sort.Strings(keys[rest:])
+	return keys
+}
+
+// inheritFieldOrder orders result after the ordered records among sources: their
+// fields in order, source by source, then the fields result adds. It does nothing
+// when no source is ordered.
+func inheritFieldOrder(result Record, sources ...Record) {
+	if orderedRecords.Load() == 0 {
+		return
+	}
+	var order []string
+	ordered := false
+	for _, source := range sources {
+		if sourceOrder := fieldOrder(source); sourceOrder != nil {
+			ordered = true
+			order = append(order, orderedKeys(source, sourceOrder)...)
+		} else {
+			order = append(order, source.Keys()...)
+		}
+	}
+	if ordered {
+		SetFieldOrder(result, orderedKeys(result, order)...)
+	}
+}
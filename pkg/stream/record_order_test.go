@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRecordKeys tests that Keys is sorted for plain records and follows the field order of ordered ones
+func TestRecordKeys(t *testing.T) {
+	t.Run("Sorted", func(t *testing.T) {
+		r := Record{"zeta": 1, "alpha": 2, "mid": 3, "beta": 4}
+		for i := 0; i < 20; i++ {
+			if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"alpha", "beta", "mid", "zeta"}) {
+				t.Fatalf("Expected sorted keys, got %v", keys)
+			}
+		}
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		r := NewRecord().Ordered().Int("id", 1).String("name", "a").Float("score", 2).Int("id", 3).Build()
+		if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"id", "name", "score"}) {
+			t.Errorf("Expected the order fields were first set in, got %v", keys)
+		}
+		if r["id"] != int64(3) {
+			t.Errorf("Expected the last value set, got %v", r["id"])
+		}
+		if order := fieldOrder(NewRecord().Int("b", 1).Int("a", 2).Build()); order != nil {
+			t.Errorf("Expected no order without Ordered, got %v", order)
+		}
+	})
+
+	t.Run("SetFieldOrder", func(t *testing.T) {
+		r := SetFieldOrder(Record{"c": 1, "b": 2, "a": 3, "z": 4, "y": 5}, "c", "gone", "a")
+		if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"c", "a", "b", "y", "z"}) {
+			t.Errorf("Expected listed fields then the rest sorted, got %v", keys)
+		}
+	})
+
+	t.Run("Propagation", func(t *testing.T) {
+		row := NewRecord().Ordered().Int("id", 1).String("name", "a").String("city", "x").Float("score", 2).Build()
+		tests := []struct {
+			name     string
+			filter   Filter[Record, Record]
+			expected []string
+		}{
+			{"Select", Select("score", "id", "name"), []string{"id", "name", "score"}},
+			{"SelectPattern", SelectPattern("*e"), []string{"name", "score"}},
+			{"DropFields", DropFields("name"), []string{"id", "city", "score"}},
+			{"AddField", AddField("bonus", func(Record) any { return int64(1) }), []string{"id", "name", "city", "score", "bonus"}},
+			{"RenameFields", RenameFields(map[string]string{"name": "full_name"}), []string{"id", "full_name", "city", "score"}},
+			{"Update", Update(func(r Record) Record {
+				return Record{"score": r["score"], "id": r["id"], "added": true}
+			}), []string{"id", "score", "added"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				records, err := Collect(tt.filter(Once(row)))
+				if err != nil || len(records) != 1 {
+					t.Fatalf("Expected one record, got %v (%v)", records, err)
+				}
+				if keys := records[0].Keys(); !reflect.DeepEqual(keys, tt.expected) {
+					t.Errorf("Expected %v, got %v", tt.expected, keys)
+				}
+			})
+		}
+	})
+
+	t.Run("ReleaseRecord", func(t *testing.T) {
+		r := AcquireRecord(2)
+		r["b"], r["a"] = 1, 2
+		SetFieldOrder(r, "b", "a")
+		ReleaseRecord(r)
+		if order := fieldOrder(r); order != nil {
+			t.Errorf("Expected a released record to lose its order, got %v", order)
+		}
+	})
+}
+
+// TestOrderedRecordSinks tests that the sinks write the same output on every run, in field order for ordered records
+func TestOrderedRecordSinks(t *testing.T) {
+	users := func() Stream[Record] {
+		return FromSlice([]Record{
+			NewRecord().Ordered().Int("user_id", 1).String("name", "Alice").String("email", "a@x").Build(),
+			NewRecord().Ordered().Int("user_id", 2).String("name", "Bob").String("email", "b@x").Build(),
+		})
+	}
+	lookup := map[string]Record{
+		"1": NewRecord().Ordered().String("team", "red").Int("rank", 3).Build(),
+		"2": NewRecord().Ordered().String("team", "blue").Int("rank", 1).Build(),
+	}
+	pipeline := func() Stream[Record] {
+		return Chain(
+			Select("user_id", "name"),
+			LookupJoin(lookup, "user_id", JoinInner),
+		)(users())
+	}
+	plain := func() Stream[Record] {
+		return FromSlice([]Record{{"zeta": int64(1), "alpha": "a", "mid": true}, {"zeta": int64(2), "alpha": "b", "mid": false}})
+	}
+
+	sinks := []struct {
+		name  string
+		write func(Stream[Record], *bytes.Buffer) error
+	}{
+		{"CSV", func(s Stream[Record], buf *bytes.Buffer) error { return NewCSVSink(buf).WriteStream(s) }},
+		{"JSONLines", func(s Stream[Record], buf *bytes.Buffer) error { return NewJSONSink(buf).WriteStream(s) }},
+		{"JSONArray", func(s Stream[Record], buf *bytes.Buffer) error {
+			return NewJSONSink(buf).WithFormat(JSONArray).WithPrettyPrint().WriteStream(s)
+		}},
+		{"Table", func(s Stream[Record], buf *bytes.Buffer) error { return WriteTable(s, buf) }},
+	}
+	inputs := []struct {
+		name   string
+		source func() Stream[Record]
+		fields []string
+	}{
+		{"Ordered", users, []string{"user_id", "name", "email"}},
+		{"SelectJoin", pipeline, []string{"user_id", "name", "team", "rank"}},
+		{"Plain", plain, []string{"alpha", "mid", "zeta"}},
+	}
+
+	for _, sink := range sinks {
+		for _, input := range inputs {
+			t.Run(sink.name+"/"+input.name, func(t *testing.T) {
+				var first bytes.Buffer
+				if err := sink.write(input.source(), &first); err != nil {
+					t.Fatalf("Failed to write: %v", err)
+				}
+				for run := 0; run < 20; run++ {
+					var again bytes.Buffer
+					if err := sink.write(input.source(), &again); err != nil {
+						t.Fatalf("Failed to write: %v", err)
+					}
+					if again.String() != first.String() {
+						t.Fatalf("Expected the same output on every run, got\n%s\nthen\n%s", first.String(), again.String())
+					}
+				}
+
+				// Fields appear in order within the first row
+				out := first.String()
+				last := -1
+				for _, field := range input.fields {
+					at := strings.Index(out, field)
+					if at <= last {
+						t.Fatalf("Expected fields in the order %v, got\n%s", input.fields, out)
+					}
+					last = at
+				}
+			})
+		}
+	}
+}
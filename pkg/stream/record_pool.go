@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"reflect"
+	"sync"
+)
+
+// ============================================================================
+// RECORD POOLING - REUSING RECORD MAPS ACROSS PIPELINE STAGES
+// ============================================================================
+
+// maxPooledFields bounds the records kept for reuse; a cleared map keeps its buckets,
+// so pooling the odd very wide record would pin its memory
+const maxPooledFields = 64
+
+var recordPool sync.Pool
+
+// AcquireRecord returns an empty Record for about sizeHint fields, reusing one given to
+// ReleaseRecord if there is one. Select, joins, SetField, Record.Set and DotFlatten build
+// their output records with it.
+func AcquireRecord(sizeHint int) Record {
+	if r, ok := recordPool.Get().(Record); ok {
+		return r
+	}
+	return make(Record, sizeHint)
+}
+
+// ReleaseRecord clears r and keeps it for AcquireRecord to hand out again. Only release a
+// record nothing else refers to: not one still held by a collected slice, a Tee branch,
+// a window or a join table, and not one passed on downstream. Nested records are not
+// released. Releasing is optional - records never released are garbage collected as usual.
+//
+// Example:
+//
+//	err := ForEach(func(r Record) {
+//	    writeRow(r)
+//	    ReleaseRecord(r) // writeRow kept nothing
+//	})(Select("id", "name")(users))
+func ReleaseRecord(r Record) {
+	if r == nil || len(r) > maxPooledFields {
+		return
+	}
+	clearFieldOrder(r)
+	clear(r)
+	recordPool.Put(r)
+}
+
+// RecycleInputs wraps a record-to-record stage so each record it reads is released once
+// the stage has moved on to the next one, letting the records the stage builds reuse the
+// maps of those it consumed. An input the stage emits as is, as Where and Update can, is
+// left alone. It is opt-in because it is only safe when the stage's input records are
+// referenced nowhere else - not collected, teed or buffered upstream - and the stage itself
+// neither keeps them (windows, sorts, Distinct) nor emits one after reading the next.
+//
+// Example:
+//
+//	enriched := Pipe(
+//	    Select("user_id", "amount"),
+//	    RecycleInputs(LookupJoin(users, "user_id", JoinInner)), // Select's records end here
+//	)(events)
+func RecycleInputs(filter Filter[Record, Record]) Filter[Record, Record] {
+	return func(input Stream[Record]) Stream[Record] {
+		var current Record     // The record the stage read last
+		passedThrough := false // current was emitted as is, so downstream may hold it
+
+		recycling := func() (Record, error) {
+			if current != nil && !passedThrough {
+				ReleaseRecord(current)
+			}
+			record, err := input()
+			current, passedThrough = record, false
+			return record, err
+		}
+
+		output := filter(recycling)
+		return func() (Record, error) {
+			record, err := output()
+			if err == nil && current != nil && sameRecord(record, current) {
+				passedThrough = true
+			}
+			return record, err
+		}
+	}
+}
+
+// sameRecord reports whether a and b are the same map, not merely equal ones
+func sameRecord(a, b Record) bool {
+	return reflect.ValueOf(a).UnsafePointer() == reflect.ValueOf(b).UnsafePointer()
+}
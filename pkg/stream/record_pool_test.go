@@ -0,0 +1,197 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestRecordPool tests reusing record maps through AcquireRecord and RecycleInputs
+func TestRecordPool(t *testing.T) {
+	t.Run("AcquireIsEmpty", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			r := AcquireRecord(2)
+			if len(r) != 0 {
+				t.Fatalf("Expected an empty record, got %v", r)
+			}
+			r["id"], r["name"] = int64(i), "x"
+			ReleaseRecord(r)
+		}
+		ReleaseRecord(nil) // Harmless
+	})
+
+	t.Run("PassThroughKept", func(t *testing.T) {
+		var input []Record
+		for i := 0; i < 1000; i++ {
+			input = append(input, Record{"id": int64(i), "even": i%2 == 0})
+		}
+		evens, err := Collect(RecycleInputs(Where(func(r Record) bool {
+			return GetOr(r, "even", false)
+		}))(Select("id", "even")(FromRecordsUnsafe(input))))
+		if err != nil || len(evens) != 500 {
+			t.Fatalf("Expected 500 records, got %d (%v)", len(evens), err)
+		}
+		// Dropped records were released and reused; the ones passed on must not have been
+		for i, r := range evens {
+			if GetOr(r, "id", int64(-1)) != int64(i*2) || !GetOr(r, "even", false) {
+				t.Fatalf("Expected record %d to be intact, got %v", i, r)
+			}
+		}
+	})
+
+	t.Run("JoinOutputsValid", func(t *testing.T) {
+		users := map[string]Record{}
+		for i := 0; i < 10; i++ {
+			users[fmt.Sprint(i)] = Record{"user_id": int64(i), "name": fmt.Sprintf("user-%d", i)}
+		}
+		var input []Record
+		for i := 0; i < 1000; i++ {
+			input = append(input, Record{"user_id": int64(i % 10), "amount": int64(i), "noise": "x"})
+		}
+		joined, err := Collect(Pipe(
+			Select("user_id", "amount"),
+			RecycleInputs(LookupJoin(users, "user_id", JoinInner)),
+		)(FromRecordsUnsafe(input)))
+		if err != nil || len(joined) != 1000 {
+			t.Fatalf("Expected 1000 records, got %d (%v)", len(joined), err)
+		}
+		for i, r := range joined {
+			if r["amount"] != int64(i) || r["name"] != fmt.Sprintf("user-%d", i%10) {
+				t.Fatalf("Expected record %d to be intact, got %v", i, r)
+			}
+		}
+	})
+
+	// Records handed to callbacks on other goroutines must stay untouched while the
+	// pipeline carries on recycling the ones it consumed; run with -race
+	t.Run("ConcurrentConsumers", func(t *testing.T) {
+		const n = 20000
+		next := 0
+		source := func() (Record, error) {
+			if next >= n {
+				return nil, EOS
+			}
+			r := AcquireRecord(3)
+			r["id"], r["value"], r["drop"] = int64(next), int64(next*3), "x"
+			next++
+			return r, nil
+		}
+		pipeline := Pipe(
+			RecycleInputs(Select("id", "value")),
+			Chain(
+				RecycleInputs(Update(func(r Record) Record { return r.Set("double", GetOr(r, "value", int64(0))*2) })),
+				RecycleInputs(Where(func(r Record) bool { return GetOr(r, "id", int64(0))%3 != 0 })),
+			),
+		)(source)
+
+		records := make(chan Record, 64)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var failures []string
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for r := range records {
+					id := GetOr(r, "id", int64(-1))
+					if r["value"] != id*3 || r["double"] != id*6 || r["drop"] != nil {
+						mu.Lock()
+						failures = append(failures, fmt.Sprint(r))
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+		for {
+			r, err := pipeline()
+			if err != nil {
+				break
+			}
+			records <- r
+		}
+		close(records)
+		wg.Wait()
+		if len(failures) > 0 {
+			t.Errorf("Expected records to stay intact, %d weren't, e.g. %s", len(failures), failures[0])
+		}
+	})
+}
+
+// recordSource generates n fresh records with a few fields, acquired from the pool
+// when pooled is set
+func recordSource(n int, pooled bool) Stream[Record] {
+	i := 0
+	return func() (Record, error) {
+		if i >= n {
+			return nil, EOS
+		}
+		var r Record
+		if pooled {
+			r = AcquireRecord(5)
+		} else {
+			r = make(Record, 5)
+		}
+		r["id"], r["user_id"], r["amount"], r["region"], r["note"] = int64(i), int64(i%1000), float64(i), "north", "unused"
+		i++
+		return r, nil
+	}
+}
+
+// BenchmarkSelectPipeline compares a five-stage record pipeline allocating a map per
+// record per stage with the same pipeline recycling consumed records
+func BenchmarkSelectPipeline(b *testing.B) {
+	const records = 100000
+	stages := func(recycle func(Filter[Record, Record]) Filter[Record, Record]) Filter[Record, Record] {
+		return Chain(
+			recycle(Select("id", "user_id", "amount", "region")),
+			recycle(Update(func(r Record) Record { return r.Set("tax", GetOr(r, "amount", 0.0)*0.2) })),
+			recycle(RenameFields(map[string]string{"region": "area"})),
+			recycle(Select("id", "amount", "tax", "area")),
+			recycle(DotFlatten(".")),
+		)
+	}
+
+	b.Run("Allocating", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ForEach(func(Record) {})(stages(func(f Filter[Record, Record]) Filter[Record, Record] { return f })(recordSource(records, false)))
+		}
+	})
+
+	b.Run("Recycled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ForEach(ReleaseRecord)(stages(RecycleInputs)(recordSource(records, true)))
+		}
+	})
+}
+
+// BenchmarkJoin10M compares joining 10M records against a lookup table with and
+// without recycling the records each stage consumes
+func BenchmarkJoin10M(b *testing.B) {
+	const records = 10_000_000
+	users := make(map[string]Record, 1000)
+	for i := 0; i < 1000; i++ {
+		users[fmt.Sprint(i)] = Record{"user_id": int64(i), "name": fmt.Sprintf("user-%d", i)}
+	}
+	pipeline := func(recycle func(Filter[Record, Record]) Filter[Record, Record]) Filter[Record, Record] {
+		return Pipe(
+			recycle(Select("user_id", "amount")),
+			recycle(LookupJoin(users, "user_id", JoinInner)),
+		)
+	}
+
+	b.Run("Allocating", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ForEach(func(Record) {})(pipeline(func(f Filter[Record, Record]) Filter[Record, Record] { return f })(recordSource(records, false)))
+		}
+	})
+
+	b.Run("Recycled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ForEach(ReleaseRecord)(pipeline(RecycleInputs)(recordSource(records, true)))
+		}
+	})
+}
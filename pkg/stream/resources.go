@@ -0,0 +1,191 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ============================================================================
+// RESOURCE MANAGEMENT - RELEASING WHAT A STREAM HOLDS
+// ============================================================================
+
+// WithCleanup runs cleanup exactly once, when stream first returns an error - EOS or
+// any other - so whatever it reads from can be released as soon as it is done. The
+// error is sticky: later pulls return it again without pulling stream.
+//
+// Example:
+//
+//	conn := dial()
+//	rows := WithCleanup(readRows(conn), func() { conn.Close() })
+func WithCleanup[T any](stream Stream[T], cleanup func()) Stream[T] {
+	return Fuse(func() (T, error) {
+		item, err := stream()
+		if err != nil {
+			cleanup()
+		}
+		return item, err
+	})
+}
+
+// onceCloser closes a file at most once, whichever of the stream ending and an
+// explicit Close gets there first. A nil *onceCloser closes nothing.
+type onceCloser struct {
+	closer io.Closer
+	once   sync.Once
+	err    error
+}
+
+// Close closes the file the first time it is called and returns that result every time
+func (c *onceCloser) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.once.Do(func() {
+		c.err = c.closer.Close()
+	})
+	return c.err
+}
+
+// closeOnEnd closes a source's file, if it opened one, once stream ends
+func closeOnEnd[T any](stream Stream[T], closer *onceCloser) Stream[T] {
+	if closer == nil {
+		return stream
+	}
+	return WithCleanup(stream, func() { closer.Close() })
+}
+
+// openFileStream opens filename and reads it with read, closing it when the stream ends
+func openFileStream(filename, kind string, read func(io.Reader) Stream[Record]) (Stream[Record], io.Closer, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s file %s: %w", kind, filename, err)
+	}
+	closer := &onceCloser{closer: file}
+	return closeOnEnd(read(file), closer), closer, nil
+}
+
+// OpenCSVFile reads a CSV file with a header row. The file is closed when the stream
+// ends; call Close on the returned io.Closer to release it when stopping earlier.
+// Closing twice is harmless.
+//
+// Example:
+//
+//	records, closer, err := OpenCSVFile("sales.csv")
+//	if err != nil {
+//	    return err
+//	}
+//	defer closer.Close()
+//	first10, err := Collect(Limit[Record](10)(records))
+func OpenCSVFile(filename string) (Stream[Record], io.Closer, error) {
+	return openFileStream(filename, "CSV", CSVToStream)
+}
+
+// OpenTSVFile reads a TSV file with a header row, like TSVToStream, closing it as
+// OpenCSVFile does
+func OpenTSVFile(filename string) (Stream[Record], io.Closer, error) {
+	return openFileStream(filename, "TSV", TSVToStream)
+}
+
+// OpenJSONFile reads a JSON Lines file, closing it as OpenCSVFile does
+func OpenJSONFile(filename string) (Stream[Record], io.Closer, error) {
+	return openFileStream(filename, "JSON", JSONToStream)
+}
+
+// ============================================================================
+// FILE OUTPUT - WRITING WHOLE FILES OR NONE
+// ============================================================================
+
+// FileWriteOption configures how StreamToCSVFile, StreamToTSVFile and StreamToJSONFile
+// write their file
+type FileWriteOption func(*fileWriteConfig)
+
+// fileWriteConfig holds file writing configuration
+type fileWriteConfig struct {
+	atomic bool
+}
+
+// WithAtomicWrite writes to a temporary file in the destination's directory and renames
+// it over the destination only once the whole stream is written and synced to disk, so
+// readers of the path see the old file or the complete new one, never a partial one. On
+// an error the temporary file is removed and the destination left as it was. A new file
+// gets mode 0644; one replacing an existing file keeps that file's mode.
+//
+// Example:
+//
+//	err := StreamToCSVFile(report, "daily.csv", WithAtomicWrite())
+func WithAtomicWrite() FileWriteOption {
+	return func(config *fileWriteConfig) {
+		config.atomic = true
+	}
+}
+
+// outputFile is a file being written by one of the StreamTo*File functions
+type outputFile struct {
+	*os.File
+	target string // The destination, when File is a temporary file renamed over it
+}
+
+// createOutputFile creates filename, or under WithAtomicWrite a temporary file beside it
+func createOutputFile(filename, kind string, options []FileWriteOption) (*outputFile, error) {
+	config := &fileWriteConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	if !config.atomic {
+		file, err := os.Create(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s file %s: %w", kind, filename, err)
+		}
+		return &outputFile{File: file}, nil
+	}
+
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s file %s: %w", kind, filename, err)
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to create %s file %s: %w", kind, filename, err)
+	}
+	return &outputFile{File: file, target: filename}, nil
+}
+
+// finish closes the file after a write that ended with writeErr. Under WithAtomicWrite it
+// then renames the temporary file over the destination, or removes it if anything failed.
+func (f *outputFile) finish(writeErr error) error {
+	if f.target == "" {
+		closeErr := f.Close()
+		if writeErr != nil {
+			return writeErr
+		}
+		return closeErr
+	}
+
+	err := writeErr
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.target)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
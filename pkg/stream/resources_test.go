@@ -0,0 +1,277 @@
+package stream
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openFiles counts the process's open descriptors for files in dir, skipping where
+// /proc isn't available. Descriptors elsewhere, such as other tests' connections, are
+// ignored.
+func openFiles(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("Open descriptors can't be counted on this platform")
+	}
+	count := 0
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", entry.Name()))
+		if err == nil && strings.HasPrefix(target, dir) {
+			count++
+		}
+	}
+	return count
+}
+
+// TestResourceClosing tests that file-backed streams release their files
+func TestResourceClosing(t *testing.T) {
+	t.Run("WithCleanup", func(t *testing.T) {
+		cleanups := 0
+		stream := WithCleanup(FromSlice([]int64{1, 2}), func() { cleanups++ })
+		results, err := Collect(stream)
+		if err != nil || len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %v (%v)", results, err)
+		}
+		if _, err := stream(); err != EOS {
+			t.Errorf("Expected EOS to be sticky, got %v", err)
+		}
+		if cleanups != 1 {
+			t.Errorf("Expected cleanup to run once at EOS, ran %d times", cleanups)
+		}
+
+		failure := errors.New("boom")
+		cleanups = 0
+		failing := WithCleanup(func() (int64, error) { return 0, failure }, func() { cleanups++ })
+		for i := 0; i < 3; i++ {
+			if _, err := failing(); err != failure {
+				t.Errorf("Expected the failure, got %v", err)
+			}
+		}
+		if cleanups != 1 {
+			t.Errorf("Expected cleanup to run once on an error, ran %d times", cleanups)
+		}
+	})
+
+	t.Run("FromFileHelpers", func(t *testing.T) {
+		dir := t.TempDir()
+		csvPath := filepath.Join(dir, "data.csv")
+		tsvPath := filepath.Join(dir, "data.tsv")
+		jsonPath := filepath.Join(dir, "data.jsonl")
+		os.WriteFile(csvPath, []byte("id,name\n1,a\n2,b\n"), 0644)
+		os.WriteFile(tsvPath, []byte("id\tname\n1\ta\n2\tb\n"), 0644)
+		os.WriteFile(jsonPath, []byte("{\"id\":1}\n{\"id\":2}\n"), 0644)
+
+		readers := map[string]func() (Stream[Record], error){
+			"CSVToStreamFromFile":     func() (Stream[Record], error) { return CSVToStreamFromFile(csvPath) },
+			"TSVToStreamFromFile":     func() (Stream[Record], error) { return TSVToStreamFromFile(tsvPath) },
+			"FastTSVToStreamFromFile": func() (Stream[Record], error) { return FastTSVToStreamFromFile(tsvPath) },
+			"JSONToStreamFromFile":    func() (Stream[Record], error) { return JSONToStreamFromFile(jsonPath) },
+			"NewCSVSourceFromFile": func() (Stream[Record], error) {
+				source, err := NewCSVSourceFromFile(csvPath)
+				if err != nil {
+					return nil, err
+				}
+				return source.ToStream(), nil
+			},
+			"NewJSONSourceFromFile": func() (Stream[Record], error) {
+				source, err := NewJSONSourceFromFile(jsonPath)
+				if err != nil {
+					return nil, err
+				}
+				return source.ToStream(), nil
+			},
+		}
+
+		for name, open := range readers {
+			for i := 0; i < 200; i++ {
+				stream, err := open()
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if records, err := Collect(stream); err != nil || len(records) != 2 {
+					t.Fatalf("%s: expected 2 records, got %d (%v)", name, len(records), err)
+				}
+			}
+		}
+		if open := openFiles(t, dir); open != 0 {
+			t.Errorf("Expected all files to be closed, %d left open", open)
+		}
+	})
+
+	t.Run("CloseEarly", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.csv")
+		os.WriteFile(path, []byte("id\n1\n2\n3\n"), 0644)
+
+		records, closer, err := OpenCSVFile(path)
+		if err != nil {
+			t.Fatalf("Failed to open: %v", err)
+		}
+		if _, err := records(); err != nil {
+			t.Fatalf("Failed to read the first record: %v", err)
+		}
+		if err := closer.Close(); err != nil {
+			t.Errorf("Failed to close: %v", err)
+		}
+		if err := closer.Close(); err != nil {
+			t.Errorf("Expected a second Close to be harmless, got %v", err)
+		}
+		if open := openFiles(t, dir); open != 0 {
+			t.Errorf("Expected the file to be closed, %d left open", open)
+		}
+
+		source, err := NewJSONSourceFromFile(path)
+		if err != nil {
+			t.Fatalf("Failed to open: %v", err)
+		}
+		if err := source.Close(); err != nil {
+			t.Errorf("Failed to close: %v", err)
+		}
+		if err := NewCSVSource(nil).Close(); err != nil {
+			t.Errorf("Expected Close on a reader source to do nothing, got %v", err)
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		if _, _, err := OpenJSONFile(filepath.Join(t.TempDir(), "missing.jsonl")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected a not-exist error, got %v", err)
+		}
+	})
+}
+
+// failingAfter returns a stream of n records followed by failure
+func failingAfter(n int, failure error) Stream[Record] {
+	i := 0
+	return func() (Record, error) {
+		if i >= n {
+			return nil, failure
+		}
+		i++
+		return Record{"id": int64(i), "name": "row"}, nil
+	}
+}
+
+// TestAtomicFileWrite tests that WithAtomicWrite leaves the destination whole or untouched
+func TestAtomicFileWrite(t *testing.T) {
+	writers := map[string]func(Stream[Record], string, ...FileWriteOption) error{
+		"CSV":  StreamToCSVFile,
+		"TSV":  StreamToTSVFile,
+		"JSON": StreamToJSONFile,
+	}
+	failure := errors.New("upstream failed")
+
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out")
+
+			// Without the option a failure leaves the rows written so far
+			if err := write(failingAfter(3, failure), path); !errors.Is(err, failure) {
+				t.Fatalf("Expected the stream's error, got %v", err)
+			}
+			if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), "3") {
+				t.Errorf("Expected a partial file without WithAtomicWrite, got %q (%v)", data, err)
+			}
+			os.Remove(path)
+
+			if err := write(failingAfter(3, failure), path, WithAtomicWrite()); !errors.Is(err, failure) {
+				t.Fatalf("Expected the stream's error, got %v", err)
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Expected no file after a failed atomic write, got %v", err)
+			}
+
+			if err := write(failingAfter(2, EOS), path, WithAtomicWrite()); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			complete, err := os.ReadFile(path)
+			if err != nil || !strings.Contains(string(complete), "2") {
+				t.Fatalf("Expected the complete file, got %q (%v)", complete, err)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+				t.Errorf("Expected mode 0644, got %v", info.Mode().Perm())
+			}
+
+			// A failed rewrite keeps the previous file as it was
+			os.Chmod(path, 0600)
+			if err := write(failingAfter(5, failure), path, WithAtomicWrite()); !errors.Is(err, failure) {
+				t.Fatalf("Expected the stream's error, got %v", err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != string(complete) {
+				t.Errorf("Expected the previous file to survive, got %q", data)
+			}
+			if err := write(failingAfter(1, EOS), path, WithAtomicWrite()); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+				t.Errorf("Expected the replaced file's mode 0600, got %v", info.Mode().Perm())
+			}
+
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("Expected only the destination to remain, got %d entries", len(entries))
+			}
+		})
+	}
+
+	t.Run("MissingDirectory", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing", "out.csv")
+		if err := StreamToCSVFile(failingAfter(1, EOS), path, WithAtomicWrite()); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected a not-exist error, got %v", err)
+		}
+	})
+}
+
+// syncCounter is a writer that counts its Sync calls
+type syncCounter struct {
+	strings.Builder
+	syncs int
+}
+
+func (s *syncCounter) Sync() error {
+	s.syncs++
+	return nil
+}
+
+// TestFsyncEvery tests that the streaming sinks sync every n records and at the end
+func TestFsyncEvery(t *testing.T) {
+	t.Run("StreamingCSVWriter", func(t *testing.T) {
+		var out syncCounter
+		writer := NewStreamingCSVWriter(&out, nil).WithFsyncEvery(2)
+		for i := 0; i < 5; i++ {
+			if err := writer.WriteRecord(Record{"id": int64(i)}); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+		}
+		if out.syncs != 2 {
+			t.Errorf("Expected 2 syncs after 5 records, got %d", out.syncs)
+		}
+		if err := writer.Close(); err != nil || out.syncs != 3 {
+			t.Errorf("Expected Close to sync the last record, got %d syncs (%v)", out.syncs, err)
+		}
+	})
+
+	t.Run("JSONSink", func(t *testing.T) {
+		var out syncCounter
+		if err := NewJSONSink(&out).WithFsyncEvery(2).WriteStream(failingAfter(4, EOS)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if out.syncs != 2 {
+			t.Errorf("Expected 2 syncs for 4 records, got %d", out.syncs)
+		}
+
+		out = syncCounter{}
+		if err := NewJSONSink(&out).WithFormat(JSONArray).WithFsyncEvery(10).WriteStream(failingAfter(4, EOS)); err != nil || out.syncs != 1 {
+			t.Errorf("Expected one sync for the array, got %d (%v)", out.syncs, err)
+		}
+
+		out = syncCounter{}
+		if err := NewJSONSink(&out).WriteStream(failingAfter(4, EOS)); err != nil || out.syncs != 0 {
+			t.Errorf("Expected no syncs by default, got %d (%v)", out.syncs, err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package stream
+
+// ============================================================================
+// RESULTS - PER-ELEMENT ERRORS THAT DON'T END THE STREAM
+// ============================================================================
+
+// Result is one element of a stream whose elements can fail individually,
+// such as rows of a CSV file. Exactly one of Value and Err is meaningful.
+type Result[T any] struct {
+	Value T
+	Err   error
+	Index int64  // 0-based position of the element in its source
+	Raw   string // Source text of a failed element, when known
+}
+
+// ToResults wraps each element of a stream in a Result, turning non-EOS errors into
+// failed Results and carrying on with the next element. Use it with sources that move
+// on after a failed element; a source that returns the same error message twice in a
+// row is treated as failed for good and ends the stream with that error.
+func ToResults[T any]() Filter[T, Result[T]] {
+	return func(input Stream[T]) Stream[Result[T]] {
+		var index int64
+		var lastErr error
+		return func() (Result[T], error) {
+			item, err := input()
+			if err == EOS || (err != nil && lastErr != nil && err.Error() == lastErr.Error()) {
+				return Result[T]{}, err
+			}
+			lastErr = err
+
+			result := Result[T]{Value: item, Err: err, Index: index}
+			index++
+			return result, nil
+		}
+	}
+}
+
+// FromResults unwraps a Result stream, ending it with the first failed element's error
+func FromResults[T any]() Filter[Result[T], T] {
+	return func(input Stream[Result[T]]) Stream[T] {
+		return func() (T, error) {
+			result, err := input()
+			if err != nil {
+				var zero T
+				return zero, err
+			}
+			return result.Value, result.Err
+		}
+	}
+}
+
+// PartitionResults splits a Result stream into the values of successful elements and
+// the failed Results, each in source order. Both streams are branches of a Tee, so they
+// may be read in any order; elements one stream hasn't read yet are buffered for it.
+func PartitionResults[T any](input Stream[Result[T]]) (Stream[T], Stream[Result[T]]) {
+	branches := Tee(input, 2)
+
+	values := func() (T, error) {
+		for {
+			result, err := branches[0]()
+			if err != nil {
+				var zero T
+				return zero, err
+			}
+			if result.Err == nil {
+				return result.Value, nil
+			}
+		}
+	}
+	failures := Where(func(result Result[T]) bool {
+		return result.Err != nil
+	})(branches[1])
+
+	return values, failures
+}
+
+// ErrorsToRecords converts the failed elements of a Result stream to Records with
+// "error" (the message), "index" (int64) and "raw" fields, ready for a dead-letter
+// JSONSink. Successful elements are skipped.
+func ErrorsToRecords[T any]() Filter[Result[T], Record] {
+	return func(input Stream[Result[T]]) Stream[Record] {
+		return func() (Record, error) {
+			for {
+				result, err := input()
+				if err != nil {
+					return nil, err
+				}
+				if result.Err == nil {
+					continue
+				}
+				return Record{
+					"error": result.Err.Error(),
+					"index": result.Index,
+					"raw":   result.Raw,
+				}, nil
+			}
+		}
+	}
+}
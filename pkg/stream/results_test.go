@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestResults tests per-element error handling with Result streams
+func TestResults(t *testing.T) {
+	// Rows 1, 3 and 5 (0-based) have the wrong number of fields
+	csvData := "name,age\nAlice,30\nBob\nCarol,25\nDave,40,extra\nEve,35\nFrank\nGrace,28"
+
+	t.Run("CSVPartition", func(t *testing.T) {
+		results := NewCSVSource(strings.NewReader(csvData)).ToResults()
+		good, bad := PartitionResults(results)
+
+		// Read the failures first; the good records are buffered meanwhile
+		var deadLetters bytes.Buffer
+		if err := NewJSONSink(&deadLetters).WriteStream(ErrorsToRecords[Record]()(bad)); err != nil {
+			t.Fatalf("Failed to write dead letters: %v", err)
+		}
+		records, err := Collect(good)
+		if err != nil {
+			t.Fatalf("Failed to collect good records: %v", err)
+		}
+
+		var names []string
+		for _, r := range records {
+			names = append(names, GetOr(r, "name", ""))
+		}
+		if strings.Join(names, ",") != "Alice,Carol,Eve,Grace" {
+			t.Errorf("Expected the 4 good rows in order, got %v", names)
+		}
+
+		errorRecords, err := Collect(NewJSONSource(&deadLetters).ToStream())
+		if err != nil {
+			t.Fatalf("Failed to read dead letters: %v", err)
+		}
+		if len(errorRecords) != 3 {
+			t.Fatalf("Expected 3 error records, got %v", errorRecords)
+		}
+		for i, expected := range []struct {
+			index int64
+			raw   string
+		}{{1, "Bob"}, {3, "Dave,40,extra"}, {5, "Frank"}} {
+			got := errorRecords[i]
+			if GetOr(got, "index", int64(-1)) != expected.index || got["raw"] != expected.raw {
+				t.Errorf("Error %d: expected index %d raw %q, got %v", i, expected.index, expected.raw, got)
+			}
+			if !strings.Contains(GetOr(got, "error", ""), "wrong number of fields") {
+				t.Errorf("Error %d: expected field count error, got %v", i, got["error"])
+			}
+		}
+	})
+
+	t.Run("CSVToStreamStopsAtBadRow", func(t *testing.T) {
+		records, err := Collect(NewCSVSource(strings.NewReader(csvData)).ToStream())
+		if err == nil || len(records) != 1 {
+			t.Errorf("Expected ToStream to end at the first bad row, got %d records (%v)", len(records), err)
+		}
+	})
+
+	t.Run("JSONLines", func(t *testing.T) {
+		input := "{\"id\": 1}\n\n{bad json}\n{\"id\": 3}\n"
+
+		results, err := Collect(NewJSONSource(strings.NewReader(input)).ToResults())
+		if err != nil {
+			t.Fatalf("Failed to collect results: %v", err)
+		}
+		if len(results) != 3 || results[1].Err == nil || results[1].Raw != "{bad json}" || results[1].Index != 1 {
+			t.Errorf("Expected the bad line as result 1, got %+v", results)
+		}
+		if results[2].Value["id"] != int64(3) || results[2].Index != 2 {
+			t.Errorf("Expected id 3 at index 2, got %+v", results[2])
+		}
+	})
+
+	t.Run("ToResultsAndBack", func(t *testing.T) {
+		bad := errors.New("bad element")
+		calls := 0
+		source := func() (int, error) {
+			calls++
+			switch {
+			case calls == 2:
+				return 0, bad
+			case calls > 3:
+				return 0, EOS
+			}
+			return calls, nil
+		}
+
+		results, err := Collect(ToResults[int]()(source))
+		if err != nil || len(results) != 3 || results[1].Err != bad || results[2].Value != 3 || results[2].Index != 2 {
+			t.Errorf("Expected value, failure, value, got %+v (%v)", results, err)
+		}
+
+		sticky := func() (int, error) { return 0, bad }
+		results, err = Collect(ToResults[int]()(sticky))
+		if err != bad || len(results) != 1 {
+			t.Errorf("Expected a repeated error to end the stream after one failed result, got %+v (%v)", results, err)
+		}
+
+		values, err := Collect(FromResults[int]()(FromSliceAny([]Result[int]{{Value: 1}, {Err: bad}})))
+		if err != bad || len(values) != 1 {
+			t.Errorf("Expected FromResults to stop at the failure, got %v (%v)", values, err)
+		}
+	})
+}
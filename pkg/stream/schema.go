@@ -0,0 +1,253 @@
+package stream
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ============================================================================
+// SCHEMA VALIDATION - ENFORCE FIELD TYPES ON UNTYPED INPUT
+// ============================================================================
+
+// FieldKind is the expected type of a schema field
+type FieldKind int
+
+const (
+	AnyKind    FieldKind = iota // Any non-nil value
+	IntKind                     // Integer types; coerced to int64
+	FloatKind                   // Floating point types; coerced to float64
+	StringKind                  // string
+	BoolKind                    // bool
+	TimeKind                    // time.Time
+)
+
+// String returns the kind name used in validation errors
+func (k FieldKind) String() string {
+	switch k {
+	case IntKind:
+		return "int"
+	case FloatKind:
+		return "float"
+	case StringKind:
+		return "string"
+	case BoolKind:
+		return "bool"
+	case TimeKind:
+		return "time"
+	default:
+		return "any"
+	}
+}
+
+// FieldSchema describes one field of a Schema
+type FieldSchema struct {
+	Kind      FieldKind
+	Required  bool // The field must be present
+	AllowNull bool // A nil value is accepted
+}
+
+// Schema maps field names to their expected shape.
+// Fields not in the schema pass through unchecked.
+type Schema map[string]FieldSchema
+
+// ValidationPolicy defines what ValidateSchema does with records that don't match the schema
+type ValidationPolicy int
+
+const (
+	FailFast    ValidationPolicy = iota // End the stream with a *SchemaError
+	DropInvalid                         // Skip invalid records
+	Coerce                              // Convert mismatched values, failing only when conversion is impossible
+)
+
+// SchemaError reports the first field of a record that does not match a Schema
+type SchemaError struct {
+	Row    int    // 1-based position of the record in the stream
+	Field  string // Offending field
+	Value  any    // Offending value (nil when the field is missing)
+	Reason string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("row %d: field %q %s", e.Row, e.Field, e.Reason)
+}
+
+// ValidationStats summarizes a ValidateSchema run
+type ValidationStats struct {
+	Records int // Records read from the input
+	Dropped int // Records skipped under DropInvalid
+	Coerced int // Field values converted under Coerce
+}
+
+// ValidationOption configures ValidateSchema behavior
+type ValidationOption func(*validationConfig)
+
+// validationConfig holds ValidateSchema configuration
+type validationConfig struct {
+	onStats   func(ValidationStats)
+	onInvalid func(err *SchemaError, record Record)
+}
+
+// WithValidationStats calls fn once when the input ends or validation fails
+func WithValidationStats(fn func(ValidationStats)) ValidationOption {
+	return func(config *validationConfig) {
+		config.onStats = fn
+	}
+}
+
+// WithInvalidRecordHandler calls fn for every record skipped under DropInvalid
+func WithInvalidRecordHandler(fn func(err *SchemaError, record Record)) ValidationOption {
+	return func(config *validationConfig) {
+		config.onInvalid = fn
+	}
+}
+
+// ValidateSchema checks every record against schema and applies policy to records that don't match.
+// Under FailFast and Coerce the stream ends with a *SchemaError naming the row and field;
+// coerced records are copies, so the input is never modified.
+// Example: ValidateSchema(Schema{"age": {Kind: IntKind, Required: true}}, DropInvalid)
+func ValidateSchema(schema Schema, policy ValidationPolicy, options ...ValidationOption) Filter[Record, Record] {
+	config := &validationConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	// Check fields in sorted order so the reported field is deterministic
+	fields := make([]string, 0, len(schema))
+	for field := range schema {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return func(input Stream[Record]) Stream[Record] {
+		var stats ValidationStats
+		var finalErr error // Sticky once the input ends or validation fails
+		finish := func(err error) error {
+			finalErr = err
+			if config.onStats != nil {
+				config.onStats(stats)
+			}
+			return err
+		}
+
+		return func() (Record, error) {
+			if finalErr != nil {
+				return nil, finalErr
+			}
+			for {
+				record, err := input()
+				if err != nil {
+					return nil, finish(err)
+				}
+				stats.Records++
+
+				result, coerced, schemaErr := checkSchema(record, schema, fields, policy == Coerce)
+				if schemaErr == nil {
+					stats.Coerced += coerced
+					return result, nil
+				}
+				schemaErr.Row = stats.Records
+
+				if policy != DropInvalid {
+					return nil, finish(schemaErr)
+				}
+				stats.Dropped++
+				if config.onInvalid != nil {
+					config.onInvalid(schemaErr, record)
+				}
+			}
+		}
+	}
+}
+
+// checkSchema checks record against schema, returning the (possibly coerced) record
+// and the number of values converted
+func checkSchema(record Record, schema Schema, fields []string, coerce bool) (Record, int, *SchemaError) {
+	result := record
+	coerced := 0
+	for _, field := range fields {
+		spec := schema[field]
+		value, exists := record[field]
+		switch {
+		case !exists:
+			if spec.Required {
+				return nil, 0, &SchemaError{Field: field, Reason: "is required but missing"}
+			}
+			continue
+		case value == nil:
+			if !spec.AllowNull {
+				return nil, 0, &SchemaError{Field: field, Reason: "is null"}
+			}
+			continue
+		case matchesKind(value, spec.Kind):
+			continue
+		case !coerce:
+			return nil, 0, &SchemaError{Field: field, Value: value,
+				Reason: fmt.Sprintf("expected %s, got %T %v", spec.Kind, value, value)}
+		}
+
+		converted, ok := coerceKind(value, spec.Kind)
+		if !ok {
+			return nil, 0, &SchemaError{Field: field, Value: value,
+				Reason: fmt.Sprintf("cannot convert %T %v to %s", value, value, spec.Kind)}
+		}
+		if coerced == 0 {
+			result = make(Record, len(record))
+			for k, v := range record {
+				result[k] = v
+			}
+		}
+		result[field] = converted
+		coerced++
+	}
+	return result, coerced, nil
+}
+
+// matchesKind reports whether value already has the expected kind
+func matchesKind(value any, kind FieldKind) bool {
+	switch kind {
+	case IntKind:
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+		return false
+	case FloatKind:
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Float32, reflect.Float64:
+			return true
+		}
+		return false
+	case StringKind:
+		_, ok := value.(string)
+		return ok
+	case BoolKind:
+		_, ok := value.(bool)
+		return ok
+	case TimeKind:
+		_, ok := value.(time.Time)
+		return ok
+	default:
+		return true
+	}
+}
+
+// coerceKind converts value to the canonical type for kind
+func coerceKind(value any, kind FieldKind) (any, bool) {
+	switch kind {
+	case IntKind:
+		return convertToInt64(value)
+	case FloatKind:
+		return convertToFloat64(value)
+	case StringKind:
+		return convertToString(value)
+	case BoolKind:
+		return convertToBool(value)
+	case TimeKind:
+		return convertToTime(value)
+	default:
+		return value, true
+	}
+}
@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestValidateSchema tests ValidateSchema under each validation policy
+func TestValidateSchema(t *testing.T) {
+	csvData := "name,age\nAlice,30\nBob,N/A\nCarol,25"
+	schema := Schema{
+		"name": {Kind: StringKind, Required: true},
+		"age":  {Kind: IntKind, Required: true},
+	}
+	source := func() Stream[Record] {
+		// Typed value by value, so the ages around N/A are ints
+		return NewCSVSource(strings.NewReader(csvData)).WithTypeInference(InferenceAggressive).ToStream()
+	}
+
+	t.Run("FailFast", func(t *testing.T) {
+		stream := ValidateSchema(schema, FailFast)(source())
+
+		first, err := stream()
+		if err != nil || first["name"] != "Alice" {
+			t.Fatalf("Expected Alice first, got %v (%v)", first, err)
+		}
+
+		_, err = stream()
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Fatalf("Expected *SchemaError, got %v", err)
+		}
+		if schemaErr.Row != 2 || schemaErr.Field != "age" || schemaErr.Value != "N/A" {
+			t.Errorf("Expected row 2 field age value N/A, got %+v", schemaErr)
+		}
+		if _, err := stream(); err != schemaErr {
+			t.Errorf("Expected error to be sticky, got %v", err)
+		}
+	})
+
+	t.Run("DropInvalid", func(t *testing.T) {
+		var stats ValidationStats
+		var invalidRows []int
+		stream := ValidateSchema(schema, DropInvalid,
+			WithValidationStats(func(s ValidationStats) { stats = s }),
+			WithInvalidRecordHandler(func(err *SchemaError, record Record) {
+				invalidRows = append(invalidRows, err.Row)
+			}),
+		)(source())
+
+		results, err := Collect(stream)
+		if err != nil {
+			t.Fatalf("Failed to collect records: %v", err)
+		}
+		if len(results) != 2 || results[0]["name"] != "Alice" || results[1]["name"] != "Carol" {
+			t.Errorf("Expected Alice and Carol, got %v", results)
+		}
+		if stats != (ValidationStats{Records: 3, Dropped: 1}) {
+			t.Errorf("Expected 3 records with 1 dropped, got %+v", stats)
+		}
+		if len(invalidRows) != 1 || invalidRows[0] != 2 {
+			t.Errorf("Expected row 2 reported invalid, got %v", invalidRows)
+		}
+	})
+
+	t.Run("CoerceUnconvertible", func(t *testing.T) {
+		_, err := Collect(ValidateSchema(schema, Coerce)(source()))
+
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) || schemaErr.Row != 2 || schemaErr.Field != "age" {
+			t.Errorf("Expected row 2 age conversion error, got %v", err)
+		}
+	})
+
+	t.Run("CoerceConvertible", func(t *testing.T) {
+		input := Record{"age": "42", "score": int64(3), "name": "Dave"}
+		coerceSchema := Schema{
+			"age":   {Kind: IntKind},
+			"score": {Kind: FloatKind},
+			"name":  {Kind: StringKind},
+		}
+		var stats ValidationStats
+
+		results, err := Collect(ValidateSchema(coerceSchema, Coerce,
+			WithValidationStats(func(s ValidationStats) { stats = s }),
+		)(FromRecordsUnsafe([]Record{input})))
+		if err != nil {
+			t.Fatalf("Failed to collect records: %v", err)
+		}
+		if results[0]["age"] != int64(42) || results[0]["score"] != 3.0 {
+			t.Errorf("Expected age=42 and score=3.0, got %v", results[0])
+		}
+		if input["age"] != "42" {
+			t.Errorf("Input record should not be modified, got %v", input)
+		}
+		if stats.Coerced != 2 {
+			t.Errorf("Expected 2 coerced values, got %d", stats.Coerced)
+		}
+	})
+
+	t.Run("RequiredAndNull", func(t *testing.T) {
+		nullSchema := Schema{
+			"id":    {Kind: IntKind, Required: true},
+			"email": {Kind: StringKind, AllowNull: true},
+			"note":  {Kind: StringKind},
+		}
+		records := []Record{
+			{"id": int64(1), "email": nil},
+			{"email": "x@y.z"},
+			{"id": int64(3), "note": nil},
+			{"id": 4, "extra": []int{1}},
+		}
+
+		var reasons []string
+		results, _ := Collect(ValidateSchema(nullSchema, DropInvalid,
+			WithInvalidRecordHandler(func(err *SchemaError, record Record) {
+				reasons = append(reasons, err.Error())
+			}),
+		)(FromRecordsUnsafe(records)))
+
+		if len(results) != 2 {
+			t.Errorf("Expected 2 valid records, got %v", results)
+		}
+		expected := []string{`row 2: field "id" is required but missing`, `row 3: field "note" is null`}
+		if strings.Join(reasons, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected %v, got %v", expected, reasons)
+		}
+	})
+}
@@ -330,4 +330,148 @@ func TestSortTimeWindow(t *testing.T) {
 
 	// Verify function doesn't panic and produces reasonable output
 	t.Logf("SortTimeWindow result: %v", result)
-}
\ No newline at end of file
+}
+
+// TestSortStability tests that records with equal sort keys keep their input order
+func TestSortStability(t *testing.T) {
+	data := []Record{
+		NewRecord().String("dept", "eng").String("name", "first").Build(),
+		NewRecord().String("dept", "ops").String("name", "second").Build(),
+		NewRecord().String("dept", "eng").String("name", "third").Build(),
+		NewRecord().String("dept", "ops").String("name", "fourth").Build(),
+		NewRecord().String("dept", "eng").String("name", "fifth").Build(),
+	}
+
+	result, err := Collect(SortBy("dept")(FromSlice(data)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Equal keys keep their input order
+	expected := []string{"first", "third", "fifth", "second", "fourth"}
+	for i, record := range result {
+		if name := GetOr(record, "name", ""); name != expected[i] {
+			t.Errorf("Expected %s at index %d, got %s", expected[i], i, name)
+		}
+	}
+}
+
+// TestSortByKeys tests sorting by multiple fields with mixed directions
+func TestSortByKeys(t *testing.T) {
+	data := []Record{
+		NewRecord().String("dept", "ops").Int("salary", 50).String("name", "A").Build(),
+		NewRecord().String("dept", "eng").Int("salary", 70).String("name", "B").Build(),
+		NewRecord().String("dept", "eng").Int("salary", 90).String("name", "C").Build(),
+		NewRecord().String("dept", "ops").Int("salary", 60).String("name", "D").Build(),
+	}
+
+	result, err := Collect(SortByKeys(Asc("dept"), Desc("salary"))(FromSlice(data)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"C", "B", "D", "A"}
+	for i, record := range result {
+		if name := GetOr(record, "name", ""); name != expected[i] {
+			t.Errorf("Expected %s at index %d, got %s", expected[i], i, name)
+		}
+	}
+}
+
+// TestSortByMixedTypes tests ordering of values of different types in one field
+func TestSortByMixedTypes(t *testing.T) {
+	data := []Record{
+		NewRecord().String("id", "s").Set("value", "text").Build(),
+		NewRecord().String("id", "f").Set("value", 2.5).Build(),
+		NewRecord().String("id", "i").Set("value", int64(2)).Build(),
+		NewRecord().String("id", "b").Set("value", true).Build(),
+		NewRecord().String("id", "small").Set("value", int32(-1)).Build(),
+		NewRecord().String("id", "big").Set("value", 10.0).Build(),
+	}
+
+	result, err := Collect(SortBy("value")(FromSlice(data)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// bool < numbers (compared numerically across int/float types) < strings
+	expected := []string{"b", "small", "i", "f", "big", "s"}
+	for i, record := range result {
+		if id := GetOr(record, "id", ""); id != expected[i] {
+			t.Errorf("Expected %s at index %d, got %s", expected[i], i, id)
+		}
+	}
+}
+
+// TestSortByTime tests that times sort chronologically, across time zones and
+// against RFC3339 strings
+func TestSortByTime(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	data := []Record{
+		NewRecord().String("id", "noon").Time("at", base).Build(),
+		NewRecord().String("id", "late").Set("at", base.Add(2*time.Hour).Format(time.RFC3339)).Build(),
+		NewRecord().String("id", "early").Time("at", base.Add(-time.Hour).In(tokyo)).Build(),
+		NewRecord().String("id", "later").Time("at", base.Add(3*time.Hour)).Build(),
+	}
+
+	result, err := Collect(SortByDesc("at")(FromSlice(data)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"later", "late", "noon", "early"}
+	for i, record := range result {
+		if id := GetOr(record, "id", ""); id != expected[i] {
+			t.Errorf("Expected %s at index %d, got %s", expected[i], i, id)
+		}
+	}
+}
+
+// TestSortIsLazy tests that sorting does not read its input until first pulled
+func TestSortIsLazy(t *testing.T) {
+	pulls := 0
+	source := func() (int, error) {
+		pulls++
+		if pulls > 3 {
+			return 0, EOS
+		}
+		return 4 - pulls, nil
+	}
+
+	sorted := SortAsc[int]()(source)
+	if pulls != 0 {
+		t.Fatalf("Expected no pulls before first element requested, got %d", pulls)
+	}
+
+	first, err := sorted()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first != 1 {
+		t.Errorf("Expected 1, got %d", first)
+	}
+}
+
+// TestSortErrorMidCollection tests that an input error while buffering is returned and sticky
+func TestSortErrorMidCollection(t *testing.T) {
+	calls := 0
+	source := func() (int, error) {
+		calls++
+		if calls == 3 {
+			return 0, fmt.Errorf("read failed")
+		}
+		return calls, nil
+	}
+
+	sorted := SortLess(func(a, b int) bool { return a < b })(source)
+	_, err := sorted()
+	if err == nil || err.Error() != "read failed" {
+		t.Fatalf("Expected 'read failed', got %v", err)
+	}
+
+	// The error is sticky rather than turning into partial output
+	if _, err := sorted(); err == nil || err.Error() != "read failed" {
+		t.Errorf("Expected repeated 'read failed', got %v", err)
+	}
+}
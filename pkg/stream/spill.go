@@ -0,0 +1,709 @@
+package stream
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// SPILLING - SORTING AND GROUPING MORE DATA THAN FITS IN MEMORY
+// ============================================================================
+
+const (
+	defaultSpillThreshold = 100000 // Records or groups held in memory before spilling
+	spillMergeFanIn       = 64     // Sorted runs merged at once; more runs are merged in passes
+	spillPartitions       = 16     // Files the groups GroupByExternal can't hold are hashed across
+	maxSpillDepth         = 8      // Times a partition is split again before it is folded in memory regardless
+)
+
+// SpillOption configures ExternalSortByFields, ExternalSortByKeys and GroupByExternal
+type SpillOption func(*spillConfig)
+
+// spillConfig holds spilling configuration
+type spillConfig struct {
+	ctx       context.Context
+	dir       string
+	threshold int
+	maxBytes  int64 // 0 means no byte limit
+}
+
+// WithSpillThreshold sets how many records an external sort buffers, or how many groups
+// GroupByExternal holds, before spilling to disk; the default is 100,000
+func WithSpillThreshold(n int) SpillOption {
+	if n <= 0 {
+		panic("spill threshold must be positive")
+	}
+	return func(config *spillConfig) {
+		config.threshold = n
+	}
+}
+
+// WithSpillBytes also spills an external sort's buffer once the records in it are
+// estimated to take n bytes. Estimates count field names and string contents plus a
+// fixed overhead per field, so leave headroom.
+func WithSpillBytes(n int64) SpillOption {
+	if n <= 0 {
+		panic("spill bytes must be positive")
+	}
+	return func(config *spillConfig) {
+		config.maxBytes = n
+	}
+}
+
+// WithSpillDir sets the directory temporary files are created in; the default is
+// os.TempDir()
+func WithSpillDir(dir string) SpillOption {
+	return func(config *spillConfig) {
+		config.dir = dir
+	}
+}
+
+// WithSpillContext removes the temporary files as soon as ctx is done, and pulls return
+// ctx.Err() from then on. Without it, a stream abandoned before its end has its files
+// removed only once it is garbage collected.
+func WithSpillContext(ctx context.Context) SpillOption {
+	return func(config *spillConfig) {
+		config.ctx = ctx
+	}
+}
+
+// newSpillConfig applies options over the defaults
+func newSpillConfig(options []SpillOption) spillConfig {
+	config := spillConfig{ctx: context.Background(), threshold: defaultSpillThreshold}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// ExternalSortByFields sorts Records by fields in ascending order like SortBy, without
+// holding the whole input in memory; see ExternalSortByKeys
+func ExternalSortByFields(fields []string, options ...SpillOption) Filter[Record, Record] {
+	keys := make([]SortKey, len(fields))
+	for i, field := range fields {
+		keys[i] = Asc(field)
+	}
+	return ExternalSortByKeys(keys, options...)
+}
+
+// ExternalSortByKeys sorts Records like SortByKeys, stably, for inputs larger than
+// memory. Records are buffered up to the spill threshold; each full buffer is sorted and
+// written to a temporary file as a run, and the runs are merged as the output is read.
+// Input that never fills the buffer is sorted in memory without touching the disk.
+// The files are removed once the output ends, when the WithSpillContext context is
+// done, or once an abandoned stream is garbage collected. Stream fields can't be
+// written to disk, so a record holding one fails the sort once it spills.
+//
+// Example:
+//
+//	sorted := ExternalSortByFields([]string{"customer", "ts"},
+//	    WithSpillThreshold(500000), WithSpillContext(ctx))(orders)
+func ExternalSortByKeys(keys []SortKey, options ...SpillOption) Filter[Record, Record] {
+	config := newSpillConfig(options)
+	cmp := compareByKeys(keys)
+
+	return fused(func(input Stream[Record]) Stream[Record] {
+		sorter := &externalSorter{config: config, cmp: cmp, files: newSpillFiles(config.dir)}
+		runtime.AddCleanup(sorter, (*spillFiles).removeAll, sorter.files)
+		return sorter.stream(input)
+	})
+}
+
+// externalSorter is the state of one ExternalSortByKeys stream
+type externalSorter struct {
+	config spillConfig
+	cmp    func(a, b Record) int
+	files  *spillFiles
+}
+
+// stream sorts input on the first pull and returns the merged output
+func (s *externalSorter) stream(input Stream[Record]) Stream[Record] {
+	var output Stream[Record]
+	return func() (Record, error) {
+		if err := s.config.ctx.Err(); err != nil {
+			s.files.removeAll()
+			return nil, err
+		}
+		if output == nil {
+			context.AfterFunc(s.config.ctx, s.files.removeAll)
+			sorted, err := s.sort(input)
+			if err != nil {
+				s.files.removeAll()
+				return nil, err
+			}
+			output = sorted
+		}
+		record, err := output()
+		if err != nil {
+			s.files.removeAll()
+			if ctxErr := s.config.ctx.Err(); ctxErr != nil {
+				return nil, ctxErr // Reads fail once the files are removed
+			}
+			return nil, err
+		}
+		return record, nil
+	}
+}
+
+// sort reads the input, spilling sorted runs, and returns a merge of the runs and the
+// final buffer
+func (s *externalSorter) sort(input Stream[Record]) (Stream[Record], error) {
+	var buffer []Record
+	var bufferBytes int64
+	var runs []string
+
+	for {
+		if err := s.config.ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := input()
+		if err == EOS {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		buffer = append(buffer, record)
+		if s.config.maxBytes > 0 {
+			bufferBytes += estimateRecordSize(record)
+		}
+		if len(buffer) >= s.config.threshold || (s.config.maxBytes > 0 && bufferBytes >= s.config.maxBytes) {
+			sortSlice(buffer, s.cmp)
+			path, err := s.files.writeRun(FromRecordsUnsafe(buffer))
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, path)
+			clear(buffer) // Let the spilled records be collected
+			buffer, bufferBytes = buffer[:0], 0
+		}
+	}
+	sortSlice(buffer, s.cmp)
+	if len(runs) == 0 {
+		return FromRecordsUnsafe(buffer), nil // Everything fit in memory
+	}
+
+	// Merge the leading runs until the rest can be merged at once with the final buffer,
+	// each pass merging only as many as needed. Merging neighbouring runs keeps earlier
+	// input first among equal records.
+	for len(runs)+1 > spillMergeFanIn {
+		count := min(spillMergeFanIn, len(runs)+2-spillMergeFanIn)
+		streams := make([]Stream[Record], count)
+		for i, path := range runs[:count] {
+			streams[i] = s.files.readRun(path)
+		}
+		path, err := s.files.writeRun(mergeSorted(streams, s.cmp))
+		if err != nil {
+			return nil, err
+		}
+		runs = append([]string{path}, runs[count:]...)
+	}
+
+	streams := make([]Stream[Record], 0, len(runs)+1)
+	for _, path := range runs {
+		streams = append(streams, s.files.readRun(path))
+	}
+	streams = append(streams, FromRecordsUnsafe(buffer))
+	return mergeSorted(streams, s.cmp), nil
+}
+
+// estimateRecordSize roughly estimates the memory a record takes
+func estimateRecordSize(record Record) int64 {
+	size := int64(48)
+	for field, value := range record {
+		size += int64(len(field)) + 32
+		switch v := value.(type) {
+		case string:
+			size += int64(len(v))
+		case []byte:
+			size += int64(len(v))
+		case Record:
+			size += estimateRecordSize(v)
+		}
+	}
+	return size
+}
+
+// ============================================================================
+// K-WAY MERGE
+// ============================================================================
+
+// mergeHead is the next record of one merged stream
+type mergeHead struct {
+	record Record
+	source int
+}
+
+// mergeHeap orders heads by cmp, then by source so equal records keep stream order
+type mergeHeap struct {
+	heads []mergeHead
+	cmp   func(a, b Record) int
+}
+
+func (h *mergeHeap) Len() int { return len(h.heads) }
+func (h *mergeHeap) Less(i, j int) bool {
+	if c := h.cmp(h.heads[i].record, h.heads[j].record); c != 0 {
+		return c < 0
+	}
+	return h.heads[i].source < h.heads[j].source
+}
+func (h *mergeHeap) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *mergeHeap) Push(x any)    { h.heads = append(h.heads, x.(mergeHead)) }
+func (h *mergeHeap) Pop() any {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
+
+// mergeSorted merges streams that are each sorted by cmp into one sorted stream
+func mergeSorted(streams []Stream[Record], cmp func(a, b Record) int) Stream[Record] {
+	h := &mergeHeap{cmp: cmp}
+	started := false
+	var finalErr error // Sticky once an input fails
+
+	// advance pulls the next record of stream i onto the heap
+	advance := func(i int) error {
+		record, err := streams[i]()
+		if err == EOS {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(h, mergeHead{record: record, source: i})
+		return nil
+	}
+
+	return func() (Record, error) {
+		if finalErr != nil {
+			return nil, finalErr
+		}
+		if !started {
+			started = true
+			for i := range streams {
+				if err := advance(i); err != nil {
+					finalErr = err
+					return nil, err
+				}
+			}
+		}
+		if h.Len() == 0 {
+			return nil, EOS
+		}
+		head := heap.Pop(h).(mergeHead)
+		if err := advance(head.source); err != nil {
+			finalErr = err
+			return nil, err
+		}
+		return head.record, nil
+	}
+}
+
+// ============================================================================
+// GROUPING WITH SPILLING
+// ============================================================================
+
+// GroupByExternal groups records like GroupByStreaming, folding each into its group's
+// accumulators, for inputs with more groups than fit in memory. Once the spill
+// threshold of groups is held, records of further new groups are hashed by key across
+// temporary files instead; records of groups already held keep being folded in memory.
+// At the end of the input the groups held are emitted in order of first appearance,
+// then each file is grouped the same way in turn, splitting it again if it still has
+// too many groups. Temporary files are cleaned up as for ExternalSortByKeys.
+//
+// Example:
+//
+//	perSession := GroupByExternal([]string{"session_id"},
+//	    []AggregatorSpec[Record]{CountField("events", "type")},
+//	    WithSpillThreshold(1000000))(events)
+func GroupByExternal(keyFields []string, aggregators []AggregatorSpec[Record], options ...SpillOption) Filter[Record, Record] {
+	config := newSpillConfig(options)
+
+	return fused(func(input Stream[Record]) Stream[Record] {
+		if err := unsupportedAggregator(aggregators); err != nil {
+			return func() (Record, error) { return nil, err }
+		}
+		grouper := &externalGrouper{config: config, keyFields: keyFields, aggregators: aggregators, files: newSpillFiles(config.dir)}
+		runtime.AddCleanup(grouper, (*spillFiles).removeAll, grouper.files)
+		return grouper.stream(input)
+	})
+}
+
+// externalGrouper is the state of one GroupByExternal stream
+type externalGrouper struct {
+	config      spillConfig
+	keyFields   []string
+	aggregators []AggregatorSpec[Record]
+	files       *spillFiles
+}
+
+// stream groups input on the first pull and returns the results
+func (g *externalGrouper) stream(input Stream[Record]) Stream[Record] {
+	var output Stream[Record]
+	return func() (Record, error) {
+		if err := g.config.ctx.Err(); err != nil {
+			g.files.removeAll()
+			return nil, err
+		}
+		if output == nil {
+			context.AfterFunc(g.config.ctx, g.files.removeAll)
+			output = g.fold(input, 0)
+		}
+		record, err := output()
+		if err != nil {
+			g.files.removeAll()
+			if ctxErr := g.config.ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, err
+		}
+		return record, nil
+	}
+}
+
+// fold groups input, spilling the groups it can't hold; its results are the groups it
+// held followed by those of each spilled partition, folded when reached
+func (g *externalGrouper) fold(input Stream[Record], depth int) Stream[Record] {
+	fail := func(err error) Stream[Record] {
+		return func() (Record, error) { return nil, err }
+	}
+
+	groups := make(map[string]*groupState)
+	var order []*groupState
+	var partitions []*spillWriter
+	seed := maphash.MakeSeed() // A new seed per level, so a split partition spreads out
+
+	for {
+		if err := g.config.ctx.Err(); err != nil {
+			return fail(err)
+		}
+		record, err := input()
+		if err == EOS {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		key := buildGroupKey(record, g.keyFields)
+		group, exists := groups[key]
+		if !exists {
+			if len(groups) >= g.config.threshold && depth < maxSpillDepth {
+				if partitions == nil {
+					partitions = make([]*spillWriter, spillPartitions)
+				}
+				p := maphash.String(seed, key) % spillPartitions
+				if partitions[p] == nil {
+					if partitions[p], err = g.files.newWriter(); err != nil {
+						return fail(err)
+					}
+				}
+				if err := partitions[p].write(record); err != nil {
+					return fail(err)
+				}
+				continue
+			}
+			group = newGroupState(record, g.keyFields, g.aggregators)
+			groups[key] = group
+			order = append(order, group)
+		}
+		group.add(record)
+	}
+
+	results := make([]Record, 0, len(order))
+	for _, group := range order {
+		if err := group.err(g.aggregators); err != nil {
+			return fail(err)
+		}
+		results = append(results, group.result(g.aggregators))
+	}
+
+	streams := []Stream[Record]{FromRecordsUnsafe(results)}
+	for _, partition := range partitions {
+		if partition == nil {
+			continue
+		}
+		path, err := partition.finish()
+		if err != nil {
+			return fail(err)
+		}
+		var spilled Stream[Record]
+		streams = append(streams, func() (Record, error) {
+			if spilled == nil {
+				spilled = g.fold(g.files.readRun(path), depth+1)
+			}
+			return spilled()
+		})
+	}
+	return Concat(streams...)
+}
+
+// ============================================================================
+// SPILL FILES
+// ============================================================================
+
+// spillFiles owns the temporary files of one external sort or group by. It holds no
+// reference back to the stream, so a runtime cleanup can remove the files once an
+// abandoned stream is garbage collected.
+type spillFiles struct {
+	parent string
+
+	mu      sync.Mutex
+	dir     string // Created on the first spill
+	open    map[*os.File]bool
+	removed bool
+}
+
+func newSpillFiles(parent string) *spillFiles {
+	return &spillFiles{parent: parent, open: make(map[*os.File]bool)}
+}
+
+// create creates a new temporary file, tracked until release or removeAll
+func (f *spillFiles) create() (*os.File, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.removed {
+		return nil, fmt.Errorf("spill: %w", os.ErrClosed)
+	}
+	if f.dir == "" {
+		dir, err := os.MkdirTemp(f.parent, "streamv2-spill-")
+		if err != nil {
+			return nil, fmt.Errorf("spill: %w", err)
+		}
+		f.dir = dir
+	}
+	file, err := os.CreateTemp(f.dir, "run-*.gob")
+	if err != nil {
+		return nil, fmt.Errorf("spill: %w", err)
+	}
+	f.open[file] = true
+	return file, nil
+}
+
+// reopen opens a file written earlier for reading, tracked until release or removeAll
+func (f *spillFiles) reopen(path string) (*os.File, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.removed {
+		return nil, fmt.Errorf("spill: %w", os.ErrClosed)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("spill: %w", err)
+	}
+	f.open[file] = true
+	return file, nil
+}
+
+// close closes a tracked file, deleting it too when remove is set
+func (f *spillFiles) close(file *os.File, remove bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.open[file] {
+		return nil // Already closed by removeAll
+	}
+	delete(f.open, file)
+	err := file.Close()
+	if remove {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// removeAll closes every open file and deletes the directory; later files can't be created
+func (f *spillFiles) removeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = true
+	for file := range f.open {
+		file.Close()
+	}
+	f.open = nil
+	if f.dir != "" {
+		os.RemoveAll(f.dir)
+	}
+}
+
+// writeRun writes every record of stream to a new file and returns its path
+func (f *spillFiles) writeRun(stream Stream[Record]) (string, error) {
+	writer, err := f.newWriter()
+	if err != nil {
+		return "", err
+	}
+	for {
+		record, err := stream()
+		if err == EOS {
+			break
+		}
+		if err == nil {
+			err = writer.write(record)
+		}
+		if err != nil {
+			f.close(writer.file, true)
+			return "", err
+		}
+	}
+	return writer.finish()
+}
+
+// readRun streams the records of a file written by writeRun, deleting it once read
+func (f *spillFiles) readRun(path string) Stream[Record] {
+	var file *os.File
+	var decoder *gob.Decoder
+	return Fuse(func() (Record, error) {
+		if file == nil {
+			opened, err := f.reopen(path)
+			if err != nil {
+				return nil, err
+			}
+			file, decoder = opened, gob.NewDecoder(bufio.NewReader(opened))
+		}
+		var spilled spillRecord
+		if err := decoder.Decode(&spilled); err != nil {
+			f.close(file, true)
+			if err == io.EOF {
+				return nil, EOS
+			}
+			return nil, fmt.Errorf("spill: failed to read %s: %w", path, err)
+		}
+		return spilled.record(), nil
+	})
+}
+
+// spillWriter encodes records to one temporary file
+type spillWriter struct {
+	files   *spillFiles
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *gob.Encoder
+}
+
+// newWriter creates a file to spill records to
+func (f *spillFiles) newWriter() (*spillWriter, error) {
+	registerSpillTypes()
+	file, err := f.create()
+	if err != nil {
+		return nil, err
+	}
+	buffer := bufio.NewWriter(file)
+	return &spillWriter{files: f, file: file, buffer: buffer, encoder: gob.NewEncoder(buffer)}, nil
+}
+
+// write appends a record to the file
+func (w *spillWriter) write(record Record) error {
+	spilled, err := newSpillRecord(record)
+	if err != nil {
+		return err
+	}
+	if err := w.encoder.Encode(spilled); err != nil {
+		return fmt.Errorf("spill: failed to write record: %w", err)
+	}
+	return nil
+}
+
+// finish flushes and closes the file, returning its path
+func (w *spillWriter) finish() (string, error) {
+	if err := w.buffer.Flush(); err != nil {
+		w.files.close(w.file, true)
+		return "", fmt.Errorf("spill: failed to write %s: %w", w.file.Name(), err)
+	}
+	if err := w.files.close(w.file, false); err != nil {
+		return "", fmt.Errorf("spill: failed to write %s: %w", w.file.Name(), err)
+	}
+	return w.file.Name(), nil
+}
+
+// ============================================================================
+// SPILL ENCODING
+// ============================================================================
+
+// spillRecord is the gob form of a Record. gob can't encode nil interface values, so
+// nil fields become spillNull.
+type spillRecord struct {
+	Fields map[string]any
+}
+
+// spillNull stands in for a nil field value
+type spillNull struct {
+	Null bool
+}
+
+var registerSpillOnce sync.Once
+
+// registerSpillTypes registers the field types gob doesn't know about already
+func registerSpillTypes() {
+	registerSpillOnce.Do(func() {
+		gob.Register(spillNull{})
+		gob.Register(Record{})
+		gob.Register(time.Time{})
+	})
+}
+
+// newSpillRecord converts a record for encoding
+func newSpillRecord(record Record) (spillRecord, error) {
+	fields, err := spillFields(record, "")
+	return spillRecord{Fields: fields}, err
+}
+
+// spillFields converts the fields of a record, nested ones included; path names them in errors
+func spillFields(record Record, path string) (Record, error) {
+	fields := make(Record, len(record))
+	for name, value := range record {
+		switch v := value.(type) {
+		case nil:
+			fields[name] = spillNull{Null: true}
+		case Record:
+			nested, err := spillFields(v, path+name+".")
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = nested
+		case map[string]any:
+			nested, err := spillFields(Record(v), path+name+".")
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = nested
+		default:
+			if IsStreamType(value) {
+				return nil, fmt.Errorf("spill: field %q: %w", path+name, errSpillStream)
+			}
+			fields[name] = value
+		}
+	}
+	return fields, nil
+}
+
+// errSpillStream reports a stream field, which can't be written to disk
+var errSpillStream = errors.New("stream fields can't be spilled to disk")
+
+// record converts a decoded record back, restoring nil fields
+func (s spillRecord) record() Record {
+	return unspillFields(s.Fields)
+}
+
+// unspillFields restores nil values in a decoded record and its nested records
+func unspillFields(fields map[string]any) Record {
+	record := make(Record, len(fields))
+	for name, value := range fields {
+		switch v := value.(type) {
+		case spillNull:
+			record[name] = nil
+		case Record:
+			record[name] = unspillFields(v)
+		default:
+			record[name] = value
+		}
+	}
+	return record
+}
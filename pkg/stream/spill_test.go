@@ -0,0 +1,230 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// spillFilesLeft lists what is left in a spill directory
+func spillFilesLeft(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read the spill directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// TestExternalSort tests sorting with runs spilled to disk
+func TestExternalSort(t *testing.T) {
+	t.Run("MillionRecords", func(t *testing.T) {
+		n := 1000000
+		if testing.Short() {
+			n = 50000
+		}
+		dir := t.TempDir()
+		input := Generate(func() func() (int64, error) {
+			i := int64(0)
+			return func() (int64, error) {
+				if i >= int64(n) {
+					return 0, EOS
+				}
+				i++
+				return (i * 7919) % int64(n), nil // A permutation of 0..n-1
+			}
+		}())
+		records := Map(func(v int64) Record {
+			return Record{"key": v % 1000, "seq": v}
+		})(input)
+
+		sorted := ExternalSortByFields([]string{"key", "seq"}, WithSpillThreshold(10000), WithSpillDir(dir))(records)
+		count := 0
+		var prev Record
+		for {
+			record, err := sorted()
+			if err == EOS {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Sort failed after %d records: %v", count, err)
+			}
+			if prev != nil && compareByKeys([]SortKey{Asc("key"), Asc("seq")})(prev, record) > 0 {
+				t.Fatalf("Out of order at %d: %v after %v", count, record, prev)
+			}
+			prev = record
+			count++
+		}
+		if count != n {
+			t.Errorf("Expected %d records, got %d", n, count)
+		}
+		if left := spillFilesLeft(t, dir); len(left) != 0 {
+			t.Errorf("Expected no temp files left, got %v", left)
+		}
+	})
+
+	t.Run("Stable", func(t *testing.T) {
+		var records []Record
+		for i := 0; i < 1000; i++ {
+			records = append(records, Record{"group": int64(i % 3), "order": int64(i), "note": nil})
+		}
+		results, err := Collect(ExternalSortByKeys([]SortKey{Desc("group")}, WithSpillThreshold(7), WithSpillDir(t.TempDir()))(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		expected, _ := Collect(SortByKeys(Desc("group"))(FromRecordsUnsafe(records)))
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d records, got %d", len(expected), len(results))
+		}
+		for i := range expected {
+			if results[i]["order"] != expected[i]["order"] {
+				t.Fatalf("Expected the order of SortByKeys, differs at %d: %v vs %v", i, results[i], expected[i])
+			}
+		}
+		if note, ok := results[0]["note"]; !ok || note != nil {
+			t.Errorf("Expected nil fields to survive spilling, got %v", results[0])
+		}
+	})
+
+	t.Run("FieldTypes", func(t *testing.T) {
+		ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		records := []Record{
+			{"id": int64(2), "at": ts, "tags": []string{"a"}, "nested": Record{"x": 1.5, "y": nil}},
+			{"id": int64(1), "at": ts, "tags": []string{"b"}, "nested": Record{"x": 2.5, "y": "z"}},
+		}
+		results, err := Collect(ExternalSortByFields([]string{"id"}, WithSpillThreshold(1), WithSpillDir(t.TempDir()))(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if results[0]["id"] != int64(1) || !results[0]["at"].(time.Time).Equal(ts) {
+			t.Errorf("Expected fields to round trip, got %v", results[0])
+		}
+		nested := results[1]["nested"].(Record)
+		if nested["x"] != 1.5 || nested["y"] != nil {
+			t.Errorf("Expected nested records to round trip, got %v", nested)
+		}
+	})
+
+	t.Run("InMemory", func(t *testing.T) {
+		dir := t.TempDir()
+		results, err := Collect(ExternalSortByFields([]string{"id"}, WithSpillDir(dir))(FromRecordsUnsafe([]Record{{"id": 2}, {"id": 1}})))
+		if err != nil || len(results) != 2 || results[0]["id"] != 1 {
+			t.Fatalf("Expected a sorted result, got %v (%v)", results, err)
+		}
+		if left := spillFilesLeft(t, dir); len(left) != 0 {
+			t.Errorf("Expected nothing written below the threshold, got %v", left)
+		}
+	})
+
+	t.Run("SpillBytes", func(t *testing.T) {
+		dir := t.TempDir()
+		var records []Record
+		for i := 0; i < 100; i++ {
+			records = append(records, Record{"id": int64(100 - i), "payload": string(make([]byte, 1000))})
+		}
+		sorted := ExternalSortByFields([]string{"id"}, WithSpillBytes(10000), WithSpillDir(dir))(FromRecordsUnsafe(records))
+		first, err := sorted()
+		if err != nil || first["id"] != int64(1) {
+			t.Fatalf("Expected the smallest id first, got %v (%v)", first, err)
+		}
+		if left := spillFilesLeft(t, dir); len(left) != 1 {
+			t.Errorf("Expected the byte limit to spill runs, got %v", left)
+		}
+		if rest, err := Collect(sorted); err != nil || len(rest) != 99 {
+			t.Errorf("Expected 99 more records, got %d (%v)", len(rest), err)
+		}
+	})
+
+	t.Run("AbandonedMidMerge", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		var records []Record
+		for i := 0; i < 1000; i++ {
+			records = append(records, Record{"id": int64(1000 - i)})
+		}
+		sorted := ExternalSortByFields([]string{"id"}, WithSpillThreshold(100), WithSpillDir(dir), WithSpillContext(ctx))(FromRecordsUnsafe(records))
+		for i := 0; i < 10; i++ {
+			if _, err := sorted(); err != nil {
+				t.Fatalf("Failed to read record %d: %v", i, err)
+			}
+		}
+		if left := spillFilesLeft(t, dir); len(left) == 0 {
+			t.Fatal("Expected runs on disk mid-merge")
+		}
+		cancel()
+		deadline := time.Now().Add(time.Second) // The files are removed on another goroutine
+		for len(spillFilesLeft(t, dir)) != 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if left := spillFilesLeft(t, dir); len(left) != 0 {
+			t.Errorf("Expected cancellation to remove the temp files, got %v", left)
+		}
+		if _, err := sorted(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("StreamField", func(t *testing.T) {
+		dir := t.TempDir()
+		records := []Record{{"id": 2, "items": FromSlice([]int64{1})}, {"id": 1, "items": FromSlice([]int64{2})}}
+		_, err := Collect(ExternalSortByFields([]string{"id"}, WithSpillThreshold(1), WithSpillDir(dir))(FromRecordsUnsafe(records)))
+		if !errors.Is(err, errSpillStream) {
+			t.Errorf("Expected a stream field error, got %v", err)
+		}
+		if left := spillFilesLeft(t, dir); len(left) != 0 {
+			t.Errorf("Expected a failed sort to remove its temp files, got %v", left)
+		}
+	})
+}
+
+// TestGroupByExternal tests grouping with groups spilled to disk
+func TestGroupByExternal(t *testing.T) {
+	var records []Record
+	for i := 0; i < 20000; i++ {
+		records = append(records, Record{"user": fmt.Sprintf("u%d", i%5000), "amount": int64(i % 7)})
+	}
+	aggregators := []AggregatorSpec[Record]{CountField("count", "user"), SumField[int64]("total", "amount")}
+
+	dir := t.TempDir()
+	results, err := Collect(GroupByExternal([]string{"user"}, aggregators, WithSpillThreshold(100), WithSpillDir(dir))(FromRecordsUnsafe(records)))
+	if err != nil {
+		t.Fatalf("GroupByExternal failed: %v", err)
+	}
+	expected, err := Collect(GroupByStreaming([]string{"user"}, aggregators...)(FromRecordsUnsafe(records)))
+	if err != nil {
+		t.Fatalf("GroupByStreaming failed: %v", err)
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d", len(expected), len(results))
+	}
+	byUser := make(map[any]Record)
+	for _, result := range results {
+		if _, dup := byUser[result["user"]]; dup {
+			t.Fatalf("Group %v emitted twice", result["user"])
+		}
+		byUser[result["user"]] = result
+	}
+	for _, want := range expected {
+		got := byUser[want["user"]]
+		if got["count"] != want["count"] || got["total"] != want["total"] {
+			t.Errorf("Group %v: expected %v, got %v", want["user"], want, got)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if results[i]["user"] != expected[i]["user"] {
+			t.Errorf("Expected the groups held in memory first, in order, got %v at %d", results[i]["user"], i)
+			break
+		}
+	}
+	if left := spillFilesLeft(t, dir); len(left) != 0 {
+		t.Errorf("Expected no temp files left, got %v", left)
+	}
+}
@@ -1,11 +1,15 @@
 package stream
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unsafe"
 )
 
 // ============================================================================
@@ -15,7 +19,13 @@ import (
 // EOS signals end of stream
 var EOS = errors.New("end of stream")
 
-// Stream represents a generic data stream - the heart of V2
+// Stream represents a generic data stream - the heart of V2.
+//
+// Each call returns the next element, or an error that ends the stream: EOS when it is
+// exhausted, anything else when it failed. After that, a stream should return the same
+// error on every further call without doing more work. The library's sources,
+// aggregators, joins, windows and flattening filters keep to this contract; wrap a
+// stream of unknown behaviour - a Generate function, say - in Fuse to enforce it.
 type Stream[T any] func() (T, error)
 
 // Common stream type aliases
@@ -65,7 +75,9 @@ type Value interface {
 
 // TypedRecord provides type-safe field setting with method chaining
 type TypedRecord struct {
-	data map[string]any
+	data    map[string]any
+	order   []string
+	ordered bool
 }
 
 // NewRecord creates a new type-safe Record builder
@@ -75,44 +87,174 @@ func NewRecord() *TypedRecord {
 
 // Set adds a field with compile-time type safety
 func (tr *TypedRecord) Set(key string, value any) *TypedRecord {
+	tr.order = append(tr.order, key)
 	tr.data[key] = value
 	return tr
 }
 
+// Ordered makes Build return an ordered record, whose fields keep the order they were
+// first set in through Keys, Select, joins and the CSV, JSON and table sinks
+func (tr *TypedRecord) Ordered() *TypedRecord {
+	tr.ordered = true
+	return tr
+}
+
 // Build returns the completed Record
 func (tr *TypedRecord) Build() Record {
+	if tr.ordered {
+		order := make([]string, 0, len(tr.data))
+		seen := make(map[string]bool, len(tr.data))
+		for _, key := range tr.order {
+			if !seen[key] {
+				seen[key] = true
+				order = append(order, key)
+			}
+		}
+		return SetFieldOrder(Record(tr.data), order...)
+	}
 	return Record(tr.data)
 }
 
 // Convenience methods for common types with type safety
 func (tr *TypedRecord) String(key string, value string) *TypedRecord {
-	tr.data[key] = value
-	return tr
+	return tr.Set(key, value)
 }
 
 func (tr *TypedRecord) Int(key string, value int64) *TypedRecord {
-	tr.data[key] = value
-	return tr
+	return tr.Set(key, value)
 }
 
 func (tr *TypedRecord) Float(key string, value float64) *TypedRecord {
-	tr.data[key] = value
-	return tr
+	return tr.Set(key, value)
 }
 
 func (tr *TypedRecord) Bool(key string, value bool) *TypedRecord {
-	tr.data[key] = value
-	return tr
+	return tr.Set(key, value)
 }
 
 func (tr *TypedRecord) Time(key string, value time.Time) *TypedRecord {
-	tr.data[key] = value
-	return tr
+	return tr.Set(key, value)
 }
 
 func (tr *TypedRecord) Record(key string, value Record) *TypedRecord {
-	tr.data[key] = value
-	return tr
+	return tr.Set(key, value)
+}
+
+// Stream stores a stream of records as a field, for CrossFlatten to expand
+func (tr *TypedRecord) Stream(key string, value Stream[Record]) *TypedRecord {
+	return tr.Set(key, value)
+}
+
+// Strings stores values as a Stream[string] field, the form CrossFlatten and the
+// sinks expect for lists
+func (tr *TypedRecord) Strings(key string, values []string) *TypedRecord {
+	return tr.Set(key, FromSlice(values))
+}
+
+// Ints stores values as a Stream[int64] field
+func (tr *TypedRecord) Ints(key string, values []int64) *TypedRecord {
+	return tr.Set(key, FromSlice(values))
+}
+
+// ============================================================================
+// STRICT RECORD BUILDER
+// ============================================================================
+
+// Errors reported by StrictRecord.Build, wrapped with the offending field name
+var (
+	ErrDuplicateField = errors.New("field set more than once")
+	ErrInvalidField   = errors.New("field value is not a Value type")
+)
+
+// StrictRecord is a Record builder that checks every field as it is set: a key set twice,
+// or a value FromRecords would reject (including inside nested Records), is reported by
+// Build instead of surfacing later in the pipeline. Use NewRecord for the permissive builder.
+type StrictRecord struct {
+	data     map[string]any
+	problems []error
+}
+
+// NewRecordStrict creates a strict Record builder
+func NewRecordStrict() *StrictRecord {
+	return &StrictRecord{data: make(map[string]any)}
+}
+
+// Set adds a field, noting a problem if the key is already set or the value is invalid
+func (sr *StrictRecord) Set(key string, value any) *StrictRecord {
+	if _, exists := sr.data[key]; exists {
+		sr.problems = append(sr.problems, fmt.Errorf("%w: %q", ErrDuplicateField, key))
+	}
+	if err := checkFieldValue(key, value); err != nil {
+		sr.problems = append(sr.problems, err)
+	}
+	sr.data[key] = value
+	return sr
+}
+
+// Build returns the completed Record, or every problem found while building it
+func (sr *StrictRecord) Build() (Record, error) {
+	if len(sr.problems) > 0 {
+		return nil, errors.Join(sr.problems...)
+	}
+	return Record(sr.data), nil
+}
+
+// Convenience methods, matching TypedRecord
+func (sr *StrictRecord) String(key string, value string) *StrictRecord {
+	return sr.Set(key, value)
+}
+
+func (sr *StrictRecord) Int(key string, value int64) *StrictRecord {
+	return sr.Set(key, value)
+}
+
+func (sr *StrictRecord) Float(key string, value float64) *StrictRecord {
+	return sr.Set(key, value)
+}
+
+func (sr *StrictRecord) Bool(key string, value bool) *StrictRecord {
+	return sr.Set(key, value)
+}
+
+func (sr *StrictRecord) Time(key string, value time.Time) *StrictRecord {
+	return sr.Set(key, value)
+}
+
+func (sr *StrictRecord) Record(key string, value Record) *StrictRecord {
+	return sr.Set(key, value)
+}
+
+func (sr *StrictRecord) Stream(key string, value Stream[Record]) *StrictRecord {
+	return sr.Set(key, value)
+}
+
+func (sr *StrictRecord) Strings(key string, values []string) *StrictRecord {
+	return sr.Set(key, FromSlice(values))
+}
+
+func (sr *StrictRecord) Ints(key string, values []int64) *StrictRecord {
+	return sr.Set(key, FromSlice(values))
+}
+
+// checkFieldValue reports a value that is not a Value type, descending into nested
+// Records; path names the field, dotted for nested fields
+func checkFieldValue(path string, value any) error {
+	if !isValueType(value) {
+		return fmt.Errorf("%w: %q has type %T", ErrInvalidField, path, value)
+	}
+	nested, ok := value.(Record)
+	if !ok {
+		return nil
+	}
+	keys := nested.Keys()
+	sort.Strings(keys)
+	var problems []error
+	for _, key := range keys {
+		if err := checkFieldValue(path+"."+key, nested[key]); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return errors.Join(problems...)
 }
 
 // Field creates a single-field Record with compile-time type safety
@@ -146,7 +288,21 @@ func recordsFrom(maps []map[string]any) []Record {
 // TYPE-SAFE RECORD ACCESS WITH AUTOMATIC CONVERSION
 // ============================================================================
 
-// Get retrieves a typed value from a record with automatic conversion
+// Get retrieves a typed value from a record with automatic conversion. It reports
+// false when the field is missing or nil, or its value doesn't convert:
+//
+//   - int64: from any integer or float type (floats truncate toward zero), and from
+//     strings holding a base-10 integer; "12.50" doesn't convert
+//   - float64: from any integer or float type, and from strings strconv.ParseFloat
+//     accepts, such as "12.50" or "1e3"
+//   - string: from any value, numbers formatted in decimal (int64(65) is "65")
+//   - bool: from int, int64 and float64 (non-zero is true), and from the strings
+//     true/false, 1/0 and yes/no in any case; any other string doesn't convert
+//   - time.Time: from RFC 3339 and "2006-01-02 15:04:05" strings, and Unix seconds
+//   - other numeric and named types: by reflect conversion, with strings parsed as
+//     numbers for numeric kinds
+//
+// Strings are trimmed of surrounding space before parsing as numbers or bools.
 func Get[T any](r Record, field string) (T, bool) {
 	val, exists := r[field]
 	if !exists {
@@ -176,13 +332,71 @@ func GetOr[T any](r Record, field string, defaultVal T) T {
 	return defaultVal
 }
 
+// AsList returns the elements of a list field, whatever form the list takes: a stream of
+// any element type (Stream[any], Stream[string], Stream[Record], ...) or a slice or array.
+// It reports false if the field is missing or isn't a list; strings and []byte aren't.
+// Reading a stream field consumes it, as reading any stream does.
+func AsList(r Record, field string) ([]any, bool) {
+	return streamFieldValues(r[field])
+}
+
+// GetErr retrieves a typed value like Get, but says why it couldn't: the error is a
+// *FieldError naming the record's fields when the field is missing, so a misspelled
+// field name shows up rather than reading as a zero value
+func GetErr[T any](r Record, field string) (T, error) {
+	val, exists := r[field]
+	if !exists {
+		var zero T
+		available := r.Keys()
+		sort.Strings(available)
+		return zero, &FieldError{Field: field, Missing: true, Type: reflect.TypeFor[T]().String(), Available: available}
+	}
+	if typed, ok := Get[T](r, field); ok {
+		return typed, nil
+	}
+	var zero T
+	return zero, &FieldError{Field: field, Value: val, Type: reflect.TypeFor[T]().String()}
+}
+
+// MustGet retrieves a typed value, panicking with GetErr's error if the field is missing
+// or doesn't convert. Use it where a missing field is a bug, not a data problem.
+//
+// Example:
+//
+//	amount := MustGet[float64](r, "amount") // Panics: field "amout" not found if misspelled
+func MustGet[T any](r Record, field string) T {
+	val, err := GetErr[T](r, field)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// FieldError reports a record field that is missing or doesn't convert to the type
+// asked for
+type FieldError struct {
+	Field     string
+	Value     any      // The value that didn't convert; nil when the field is missing
+	Missing   bool     // The record has no such field
+	Type      string   // The type asked for
+	Available []string // The record's fields, sorted, when the field is missing
+}
+
+func (e *FieldError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("field %q not found (record has %s)", e.Field, strings.Join(e.Available, ", "))
+	}
+	return fmt.Sprintf("field %q: cannot convert %T %v to %s", e.Field, e.Value, e.Value, e.Type)
+}
+
 // SetField assigns a value to a record field with compile-time type safety
 func SetField[V Value](r Record, field string, value V) Record {
-	result := make(Record, len(r)+1)
+	result := AcquireRecord(len(r) + 1)
 	for k, v := range r {
 		result[k] = v
 	}
 	result[field] = value
+	inheritFieldOrder(result, r)
 	return result
 }
 
@@ -192,48 +406,386 @@ func (r Record) Has(field string) bool {
 	return exists
 }
 
-// Keys returns all field names
+// Keys returns all field names, in sorted order - or, for an ordered record, in its
+// field order followed by any fields the order doesn't cover, sorted
 func (r Record) Keys() []string {
+	if order := fieldOrder(r); order != nil {
+		return orderedKeys(r, order)
+	}
 	keys := make([]string, 0, len(r))
 	for k := range r {
 		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
-// Set creates a new Record with an additional field - immutable update
+// Set creates a new Record with an additional field - immutable update. The new
+// record keeps an ordered record's field order, with field appended if it is new.
 func (r Record) Set(field string, value any) Record {
-	result := make(Record, len(r)+1)
+	result := AcquireRecord(len(r) + 1)
 	for k, v := range r {
 		result[k] = v
 	}
 	result[field] = value
+	inheritFieldOrder(result, r)
+	return result
+}
+
+// ============================================================================
+// PATH ACCESS INTO NESTED RECORDS
+// ============================================================================
+
+// GetPath retrieves a typed value by a sep-separated path into nested records, such as
+// "customer.address.city". At each level a flat key wins over descending, so the path
+// also finds values in DotFlatten output and in partly flattened records. Nested
+// map[string]any values (as decoded from JSON) are traversed like Records; anything else
+// in the way, including streams, ends the path with false. An empty sep means ".".
+func GetPath[T any](r Record, path, sep string) (T, bool) {
+	holder, key, ok := lookupPath(r, path, pathSeparator(sep))
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return Get[T](holder, key)
+}
+
+// HasPath reports whether GetPath would find a field at path
+func HasPath(r Record, path, sep string) bool {
+	_, _, ok := lookupPath(r, path, pathSeparator(sep))
+	return ok
+}
+
+// SetPath creates a new Record with value stored at path - an immutable update like Set.
+// An existing field found as GetPath would find it is replaced; otherwise intermediate
+// Records are created, replacing any non-Record value in the way. Records along the path
+// are copied, so r and the records nested in it are not modified.
+func SetPath(r Record, path, sep string, value any) Record {
+	sep = pathSeparator(sep)
+	set := func(holder Record, key string) { holder[key] = value }
+	if updated, ok := rewritePath(r, path, sep, set); ok {
+		return updated
+	}
+	return createPath(r, path, sep, set)
+}
+
+// DeletePath creates a new Record without the field GetPath would find at path, copying
+// the records along the path. r is returned as it is when there is no such field.
+func DeletePath(r Record, path, sep string) Record {
+	updated, ok := rewritePath(r, path, pathSeparator(sep), func(holder Record, key string) {
+		delete(holder, key)
+	})
+	if !ok {
+		return r
+	}
+	return updated
+}
+
+// pathSeparator defaults an empty separator to ".", as DotFlatten does
+func pathSeparator(sep string) string {
+	if sep == "" {
+		return "."
+	}
+	return sep
+}
+
+// nestedRecord returns value as a Record if it is one, or a map[string]any
+func nestedRecord(value any) (Record, bool) {
+	switch v := value.(type) {
+	case Record:
+		return v, true
+	case map[string]any:
+		return Record(v), true
+	}
+	return nil, false
+}
+
+// pathSplits calls fn with each way of splitting path at a separator, shortest prefix
+// first, until fn returns true
+func pathSplits(path, sep string, fn func(prefix, rest string) bool) {
+	for i := 0; ; {
+		j := strings.Index(path[i:], sep)
+		if j < 0 {
+			return
+		}
+		i += j
+		if fn(path[:i], path[i+len(sep):]) {
+			return
+		}
+		i += len(sep)
+	}
+}
+
+// lookupPath finds the record holding the field at path, and the field's key in it
+func lookupPath(r Record, path, sep string) (holder Record, key string, found bool) {
+	if _, exists := r[path]; exists {
+		return r, path, true
+	}
+	pathSplits(path, sep, func(prefix, rest string) bool {
+		if nested, ok := nestedRecord(r[prefix]); ok {
+			holder, key, found = lookupPath(nested, rest, sep)
+		}
+		return found
+	})
+	return holder, key, found
+}
+
+// rewritePath applies update to a copy of the record holding the field at path, copying
+// every record above it; it reports false, changing nothing, when there is no such field
+func rewritePath(r Record, path, sep string, update func(holder Record, key string)) (Record, bool) {
+	if _, exists := r[path]; exists {
+		result := r.Set(path, r[path])
+		update(result, path)
+		return result, true
+	}
+	var result Record
+	pathSplits(path, sep, func(prefix, rest string) bool {
+		nested, ok := nestedRecord(r[prefix])
+		if !ok {
+			return false
+		}
+		if rewritten, ok := rewritePath(nested, rest, sep, update); ok {
+			result = r.Set(prefix, rewritten)
+		}
+		return result != nil
+	})
+	return result, result != nil
+}
+
+// createPath applies update at path in a copy of r, descending into the record under
+// each first segment and creating it if it is missing
+func createPath(r Record, path, sep string, update func(holder Record, key string)) Record {
+	i := strings.Index(path, sep)
+	if i < 0 {
+		result := r.Set(path, nil)
+		update(result, path)
+		return result
+	}
+	first, rest := path[:i], path[i+len(sep):]
+	nested, _ := nestedRecord(r[first])
+	return r.Set(first, createPath(nested, rest, sep, update))
+}
+
+// Clone returns a deep copy of the record: nested Records, maps and slices are copied, so
+// changing the clone never changes the original. Stream fields are shared, not copied - a
+// stream can only be read once, so the clone and the original draw from the same stream.
+// Materialize a stream field first if both need to read it.
+func (r Record) Clone() Record {
+	if r == nil {
+		return nil
+	}
+	result := make(Record, len(r))
+	for k, v := range r {
+		result[k] = cloneValue(v)
+	}
 	return result
 }
 
+// cloneValue deep-copies the maps and slices inside a field value
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case Record:
+		return v.Clone()
+	case map[string]any:
+		return map[string]any(Record(v).Clone())
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice || rv.IsNil() {
+		return value
+	}
+	copied := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		if item := cloneValue(rv.Index(i).Interface()); item != nil {
+			copied.Index(i).Set(reflect.ValueOf(item))
+		}
+	}
+	return copied.Interface()
+}
+
+// EqualOption configures Record.Equal
+type EqualOption func(*equalConfig)
+
+// equalConfig holds Record.Equal configuration
+type equalConfig struct {
+	streamContents bool
+}
+
+// WithStreamContents compares stream fields by their elements instead of by identity.
+// Reading a stream consumes it, so the stream fields of both records are drained.
+func WithStreamContents() EqualOption {
+	return func(config *equalConfig) {
+		config.streamContents = true
+	}
+}
+
+// Equal reports whether two records hold the same fields with deeply equal values.
+// Nested Records and maps compare field by field, slices element by element, and times
+// by instant. Values of different types are unequal, so int64(1) is not 1.0. Stream
+// fields are equal only when they are the same stream, unless WithStreamContents is set.
+func (r Record) Equal(other Record, options ...EqualOption) bool {
+	config := &equalConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	return recordsEqual(r, other, config)
+}
+
+// recordsEqual compares two records field by field
+func recordsEqual(a, b Record, config *equalConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, av := range a {
+		bv, exists := b[key]
+		if !exists || !valuesEqual(av, bv, config) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares two field values for Record.Equal
+func valuesEqual(a, b any, config *equalConfig) bool {
+	switch av := a.(type) {
+	case Record:
+		bv, ok := b.(Record)
+		return ok && recordsEqual(av, bv, config)
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		return ok && recordsEqual(av, bv, config)
+	case time.Time:
+		bv, ok := b.(time.Time)
+		return ok && av.Equal(bv)
+	}
+
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if ra.Kind() == reflect.Func && rb.Kind() == reflect.Func {
+		if ra.Type() != rb.Type() {
+			return false
+		}
+		if !config.streamContents {
+			return closureAddress(a) == closureAddress(b)
+		}
+		aItems, aOk := streamFieldValues(a)
+		bItems, bOk := streamFieldValues(b)
+		return aOk && bOk && sliceValuesEqual(aItems, bItems, config)
+	}
+	if ra.Kind() == reflect.Slice && rb.Kind() == reflect.Slice {
+		if ra.Type() != rb.Type() || ra.Len() != rb.Len() {
+			return false
+		}
+		for i := 0; i < ra.Len(); i++ {
+			if !valuesEqual(ra.Index(i).Interface(), rb.Index(i).Interface(), config) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sliceValuesEqual compares collected stream elements pairwise
+func sliceValuesEqual(a, b []any, config *equalConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !valuesEqual(a[i], b[i], config) {
+			return false
+		}
+	}
+	return true
+}
+
+// closureAddress returns the address of the closure held by a func value in an
+// interface; funcs are pointer-shaped, so it is the interface's data word
+func closureAddress(value any) unsafe.Pointer {
+	return (*[2]unsafe.Pointer)(unsafe.Pointer(&value))[1]
+}
+
+// ErrFieldConflict is returned by MergeInto under FailOnConflict
+var ErrFieldConflict = errors.New("field present in both records")
+
+// ConflictPolicy decides what MergeInto does with a field present in both records
+type ConflictPolicy struct {
+	mode      conflictMode
+	dstPrefix string
+	srcPrefix string
+}
+
+type conflictMode int
+
+const (
+	conflictOverwrite conflictMode = iota
+	conflictKeep
+	conflictPrefix
+	conflictFail
+)
+
+// Conflict policies for MergeInto; see also PrefixOnConflict
+var (
+	OverwriteOnConflict = ConflictPolicy{mode: conflictOverwrite} // The src value replaces the dst value
+	KeepOnConflict      = ConflictPolicy{mode: conflictKeep}      // The dst value is kept
+	FailOnConflict      = ConflictPolicy{mode: conflictFail}      // MergeInto returns ErrFieldConflict and leaves dst unchanged
+)
+
+// PrefixOnConflict keeps both values of a conflicting field, renaming them dstPrefix+field
+// and srcPrefix+field - the joins' WithPrefixes behaviour
+func PrefixOnConflict(dstPrefix, srcPrefix string) ConflictPolicy {
+	if dstPrefix == "" || srcPrefix == "" {
+		panic("conflict prefixes must not be empty")
+	}
+	return ConflictPolicy{mode: conflictPrefix, dstPrefix: dstPrefix, srcPrefix: srcPrefix}
+}
+
+// MergeInto copies the fields of src into dst, resolving fields present in both with
+// policy. Unlike Set it modifies dst in place. Values are not copied, so nested Records
+// end up shared between src and dst; merge src.Clone() to keep them apart.
+func MergeInto(dst, src Record, policy ConflictPolicy) error {
+	if policy.mode == conflictFail {
+		for key := range src {
+			if _, exists := dst[key]; exists {
+				return fmt.Errorf("%w: %q", ErrFieldConflict, key)
+			}
+		}
+	}
+
+	for key, value := range src {
+		existing, exists := dst[key]
+		if !exists {
+			dst[key] = value
+			continue
+		}
+		switch policy.mode {
+		case conflictKeep:
+			// Leave the dst value in place
+		case conflictPrefix:
+			delete(dst, key)
+			dst[policy.dstPrefix+key] = existing
+			dst[policy.srcPrefix+key] = value
+		default:
+			dst[key] = value
+		}
+	}
+	return nil
+}
+
 // ============================================================================
 // SMART TYPE CONVERSION SYSTEM
 // ============================================================================
 
+// convertTo converts val to T following the matrix documented on Get
 func convertTo[T any](val any) (T, bool) {
 	var zero T
-	targetType := reflect.TypeOf(zero)
 
 	// Handle nil
 	if val == nil {
 		return zero, false
 	}
 
-	sourceVal := reflect.ValueOf(val)
-
-	// Try direct conversion for basic types
-	if sourceVal.Type().ConvertibleTo(targetType) {
-		converted := sourceVal.Convert(targetType)
-		return converted.Interface().(T), true
-	}
-
 	// Custom conversions for common cases
-	switch target := any(zero).(type) {
+	switch any(zero).(type) {
 	case int64:
 		if converted, ok := convertToInt64(val); ok {
 			return any(converted).(T), true
@@ -259,10 +811,50 @@ func convertTo[T any](val any) (T, bool) {
 			return any(converted).(T), true
 		}
 		return zero, false
-	default:
-		_ = target
-		return zero, false
 	}
+
+	// Other types, such as int32 or named types, convert by kind
+	targetType := reflect.TypeFor[T]()
+	sourceVal := reflect.ValueOf(val)
+	if targetType.Kind() == reflect.String && isNumericKind(sourceVal.Kind()) {
+		// reflect would turn integers into one-rune strings
+		converted, _ := convertToString(val)
+		return reflect.ValueOf(converted).Convert(targetType).Interface().(T), true
+	}
+	if sourceVal.Type().ConvertibleTo(targetType) {
+		converted := sourceVal.Convert(targetType)
+		return converted.Interface().(T), true
+	}
+	if str, ok := val.(string); ok && isNumericKind(targetType.Kind()) {
+		// Parse to the widest type of the kind, then convert; out of range fails
+		var parsed any
+		var err error
+		str = strings.TrimSpace(str)
+		switch targetType.Kind() {
+		case reflect.Float32, reflect.Float64:
+			parsed, err = strconv.ParseFloat(str, targetType.Bits())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			parsed, err = strconv.ParseUint(str, 10, targetType.Bits())
+		default:
+			parsed, err = strconv.ParseInt(str, 10, targetType.Bits())
+		}
+		if err != nil {
+			return zero, false
+		}
+		return reflect.ValueOf(parsed).Convert(targetType).Interface().(T), true
+	}
+	return zero, false
+}
+
+// isNumericKind reports whether kind is an integer or floating point kind
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
 }
 
 func convertToInt64(val any) (int64, bool) {
@@ -277,6 +869,8 @@ func convertToInt64(val any) (int64, bool) {
 		return int64(v), true
 	case int8:
 		return int64(v), true
+	case uint:
+		return int64(v), true
 	case uint64:
 		return int64(v), true
 	case uint32:
@@ -290,7 +884,7 @@ func convertToInt64(val any) (int64, bool) {
 	case float32:
 		return int64(v), true
 	case string:
-		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if parsed, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
 			return parsed, true
 		}
 		return 0, false
@@ -315,6 +909,8 @@ func convertToFloat64(val any) (float64, bool) {
 		return float64(v), true
 	case int8:
 		return float64(v), true
+	case uint:
+		return float64(v), true
 	case uint64:
 		return float64(v), true
 	case uint32:
@@ -324,7 +920,7 @@ func convertToFloat64(val any) (float64, bool) {
 	case uint8:
 		return float64(v), true
 	case string:
-		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
 			return parsed, true
 		}
 		return 0, false
@@ -355,7 +951,14 @@ func convertToBool(val any) (bool, bool) {
 	case float64:
 		return v != 0, true
 	case string:
-		return v != "", true
+		// Only unambiguous words: "false" or "N/A" must not read as true
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "1", "yes":
+			return true, true
+		case "false", "0", "no":
+			return false, true
+		}
+		return false, false
 	default:
 		return false, false
 	}
@@ -429,6 +1032,13 @@ func FromChannel[V Value](ch <-chan V) Stream[V] {
 	return fromChannelImpl(ch)
 }
 
+// FromChannelContext is FromChannel that also stops when ctx is done: a pull waits for
+// an item and for ctx at once, so cancelling ctx releases a pull blocked on a channel
+// nobody sends on. Once ctx is done every pull returns ctx.Err().
+func FromChannelContext[V Value](ctx context.Context, ch <-chan V) Stream[V] {
+	return fromChannelContextImpl(ctx, ch)
+}
+
 // From creates a type-safe stream from variadic arguments (Safe by Default)
 func From[V Value](items ...V) Stream[V] {
 	return fromSliceImpl(items)
@@ -450,6 +1060,131 @@ func FromChannelAny[T any](ch <-chan T) Stream[T] {
 	return fromChannelImpl(ch)
 }
 
+// FromChannelAnyContext is FromChannelContext for any type channel - USE WITH CAUTION
+func FromChannelAnyContext[T any](ctx context.Context, ch <-chan T) Stream[T] {
+	return fromChannelContextImpl(ctx, ch)
+}
+
+// ============================================================================
+// CHANNEL SINKS - BRIDGING TO WORKER POOLS AND PRODUCERS
+// ============================================================================
+
+// ToChannel pumps a stream into ch until the stream ends or ctx is cancelled.
+// The source is only pulled once the previous item has been accepted, so a
+// slow reader applies backpressure. Returns nil at EOS, the first stream error,
+// or ctx.Err(). ch is not closed; cancel ctx if the reader may stop early.
+func ToChannel[T any](stream Stream[T], ch chan<- T, ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		item, err := stream()
+		if err != nil {
+			if err == EOS {
+				return nil
+			}
+			return err
+		}
+		select {
+		case ch <- item:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ToChannelBatched pumps a stream into ch as slices of up to batchSize items.
+// A partial batch is sent once maxLatency has passed since its first item, so
+// a quiet source doesn't hold items back indefinitely. The final partial batch
+// is sent before returning at EOS or on a stream error. Like ToChannel, at most
+// one item is read ahead of a blocked reader and ch is not closed.
+func ToChannelBatched[T any](stream Stream[T], ch chan<- []T, batchSize int, maxLatency time.Duration, ctx context.Context) error {
+	if batchSize <= 0 {
+		panic("batch size must be positive")
+	}
+	if maxLatency <= 0 {
+		panic("max latency must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type pulled struct {
+		item T
+		err  error
+	}
+
+	// The source is pulled in its own goroutine so the latency timer can fire
+	// while it blocks; cancel releases it on return
+	items := make(chan pulled)
+	go func() {
+		for {
+			item, err := stream()
+			select {
+			case items <- pulled{item: item, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	batch := make([]T, 0, batchSize)
+	var timer *time.Timer
+	var deadline <-chan time.Time
+
+	flush := func() error {
+		if timer != nil {
+			timer.Stop()
+			timer, deadline = nil, nil
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		select {
+		case ch <- batch:
+			batch = make([]T, 0, batchSize)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for {
+		select {
+		case next := <-items:
+			if next.err != nil {
+				if err := flush(); err != nil {
+					return err
+				}
+				if next.err == EOS {
+					return nil
+				}
+				return next.err
+			}
+			batch = append(batch, next.item)
+			if len(batch) == 1 {
+				timer = time.NewTimer(maxLatency)
+				deadline = timer.C
+			}
+			if len(batch) == batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-deadline:
+			timer, deadline = nil, nil
+			if err := flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // ============================================================================
 // INTERNAL IMPLEMENTATIONS - SHARED BY SAFE AND UNSAFE VARIANTS
 // ============================================================================
@@ -478,6 +1213,24 @@ func fromChannelImpl[T any](ch <-chan T) Stream[T] {
 	}
 }
 
+func fromChannelContextImpl[T any](ctx context.Context, ch <-chan T) Stream[T] {
+	return func() (T, error) {
+		var zero T
+		if err := ctx.Err(); err != nil {
+			return zero, err // Checked first so a ready channel can't win the select
+		}
+		select {
+		case item, ok := <-ch:
+			if !ok {
+				return zero, EOS
+			}
+			return item, nil
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
 // ============================================================================
 // RECORD STREAM CREATION - WITH VALIDATION
 // ============================================================================
@@ -579,6 +1332,41 @@ func GenerateAny[T any](generator func() (T, error)) Stream[T] {
 	return generator
 }
 
+// GenerateContext creates a Value-safe stream from a generator that is handed ctx, so
+// one blocked in I/O or a select can give up when ctx is done. ctx is checked before
+// every call, and an error the generator returns once ctx is done is reported as
+// ctx.Err().
+//
+// Example:
+//
+//	messages := GenerateContext(ctx, func(ctx context.Context) (string, error) {
+//	    return queue.Receive(ctx)
+//	})
+func GenerateContext[V Value](ctx context.Context, generator func(ctx context.Context) (V, error)) Stream[V] {
+	return generateContextImpl(ctx, generator)
+}
+
+// GenerateAnyContext is GenerateContext for any type - USE WITH CAUTION
+func GenerateAnyContext[T any](ctx context.Context, generator func(ctx context.Context) (T, error)) Stream[T] {
+	return generateContextImpl(ctx, generator)
+}
+
+func generateContextImpl[T any](ctx context.Context, generator func(ctx context.Context) (T, error)) Stream[T] {
+	return func() (T, error) {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+		item, err := generator(ctx)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return item, ctxErr
+			}
+		}
+		return item, err
+	}
+}
+
 // Range creates a numeric stream (int64 values are Value-compatible)
 func Range(start, end, step int64) Stream[int64] {
 	current := start
@@ -616,4 +1404,76 @@ func OnceAny[T any](item T) Stream[T] {
 		consumed = true
 		return item, nil
 	}
+}
+
+// Repeat creates a Value-safe stream that emits item n times
+func Repeat[V Value](item V, n int) Stream[V] {
+	if n < 0 {
+		panic("n must not be negative")
+	}
+	remaining := n
+	return func() (V, error) {
+		if remaining == 0 {
+			var zero V
+			return zero, EOS
+		}
+		remaining--
+		return item, nil
+	}
+}
+
+// RepeatForever creates an infinite Value-safe stream of item; bound it with Take or
+// LimitDuration
+func RepeatForever[V Value](item V) Stream[V] {
+	return func() (V, error) {
+		return item, nil
+	}
+}
+
+// Tick creates an infinite stream that emits the current time every d, for heartbeats,
+// demos and load tests. The first tick comes d after the first pull. Ticks come from a
+// time.Ticker, so pacing doesn't drift however long the stream runs; like the ticker,
+// it drops ticks a slow consumer misses rather than queueing them. Once ctx is
+// cancelled the ticker is stopped and every pull returns ctx.Err().
+func Tick(ctx context.Context, d time.Duration) Stream[time.Time] {
+	if d <= 0 {
+		panic("tick interval must be positive")
+	}
+	var ticker *time.Ticker
+	return func() (time.Time, error) {
+		if err := ctx.Err(); err != nil {
+			return time.Time{}, err
+		}
+		if ticker == nil {
+			ticker = time.NewTicker(d)
+			context.AfterFunc(ctx, ticker.Stop)
+		}
+		select {
+		case t := <-ticker.C:
+			return t, nil
+		case <-ctx.Done():
+			return time.Time{}, ctx.Err()
+		}
+	}
+}
+
+// Interval creates an infinite stream with one record per tick of Tick(ctx, d), built
+// by fn from the tick's index (starting at 0)
+//
+// Example:
+//
+//	heartbeats := Interval(ctx, time.Second, func(i int64) Record {
+//	    return NewRecord().Int("seq", i).Time("at", time.Now()).Build()
+//	})
+func Interval(ctx context.Context, d time.Duration, fn func(i int64) Record) Stream[Record] {
+	ticks := Tick(ctx, d)
+	index := int64(0)
+	return func() (Record, error) {
+		if _, err := ticks(); err != nil {
+			return nil, err
+		}
+		record := fn(index)
+		index++
+		return record, nil
+	}
 }
\ No newline at end of file
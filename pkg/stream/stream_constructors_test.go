@@ -1,6 +1,11 @@
 package stream
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -474,6 +479,267 @@ func TestSetField(t *testing.T) {
 	})
 }
 
+// TestPathAccess tests dotted access into nested, flattened and mixed records
+func TestPathAccess(t *testing.T) {
+	nested := Record{"customer": Record{"name": "Alice", "address": Record{"city": "Paris"}}}
+	flattened := Record{"customer.name": "Alice", "customer.address.city": "Paris"}
+	mixed := Record{"customer": Record{"name": "Alice", "address.city": "Paris"}}
+	decoded := Record{"customer": map[string]any{"name": "Alice", "address": map[string]any{"city": "Paris"}}}
+	streamed := Record{"customer": FromRecordsUnsafe([]Record{{"address": Record{"city": "Paris"}}})}
+
+	t.Run("GetPath", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			record Record
+			path   string
+			want   string
+			found  bool
+		}{
+			{"Nested", nested, "customer.address.city", "Paris", true},
+			{"Flattened", flattened, "customer.address.city", "Paris", true},
+			{"Mixed", mixed, "customer.address.city", "Paris", true},
+			{"DecodedJSON", decoded, "customer.address.city", "Paris", true},
+			{"TopLevel", nested, "customer.name", "Alice", true},
+			{"Missing", nested, "customer.address.zip", "", false},
+			{"ThroughScalar", nested, "customer.name.first", "", false},
+			{"ThroughStream", streamed, "customer.address.city", "", false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, found := GetPath[string](tt.record, tt.path, ".")
+				if got != tt.want || found != tt.found {
+					t.Errorf("GetPath(%q) = %q, %v; want %q, %v", tt.path, got, found, tt.want, tt.found)
+				}
+				if has := HasPath(tt.record, tt.path, "."); has != tt.found {
+					t.Errorf("HasPath(%q) = %v; want %v", tt.path, has, tt.found)
+				}
+			})
+		}
+	})
+
+	t.Run("SetPath", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			record Record
+			path   string
+			want   Record
+		}{
+			{"Nested", nested, "customer.address.city", Record{"customer": Record{"name": "Alice", "address": Record{"city": "Lyon"}}}},
+			{"Flattened", flattened, "customer.address.city", Record{"customer.name": "Alice", "customer.address.city": "Lyon"}},
+			{"Mixed", mixed, "customer.address.city", Record{"customer": Record{"name": "Alice", "address.city": "Lyon"}}},
+			{"CreatesIntermediates", Record{"id": int64(1)}, "customer.address.city", Record{"id": int64(1), "customer": Record{"address": Record{"city": "Lyon"}}}},
+			{"ExtendsExisting", nested, "customer.address.zip", Record{"customer": Record{"name": "Alice", "address": Record{"city": "Paris", "zip": "Lyon"}}}},
+			{"ReplacesScalar", Record{"customer": "Alice"}, "customer.address.city", Record{"customer": Record{"address": Record{"city": "Lyon"}}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				before := tt.record.Clone()
+				got := SetPath(tt.record, tt.path, ".", "Lyon")
+				if !got.Equal(tt.want) {
+					t.Errorf("SetPath(%q) = %v; want %v", tt.path, got, tt.want)
+				}
+				if !tt.record.Equal(before) {
+					t.Errorf("Expected the input to be unchanged, got %v", tt.record)
+				}
+			})
+		}
+	})
+
+	t.Run("DeletePath", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			record Record
+			path   string
+			want   Record
+		}{
+			{"Nested", nested, "customer.address.city", Record{"customer": Record{"name": "Alice", "address": Record{}}}},
+			{"Flattened", flattened, "customer.address.city", Record{"customer.name": "Alice"}},
+			{"Mixed", mixed, "customer.address.city", Record{"customer": Record{"name": "Alice"}}},
+			{"Missing", nested, "customer.phone", nested},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				before := tt.record.Clone()
+				got := DeletePath(tt.record, tt.path, ".")
+				if !got.Equal(tt.want) {
+					t.Errorf("DeletePath(%q) = %v; want %v", tt.path, got, tt.want)
+				}
+				if !tt.record.Equal(before) {
+					t.Errorf("Expected the input to be unchanged, got %v", tt.record)
+				}
+			})
+		}
+	})
+
+	t.Run("MatchesDotFlatten", func(t *testing.T) {
+		flat, err := Collect(DotFlatten(".")(FromRecordsUnsafe([]Record{nested})))
+		if err != nil || len(flat) != 1 {
+			t.Fatalf("DotFlatten failed: %v", err)
+		}
+		for _, path := range []string{"customer.name", "customer.address.city"} {
+			a, _ := GetPath[string](nested, path, "")
+			b, _ := GetPath[string](flat[0], path, "")
+			if a != b {
+				t.Errorf("Expected %q to match after DotFlatten, got %q and %q", path, a, b)
+			}
+		}
+	})
+}
+
+// TestRecordClone tests deep copies of records
+func TestRecordClone(t *testing.T) {
+	t.Run("NestedAliasing", func(t *testing.T) {
+		address := Record{"city": "Paris", "lines": []string{"1 Rue de Rivoli"}}
+		original := Record{
+			"name":    "Alice",
+			"address": address,
+			"tags":    []any{Record{"tag": "vip"}},
+			"meta":    map[string]any{"source": "crm"},
+		}
+
+		clone := original.Clone()
+		clone["address"].(Record)["city"] = "Lyon"
+		clone["address"].(Record)["lines"].([]string)[0] = "2 Place Bellecour"
+		clone["tags"].([]any)[0].(Record)["tag"] = "regular"
+		clone["meta"].(map[string]any)["source"] = "import"
+
+		if address["city"] != "Paris" || address["lines"].([]string)[0] != "1 Rue de Rivoli" {
+			t.Errorf("Expected the original address to be unchanged, got %v", address)
+		}
+		if original["tags"].([]any)[0].(Record)["tag"] != "vip" || original["meta"].(map[string]any)["source"] != "crm" {
+			t.Errorf("Expected the original record to be unchanged, got %v", original)
+		}
+	})
+
+	t.Run("JoinedRecordsSharingNestedRecord", func(t *testing.T) {
+		address := Record{"city": "Paris"}
+		left := FromRecordsUnsafe([]Record{{"id": int64(1)}, {"id": int64(1)}})
+		right := FromRecordsUnsafe([]Record{{"userId": int64(1), "address": address}})
+		joined, _ := Collect(InnerJoin(right, "id", "userId")(left))
+
+		first := joined[0].Clone()
+		first["address"].(Record)["city"] = "Lyon"
+		if joined[1]["address"].(Record)["city"] != "Paris" {
+			t.Errorf("Expected the second joined record to keep its address, got %v", joined[1])
+		}
+	})
+
+	t.Run("StreamsShared", func(t *testing.T) {
+		original := Record{"values": FromSlice([]int64{1, 2})}
+		clone := original.Clone()
+		if !clone.Equal(original) {
+			t.Errorf("Expected the clone to share the stream field")
+		}
+		if Record(nil).Clone() != nil {
+			t.Error("Expected a nil clone of a nil record")
+		}
+	})
+}
+
+// TestRecordEqual tests deep record equality
+func TestRecordEqual(t *testing.T) {
+	t.Run("DeepAndUnordered", func(t *testing.T) {
+		instant := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		a := make(Record)
+		b := make(Record)
+		// Build the same fields in opposite orders
+		fields := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+		for i, field := range fields {
+			a[field] = int64(i)
+			b[fields[len(fields)-1-i]] = int64(len(fields) - 1 - i)
+		}
+		a["nested"] = Record{"x": 1.5, "when": instant, "list": []any{"p", Record{"q": true}}}
+		b["nested"] = Record{"list": []any{"p", Record{"q": true}}, "when": instant.In(time.FixedZone("CET", 3600)), "x": 1.5}
+
+		if !a.Equal(b) || !b.Equal(a) {
+			t.Errorf("Expected %v and %v to be equal", a, b)
+		}
+
+		b["nested"].(Record)["list"].([]any)[1].(Record)["q"] = false
+		if a.Equal(b) {
+			t.Error("Expected a change deep inside a nested record to make them unequal")
+		}
+	})
+
+	t.Run("TypesMatter", func(t *testing.T) {
+		if (Record{"n": int64(1)}).Equal(Record{"n": 1.0}) {
+			t.Error("Expected int64(1) and 1.0 to be unequal")
+		}
+		if (Record{"n": nil}).Equal(Record{"m": nil}) {
+			t.Error("Expected different field names to be unequal")
+		}
+	})
+
+	t.Run("StreamFields", func(t *testing.T) {
+		shared := FromSlice([]int64{1, 2, 3})
+		if !(Record{"s": shared}).Equal(Record{"s": shared}) {
+			t.Error("Expected the same stream to be equal by identity")
+		}
+
+		a := Record{"s": FromSlice([]int64{1, 2, 3})}
+		b := Record{"s": FromSlice([]int64{1, 2, 3})}
+		if a.Equal(b) {
+			t.Error("Expected distinct streams to be unequal by identity")
+		}
+		if !a.Equal(b, WithStreamContents()) {
+			t.Error("Expected streams with the same elements to be equal by contents")
+		}
+
+		c := Record{"s": FromSlice([]int64{1, 2})}
+		d := Record{"s": FromSlice([]int64{1, 2, 4})}
+		if c.Equal(d, WithStreamContents()) {
+			t.Error("Expected streams with different elements to be unequal by contents")
+		}
+	})
+}
+
+// TestMergeInto tests merging records under each conflict policy
+func TestMergeInto(t *testing.T) {
+	src := func() Record { return Record{"name": "Bob", "dept": "Sales"} }
+	dst := func() Record { return Record{"name": "Alice", "id": int64(1)} }
+
+	t.Run("Overwrite", func(t *testing.T) {
+		merged := dst()
+		if err := MergeInto(merged, src(), OverwriteOnConflict); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !merged.Equal(Record{"name": "Bob", "id": int64(1), "dept": "Sales"}) {
+			t.Errorf("Expected src to win, got %v", merged)
+		}
+	})
+
+	t.Run("Keep", func(t *testing.T) {
+		merged := dst()
+		MergeInto(merged, src(), KeepOnConflict)
+		if !merged.Equal(Record{"name": "Alice", "id": int64(1), "dept": "Sales"}) {
+			t.Errorf("Expected dst to win, got %v", merged)
+		}
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		merged := dst()
+		MergeInto(merged, src(), PrefixOnConflict("user.", "manager."))
+		expected := Record{"user.name": "Alice", "manager.name": "Bob", "id": int64(1), "dept": "Sales"}
+		if !merged.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, merged)
+		}
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		merged := dst()
+		err := MergeInto(merged, src(), FailOnConflict)
+		if !errors.Is(err, ErrFieldConflict) {
+			t.Errorf("Expected ErrFieldConflict, got %v", err)
+		}
+		if !merged.Equal(dst()) {
+			t.Errorf("Expected dst to be unchanged after a failed merge, got %v", merged)
+		}
+		if err := MergeInto(merged, Record{"other": true}, FailOnConflict); err != nil || !merged.Has("other") {
+			t.Errorf("Expected a merge without conflicts to succeed, got %v (%v)", merged, err)
+		}
+	})
+}
+
 // TestFromChannelAny tests the FromChannelAny function
 func TestFromChannelAny(t *testing.T) {
 	t.Run("CustomStruct", func(t *testing.T) {
@@ -561,4 +827,276 @@ func TestOnceAny(t *testing.T) {
 			t.Errorf("Expected debug=true/port=8080, got %+v", results[0])
 		}
 	})
-}
\ No newline at end of file
+}
+// TestToChannel tests pumping a stream into a channel
+func TestToChannel(t *testing.T) {
+	t.Run("AllElements", func(t *testing.T) {
+		ch := make(chan int64, 3)
+		if err := ToChannel(FromSlice([]int64{1, 2, 3}), ch, context.Background()); err != nil {
+			t.Fatalf("ToChannel failed: %v", err)
+		}
+		close(ch)
+
+		results, _ := Collect(FromChannel(ch))
+		if len(results) != 3 || results[0] != 1 || results[2] != 3 {
+			t.Errorf("Expected [1 2 3], got %v", results)
+		}
+	})
+
+	t.Run("Backpressure", func(t *testing.T) {
+		var pulls atomic.Int64
+		source := Generate(func() (int64, error) { return pulls.Add(1), nil })
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := make(chan int64)
+		go ToChannel(source, ch, ctx)
+		<-ch
+		<-ch
+		time.Sleep(50 * time.Millisecond)
+
+		// Two items read, at most one more pulled and waiting to be sent
+		if n := pulls.Load(); n > 3 {
+			t.Errorf("Expected the source to stop being pulled, got %d pulls", n)
+		}
+	})
+
+	t.Run("ContextCancel", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() { done <- ToChannel(Generate(func() (int, error) { return 1, nil }), make(chan int), ctx) }()
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != context.Canceled {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("ToChannel did not return after cancel")
+		}
+		time.Sleep(20 * time.Millisecond)
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Potential goroutine leak: %d -> %d", before, after)
+		}
+	})
+
+	t.Run("PropagatesError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		err := ToChannel(func() (int, error) { return 0, failure }, make(chan int), context.Background())
+		if err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+	})
+}
+
+// TestToChannelBatched tests pumping a stream into a channel in batches
+func TestToChannelBatched(t *testing.T) {
+	t.Run("FullAndFinalBatches", func(t *testing.T) {
+		ch := make(chan []int64, 3)
+		err := ToChannelBatched(FromSlice([]int64{1, 2, 3, 4, 5, 6, 7}), ch, 3, time.Second, context.Background())
+		if err != nil {
+			t.Fatalf("ToChannelBatched failed: %v", err)
+		}
+		close(ch)
+
+		var sizes []int
+		for batch := range ch {
+			sizes = append(sizes, len(batch))
+		}
+		if fmt.Sprint(sizes) != "[3 3 1]" {
+			t.Errorf("Expected batch sizes [3 3 1], got %v", sizes)
+		}
+	})
+
+	t.Run("MaxLatencyFlush", func(t *testing.T) {
+		release := make(chan struct{})
+		sent := 0
+		source := func() (int, error) {
+			sent++
+			if sent > 2 {
+				<-release // Stall until the partial batch has been seen
+				return 0, EOS
+			}
+			return sent, nil
+		}
+
+		ch := make(chan []int, 2)
+		done := make(chan error)
+		go func() { done <- ToChannelBatched(source, ch, 10, 20*time.Millisecond, context.Background()) }()
+
+		select {
+		case batch := <-ch:
+			if len(batch) != 2 {
+				t.Errorf("Expected a partial batch of 2, got %v", batch)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Partial batch was not flushed after maxLatency")
+		}
+		close(release)
+		if err := <-done; err != nil {
+			t.Errorf("Expected nil at EOS, got %v", err)
+		}
+	})
+
+	t.Run("Backpressure", func(t *testing.T) {
+		var pulls atomic.Int64
+		source := Generate(func() (int64, error) { return pulls.Add(1), nil })
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := make(chan []int64)
+		go ToChannelBatched(source, ch, 4, time.Second, ctx)
+		<-ch
+		time.Sleep(50 * time.Millisecond)
+
+		// One batch read, one full batch waiting to be sent and one item read ahead
+		if n := pulls.Load(); n > 9 {
+			t.Errorf("Expected the source to stop being pulled, got %d pulls", n)
+		}
+	})
+
+	t.Run("ContextCancel", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		source := Generate(func() (int, error) { return 1, nil })
+		go func() { done <- ToChannelBatched(source, make(chan []int), 2, time.Second, ctx) }()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != context.Canceled {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("ToChannelBatched did not return after cancel")
+		}
+		time.Sleep(50 * time.Millisecond)
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Potential goroutine leak: %d -> %d", before, after)
+		}
+	})
+
+	t.Run("FlushesBeforeError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		calls := 0
+		source := func() (int, error) {
+			calls++
+			if calls > 2 {
+				return 0, failure
+			}
+			return calls, nil
+		}
+
+		ch := make(chan []int, 1)
+		err := ToChannelBatched(source, ch, 10, time.Second, context.Background())
+		if err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+		if batch := <-ch; len(batch) != 2 {
+			t.Errorf("Expected the partial batch before the error, got %v", batch)
+		}
+	})
+}
+
+// TestRepeat tests the Repeat and RepeatForever sources
+func TestRepeat(t *testing.T) {
+	t.Run("Repeat", func(t *testing.T) {
+		items, err := Collect(Repeat("x", 3))
+		if err != nil || fmt.Sprintf("%q", items) != `["x" "x" "x"]` {
+			t.Errorf("Expected three x, got %q (%v)", items, err)
+		}
+		if items, _ := Collect(Repeat(int64(1), 0)); len(items) != 0 {
+			t.Errorf("Expected no items, got %v", items)
+		}
+	})
+
+	t.Run("RepeatForeverWithTake", func(t *testing.T) {
+		done := make(chan []int64)
+		go func() {
+			items, _ := Collect(Take[int64](5)(RepeatForever(int64(7))))
+			done <- items
+		}()
+		select {
+		case items := <-done:
+			if fmt.Sprint(items) != "[7 7 7 7 7]" {
+				t.Errorf("Expected five 7s, got %v", items)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Take(5) on RepeatForever did not terminate")
+		}
+	})
+
+	t.Run("NegativeCount", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic for a negative count")
+			}
+		}()
+		Repeat("x", -1)
+	})
+}
+
+// TestTick tests the Tick and Interval sources
+func TestTick(t *testing.T) {
+	const interval = 20 * time.Millisecond
+
+	t.Run("Pacing", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		start := time.Now()
+		ticks, err := Collect(Take[time.Time](5)(Tick(ctx, interval)))
+		elapsed := time.Since(start)
+		if err != nil || len(ticks) != 5 {
+			t.Fatalf("Expected 5 ticks, got %d (%v)", len(ticks), err)
+		}
+		if elapsed < 4*interval || elapsed > 25*interval {
+			t.Errorf("Expected about %v for 5 ticks, took %v", 5*interval, elapsed)
+		}
+		for i := 1; i < len(ticks); i++ {
+			if !ticks[i].After(ticks[i-1]) {
+				t.Errorf("Expected increasing tick times, got %v", ticks)
+			}
+		}
+	})
+
+	t.Run("CancelWhileWaiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ticks := Tick(ctx, time.Hour)
+		time.AfterFunc(interval, cancel)
+
+		start := time.Now()
+		if _, err := ticks(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the pull to return promptly on cancel, took %v", elapsed)
+		}
+		if _, err := ticks(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected later pulls to keep returning context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("Interval", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		records, err := Collect(Take[Record](3)(Interval(ctx, interval, func(i int64) Record {
+			return NewRecord().Int("seq", i).Build()
+		})))
+		if err != nil || len(records) != 3 {
+			t.Fatalf("Expected 3 records, got %v (%v)", records, err)
+		}
+		for i, record := range records {
+			if record["seq"] != int64(i) {
+				t.Errorf("Expected seq %d, got %v", i, record["seq"])
+			}
+		}
+	})
+}
@@ -2,6 +2,13 @@ package stream
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -253,6 +260,94 @@ func TestCountWindow(t *testing.T) {
 	})
 }
 
+// TestChunk tests Chunk, ChunkBy and Flatten
+func TestChunk(t *testing.T) {
+	t.Run("EmptyInput", func(t *testing.T) {
+		chunks, err := Collect(Chunk[int64](3)(FromSlice([]int64{})))
+		if err != nil || len(chunks) != 0 {
+			t.Errorf("Expected no chunks, got %v (%v)", chunks, err)
+		}
+	})
+
+	t.Run("ShortFinalChunk", func(t *testing.T) {
+		chunks, err := Collect(Chunk[int64](3)(Range(1, 8, 1)))
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		if fmt.Sprint(chunks) != "[[1 2 3] [4 5 6] [7]]" {
+			t.Errorf("Expected [[1 2 3] [4 5 6] [7]], got %v", chunks)
+		}
+	})
+
+	t.Run("ExactMultiple", func(t *testing.T) {
+		chunks, err := Collect(Chunk[int64](2)(Range(1, 7, 1)))
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		if fmt.Sprint(chunks) != "[[1 2] [3 4] [5 6]]" {
+			t.Errorf("Expected [[1 2] [3 4] [5 6]], got %v", chunks)
+		}
+	})
+
+	t.Run("ErrorAfterPartialChunk", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		calls := int64(0)
+		source := func() (int64, error) {
+			calls++
+			if calls > 4 {
+				return 0, failure
+			}
+			return calls, nil
+		}
+
+		chunked := Chunk[int64](3)(source)
+		chunked()
+		if partial, err := chunked(); err != nil || len(partial) != 1 {
+			t.Fatalf("Expected the partial chunk [4], got %v (%v)", partial, err)
+		}
+		if _, err := chunked(); err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+	})
+
+	t.Run("ChunkByBoundaries", func(t *testing.T) {
+		words := []string{"apple", "avocado", "banana", "blueberry", "cherry", "apricot"}
+		chunks, err := Collect(ChunkBy(func(s string) byte { return s[0] })(FromSlice(words)))
+		if err != nil {
+			t.Fatalf("ChunkBy failed: %v", err)
+		}
+
+		// A key that reappears after a change starts a new chunk
+		expected := "[[apple avocado] [banana blueberry] [cherry] [apricot]]"
+		if fmt.Sprint(chunks) != expected {
+			t.Errorf("Expected %s, got %v", expected, chunks)
+		}
+	})
+
+	t.Run("ChunkByEmpty", func(t *testing.T) {
+		chunks, err := Collect(ChunkBy(func(x int64) int64 { return x })(FromSlice([]int64{})))
+		if err != nil || len(chunks) != 0 {
+			t.Errorf("Expected no chunks, got %v (%v)", chunks, err)
+		}
+	})
+
+	t.Run("FlattenInvertsChunk", func(t *testing.T) {
+		results, err := Collect(Flatten[int64]()(Chunk[int64](4)(Range(0, 10, 1))))
+		if err != nil {
+			t.Fatalf("Flatten failed: %v", err)
+		}
+		if fmt.Sprint(results) != "[0 1 2 3 4 5 6 7 8 9]" {
+			t.Errorf("Expected 0..9, got %v", results)
+		}
+
+		// Empty slices are skipped
+		skipped, _ := Collect(Flatten[int64]()(FromSliceAny([][]int64{{}, {1}, {}, {2, 3}})))
+		if fmt.Sprint(skipped) != "[1 2 3]" {
+			t.Errorf("Expected [1 2 3], got %v", skipped)
+		}
+	})
+}
+
 // TestTimeWindow tests the TimeWindow filter
 func TestTimeWindow(t *testing.T) {
 	t.Run("TimeBasedWindows", func(t *testing.T) {
@@ -289,6 +384,240 @@ func TestTimeWindow(t *testing.T) {
 			t.Errorf("Expected at least 1 window, got %d", windowCount)
 		}
 	})
+	
+	t.Run("SlowSourceNoLoss", func(t *testing.T) {
+		// Each element takes longer than a window, so reads are often in flight when a window closes
+		next := int64(0)
+		stream := Limit[int64](12)(Generate(func() (int64, error) {
+			time.Sleep(15 * time.Millisecond)
+			next++
+			return next, nil
+		}))
+		
+		windowed := TimeWindow[int64](10 * time.Millisecond)(stream)
+		
+		var all []int64
+		windowCount := 0
+		for {
+			window, err := windowed()
+			if err == EOS {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			windowData, _ := Collect(window)
+			if len(windowData) == 0 {
+				t.Errorf("Expected empty windows to be skipped")
+			}
+			all = append(all, windowData...)
+			windowCount++
+		}
+		
+		if len(all) != 12 {
+			t.Fatalf("Expected all 12 elements, got %d: %v", len(all), all)
+		}
+		for i, v := range all {
+			if v != int64(i+1) {
+				t.Fatalf("Expected elements in order, got %v", all)
+			}
+		}
+		if windowCount < 2 {
+			t.Errorf("Expected windows to close on timeout, got %d window(s)", windowCount)
+		}
+	})
+	
+	t.Run("WindowBoundaries", func(t *testing.T) {
+		// Bursts of 3 quick elements separated by pauses longer than the window
+		count := 0
+		stream := Limit[int](9)(Generate(func() (int, error) {
+			if count > 0 && count%3 == 0 {
+				time.Sleep(120 * time.Millisecond)
+			}
+			count++
+			return count, nil
+		}))
+		
+		windows, err := Collect(Map(func(w Stream[int]) int {
+			items, _ := Collect(w)
+			return len(items)
+		})(TimeWindow[int](60 * time.Millisecond)(stream)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		if len(windows) != 3 || windows[0] != 3 || windows[1] != 3 || windows[2] != 3 {
+			t.Errorf("Expected 3 windows of 3 elements, got %v", windows)
+		}
+	})
+	
+	t.Run("StableGoroutines", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := Generate(func() (int64, error) {
+			time.Sleep(5 * time.Millisecond)
+			return 1, nil
+		})
+		windowed := TimeWindowContext[int64](ctx, 2*time.Millisecond)(stream)
+		
+		var during int
+		for i := 0; i < 10; i++ {
+			if _, err := windowed(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if i == 1 {
+				during = runtime.NumGoroutine()
+			}
+		}
+		if after := runtime.NumGoroutine(); after > during {
+			t.Errorf("Goroutines grew while windowing: %d -> %d", during, after)
+		}
+		
+		cancel()
+		if _, err := windowed(); err != context.Canceled {
+			t.Errorf("Expected context.Canceled after cancel, got %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Reader goroutine leaked after cancel: %d -> %d", before, after)
+		}
+	})
+}
+
+// TestSlidingTimeWindow tests the SlidingTimeWindow filter
+func TestSlidingTimeWindow(t *testing.T) {
+	t.Run("OverlappingWindows", func(t *testing.T) {
+		next := 0
+		stream := Limit[int](10)(Generate(func() (int, error) {
+			time.Sleep(20 * time.Millisecond)
+			next++
+			return next, nil
+		}))
+		
+		windowed := SlidingTimeWindow[int](60*time.Millisecond, 30*time.Millisecond)(stream)
+		windows, err := Collect(Map(func(w Stream[int]) []int {
+			items, _ := Collect(w)
+			return items
+		})(windowed))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		seen := make(map[int]int)
+		for _, window := range windows {
+			for k, v := range window {
+				seen[v]++
+				if k > 0 && v != window[k-1]+1 {
+					t.Errorf("Expected consecutive elements within a window, got %v", window)
+				}
+			}
+		}
+		overlapping := 0
+		for v := 1; v <= 10; v++ {
+			if seen[v] == 0 {
+				t.Errorf("Element %d missing from all windows: %v", v, windows)
+			}
+			if seen[v] > 1 {
+				overlapping++
+			}
+		}
+		if overlapping == 0 {
+			t.Errorf("Expected windows to overlap, got %v", windows)
+		}
+	})
+	
+	t.Run("StopsOnCancel", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := Generate(func() (int, error) {
+			time.Sleep(2 * time.Millisecond)
+			return 1, nil
+		})
+		windowed := SlidingTimeWindowContext[int](ctx, 10*time.Millisecond, 5*time.Millisecond)(stream)
+		for i := 0; i < 3; i++ {
+			if _, err := windowed(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		
+		cancel()
+		if _, err := windowed(); err != context.Canceled {
+			t.Errorf("Expected context.Canceled after cancel, got %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Reader goroutine leaked after cancel: %d -> %d", before, after)
+		}
+	})
+}
+
+// TestSessionGapWindow tests the SessionGapWindow filter
+func TestSessionGapWindow(t *testing.T) {
+	t.Run("Bursts", func(t *testing.T) {
+		// Bursts of 3, 2 and 4 lines separated by pauses longer than the gap
+		bursts := []int{3, 2, 4}
+		burst, inBurst := 0, 0
+		stream := Generate(func() (string, error) {
+			if burst == len(bursts) {
+				return "", EOS
+			}
+			if inBurst == bursts[burst] {
+				burst, inBurst = burst+1, 0
+				if burst == len(bursts) {
+					return "", EOS
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			inBurst++
+			return fmt.Sprintf("line %d.%d", burst, inBurst), nil
+		})
+		
+		sizes, err := Collect(Map(func(w Stream[string]) int {
+			items, _ := Collect(w)
+			return len(items)
+		})(SessionGapWindow[string](40 * time.Millisecond)(stream)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fmt.Sprint(sizes) != "[3 2 4]" {
+			t.Errorf("Expected sessions of [3 2 4], got %v", sizes)
+		}
+	})
+	
+	t.Run("InfiniteSourceCancel", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		count := 0
+		stream := Generate(func() (int, error) {
+			if count > 0 && count%5 == 0 {
+				time.Sleep(30 * time.Millisecond)
+			}
+			count++
+			return count, nil
+		})
+		windowed := SessionGapWindowContext[int](ctx, 10*time.Millisecond)(stream)
+		for i := 0; i < 2; i++ {
+			window, err := windowed()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if items, _ := Collect(window); len(items) != 5 {
+				t.Errorf("Expected sessions of 5, got %v", items)
+			}
+		}
+		
+		cancel()
+		if _, err := windowed(); err != context.Canceled {
+			t.Errorf("Expected context.Canceled after cancel, got %v", err)
+		}
+		time.Sleep(60 * time.Millisecond)
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Reader goroutine leaked after cancel: %d -> %d", before, after)
+		}
+	})
 }
 
 // TestSlidingCountWindow tests the SlidingCountWindow filter
@@ -371,9 +700,9 @@ func TestNewValueChangeTrigger(t *testing.T) {
 			return len(s)
 		})
 		
-		// First item should trigger (no previous value)
-		if !trigger.ShouldFire("hello", nil) {
-			t.Errorf("Expected trigger to fire on first item")
+		// First item only starts the window (no previous value)
+		if trigger.ShouldFire("hello", nil) {
+			t.Errorf("Expected trigger not to fire on first item")
 		}
 		
 		// Same length should not trigger
@@ -382,10 +711,251 @@ func TestNewValueChangeTrigger(t *testing.T) {
 			t.Errorf("Expected trigger not to fire on same length")
 		}
 		
-		// Different length should trigger
+		// Different length should trigger, holding the element back for the next window
 		if !trigger.ShouldFire("hi", state) {
 			t.Errorf("Expected trigger to fire on different length")
 		}
+		if trigger.FireMode(state) != FireExcluding {
+			t.Errorf("Expected the changed element to be excluded by default")
+		}
+	})
+
+	t.Run("IncludingFiresOnFirstItem", func(t *testing.T) {
+		trigger := NewValueChangeTrigger(func(s string) any { return len(s) })
+		trigger.Mode = FireIncluding
+		if !trigger.ShouldFire("hello", nil) {
+			t.Errorf("Expected trigger to fire on first item")
+		}
+	})
+
+	t.Run("SortedRecordsByDay", func(t *testing.T) {
+		records := []Record{
+			{"day": "2024-01-01", "id": "a"},
+			{"day": "2024-01-01", "id": "b"},
+			{"day": "2024-01-02", "id": "c"},
+			{"day": "2024-01-03", "id": "d"},
+			{"day": "2024-01-03", "id": "e"},
+			{"day": "2024-01-03", "id": "f"},
+		}
+		members := func(trigger Trigger[Record]) []string {
+			windows, err := Collect(TriggeredWindow(trigger)(FromRecordsUnsafe(records)))
+			if err != nil {
+				t.Fatalf("Failed to collect windows: %v", err)
+			}
+			var result []string
+			for _, window := range windows {
+				var ids []string
+				windowRecords, _ := Collect(window)
+				for _, r := range windowRecords {
+					ids = append(ids, GetOr(r, "day", "")+":"+GetOr(r, "id", ""))
+				}
+				result = append(result, strings.Join(ids, " "))
+			}
+			return result
+		}
+		byDay := func(r Record) any { return GetOr(r, "day", "") }
+
+		expected := []string{
+			"2024-01-01:a 2024-01-01:b",
+			"2024-01-02:c",
+			"2024-01-03:d 2024-01-03:e 2024-01-03:f",
+		}
+		if got := members(NewValueChangeTrigger(byDay)); fmt.Sprintf("%q", got) != fmt.Sprintf("%q", expected) {
+			t.Errorf("Expected windows %q, got %q", expected, got)
+		}
+
+		// Including mode keeps the old behaviour: the changed element closes the previous window
+		including := NewValueChangeTrigger(byDay)
+		including.Mode = FireIncluding
+		expected = []string{
+			"2024-01-01:a",
+			"2024-01-01:b 2024-01-02:c",
+			"2024-01-03:d",
+			"2024-01-03:e 2024-01-03:f",
+		}
+		if got := members(including); fmt.Sprintf("%q", got) != fmt.Sprintf("%q", expected) {
+			t.Errorf("Expected windows %q, got %q", expected, got)
+		}
+
+		// Combined with a count trigger, a window also closes after 2 records, but a
+		// change of day still starts a new window when both fire on the same record
+		combined := NewAnyTrigger[Record](NewValueChangeTrigger(byDay), NewCountTrigger[Record](2))
+		expected = []string{
+			"2024-01-01:a 2024-01-01:b",
+			"2024-01-02:c",
+			"2024-01-03:d 2024-01-03:e",
+			"2024-01-03:f",
+		}
+		if got := members(combined); fmt.Sprintf("%q", got) != fmt.Sprintf("%q", expected) {
+			t.Errorf("Expected windows %q, got %q", expected, got)
+		}
+	})
+}
+
+// TestNewTimeTrigger tests the NewTimeTrigger function
+func TestNewTimeTrigger(t *testing.T) {
+	t.Run("FiresAfterDuration", func(t *testing.T) {
+		trigger := NewTimeTrigger[int64](20 * time.Millisecond)
+		state := trigger.ResetState()
+		if trigger.ShouldFire(1, state) {
+			t.Errorf("Expected trigger not to fire before the duration")
+		}
+		if deadline := trigger.Deadline(state); deadline.Sub(state.(time.Time)) != 20*time.Millisecond {
+			t.Errorf("Expected deadline 20ms after the window opened, got %v", deadline)
+		}
+		time.Sleep(25 * time.Millisecond)
+		if !trigger.ShouldFire(2, state) {
+			t.Errorf("Expected trigger to fire after the duration")
+		}
+	})
+}
+
+// TestNewPredicateTrigger tests the NewPredicateTrigger function
+func TestNewPredicateTrigger(t *testing.T) {
+	t.Run("EndOfBatchMarker", func(t *testing.T) {
+		records := []Record{
+			{"id": int64(1)}, {"id": int64(2)}, {"end": true},
+			{"id": int64(3)}, {"end": true},
+			{"id": int64(4)},
+		}
+		trigger := NewPredicateTrigger(func(r Record) bool { return GetOr(r, "end", false) })
+		windows, err := Collect(TriggeredWindow[Record](trigger)(FromRecordsUnsafe(records)))
+		if err != nil {
+			t.Fatalf("Failed to collect windows: %v", err)
+		}
+		var sizes []int64
+		for _, window := range windows {
+			n, _ := Count(window)
+			sizes = append(sizes, n)
+		}
+		if fmt.Sprint(sizes) != "[3 2 1]" {
+			t.Errorf("Expected windows of 3, 2 and 1 records, got %v", sizes)
+		}
+	})
+}
+
+// TestNewAnyTrigger tests the NewAnyTrigger function
+func TestNewAnyTrigger(t *testing.T) {
+	t.Run("CountOrTimeWithSlowSource", func(t *testing.T) {
+		// Three fast elements, then slow ones the time trigger must cut off
+		var next int64
+		source := Generate(func() (int64, error) {
+			next++
+			if next > 8 {
+				return 0, EOS
+			}
+			if next > 3 {
+				time.Sleep(30 * time.Millisecond)
+			}
+			return next, nil
+		})
+
+		trigger := NewAnyTrigger[int64](NewCountTrigger[int64](3), NewTimeTrigger[int64](50*time.Millisecond))
+		windowed := TriggeredWindow[int64](trigger)(source)
+
+		var all []int64
+		var sizes []int
+		for {
+			window, err := windowed()
+			if err == EOS {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			items, _ := Collect(window)
+			all = append(all, items...)
+			sizes = append(sizes, len(items))
+		}
+
+		if fmt.Sprint(all) != "[1 2 3 4 5 6 7 8]" {
+			t.Errorf("Expected every element once in order, got %v", all)
+		}
+		if len(sizes) == 0 || sizes[0] != 3 {
+			t.Errorf("Expected the count trigger to fire the first window, got sizes %v", sizes)
+		}
+		timedOut := false
+		for _, size := range sizes[1:] {
+			if size > 3 {
+				t.Errorf("Expected no window over 3 elements, got sizes %v", sizes)
+			}
+			if size < 3 {
+				timedOut = true
+			}
+		}
+		if !timedOut {
+			t.Errorf("Expected the time trigger to fire a short window, got sizes %v", sizes)
+		}
+	})
+
+	t.Run("EmptyWindows", func(t *testing.T) {
+		ch := make(chan int64)
+		source := FromChannel(ch)
+		go func() {
+			time.Sleep(70 * time.Millisecond)
+			ch <- 1
+			close(ch)
+		}()
+
+		trigger := NewAnyTrigger[int64](NewCountTrigger[int64](2), NewTimeTrigger[int64](20*time.Millisecond))
+		windowed := TriggeredWindow[int64](trigger, WithEmptyWindows())(source)
+		window, err := windowed()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n, _ := Count(window); n != 0 {
+			t.Errorf("Expected an empty window before the first element, got %d elements", n)
+		}
+
+		// Without the option empty windows are skipped
+		ch2 := make(chan int64)
+		go func() {
+			time.Sleep(70 * time.Millisecond)
+			ch2 <- 1
+			close(ch2)
+		}()
+		windows, err := Collect(TriggeredWindow[int64](trigger)(FromChannel(ch2)))
+		if err != nil || len(windows) != 1 {
+			t.Fatalf("Expected one window, got %d (%v)", len(windows), err)
+		}
+	})
+}
+
+// TestNewAllTrigger tests the NewAllTrigger function
+func TestNewAllTrigger(t *testing.T) {
+	t.Run("CountAndPredicate", func(t *testing.T) {
+		// Fire once at least 3 elements have arrived and a multiple of 5 has been seen
+		trigger := NewAllTrigger[int64](
+			NewCountTrigger[int64](3),
+			NewPredicateTrigger(func(x int64) bool { return x%5 == 0 }),
+		)
+		windows, err := Collect(TriggeredWindow[int64](trigger)(FromSlice([]int64{5, 6, 7, 8, 9, 10, 11})))
+		if err != nil {
+			t.Fatalf("Failed to collect windows: %v", err)
+		}
+		var sizes []int64
+		for _, window := range windows {
+			n, _ := Count(window)
+			sizes = append(sizes, n)
+		}
+		// [5 6 7] has 3 elements after seeing 5; [8 9 10] sees 10 on its third; [11] is partial
+		if fmt.Sprint(sizes) != "[3 3 1]" {
+			t.Errorf("Expected windows of 3, 3 and 1, got %v", sizes)
+		}
+	})
+
+	t.Run("DeadlineWaitsForUntimedTriggers", func(t *testing.T) {
+		trigger := NewAllTrigger[int64](NewCountTrigger[int64](2), NewTimeTrigger[int64](time.Millisecond))
+		state := trigger.ResetState()
+		if !trigger.Deadline(state).IsZero() {
+			t.Errorf("Expected no deadline before the count trigger fires")
+		}
+		trigger.ShouldFire(1, state)
+		trigger.ShouldFire(2, state)
+		time.Sleep(2 * time.Millisecond)
+		if !trigger.ShouldFire(3, state) {
+			t.Errorf("Expected the trigger to fire once both have fired")
+		}
 	})
 }
 
@@ -436,6 +1006,59 @@ func TestTriggeredWindow(t *testing.T) {
 	})
 }
 
+// TestWindowsLatchEOS tests that windows never pull their input again after it ends
+func TestWindowsLatchEOS(t *testing.T) {
+	// oneShot is a generator that must not be called again once it has returned EOS
+	oneShot := func(n int64) Stream[int64] {
+		var next int64
+		ended := false
+		return Generate(func() (int64, error) {
+			if ended {
+				panic("generator called after EOS")
+			}
+			if next >= n {
+				ended = true
+				return 0, EOS
+			}
+			next++
+			return next, nil
+		})
+	}
+
+	windows := map[string]Filter[int64, Stream[int64]]{
+		"CountWindow":        CountWindow[int64](3),
+		"TriggeredWindow":    TriggeredWindow[int64](NewCountTrigger[int64](3)),
+		"SlidingCountWindow": SlidingCountWindow[int64](3, 2),
+	}
+	for name, window := range windows {
+		t.Run(name, func(t *testing.T) {
+			windowed := window(oneShot(7))
+			windowCount := 0
+			for {
+				w, err := windowed()
+				if err == EOS {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if _, err := Collect(w); err != nil {
+					t.Fatalf("Failed to collect window: %v", err)
+				}
+				windowCount++
+			}
+			if windowCount == 0 {
+				t.Fatal("Expected at least one window")
+			}
+			for i := 0; i < 3; i++ {
+				if _, err := windowed(); err != EOS {
+					t.Errorf("Expected EOS after the end of the input, got %v", err)
+				}
+			}
+		})
+	}
+}
+
 // TestStreamingGroupBy tests the StreamingGroupBy function
 func TestStreamingGroupBy(t *testing.T) {
 	t.Run("GroupByCategory", func(t *testing.T) {
@@ -508,6 +1131,196 @@ func TestParallel(t *testing.T) {
 	})
 }
 
+// TestParallelOrdered tests order-preserving parallel processing
+func TestParallelOrdered(t *testing.T) {
+	t.Run("ExactOrder", func(t *testing.T) {
+		const n = 10_000
+		delays := make([]time.Duration, n)
+		rng := rand.New(rand.NewSource(1))
+		for i := range delays {
+			delays[i] = time.Duration(rng.Intn(50)) * time.Microsecond
+		}
+
+		doubled := ParallelOrdered(8, func(x int64) int64 {
+			time.Sleep(delays[x])
+			return x * 2
+		})(Range(0, n, 1))
+
+		results, err := Collect(doubled)
+		if err != nil {
+			t.Fatalf("Failed to collect parallel results: %v", err)
+		}
+		if len(results) != n {
+			t.Fatalf("Expected %d results, got %d", n, len(results))
+		}
+		for i, result := range results {
+			if result != int64(i)*2 {
+				t.Fatalf("Expected %d at index %d, got %d", i*2, i, result)
+			}
+		}
+	})
+
+	t.Run("BoundedReorderBuffer", func(t *testing.T) {
+		var pulled atomic.Int64
+		source := Generate(func() (int64, error) { return pulled.Add(1) - 1, nil })
+
+		// The first item is slow, so everything after it waits to be reordered
+		ordered := ParallelOrdered(2, func(x int64) int64 {
+			if x == 0 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			return x
+		})(source)
+
+		if first, err := ordered(); err != nil || first != 0 {
+			t.Fatalf("Expected 0 first, got %d (%v)", first, err)
+		}
+		if n := pulled.Load(); n > 2*orderedWindowPerWorker+1 {
+			t.Errorf("Expected at most %d items pulled while blocked, got %d", 2*orderedWindowPerWorker+1, n)
+		}
+	})
+
+	t.Run("PropagatesInputError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		calls := int64(0)
+		source := func() (int64, error) {
+			calls++
+			if calls > 3 {
+				return 0, failure
+			}
+			return calls, nil
+		}
+
+		results, err := Collect(ParallelOrdered(2, func(x int64) int64 { return x })(source))
+		if err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+		if len(results) != 3 || results[0] != 1 || results[2] != 3 {
+			t.Errorf("Expected results before the error in order, got %v", results)
+		}
+	})
+
+	t.Run("AutoParallelMapKeepsOrder", func(t *testing.T) {
+		data := make([]int64, 1000)
+		for i := range data {
+			data[i] = int64(i)
+		}
+
+		// The path Map takes for complex functions
+		results, err := Collect(autoParallelMap(func(x int64) int64 {
+			time.Sleep(time.Duration(x%7) * time.Microsecond)
+			return x
+		}, FromSlice(data), 5))
+		if err != nil {
+			t.Fatalf("Failed to collect: %v", err)
+		}
+		for i, result := range results {
+			if result != int64(i) {
+				t.Fatalf("Expected %d at index %d, got %d", i, i, result)
+			}
+		}
+	})
+}
+
+// TestParallelWithError tests error reporting and panic recovery in parallel filters
+func TestParallelWithError(t *testing.T) {
+	// collectWithin fails the test if the stream hangs instead of ending
+	collectWithin := func(t *testing.T, stream Stream[int64]) ([]int64, error) {
+		t.Helper()
+		type outcome struct {
+			results []int64
+			err     error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			results, err := Collect(stream)
+			done <- outcome{results, err}
+		}()
+		select {
+		case o := <-done:
+			return o.results, o.err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Stream hung instead of terminating")
+			return nil, nil
+		}
+	}
+
+	t.Run("FnError", func(t *testing.T) {
+		failure := fmt.Errorf("bad record")
+		stream := ParallelWithError(4, func(x int64) (int64, error) {
+			if x == 50 {
+				return 0, failure
+			}
+			return x, nil
+		})(Range(0, 1000, 1))
+
+		_, err := collectWithin(t, stream)
+		if err != failure {
+			t.Fatalf("Expected fn error, got %v", err)
+		}
+		if _, err := stream(); err != failure {
+			t.Errorf("Expected sticky fn error, got %v", err)
+		}
+	})
+
+	t.Run("PanicInParallel", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		stream := Parallel(4, func(x int64) int64 {
+			if x == 13 {
+				panic("unlucky")
+			}
+			return x
+		})(Range(0, 1000, 1))
+
+		_, err := collectWithin(t, stream)
+		if err == nil || !strings.Contains(err.Error(), "panicked on item 13") || !strings.Contains(err.Error(), "unlucky") {
+			t.Fatalf("Expected descriptive panic error, got %v", err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Potential goroutine leak after panic: %d -> %d", before, after)
+		}
+	})
+
+	t.Run("PanicInParallelOrdered", func(t *testing.T) {
+		stream := ParallelOrdered(4, func(x int64) int64 {
+			if x == 13 {
+				panic("unlucky")
+			}
+			return x
+		})(Range(0, 1000, 1))
+
+		results, err := collectWithin(t, stream)
+		if err == nil || !strings.Contains(err.Error(), "panicked on item 13") {
+			t.Fatalf("Expected descriptive panic error, got %v", err)
+		}
+		if len(results) != 13 || results[12] != 12 {
+			t.Errorf("Expected the 13 results before the panic, got %v", results)
+		}
+	})
+
+	t.Run("InputError", func(t *testing.T) {
+		failure := fmt.Errorf("source failed")
+		calls := int64(0)
+		source := func() (int64, error) {
+			calls++
+			if calls > 5 {
+				return 0, failure
+			}
+			return calls, nil
+		}
+
+		results, err := collectWithin(t, Parallel(2, func(x int64) int64 { return x })(source))
+		if err != failure {
+			t.Errorf("Expected source error, got %v", err)
+		}
+		if len(results) != 5 {
+			t.Errorf("Expected the 5 results before the error, got %v", results)
+		}
+	})
+}
+
 // TestSplit tests the Split filter
 func TestSplit(t *testing.T) {
 	t.Run("SplitByKey", func(t *testing.T) {
@@ -548,6 +1361,104 @@ func TestSplit(t *testing.T) {
 			}
 		}
 	})
+
+	// 10 groups x 1000 records, interleaved so every group is live until the input ends
+	interleaved := func() Stream[Record] {
+		records := make([]Record, 0, 10000)
+		for i := 0; i < 1000; i++ {
+			for g := 0; g < 10; g++ {
+				records = append(records, Record{"group": int64(g), "seq": int64(i)})
+			}
+		}
+		return FromRecordsUnsafe(records)
+	}
+
+	t.Run("SequentialReadNoLoss", func(t *testing.T) {
+		groups := Split([]string{"group"})(interleaved())
+
+		count := 0
+		for {
+			substream, err := groups()
+			if err == EOS {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			records, err := Collect(substream)
+			if err != nil {
+				t.Fatalf("Failed to collect substream %d: %v", count, err)
+			}
+			if len(records) != 1000 || records[999]["seq"] != int64(999) {
+				t.Errorf("Group %d: expected 1000 records in order, got %d", count, len(records))
+			}
+			count++
+		}
+		if count != 10 {
+			t.Errorf("Expected 10 groups, got %d", count)
+		}
+	})
+
+	t.Run("BoundedBlockConcurrentReaders", func(t *testing.T) {
+		groups := SplitWithOptions([]string{"group"}, WithSplitBuffer(16, SplitBlock))(interleaved())
+
+		var wg sync.WaitGroup
+		counts := make(chan int, 10)
+		for {
+			substream, err := groups()
+			if err != nil {
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				records, _ := Collect(substream)
+				counts <- len(records)
+			}()
+		}
+		wg.Wait()
+		close(counts)
+
+		total := 0
+		for n := range counts {
+			total += n
+		}
+		if total != 10000 {
+			t.Errorf("Expected 10000 records across groups, got %d", total)
+		}
+	})
+
+	t.Run("BoundedBlockTimeout", func(t *testing.T) {
+		groups := SplitWithOptions([]string{"group"}, WithSplitBuffer(16, SplitBlock), WithSplitTimeout(20*time.Millisecond))(interleaved())
+
+		first, _ := groups()
+		_, err := Collect(first)
+		if err != ErrSplitTimeout {
+			t.Errorf("Expected ErrSplitTimeout reading one group while others are full, got %v", err)
+		}
+	})
+
+	t.Run("DropAndFailPolicies", func(t *testing.T) {
+		dropped := 0
+		groups := SplitWithOptions([]string{"group"}, WithSplitBuffer(100, SplitDrop),
+			WithSplitDropHandler(func(Record) { dropped++ }))(interleaved())
+		first, _ := groups()
+		records, err := Collect(first)
+		if err != nil || len(records) != 1000 {
+			t.Errorf("Expected the group being read to be complete, got %d records (%v)", len(records), err)
+		}
+		if dropped != 9*900 {
+			t.Errorf("Expected %d dropped records, got %d", 9*900, dropped)
+		}
+
+		groups = SplitWithOptions([]string{"group"}, WithSplitBuffer(100, SplitFailGroup))(interleaved())
+		first, _ = groups()
+		Collect(first)
+		second, _ := groups()
+		if _, err := second(); err != ErrSplitOverflow {
+			t.Errorf("Expected ErrSplitOverflow from an overflowed group, got %v", err)
+		}
+	})
 }
 
 // Test timeout scenario for context cancellation
@@ -575,4 +1486,73 @@ func TestContextTimeout(t *testing.T) {
 			t.Errorf("Expected quick termination due to context, took %v", elapsed)
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestStreamingReduce tests the StreamingReduce filter
+func TestStreamingReduce(t *testing.T) {
+	input := []string{"a", "b", "c"}
+	running := StreamingReduce(0, func(acc int, s string) int { return acc + len(s) })(FromSlice(input))
+
+	expected := []int{1, 2, 3}
+	for i, want := range expected {
+		got, err := running()
+		if err != nil {
+			t.Fatalf("Unexpected error at %d: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("Expected %d at position %d, got %d", want, i, got)
+		}
+	}
+
+	// Final accumulator is emitted alongside EOS
+	final, err := running()
+	if err != EOS {
+		t.Fatalf("Expected EOS, got %v", err)
+	}
+	if final != 3 {
+		t.Errorf("Expected final accumulator 3 with EOS, got %d", final)
+	}
+}
+
+// TestStreamingPercentile tests the approximate P-squared percentile
+func TestStreamingPercentile(t *testing.T) {
+	t.Run("UniformDistribution", func(t *testing.T) {
+		// Deterministic permutation of 1..10000
+		values := make([]int64, 10000)
+		for i := range values {
+			values[i] = int64((i*7919)%10000 + 1)
+		}
+
+		for _, p := range []float64{50, 95, 99} {
+			estimates, err := Collect(StreamingPercentile[int64](p)(FromSlice(values)))
+			if err != nil {
+				t.Fatalf("StreamingPercentile failed: %v", err)
+			}
+			final := estimates[len(estimates)-1]
+			expected := p / 100 * 10000
+			if math.Abs(final-expected)/expected > 0.01 {
+				t.Errorf("P%v: expected about %v, got %v", p, expected, final)
+			}
+		}
+	})
+
+	t.Run("ExactForFewElements", func(t *testing.T) {
+		estimates, err := Collect(StreamingPercentile[int64](50)(FromSlice([]int64{10, 30, 20})))
+		if err != nil {
+			t.Fatalf("StreamingPercentile failed: %v", err)
+		}
+		expected := []float64{10, 20, 20}
+		for i := range expected {
+			if estimates[i] != expected[i] {
+				t.Errorf("Expected %v at position %d, got %v", expected[i], i, estimates[i])
+			}
+		}
+	})
+
+	t.Run("EmptyStream", func(t *testing.T) {
+		result, err := StreamingPercentile[int64](95)(FromSlice([]int64{}))()
+		if err != EOS || result != 0 {
+			t.Errorf("Expected 0 with EOS, got %v, %v", result, err)
+		}
+	})
+}
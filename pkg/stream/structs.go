@@ -0,0 +1,296 @@
+package stream
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// STRUCT MAPPING - CONVERT BETWEEN DOMAIN STRUCTS AND RECORDS
+// ============================================================================
+
+// FromStructs creates a Record stream from a slice of structs (or pointers to structs).
+// Exported fields become record fields named by their `stream:"name"` tag, then their
+// `json:"name"` tag, then the Go field name; a tag of "-" skips the field.
+// Nested structs become nested Records, slices become Stream[any] fields, embedded
+// structs have their fields promoted, and time.Time values are kept as-is.
+func FromStructs[T any](items []T) Stream[Record] {
+	return FromStructStream(fromSliceImpl(items))
+}
+
+// FromStructStream converts a stream of structs to Records (see FromStructs)
+func FromStructStream[T any](input Stream[T]) Stream[Record] {
+	return func() (Record, error) {
+		item, err := input()
+		if err != nil {
+			return nil, err
+		}
+		return structToRecord(reflect.ValueOf(item)), nil
+	}
+}
+
+// ToStructs collects a Record stream into a slice of structs, the inverse of FromStructs.
+// Fields are converted like Get (e.g. "42" fills an int field); missing fields keep their
+// zero value. An unconvertible value fails with an error naming the record and field.
+func ToStructs[T any](stream Stream[Record]) ([]T, error) {
+	return Collect(MapToStruct[T]()(stream))
+}
+
+// MapToStruct converts each Record to a struct of type T (see ToStructs).
+// The first unconvertible value ends the stream with an error naming the record and field.
+func MapToStruct[T any]() Filter[Record, T] {
+	return func(input Stream[Record]) Stream[T] {
+		index := 0
+		var finalErr error // Sticky once a conversion fails
+		return func() (T, error) {
+			var zero T
+			if finalErr != nil {
+				return zero, finalErr
+			}
+			record, err := input()
+			if err != nil {
+				return zero, err
+			}
+
+			var result T
+			if err := recordToStruct(record, reflect.ValueOf(&result).Elem(), ""); err != nil {
+				finalErr = fmt.Errorf("record %d: %w", index, err)
+				return zero, finalErr
+			}
+			index++
+			return result, nil
+		}
+	}
+}
+
+// structField describes how one struct field maps to a record field
+type structField struct {
+	name     string
+	index    int
+	embedded bool // Anonymous struct field whose fields are promoted
+}
+
+var structFieldCache sync.Map // reflect.Type → []structField
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structFieldsOf returns the record mapping for the fields of struct type t
+func structFieldsOf(t reflect.Type) []structField {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.([]structField)
+	}
+
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("stream")
+		if name == "" {
+			name, _, _ = strings.Cut(f.Tag.Get("json"), ",")
+		}
+		if name == "-" {
+			continue
+		}
+
+		// Untagged embedded structs are promoted; unexported ones only when not behind a pointer
+		ft := f.Type
+		if f.Anonymous && name == "" && ft != timeType {
+			if ft.Kind() == reflect.Struct || (ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct && f.IsExported()) {
+				fields = append(fields, structField{index: i, embedded: true})
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{name: name, index: i})
+	}
+
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+// structToRecord converts a struct value to a Record; rv may be a pointer to a struct
+func structToRecord(rv reflect.Value) Record {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return Record{}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return Record{}
+	}
+
+	result := make(Record)
+	for _, field := range structFieldsOf(rv.Type()) {
+		fv := rv.Field(field.index)
+		if field.embedded {
+			// Promoted fields never override the outer struct's own fields
+			for k, v := range structToRecord(fv) {
+				if _, exists := result[k]; !exists {
+					result[k] = v
+				}
+			}
+			continue
+		}
+		result[field.name] = structValueToField(fv)
+	}
+	return result
+}
+
+// structValueToField converts one struct field value to its record representation
+func structValueToField(fv reflect.Value) any {
+	switch fv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if fv.IsNil() {
+			return nil
+		}
+		return structValueToField(fv.Elem())
+	case reflect.Struct:
+		if fv.Type() == timeType {
+			return fv.Interface()
+		}
+		return structToRecord(fv)
+	case reflect.Slice, reflect.Array:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return fv.Interface() // []byte stays a value
+		}
+		items := make([]any, fv.Len())
+		for i := range items {
+			items[i] = structValueToField(fv.Index(i))
+		}
+		return FromSliceAny(items)
+	default:
+		return fv.Interface()
+	}
+}
+
+// recordToStruct fills the struct rv from record; path prefixes field names in errors
+func recordToStruct(record Record, rv reflect.Value, path string) error {
+	for _, field := range structFieldsOf(rv.Type()) {
+		fv := rv.Field(field.index)
+		if field.embedded {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if err := recordToStruct(record, fv, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, exists := record[field.name]
+		if !exists || value == nil {
+			continue
+		}
+		if err := setStructField(fv, value, path+field.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setStructField converts value to the type of fv and stores it
+func setStructField(fv reflect.Value, value any, path string) error {
+	ft := fv.Type()
+	fail := func() error {
+		return fmt.Errorf("field %q: cannot convert %T %v to %s", path, value, value, ft)
+	}
+
+	// Values that already fit need no conversion
+	if vt := reflect.TypeOf(value); vt.AssignableTo(ft) {
+		fv.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	switch ft.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(ft.Elem())
+		if err := setStructField(elem.Elem(), value, path); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case reflect.Struct:
+		if ft == timeType {
+			t, ok := convertToTime(value)
+			if !ok {
+				return fail()
+			}
+			fv.Set(reflect.ValueOf(t))
+			return nil
+		}
+		var nested Record
+		switch v := value.(type) {
+		case Record:
+			nested = v
+		case map[string]any:
+			nested = Record(v)
+		default:
+			return fail()
+		}
+		return recordToStruct(nested, fv, path+".")
+	case reflect.Slice:
+		values, ok := streamFieldValues(value)
+		if !ok {
+			return fail()
+		}
+		slice := reflect.MakeSlice(ft, len(values), len(values))
+		for i, item := range values {
+			if item == nil {
+				continue
+			}
+			if err := setStructField(slice.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := convertToInt64(value)
+		if !ok || fv.OverflowInt(n) {
+			return fail()
+		}
+		fv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := convertToInt64(value)
+		if !ok || n < 0 || fv.OverflowUint(uint64(n)) {
+			return fail()
+		}
+		fv.SetUint(uint64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := convertToFloat64(value)
+		if !ok {
+			return fail()
+		}
+		fv.SetFloat(f)
+		return nil
+	case reflect.String:
+		s, ok := convertToString(value)
+		if !ok {
+			return fail()
+		}
+		fv.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, ok := convertToBool(value)
+		if !ok {
+			return fail()
+		}
+		fv.SetBool(b)
+		return nil
+	default:
+		return fail()
+	}
+}
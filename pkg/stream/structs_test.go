@@ -0,0 +1,187 @@
+package stream
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAudit struct {
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testAddress struct {
+	City string `stream:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type testCustomer struct {
+	testAudit
+	ID      int64       `stream:"id"`
+	Name    string      `json:"name"`
+	Address testAddress `stream:"address"`
+	Tags    []string    `stream:"tags"`
+	Score   *float64    `stream:"score"`
+	Secret  string      `stream:"-"`
+	note    string
+}
+
+// TestFromStructs tests struct to Record conversion
+func TestFromStructs(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	score := 9.5
+	customers := []testCustomer{{
+		testAudit: testAudit{CreatedBy: "admin", CreatedAt: created},
+		ID:        1,
+		Name:      "Alice",
+		Address:   testAddress{City: "NYC", Zip: "10001"},
+		Tags:      []string{"vip", "new"},
+		Score:     &score,
+		Secret:    "hidden",
+		note:      "private",
+	}}
+
+	results, err := Collect(FromStructs(customers))
+	if err != nil {
+		t.Fatalf("Failed to collect records: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(results))
+	}
+	record := results[0]
+
+	t.Run("TagRenaming", func(t *testing.T) {
+		if record["id"] != int64(1) || record["name"] != "Alice" {
+			t.Errorf("Expected tagged names id and name, got %v", record)
+		}
+		for _, field := range []string{"Secret", "note", "ID", "Name"} {
+			if _, exists := record[field]; exists {
+				t.Errorf("Expected field %s to be absent", field)
+			}
+		}
+	})
+
+	t.Run("EmbeddedAndTime", func(t *testing.T) {
+		if record["created_by"] != "admin" {
+			t.Errorf("Expected promoted created_by=admin, got %v", record["created_by"])
+		}
+		if record["created_at"] != created {
+			t.Errorf("Expected created_at kept as time.Time, got %T %v", record["created_at"], record["created_at"])
+		}
+	})
+
+	t.Run("NestedAndSlices", func(t *testing.T) {
+		address, ok := record["address"].(Record)
+		if !ok || address["city"] != "NYC" || address["zip"] != "10001" {
+			t.Errorf("Expected nested address Record, got %v", record["address"])
+		}
+		tags, ok := record["tags"].(Stream[any])
+		if !ok {
+			t.Fatalf("Expected tags to be a Stream[any], got %T", record["tags"])
+		}
+		if values, _ := Collect(tags); len(values) != 2 || values[0] != "vip" {
+			t.Errorf("Expected [vip new], got %v", values)
+		}
+		if record["score"] != 9.5 {
+			t.Errorf("Expected pointer dereferenced to 9.5, got %v", record["score"])
+		}
+	})
+
+	t.Run("FromStructStream", func(t *testing.T) {
+		results, _ := Collect(FromStructStream(FromSliceAny([]*testAddress{{City: "LA"}, nil})))
+		if len(results) != 2 || results[0]["city"] != "LA" || len(results[1]) != 0 {
+			t.Errorf("Expected LA record then empty record, got %v", results)
+		}
+	})
+}
+
+// TestToStructs tests Record to struct conversion
+func TestToStructs(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		score := 1.5
+		original := []testCustomer{{
+			testAudit: testAudit{CreatedBy: "admin", CreatedAt: created},
+			ID:        7,
+			Name:      "Bob",
+			Address:   testAddress{City: "SF"},
+			Tags:      []string{"a", "b"},
+			Score:     &score,
+		}}
+
+		results, err := ToStructs[testCustomer](FromStructs(original))
+		if err != nil {
+			t.Fatalf("Failed to convert records: %v", err)
+		}
+		got := results[0]
+		if got.ID != 7 || got.Name != "Bob" || got.Address.City != "SF" || got.CreatedBy != "admin" {
+			t.Errorf("Expected fields restored, got %+v", got)
+		}
+		if !got.CreatedAt.Equal(created) {
+			t.Errorf("Expected created_at %v, got %v", created, got.CreatedAt)
+		}
+		if len(got.Tags) != 2 || got.Tags[1] != "b" {
+			t.Errorf("Expected tags [a b], got %v", got.Tags)
+		}
+		if got.Score == nil || *got.Score != 1.5 {
+			t.Errorf("Expected score 1.5, got %v", got.Score)
+		}
+	})
+
+	t.Run("GetStyleConversion", func(t *testing.T) {
+		records := []Record{{
+			"id":         "42",
+			"name":       "Carol",
+			"address":    map[string]any{"city": "Austin"},
+			"created_at": "2024-05-06T07:08:09Z",
+			"tags":       []any{"x"},
+		}}
+
+		results, err := ToStructs[testCustomer](FromRecordsUnsafe(records))
+		if err != nil {
+			t.Fatalf("Failed to convert records: %v", err)
+		}
+		got := results[0]
+		if got.ID != 42 || got.Address.City != "Austin" || got.CreatedAt.Year() != 2024 || got.Tags[0] != "x" {
+			t.Errorf("Expected converted fields, got %+v", got)
+		}
+	})
+
+	t.Run("UnconvertibleValue", func(t *testing.T) {
+		records := []Record{
+			{"id": int64(1)},
+			{"id": int64(2), "address": Record{"city": "Boston"}, "created_at": "not a time"},
+		}
+
+		_, err := ToStructs[testCustomer](FromRecordsUnsafe(records))
+		if err == nil {
+			t.Fatal("Expected conversion error")
+		}
+		if !strings.Contains(err.Error(), "record 1") || !strings.Contains(err.Error(), `"created_at"`) {
+			t.Errorf("Expected error naming record 1 and created_at, got %v", err)
+		}
+
+		_, err = ToStructs[testCustomer](FromRecordsUnsafe([]Record{{"address": Record{"city": Record{}}, "id": "abc"}}))
+		if err == nil || !strings.Contains(err.Error(), `"id"`) {
+			t.Errorf("Expected error naming id, got %v", err)
+		}
+	})
+
+	t.Run("NestedFieldPath", func(t *testing.T) {
+		type order struct {
+			Customer struct {
+				Age int `stream:"age"`
+			} `stream:"customer"`
+		}
+
+		stream := MapToStruct[order]()(FromRecordsUnsafe([]Record{{"customer": Record{"age": "old"}}}))
+		_, err := stream()
+		if err == nil || !strings.Contains(err.Error(), `"customer.age"`) {
+			t.Errorf("Expected error naming customer.age, got %v", err)
+		}
+		if _, again := stream(); again != err {
+			t.Errorf("Expected error to be sticky, got %v", again)
+		}
+	})
+}
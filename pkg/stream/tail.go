@@ -0,0 +1,329 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ============================================================================
+// FILE TAILING - FOLLOW A GROWING FILE LIKE tail -f
+// ============================================================================
+
+// TailFormat specifies how NewTailSource turns lines into Records
+type TailFormat int
+
+const (
+	TailLines TailFormat = iota // Each line becomes a Record with the text in TailLineField
+	TailJSON                    // Each line is a JSON object, decoded like JSONSource; blank lines are skipped
+)
+
+// Fields NewTailSource adds to every record, so a consumer can checkpoint its position
+const (
+	TailLineField       = "line"        // string: the line's text, without the newline (TailLines only)
+	TailOffsetField     = "tail_offset" // int64: byte offset just past the line; resume from it with WithTailPosition
+	TailLineNumberField = "tail_line"   // int64: line number in the current file, from 1
+)
+
+// TailOption configures NewTailSource
+type TailOption func(*tailConfig)
+
+// tailConfig holds NewTailSource configuration
+type tailConfig struct {
+	ctx          context.Context
+	format       TailFormat
+	pollInterval time.Duration
+	startAtEnd   bool
+	offset       int64
+	lineNumber   int64
+	reopen       bool
+}
+
+// WithTailFormat sets how lines are turned into records; the default is TailLines
+func WithTailFormat(format TailFormat) TailOption {
+	return func(config *tailConfig) {
+		config.format = format
+	}
+}
+
+// WithTailContext stops the source when ctx is cancelled: the file is closed and every
+// pull returns ctx.Err(), including one waiting for new lines. Without it the source
+// follows the file until the process exits.
+func WithTailContext(ctx context.Context) TailOption {
+	return func(config *tailConfig) {
+		config.ctx = ctx
+	}
+}
+
+// WithPollInterval sets how often the file is checked for new lines and rotation
+// once the end has been reached; the default is 250ms
+func WithPollInterval(d time.Duration) TailOption {
+	if d <= 0 {
+		panic("poll interval must be positive")
+	}
+	return func(config *tailConfig) {
+		config.pollInterval = d
+	}
+}
+
+// WithStartAtEnd skips the content present when NewTailSource is called and emits only
+// lines appended after it
+func WithStartAtEnd() TailOption {
+	return func(config *tailConfig) {
+		config.startAtEnd = true
+	}
+}
+
+// WithTailPosition resumes from a checkpointed TailOffsetField and TailLineNumberField.
+// If the file has since shrunk below offset it is read from the start.
+func WithTailPosition(offset, lineNumber int64) TailOption {
+	if offset < 0 || lineNumber < 0 {
+		panic("tail position must not be negative")
+	}
+	return func(config *tailConfig) {
+		config.offset = offset
+		config.lineNumber = lineNumber
+	}
+}
+
+// WithoutReopen keeps reading the file first opened when path is renamed or replaced,
+// instead of switching to the new file at path. Truncation is still detected.
+func WithoutReopen() TailOption {
+	return func(config *tailConfig) {
+		config.reopen = false
+	}
+}
+
+// NewTailSource follows a growing file, like tail -f: it reads the existing lines, then
+// polls for appended ones, so the stream never ends on its own. A line is emitted once
+// its newline has been written. When the file is truncated it is read again from the
+// start; when it is rotated (path now names a different file) the rest of the old file
+// is read before switching to the new one, with offsets and line numbers starting over.
+// The file is opened on the first pull; use WithTailContext to stop the source.
+//
+// Example:
+//
+//	failures := Where(func(r Record) bool {
+//	    return strings.Contains(GetOr(r, TailLineField, ""), "ERROR")
+//	})(NewTailSource("/var/log/app.log", WithTailContext(ctx)))
+func NewTailSource(path string, options ...TailOption) Stream[Record] {
+	config := tailConfig{
+		ctx:          context.Background(),
+		pollInterval: 250 * time.Millisecond,
+		reopen:       true,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	if config.startAtEnd {
+		// Only the position is taken now; the file is still opened on the first pull
+		if info, err := os.Stat(path); err == nil {
+			config.offset, config.lineNumber = info.Size(), 0
+		}
+	}
+
+	t := &tailer{path: path, config: config}
+	var finalErr error // Sticky once the file can't be read or the context is done
+	return func() (Record, error) {
+		if finalErr != nil {
+			return nil, finalErr
+		}
+		record, err := t.next()
+		if err != nil {
+			var jsonErr *tailJSONError
+			if !errors.As(err, &jsonErr) {
+				t.close()
+				finalErr = err
+			}
+			return nil, err
+		}
+		return record, nil
+	}
+}
+
+// tailJSONError reports a line that failed to parse under TailJSON; the source
+// carries on with the next line if pulled again
+type tailJSONError struct {
+	lineNumber int64
+	err        error
+}
+
+func (e *tailJSONError) Error() string {
+	return fmt.Sprintf("failed to parse JSON line %d: %v", e.lineNumber, e.err)
+}
+
+func (e *tailJSONError) Unwrap() error {
+	return e.err
+}
+
+// tailer is the state of one NewTailSource
+type tailer struct {
+	path       string
+	config     tailConfig
+	file       *os.File
+	info       os.FileInfo // Of the open file, for detecting rotation
+	reader     *bufio.Reader
+	pending    []byte // Start of a line whose newline hasn't been written yet
+	offset     int64  // Offset of the first byte of pending
+	lineNumber int64
+}
+
+// next returns the next complete line as a record, waiting for one to be appended
+func (t *tailer) next() (Record, error) {
+	for {
+		if err := t.config.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if t.file == nil {
+			if err := t.open(); err != nil {
+				return nil, err
+			}
+		}
+
+		line, complete, err := t.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if complete {
+			record, skip, err := t.record(line)
+			if skip {
+				continue
+			}
+			return record, err
+		}
+
+		switched, err := t.checkRotation()
+		if err != nil {
+			return nil, err
+		}
+		if switched {
+			continue
+		}
+		if err := t.wait(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// open opens path, positioned as configured
+func (t *tailer) open() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open tailed file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat tailed file: %w", err)
+	}
+
+	start := t.config.offset
+	t.lineNumber = t.config.lineNumber
+	if start > info.Size() {
+		start, t.lineNumber = 0, 0
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek tailed file: %w", err)
+	}
+
+	t.file, t.info, t.offset = file, info, start
+	t.reader = bufio.NewReader(file)
+	t.pending = t.pending[:0]
+	return nil
+}
+
+// readLine reads up to the next newline; complete is false at the end of the file,
+// with any partial line kept in pending
+func (t *tailer) readLine() (line []byte, complete bool, err error) {
+	chunk, err := t.reader.ReadBytes('\n')
+	t.pending = append(t.pending, chunk...)
+	if err == io.EOF {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read tailed file: %w", err)
+	}
+
+	line = t.pending
+	t.offset += int64(len(line))
+	t.pending = nil
+	return bytes.TrimRight(line, "\r\n"), true, nil
+}
+
+// record turns a complete line into a record; skip is true for blank lines under TailJSON
+func (t *tailer) record(line []byte) (record Record, skip bool, err error) {
+	t.lineNumber++
+	switch t.config.format {
+	case TailJSON:
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil, true, nil
+		}
+		var jsonObj map[string]any
+		if err := json.Unmarshal(line, &jsonObj); err != nil {
+			return nil, false, &tailJSONError{lineNumber: t.lineNumber, err: err}
+		}
+		record = convertJSONToRecord(jsonObj)
+	default:
+		record = Record{TailLineField: string(line)}
+	}
+	record[TailOffsetField] = t.offset
+	record[TailLineNumberField] = t.lineNumber
+	return record, false, nil
+}
+
+// checkRotation handles truncation and rotation once the open file has been read to
+// its end, reporting whether reading should resume straight away
+func (t *tailer) checkRotation() (bool, error) {
+	info, err := t.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat tailed file: %w", err)
+	}
+	if info.Size() < t.offset+int64(len(t.pending)) {
+		// Truncated in place: start over from the beginning
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return false, fmt.Errorf("failed to seek tailed file: %w", err)
+		}
+		t.reader.Reset(t.file)
+		t.offset, t.lineNumber, t.pending = 0, 0, nil
+		return true, nil
+	}
+	if !t.config.reopen {
+		return false, nil
+	}
+
+	current, err := os.Stat(t.path)
+	if err != nil || os.SameFile(current, t.info) {
+		return false, nil // Not rotated, or the new file hasn't been created yet
+	}
+	// The old file has been read to its end; a final line without a newline is dropped
+	t.close()
+	t.config.offset, t.config.lineNumber = 0, 0
+	return true, nil
+}
+
+// wait sleeps for the poll interval, returning early if the context is done
+func (t *tailer) wait() error {
+	timer := time.NewTimer(t.config.pollInterval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.config.ctx.Done():
+		return t.config.ctx.Err()
+	}
+}
+
+// close releases the open file
+func (t *tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file, t.reader = nil, nil
+	}
+}
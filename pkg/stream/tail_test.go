@@ -0,0 +1,183 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// appendToFile appends text to the file at path, as a logging process would
+func appendToFile(t *testing.T, path, text string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(text); err != nil {
+		t.Fatalf("Failed to append to %s: %v", path, err)
+	}
+}
+
+// pullWithin pulls one record, failing the test if none arrives in time
+func pullWithin(t *testing.T, stream Stream[Record]) Record {
+	t.Helper()
+	type pulled struct {
+		record Record
+		err    error
+	}
+	done := make(chan pulled, 1)
+	go func() {
+		record, err := stream()
+		done <- pulled{record, err}
+	}()
+	select {
+	case p := <-done:
+		if p.err != nil {
+			t.Fatalf("Unexpected error: %v", p.err)
+		}
+		return p.record
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a tailed line")
+		return nil
+	}
+}
+
+// TestTailSource tests following a growing file
+func TestTailSource(t *testing.T) {
+	poll := WithPollInterval(5 * time.Millisecond)
+
+	t.Run("FollowsAppends", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendToFile(t, path, "first\nsecond\n")
+
+		lines := NewTailSource(path, poll, WithTailContext(ctx))
+		for _, expected := range []string{"first", "second"} {
+			if line := pullWithin(t, lines)[TailLineField]; line != expected {
+				t.Errorf("Expected %q, got %q", expected, line)
+			}
+		}
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			appendToFile(t, path, "thi")
+			time.Sleep(20 * time.Millisecond)
+			appendToFile(t, path, "rd\r\n")
+		}()
+		record := pullWithin(t, lines)
+		if record[TailLineField] != "third" || record[TailLineNumberField] != int64(3) || record[TailOffsetField] != int64(20) {
+			t.Errorf("Expected line 3 \"third\" ending at offset 20, got %v", record)
+		}
+	})
+
+	t.Run("JSONFromEnd", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		appendToFile(t, path, `{"old": true}`+"\n")
+
+		events := NewTailSource(path, poll, WithTailContext(ctx), WithTailFormat(TailJSON), WithStartAtEnd())
+		appendToFile(t, path, "\nnot json\n"+`{"level": "error", "code": 500}`+"\n")
+
+		if _, err := events(); err == nil {
+			t.Error("Expected an error for a line that isn't JSON")
+		}
+		record := pullWithin(t, events)
+		if record["level"] != "error" || record["code"] != int64(500) || record["old"] != nil {
+			t.Errorf("Expected the appended event, got %v", record)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		appendToFile(t, path, "one\n")
+
+		lines := NewTailSource(path, poll, WithTailContext(ctx))
+		pullWithin(t, lines)
+
+		// Rotate: the last lines go to the old file, then a new file takes its name
+		appendToFile(t, path, "two\n")
+		if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+			t.Fatalf("Failed to rotate: %v", err)
+		}
+		appendToFile(t, path, "new one\n")
+
+		if record := pullWithin(t, lines); record[TailLineField] != "two" {
+			t.Errorf("Expected the rest of the old file first, got %v", record)
+		}
+		record := pullWithin(t, lines)
+		if record[TailLineField] != "new one" || record[TailLineNumberField] != int64(1) || record[TailOffsetField] != int64(8) {
+			t.Errorf("Expected line 1 of the new file, got %v", record)
+		}
+	})
+
+	t.Run("Truncation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendToFile(t, path, "a long first line\n")
+
+		lines := NewTailSource(path, poll, WithTailContext(ctx))
+		pullWithin(t, lines)
+		if err := os.Truncate(path, 0); err != nil {
+			t.Fatalf("Failed to truncate: %v", err)
+		}
+		appendToFile(t, path, "fresh\n")
+
+		if record := pullWithin(t, lines); record[TailLineField] != "fresh" || record[TailLineNumberField] != int64(1) {
+			t.Errorf("Expected to start over after truncation, got %v", record)
+		}
+	})
+
+	t.Run("ResumeFromCheckpoint", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendToFile(t, path, "one\ntwo\nthree\n")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		first := pullWithin(t, NewTailSource(path, poll, WithTailContext(ctx)))
+		cancel()
+
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		resumed := NewTailSource(path, poll, WithTailContext(ctx),
+			WithTailPosition(first[TailOffsetField].(int64), first[TailLineNumberField].(int64)))
+		if record := pullWithin(t, resumed); record[TailLineField] != "two" || record[TailLineNumberField] != int64(2) {
+			t.Errorf("Expected to resume at line 2, got %v", record)
+		}
+	})
+
+	t.Run("CancelWhileWaiting", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendToFile(t, path, "")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		lines := NewTailSource(path, WithTailContext(ctx), WithPollInterval(time.Hour))
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		start := time.Now()
+		if _, err := lines(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the pull to return promptly on cancel, took %v", elapsed)
+		}
+		if _, err := lines(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected later pulls to keep returning context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		lines := NewTailSource(filepath.Join(t.TempDir(), "missing.log"))
+		if _, err := lines(); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected a not-exist error, got %v", err)
+		}
+	})
+}
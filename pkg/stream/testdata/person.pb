@@ -0,0 +1,10 @@
+
+
+person.protostreamv2.test"
+Address
+city (	Rcity"t
+Person
+name (	Rname
+age (Rage
+tags (	Rtags0
+address (2.streamv2.test.AddressRaddressbproto3
\ No newline at end of file
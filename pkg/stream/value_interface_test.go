@@ -1,6 +1,8 @@
 package stream
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -181,6 +183,85 @@ func TestNewRecordBuilder(t *testing.T) {
 	})
 }
 
+// TestStrictRecordBuilder tests build-time validation in the strict record builder
+func TestStrictRecordBuilder(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		record, err := NewRecordStrict().
+			String("name", "Alice").
+			Int("age", 30).
+			Record("address", NewRecord().String("city", "Paris").Build()).
+			Build()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if record["name"] != "Alice" || record["age"] != int64(30) {
+			t.Errorf("Unexpected record %v", record)
+		}
+	})
+
+	t.Run("DuplicateKeys", func(t *testing.T) {
+		record, err := NewRecordStrict().Int("x", 1).String("x", "oops").Int("y", 2).Build()
+		if !errors.Is(err, ErrDuplicateField) || record != nil {
+			t.Fatalf("Expected ErrDuplicateField and no record, got %v (%v)", record, err)
+		}
+		if !strings.Contains(err.Error(), `"x"`) || strings.Contains(err.Error(), `"y"`) {
+			t.Errorf("Expected the error to name x only, got %v", err)
+		}
+
+		// The permissive builder still overwrites quietly
+		if permissive := NewRecord().Int("x", 1).String("x", "oops").Build(); permissive["x"] != "oops" {
+			t.Errorf("Expected the permissive builder to keep the last value, got %v", permissive)
+		}
+	})
+
+	t.Run("InvalidValues", func(t *testing.T) {
+		_, err := NewRecordStrict().
+			Set("tags", []string{"a", "b"}).
+			Record("address", Record{"city": "Paris", "geo": map[string]float64{"lat": 48.8}}).
+			Build()
+		if !errors.Is(err, ErrInvalidField) {
+			t.Fatalf("Expected ErrInvalidField, got %v", err)
+		}
+		for _, path := range []string{`"tags"`, `"address.geo"`} {
+			if !strings.Contains(err.Error(), path) {
+				t.Errorf("Expected the error to name %s, got %v", path, err)
+			}
+		}
+		if strings.Contains(err.Error(), `"address.city"`) {
+			t.Errorf("Expected valid nested fields to pass, got %v", err)
+		}
+	})
+
+	t.Run("ListSetters", func(t *testing.T) {
+		record, err := NewRecordStrict().
+			String("id", "r1").
+			Strings("tags", []string{"a", "b"}).
+			Ints("scores", []int64{7, 9}).
+			Stream("items", FromRecordsUnsafe([]Record{{"sku": "x"}})).
+			Build()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := FromRecords([]Record{record}); err != nil {
+			t.Errorf("Expected FromRecords to accept the list fields, got %v", err)
+		}
+
+		// Stream fields set this way expand under CrossFlatten
+		flattened, err := Collect(CrossFlatten(".", "tags", "scores")(FromRecordsUnsafe([]Record{record})))
+		if err != nil {
+			t.Fatalf("CrossFlatten failed: %v", err)
+		}
+		if len(flattened) != 4 {
+			t.Errorf("Expected 4 combinations of tags and scores, got %d: %v", len(flattened), flattened)
+		}
+
+		permissive := NewRecord().Strings("tags", []string{"a"}).Ints("n", []int64{1}).Build()
+		if !isValueType(permissive["tags"]) || !isValueType(permissive["n"]) {
+			t.Errorf("Expected the permissive setters to store Value stream types, got %T and %T", permissive["tags"], permissive["n"])
+		}
+	})
+}
+
 // BenchmarkConstructorPerformance compares old vs new approaches
 func BenchmarkConstructorPerformance(b *testing.B) {
 	b.Run("NewTypeSafeBuilder", func(b *testing.B) {
@@ -345,6 +426,87 @@ func TestFloatStringParsing(t *testing.T) {
 	})
 }
 
+// TestConversionMatrix tests Get's conversions between strings, numbers and bools
+func TestConversionMatrix(t *testing.T) {
+	type celsius float64
+	record := Record{
+		"price":    "12.50",
+		"padded":   " 42 ",
+		"false":    "false",
+		"yes":      "YES",
+		"zero":     "0",
+		"na":       "N/A",
+		"empty":    "",
+		"code":     int64(65),
+		"small":    uint8(7),
+		"ratio":    2.5,
+		"count":    int64(3),
+		"flag":     true,
+		"big":      "300",
+		"negative": "-5",
+	}
+
+	check := func(name string, got, want any, ok, wantOK bool) {
+		t.Helper()
+		if ok != wantOK || (wantOK && got != want) {
+			t.Errorf("%s: expected %v (%v), got %v (%v)", name, want, wantOK, got, ok)
+		}
+	}
+
+	f, ok := Get[float64](record, "price")
+	check("float64 from \"12.50\"", f, 12.5, ok, true)
+	i, ok := Get[int64](record, "price")
+	check("int64 from \"12.50\"", i, int64(0), ok, false)
+	i, ok = Get[int64](record, "padded")
+	check("int64 from \" 42 \"", i, int64(42), ok, true)
+	i, ok = Get[int64](record, "ratio")
+	check("int64 from 2.5", i, int64(2), ok, true)
+
+	b, ok := Get[bool](record, "false")
+	check("bool from \"false\"", b, false, ok, true)
+	b, ok = Get[bool](record, "yes")
+	check("bool from \"YES\"", b, true, ok, true)
+	b, ok = Get[bool](record, "zero")
+	check("bool from \"0\"", b, false, ok, true)
+	b, ok = Get[bool](record, "na")
+	check("bool from \"N/A\"", b, false, ok, false)
+	b, ok = Get[bool](record, "empty")
+	check("bool from \"\"", b, false, ok, false)
+	b, ok = Get[bool](record, "count")
+	check("bool from int64(3)", b, true, ok, true)
+	if GetOr(record, "false", true) {
+		t.Error("Expected GetOr to read \"false\" as false")
+	}
+
+	str, ok := Get[string](record, "code")
+	check("string from int64(65)", str, "65", ok, true)
+	str, ok = Get[string](record, "small")
+	check("string from uint8(7)", str, "7", ok, true)
+	str, ok = Get[string](record, "ratio")
+	check("string from 2.5", str, "2.5", ok, true)
+	str, ok = Get[string](record, "flag")
+	check("string from true", str, "true", ok, true)
+
+	n, ok := Get[int](record, "padded")
+	check("int from \" 42 \"", n, 42, ok, true)
+	small, ok := Get[int8](record, "big")
+	check("int8 from \"300\"", small, int8(0), ok, false)
+	u, ok := Get[uint](record, "negative")
+	check("uint from \"-5\"", u, uint(0), ok, false)
+	c, ok := Get[celsius](record, "price")
+	check("named float from \"12.50\"", c, celsius(12.5), ok, true)
+	c, ok = Get[celsius](record, "count")
+	check("named float from int64(3)", c, celsius(3), ok, true)
+
+	t.Run("WhereOnStringBools", func(t *testing.T) {
+		users := []Record{{"name": "a", "active": "true"}, {"name": "b", "active": "false"}, {"name": "c", "active": "no"}}
+		active, err := Collect(Where(func(r Record) bool { return GetOr(r, "active", false) })(FromRecordsUnsafe(users)))
+		if err != nil || len(active) != 1 || active[0]["name"] != "a" {
+			t.Errorf("Expected only a to be active, got %v (%v)", active, err)
+		}
+	})
+}
+
 // Helper function for float comparison
 func abs(x float64) float64 {
 	if x < 0 {